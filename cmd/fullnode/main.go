@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,15 +15,20 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ParichayaHQ/credence/internal/crypto"
 	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/statuslist"
 	"github.com/ParichayaHQ/credence/internal/store"
 )
 
 // FullNodeServer wraps the full node storage with HTTP API
 type FullNodeServer struct {
-	fullNode *store.FullNode
-	config   *ServerConfig
-	server   *http.Server
+	fullNode      *store.FullNode
+	mirror        *statuslist.StatusListMirror
+	announcements *statuslist.AnnouncementStore
+	deltas        *statuslist.DeltaLog
+	config        *ServerConfig
+	server        *http.Server
 }
 
 // ServerConfig holds server configuration
@@ -72,10 +79,34 @@ func main() {
 	}
 	defer fullNode.Close()
 	
+	// Mirror issuers' status lists under this node's receipt key
+	mirrorKey, err := loadMirrorKey(os.Getenv("FULLNODE_MIRROR_SEED"))
+	if err != nil {
+		log.Fatalf("Failed to load mirror key: %v", err)
+	}
+	mirrorSigner := crypto.NewEd25519Signer(mirrorKey)
+	log.Printf("Status list mirror key: %s", mirrorSigner.PublicKeyBase64())
+	
+	// Receipts outlive restarts along with the mirrored snapshots
+	mirror := statuslist.NewStatusListMirror(fullNode,
+		statuslist.NewHTTPSnapshotFetcher(0), nil, mirrorSigner)
+	if err := mirror.SetStateStore(fullNode); err != nil {
+		log.Fatalf("Failed to load mirror receipts: %v", err)
+	}
+	
+	// Status list deltas let verifiers catch up without refetching lists
+	deltas := statuslist.NewDeltaLog()
+	if err := deltas.SetStateStore(fullNode); err != nil {
+		log.Fatalf("Failed to load status list deltas: %v", err)
+	}
+	
 	// Create server
 	server := &FullNodeServer{
-		fullNode: fullNode,
-		config:   serverConfig,
+		fullNode:      fullNode,
+		mirror:        mirror,
+		announcements: statuslist.NewAnnouncementStore(fullNode, fullNode, nil),
+		deltas:        deltas,
+		config:        serverConfig,
 	}
 	
 	// Start HTTP server
@@ -101,6 +132,20 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// loadMirrorKey derives the receipt signing key from a base64 seed. The key
+// must stay the same across restarts for verifiers to keep trusting the
+// receipts, so there is no generated fallback.
+func loadMirrorKey(seed string) (*crypto.Ed25519KeyPair, error) {
+	if seed == "" {
+		return nil, fmt.Errorf("FULLNODE_MIRROR_SEED is not set")
+	}
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed: %w", err)
+	}
+	return crypto.NewEd25519KeyPairFromSeed(raw)
+}
+
 // Start starts the HTTP server
 func (s *FullNodeServer) Start() error {
 	router := s.setupRoutes()
@@ -163,8 +208,13 @@ func (s *FullNodeServer) setupRoutes() *mux.Router {
 	v1.HandleFunc("/checkpoints", s.handleListCheckpoints).Methods("GET")
 	
 	// Status list endpoints  
+	v1.HandleFunc("/status/mirror", s.handleMirrorStatusList).Methods("POST")
+	v1.HandleFunc("/status/mirror", s.handleGetMirroredStatusList).Methods("GET")
+	v1.HandleFunc("/status/deltas", s.handleStoreStatusListDelta).Methods("POST")
+	v1.HandleFunc("/status/deltas", s.handleGetStatusListDeltas).Methods("GET")
 	v1.HandleFunc("/status/{issuer}/{epoch}", s.handleStoreStatusList).Methods("POST")
 	v1.HandleFunc("/status/{issuer}/{epoch}", s.handleGetStatusList).Methods("GET")
+	v1.HandleFunc("/status/{issuer}/{epoch}/entry", s.handleGetHistoricalStatus).Methods("GET")
 	
 	// Combined operations
 	v1.HandleFunc("/events/with-blob", s.handleStoreEventWithBlob).Methods("POST")
@@ -433,21 +483,31 @@ func (s *FullNodeServer) handleListCheckpoints(w http.ResponseWriter, r *http.Re
 }
 
 // Status list handlers
+
+// handleStoreStatusList indexes the snapshot an issuer announced for an
+// epoch. The announcement must be signed by the issuer and is kept so
+// historical queries can check the index against it.
 func (s *FullNodeServer) handleStoreStatusList(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	issuer := vars["issuer"]
 	epoch := vars["epoch"]
 	
-	var payload struct {
-		BitmapCID string `json:"bitmap_cid"`
+	var announcement events.RevocationAnnounceEvent
+	if err := json.NewDecoder(r.Body).Decode(&announcement); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if announcement.Issuer != issuer {
+		http.Error(w, "Announcement belongs to another issuer", http.StatusBadRequest)
+		return
 	}
 	
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	if err := s.announcements.StoreAnnouncement(r.Context(), epoch, &announcement); err != nil {
+		writeAnnouncementError(w, err)
 		return
 	}
 	
-	if err := s.fullNode.StoreStatusList(r.Context(), issuer, epoch, payload.BitmapCID); err != nil {
+	if err := s.fullNode.StoreStatusList(r.Context(), issuer, epoch, announcement.BitmapCID); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to store status list: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -470,10 +530,176 @@ func (s *FullNodeServer) handleGetStatusList(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	response := map[string]interface{}{
 		"bitmap_cid": bitmapCID,
-	})
+	}
+	if receipt, exists := s.mirror.Receipt(issuer, epoch); exists && receipt.BitmapCID == bitmapCID {
+		response["receipt"] = receipt
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleMirrorStatusList mirrors the snapshot a revocation announcement
+// points at and returns this node's receipt for it
+func (s *FullNodeServer) handleMirrorStatusList(w http.ResponseWriter, r *http.Request) {
+	var announcement events.RevocationAnnounceEvent
+	if err := json.NewDecoder(r.Body).Decode(&announcement); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	
+	receipt, err := s.mirror.Mirror(r.Context(), &announcement)
+	if err != nil {
+		var statusErr *statuslist.StatusListError
+		if errors.As(err, &statusErr) && statusErr.Code == statuslist.ErrorNetworkError {
+			http.Error(w, fmt.Sprintf("Failed to mirror status list: %v", err), http.StatusBadGateway)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Rejected announcement: %v", err), http.StatusBadRequest)
+		return
+	}
+	// The mirrored snapshot is indexed, so its announcement is kept too
+	if err := s.announcements.StoreAnnouncement(r.Context(), receipt.Epoch, &announcement); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record announcement: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// handleGetMirroredStatusList serves the latest mirrored snapshot holding a
+// status list, with this node's receipt
+func (s *FullNodeServer) handleGetMirroredStatusList(w http.ResponseWriter, r *http.Request) {
+	listID := r.URL.Query().Get("list")
+	if listID == "" {
+		http.Error(w, "list parameter is required", http.StatusBadRequest)
+		return
+	}
+	
+	mirrored, err := s.mirror.Lookup(r.Context(), listID)
+	if err != nil {
+		var statusErr *statuslist.StatusListError
+		if errors.As(err, &statusErr) && statusErr.Code == statuslist.ErrorListNotFound {
+			http.Error(w, "Status list not mirrored", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to get mirrored status list: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mirrored)
+}
+
+// writeAnnouncementError reports why an announcement was not accepted
+func writeAnnouncementError(w http.ResponseWriter, err error) {
+	var statusErr *statuslist.StatusListError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case statuslist.ErrorNetworkError:
+			http.Error(w, fmt.Sprintf("Failed to record announcement: %v", err), http.StatusInternalServerError)
+			return
+		case statuslist.ErrorPermissionDenied:
+			http.Error(w, fmt.Sprintf("Rejected announcement: %v", err), http.StatusForbidden)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("Rejected announcement: %v", err), http.StatusBadRequest)
+}
+
+// handleStoreStatusListDelta records a delta signed by the issuer of its
+// status list
+func (s *FullNodeServer) handleStoreStatusListDelta(w http.ResponseWriter, r *http.Request) {
+	var delta statuslist.StatusListDelta
+	if err := json.NewDecoder(r.Body).Decode(&delta); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	
+	if err := delta.VerifyIssuer(statuslist.DIDKeyResolver); err != nil {
+		http.Error(w, fmt.Sprintf("Rejected delta: %v", err), http.StatusForbidden)
+		return
+	}
+	if err := s.deltas.PublishDelta(r.Context(), &delta); err != nil {
+		var statusErr *statuslist.StatusListError
+		if errors.As(err, &statusErr) && statusErr.Code == statuslist.ErrorInvalidStatusList {
+			http.Error(w, fmt.Sprintf("Rejected delta: %v", err), http.StatusConflict)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to store delta: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleGetStatusListDeltas serves the deltas of a status list from a
+// version onwards
+func (s *FullNodeServer) handleGetStatusListDeltas(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	listID := query.Get("list")
+	if listID == "" {
+		http.Error(w, "list parameter is required", http.StatusBadRequest)
+		return
+	}
+	var fromVersion uint64
+	if from := query.Get("from"); from != "" {
+		parsed, err := strconv.ParseUint(from, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid from version", http.StatusBadRequest)
+			return
+		}
+		fromVersion = parsed
+	}
+	
+	deltas, err := s.deltas.FetchDeltas(listID, fromVersion)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get deltas: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deltas)
+}
+
+// handleGetHistoricalStatus reports whether a status list entry was set as
+// of an epoch, with the published bitmap CID that shows it
+func (s *FullNodeServer) handleGetHistoricalStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	issuer := vars["issuer"]
+	epoch := vars["epoch"]
+	query := r.URL.Query()
+	
+	entry := &statuslist.StatusListEntry{
+		StatusListCredential: query.Get("list"),
+		StatusListIndex:      query.Get("index"),
+		StatusPurpose:        query.Get("purpose"),
+	}
+	
+	history := statuslist.NewStatusListHistory(s.fullNode, s.announcements, s.fullNode, nil, 0)
+	status, err := history.StatusAt(r.Context(), issuer, entry, epoch)
+	if err != nil {
+		var statusErr *statuslist.StatusListError
+		if errors.As(err, &statusErr) {
+			switch statusErr.Code {
+			case statuslist.ErrorListNotFound:
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			case statuslist.ErrorInvalidEntry, statuslist.ErrorInvalidIndex:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		http.Error(w, fmt.Sprintf("Failed to get status: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
 }
 
 // Combined operation handlers
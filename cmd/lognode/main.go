@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -22,6 +23,12 @@ type LogNodeServer struct {
 	transparencyLog logpkg.TransparencyLog
 	config          *ServerConfig
 	server          *http.Server
+	
+	// eventLeaves maps event CIDs to the index of their reference leaf,
+	// covering the first indexedSize leaves of the log
+	eventLeaves map[string]int64
+	indexedSize int64
+	indexMu     sync.Mutex
 }
 
 // ServerConfig holds server configuration
@@ -75,6 +82,7 @@ func main() {
 	server := &LogNodeServer{
 		transparencyLog: transparencyLog,
 		config:          serverConfig,
+		eventLeaves:     make(map[string]int64),
 	}
 	
 	// Start HTTP server
@@ -154,6 +162,7 @@ func (s *LogNodeServer) setupRoutes() *mux.Router {
 	
 	// Event reference helpers
 	v1.HandleFunc("/events/append", s.handleAppendEventReferences).Methods("POST")
+	v1.HandleFunc("/events/{cid}/inclusion", s.handleGetEventInclusion).Methods("GET")
 	
 	// Add middleware
 	r.Use(loggingMiddleware)
@@ -214,6 +223,7 @@ func (s *LogNodeServer) handleAppendLeaves(w http.ResponseWriter, r *http.Reques
 		http.Error(w, fmt.Sprintf("Failed to append leaves: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.indexEventLeaves(r.Context())
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -416,6 +426,7 @@ func (s *LogNodeServer) handleAppendEventReferences(w http.ResponseWriter, r *ht
 		http.Error(w, fmt.Sprintf("Failed to append event references: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.indexEventLeaves(r.Context())
 	
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -427,6 +438,99 @@ func (s *LogNodeServer) handleAppendEventReferences(w http.ResponseWriter, r *ht
 	})
 }
 
+// Get the inclusion proof for an event by its CID. Clients that only know the
+// event (such as wallets) cannot recompute the leaf hash, which covers the
+// reference metadata added when the event was appended, so the leaf value is
+// returned for them to hash and check.
+func (s *LogNodeServer) handleGetEventInclusion(w http.ResponseWriter, r *http.Request) {
+	cid := mux.Vars(r)["cid"]
+	
+	var treeSize int64
+	if treeSizeParam := r.URL.Query().Get("tree_size"); treeSizeParam != "" {
+		parsed, err := strconv.ParseInt(treeSizeParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid tree_size parameter", http.StatusBadRequest)
+			return
+		}
+		treeSize = parsed
+	}
+	
+	leaf, err := s.findEventLeaf(r.Context(), cid)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search log: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if leaf == nil {
+		http.Error(w, "Event not found in log", http.StatusNotFound)
+		return
+	}
+	
+	proof, err := s.transparencyLog.GetInclusionProof(r.Context(), leaf.LeafHash, treeSize)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get inclusion proof: %v", err), http.StatusNotFound)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cid":       cid,
+		"leaf_hash":  hex.EncodeToString(leaf.LeafHash),
+		"leaf_value": leaf.LeafValue,
+		"proof":      proof,
+	})
+}
+
+// findEventLeaf returns the event reference leaf of cid, or nil if the
+// event is not in the log
+func (s *LogNodeServer) findEventLeaf(ctx context.Context, cid string) (*logpkg.Leaf, error) {
+	if err := s.indexEventLeaves(ctx); err != nil {
+		return nil, err
+	}
+	
+	s.indexMu.Lock()
+	index, exists := s.eventLeaves[cid]
+	s.indexMu.Unlock()
+	if !exists {
+		return nil, nil
+	}
+	
+	leaves, err := s.transparencyLog.GetLeavesByRange(ctx, index, index)
+	if err != nil || len(leaves) == 0 {
+		return nil, err
+	}
+	return &leaves[0], nil
+}
+
+// indexEventLeaves adds the event reference leaves appended since the last
+// call to the CID index. Appends call it so lookups only read new leaves.
+func (s *LogNodeServer) indexEventLeaves(ctx context.Context) error {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	
+	size, err := s.transparencyLog.GetTreeSize(ctx)
+	if err != nil || size <= s.indexedSize {
+		return err
+	}
+	
+	leaves, err := s.transparencyLog.GetLeavesByRange(ctx, s.indexedSize, size-1)
+	if err != nil {
+		return err
+	}
+	
+	for i := range leaves {
+		var ref logpkg.EventReference
+		if err := json.Unmarshal(leaves[i].LeafValue, &ref); err != nil || ref.CID == "" {
+			continue
+		}
+		// The first reference to an event is the one proofs are served for
+		if _, exists := s.eventLeaves[ref.CID]; !exists {
+			s.eventLeaves[ref.CID] = s.indexedSize + int64(i)
+		}
+	}
+	s.indexedSize = size
+	return nil
+}
+
 // Utility function to get tree size without error handling
 func (s *LogNodeServer) getTreeSizeUnsafe() int64 {
 	size, _ := s.transparencyLog.GetTreeSize(context.Background())
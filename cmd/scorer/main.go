@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/ParichayaHQ/credence/internal/consensus"
 	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/did"
 	"github.com/ParichayaHQ/credence/internal/score"
 	"github.com/ParichayaHQ/credence/internal/store"
 )
@@ -22,10 +27,28 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// loadCheckpointVerifier reads the checkpoint committee from a JSON file
+func loadCheckpointVerifier(path string) (*consensus.CommitteeCheckpointVerifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var committee consensus.CheckpointCommittee
+	if err := json.Unmarshal(data, &committee); err != nil {
+		return nil, fmt.Errorf("failed to decode committee: %w", err)
+	}
+	return consensus.NewCommitteeCheckpointVerifier(&committee)
+}
+
 func main() {
 	var (
-		port     = flag.Int("port", 8082, "HTTP server port")
-		dbPath   = flag.String("db", getEnvOrDefault("DATA_DIR", "./scorer-data"), "Database path")
+		port         = flag.Int("port", 8082, "HTTP server port")
+		dbPath       = flag.String("db", getEnvOrDefault("DATA_DIR", "./scorer-data"), "Database path")
+		logNode      = flag.String("log-node", getEnvOrDefault("LOG_NODE_URL", ""), "Log node URL to read checkpoint events from")
+		bridge       = flag.String("p2p-bridge", getEnvOrDefault("P2P_BRIDGE_URL", ""), "P2P bridge URL to follow checkpoints/epoch on")
+		fullNode     = flag.String("fullnode-url", getEnvOrDefault("FULL_NODE_URL", ""), "Full node URL to read adjudication events and decisions from")
+		adjudicators = flag.String("adjudicators", getEnvOrDefault("ADJUDICATORS", ""), "Comma-separated adjudicator DIDs (defaults to the ruleset's)")
+		committee    = flag.String("committee", getEnvOrDefault("CHECKPOINT_COMMITTEE", ""), "JSON file with the checkpoint committee's keys and threshold")
 	)
 	flag.Parse()
 
@@ -46,7 +69,25 @@ func main() {
 	// In production, you'd want separate storage or use RocksDB
 
 	// Initialize data provider (mock implementation for now)
-	dataProvider := NewMockDataProvider(blobStore)
+	baseProvider := NewMockDataProvider(blobStore)
+
+	// Keep the scores of every solved epoch as a per-epoch time series
+	if err := os.MkdirAll(storeConfig.RocksDB.Path, 0755); err != nil {
+		log.Fatalf("Failed to create score history directory: %v", err)
+	}
+	scorerStore, err := store.NewScorerStore(storeConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize scorer store: %v", err)
+	}
+	defer scorerStore.Close()
+	scoreHistory := score.NewPersistentScoreHistory(scorerStore)
+
+	// Carry trust across verified DID key migrations
+	migrations := score.NewMigrationRegistry(did.NewDefaultKeyManager())
+	if err := migrations.SetStateStore(scorerStore); err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	dataProvider := score.NewMigrationAwareDataProvider(baseProvider, migrations)
 
 	// Initialize cryptographic components
 	keyPair, err := crypto.NewEd25519KeyPair()
@@ -74,6 +115,24 @@ func main() {
 		signer,
 	)
 
+	// Weight KYC and attestation issuers by their adjudication history
+	issuerReputation := score.NewIssuerReputationRegistry(nil)
+	if err := issuerReputation.SetStateStore(scorerStore); err != nil {
+		log.Fatalf("Failed to load issuer reputation: %v", err)
+	}
+	engine.SetIssuerReputation(issuerReputation)
+
+	// Vouchers answer for vouchees found abusive within the dispute window
+	bondLedger := score.NewBondLedger(dataProvider, nil)
+	if err := bondLedger.SetStateStore(scorerStore); err != nil {
+		log.Fatalf("Failed to load bond ledger: %v", err)
+	}
+	engine.SetBondProvider(bondLedger)
+
+	// Serve per-DID scores from solved epoch snapshots
+	engine.SetSnapshotStore(score.NewMemorySnapshotStore())
+	engine.SetScoreHistory(scoreHistory)
+
 	// Initialize HTTP service
 	httpService := score.NewHTTPService(
 		engine,
@@ -82,6 +141,56 @@ func main() {
 		config,
 		*port,
 	)
+	httpService.SetMigrationRegistry(migrations)
+	httpService.SetIssuerReputation(issuerReputation)
+	httpService.SetBondLedger(bondLedger)
+	httpService.SetScoreHistory(scoreHistory)
+	httpService.SetEpochSolver(engine)
+
+	// Recompute affected scores as checkpoints are finalized
+	recomputeCtx, stopRecompute := context.WithCancel(context.Background())
+	defer stopRecompute()
+	if *logNode != "" && *bridge != "" && *committee == "" {
+		log.Printf("No checkpoint committee configured, not following checkpoints")
+	} else if *logNode != "" && *bridge != "" {
+		verifier, err := loadCheckpointVerifier(*committee)
+		if err != nil {
+			log.Fatalf("Failed to load checkpoint committee: %v", err)
+		}
+		config := score.DefaultCheckpointRecomputerConfig()
+		config.Logf = log.Printf
+		recomputer := score.NewCheckpointRecomputer(engine, score.NewHTTPLogEventSource(*logNode), verifier, config)
+		if err := recomputer.SetStateStore(scorerStore); err != nil {
+			log.Fatalf("Failed to load checkpoint state: %v", err)
+		}
+		httpService.SetCheckpointRecomputer(recomputer)
+
+		// Verdicts, appeals and issuer outcomes only come from logged,
+		// signed adjudications
+		adjudicatorDIDs := consensus.DefaultRuleSet().Adjudicators
+		if *adjudicators != "" {
+			adjudicatorDIDs = strings.Split(*adjudicators, ",")
+		}
+		if *fullNode != "" && len(adjudicatorDIDs) == 0 {
+			log.Printf("No adjudicators configured, not applying verdicts, appeals or issuer outcomes (set -adjudicators)")
+		} else if *fullNode != "" {
+			processor := score.NewAdjudicationProcessor(score.NewHTTPAdjudicationFetcher(*fullNode), adjudicatorDIDs)
+			processor.SetBondLedger(bondLedger)
+			processor.SetIssuerReputation(issuerReputation)
+			if err := processor.SetStateStore(scorerStore); err != nil {
+				log.Fatalf("Failed to load adjudications: %v", err)
+			}
+			recomputer.SetAdjudicationProcessor(processor)
+			log.Printf("Applying adjudications from %d adjudicators", len(adjudicatorDIDs))
+		}
+		go func() {
+			source := score.NewHTTPCheckpointSource(*bridge, 10*time.Second)
+			if err := recomputer.Run(recomputeCtx, source); err != nil && err != context.Canceled {
+				log.Printf("Checkpoint recomputation stopped: %v", err)
+			}
+		}()
+		log.Printf("Following checkpoints from %s", *bridge)
+	}
 
 	// Start server in background
 	serverErrors := make(chan error, 1)
@@ -166,6 +275,45 @@ func (m *MockDataProvider) GetVouches(ctx context.Context, did, context string,
 	return filtered, nil
 }
 
+// ListDIDs implements score.DIDLister with every DID the provider has
+// vouches or scores for
+func (m *MockDataProvider) ListDIDs(ctx context.Context, context string, epoch int64) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, vouches := range m.vouches {
+		for _, vouch := range vouches {
+			if vouch.Context == context && vouch.Epoch <= epoch {
+				seen[vouch.FromDID], seen[vouch.ToDID] = true, true
+			}
+		}
+	}
+	for _, stored := range m.scores {
+		if stored.Context == context && stored.Epoch <= epoch {
+			seen[stored.DID] = true
+		}
+	}
+	
+	dids := make([]string, 0, len(seen))
+	for did := range seen {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	return dids, nil
+}
+
+// GetVouchees implements score.VoucheeProvider
+func (m *MockDataProvider) GetVouchees(ctx context.Context, did, context string, maxEpoch int64) ([]string, error) {
+	var vouchees []string
+	for _, vouches := range m.vouches {
+		for _, vouch := range vouches {
+			if vouch.FromDID == did && vouch.Context == context && vouch.Epoch <= maxEpoch {
+				vouchees = append(vouchees, vouch.ToDID)
+			}
+		}
+	}
+	sort.Strings(vouchees)
+	return vouchees, nil
+}
+
 func (m *MockDataProvider) GetAttestations(ctx context.Context, did, context string, maxEpoch int64) ([]*score.AttestationData, error) {
 	// Return mock attestations
 	return []*score.AttestationData{
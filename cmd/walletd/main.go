@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ParichayaHQ/credence/cmd/walletd/server"
+	"github.com/ParichayaHQ/credence/internal/consensus"
 	"github.com/ParichayaHQ/credence/internal/wallet"
 )
 
@@ -20,6 +26,24 @@ var (
 	host     = flag.String("host", "127.0.0.1", "HTTP server host")
 	logLevel = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
 	dataDir  = flag.String("data-dir", "", "Data directory for wallet storage (defaults to OS-specific location)")
+
+	tokenFile  = flag.String("token-file", "", "File the local admin token is written to (defaults to <data-dir>/auth-token)")
+	sessionTTL = flag.Duration("session-ttl", 15*time.Minute, "Lifetime of session tokens")
+	autoLock   = flag.Duration("auto-lock", time.Hour, "Lock the wallet after this much inactivity (negative disables)")
+
+	gatewayURL        = flag.String("gateway-url", "", "P2P gateway HTTP bridge URL events are published through")
+	fullNodeURL       = flag.String("fullnode-url", "", "Full node URL events are stored on and checkpoints are read from")
+	logNodeURL        = flag.String("lognode-url", "", "Log node URL inclusion proofs are read from")
+	committeeFile     = flag.String("checkpoint-committee", "", "JSON file with the checkpoint committee's keys and threshold, needed to mark events checkpointed")
+	eventSyncInterval = flag.Duration("event-sync-interval", time.Minute, "How often submitted events are checked for inclusion")
+	statusInterval    = flag.Duration("status-publish-interval", time.Minute, "How often changed status lists of issued credentials are published")
+	statusMirrors     = flag.String("status-mirrors", "", "Comma-separated full node URLs that mirror the status lists of issued credentials")
+
+	rulesURL        = flag.String("rules-url", "", "URL the active consensus ruleset is fetched from")
+	rulesPublicKey  = flag.String("rules-public-key", "", "Base64 Ed25519 key that must sign the ruleset hash (required with -rules-url)")
+	scorerURL       = flag.String("scorer-url", "", "Scorer URL signed trust scores are fetched from")
+	scorerPublicKey = flag.String("scorer-public-key", "", "Base64 Ed25519 key score proofs must be signed with")
+	vouchBudgetMode = flag.String("vouch-budget-mode", "enforce", "What to do with over-budget vouches (enforce, warn, off)")
 )
 
 func main() {
@@ -29,13 +53,19 @@ func main() {
 	setupLogging(*logLevel)
 
 	// Initialize wallet service
-	walletService, err := initializeWallet(*dataDir)
+	walletService, resolvedDataDir, err := initializeWallet(*dataDir)
 	if err != nil {
 		log.Fatalf("Failed to initialize wallet: %v", err)
 	}
 
+	// Issue the local admin token; clients read it from the token file
+	auth, err := initializeAuth(resolvedDataDir)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+
 	// Create HTTP server
-	srv := server.NewServer(walletService)
+	srv := server.NewServer(walletService, auth)
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf("%s:%s", *host, *port),
 		Handler:      srv.Router(),
@@ -44,6 +74,18 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Track submitted events until they are checkpointed
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	if *gatewayURL != "" || *fullNodeURL != "" {
+		go syncEvents(syncCtx, walletService, *eventSyncInterval)
+	}
+	if walletService.PublishesStatusLists() {
+		go publishStatusLists(syncCtx, walletService, *statusInterval)
+	} else {
+		log.Printf("No -fullnode-url set: status changes of issued credentials stay in the wallet and are not published")
+	}
+
 	// Start server in goroutine
 	go func() {
 		log.Printf("Starting walletd HTTP server on %s:%s", *host, *port)
@@ -67,6 +109,13 @@ func main() {
 		log.Printf("Error during server shutdown: %v", err)
 	}
 
+	// Publish status changes made since the last tick
+	if walletService.PublishesStatusLists() {
+		if _, err := walletService.PublishStatusLists(ctx); err != nil {
+			log.Printf("Status list publication failed: %v", err)
+		}
+	}
+
 	// Close wallet service
 	if err := walletService.Close(); err != nil {
 		log.Printf("Error closing wallet service: %v", err)
@@ -75,35 +124,181 @@ func main() {
 	log.Println("Walletd server stopped")
 }
 
-func initializeWallet(dataDir string) (*wallet.Service, error) {
+func initializeWallet(dataDir string) (*wallet.Service, string, error) {
 	// Use default data directory if not specified
 	if dataDir == "" {
 		var err error
 		dataDir, err = getDefaultDataDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get default data directory: %w", err)
+			return nil, "", fmt.Errorf("failed to get default data directory: %w", err)
 		}
 	}
 
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create data directory %s: %w", dataDir, err)
+		return nil, "", fmt.Errorf("failed to create data directory %s: %w", dataDir, err)
 	}
 
 	log.Printf("Using data directory: %s", dataDir)
 
 	// Initialize wallet service
 	config := &wallet.Config{
-		DataDir: dataDir,
+		DataDir:         dataDir,
+		AutoLockTimeout: *autoLock,
 		// Add other configuration options as needed
 	}
 
+	if *gatewayURL != "" || *fullNodeURL != "" {
+		config.Network = &wallet.NetworkConfig{
+			GatewayURL:  *gatewayURL,
+			FullNodeURL: *fullNodeURL,
+			LogNodeURL:  *logNodeURL,
+		}
+		for _, mirror := range strings.Split(*statusMirrors, ",") {
+			if mirror = strings.TrimSpace(mirror); mirror != "" {
+				config.Network.StatusMirrors = append(config.Network.StatusMirrors, mirror)
+			}
+		}
+		if *committeeFile != "" {
+			committee, err := loadCheckpointCommittee(*committeeFile)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to load checkpoint committee: %w", err)
+			}
+			config.Network.Committee = committee
+		}
+	}
+
+	budget, err := budgetConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	config.Budget = budget
+
 	walletService, err := wallet.NewService(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create wallet service: %w", err)
+		return nil, "", fmt.Errorf("failed to create wallet service: %w", err)
 	}
 
-	return walletService, nil
+	return walletService, dataDir, nil
+}
+
+func loadCheckpointCommittee(path string) (*consensus.CheckpointCommittee, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var committee consensus.CheckpointCommittee
+	if err := json.Unmarshal(data, &committee); err != nil {
+		return nil, err
+	}
+	return &committee, nil
+}
+
+func budgetConfig() (*wallet.BudgetConfig, error) {
+	mode := wallet.BudgetMode(*vouchBudgetMode)
+	switch mode {
+	case wallet.BudgetModeEnforce, wallet.BudgetModeWarn, wallet.BudgetModeOff:
+	default:
+		return nil, fmt.Errorf("invalid vouch budget mode: %s", *vouchBudgetMode)
+	}
+
+	config := &wallet.BudgetConfig{
+		RulesURL:  *rulesURL,
+		ScorerURL: *scorerURL,
+		Mode:      mode,
+	}
+
+	var err error
+	if config.RulesPublicKey, err = decodePublicKey(*rulesPublicKey); err != nil {
+		return nil, fmt.Errorf("invalid rules public key: %w", err)
+	}
+	if config.ScorerPublicKey, err = decodePublicKey(*scorerPublicKey); err != nil {
+		return nil, fmt.Errorf("invalid scorer public key: %w", err)
+	}
+
+	return config, nil
+}
+
+func decodePublicKey(encoded string) (ed25519.PublicKey, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+func initializeAuth(dataDir string) (*server.Authenticator, error) {
+	authConfig := server.DefaultAuthConfig()
+	authConfig.SessionTTL = *sessionTTL
+	authConfig.StatePath = filepath.Join(dataDir, "auth-state.json")
+
+	auth, token, err := server.NewAuthenticator(authConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	path := *tokenFile
+	if path == "" {
+		path = filepath.Join(dataDir, "auth-token")
+	}
+
+	// The local token is rotated on every start while other clients are
+	// restored, so replace any stale file rather than trusting its
+	// permissions
+	_ = os.Remove(path)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write token file %s: %w", path, err)
+	}
+
+	log.Printf("Local admin token written to %s", path)
+	return auth, nil
+}
+
+func syncEvents(ctx context.Context, walletService *wallet.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			updated, err := walletService.SyncEvents(ctx)
+			if err != nil {
+				log.Printf("Event sync failed: %v", err)
+				continue
+			}
+			for _, event := range updated {
+				log.Printf("Event %s is now %s", event.ID, event.Status)
+			}
+		}
+	}
+}
+
+func publishStatusLists(ctx context.Context, walletService *wallet.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			announcements, err := walletService.PublishStatusLists(ctx)
+			if err != nil {
+				log.Printf("Status list publication failed: %v", err)
+			}
+			for _, announcement := range announcements {
+				log.Printf("Published status lists of %s as %s", announcement.Issuer, announcement.BitmapCID)
+			}
+		}
+	}
 }
 
 func getDefaultDataDir() (string, error) {
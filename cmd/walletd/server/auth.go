@@ -0,0 +1,549 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is a permission granted to a walletd client
+type Scope string
+
+const (
+	// ScopeRead allows reading keys, DIDs, credentials, events and status
+	ScopeRead Scope = "read"
+	// ScopeWrite allows storing and deleting credentials
+	ScopeWrite Scope = "write"
+	// ScopeSign allows operations that sign with wallet keys
+	ScopeSign Scope = "sign"
+	// ScopeAdmin allows key management, backup, recovery, locking and client
+	// management; it implies every other scope
+	ScopeAdmin Scope = "admin"
+)
+
+const (
+	clientTokenPrefix  = "cwc_"
+	sessionTokenPrefix = "cws_"
+	localClientName    = "local"
+)
+
+var (
+	// ErrUnauthenticated is returned when no valid token was presented
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrInsufficientScope is returned when a token lacks a required scope
+	ErrInsufficientScope = errors.New("insufficient scope")
+	// ErrClientNotFound is returned when revoking an unknown client
+	ErrClientNotFound = errors.New("client not found")
+	// ErrInvalidScope is returned for unknown or ungranted scopes
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// AuthConfig configures the walletd authenticator
+type AuthConfig struct {
+	// SessionTTL is how long a session token stays valid
+	SessionTTL time.Duration
+
+	// StatePath is the file registered clients and live sessions are kept
+	// in across restarts. Only token hashes are written. When empty they are
+	// kept in memory.
+	StatePath string
+}
+
+// DefaultAuthConfig returns the default authenticator configuration
+func DefaultAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		SessionTTL: 15 * time.Minute,
+	}
+}
+
+// Client is a registered API client. Its token is only returned when the
+// client is registered; walletd keeps the SHA-256 of it.
+type Client struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Scopes  []Scope   `json:"scopes"`
+	Created time.Time `json:"created"`
+
+	tokenHash [32]byte
+}
+
+// Session is a short-lived token derived from a client token
+type Session struct {
+	ID       string    `json:"id"`
+	ClientID string    `json:"clientId"`
+	Scopes   []Scope   `json:"scopes"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	ClientID  string  `json:"clientId"`
+	SessionID string  `json:"sessionId,omitempty"`
+	Scopes    []Scope `json:"scopes"`
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope Scope) bool {
+	return hasScope(p.Scopes, scope)
+}
+
+// Authenticator issues and checks walletd client and session tokens
+type Authenticator struct {
+	config *AuthConfig
+
+	mu       sync.RWMutex
+	clients  map[string]*Client
+	tokens   map[[32]byte]string
+	sessions map[[32]byte]*Session
+
+	now func() time.Time
+}
+
+// NewAuthenticator creates an authenticator with a local admin client and
+// returns that client's token. walletd writes the token to its data
+// directory so that only users who can read the directory can call the API.
+// Clients and sessions saved under config.StatePath are restored; the local
+// client keeps its ID but gets a fresh token on every start.
+func NewAuthenticator(config *AuthConfig) (*Authenticator, string, error) {
+	if config == nil {
+		config = DefaultAuthConfig()
+	}
+
+	a := &Authenticator{
+		config:   config,
+		clients:  make(map[string]*Client),
+		tokens:   make(map[[32]byte]string),
+		sessions: make(map[[32]byte]*Session),
+		now:      time.Now,
+	}
+
+	if err := a.load(); err != nil {
+		return nil, "", err
+	}
+
+	for _, client := range a.clients {
+		if client.Name == localClientName && hasScope(client.Scopes, ScopeAdmin) {
+			token, err := a.rotateToken(client)
+			if err != nil {
+				return nil, "", err
+			}
+			return a, token, nil
+		}
+	}
+
+	_, token, err := a.RegisterClient(localClientName, []Scope{ScopeAdmin})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return a, token, nil
+}
+
+// rotateToken replaces a client's token, dropping its sessions
+func (a *Authenticator) rotateToken(client *Client) (string, error) {
+	token, err := newToken(clientTokenPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.tokens, client.tokenHash)
+	a.dropSessions(client.ID)
+	client.tokenHash = sha256.Sum256([]byte(token))
+	a.tokens[client.tokenHash] = client.ID
+
+	if err := a.save(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RegisterClient registers a client with the given scopes and returns its token
+func (a *Authenticator) RegisterClient(name string, scopes []Scope) (*Client, string, error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("client name is required")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := newToken(clientTokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &Client{
+		ID:        id,
+		Name:      name,
+		Scopes:    scopes,
+		Created:   a.now(),
+		tokenHash: sha256.Sum256([]byte(token)),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clients[client.ID] = client
+	a.tokens[client.tokenHash] = client.ID
+	if err := a.save(); err != nil {
+		delete(a.clients, client.ID)
+		delete(a.tokens, client.tokenHash)
+		return nil, "", err
+	}
+
+	return client, token, nil
+}
+
+// ListClients returns all registered clients ordered by creation time
+func (a *Authenticator) ListClients() []*Client {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(a.clients))
+	for _, client := range a.clients {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Created.Before(clients[j].Created)
+	})
+	return clients
+}
+
+// RevokeClient removes a client together with all of its sessions
+func (a *Authenticator) RevokeClient(clientID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	client, ok := a.clients[clientID]
+	if !ok {
+		return ErrClientNotFound
+	}
+
+	delete(a.clients, clientID)
+	delete(a.tokens, client.tokenHash)
+	a.dropSessions(clientID)
+	return a.save()
+}
+
+// dropSessions removes all sessions of a client; callers must hold a.mu
+func (a *Authenticator) dropSessions(clientID string) {
+	for hash, session := range a.sessions {
+		if session.ClientID == clientID {
+			delete(a.sessions, hash)
+		}
+	}
+}
+
+// CreateSession exchanges a client token for a session token. The session
+// gets the requested scopes, which must be a subset of the client's, or all
+// of the client's scopes when none are requested.
+func (a *Authenticator) CreateSession(clientToken string, scopes []Scope) (*Session, string, error) {
+	a.mu.RLock()
+	clientID, ok := a.tokens[sha256.Sum256([]byte(clientToken))]
+	var client *Client
+	if ok {
+		client = a.clients[clientID]
+	}
+	a.mu.RUnlock()
+
+	if client == nil {
+		return nil, "", ErrUnauthenticated
+	}
+
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, scope := range scopes {
+		if !hasScope(client.Scopes, scope) {
+			return nil, "", fmt.Errorf("%w: client was not granted %s", ErrInvalidScope, scope)
+		}
+	}
+
+	token, err := newToken(sessionTokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := &Session{
+		ID:       id,
+		ClientID: client.ID,
+		Scopes:   scopes,
+		Expires:  a.now().Add(a.config.SessionTTL),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// The client may have been revoked meanwhile
+	if _, ok := a.clients[client.ID]; !ok {
+		return nil, "", ErrUnauthenticated
+	}
+	a.pruneSessions()
+	hash := sha256.Sum256([]byte(token))
+	a.sessions[hash] = session
+	if err := a.save(); err != nil {
+		delete(a.sessions, hash)
+		return nil, "", err
+	}
+
+	return session, token, nil
+}
+
+// RevokeSession invalidates a session token
+func (a *Authenticator) RevokeSession(sessionToken string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.sessions, sha256.Sum256([]byte(sessionToken)))
+	return a.save()
+}
+
+// Authenticate resolves a client or session token to a principal
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+	hash := sha256.Sum256([]byte(token))
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if strings.HasPrefix(token, sessionTokenPrefix) {
+		session, ok := a.sessions[hash]
+		if !ok || !a.now().Before(session.Expires) {
+			return nil, ErrUnauthenticated
+		}
+		return &Principal{ClientID: session.ClientID, SessionID: session.ID, Scopes: session.Scopes}, nil
+	}
+
+	clientID, ok := a.tokens[hash]
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	client := a.clients[clientID]
+	return &Principal{ClientID: client.ID, Scopes: client.Scopes}, nil
+}
+
+// pruneSessions drops expired sessions; callers must hold a.mu
+func (a *Authenticator) pruneSessions() {
+	now := a.now()
+	for hash, session := range a.sessions {
+		if !now.Before(session.Expires) {
+			delete(a.sessions, hash)
+		}
+	}
+}
+
+// authState is the persisted form of the authenticator
+type authState struct {
+	Clients  []storedClient  `json:"clients"`
+	Sessions []storedSession `json:"sessions"`
+}
+
+type storedClient struct {
+	Client
+	TokenHash string `json:"tokenHash"`
+}
+
+type storedSession struct {
+	Session
+	TokenHash string `json:"tokenHash"`
+}
+
+// load restores clients and unexpired sessions from the state file
+func (a *Authenticator) load() error {
+	if a.config.StatePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.config.StatePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read auth state: %w", err)
+	}
+
+	var state authState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode auth state: %w", err)
+	}
+
+	for i := range state.Clients {
+		hash, err := decodeTokenHash(state.Clients[i].TokenHash)
+		if err != nil {
+			return fmt.Errorf("invalid token hash for client %s: %w", state.Clients[i].ID, err)
+		}
+		client := state.Clients[i].Client
+		client.tokenHash = hash
+		a.clients[client.ID] = &client
+		a.tokens[hash] = client.ID
+	}
+
+	now := a.now()
+	for i := range state.Sessions {
+		session := state.Sessions[i].Session
+		if _, ok := a.clients[session.ClientID]; !ok || !now.Before(session.Expires) {
+			continue
+		}
+		hash, err := decodeTokenHash(state.Sessions[i].TokenHash)
+		if err != nil {
+			return fmt.Errorf("invalid token hash for session %s: %w", session.ID, err)
+		}
+		a.sessions[hash] = &session
+	}
+	return nil
+}
+
+// save writes clients and sessions to the state file; callers must hold a.mu
+func (a *Authenticator) save() error {
+	if a.config.StatePath == "" {
+		return nil
+	}
+
+	state := authState{
+		Clients:  make([]storedClient, 0, len(a.clients)),
+		Sessions: make([]storedSession, 0, len(a.sessions)),
+	}
+	for _, client := range a.clients {
+		state.Clients = append(state.Clients, storedClient{
+			Client:    *client,
+			TokenHash: hex.EncodeToString(client.tokenHash[:]),
+		})
+	}
+	for hash, session := range a.sessions {
+		state.Sessions = append(state.Sessions, storedSession{
+			Session:   *session,
+			TokenHash: hex.EncodeToString(hash[:]),
+		})
+	}
+	sort.Slice(state.Clients, func(i, j int) bool { return state.Clients[i].ID < state.Clients[j].ID })
+	sort.Slice(state.Sessions, func(i, j int) bool { return state.Sessions[i].ID < state.Sessions[j].ID })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode auth state: %w", err)
+	}
+
+	// Write a private temporary file and rename it so a crash never leaves
+	// a truncated state behind
+	tmp, err := os.CreateTemp(filepath.Dir(a.config.StatePath), ".auth-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write auth state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write auth state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write auth state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), a.config.StatePath); err != nil {
+		return fmt.Errorf("failed to write auth state: %w", err)
+	}
+	return nil
+}
+
+func decodeTokenHash(encoded string) ([32]byte, error) {
+	var hash [32]byte
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil {
+		return hash, err
+	}
+	if len(decoded) != len(hash) {
+		return hash, fmt.Errorf("expected %d bytes, got %d", len(hash), len(decoded))
+	}
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the principal attached by the auth middleware
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+func newToken(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func normalizeScopes(scopes []Scope) ([]Scope, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	seen := make(map[Scope]bool, len(scopes))
+	normalized := make([]Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeRead, ScopeWrite, ScopeSign, ScopeAdmin:
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+func hasScope(granted []Scope, scope Scope) bool {
+	for _, g := range granted {
+		if g == scope || g == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_Sessions(t *testing.T) {
+	auth, adminToken, err := NewAuthenticator(&AuthConfig{SessionTTL: time.Minute})
+	require.NoError(t, err)
+
+	now := time.Now()
+	auth.now = func() time.Time { return now }
+
+	client, clientToken, err := auth.RegisterClient("dapp", []Scope{ScopeRead, ScopeSign})
+	require.NoError(t, err)
+
+	_, _, err = auth.CreateSession(clientToken, []Scope{ScopeAdmin})
+	require.ErrorIs(t, err, ErrInvalidScope, "sessions cannot widen client scopes")
+
+	session, sessionToken, err := auth.CreateSession(clientToken, []Scope{ScopeRead})
+	require.NoError(t, err)
+	assert.Equal(t, client.ID, session.ClientID)
+
+	principal, err := auth.Authenticate(sessionToken)
+	require.NoError(t, err)
+	assert.True(t, principal.HasScope(ScopeRead))
+	assert.False(t, principal.HasScope(ScopeSign))
+
+	// Sessions expire after the TTL
+	now = now.Add(2 * time.Minute)
+	_, err = auth.Authenticate(sessionToken)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	// Revoking a client drops its token and sessions
+	_, sessionToken, err = auth.CreateSession(clientToken, nil)
+	require.NoError(t, err)
+	require.NoError(t, auth.RevokeClient(client.ID))
+	_, err = auth.Authenticate(clientToken)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = auth.Authenticate(sessionToken)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	admin, err := auth.Authenticate(adminToken)
+	require.NoError(t, err)
+	assert.True(t, admin.HasScope(ScopeSign), "admin implies every scope")
+}
+
+func TestAuthenticator_Persistence(t *testing.T) {
+	config := &AuthConfig{SessionTTL: time.Minute, StatePath: filepath.Join(t.TempDir(), "auth-state.json")}
+
+	auth, adminToken, err := NewAuthenticator(config)
+	require.NoError(t, err)
+	local, err := auth.Authenticate(adminToken)
+	require.NoError(t, err)
+	client, clientToken, err := auth.RegisterClient("dapp", []Scope{ScopeRead})
+	require.NoError(t, err)
+	_, sessionToken, err := auth.CreateSession(clientToken, nil)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(config.StatePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), clientToken, "only token hashes are stored")
+
+	// Clients and sessions survive a restart; the local token is rotated
+	restarted, newAdminToken, err := NewAuthenticator(config)
+	require.NoError(t, err)
+	principal, err := restarted.Authenticate(clientToken)
+	require.NoError(t, err)
+	assert.Equal(t, client.ID, principal.ClientID)
+	_, err = restarted.Authenticate(sessionToken)
+	require.NoError(t, err)
+
+	_, err = restarted.Authenticate(adminToken)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	admin, err := restarted.Authenticate(newAdminToken)
+	require.NoError(t, err)
+	assert.Equal(t, local.ClientID, admin.ClientID)
+	assert.Len(t, restarted.ListClients(), 2)
+
+	// Revocations are persisted too
+	require.NoError(t, restarted.RevokeClient(client.ID))
+	restarted, _, err = NewAuthenticator(config)
+	require.NoError(t, err)
+	_, err = restarted.Authenticate(clientToken)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestServer_RequiresScopes(t *testing.T) {
+	srv, adminToken := setupAuthTestServer(t)
+
+	rec := doAuthRequest(t, srv, "GET", "/v1/health", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "health check is public")
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/wallet/status", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/wallet/status", "cwc_bogus", nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Register a read-only client through the API
+	rec = doAuthRequest(t, srv, "POST", "/v1/auth/clients", adminToken, RegisterClientRequest{
+		Name:   "viewer",
+		Scopes: []Scope{ScopeRead},
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var registered struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &registered))
+	readToken := registered.Data.Token
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/wallet/status", readToken, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doAuthRequest(t, srv, "POST", "/v1/keys/generate", readToken, GenerateKeyRequest{KeyType: "Ed25519"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Keys are listed without their private halves
+	rec = doAuthRequest(t, srv, "POST", "/v1/keys/generate", adminToken, GenerateKeyRequest{KeyType: "Ed25519"})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "privateKeyJwk")
+	rec = doAuthRequest(t, srv, "GET", "/v1/keys", readToken, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "publicKeyJwk")
+	assert.NotContains(t, rec.Body.String(), "privateKeyJwk")
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/auth/clients", readToken, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Exchange the client token for a session and log out again
+	rec = doAuthRequest(t, srv, "POST", "/v1/auth/sessions", readToken, nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &registered))
+	sessionToken := registered.Data.Token
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/auth/whoami", sessionToken, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doAuthRequest(t, srv, "DELETE", "/v1/auth/sessions", sessionToken, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doAuthRequest(t, srv, "GET", "/v1/auth/whoami", sessionToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestServer_UnlockRateLimit(t *testing.T) {
+	srv, adminToken := setupAuthTestServer(t)
+
+	// A fresh service starts unlocked; locking it sets the password
+	rec := doAuthRequest(t, srv, "POST", "/v1/wallet/lock", adminToken, LockWalletRequest{Password: "secret"})
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	maxAttempts := wallet.DefaultWalletConfig().MaxUnlockAttempts
+	for i := 0; i < maxAttempts; i++ {
+		rec = doAuthRequest(t, srv, "POST", "/v1/wallet/unlock", adminToken, UnlockWalletRequest{Password: "wrong"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Even the right password is refused while throttled
+	rec = doAuthRequest(t, srv, "POST", "/v1/wallet/unlock", adminToken, UnlockWalletRequest{Password: "secret"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func setupAuthTestServer(t *testing.T) (*Server, string) {
+	walletService, err := wallet.NewService(&wallet.Config{DataDir: t.TempDir()})
+	require.NoError(t, err)
+
+	auth, adminToken, err := NewAuthenticator(nil)
+	require.NoError(t, err)
+
+	return NewServer(walletService, auth), adminToken
+}
+
+func doAuthRequest(t *testing.T, srv *Server, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rec, req)
+	return rec
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/ParichayaHQ/credence/internal/wallet"
@@ -22,6 +25,32 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // Key Management Handlers
 
+// publicKeyView strips private key material from keys before they are
+// returned. Private keys only leave walletd through an encrypted backup.
+func publicKeyView(keys interface{}) interface{} {
+	strip := func(key *wallet.KeyPair) *wallet.KeyPair {
+		if key == nil {
+			return nil
+		}
+		public := *key
+		public.PrivateKeyJWK = nil
+		return &public
+	}
+
+	switch keys := keys.(type) {
+	case *wallet.KeyPair:
+		return strip(keys)
+	case []*wallet.KeyPair:
+		public := make([]*wallet.KeyPair, len(keys))
+		for i, key := range keys {
+			public[i] = strip(key)
+		}
+		return public
+	default:
+		return keys
+	}
+}
+
 type GenerateKeyRequest struct {
 	KeyType string `json:"keyType"`
 }
@@ -44,7 +73,7 @@ func (s *Server) handleGenerateKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.writeResponse(w, http.StatusCreated, key, nil)
+	s.writeResponse(w, http.StatusCreated, publicKeyView(key), nil)
 }
 
 func (s *Server) handleListKeys(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +83,7 @@ func (s *Server) handleListKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, keys, nil)
+	s.writeResponse(w, http.StatusOK, publicKeyView(keys), nil)
 }
 
 func (s *Server) handleGetKey(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +105,7 @@ func (s *Server) handleGetKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.writeResponse(w, http.StatusOK, key, nil)
+	s.writeResponse(w, http.StatusOK, publicKeyView(key), nil)
 }
 
 func (s *Server) handleDeleteKey(w http.ResponseWriter, r *http.Request) {
@@ -101,6 +130,29 @@ func (s *Server) handleDeleteKey(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(w, http.StatusOK, map[string]string{"message": "Key deleted successfully"}, nil)
 }
 
+type DeriveKeyRequest struct {
+	KeyType string `json:"keyType,omitempty"`
+	Account uint32 `json:"account"`
+	Purpose string `json:"purpose,omitempty"`
+	Index   uint32 `json:"index"`
+}
+
+func (s *Server) handleDeriveKey(w http.ResponseWriter, r *http.Request) {
+	var req DeriveKeyRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	key, err := s.walletService.DeriveKey(req.KeyType, req.Account, req.Purpose, req.Index)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, publicKeyView(key), nil)
+}
+
 // DID Management Handlers
 
 type CreateDIDRequest struct {
@@ -281,6 +333,90 @@ func (s *Server) handleDeleteCredential(w http.ResponseWriter, r *http.Request)
 	s.writeResponse(w, http.StatusOK, map[string]string{"message": "Credential deleted successfully"}, nil)
 }
 
+// Credential Issuance Handlers
+
+type CredentialStatusRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// issuerErrorStatus maps issuer service errors to HTTP status codes
+func issuerErrorStatus(err error) int {
+	var walletErr *wallet.WalletError
+	if errors.As(err, &walletErr) {
+		switch walletErr.Code {
+		case wallet.ErrorCredentialNotFound:
+			return http.StatusNotFound
+		case wallet.ErrorInvalidCredential, wallet.ErrorKeyNotFound, wallet.ErrorDIDNotFound:
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) handleIssueCredential(w http.ResponseWriter, r *http.Request) {
+	var req wallet.IssuanceRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	credential, err := s.walletService.IssueCredential(r.Context(), &req)
+	if err != nil {
+		s.writeError(w, issuerErrorStatus(err), err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, credential, nil)
+}
+
+// handleCredentialStatus parses a status change request and applies it to
+// the credential named in the path
+func (s *Server) handleCredentialStatus(w http.ResponseWriter, r *http.Request, apply func(credentialID, reason string) error) {
+	credentialID := mux.Vars(r)["credentialId"]
+
+	var req CredentialStatusRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := apply(credentialID, req.Reason); err != nil {
+		s.writeError(w, issuerErrorStatus(err), err)
+		return
+	}
+
+	// Without a full node the change never reaches verifiers
+	if !s.walletService.PublishesStatusLists() {
+		s.writeResponse(w, http.StatusOK, map[string]interface{}{
+			"message":   "Credential status updated in the wallet only; no full node is configured to publish it",
+			"published": false,
+		}, nil)
+		return
+	}
+	s.writeResponse(w, http.StatusOK, map[string]interface{}{
+		"message":   "Credential status updated",
+		"published": true,
+	}, nil)
+}
+
+func (s *Server) handleRevokeCredential(w http.ResponseWriter, r *http.Request) {
+	s.handleCredentialStatus(w, r, func(credentialID, reason string) error {
+		return s.walletService.RevokeCredential(r.Context(), credentialID, reason)
+	})
+}
+
+func (s *Server) handlePublishStatusLists(w http.ResponseWriter, r *http.Request) {
+	announcements, err := s.walletService.PublishStatusLists(r.Context())
+	if err != nil {
+		s.writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, announcements, nil)
+}
+
 // Event Management Handlers
 
 type CreateEventRequest struct {
@@ -324,8 +460,13 @@ func (s *Server) handleListEvents(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		event, err := s.walletService.CreateEvent(req.Type, req.From, req.To, req.Context, req.PayloadCID)
+		event, err := s.walletService.CreateEvent(r.Context(), req.Type, req.From, req.To, req.Context, req.PayloadCID)
 		if err != nil {
+			var walletErr *wallet.WalletError
+			if errors.As(err, &walletErr) && walletErr.Code == wallet.ErrorBudgetExceeded {
+				s.writeError(w, http.StatusTooManyRequests, err)
+				return
+			}
 			s.writeError(w, http.StatusInternalServerError, err)
 			return
 		}
@@ -356,6 +497,33 @@ func (s *Server) handleGetEvent(w http.ResponseWriter, r *http.Request) {
 	s.writeResponse(w, http.StatusOK, event, nil)
 }
 
+func (s *Server) handleSyncEvents(w http.ResponseWriter, r *http.Request) {
+	updated, err := s.walletService.SyncEvents(r.Context())
+	if err != nil {
+		s.writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, updated, nil)
+}
+
+func (s *Server) handleGetVouchBudget(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	did, context := query.Get("did"), query.Get("context")
+	if did == "" || context == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("did and context are required"))
+		return
+	}
+
+	budget, err := s.walletService.GetVouchBudget(r.Context(), did, context)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, budget, nil)
+}
+
 // Trust Score Handlers
 
 func (s *Server) handleListTrustScores(w http.ResponseWriter, r *http.Request) {
@@ -435,6 +603,23 @@ func (s *Server) handleUnlockWallet(w http.ResponseWriter, r *http.Request) {
 
 	err := s.walletService.Unlock(req.Password)
 	if err != nil {
+		var walletErr *wallet.WalletError
+		if errors.As(err, &walletErr) {
+			switch walletErr.Code {
+			case wallet.ErrorTooManyAttempts:
+				if until := s.walletService.UnlockBlockedUntil(); !until.IsZero() {
+					w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+				}
+				s.writeError(w, http.StatusTooManyRequests, err)
+				return
+			case wallet.ErrorInvalidPassword:
+				s.writeError(w, http.StatusUnauthorized, err)
+				return
+			case wallet.ErrorWalletUnlocked:
+				s.writeError(w, http.StatusConflict, err)
+				return
+			}
+		}
 		s.writeError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -499,4 +684,354 @@ func (s *Server) handleCreatePresentationSubmission(w http.ResponseWriter, r *ht
 	}
 
 	s.writeResponse(w, http.StatusCreated, submission, nil)
-}
\ No newline at end of file
+}
+// Social Recovery Handlers
+
+type SetupRecoveryRequest struct {
+	DID       string   `json:"did"`
+	Guardians []string `json:"guardians"`
+	Threshold int      `json:"threshold"`
+}
+
+func (s *Server) handleSetupRecovery(w http.ResponseWriter, r *http.Request) {
+	var req SetupRecoveryRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.DID == "" || len(req.Guardians) == 0 || req.Threshold == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("did, guardians and threshold are required"))
+		return
+	}
+
+	result, err := s.walletService.SetupRecovery(req.DID, req.Guardians, req.Threshold)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, result, nil)
+}
+
+func (s *Server) handleAcceptGuardianShare(w http.ResponseWriter, r *http.Request) {
+	var share wallet.GuardianShare
+	if err := s.parseJSON(r, &share); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.walletService.AcceptGuardianShare(&share); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, map[string]string{"message": "Guardian share stored successfully"}, nil)
+}
+
+func (s *Server) handleImportRecoveryConfig(w http.ResponseWriter, r *http.Request) {
+	var config wallet.RecoveryConfig
+	if err := s.parseJSON(r, &config); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.walletService.ImportRecoveryConfig(&config); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, map[string]string{"message": "Recovery configuration stored successfully"}, nil)
+}
+
+type RequestRecoveryRequest struct {
+	OldDID string `json:"oldDid"`
+	NewDID string `json:"newDid"`
+}
+
+func (s *Server) handleRequestRecovery(w http.ResponseWriter, r *http.Request) {
+	var req RequestRecoveryRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.OldDID == "" || req.NewDID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("oldDid and newDid are required"))
+		return
+	}
+
+	request, err := s.walletService.RequestRecovery(req.OldDID, req.NewDID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, request, nil)
+}
+
+func (s *Server) handleGetRecoveryRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestID := vars["requestId"]
+
+	request, err := s.walletService.GetRecoveryRequest(requestID)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, request, nil)
+}
+
+type ApproveRecoveryRequest struct {
+	GuardianDID string                  `json:"guardianDid"`
+	Request     *wallet.RecoveryRequest `json:"request"`
+}
+
+func (s *Server) handleApproveRecovery(w http.ResponseWriter, r *http.Request) {
+	var req ApproveRecoveryRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.GuardianDID == "" || req.Request == nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("guardianDid and request are required"))
+		return
+	}
+
+	approval, err := s.walletService.ApproveRecovery(req.GuardianDID, req.Request)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, approval, nil)
+}
+
+func (s *Server) handleAddRecoveryApproval(w http.ResponseWriter, r *http.Request) {
+	var approval wallet.GuardianApproval
+	if err := s.parseJSON(r, &approval); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	request, err := s.walletService.AddRecoveryApproval(&approval)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, request, nil)
+}
+
+func (s *Server) handleCompleteRecovery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestID := vars["requestId"]
+
+	statement, err := s.walletService.CompleteRecovery(requestID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, statement, nil)
+}
+
+func (s *Server) handleListKeyMigrations(w http.ResponseWriter, r *http.Request) {
+	migrations, err := s.walletService.ListKeyMigrations()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, migrations, nil)
+}
+
+// Mnemonic Backup Handlers
+
+type CreateMnemonicRequest struct {
+	Strength   int    `json:"strength,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type RestoreMnemonicRequest struct {
+	Mnemonic   string `json:"mnemonic"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Accounts   int    `json:"accounts,omitempty"`
+	// Keys are the records from /mnemonic/keys; with them every derived key
+	// and DID is restored, not just the first accounts
+	Keys []*wallet.HDKeyRecord `json:"keys,omitempty"`
+}
+
+type VerifyMnemonicRequest struct {
+	Mnemonic   string `json:"mnemonic"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+func (s *Server) handleCreateMnemonic(w http.ResponseWriter, r *http.Request) {
+	var req CreateMnemonicRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := s.walletService.CreateMnemonic(req.Strength, req.Passphrase)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, result, nil)
+}
+
+func (s *Server) handleRestoreMnemonic(w http.ResponseWriter, r *http.Request) {
+	var req RestoreMnemonicRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Mnemonic == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("mnemonic is required"))
+		return
+	}
+	if req.Accounts > wallet.MaxRestoreAccounts {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("accounts cannot exceed %d", wallet.MaxRestoreAccounts))
+		return
+	}
+
+	dids, err := s.walletService.RestoreFromMnemonic(req.Mnemonic, req.Passphrase, req.Accounts, req.Keys)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, dids, nil)
+}
+
+func (s *Server) handleListHDKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.walletService.ListHDKeys()
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, keys, nil)
+}
+
+func (s *Server) handleVerifyMnemonic(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMnemonicRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Mnemonic == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("mnemonic is required"))
+		return
+	}
+
+	result, err := s.walletService.VerifyMnemonic(req.Mnemonic, req.Passphrase)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, result, nil)
+}
+
+// Authentication Handlers
+
+type CreateSessionRequest struct {
+	Scopes []Scope `json:"scopes,omitempty"`
+}
+
+type RegisterClientRequest struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	session, token, err := s.auth.CreateSession(bearerToken(r), req.Scopes)
+	if err != nil {
+		if errors.Is(err, ErrUnauthenticated) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="walletd"`)
+			s.writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, map[string]interface{}{
+		"session": session,
+		"token":   token,
+	}, nil)
+}
+
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	principal, _ := PrincipalFromContext(r.Context())
+	if principal.SessionID == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("request was not made with a session token"))
+		return
+	}
+
+	if err := s.auth.RevokeSession(bearerToken(r)); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeResponse(w, http.StatusOK, map[string]string{"message": "Session revoked successfully"}, nil)
+}
+
+func (s *Server) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	principal, _ := PrincipalFromContext(r.Context())
+	s.writeResponse(w, http.StatusOK, principal, nil)
+}
+
+func (s *Server) handleListClients(w http.ResponseWriter, r *http.Request) {
+	s.writeResponse(w, http.StatusOK, s.auth.ListClients(), nil)
+}
+
+func (s *Server) handleRegisterClient(w http.ResponseWriter, r *http.Request) {
+	var req RegisterClientRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	client, token, err := s.auth.RegisterClient(req.Name, req.Scopes)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusCreated, map[string]interface{}{
+		"client": client,
+		"token":  token,
+	}, nil)
+}
+
+func (s *Server) handleRevokeClient(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID := vars["clientId"]
+
+	if err := s.auth.RevokeClient(clientID); err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			s.writeError(w, http.StatusNotFound, err)
+			return
+		}
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeResponse(w, http.StatusOK, map[string]string{"message": "Client revoked successfully"}, nil)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,13 +16,22 @@ import (
 // Server represents the HTTP server for the wallet service
 type Server struct {
 	walletService *wallet.Service
+	auth          *Authenticator
 	router        *mux.Router
 }
 
-// NewServer creates a new HTTP server instance
-func NewServer(walletService *wallet.Service) *Server {
+// NewServer creates a new HTTP server instance. Every route except the
+// health check requires a token issued by auth; when auth is nil a fresh
+// authenticator is created and clients must be registered through
+// Authenticator().
+func NewServer(walletService *wallet.Service, auth *Authenticator) *Server {
+	if auth == nil {
+		auth, _, _ = NewAuthenticator(nil)
+	}
+
 	s := &Server{
 		walletService: walletService,
+		auth:          auth,
 		router:        mux.NewRouter(),
 	}
 	
@@ -36,6 +46,11 @@ func (s *Server) Router() http.Handler {
 	return s.router
 }
 
+// Authenticator returns the authenticator guarding the API
+func (s *Server) Authenticator() *Authenticator {
+	return s.auth
+}
+
 func (s *Server) setupRoutes() {
 	// API version prefix
 	api := s.router.PathPrefix("/v1").Subrouter()
@@ -43,46 +58,86 @@ func (s *Server) setupRoutes() {
 	// Health check
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
 
+	// Authentication: sessions are created with a client token, clients are
+	// managed by admins
+	authRouter := api.PathPrefix("/auth").Subrouter()
+	authRouter.HandleFunc("/sessions", s.handleCreateSession).Methods("POST")
+	authRouter.HandleFunc("/sessions", s.requireScope("", s.handleRevokeSession)).Methods("DELETE")
+	authRouter.HandleFunc("/whoami", s.requireScope("", s.handleWhoAmI)).Methods("GET")
+	authRouter.HandleFunc("/clients", s.requireScope(ScopeAdmin, s.handleListClients)).Methods("GET")
+	authRouter.HandleFunc("/clients", s.requireScope(ScopeAdmin, s.handleRegisterClient)).Methods("POST")
+	authRouter.HandleFunc("/clients/{clientId}", s.requireScope(ScopeAdmin, s.handleRevokeClient)).Methods("DELETE")
+
 	// Key management
 	keyRouter := api.PathPrefix("/keys").Subrouter()
-	keyRouter.HandleFunc("", s.handleListKeys).Methods("GET")
-	keyRouter.HandleFunc("/generate", s.handleGenerateKey).Methods("POST")
-	keyRouter.HandleFunc("/{keyId}", s.handleGetKey).Methods("GET")
-	keyRouter.HandleFunc("/{keyId}", s.handleDeleteKey).Methods("DELETE")
+	keyRouter.HandleFunc("", s.requireScope(ScopeRead, s.handleListKeys)).Methods("GET")
+	keyRouter.HandleFunc("/generate", s.requireScope(ScopeAdmin, s.handleGenerateKey)).Methods("POST")
+	keyRouter.HandleFunc("/derive", s.requireScope(ScopeAdmin, s.handleDeriveKey)).Methods("POST")
+	keyRouter.HandleFunc("/{keyId}", s.requireScope(ScopeRead, s.handleGetKey)).Methods("GET")
+	keyRouter.HandleFunc("/{keyId}", s.requireScope(ScopeAdmin, s.handleDeleteKey)).Methods("DELETE")
 
 	// DID management
 	didRouter := api.PathPrefix("/dids").Subrouter()
-	didRouter.HandleFunc("", s.handleListDIDs).Methods("GET")
-	didRouter.HandleFunc("/create", s.handleCreateDID).Methods("POST")
-	didRouter.HandleFunc("/resolve", s.handleResolveDID).Methods("POST")
-	didRouter.HandleFunc("/{did:.*}", s.handleGetDID).Methods("GET")
+	didRouter.HandleFunc("", s.requireScope(ScopeRead, s.handleListDIDs)).Methods("GET")
+	didRouter.HandleFunc("/create", s.requireScope(ScopeAdmin, s.handleCreateDID)).Methods("POST")
+	didRouter.HandleFunc("/resolve", s.requireScope(ScopeRead, s.handleResolveDID)).Methods("POST")
+	didRouter.HandleFunc("/{did:.*}", s.requireScope(ScopeRead, s.handleGetDID)).Methods("GET")
 
 	// Credential management
 	credRouter := api.PathPrefix("/credentials").Subrouter()
-	credRouter.HandleFunc("", s.handleListCredentials).Methods("GET", "POST")
-	credRouter.HandleFunc("/{credentialId}", s.handleGetCredential).Methods("GET")
-	credRouter.HandleFunc("/{credentialId}", s.handleDeleteCredential).Methods("DELETE")
+	credRouter.HandleFunc("", s.requireScope(ScopeRead, s.handleListCredentials)).Methods("GET")
+	credRouter.HandleFunc("", s.requireScope(ScopeWrite, s.handleListCredentials)).Methods("POST")
+	credRouter.HandleFunc("/{credentialId}", s.requireScope(ScopeRead, s.handleGetCredential)).Methods("GET")
+	credRouter.HandleFunc("/{credentialId}", s.requireScope(ScopeWrite, s.handleDeleteCredential)).Methods("DELETE")
+
+	// Credential issuance and status
+	issuerRouter := api.PathPrefix("/issuer").Subrouter()
+	issuerRouter.HandleFunc("/credentials", s.requireScope(ScopeSign, s.handleIssueCredential)).Methods("POST")
+	issuerRouter.HandleFunc("/credentials/{credentialId}/revoke", s.requireScope(ScopeSign, s.handleRevokeCredential)).Methods("POST")
+	issuerRouter.HandleFunc("/status-lists/publish", s.requireScope(ScopeSign, s.handlePublishStatusLists)).Methods("POST")
 
 	// Event management (vouches/reports)
 	eventRouter := api.PathPrefix("/events").Subrouter()
-	eventRouter.HandleFunc("", s.handleListEvents).Methods("GET", "POST")
-	eventRouter.HandleFunc("/{eventId}", s.handleGetEvent).Methods("GET")
+	eventRouter.HandleFunc("", s.requireScope(ScopeRead, s.handleListEvents)).Methods("GET")
+	eventRouter.HandleFunc("", s.requireScope(ScopeSign, s.handleListEvents)).Methods("POST")
+	eventRouter.HandleFunc("/sync", s.requireScope(ScopeSign, s.handleSyncEvents)).Methods("POST")
+	eventRouter.HandleFunc("/budget", s.requireScope(ScopeRead, s.handleGetVouchBudget)).Methods("GET")
+	eventRouter.HandleFunc("/{eventId}", s.requireScope(ScopeRead, s.handleGetEvent)).Methods("GET")
 
 	// Trust scores
 	scoreRouter := api.PathPrefix("/scores").Subrouter()
-	scoreRouter.HandleFunc("", s.handleListTrustScores).Methods("GET")
-	scoreRouter.HandleFunc("/{did:.*}", s.handleGetTrustScore).Methods("GET")
+	scoreRouter.HandleFunc("", s.requireScope(ScopeRead, s.handleListTrustScores)).Methods("GET")
+	scoreRouter.HandleFunc("/{did:.*}", s.requireScope(ScopeRead, s.handleGetTrustScore)).Methods("GET")
 
 	// Presentation definitions
 	presDefRouter := api.PathPrefix("/presentation-definitions").Subrouter()
-	presDefRouter.HandleFunc("/evaluate", s.handleEvaluatePresentationDefinition).Methods("POST")
-	presDefRouter.HandleFunc("/submissions", s.handleCreatePresentationSubmission).Methods("POST")
+	presDefRouter.HandleFunc("/evaluate", s.requireScope(ScopeRead, s.handleEvaluatePresentationDefinition)).Methods("POST")
+	presDefRouter.HandleFunc("/submissions", s.requireScope(ScopeSign, s.handleCreatePresentationSubmission)).Methods("POST")
 
 	// Wallet operations
 	walletRouter := api.PathPrefix("/wallet").Subrouter()
-	walletRouter.HandleFunc("/lock", s.handleLockWallet).Methods("POST")
-	walletRouter.HandleFunc("/unlock", s.handleUnlockWallet).Methods("POST")
-	walletRouter.HandleFunc("/status", s.handleWalletStatus).Methods("GET")
+	walletRouter.HandleFunc("/lock", s.requireScope(ScopeAdmin, s.handleLockWallet)).Methods("POST")
+	walletRouter.HandleFunc("/unlock", s.requireScope(ScopeAdmin, s.handleUnlockWallet)).Methods("POST")
+	walletRouter.HandleFunc("/status", s.requireScope(ScopeRead, s.handleWalletStatus)).Methods("GET")
+
+	// Mnemonic backup routes
+	mnemonicRouter := api.PathPrefix("/mnemonic").Subrouter()
+	mnemonicRouter.HandleFunc("/create", s.requireScope(ScopeAdmin, s.handleCreateMnemonic)).Methods("POST")
+	mnemonicRouter.HandleFunc("/restore", s.requireScope(ScopeAdmin, s.handleRestoreMnemonic)).Methods("POST")
+	mnemonicRouter.HandleFunc("/verify", s.requireScope(ScopeAdmin, s.handleVerifyMnemonic)).Methods("POST")
+	mnemonicRouter.HandleFunc("/keys", s.requireScope(ScopeAdmin, s.handleListHDKeys)).Methods("GET")
+
+	// Social recovery routes
+	recoveryRouter := api.PathPrefix("/recovery").Subrouter()
+	recoveryRouter.HandleFunc("/setup", s.requireScope(ScopeAdmin, s.handleSetupRecovery)).Methods("POST")
+	recoveryRouter.HandleFunc("/shares", s.requireScope(ScopeWrite, s.handleAcceptGuardianShare)).Methods("POST")
+	recoveryRouter.HandleFunc("/configs", s.requireScope(ScopeAdmin, s.handleImportRecoveryConfig)).Methods("POST")
+	recoveryRouter.HandleFunc("/requests", s.requireScope(ScopeAdmin, s.handleRequestRecovery)).Methods("POST")
+	recoveryRouter.HandleFunc("/requests/{requestId}", s.requireScope(ScopeRead, s.handleGetRecoveryRequest)).Methods("GET")
+	recoveryRouter.HandleFunc("/requests/{requestId}/complete", s.requireScope(ScopeAdmin, s.handleCompleteRecovery)).Methods("POST")
+	recoveryRouter.HandleFunc("/approve", s.requireScope(ScopeSign, s.handleApproveRecovery)).Methods("POST")
+	recoveryRouter.HandleFunc("/approvals", s.requireScope(ScopeAdmin, s.handleAddRecoveryApproval)).Methods("POST")
+	recoveryRouter.HandleFunc("/migrations", s.requireScope(ScopeRead, s.handleListKeyMigrations)).Methods("GET")
 }
 
 func (s *Server) setupMiddleware() {
@@ -122,6 +177,27 @@ func (s *Server) errorHandlingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requireScope authenticates the bearer token of a request and checks that it
+// grants scope; an empty scope accepts any authenticated caller
+func (s *Server) requireScope(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, err := s.auth.Authenticate(bearerToken(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="walletd"`)
+			s.writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		if scope != "" && !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="walletd", error="insufficient_scope", scope="%s"`, scope))
+			s.writeError(w, http.StatusForbidden, fmt.Errorf("%w: %s required", ErrInsufficientScope, scope))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal)))
+	}
+}
+
 func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
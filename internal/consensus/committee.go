@@ -0,0 +1,64 @@
+package consensus
+
+import "fmt"
+
+// CheckpointVerifier checks that a checkpoint was signed by the committee
+type CheckpointVerifier interface {
+	VerifyCheckpoint(checkpoint *Checkpoint) error
+}
+
+// CheckpointCommittee is the committee whose threshold signatures make a
+// checkpoint binding
+type CheckpointCommittee struct {
+	Members   map[string]*BLSPublicKey `json:"members"` // DID -> BLS public key
+	Threshold int                      `json:"threshold"`
+}
+
+// CommitteeCheckpointVerifier accepts checkpoints signed by at least a
+// threshold of distinct committee members
+type CommitteeCheckpointVerifier struct {
+	committee  *CheckpointCommittee
+	aggregator *DefaultBLSAggregator
+}
+
+// NewCommitteeCheckpointVerifier creates a verifier for a committee
+func NewCommitteeCheckpointVerifier(committee *CheckpointCommittee) (*CommitteeCheckpointVerifier, error) {
+	if committee == nil || committee.Threshold <= 0 || committee.Threshold > len(committee.Members) {
+		return nil, fmt.Errorf("committee threshold must be between 1 and the number of members")
+	}
+
+	aggregator := NewDefaultBLSAggregator()
+	for member, publicKey := range committee.Members {
+		if publicKey == nil {
+			return nil, fmt.Errorf("committee member %s has no public key", member)
+		}
+		aggregator.RegisterMember(member, publicKey)
+	}
+	return &CommitteeCheckpointVerifier{committee: committee, aggregator: aggregator}, nil
+}
+
+// VerifyCheckpoint implements CheckpointVerifier
+func (v *CommitteeCheckpointVerifier) VerifyCheckpoint(checkpoint *Checkpoint) error {
+	if checkpoint == nil || len(checkpoint.Root) == 0 {
+		return fmt.Errorf("checkpoint has no root")
+	}
+
+	signers := make(map[string]bool, len(checkpoint.Signers))
+	for _, signer := range checkpoint.Signers {
+		if _, exists := v.committee.Members[signer]; !exists {
+			return fmt.Errorf("%s is not a committee member", signer)
+		}
+		if signers[signer] {
+			return fmt.Errorf("%s signed more than once", signer)
+		}
+		signers[signer] = true
+	}
+	if len(signers) < v.committee.Threshold {
+		return fmt.Errorf("checkpoint has %d signers, need %d", len(signers), v.committee.Threshold)
+	}
+
+	if err := v.aggregator.VerifyAggregatedSignature(checkpoint, nil); err != nil {
+		return fmt.Errorf("invalid checkpoint signature: %w", err)
+	}
+	return nil
+}
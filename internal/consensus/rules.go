@@ -1,10 +1,13 @@
 package consensus
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -22,6 +25,7 @@ type RuleSet struct {
 	
 	// Budget and limits
 	VouchBudgets    map[string]int `json:"vouch_budgets"`     // context -> budget per epoch
+	VouchBudgetLambda float64      `json:"vouch_budget_lambda,omitempty"` // λ: budget growth with score
 	ScoreCaps       ScoreCaps      `json:"score_caps"`        // various scoring caps
 	DecayParameters DecayParams    `json:"decay_parameters"`  // time-based decay
 	
@@ -38,6 +42,8 @@ type RuleSet struct {
 	DisputeWindow    time.Duration `json:"dispute_window"`
 	SlashingPenalty  float64       `json:"slashing_penalty"`
 	ReputationDecay  float64       `json:"reputation_decay"`
+	VouchBondSlash   float64       `json:"vouch_bond_slash"`       // ε: share of a voucher's V forfeited per slashed bond
+	Adjudicators     []string      `json:"adjudicators,omitempty"` // did:key DIDs whose signed adjudication events are binding
 	
 	// Digital signature
 	Hash      []byte `json:"hash"`      // SHA256 of canonical JSON
@@ -45,6 +51,49 @@ type RuleSet struct {
 	SignerDID string `json:"signer_did"`
 }
 
+// VouchBudget returns the vouch budget b_i = b0 + λ·log(1+S) for a DID with
+// trust score S in context, where b0 is the context's base budget
+func (rs *RuleSet) VouchBudget(context string, score float64) float64 {
+	if score < 0 {
+		score = 0
+	}
+	return float64(rs.VouchBudgets[context]) + rs.VouchBudgetLambda*math.Log1p(score)
+}
+
+// ComputeRuleSetHash returns the SHA256 of the ruleset's JSON encoding with
+// the hash and signature fields cleared
+func ComputeRuleSetHash(ruleSet *RuleSet) ([]byte, error) {
+	canonical := *ruleSet
+	canonical.Hash = nil
+	canonical.Signature = nil
+	
+	jsonBytes, err := json.Marshal(canonical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ruleset: %w", err)
+	}
+	
+	hash := sha256.Sum256(jsonBytes)
+	return hash[:], nil
+}
+
+// VerifyRuleSet checks that a ruleset's hash matches its content and, when
+// signerKey is given, that the signature over the hash was made with it
+func VerifyRuleSet(ruleSet *RuleSet, signerKey ed25519.PublicKey) error {
+	hash, err := ComputeRuleSetHash(ruleSet)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, ruleSet.Hash) {
+		return fmt.Errorf("ruleset %s hash does not match its content", ruleSet.ID)
+	}
+	
+	if signerKey != nil && !ed25519.Verify(signerKey, ruleSet.Hash, ruleSet.Signature) {
+		return fmt.Errorf("ruleset %s signature verification failed", ruleSet.ID)
+	}
+	
+	return nil
+}
+
 // ScoringWeights defines weights for trust score calculation
 type ScoringWeights struct {
 	KYCWeight        float64 `json:"kyc_weight"`         // α - KYC credential weight
@@ -336,20 +385,11 @@ func (r *DefaultRulesRegistry) ListProposals(ctx context.Context, status Proposa
 
 // calculateRuleSetHash calculates SHA256 hash of a ruleset
 func (r *DefaultRulesRegistry) calculateRuleSetHash(ruleSet *RuleSet) []byte {
-	// Create a copy without hash and signature for canonical hashing
-	canonical := *ruleSet
-	canonical.Hash = nil
-	canonical.Signature = nil
-	
-	// Marshal to canonical JSON
-	jsonBytes, err := json.Marshal(canonical)
+	hash, err := ComputeRuleSetHash(ruleSet)
 	if err != nil {
 		return nil
 	}
-	
-	hasher := sha256.New()
-	hasher.Write(jsonBytes)
-	return hasher.Sum(nil)
+	return hash
 }
 
 // calculateProposalHash calculates SHA256 hash of a proposal
@@ -392,6 +432,7 @@ func DefaultRuleSet() *RuleSet {
 			"commerce": 3,  // 3 vouches per epoch in commerce context
 			"hiring":   2,  // 2 vouches per epoch in hiring context
 		},
+		VouchBudgetLambda: 1.2, // budget grows by 1.2·log(1+S)
 		
 		ScoreCaps: ScoreCaps{
 			MaxIndividualScore: 100.0,
@@ -417,5 +458,6 @@ func DefaultRuleSet() *RuleSet {
 		DisputeWindow:    24 * time.Hour, // 24 hours to dispute
 		SlashingPenalty:  0.1,            // 10% penalty
 		ReputationDecay:  0.05,           // 5% reputation decay
+		VouchBondSlash:   0.05,           // 5% of V per slashed bond
 	}
 }
\ No newline at end of file
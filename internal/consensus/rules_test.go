@@ -0,0 +1,40 @@
+package consensus
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleSet_VouchBudget(t *testing.T) {
+	ruleSet := DefaultRuleSet()
+
+	assert.Equal(t, 5.0, ruleSet.VouchBudget("general", 0))
+	assert.InDelta(t, 3+1.2*math.Log(11), ruleSet.VouchBudget("commerce", 10), 1e-9)
+	assert.Equal(t, 2.0, ruleSet.VouchBudget("hiring", -4), "negative scores do not shrink the base budget")
+	assert.Equal(t, 0.0, ruleSet.VouchBudget("unknown", 0))
+}
+
+func TestVerifyRuleSet(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ruleSet := DefaultRuleSet()
+	ruleSet.Hash, err = ComputeRuleSetHash(ruleSet)
+	require.NoError(t, err)
+	ruleSet.Signature = ed25519.Sign(privateKey, ruleSet.Hash)
+
+	require.NoError(t, VerifyRuleSet(ruleSet, publicKey))
+	require.NoError(t, VerifyRuleSet(ruleSet, nil), "the signature is only checked when a key is given")
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.Error(t, VerifyRuleSet(ruleSet, otherKey))
+
+	ruleSet.VouchBudgets["general"] = 50
+	require.Error(t, VerifyRuleSet(ruleSet, nil), "tampering changes the hash")
+}
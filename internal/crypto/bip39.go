@@ -0,0 +1,144 @@
+package crypto
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+)
+
+// BIP-39 mnemonic encoding. Entropy of 128-256 bits is extended with a
+// SHA-256 checksum of ENT/32 bits and split into 11-bit word indexes.
+
+const (
+	// MnemonicStrength128 produces a 12 word mnemonic
+	MnemonicStrength128 = 128
+	// MnemonicStrength256 produces a 24 word mnemonic
+	MnemonicStrength256 = 256
+
+	bip39SeedIterations = 2048
+	bip39SeedLength     = 64
+)
+
+var bip39WordIndex map[string]int
+
+func init() {
+	bip39WordIndex = make(map[string]int, len(bip39English))
+	for i, word := range bip39English {
+		bip39WordIndex[word] = i
+	}
+}
+
+// GenerateMnemonic creates a new random mnemonic with the given entropy strength in bits
+func GenerateMnemonic(strength int) (string, error) {
+	if err := validateEntropyBits(strength); err != nil {
+		return "", err
+	}
+
+	entropy := make([]byte, strength/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRandomnessGenerationFailed, err)
+	}
+
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic encodes entropy as a BIP-39 mnemonic
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	entropyBits := len(entropy) * 8
+	if err := validateEntropyBits(entropyBits); err != nil {
+		return "", err
+	}
+
+	checksumBits := entropyBits / 32
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+
+	wordCount := (entropyBits + checksumBits) / 11
+	words := make([]string, wordCount)
+	for i := 0; i < wordCount; i++ {
+		words[i] = bip39English[readBits(data, i*11, 11)]
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic and verifies its checksum
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words)%3 != 0 || len(words) < 12 || len(words) > 24 {
+		return nil, fmt.Errorf("%w: unexpected word count %d", ErrInvalidMnemonic, len(words))
+	}
+
+	totalBits := len(words) * 11
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+
+	data := make([]byte, (totalBits+7)/8)
+	for i, word := range words {
+		index, ok := bip39WordIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown word %q", ErrInvalidMnemonic, word)
+		}
+		writeBits(data, i*11, 11, index)
+	}
+
+	entropy := data[:entropyBits/8]
+	checksum := sha256.Sum256(entropy)
+	if readBits(data, entropyBits, checksumBits) != readBits(checksum[:], 0, checksumBits) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidMnemonic)
+	}
+
+	return append([]byte{}, entropy...), nil
+}
+
+// ValidateMnemonic reports whether a mnemonic uses known words and a valid checksum
+func ValidateMnemonic(mnemonic string) bool {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err == nil
+}
+
+// MnemonicToSeed derives the 64-byte BIP-39 seed from a mnemonic and optional
+// passphrase. Only the English wordlist is supported, so NFKD normalization of
+// the mnemonic is a no-op; passphrases are expected to be ASCII or already
+// normalized.
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	if !ValidateMnemonic(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+
+	normalized := strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	return pbkdf2.Key(sha512.New, normalized, []byte("mnemonic"+passphrase), bip39SeedIterations, bip39SeedLength)
+}
+
+func validateEntropyBits(bits int) error {
+	if bits < MnemonicStrength128 || bits > MnemonicStrength256 || bits%32 != 0 {
+		return fmt.Errorf("%w: %d bits", ErrInvalidEntropy, bits)
+	}
+	return nil
+}
+
+// readBits reads count bits (at most 16) starting at bit offset, MSB first
+func readBits(data []byte, offset, count int) int {
+	value := 0
+	for i := 0; i < count; i++ {
+		bit := offset + i
+		value <<= 1
+		if data[bit/8]&(0x80>>(bit%8)) != 0 {
+			value |= 1
+		}
+	}
+	return value
+}
+
+// writeBits writes the low count bits of value at bit offset, MSB first
+func writeBits(data []byte, offset, count, value int) {
+	for i := 0; i < count; i++ {
+		if value&(1<<(count-1-i)) != 0 {
+			bit := offset + i
+			data[bit/8] |= 0x80 >> (bit % 8)
+		}
+	}
+}
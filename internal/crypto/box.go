@@ -0,0 +1,177 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+// SealedBox is an anonymous public-key encryption envelope addressed to an
+// Ed25519 key. The recipient key is converted to its X25519 form, an
+// ephemeral X25519 key agreement derives an AES-256-GCM key via HKDF-SHA256.
+type SealedBox struct {
+	EphemeralPublicKey []byte `json:"epk"`
+	Nonce              []byte `json:"nonce"`
+	Ciphertext         []byte `json:"ciphertext"`
+}
+
+const sealedBoxInfo = "credence-sealed-box-v1"
+
+// curve25519P is the field prime 2^255 - 19
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// SealToEd25519 encrypts plaintext so that only the holder of the private key
+// matching recipient can open it
+func SealToEd25519(recipient ed25519.PublicKey, plaintext []byte) (*SealedBox, error) {
+	recipientX, err := Ed25519PublicKeyToX25519(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRandomnessGenerationFailed, err)
+	}
+
+	shared, err := ephemeral.ECDH(recipientX)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	aead, err := sealedBoxAEAD(shared, ephemeral.PublicKey().Bytes(), recipientX.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := GenerateSecureRandom(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SealedBox{
+		EphemeralPublicKey: ephemeral.PublicKey().Bytes(),
+		Nonce:              nonce,
+		Ciphertext:         aead.Seal(nil, nonce, plaintext, ephemeral.PublicKey().Bytes()),
+	}, nil
+}
+
+// OpenWithEd25519 decrypts a sealed box using the recipient's Ed25519 private key
+func OpenWithEd25519(recipient ed25519.PrivateKey, box *SealedBox) ([]byte, error) {
+	if box == nil {
+		return nil, ErrDecryptionFailed
+	}
+	if len(recipient) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	recipientX, err := Ed25519PrivateKeyToX25519(recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(box.EphemeralPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ephemeral key", ErrDecryptionFailed)
+	}
+
+	shared, err := recipientX.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+
+	aead, err := sealedBoxAEAD(shared, box.EphemeralPublicKey, recipientX.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(box.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce size", ErrDecryptionFailed)
+	}
+
+	plaintext, err := aead.Open(nil, box.Nonce, box.Ciphertext, box.EphemeralPublicKey)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
+
+// Ed25519PublicKeyToX25519 converts an Ed25519 public key to the birationally
+// equivalent X25519 public key: u = (1 + y) / (1 - y) mod p
+func Ed25519PublicKeyToX25519(publicKey ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	// The encoding is little-endian y with the sign of x in the top bit
+	yBytes := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		yBytes[i] = publicKey[31-i]
+	}
+	yBytes[0] &= 0x7f
+	y := new(big.Int).SetBytes(yBytes)
+
+	one := big.NewInt(1)
+	denominator := new(big.Int).Sub(one, y)
+	denominator.Mod(denominator, curve25519P)
+	if denominator.Sign() == 0 {
+		return nil, ErrInvalidPublicKey
+	}
+	denominator.ModInverse(denominator, curve25519P)
+
+	u := new(big.Int).Add(one, y)
+	u.Mul(u, denominator)
+	u.Mod(u, curve25519P)
+
+	uBytes := u.FillBytes(make([]byte, 32))
+	for i, j := 0, 31; i < j; i, j = i+1, j-1 {
+		uBytes[i], uBytes[j] = uBytes[j], uBytes[i]
+	}
+
+	key, err := ecdh.X25519().NewPublicKey(uBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	return key, nil
+}
+
+// Ed25519PrivateKeyToX25519 converts an Ed25519 private key to the matching
+// X25519 private key (the clamped first half of SHA-512(seed))
+func Ed25519PrivateKeyToX25519(privateKey ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	digest := sha512.Sum512(privateKey.Seed())
+	scalar := digest[:32]
+	scalar[0] &= 248
+	scalar[31] &= 127
+	scalar[31] |= 64
+
+	key, err := ecdh.X25519().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	return key, nil
+}
+
+func sealedBoxAEAD(shared, ephemeralPublic, recipientPublic []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeralPublic...), recipientPublic...)
+	key, err := hkdf.Key(sha256.New, shared, salt, sealedBoxInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("key derivation failed: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -38,4 +38,22 @@ var (
 
 	// ErrRandomnessGenerationFailed indicates random number generation failed
 	ErrRandomnessGenerationFailed = errors.New("randomness generation failed")
+
+	// ErrDuplicateShare indicates the same secret share index was supplied twice
+	ErrDuplicateShare = errors.New("duplicate secret share")
+
+	// ErrDecryptionFailed indicates a sealed box could not be opened
+	ErrDecryptionFailed = errors.New("decryption failed")
+
+	// ErrInvalidMnemonic indicates a mnemonic has unknown words or a bad checksum
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrInvalidEntropy indicates mnemonic entropy has an unsupported size
+	ErrInvalidEntropy = errors.New("invalid entropy length")
+
+	// ErrInvalidDerivationPath indicates an HD derivation path is malformed
+	ErrInvalidDerivationPath = errors.New("invalid derivation path")
+
+	// ErrUnsupportedCurve indicates the curve is not supported for HD derivation
+	ErrUnsupportedCurve = errors.New("unsupported curve")
 )
\ No newline at end of file
@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBIP39Wordlist(t *testing.T) {
+	require.Len(t, bip39English, 2048)
+
+	digest := sha256.Sum256([]byte(strings.Join(bip39English, "\n") + "\n"))
+	assert.Equal(t, "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda", hex.EncodeToString(digest[:]))
+}
+
+func TestBIP39Vectors(t *testing.T) {
+	// Vectors from the BIP-39 reference test suite (passphrase "TREZOR")
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+	}
+
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+
+		mnemonic, err := EntropyToMnemonic(entropy)
+		require.NoError(t, err)
+		assert.Equal(t, v.mnemonic, mnemonic)
+
+		decoded, err := MnemonicToEntropy(v.mnemonic)
+		require.NoError(t, err)
+		assert.Equal(t, entropy, decoded)
+
+		seed, err := MnemonicToSeed(v.mnemonic, "TREZOR")
+		require.NoError(t, err)
+		assert.Equal(t, v.seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestBIP39Validation(t *testing.T) {
+	mnemonic, err := GenerateMnemonic(MnemonicStrength256)
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(mnemonic), 24)
+	assert.True(t, ValidateMnemonic(mnemonic))
+
+	// Swapping the last word breaks the checksum
+	assert.False(t, ValidateMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"))
+	assert.False(t, ValidateMnemonic("abandon abandon abandon"))
+	assert.False(t, ValidateMnemonic("notaword abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"))
+
+	_, err = GenerateMnemonic(100)
+	assert.ErrorIs(t, err, ErrInvalidEntropy)
+}
+
+func TestSLIP10Vectors(t *testing.T) {
+	// Test vector 1 from SLIP-0010
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	vectors := []struct {
+		curve     Curve
+		path      string
+		chainCode string
+		key       string
+	}{
+		{CurveEd25519, "m", "90046a93de5380a72b5e45010748567d5ea02bbf6522f979e05c0d8d8ca9fffb", "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{CurveEd25519, "m/0'", "8b59aa11380b624e81507a27fedda59fea6d0b779a778918a2fd3590e16e9c69", "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{CurveSecp256k1, "m", "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508", "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{CurveSecp256k1, "m/0'", "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{CurveP256, "m", "beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea", "612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2"},
+		{CurveP256, "m/0'", "3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11", "6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c"},
+	}
+
+	for _, v := range vectors {
+		t.Run(string(v.curve)+" "+v.path, func(t *testing.T) {
+			key, err := DerivePath(v.curve, seed, v.path)
+			require.NoError(t, err)
+			assert.Equal(t, v.chainCode, hex.EncodeToString(key.ChainCode))
+			assert.Equal(t, v.key, hex.EncodeToString(key.Key))
+		})
+	}
+}
+
+func TestSLIP10Paths(t *testing.T) {
+	indexes, err := ParseDerivationPath("m/44'/0h/7")
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{44 + HardenedOffset, HardenedOffset, 7}, indexes)
+	assert.Equal(t, "m/44'/0'/7", FormatDerivationPath(indexes))
+
+	_, err = ParseDerivationPath("44'/0'")
+	assert.ErrorIs(t, err, ErrInvalidDerivationPath)
+
+	seed := make([]byte, 32)
+	_, err = DerivePath(CurveEd25519, seed, "m/0")
+	assert.ErrorIs(t, err, ErrInvalidDerivationPath, "ed25519 rejects non-hardened derivation")
+
+	_, err = DerivePath(CurveSecp256k1, seed, "m/0'/1")
+	assert.NoError(t, err)
+	_, err = DerivePath(CurveP256, seed, "m/0'/1")
+	assert.NoError(t, err)
+}
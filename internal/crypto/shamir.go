@@ -0,0 +1,169 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// Shamir secret sharing over GF(2^8) using the AES reduction polynomial
+// (x^8 + x^4 + x^3 + x + 1). Each byte of the secret is shared with an
+// independent random polynomial of degree threshold-1; share indexes are
+// the non-zero x coordinates 1..n.
+
+// SecretShare represents a single Shamir share of a secret
+type SecretShare struct {
+	// Index is the x coordinate of the share (1-255)
+	Index byte `json:"index"`
+
+	// Value holds the polynomial evaluations, one byte per secret byte
+	Value []byte `json:"value"`
+}
+
+const (
+	// MaxShares is the maximum number of shares that can be produced
+	MaxShares = 255
+)
+
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	// Build log/exp tables using generator 0x03
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		x = gfMulNoTable(x, 0x03)
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+// SplitSecret splits a secret into n shares, any threshold of which can
+// reconstruct it
+func SplitSecret(secret []byte, n, threshold int) ([]SecretShare, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret cannot be empty")
+	}
+	if threshold < 2 || threshold > n {
+		return nil, fmt.Errorf("%w: threshold %d with %d shares", ErrInvalidThreshold, threshold, n)
+	}
+	if n > MaxShares {
+		return nil, fmt.Errorf("too many shares: %d > %d", n, MaxShares)
+	}
+
+	shares := make([]SecretShare, n)
+	for i := range shares {
+		shares[i] = SecretShare{
+			Index: byte(i + 1),
+			Value: make([]byte, len(secret)),
+		}
+	}
+
+	coefficients := make([]byte, threshold)
+	for b, secretByte := range secret {
+		coefficients[0] = secretByte
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRandomnessGenerationFailed, err)
+		}
+
+		for i := range shares {
+			shares[i].Value[b] = gfEvaluate(coefficients, shares[i].Index)
+		}
+	}
+
+	// Wipe coefficients
+	for i := range coefficients {
+		coefficients[i] = 0
+	}
+
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret from threshold or more shares using
+// Lagrange interpolation at x = 0
+func CombineShares(shares []SecretShare) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: at least 2 shares required", ErrThresholdNotMet)
+	}
+
+	length := len(shares[0].Value)
+	seen := make(map[byte]bool, len(shares))
+	for _, share := range shares {
+		if share.Index == 0 {
+			return nil, fmt.Errorf("invalid share index 0")
+		}
+		if len(share.Value) != length || length == 0 {
+			return nil, fmt.Errorf("share length mismatch")
+		}
+		if seen[share.Index] {
+			return nil, fmt.Errorf("%w: index %d", ErrDuplicateShare, share.Index)
+		}
+		seen[share.Index] = true
+	}
+
+	secret := make([]byte, length)
+	for b := 0; b < length; b++ {
+		var value byte
+		for i, share := range shares {
+			// Lagrange basis polynomial evaluated at 0
+			basis := byte(1)
+			for j, other := range shares {
+				if i == j {
+					continue
+				}
+				basis = gfMul(basis, gfDiv(other.Index, share.Index^other.Index))
+			}
+			value ^= gfMul(share.Value[b], basis)
+		}
+		secret[b] = value
+	}
+
+	return secret, nil
+}
+
+// gfEvaluate evaluates a polynomial at x using Horner's method
+func gfEvaluate(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if b == 0 {
+		panic("division by zero in GF(256)")
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfMulNoTable multiplies two field elements without lookup tables
+func gfMulNoTable(a, b byte) byte {
+	var product byte
+	for b > 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return product
+}
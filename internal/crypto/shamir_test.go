@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShamirSecretSharing(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	t.Run("AnyThresholdSubsetRecovers", func(t *testing.T) {
+		shares, err := SplitSecret(secret, 5, 3)
+		require.NoError(t, err)
+		require.Len(t, shares, 5)
+
+		subsets := [][]int{{0, 1, 2}, {1, 3, 4}, {4, 2, 0}, {0, 1, 2, 3, 4}}
+		for _, subset := range subsets {
+			selected := make([]SecretShare, 0, len(subset))
+			for _, i := range subset {
+				selected = append(selected, shares[i])
+			}
+			recovered, err := CombineShares(selected)
+			require.NoError(t, err)
+			assert.Equal(t, secret, recovered)
+		}
+	})
+
+	t.Run("BelowThresholdDoesNotRecover", func(t *testing.T) {
+		shares, err := SplitSecret(secret, 5, 3)
+		require.NoError(t, err)
+
+		recovered, err := CombineShares(shares[:2])
+		require.NoError(t, err)
+		assert.NotEqual(t, secret, recovered)
+	})
+
+	t.Run("InvalidParameters", func(t *testing.T) {
+		_, err := SplitSecret(secret, 3, 1)
+		assert.ErrorIs(t, err, ErrInvalidThreshold)
+
+		_, err = SplitSecret(secret, 2, 3)
+		assert.ErrorIs(t, err, ErrInvalidThreshold)
+
+		_, err = SplitSecret(nil, 3, 2)
+		assert.Error(t, err)
+	})
+
+	t.Run("DuplicateShares", func(t *testing.T) {
+		shares, err := SplitSecret(secret, 3, 2)
+		require.NoError(t, err)
+
+		_, err = CombineShares([]SecretShare{shares[0], shares[0]})
+		assert.ErrorIs(t, err, ErrDuplicateShare)
+	})
+}
+
+func TestSealedBox(t *testing.T) {
+	recipient, err := NewEd25519KeyPair()
+	require.NoError(t, err)
+
+	plaintext := []byte("guardian share")
+	box, err := SealToEd25519(recipient.PublicKey, plaintext)
+	require.NoError(t, err)
+
+	opened, err := OpenWithEd25519(recipient.PrivateKey, box)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	t.Run("WrongRecipient", func(t *testing.T) {
+		other, err := NewEd25519KeyPair()
+		require.NoError(t, err)
+
+		_, err = OpenWithEd25519(other.PrivateKey, box)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+
+	t.Run("TamperedCiphertext", func(t *testing.T) {
+		tampered := *box
+		tampered.Ciphertext = append([]byte{}, box.Ciphertext...)
+		tampered.Ciphertext[0] ^= 0xff
+
+		_, err := OpenWithEd25519(recipient.PrivateKey, &tampered)
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+
+	t.Run("InvalidRecipientKey", func(t *testing.T) {
+		_, err := SealToEd25519(ed25519.PublicKey{1, 2, 3}, plaintext)
+		assert.ErrorIs(t, err, ErrInvalidPublicKey)
+	})
+}
@@ -0,0 +1,265 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// SLIP-0010 hierarchical deterministic key derivation for Ed25519,
+// secp256k1 and NIST P-256. Ed25519 only supports hardened derivation.
+
+// Curve identifies the curve used for HD derivation
+type Curve string
+
+const (
+	CurveEd25519   Curve = "ed25519"
+	CurveSecp256k1 Curve = "secp256k1"
+	CurveP256      Curve = "p256"
+)
+
+// HardenedOffset marks a child index as hardened
+const HardenedOffset uint32 = 0x80000000
+
+// ExtendedKey is a private key together with its chain code
+type ExtendedKey struct {
+	Curve     Curve
+	Key       []byte // 32-byte private key (Ed25519 seed or EC scalar)
+	ChainCode []byte
+	Depth     uint8
+	Index     uint32
+}
+
+// hmacKey returns the SLIP-0010 master key HMAC key for a curve
+func (c Curve) hmacKey() ([]byte, error) {
+	switch c {
+	case CurveEd25519:
+		return []byte("ed25519 seed"), nil
+	case CurveSecp256k1:
+		return []byte("Bitcoin seed"), nil
+	case CurveP256:
+		return []byte("Nist256p1 seed"), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurve, c)
+	}
+}
+
+// order returns the group order for EC curves, or nil for Ed25519
+func (c Curve) order() *big.Int {
+	switch c {
+	case CurveSecp256k1:
+		return secp256k1.S256().N
+	case CurveP256:
+		return elliptic.P256().Params().N
+	default:
+		return nil
+	}
+}
+
+// NewMasterKey derives the master extended key from a seed
+func NewMasterKey(curve Curve, seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("%w: seed must be 16-64 bytes", ErrInvalidKeySize)
+	}
+
+	hmacKey, err := curve.hmacKey()
+	if err != nil {
+		return nil, err
+	}
+
+	data := seed
+	for {
+		mac := hmac.New(sha512.New, hmacKey)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		key, chainCode := sum[:32], sum[32:]
+		if curve.validScalar(key) {
+			return &ExtendedKey{Curve: curve, Key: key, ChainCode: chainCode}, nil
+		}
+		// Invalid EC scalar: retry with I as the new input
+		data = sum
+	}
+}
+
+// Derive returns the child key at index
+func (k *ExtendedKey) Derive(index uint32) (*ExtendedKey, error) {
+	hardened := index >= HardenedOffset
+	if k.Curve == CurveEd25519 && !hardened {
+		return nil, fmt.Errorf("%w: ed25519 requires hardened derivation", ErrInvalidDerivationPath)
+	}
+
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0x00)
+		data = append(data, k.Key...)
+	} else {
+		publicKey, err := k.compressedPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, publicKey...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	for {
+		mac := hmac.New(sha512.New, k.ChainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		childKey, chainCode := sum[:32], sum[32:]
+
+		if k.Curve == CurveEd25519 {
+			return &ExtendedKey{Curve: k.Curve, Key: childKey, ChainCode: chainCode, Depth: k.Depth + 1, Index: index}, nil
+		}
+
+		n := k.Curve.order()
+		il := new(big.Int).SetBytes(childKey)
+		if il.Cmp(n) < 0 {
+			il.Add(il, new(big.Int).SetBytes(k.Key))
+			il.Mod(il, n)
+			if il.Sign() != 0 {
+				return &ExtendedKey{
+					Curve:     k.Curve,
+					Key:       il.FillBytes(make([]byte, 32)),
+					ChainCode: chainCode,
+					Depth:     k.Depth + 1,
+					Index:     index,
+				}, nil
+			}
+		}
+
+		// Invalid child: retry with 0x01 || IR || index
+		data = append([]byte{0x01}, chainCode...)
+		data = binary.BigEndian.AppendUint32(data, index)
+	}
+}
+
+// DerivePath derives a key along a path such as m/44'/0'/0'
+func DerivePath(curve Curve, seed []byte, path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := NewMasterKey(curve, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, index := range indexes {
+		key, err = key.Derive(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// ParseDerivationPath parses a BIP-32 style path; hardened segments end in ' or h
+func ParseDerivationPath(path string) ([]uint32, error) {
+	segments := strings.Split(strings.TrimSpace(path), "/")
+	if len(segments) == 0 || segments[0] != "m" {
+		return nil, fmt.Errorf("%w: %q must start with m", ErrInvalidDerivationPath, path)
+	}
+
+	indexes := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		hardened := strings.HasSuffix(segment, "'") || strings.HasSuffix(segment, "h")
+		if hardened {
+			segment = segment[:len(segment)-1]
+		}
+
+		value, err := strconv.ParseUint(segment, 10, 32)
+		if err != nil || uint32(value) >= HardenedOffset {
+			return nil, fmt.Errorf("%w: invalid segment %q", ErrInvalidDerivationPath, segment)
+		}
+
+		index := uint32(value)
+		if hardened {
+			index += HardenedOffset
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// FormatDerivationPath renders hardened indexes as a path string
+func FormatDerivationPath(indexes []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range indexes {
+		if index >= HardenedOffset {
+			fmt.Fprintf(&b, "/%d'", index-HardenedOffset)
+		} else {
+			fmt.Fprintf(&b, "/%d", index)
+		}
+	}
+	return b.String()
+}
+
+// PrivateKey returns the derived key as ed25519.PrivateKey for Ed25519 or
+// *ecdsa.PrivateKey for secp256k1 and P-256
+func (k *ExtendedKey) PrivateKey() (interface{}, error) {
+	switch k.Curve {
+	case CurveEd25519:
+		return ed25519.NewKeyFromSeed(k.Key), nil
+	case CurveSecp256k1:
+		return secp256k1.PrivKeyFromBytes(k.Key).ToECDSA(), nil
+	case CurveP256:
+		privateKey, err := ecdh.P256().NewPrivateKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+		}
+		point := privateKey.PublicKey().Bytes()
+		return &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(point[1:33]),
+				Y:     new(big.Int).SetBytes(point[33:65]),
+			},
+			D: new(big.Int).SetBytes(k.Key),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurve, k.Curve)
+	}
+}
+
+func (c Curve) validScalar(key []byte) bool {
+	n := c.order()
+	if n == nil {
+		return true
+	}
+	k := new(big.Int).SetBytes(key)
+	return k.Sign() != 0 && k.Cmp(n) < 0
+}
+
+// compressedPublicKey returns the SEC1 compressed public key for EC curves
+func (k *ExtendedKey) compressedPublicKey() ([]byte, error) {
+	switch k.Curve {
+	case CurveSecp256k1:
+		return secp256k1.PrivKeyFromBytes(k.Key).PubKey().SerializeCompressed(), nil
+	case CurveP256:
+		privateKey, err := ecdh.P256().NewPrivateKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+		}
+		uncompressed := privateKey.PublicKey().Bytes() // 0x04 || X || Y
+		compressed := make([]byte, 33)
+		compressed[0] = 0x02 | (uncompressed[64] & 1)
+		copy(compressed[1:], uncompressed[1:33])
+		return compressed, nil
+	default:
+		return nil, fmt.Errorf("%w: %s has no compressed public key", ErrUnsupportedCurve, k.Curve)
+	}
+}
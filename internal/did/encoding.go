@@ -134,4 +134,10 @@ func multibaseDecode(encoded string) ([]byte, error) {
 	default:
 		return base58Decode(encoded) // fallback for legacy support
 	}
-}
\ No newline at end of file
+}
+
+// MultibaseDecode decodes a multibase string such as a publicKeyMultibase
+// value
+func MultibaseDecode(encoded string) ([]byte, error) {
+	return multibaseDecode(encoded)
+}
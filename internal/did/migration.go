@@ -0,0 +1,260 @@
+package did
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// KeyMigrationStatementType is the type tag for key migration statements
+const KeyMigrationStatementType = "KeyMigrationStatement"
+
+// GuardianAttestationType is the type tag for guardian attestations
+const GuardianAttestationType = "GuardianAttestation"
+
+// MigrationReasonSocialRecovery marks migrations made by guardian recovery
+const MigrationReasonSocialRecovery = "social-recovery"
+
+// KeyMigrationStatement links a retired DID to its successor. It is signed by
+// both the old and the new DID keys so that consumers such as the scorer can
+// carry vouches and tenure over to the new identifier.
+type KeyMigrationStatement struct {
+	Type      string    `json:"type"`
+	OldDID    string    `json:"oldDid"`
+	NewDID    string    `json:"newDid"`
+	Reason    string    `json:"reason,omitempty"`
+	Guardians []string  `json:"guardians,omitempty"` // Guardians who approved a social recovery
+	IssuedAt  time.Time `json:"issuedAt"`
+
+	// Proofs are base64url signatures over SigningInput
+	OldProof string `json:"oldProof,omitempty"`
+	NewProof string `json:"newProof,omitempty"`
+
+	// Attestations are the guardians' own signatures approving a social
+	// recovery, one for each of Guardians. The old key is rebuilt from their
+	// shares, so its proof alone does not show that they took part.
+	Attestations []*GuardianAttestation `json:"attestations,omitempty"`
+}
+
+// GuardianAttestation is a guardian's signed approval of recovering OldDID
+// onto NewDID, made under a guardian set of the given threshold
+type GuardianAttestation struct {
+	Type        string    `json:"type"`
+	OldDID      string    `json:"oldDid"`
+	NewDID      string    `json:"newDid"`
+	GuardianDID string    `json:"guardianDid"`
+	Threshold   int       `json:"threshold"`
+	IssuedAt    time.Time `json:"issuedAt"`
+	Proof       string    `json:"proof,omitempty"` // base64url signature over SigningInput
+}
+
+// SigningInput returns the deterministic bytes covered by the proof
+func (a *GuardianAttestation) SigningInput() ([]byte, error) {
+	unsigned := *a
+	unsigned.Proof = ""
+	unsigned.IssuedAt = a.IssuedAt.UTC()
+	return json.Marshal(unsigned)
+}
+
+// SignGuardianAttestation signs an attestation with the guardian's private key
+func SignGuardianAttestation(keyManager KeyManager, attestation *GuardianAttestation, guardianPrivateKey interface{}) error {
+	if attestation == nil {
+		return NewDIDError(ErrorInvalidDocument, "guardian attestation is nil")
+	}
+	if attestation.Type == "" {
+		attestation.Type = GuardianAttestationType
+	}
+
+	input, err := attestation.SigningInput()
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInternalError, "failed to encode guardian attestation", err)
+	}
+	signature, err := keyManager.Sign(guardianPrivateKey, input)
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInvalidKey, "failed to sign with guardian key", err)
+	}
+
+	attestation.Proof = base64.RawURLEncoding.EncodeToString(signature)
+	return nil
+}
+
+// VerifyGuardianAttestation checks an attestation's proof against the key
+// embedded in its guardian did:key
+func VerifyGuardianAttestation(keyManager KeyManager, attestation *GuardianAttestation) error {
+	if attestation == nil {
+		return NewDIDError(ErrorInvalidDocument, "guardian attestation is nil")
+	}
+	if attestation.Type != GuardianAttestationType {
+		return NewDIDError(ErrorInvalidDocument, "unexpected attestation type: "+attestation.Type)
+	}
+
+	input, err := attestation.SigningInput()
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInternalError, "failed to encode guardian attestation", err)
+	}
+	return verifyDIDKeyProof(keyManager, attestation.GuardianDID, attestation.Proof, input, "guardian")
+}
+
+// SigningInput returns the deterministic bytes covered by both proofs
+func (s *KeyMigrationStatement) SigningInput() ([]byte, error) {
+	unsigned := struct {
+		Type      string    `json:"type"`
+		OldDID    string    `json:"oldDid"`
+		NewDID    string    `json:"newDid"`
+		Reason    string    `json:"reason,omitempty"`
+		Guardians []string  `json:"guardians,omitempty"`
+		IssuedAt  time.Time `json:"issuedAt"`
+	}{
+		Type:      s.Type,
+		OldDID:    s.OldDID,
+		NewDID:    s.NewDID,
+		Reason:    s.Reason,
+		Guardians: s.Guardians,
+		IssuedAt:  s.IssuedAt.UTC(),
+	}
+	return json.Marshal(unsigned)
+}
+
+// SignKeyMigration signs a migration statement with the old and new private keys
+func SignKeyMigration(keyManager KeyManager, statement *KeyMigrationStatement, oldPrivateKey, newPrivateKey interface{}) error {
+	if statement == nil {
+		return NewDIDError(ErrorInvalidDocument, "migration statement is nil")
+	}
+	if statement.OldDID == "" || statement.NewDID == "" || statement.OldDID == statement.NewDID {
+		return NewDIDError(ErrorInvalidDID, "migration requires distinct old and new DIDs")
+	}
+	if statement.Type == "" {
+		statement.Type = KeyMigrationStatementType
+	}
+
+	input, err := statement.SigningInput()
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInternalError, "failed to encode migration statement", err)
+	}
+
+	oldSig, err := keyManager.Sign(oldPrivateKey, input)
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInvalidKey, "failed to sign with old key", err)
+	}
+	newSig, err := keyManager.Sign(newPrivateKey, input)
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInvalidKey, "failed to sign with new key", err)
+	}
+
+	statement.OldProof = base64.RawURLEncoding.EncodeToString(oldSig)
+	statement.NewProof = base64.RawURLEncoding.EncodeToString(newSig)
+	return nil
+}
+
+// VerifyKeyMigration checks both proofs on a migration statement. Only
+// did:key identifiers are supported since their keys are self-certifying.
+func VerifyKeyMigration(keyManager KeyManager, statement *KeyMigrationStatement) error {
+	if statement == nil {
+		return NewDIDError(ErrorInvalidDocument, "migration statement is nil")
+	}
+	if statement.Type != KeyMigrationStatementType {
+		return NewDIDError(ErrorInvalidDocument, "unexpected statement type: "+statement.Type)
+	}
+	if statement.OldDID == statement.NewDID {
+		return NewDIDError(ErrorInvalidDID, "old and new DID must differ")
+	}
+
+	input, err := statement.SigningInput()
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInternalError, "failed to encode migration statement", err)
+	}
+
+	checks := []struct {
+		did   string
+		proof string
+		label string
+	}{
+		{statement.OldDID, statement.OldProof, "old"},
+		{statement.NewDID, statement.NewProof, "new"},
+	}
+
+	for _, check := range checks {
+		if err := verifyDIDKeyProof(keyManager, check.did, check.proof, input, check.label); err != nil {
+			return err
+		}
+	}
+
+	if statement.Reason == MigrationReasonSocialRecovery || len(statement.Guardians) > 0 || len(statement.Attestations) > 0 {
+		return verifyGuardianApprovals(keyManager, statement)
+	}
+	return nil
+}
+
+// verifyGuardianApprovals checks that every guardian a recovery names signed
+// an attestation for this migration, and that they meet the threshold the
+// attestations were made under. Guardian sets have at least two members.
+func verifyGuardianApprovals(keyManager KeyManager, statement *KeyMigrationStatement) error {
+	if len(statement.Attestations) != len(statement.Guardians) {
+		return NewDIDError(ErrorInvalidDocument, "recovery must carry one attestation per guardian")
+	}
+
+	threshold := 0
+	attested := make(map[string]bool, len(statement.Attestations))
+	for _, attestation := range statement.Attestations {
+		if err := VerifyGuardianAttestation(keyManager, attestation); err != nil {
+			return err
+		}
+		if attestation.OldDID != statement.OldDID || attestation.NewDID != statement.NewDID {
+			return NewDIDError(ErrorInvalidDocument, "guardian attestation is for a different migration")
+		}
+		if attested[attestation.GuardianDID] {
+			return NewDIDError(ErrorInvalidDocument, "guardian attested more than once: "+attestation.GuardianDID)
+		}
+		if threshold != 0 && attestation.Threshold != threshold {
+			return NewDIDError(ErrorInvalidDocument, "guardian attestations disagree on the threshold")
+		}
+		attested[attestation.GuardianDID] = true
+		threshold = attestation.Threshold
+	}
+
+	for _, guardian := range statement.Guardians {
+		if !attested[guardian] {
+			return NewDIDError(ErrorInvalidDocument, "no attestation from guardian "+guardian)
+		}
+	}
+	if threshold < 2 {
+		return NewDIDError(ErrorInvalidDocument, "guardian threshold must be at least 2")
+	}
+	if len(attested) < threshold {
+		return NewDIDError(ErrorInvalidDocument, fmt.Sprintf("recovery has %d of %d guardian attestations", len(attested), threshold))
+	}
+	return nil
+}
+
+// verifyDIDKeyProof checks a base64url signature against a did:key
+func verifyDIDKeyProof(keyManager KeyManager, didStr, proof string, input []byte, label string) error {
+	publicKey, _, err := PublicKeyFromDIDKey(didStr)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(proof)
+	if err != nil {
+		return NewDIDErrorWithCause(ErrorInvalidDocument, "invalid "+label+" proof encoding", err)
+	}
+
+	if !keyManager.Verify(publicKey, input, signature) {
+		return NewDIDError(ErrorInvalidKey, label+" DID proof verification failed")
+	}
+	return nil
+}
+
+// PublicKeyFromDIDKey extracts the public key embedded in a did:key identifier
+func PublicKeyFromDIDKey(didStr string) (interface{}, KeyType, error) {
+	parsed, err := ParseDID(didStr)
+	if err != nil {
+		return nil, "", NewDIDErrorWithCause(ErrorInvalidDID, "invalid DID", err)
+	}
+	if parsed.Method != "key" {
+		return nil, "", NewDIDError(ErrorMethodNotSupported, "public key extraction requires did:key, got did:"+parsed.Method)
+	}
+
+	resolver := &KeyMethodResolver{}
+	return resolver.decodePublicKey(parsed.Identifier)
+}
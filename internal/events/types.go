@@ -12,11 +12,12 @@ const (
 	EventTypeReport            EventType = "report"
 	EventTypeAppeal            EventType = "appeal"
 	EventTypeRevocationAnnounce EventType = "revocation_announce"
+	EventTypeAdjudication      EventType = "adjudication"
 )
 
 // Event represents a canonical event in the system
 type Event struct {
-	Type       EventType `json:"type" validate:"required,oneof=vouch report appeal revocation_announce"`
+	Type       EventType `json:"type" validate:"required,oneof=vouch report appeal revocation_announce adjudication"`
 	From       string    `json:"from" validate:"required,did"`
 	To         string    `json:"to,omitempty" validate:"omitempty,did"`
 	Context    string    `json:"ctx" validate:"required,oneof=general commerce hiring"`
@@ -47,6 +48,13 @@ type AppealEvent struct {
 	NewEvidenceCID string `json:"new_evidenceCID,omitempty"`
 }
 
+// AdjudicationEvent represents an adjudicator's decision on a case. The
+// decision is the payload referenced by PayloadCID.
+type AdjudicationEvent struct {
+	Event
+	CaseID string `json:"case_id" validate:"required"`
+}
+
 // RevocationAnnounceEvent represents a revocation announcement
 type RevocationAnnounceEvent struct {
 	Event
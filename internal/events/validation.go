@@ -30,6 +30,7 @@ var (
 		EventTypeReport:             true,
 		EventTypeAppeal:             true,
 		EventTypeRevocationAnnounce: true,
+		EventTypeAdjudication:       true,
 	}
 
 	// Validator instance
@@ -103,6 +104,10 @@ func IsValidBase64(str string) bool {
 	return err == nil
 }
 
+// MaxEventAge is how long after signing an event is accepted, allowing for
+// offline signing
+const MaxEventAge = 24 * time.Hour
+
 // ValidateEvent validates an event using struct tags and custom rules
 func ValidateEvent(event *Event) error {
 	if event == nil {
@@ -134,8 +139,8 @@ func validateEventSemantics(event *Event) error {
 		return fmt.Errorf("event timestamp too far in future: %w", ErrInvalidEventStructure)
 	}
 
-	// Check timestamp is not too old (allow up to 24 hours for offline signing)
-	if event.IssuedAt.Before(time.Now().Add(-24 * time.Hour)) {
+	// Check timestamp is not too old
+	if event.IssuedAt.Before(time.Now().Add(-MaxEventAge)) {
 		return fmt.Errorf("event timestamp too old: %w", ErrInvalidEventStructure)
 	}
 
@@ -165,6 +170,8 @@ func validateEventSemantics(event *Event) error {
 		return validateAppealEvent(event)
 	case EventTypeRevocationAnnounce:
 		return validateRevocationEvent(event)
+	case EventTypeAdjudication:
+		return validateAdjudicationEvent(event)
 	}
 
 	return nil
@@ -229,6 +236,19 @@ func validateRevocationEvent(event *Event) error {
 	return nil
 }
 
+// validateAdjudicationEvent validates adjudication rules
+func validateAdjudicationEvent(event *Event) error {
+	// The decision is carried in the payload
+	if event.PayloadCID == "" {
+		return fmt.Errorf("adjudication requires a payload: %w", ErrMissingRequiredField)
+	}
+	if event.From == event.To {
+		return fmt.Errorf("cannot adjudicate own case: %w", ErrInvalidEventStructure)
+	}
+
+	return nil
+}
+
 // ValidateSignableEvent validates an event before signing
 func ValidateSignableEvent(event *SignableEvent) error {
 	if event == nil {
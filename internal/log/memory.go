@@ -2,7 +2,6 @@ package log
 
 import (
 	"context"
-	"crypto/sha256"
 	"fmt"
 	"sync"
 	"time"
@@ -108,8 +107,7 @@ func (m *MemoryTransparencyLog) AppendLeaves(ctx context.Context, leaves []Leaf)
 		// Validate hash if provided
 		if len(leaf.LeafHash) == 0 {
 			// Compute hash from leaf value
-			hash := sha256.Sum256(leaf.LeafValue)
-			leaf.LeafHash = hash[:]
+			leaf.LeafHash = HashLeaf(leaf.LeafValue)
 		}
 		
 		// Store leaf
@@ -315,74 +313,29 @@ func (m *MemoryTransparencyLog) GetStats() LogStats {
 
 // computeRootHash computes the Merkle tree root hash
 func (m *MemoryTransparencyLog) computeRootHash() []byte {
-	if m.treeSize == 0 {
-		return nil
-	}
-	
-	// Simple implementation: hash all leaf hashes together
-	// In a real implementation, this would be a proper Merkle tree
-	hasher := sha256.New()
-	
-	for i := int64(0); i < m.treeSize; i++ {
-		hasher.Write(m.leaves[i].LeafHash)
-	}
-	
-	hash := hasher.Sum(nil)
-	return hash
+	return RootHash(m.leafHashes(m.treeSize))
 }
 
 // computeAuditPath computes the audit path for inclusion proof
 func (m *MemoryTransparencyLog) computeAuditPath(leafIndex, treeSize int64) [][]byte {
-	// Simplified implementation - in practice this would compute
-	// the actual Merkle tree audit path
-	var path [][]byte
-	
-	// For demonstration, return sibling hashes at each level
-	// This is NOT a correct Merkle audit path implementation
-	currentIndex := leafIndex
-	currentSize := treeSize
-	
-	for currentSize > 1 {
-		// Find sibling
-		var siblingIndex int64
-		if currentIndex%2 == 0 {
-			siblingIndex = currentIndex + 1
-		} else {
-			siblingIndex = currentIndex - 1
-		}
-		
-		// Add sibling hash if it exists
-		if siblingIndex < currentSize && siblingIndex < int64(len(m.leaves)) {
-			path = append(path, m.leaves[siblingIndex].LeafHash)
-		}
-		
-		// Move up one level
-		currentIndex /= 2
-		currentSize = (currentSize + 1) / 2
-	}
-	
-	return path
+	return auditPath(leafIndex, m.leafHashes(treeSize))
 }
 
 // computeConsistencyPath computes the consistency proof path
 func (m *MemoryTransparencyLog) computeConsistencyPath(fromSize, toSize int64) [][]byte {
-	// Simplified implementation - in practice this would compute
-	// the actual Merkle tree consistency path
-	var path [][]byte
-	
-	// For demonstration, include intermediate root hashes
-	// This is NOT a correct consistency proof implementation
-	if fromSize < toSize && fromSize < int64(len(m.leaves)) {
-		// Add a hash representing the state at fromSize
-		hasher := sha256.New()
-		for i := int64(0); i < fromSize; i++ {
-			hasher.Write(m.leaves[i].LeafHash)
-		}
-		fromHash := hasher.Sum(nil)
-		path = append(path, fromHash)
+	if fromSize == 0 {
+		return [][]byte{}
 	}
-	
-	return path
+	return consistencyPath(fromSize, m.leafHashes(toSize), true)
+}
+
+// leafHashes returns the hashes of the first treeSize leaves
+func (m *MemoryTransparencyLog) leafHashes(treeSize int64) [][]byte {
+	hashes := make([][]byte, treeSize)
+	for i := int64(0); i < treeSize; i++ {
+		hashes[i] = m.leaves[i].LeafHash
+	}
+	return hashes
 }
 
 // createSignedTreeHead creates and signs a tree head
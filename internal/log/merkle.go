@@ -0,0 +1,236 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+)
+
+// The log is a Merkle tree as in RFC 6962: a leaf hashes its value behind a
+// 0x00 byte and an interior node its children behind a 0x01 byte, so no
+// leaf can pass for an interior node. Trees are built over leaf hashes.
+
+// HashLeaf returns the leaf hash of a log entry
+func HashLeaf(value []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte{0x00})
+	hasher.Write(value)
+	return hasher.Sum(nil)
+}
+
+// hashChildren returns the hash of an interior node
+func hashChildren(left, right []byte) []byte {
+	hasher := sha256.New()
+	hasher.Write([]byte{0x01})
+	hasher.Write(left)
+	hasher.Write(right)
+	return hasher.Sum(nil)
+}
+
+// splitPoint returns the largest power of two smaller than n, for n > 1
+func splitPoint(n int64) int64 {
+	k := int64(1)
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// RootHash returns the root of the tree over leaf hashes, or nil for an
+// empty tree
+func RootHash(leafHashes [][]byte) []byte {
+	switch n := int64(len(leafHashes)); n {
+	case 0:
+		return nil
+	case 1:
+		return leafHashes[0]
+	default:
+		k := splitPoint(n)
+		return hashChildren(RootHash(leafHashes[:k]), RootHash(leafHashes[k:]))
+	}
+}
+
+// auditPath returns the inclusion proof path of a leaf, leaf first
+func auditPath(index int64, leafHashes [][]byte) [][]byte {
+	n := int64(len(leafHashes))
+	if n <= 1 {
+		return [][]byte{}
+	}
+	k := splitPoint(n)
+	if index < k {
+		return append(auditPath(index, leafHashes[:k]), RootHash(leafHashes[k:]))
+	}
+	return append(auditPath(index-k, leafHashes[k:]), RootHash(leafHashes[:k]))
+}
+
+// consistencyPath returns the proof that the tree of the first size leaves
+// is a prefix of the tree over all leaf hashes
+func consistencyPath(size int64, leafHashes [][]byte, complete bool) [][]byte {
+	n := int64(len(leafHashes))
+	if size == n {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{RootHash(leafHashes)}
+	}
+	k := splitPoint(n)
+	if size <= k {
+		return append(consistencyPath(size, leafHashes[:k], complete), RootHash(leafHashes[k:]))
+	}
+	return append(consistencyPath(size-k, leafHashes[k:], false), RootHash(leafHashes[:k]))
+}
+
+// VerifyInclusion checks that a leaf hash is at the proof's index in the
+// tree with the given root
+func VerifyInclusion(leafHash []byte, proof *InclusionProof, root []byte) error {
+	if proof == nil || proof.LeafIndex < 0 || proof.LeafIndex >= proof.TreeSize {
+		return fmt.Errorf("inclusion proof index out of range")
+	}
+
+	index, last := proof.LeafIndex, proof.TreeSize-1
+	hash := leafHash
+	for _, sibling := range proof.AuditPath {
+		if last == 0 {
+			return fmt.Errorf("inclusion proof is too long")
+		}
+		if index&1 == 1 || index == last {
+			hash = hashChildren(sibling, hash)
+			for index&1 == 0 && index != 0 {
+				index >>= 1
+				last >>= 1
+			}
+		} else {
+			hash = hashChildren(hash, sibling)
+		}
+		index >>= 1
+		last >>= 1
+	}
+
+	if last != 0 || !bytes.Equal(hash, root) {
+		return fmt.Errorf("inclusion proof does not match root")
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree with the first root is a prefix of
+// the tree with the second
+func VerifyConsistency(proof *ConsistencyProof, firstRoot, secondRoot []byte) error {
+	if proof == nil || proof.FirstTreeSize < 0 || proof.SecondTreeSize < proof.FirstTreeSize {
+		return fmt.Errorf("consistency proof sizes out of range")
+	}
+	if proof.FirstTreeSize == proof.SecondTreeSize {
+		if len(proof.ProofPath) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return fmt.Errorf("roots of equal trees differ")
+		}
+		return nil
+	}
+	if proof.FirstTreeSize == 0 {
+		// Every tree extends the empty tree
+		return nil
+	}
+
+	path := proof.ProofPath
+	// A first tree of a power of two leaves is a subtree of the second and
+	// is left out of the proof
+	if proof.FirstTreeSize&(proof.FirstTreeSize-1) == 0 {
+		path = append([][]byte{firstRoot}, path...)
+	}
+	if len(path) == 0 {
+		return fmt.Errorf("consistency proof is empty")
+	}
+
+	index, last := proof.FirstTreeSize-1, proof.SecondTreeSize-1
+	for index&1 == 1 {
+		index >>= 1
+		last >>= 1
+	}
+	first, second := path[0], path[0]
+	for _, node := range path[1:] {
+		if last == 0 {
+			return fmt.Errorf("consistency proof is too long")
+		}
+		if index&1 == 1 || index == last {
+			first = hashChildren(node, first)
+			second = hashChildren(node, second)
+			for index&1 == 0 && index != 0 {
+				index >>= 1
+				last >>= 1
+			}
+		} else {
+			second = hashChildren(second, node)
+		}
+		index >>= 1
+		last >>= 1
+	}
+
+	if last != 0 || !bytes.Equal(first, firstRoot) || !bytes.Equal(second, secondRoot) {
+		return fmt.Errorf("consistency proof does not match roots")
+	}
+	return nil
+}
+
+// Frontier holds the roots of the perfect subtrees that cover a tree's
+// leaves, which is enough to extend the tree and compute its root without
+// keeping the leaves
+type Frontier struct {
+	size   int64
+	hashes [][]byte // Largest subtree first
+}
+
+// Size returns the number of leaves appended
+func (f *Frontier) Size() int64 {
+	return f.size
+}
+
+// Append adds a leaf hash to the tree
+func (f *Frontier) Append(leafHash []byte) {
+	f.hashes = append(f.hashes, leafHash)
+	for size := f.size; size&1 == 1; size >>= 1 {
+		n := len(f.hashes)
+		f.hashes = append(f.hashes[:n-2], hashChildren(f.hashes[n-2], f.hashes[n-1]))
+	}
+	f.size++
+}
+
+// Root returns the root of the tree, or nil while it is empty
+func (f *Frontier) Root() []byte {
+	if len(f.hashes) == 0 {
+		return nil
+	}
+	root := f.hashes[len(f.hashes)-1]
+	for i := len(f.hashes) - 2; i >= 0; i-- {
+		root = hashChildren(f.hashes[i], root)
+	}
+	return root
+}
+
+// Clone returns an independent copy of the frontier
+func (f *Frontier) Clone() *Frontier {
+	return &Frontier{size: f.size, hashes: append([][]byte{}, f.hashes...)}
+}
+
+type frontierJSON struct {
+	Size   int64    `json:"size"`
+	Hashes [][]byte `json:"hashes"`
+}
+
+// MarshalJSON encodes the frontier so it can be saved and restored
+func (f *Frontier) MarshalJSON() ([]byte, error) {
+	return json.Marshal(frontierJSON{Size: f.size, Hashes: f.hashes})
+}
+
+// UnmarshalJSON restores a frontier, checking that it has one subtree per
+// set bit of its size
+func (f *Frontier) UnmarshalJSON(data []byte) error {
+	var decoded frontierJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Size < 0 || bits.OnesCount64(uint64(decoded.Size)) != len(decoded.Hashes) {
+		return fmt.Errorf("frontier of %d leaves cannot have %d subtrees", decoded.Size, len(decoded.Hashes))
+	}
+	f.size, f.hashes = decoded.Size, decoded.Hashes
+	return nil
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLeafHashes(n int) [][]byte {
+	hashes := make([][]byte, n)
+	for i := range hashes {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("leaf-%d", i)))
+		hashes[i] = hash[:]
+	}
+	return hashes
+}
+
+func TestMerkleProofs(t *testing.T) {
+	hashes := testLeafHashes(17)
+
+	t.Run("RootHash", func(t *testing.T) {
+		assert.Nil(t, RootHash(nil))
+		assert.Equal(t, hashes[0], RootHash(hashes[:1]))
+		assert.Equal(t, hashChildren(hashChildren(hashes[0], hashes[1]), hashes[2]), RootHash(hashes[:3]))
+	})
+
+	t.Run("Inclusion", func(t *testing.T) {
+		for size := int64(1); size <= int64(len(hashes)); size++ {
+			root := RootHash(hashes[:size])
+			for index := int64(0); index < size; index++ {
+				proof := &InclusionProof{LeafIndex: index, TreeSize: size, AuditPath: auditPath(index, hashes[:size])}
+				require.NoError(t, VerifyInclusion(hashes[index], proof, root), "leaf %d of %d", index, size)
+
+				other := hashes[(index+1)%int64(len(hashes))]
+				assert.Error(t, VerifyInclusion(other, proof, root), "leaf %d of %d", index, size)
+			}
+		}
+
+		proof := &InclusionProof{LeafIndex: 3, TreeSize: 3}
+		assert.Error(t, VerifyInclusion(hashes[3], proof, RootHash(hashes[:3])))
+	})
+
+	t.Run("Consistency", func(t *testing.T) {
+		for second := int64(1); second <= int64(len(hashes)); second++ {
+			secondRoot := RootHash(hashes[:second])
+			for first := int64(1); first <= second; first++ {
+				proof := &ConsistencyProof{
+					FirstTreeSize:  first,
+					SecondTreeSize: second,
+					ProofPath:      consistencyPath(first, hashes[:second], true),
+				}
+				firstRoot := RootHash(hashes[:first])
+				require.NoError(t, VerifyConsistency(proof, firstRoot, secondRoot), "%d to %d", first, second)
+
+				if first < second {
+					assert.Error(t, VerifyConsistency(proof, RootHash(hashes[1:first+1]), secondRoot), "%d to %d", first, second)
+				}
+			}
+		}
+	})
+
+	t.Run("Frontier", func(t *testing.T) {
+		frontier := &Frontier{}
+		assert.Nil(t, frontier.Root())
+		for i, hash := range hashes {
+			frontier.Append(hash)
+			assert.Equal(t, int64(i+1), frontier.Size())
+			assert.Equal(t, RootHash(hashes[:i+1]), frontier.Root())
+		}
+
+		clone := frontier.Clone()
+		clone.Append(hashes[0])
+		assert.Equal(t, RootHash(hashes), frontier.Root())
+
+		data, err := json.Marshal(frontier)
+		require.NoError(t, err)
+		var restored Frontier
+		require.NoError(t, json.Unmarshal(data, &restored))
+		assert.Equal(t, frontier.Size(), restored.Size())
+		assert.Equal(t, frontier.Root(), restored.Root())
+		assert.Error(t, json.Unmarshal([]byte(`{"size":3,"hashes":[]}`), &restored))
+	})
+}
+
+// The RFC 6962 reference tree, from the certificate-transparency test suite
+func TestMerkleRFC6962Vectors(t *testing.T) {
+	leaves := []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"}
+	roots := []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+
+	var hashes [][]byte
+	for i, leaf := range leaves {
+		value, err := hex.DecodeString(leaf)
+		require.NoError(t, err)
+		hashes = append(hashes, HashLeaf(value))
+		assert.Equal(t, roots[i], hex.EncodeToString(RootHash(hashes)), "tree of %d leaves", i+1)
+	}
+}
+
+func TestMemoryTransparencyLog_VerifiableProofs(t *testing.T) {
+	transparencyLog, err := NewMemoryTransparencyLog(nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var leaves []Leaf
+	for i := 0; i < 11; i++ {
+		leaves = append(leaves, Leaf{LeafValue: []byte(fmt.Sprintf("event-%d", i))})
+	}
+	first, err := transparencyLog.AppendLeaves(ctx, leaves[:5])
+	require.NoError(t, err)
+	second, err := transparencyLog.AppendLeaves(ctx, leaves[5:])
+	require.NoError(t, err)
+
+	hash := HashLeaf(leaves[2].LeafValue)
+	inclusion, err := transparencyLog.GetInclusionProof(ctx, hash, second.TreeSize)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyInclusion(hash, inclusion, second.RootHash))
+
+	consistency, err := transparencyLog.GetConsistencyProof(ctx, first.TreeSize, second.TreeSize)
+	require.NoError(t, err)
+	assert.NoError(t, VerifyConsistency(consistency, first.RootHash, second.RootHash))
+	assert.Error(t, VerifyConsistency(consistency, second.RootHash, first.RootHash))
+}
@@ -455,6 +455,11 @@ func (p *P2PHost) processMessage(ctx context.Context, topic string, msg *pubsub.
 	case "checkpoint":
 		// Cache checkpoint messages
 		p.checkpointCache.Set(topic, msg.Data)
+		// The bridge serves the newest epoch checkpoint as "latest". Gossip
+		// is unauthenticated, so consumers verify its signatures themselves.
+		if topic == TopicCheckpointsEpoch {
+			p.checkpointCache.Set("latest", msg.Data)
+		}
 		logger.Debug("Cached checkpoint message", map[string]interface{}{
 			"cache_key": topic,
 		})
@@ -29,7 +29,7 @@ const (
 var (
 	// Valid topic patterns
 	eventTopicRegex      = regexp.MustCompile(`^events/(vouch|report|appeal)$`)
-	revocationTopicRegex = regexp.MustCompile(`^revocations/[a-zA-Z0-9._-]+$`)
+	revocationTopicRegex = regexp.MustCompile(`^revocations/[a-zA-Z0-9._:%-]+$`) // issuer DIDs
 	rulesTopicRegex      = regexp.MustCompile(`^rules/(active|[a-zA-Z0-9._-]+)$`)
 	checkpointTopicRegex = regexp.MustCompile(`^checkpoints/(epoch|[0-9]+)$`)
 	blobTopicRegex       = regexp.MustCompile(`^blobs/[a-zA-Z0-9]+$`)
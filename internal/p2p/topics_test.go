@@ -15,6 +15,7 @@ func TestTopicManager(t *testing.T) {
 			"events/report", 
 			"events/appeal",
 			"revocations/announce",
+			"revocations/did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK",
 			"rules/active",
 			"checkpoints/epoch",
 			"checkpoints/12345",
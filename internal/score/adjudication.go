@@ -0,0 +1,335 @@
+package score
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/log"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// DecisionKind is what an adjudication event decides
+type DecisionKind string
+
+const (
+	// DecisionReport rules on a report. An upheld report slashes the bonds
+	// of the reported DID's vouchers.
+	DecisionReport DecisionKind = "report"
+	// DecisionAppeal overturns the verdict of a case on appeal
+	DecisionAppeal DecisionKind = "appeal"
+	// DecisionIssuer rules on a disputed credential, moving its issuer's
+	// reputation
+	DecisionIssuer DecisionKind = "issuer"
+)
+
+// AdjudicationDecision is the payload of an adjudication event, stored as
+// a blob under the event's PayloadCID
+type AdjudicationDecision struct {
+	Kind   DecisionKind        `json:"kind"`
+	CaseID string              `json:"case_id"`
+	Report *ReportData         `json:"report,omitempty"` // The ruling, for report decisions
+	Issuer *IssuerAdjudication `json:"issuer,omitempty"` // The outcome, for issuer decisions
+}
+
+// AdjudicationFetcher retrieves logged events and their payloads
+type AdjudicationFetcher interface {
+	// GetEvent returns the event stored under a CID
+	GetEvent(ctx context.Context, cid string) (*events.Event, error)
+
+	// GetBlob returns the blob stored under a CID
+	GetBlob(ctx context.Context, cid string) ([]byte, error)
+}
+
+// transientError marks a failure that says nothing about the decision
+// being applied, such as an unavailable store, so it is retried rather than
+// skipped
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+// adjudicationStateKey is where the processor keeps the events it has
+// handled in a state store
+const adjudicationStateKey = "score/adjudications"
+
+// AdjudicationProcessor applies the decisions of adjudication events as
+// they are logged. Only events signed by one of the ruleset's adjudicators
+// are binding, so every node that follows the log derives the same verdicts
+// and applies them at the same checkpoint epoch.
+type AdjudicationProcessor struct {
+	fetcher      AdjudicationFetcher
+	adjudicators map[string]bool
+	bonds        *BondLedger
+	reputation   *IssuerReputationRegistry
+	state        store.StateStore
+	handled      map[string]bool // Event CIDs already applied or rejected
+	mu           sync.Mutex
+}
+
+// NewAdjudicationProcessor creates a processor that accepts decisions
+// signed by the given adjudicator DIDs
+func NewAdjudicationProcessor(fetcher AdjudicationFetcher, adjudicators []string) *AdjudicationProcessor {
+	allowed := make(map[string]bool, len(adjudicators))
+	for _, adjudicator := range adjudicators {
+		allowed[adjudicator] = true
+	}
+
+	return &AdjudicationProcessor{
+		fetcher:      fetcher,
+		adjudicators: allowed,
+		handled:      make(map[string]bool),
+	}
+}
+
+// SetBondLedger applies report and appeal decisions to a bond ledger
+func (p *AdjudicationProcessor) SetBondLedger(ledger *BondLedger) {
+	p.bonds = ledger
+}
+
+// SetIssuerReputation applies issuer decisions to an issuer reputation
+// registry
+func (p *AdjudicationProcessor) SetIssuerReputation(registry *IssuerReputationRegistry) {
+	p.reputation = registry
+}
+
+// SetStateStore loads the events already handled from a state store and
+// records newly handled ones in it, so replaying the log after a restart
+// applies nothing twice
+func (p *AdjudicationProcessor) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), adjudicationStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load adjudications: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if data != nil {
+		var handled []string
+		if err := json.Unmarshal(data, &handled); err != nil {
+			return fmt.Errorf("failed to decode adjudications: %w", err)
+		}
+		for _, cid := range handled {
+			p.handled[cid] = true
+		}
+	}
+
+	p.state = stateStore
+	return nil
+}
+
+// ProcessEvents applies the adjudication events among log entries at a
+// score epoch and returns how many were applied. Events that are unsigned,
+// not from an adjudicator or otherwise invalid are skipped for good; an
+// event that cannot be fetched or applied fails the call so it is retried.
+func (p *AdjudicationProcessor) ProcessEvents(ctx context.Context, refs []log.EventReference, epoch int64) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	applied := 0
+	changed := false
+	for _, ref := range refs {
+		if ref.Type != string(events.EventTypeAdjudication) || p.handled[ref.CID] {
+			continue
+		}
+
+		event, decision, err := p.fetch(ctx, ref)
+		if err != nil {
+			return applied, err
+		}
+		if event != nil {
+			if ok, err := p.apply(ctx, event, decision, epoch); err != nil {
+				return applied, err
+			} else if ok {
+				applied++
+			}
+		}
+
+		p.handled[ref.CID] = true
+		changed = true
+	}
+
+	if changed {
+		if err := p.save(ctx); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+// fetch retrieves and verifies an adjudication event and its decision. A
+// nil event without an error means the event is not a valid adjudication.
+// Content that does not match its CID was served wrongly rather than logged
+// wrongly, so it fails the fetch instead.
+func (p *AdjudicationProcessor) fetch(ctx context.Context, ref log.EventReference) (*events.Event, *AdjudicationDecision, error) {
+	event, err := p.fetcher.GetEvent(ctx, ref.CID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch adjudication %s: %w", ref.CID, err)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode adjudication %s: %w", ref.CID, err)
+	}
+	if cid, err := events.GenerateCIDFromJSON(data); err != nil || cid != ref.CID {
+		return nil, nil, fmt.Errorf("adjudication %s does not match its CID", ref.CID)
+	}
+	if err := p.verifyEvent(event); err != nil {
+		return nil, nil, nil
+	}
+
+	payload, err := p.fetcher.GetBlob(ctx, event.PayloadCID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch decision %s: %w", event.PayloadCID, err)
+	}
+	if cid, err := events.GenerateCID(payload); err != nil || cid != event.PayloadCID {
+		return nil, nil, fmt.Errorf("decision %s does not match its CID", event.PayloadCID)
+	}
+
+	var decision AdjudicationDecision
+	if err := json.Unmarshal(payload, &decision); err != nil || decision.CaseID == "" {
+		return nil, nil, nil
+	}
+	return event, &decision, nil
+}
+
+// verifyEvent checks that an event is an adjudication signed by an
+// adjudicator
+func (p *AdjudicationProcessor) verifyEvent(event *events.Event) error {
+	if event.Type != events.EventTypeAdjudication || event.PayloadCID == "" {
+		return fmt.Errorf("event is not an adjudication")
+	}
+	if !p.adjudicators[event.From] {
+		return fmt.Errorf("%s is not an adjudicator", event.From)
+	}
+
+	publicKey, _, err := did.PublicKeyFromDIDKey(event.From)
+	if err != nil {
+		return fmt.Errorf("failed to resolve adjudicator key: %w", err)
+	}
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("adjudicator key is not Ed25519")
+	}
+	canonical, err := events.CanonicalizeEvent(event.ToSignable())
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(event.Signature)
+	if err != nil || !ed25519.Verify(edKey, canonical, signature) {
+		return fmt.Errorf("invalid adjudication signature")
+	}
+	return nil
+}
+
+// apply records a verified decision. Decisions the registries reject, such
+// as a second verdict on a case, are skipped.
+func (p *AdjudicationProcessor) apply(ctx context.Context, event *events.Event, decision *AdjudicationDecision, epoch int64) (bool, error) {
+	var err error
+	switch decision.Kind {
+	case DecisionReport:
+		report := decision.Report
+		if p.bonds == nil || report == nil || report.CaseID != decision.CaseID || report.ReportedDID != event.To {
+			return false, nil
+		}
+		_, err = p.bonds.RecordVerdict(ctx, report, epoch)
+	case DecisionAppeal:
+		if p.bonds == nil {
+			return false, nil
+		}
+		_, err = p.bonds.RecordAppeal(ctx, decision.CaseID, epoch)
+	case DecisionIssuer:
+		if p.reputation == nil || decision.Issuer == nil || decision.Issuer.IssuerDID != event.To {
+			return false, nil
+		}
+		// The outcome counts from the epoch it was logged in, whatever the
+		// decision claims
+		adjudication := *decision.Issuer
+		adjudication.Epoch = epoch
+		err = p.reputation.RecordAdjudication(ctx, &adjudication)
+	default:
+		return false, nil
+	}
+
+	var transient *transientError
+	if errors.As(err, &transient) {
+		return false, fmt.Errorf("failed to apply adjudication of case %s: %w", decision.CaseID, err)
+	}
+	return err == nil, nil
+}
+
+// save writes the handled events to the state store, if there is one. The
+// caller holds the lock.
+func (p *AdjudicationProcessor) save(ctx context.Context) error {
+	if p.state == nil {
+		return nil
+	}
+
+	handled := make([]string, 0, len(p.handled))
+	for cid := range p.handled {
+		handled = append(handled, cid)
+	}
+	data, err := json.Marshal(handled)
+	if err != nil {
+		return fmt.Errorf("failed to encode adjudications: %w", err)
+	}
+	if err := p.state.PutState(ctx, adjudicationStateKey, data); err != nil {
+		return fmt.Errorf("failed to save adjudications: %w", err)
+	}
+	return nil
+}
+
+// HTTPAdjudicationFetcher reads events and blobs from a full node
+type HTTPAdjudicationFetcher struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPAdjudicationFetcher creates a fetcher for a full node
+func NewHTTPAdjudicationFetcher(baseURL string) *HTTPAdjudicationFetcher {
+	return &HTTPAdjudicationFetcher{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetEvent implements AdjudicationFetcher
+func (f *HTTPAdjudicationFetcher) GetEvent(ctx context.Context, cid string) (*events.Event, error) {
+	var event events.Event
+	if err := getJSON(ctx, f.client, f.baseURL+"/v1/events/"+url.PathEscape(cid), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetBlob implements AdjudicationFetcher
+func (f *HTTPAdjudicationFetcher) GetBlob(ctx context.Context, cid string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, f.baseURL+"/v1/blobs/"+url.PathEscape(cid), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := f.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
@@ -0,0 +1,240 @@
+package score
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/log"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// memoryFullNode serves logged events and blobs by CID
+type memoryFullNode struct {
+	events map[string]*events.Event
+	blobs  map[string][]byte
+}
+
+func newMemoryFullNode() *memoryFullNode {
+	return &memoryFullNode{
+		events: make(map[string]*events.Event),
+		blobs:  make(map[string][]byte),
+	}
+}
+
+func (n *memoryFullNode) GetEvent(ctx context.Context, cid string) (*events.Event, error) {
+	event, exists := n.events[cid]
+	if !exists {
+		return nil, fmt.Errorf("event %s not found", cid)
+	}
+	record := *event
+	return &record, nil
+}
+
+func (n *memoryFullNode) GetBlob(ctx context.Context, cid string) ([]byte, error) {
+	blob, exists := n.blobs[cid]
+	if !exists {
+		return nil, fmt.Errorf("blob %s not found", cid)
+	}
+	return blob, nil
+}
+
+// logAdjudication stores a signed adjudication event and its decision and
+// returns the log entry for it
+func (n *memoryFullNode) logAdjudication(t *testing.T, privateKey interface{}, from, to string, decision *AdjudicationDecision) log.EventReference {
+	payload, err := json.Marshal(decision)
+	if err != nil {
+		t.Fatalf("Failed to encode decision: %v", err)
+	}
+	payloadCID, err := events.GenerateCID(payload)
+	if err != nil {
+		t.Fatalf("Failed to generate payload CID: %v", err)
+	}
+	n.blobs[payloadCID] = payload
+
+	event := &events.Event{
+		Type:       events.EventTypeAdjudication,
+		From:       from,
+		To:         to,
+		Context:    "general",
+		Epoch:      "2024-01",
+		PayloadCID: payloadCID,
+		Nonce:      decision.CaseID + string(decision.Kind),
+		IssuedAt:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	canonical, err := events.CanonicalizeEvent(event.ToSignable())
+	if err != nil {
+		t.Fatalf("Failed to canonicalize event: %v", err)
+	}
+	event.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey.(ed25519.PrivateKey), canonical))
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+	cid, err := events.GenerateCIDFromJSON(data)
+	if err != nil {
+		t.Fatalf("Failed to generate event CID: %v", err)
+	}
+	n.events[cid] = event
+
+	return log.EventReference{CID: cid, Type: string(event.Type), From: from, To: to}
+}
+
+func TestAdjudicationProcessor_Bonds(t *testing.T) {
+	_, provider := newPropagationEngine()
+	ledger := NewBondLedger(provider, &BondConfig{Fraction: 0.5, Window: 3})
+	keyManager := did.NewDefaultKeyManager()
+	adjudicator, adjudicatorKey := newMigrationTestDID(t, keyManager)
+	impostor, impostorKey := newMigrationTestDID(t, keyManager)
+	node := newMemoryFullNode()
+	ctx := context.Background()
+
+	report := &ReportData{
+		CaseID: "case-1", ReporterDID: "did:key:dave", ReportedDID: "did:key:carol",
+		Context: "test_context", Severity: 0.8, Adjudicated: true, Upheld: true, Epoch: 101,
+	}
+	forged := node.logAdjudication(t, impostorKey, impostor, "did:key:carol", &AdjudicationDecision{
+		Kind: DecisionReport, CaseID: "case-1", Report: report,
+	})
+	verdict := node.logAdjudication(t, adjudicatorKey, adjudicator, "did:key:carol", &AdjudicationDecision{
+		Kind: DecisionReport, CaseID: "case-1", Report: report,
+	})
+	// Signed by another key in the adjudicator's name
+	missigned := node.logAdjudication(t, impostorKey, adjudicator, "did:key:bob", &AdjudicationDecision{
+		Kind: DecisionReport, CaseID: "case-2", Report: &ReportData{
+			CaseID: "case-2", ReportedDID: "did:key:bob", Context: "test_context",
+			Adjudicated: true, Upheld: true, Epoch: 101,
+		},
+	})
+
+	processor := NewAdjudicationProcessor(node, []string{adjudicator})
+	processor.SetBondLedger(ledger)
+
+	// Served content must match the logged CID
+	node.events[verdict.CID].Nonce = "replayed"
+	if _, err := processor.ProcessEvents(ctx, []log.EventReference{verdict}, 101); err == nil {
+		t.Error("Expected an event that does not match its CID to fail")
+	}
+	node.events[verdict.CID].Nonce = "case-1report"
+
+	refs := []log.EventReference{{CID: "bafyvouch", Type: string(events.EventTypeVouch)}, forged, verdict, missigned}
+	applied, err := processor.ProcessEvents(ctx, refs, 101)
+	if err != nil {
+		t.Fatalf("ProcessEvents failed: %v", err)
+	}
+	if applied != 1 {
+		t.Fatalf("Expected only the adjudicator's verdict to apply, applied %d", applied)
+	}
+	bonds := ledger.Bonds("did:key:alice", "test_context")
+	if len(bonds) != 1 || bonds[0].CaseID != "case-1" || bonds[0].SlashedEpoch != 101 {
+		t.Fatalf("Expected alice's bond slashed by case-1, got %+v", bonds)
+	}
+	if applied, err := processor.ProcessEvents(ctx, refs, 102); err != nil || applied != 0 {
+		t.Errorf("Expected replayed events to apply nothing, got %d, %v", applied, err)
+	}
+
+	appeal := node.logAdjudication(t, adjudicatorKey, adjudicator, "did:key:carol", &AdjudicationDecision{
+		Kind: DecisionAppeal, CaseID: "case-1",
+	})
+	if applied, err := processor.ProcessEvents(ctx, []log.EventReference{appeal}, 103); err != nil || applied != 1 {
+		t.Fatalf("Expected the appeal to apply, got %d, %v", applied, err)
+	}
+	if bonds := ledger.Bonds("did:key:alice", "test_context"); bonds[0].Status != BondReinstated || bonds[0].ReinstatedEpoch != 103 {
+		t.Errorf("Expected alice's bond reinstated, got %+v", bonds[0])
+	}
+}
+
+func TestAdjudicationProcessor_Persistence(t *testing.T) {
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	_, provider := newPropagationEngine()
+	keyManager := did.NewDefaultKeyManager()
+	adjudicator, adjudicatorKey := newMigrationTestDID(t, keyManager)
+	node := newMemoryFullNode()
+	ctx := context.Background()
+
+	refs := []log.EventReference{
+		node.logAdjudication(t, adjudicatorKey, adjudicator, "did:key:carol", &AdjudicationDecision{
+			Kind: DecisionReport, CaseID: "case-1", Report: &ReportData{
+				CaseID: "case-1", ReportedDID: "did:key:carol", Context: "test_context",
+				Adjudicated: true, Upheld: true, Epoch: 101,
+			},
+		}),
+	}
+
+	// A restarted node replays the log from the start
+	for run := 0; run < 2; run++ {
+		ledger := NewBondLedger(provider, &BondConfig{Fraction: 0.5, Window: 3})
+		if err := ledger.SetStateStore(stateStore); err != nil {
+			t.Fatalf("SetStateStore failed: %v", err)
+		}
+		processor := NewAdjudicationProcessor(node, []string{adjudicator})
+		processor.SetBondLedger(ledger)
+		if err := processor.SetStateStore(stateStore); err != nil {
+			t.Fatalf("SetStateStore failed: %v", err)
+		}
+
+		applied, err := processor.ProcessEvents(ctx, refs, 101+int64(run))
+		if err != nil {
+			t.Fatalf("ProcessEvents failed: %v", err)
+		}
+		if expected := 1 - run; applied != expected {
+			t.Errorf("Run %d: expected %d adjudications applied, got %d", run, expected, applied)
+		}
+		bonds, err := ledger.SlashedBonds(ctx, "did:key:alice", "test_context", 102)
+		if err != nil || len(bonds) != 1 || bonds[0].SlashedEpoch != 101 {
+			t.Errorf("Run %d: expected the slash from epoch 101, got %+v, %v", run, bonds, err)
+		}
+	}
+}
+
+func TestAdjudicationProcessor_IssuerReputation(t *testing.T) {
+	registry := NewIssuerReputationRegistry(nil)
+	keyManager := did.NewDefaultKeyManager()
+	adjudicator, adjudicatorKey := newMigrationTestDID(t, keyManager)
+	node := newMemoryFullNode()
+	ctx := context.Background()
+
+	outcome := &IssuerAdjudication{
+		IssuerDID: "did:key:mill", CredentialID: "kyc-1", Outcome: AdjudicationFalsePositive, Epoch: 1,
+	}
+	refs := []log.EventReference{
+		// The event must name the issuer it rules on
+		node.logAdjudication(t, adjudicatorKey, adjudicator, "did:key:bank", &AdjudicationDecision{
+			Kind: DecisionIssuer, CaseID: "case-9", Issuer: outcome,
+		}),
+		node.logAdjudication(t, adjudicatorKey, adjudicator, "did:key:mill", &AdjudicationDecision{
+			Kind: DecisionIssuer, CaseID: "case-1", Issuer: outcome,
+		}),
+	}
+
+	processor := NewAdjudicationProcessor(node, []string{adjudicator})
+	processor.SetIssuerReputation(registry)
+	if applied, err := processor.ProcessEvents(ctx, refs, 50); err != nil || applied != 1 {
+		t.Fatalf("Expected one issuer decision to apply, got %d, %v", applied, err)
+	}
+
+	history := registry.History("did:key:mill")
+	if len(history) != 1 || history[0].Epoch != 50 {
+		t.Fatalf("Expected the outcome at the checkpoint epoch, got %+v", history)
+	}
+	if len(registry.History("did:key:bank")) != 0 {
+		t.Error("Expected no outcome for an issuer the event does not name")
+	}
+	if weight := registry.Preview(51).Weight("did:key:mill"); weight >= DefaultIssuerReputationConfig().InitialWeight {
+		t.Errorf("Expected the false positive to lower the weight, got %f", weight)
+	}
+}
@@ -0,0 +1,346 @@
+package score
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// BondStatus is the state of the reputational bond behind a vouch
+type BondStatus string
+
+const (
+	// BondSlashed means the vouchee was found abusive within the bond window
+	BondSlashed BondStatus = "slashed"
+	// BondReinstated means the verdict that slashed the bond was overturned
+	// on appeal
+	BondReinstated BondStatus = "reinstated"
+)
+
+// VouchBond is the implicit bond a voucher posts with a vouch. Only bonds
+// that an upheld report has reached are tracked; every other vouch is
+// implicitly in good standing.
+type VouchBond struct {
+	VoucherDID      string     `json:"voucher_did"`
+	VoucheeDID      string     `json:"vouchee_did"`
+	Context         string     `json:"context"`
+	VouchEpoch      int64      `json:"vouch_epoch"`
+	Status          BondStatus `json:"status"`
+	CaseID          string     `json:"case_id"`
+	Fraction        float64    `json:"fraction"`      // ε in force when slashed
+	SlashedEpoch    int64      `json:"slashed_epoch"` // Epoch of the verdict
+	ReinstatedEpoch int64      `json:"reinstated_epoch,omitempty"`
+}
+
+// activeAt reports whether the slash counts against scores for an epoch.
+// Like issuer adjudications, verdicts and appeals apply from the epoch after
+// they are recorded in.
+func (b *VouchBond) activeAt(epoch int64) bool {
+	if b.SlashedEpoch >= epoch {
+		return false
+	}
+	return b.Status != BondReinstated || b.ReinstatedEpoch >= epoch
+}
+
+// BondConfig controls how vouchers answer for their vouchees
+type BondConfig struct {
+	Fraction float64 `json:"fraction"` // ε: share of the voucher's V forfeited per slashed bond
+	Window   int64   `json:"window"`   // W: epochs after a vouch in which abuse slashes its bond
+}
+
+// DefaultBondConfig returns the bond parameters of the default ruleset
+func DefaultBondConfig() *BondConfig {
+	return BondConfigFromRuleSet(consensus.DefaultRuleSet())
+}
+
+// BondConfigFromRuleSet takes ε from the ruleset's vouch bond slash and W
+// from its dispute window, rounded up to whole epochs
+func BondConfigFromRuleSet(ruleSet *consensus.RuleSet) *BondConfig {
+	epochLength := 24 * time.Hour
+	return &BondConfig{
+		Fraction: ruleSet.VouchBondSlash,
+		Window:   max(int64((ruleSet.DisputeWindow+epochLength-1)/epochLength), 1),
+	}
+}
+
+// BondProvider supplies the slashed bonds that reduce a voucher's V
+type BondProvider interface {
+	// SlashedBonds returns the voucher's bonds slashed as of an epoch
+	SlashedBonds(ctx context.Context, voucherDID, context string, epoch int64) ([]*VouchBond, error)
+}
+
+// BondLedger links upheld reports back to the vouches made for the reported
+// DID and tracks the bonds they slash. Each case keeps its own slashes, so a
+// vouch reached by several cases stays slashed until all of them are
+// overturned, and forfeits V once. Like the issuer reputation registry, an
+// epoch's slashes are fixed once it has been read.
+type BondLedger struct {
+	dataProvider DataProvider
+	config       *BondConfig
+	byVoucher    map[string][]*VouchBond // voucher|context -> bonds
+	cases        map[string][]*VouchBond // case -> bonds it slashed
+	sealed       int64
+	state        store.StateStore
+	mu           sync.RWMutex
+}
+
+// bondLedgerStateKey is where the ledger keeps its cases in a state store
+const bondLedgerStateKey = "score/bonds"
+
+// bondLedgerState is the persisted form of a bond ledger. The bond index is
+// rebuilt from the cases.
+type bondLedgerState struct {
+	Cases  map[string][]*VouchBond `json:"cases"`
+	Sealed int64                   `json:"sealed"`
+}
+
+// NewBondLedger creates a bond ledger that finds vouchers through the data
+// provider
+func NewBondLedger(dataProvider DataProvider, config *BondConfig) *BondLedger {
+	if config == nil {
+		config = DefaultBondConfig()
+	}
+
+	return &BondLedger{
+		dataProvider: dataProvider,
+		config:       config,
+		byVoucher:    make(map[string][]*VouchBond),
+		cases:        make(map[string][]*VouchBond),
+		sealed:       math.MinInt64,
+	}
+}
+
+// SetStateStore loads the ledger from a state store and saves every
+// verdict and appeal to it from then on
+func (l *BondLedger) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), bondLedgerStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load bond ledger: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if data != nil {
+		var state bondLedgerState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode bond ledger: %w", err)
+		}
+		caseIDs := make([]string, 0, len(state.Cases))
+		for caseID := range state.Cases {
+			caseIDs = append(caseIDs, caseID)
+		}
+		sort.Strings(caseIDs)
+
+		for _, caseID := range caseIDs {
+			bonds := state.Cases[caseID]
+			for _, bond := range bonds {
+				voucherKey := bond.VoucherDID + "|" + bond.Context
+				l.byVoucher[voucherKey] = append(l.byVoucher[voucherKey], bond)
+			}
+			l.cases[caseID] = bonds
+		}
+		if state.Sealed > l.sealed {
+			l.sealed = state.Sealed
+		}
+	}
+
+	l.state = stateStore
+	return nil
+}
+
+// save writes the ledger to its state store, if it has one. The caller
+// holds the lock.
+func (l *BondLedger) save(ctx context.Context) error {
+	if l.state == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&bondLedgerState{Cases: l.cases, Sealed: l.sealed})
+	if err != nil {
+		return fmt.Errorf("failed to encode bond ledger: %w", err)
+	}
+	if err := l.state.PutState(ctx, bondLedgerStateKey, data); err != nil {
+		return &transientError{fmt.Errorf("failed to save bond ledger: %w", err)}
+	}
+	return nil
+}
+
+// RecordVerdict slashes the bond of every vouch for the reported DID made
+// within the window before the report, taking effect after the given epoch.
+// Reports that were not upheld slash nothing. A vouch already slashed by an
+// earlier case is slashed for this case too, so overturning either case
+// leaves the other's slash in force.
+func (l *BondLedger) RecordVerdict(ctx context.Context, report *ReportData, epoch int64) ([]*VouchBond, error) {
+	if report.CaseID == "" || report.ReportedDID == "" {
+		return nil, fmt.Errorf("verdict requires a case and a reported DID")
+	}
+	if !report.Adjudicated || !report.Upheld {
+		return nil, nil
+	}
+	if epoch < report.Epoch {
+		return nil, fmt.Errorf("verdict epoch %d is before report epoch %d", epoch, report.Epoch)
+	}
+
+	vouches, err := l.dataProvider.GetVouches(ctx, report.ReportedDID, report.Context, report.Epoch)
+	if err != nil {
+		return nil, &transientError{fmt.Errorf("failed to get vouches for %s: %w", report.ReportedDID, err)}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch < l.sealed {
+		return nil, fmt.Errorf("epoch %d is before sealed epoch %d", epoch, l.sealed)
+	}
+	if _, exists := l.cases[report.CaseID]; exists {
+		return nil, fmt.Errorf("case %s already recorded", report.CaseID)
+	}
+
+	// The latest vouch per voucher decides whether its bond was at risk
+	latest := make(map[string]*VouchData)
+	for _, vouch := range vouches {
+		age := report.Epoch - vouch.Epoch
+		if age < 0 || age > l.config.Window {
+			continue
+		}
+		if current := latest[vouch.FromDID]; current == nil || vouch.Epoch > current.Epoch {
+			latest[vouch.FromDID] = vouch
+		}
+	}
+	vouchers := make([]string, 0, len(latest))
+	for voucher := range latest {
+		vouchers = append(vouchers, voucher)
+	}
+	sort.Strings(vouchers)
+
+	slashed := []*VouchBond{}
+	for _, voucher := range vouchers {
+		bond := &VouchBond{
+			VoucherDID:   voucher,
+			VoucheeDID:   report.ReportedDID,
+			Context:      report.Context,
+			VouchEpoch:   latest[voucher].Epoch,
+			Status:       BondSlashed,
+			CaseID:       report.CaseID,
+			Fraction:     l.config.Fraction,
+			SlashedEpoch: epoch,
+		}
+		voucherKey := voucher + "|" + report.Context
+		l.byVoucher[voucherKey] = append(l.byVoucher[voucherKey], bond)
+		slashed = append(slashed, bond)
+	}
+	l.cases[report.CaseID] = slashed
+	if err := l.save(ctx); err != nil {
+		return nil, err
+	}
+
+	return copyBonds(slashed), nil
+}
+
+// RecordAppeal reinstates the bonds a case slashed after its verdict was
+// overturned, taking effect after the given epoch
+func (l *BondLedger) RecordAppeal(ctx context.Context, caseID string, epoch int64) ([]*VouchBond, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bonds, exists := l.cases[caseID]
+	if !exists {
+		return nil, fmt.Errorf("unknown case %s", caseID)
+	}
+	if epoch < l.sealed {
+		return nil, fmt.Errorf("epoch %d is before sealed epoch %d", epoch, l.sealed)
+	}
+
+	for _, bond := range bonds {
+		if bond.Status == BondReinstated {
+			return nil, fmt.Errorf("case %s already overturned", caseID)
+		}
+		if epoch < bond.SlashedEpoch {
+			return nil, fmt.Errorf("appeal epoch %d is before verdict epoch %d", epoch, bond.SlashedEpoch)
+		}
+	}
+	for _, bond := range bonds {
+		bond.Status = BondReinstated
+		bond.ReinstatedEpoch = epoch
+	}
+	if err := l.save(ctx); err != nil {
+		return nil, err
+	}
+
+	return copyBonds(bonds), nil
+}
+
+// SlashedBonds implements BondProvider and seals the epoch
+func (l *BondLedger) SlashedBonds(ctx context.Context, voucherDID, context string, epoch int64) ([]*VouchBond, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch > l.sealed {
+		l.sealed = epoch
+	}
+	return l.slashedAt(voucherDID, context, epoch), nil
+}
+
+// PreviewSlashedBonds returns the bonds SlashedBonds would for an epoch
+// without sealing it, so later verdicts and appeals may still change them
+func (l *BondLedger) PreviewSlashedBonds(ctx context.Context, voucherDID, context string, epoch int64) ([]*VouchBond, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.slashedAt(voucherDID, context, epoch), nil
+}
+
+// slashedAt copies the voucher's bonds slashed at an epoch, one per
+// vouchee: when several cases slashed the same vouch, the earliest verdict
+// in force stands for them. The caller holds the lock.
+func (l *BondLedger) slashedAt(voucherDID, context string, epoch int64) []*VouchBond {
+	byVouchee := make(map[string]*VouchBond)
+	for _, bond := range l.byVoucher[voucherDID+"|"+context] {
+		if !bond.activeAt(epoch) {
+			continue
+		}
+		current := byVouchee[bond.VoucheeDID]
+		if current == nil || bond.SlashedEpoch < current.SlashedEpoch ||
+			(bond.SlashedEpoch == current.SlashedEpoch && bond.CaseID < current.CaseID) {
+			byVouchee[bond.VoucheeDID] = bond
+		}
+	}
+
+	slashed := make([]*VouchBond, 0, len(byVouchee))
+	for _, bond := range byVouchee {
+		slashed = append(slashed, bond)
+	}
+	return copyBonds(slashed)
+}
+
+// Bonds returns every tracked bond of a voucher in a context
+func (l *BondLedger) Bonds(voucherDID, context string) []*VouchBond {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return copyBonds(l.byVoucher[voucherDID+"|"+context])
+}
+
+// copyBonds copies bonds out of the ledger in vouchee order
+func copyBonds(bonds []*VouchBond) []*VouchBond {
+	result := make([]*VouchBond, len(bonds))
+	for i, bond := range bonds {
+		record := *bond
+		result[i] = &record
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].VoucheeDID != result[j].VoucheeDID {
+			return result[i].VoucheeDID < result[j].VoucheeDID
+		}
+		return result[i].VoucherDID < result[j].VoucherDID
+	})
+	return result
+}
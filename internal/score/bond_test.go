@@ -0,0 +1,146 @@
+package score
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+)
+
+func TestBondConfigFromRuleSet(t *testing.T) {
+	config := BondConfigFromRuleSet(consensus.DefaultRuleSet())
+	if config.Fraction != 0.05 || config.Window != 1 {
+		t.Errorf("Expected ε = 0.05 over one epoch, got %+v", config)
+	}
+}
+
+func TestBondLedger_SlashAndAppeal(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	ledger := NewBondLedger(provider, &BondConfig{Fraction: 0.5, Window: 3})
+	ctx := context.Background()
+
+	// alice vouched for carol one epoch before the report
+	slashed, err := ledger.RecordVerdict(ctx, &ReportData{
+		CaseID: "case-1", ReporterDID: "did:key:dave", ReportedDID: "did:key:carol",
+		Context: "test_context", Severity: 0.8, Adjudicated: true, Upheld: true, Epoch: 101,
+	}, 101)
+	if err != nil {
+		t.Fatalf("RecordVerdict failed: %v", err)
+	}
+	if len(slashed) != 1 || slashed[0].VoucherDID != "did:key:alice" || slashed[0].VouchEpoch != 100 {
+		t.Fatalf("Expected alice's bond to be slashed, got %+v", slashed)
+	}
+
+	// bob's vouchers vouched too long before the report
+	if slashed, err := ledger.RecordVerdict(ctx, &ReportData{
+		CaseID: "case-2", ReportedDID: "did:key:bob", Context: "test_context",
+		Adjudicated: true, Upheld: true, Epoch: 110,
+	}, 110); err != nil || len(slashed) != 0 {
+		t.Errorf("Expected no bonds at risk, got %+v, %v", slashed, err)
+	}
+	if slashed, err := ledger.RecordVerdict(ctx, &ReportData{
+		CaseID: "case-3", ReportedDID: "did:key:carol", Context: "test_context",
+		Adjudicated: true, Upheld: false, Epoch: 101,
+	}, 101); err != nil || slashed != nil {
+		t.Errorf("Expected a dismissed report to slash nothing, got %+v, %v", slashed, err)
+	}
+	if _, err := ledger.RecordVerdict(ctx, &ReportData{
+		CaseID: "case-1", ReportedDID: "did:key:carol", Context: "test_context", Adjudicated: true, Upheld: true,
+	}, 101); err == nil {
+		t.Error("Expected a case to be recorded once")
+	}
+
+	// The slash applies from the epoch after the verdict
+	if bonds, _ := ledger.SlashedBonds(ctx, "did:key:alice", "test_context", 101); len(bonds) != 0 {
+		t.Errorf("Expected no slash in the verdict epoch, got %+v", bonds)
+	}
+	engine.SetBondProvider(ledger)
+	snapshot, err := engine.solve(ctx, "test_context", 102, []string{"did:key:alice"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	alice := snapshot.Scores["did:key:alice"].Components
+	if len(alice.SlashedBonds) != 1 || alice.SlashedBonds[0] != "did:key:carol" {
+		t.Fatalf("Expected carol's bond in alice's breakdown, got %v", alice.SlashedBonds)
+	}
+	if math.Abs(alice.V-(alice.V+alice.BondSlash)*0.5) > 1e-6 || alice.BondSlash <= 0 {
+		t.Errorf("Expected half of V forfeited, got V %f and slash %f", alice.V, alice.BondSlash)
+	}
+	if bob := snapshot.Scores["did:key:bob"].Components; bob.BondSlash != 0 {
+		t.Errorf("Expected bob's bonds to be intact, got %f", bob.BondSlash)
+	}
+
+	// A successful appeal reinstates the bond from the next epoch
+	if _, err := ledger.RecordAppeal(ctx, "case-1", 101); err == nil {
+		t.Error("Expected an appeal before the sealed epoch to be rejected")
+	}
+	reinstated, err := ledger.RecordAppeal(ctx, "case-1", 103)
+	if err != nil {
+		t.Fatalf("RecordAppeal failed: %v", err)
+	}
+	if len(reinstated) != 1 || reinstated[0].Status != BondReinstated {
+		t.Errorf("Expected alice's bond reinstated, got %+v", reinstated)
+	}
+	if _, err := ledger.RecordAppeal(ctx, "case-1", 103); err == nil {
+		t.Error("Expected a case to be overturned once")
+	}
+	if bonds, _ := ledger.SlashedBonds(ctx, "did:key:alice", "test_context", 103); len(bonds) != 1 {
+		t.Errorf("Expected the slash to hold in the appeal epoch, got %+v", bonds)
+	}
+	snapshot, err = engine.solve(ctx, "test_context", 104, []string{"did:key:alice"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	if alice := snapshot.Scores["did:key:alice"].Components; alice.BondSlash != 0 || alice.SlashedBonds != nil {
+		t.Errorf("Expected the slash reversed, got %+v", alice)
+	}
+	if bonds := ledger.Bonds("did:key:alice", "test_context"); len(bonds) != 1 || bonds[0].ReinstatedEpoch != 103 {
+		t.Errorf("Expected the reinstated bond to stay on record, got %+v", bonds)
+	}
+}
+
+func TestBondLedger_OverlappingCases(t *testing.T) {
+	_, provider := newPropagationEngine()
+	ledger := NewBondLedger(provider, &BondConfig{Fraction: 0.5, Window: 3})
+	ctx := context.Background()
+
+	// Two cases reach alice's vouch for carol
+	for _, verdict := range []struct {
+		caseID string
+		epoch  int64
+	}{{"case-1", 101}, {"case-2", 102}} {
+		slashed, err := ledger.RecordVerdict(ctx, &ReportData{
+			CaseID: verdict.caseID, ReportedDID: "did:key:carol", Context: "test_context",
+			Adjudicated: true, Upheld: true, Epoch: verdict.epoch,
+		}, verdict.epoch)
+		if err != nil {
+			t.Fatalf("RecordVerdict %s failed: %v", verdict.caseID, err)
+		}
+		if len(slashed) != 1 || slashed[0].CaseID != verdict.caseID {
+			t.Fatalf("Expected %s to slash alice's bond, got %+v", verdict.caseID, slashed)
+		}
+	}
+
+	// The vouch forfeits once however many cases reached it
+	bonds, _ := ledger.PreviewSlashedBonds(ctx, "did:key:alice", "test_context", 103)
+	if len(bonds) != 1 || bonds[0].CaseID != "case-1" {
+		t.Fatalf("Expected one slash from the earlier case, got %+v", bonds)
+	}
+
+	// Overturning the earlier case leaves the later one in force
+	if _, err := ledger.RecordAppeal(ctx, "case-1", 103); err != nil {
+		t.Fatalf("RecordAppeal failed: %v", err)
+	}
+	bonds, _ = ledger.PreviewSlashedBonds(ctx, "did:key:alice", "test_context", 104)
+	if len(bonds) != 1 || bonds[0].CaseID != "case-2" {
+		t.Fatalf("Expected the later case's slash to stand, got %+v", bonds)
+	}
+
+	if _, err := ledger.RecordAppeal(ctx, "case-2", 104); err != nil {
+		t.Fatalf("RecordAppeal failed: %v", err)
+	}
+	if bonds, _ := ledger.PreviewSlashedBonds(ctx, "did:key:alice", "test_context", 105); len(bonds) != 0 {
+		t.Errorf("Expected no slash once both cases are overturned, got %+v", bonds)
+	}
+}
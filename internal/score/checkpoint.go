@@ -0,0 +1,846 @@
+package score
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/log"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// checkpointRecomputerStateKey is the state store key holding the last
+// applied checkpoint, its tree and the freshness markers
+const checkpointRecomputerStateKey = "score/recomputer"
+
+// CheckpointMarker ties a score to the log checkpoint whose events it
+// includes
+type CheckpointMarker struct {
+	Epoch    int64  `json:"epoch"`     // Checkpoint epoch
+	Root     string `json:"root"`      // Hex log root
+	TreeSize int64  `json:"tree_size"` // Log entries covered
+}
+
+// ScoreFreshness is published once a checkpoint's events are reflected in
+// a context's scores
+type ScoreFreshness struct {
+	Context         string           `json:"context"`
+	Epoch           int64            `json:"epoch"` // Score epoch
+	Checkpoint      CheckpointMarker `json:"checkpoint"`
+	SnapshotVersion string           `json:"snapshot_version"`
+	Events          int              `json:"events"`     // Log entries since the previous checkpoint
+	Recomputed      int              `json:"recomputed"` // DIDs re-solved
+	CheckpointTime  time.Time        `json:"checkpoint_time"`
+	CompletedAt     time.Time        `json:"completed_at"`
+	LatencyMS       int64            `json:"latency_ms"` // From checkpoint to completion
+}
+
+// RecomputeStats summarizes how quickly checkpoints are reflected in scores
+type RecomputeStats struct {
+	Checkpoints   int    `json:"checkpoints"`
+	LastLatencyMS int64  `json:"last_latency_ms"`
+	P95LatencyMS  int64  `json:"p95_latency_ms"` // Over the latency window
+	Failures      int    `json:"failures"`
+	LastError     string `json:"last_error,omitempty"` // Why the latest failed checkpoint was not applied
+}
+
+// CheckpointSource delivers checkpoints as they are finalized.
+// consensus.P2PPublisher satisfies it.
+type CheckpointSource interface {
+	SubscribeCheckpoints(ctx context.Context) (<-chan *consensus.Checkpoint, error)
+}
+
+// CheckpointEventSource reads the log a checkpoint commits to. Nothing it
+// returns is trusted: leaves and proofs are checked against signed roots.
+type CheckpointEventSource interface {
+	// LeavesBetween returns the leaves between two tree sizes, fromSize
+	// inclusive and toSize exclusive
+	LeavesBetween(ctx context.Context, fromSize, toSize int64) ([]log.Leaf, error)
+
+	// ConsistencyProof proves that the tree of fromSize leaves is a prefix
+	// of the tree of toSize leaves
+	ConsistencyProof(ctx context.Context, fromSize, toSize int64) (*log.ConsistencyProof, error)
+}
+
+// VoucheeProvider is implemented by data providers that can list who a DID
+// vouches for. Without it the recomputer derives vouchees from the vouches
+// of the DIDs already in the snapshot.
+type VoucheeProvider interface {
+	GetVouchees(ctx context.Context, did, context string, maxEpoch int64) ([]string, error)
+}
+
+// FreshnessPublisher announces freshness markers, e.g. over gossip
+type FreshnessPublisher interface {
+	PublishFreshness(ctx context.Context, freshness *ScoreFreshness) error
+}
+
+// CheckpointRecomputerConfig configures checkpoint-driven recomputation
+type CheckpointRecomputerConfig struct {
+	Contexts      []string      `json:"contexts"`       // Contexts kept fresh
+	Deadline      time.Duration `json:"deadline"`       // Budget for applying one checkpoint
+	LatencyWindow int           `json:"latency_window"` // Checkpoints the P95 is taken over
+	MaxClockSkew  time.Duration `json:"max_clock_skew"` // How far ahead of the local clock a checkpoint may be timestamped
+
+	// EpochOf maps a checkpoint to the score epoch it updates. The default
+	// is the day of the checkpoint timestamp, as the HTTP service uses.
+	EpochOf func(checkpoint *consensus.Checkpoint) int64 `json:"-"`
+
+	// Logf reports checkpoints that could not be applied, if set
+	Logf func(format string, args ...interface{}) `json:"-"`
+}
+
+// DefaultCheckpointRecomputerConfig returns the default configuration, sized
+// for the P95 < 5 min post-checkpoint target
+func DefaultCheckpointRecomputerConfig() *CheckpointRecomputerConfig {
+	return &CheckpointRecomputerConfig{
+		Contexts:      []string{"default"},
+		Deadline:      5 * time.Minute,
+		LatencyWindow: 100,
+		MaxClockSkew:  5 * time.Minute,
+	}
+}
+
+// CheckpointRecomputer keeps epoch snapshots current with the log. Each
+// checkpoint must carry the committee's threshold signature and extend the
+// last applied tree, and the leaves read for it must hash to its root. For
+// each checkpoint it reads the events since the previous one, marks their DIDs
+// and everyone downstream of them through vouches dirty, and re-solves only
+// those against the snapshot. Clean DIDs are held at their snapshot scores,
+// so the result matches a full solve to within the propagation tolerance,
+// and exactly when the tolerance is zero.
+type CheckpointRecomputer struct {
+	engine        *DeterministicEngine
+	events        CheckpointEventSource
+	verifier      consensus.CheckpointVerifier
+	publisher     FreshnessPublisher
+	adjudications *AdjudicationProcessor
+	config        *CheckpointRecomputerConfig
+
+	last       *consensus.Checkpoint
+	frontier   *log.Frontier              // Tree of the last applied checkpoint
+	freshness  map[string]*ScoreFreshness // context -> latest marker
+	state      store.StateStore
+	latencies  []time.Duration
+	processed  int
+	failures   int
+	lastError  string
+	mu         sync.RWMutex
+	processing sync.Mutex
+}
+
+// NewCheckpointRecomputer creates a recomputer over an engine that applies
+// checkpoints the verifier accepts. A nil config uses
+// DefaultCheckpointRecomputerConfig.
+func NewCheckpointRecomputer(engine *DeterministicEngine, events CheckpointEventSource, verifier consensus.CheckpointVerifier, config *CheckpointRecomputerConfig) *CheckpointRecomputer {
+	if config == nil {
+		config = DefaultCheckpointRecomputerConfig()
+	}
+	return &CheckpointRecomputer{
+		engine:    engine,
+		events:    events,
+		verifier:  verifier,
+		config:    config,
+		frontier:  &log.Frontier{},
+		freshness: make(map[string]*ScoreFreshness),
+	}
+}
+
+// recomputerState is what a recomputer keeps across restarts
+type recomputerState struct {
+	Checkpoint *consensus.Checkpoint      `json:"checkpoint"`
+	Frontier   *log.Frontier              `json:"frontier"`
+	Freshness  map[string]*ScoreFreshness `json:"freshness"`
+}
+
+// SetStateStore restores the last applied checkpoint from a state store and
+// records each new one in it, so a restart resumes from that checkpoint
+// instead of replaying the log from the start
+func (r *CheckpointRecomputer) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), checkpointRecomputerStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load checkpoint state: %w", err)
+	}
+
+	r.processing.Lock()
+	defer r.processing.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if data != nil {
+		var state recomputerState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode checkpoint state: %w", err)
+		}
+		if state.Checkpoint == nil || state.Frontier == nil {
+			return fmt.Errorf("stored checkpoint state is incomplete")
+		}
+		if r.verifier != nil {
+			if err := r.verifier.VerifyCheckpoint(state.Checkpoint); err != nil {
+				return fmt.Errorf("stored checkpoint %d: %w", state.Checkpoint.Epoch, err)
+			}
+		}
+		if state.Frontier.Size() != state.Checkpoint.TreeSize || !bytes.Equal(state.Frontier.Root(), state.Checkpoint.Root) {
+			return fmt.Errorf("stored tree does not match checkpoint %d", state.Checkpoint.Epoch)
+		}
+
+		r.last = state.Checkpoint
+		r.frontier = state.Frontier
+		if state.Freshness != nil {
+			r.freshness = state.Freshness
+		}
+	}
+
+	r.state = stateStore
+	return nil
+}
+
+func (r *CheckpointRecomputer) save(ctx context.Context, checkpoint *consensus.Checkpoint, frontier *log.Frontier, freshness map[string]*ScoreFreshness) error {
+	if r.state == nil {
+		return nil
+	}
+	data, err := json.Marshal(&recomputerState{Checkpoint: checkpoint, Frontier: frontier, Freshness: freshness})
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint state: %w", err)
+	}
+	if err := r.state.PutState(ctx, checkpointRecomputerStateKey, data); err != nil {
+		return fmt.Errorf("failed to save checkpoint state: %w", err)
+	}
+	return nil
+}
+
+// SetPublisher announces freshness markers after each checkpoint
+func (r *CheckpointRecomputer) SetPublisher(publisher FreshnessPublisher) {
+	r.publisher = publisher
+}
+
+// SetAdjudicationProcessor applies the adjudications among each
+// checkpoint's events at the checkpoint's score epoch
+func (r *CheckpointRecomputer) SetAdjudicationProcessor(processor *AdjudicationProcessor) {
+	r.adjudications = processor
+}
+
+// Run applies checkpoints from the source until the context is cancelled
+// or the subscription ends. A checkpoint that fails is retried as part of
+// the next one, since events are read from the last applied checkpoint.
+func (r *CheckpointRecomputer) Run(ctx context.Context, source CheckpointSource) error {
+	checkpoints, err := source.SubscribeCheckpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to checkpoints: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case checkpoint, ok := <-checkpoints:
+			if !ok {
+				return nil
+			}
+			if _, err := r.ProcessCheckpoint(ctx, checkpoint); err != nil {
+				r.mu.Lock()
+				r.failures++
+				r.lastError = err.Error()
+				r.mu.Unlock()
+				if r.config.Logf != nil {
+					r.config.Logf("Failed to apply checkpoint %d: %v", checkpoint.Epoch, err)
+				}
+			}
+		}
+	}
+}
+
+// ProcessCheckpoint brings every configured context up to a checkpoint and
+// returns the freshness markers. Checkpoints no newer than the last one
+// applied are ignored, and checkpoints that fail verification are rejected
+// before anything is read for them.
+func (r *CheckpointRecomputer) ProcessCheckpoint(ctx context.Context, checkpoint *consensus.Checkpoint) ([]*ScoreFreshness, error) {
+	r.processing.Lock()
+	defer r.processing.Unlock()
+
+	received := time.Now()
+	if r.verifier == nil {
+		return nil, fmt.Errorf("no checkpoint verifier configured")
+	}
+	if err := r.verifier.VerifyCheckpoint(checkpoint); err != nil {
+		return nil, fmt.Errorf("checkpoint %d rejected: %w", checkpoint.Epoch, err)
+	}
+	// The timestamp picks the score epoch, so it may not run ahead
+	if checkpoint.Timestamp.After(received.Add(r.config.MaxClockSkew)) {
+		return nil, fmt.Errorf("checkpoint %d is timestamped in the future", checkpoint.Epoch)
+	}
+
+	r.mu.RLock()
+	last := r.last
+	frontier := r.frontier.Clone()
+	r.mu.RUnlock()
+	if last != nil && checkpoint.Epoch <= last.Epoch {
+		return nil, nil
+	}
+
+	fromSize := frontier.Size()
+	if checkpoint.TreeSize < fromSize {
+		return nil, fmt.Errorf("checkpoint %d shrinks the log from %d to %d entries",
+			checkpoint.Epoch, fromSize, checkpoint.TreeSize)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Deadline)
+	defer cancel()
+
+	if last != nil {
+		proof, err := r.events.ConsistencyProof(ctx, fromSize, checkpoint.TreeSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch consistency proof for checkpoint %d: %w", checkpoint.Epoch, err)
+		}
+		if proof.FirstTreeSize != fromSize || proof.SecondTreeSize != checkpoint.TreeSize {
+			return nil, fmt.Errorf("consistency proof for checkpoint %d covers the wrong trees", checkpoint.Epoch)
+		}
+		if err := log.VerifyConsistency(proof, last.Root, checkpoint.Root); err != nil {
+			return nil, fmt.Errorf("checkpoint %d does not extend checkpoint %d: %w", checkpoint.Epoch, last.Epoch, err)
+		}
+	}
+
+	var events []log.EventReference
+	if checkpoint.TreeSize > fromSize {
+		leaves, err := r.events.LeavesBetween(ctx, fromSize, checkpoint.TreeSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch events for checkpoint %d: %w", checkpoint.Epoch, err)
+		}
+		if int64(len(leaves)) != checkpoint.TreeSize-fromSize {
+			return nil, fmt.Errorf("log returned %d leaves for checkpoint %d, expected %d",
+				len(leaves), checkpoint.Epoch, checkpoint.TreeSize-fromSize)
+		}
+		for _, leaf := range leaves {
+			if !bytes.Equal(log.HashLeaf(leaf.LeafValue), leaf.LeafHash) {
+				return nil, fmt.Errorf("leaf %d does not match its hash", leaf.LeafIndex)
+			}
+			frontier.Append(leaf.LeafHash)
+		}
+		events = decodeEventLeaves(leaves)
+	}
+	if !bytes.Equal(frontier.Root(), checkpoint.Root) {
+		return nil, fmt.Errorf("log leaves do not match the root of checkpoint %d", checkpoint.Epoch)
+	}
+	affected := eventDIDs(events)
+
+	epoch := r.epochOf(checkpoint)
+	if r.adjudications != nil {
+		if _, err := r.adjudications.ProcessEvents(ctx, events, epoch); err != nil {
+			return nil, fmt.Errorf("failed to apply adjudications for checkpoint %d: %w", checkpoint.Epoch, err)
+		}
+	}
+	var previousEpoch int64 = -1
+	if last != nil {
+		previousEpoch = r.epochOf(last)
+	}
+	marker := &CheckpointMarker{
+		Epoch:    checkpoint.Epoch,
+		Root:     hex.EncodeToString(checkpoint.Root),
+		TreeSize: checkpoint.TreeSize,
+	}
+
+	results := make([]*ScoreFreshness, 0, len(r.config.Contexts))
+	for _, scoreContext := range r.config.Contexts {
+		snapshot, recomputed, err := r.refresh(ctx, scoreContext, epoch, previousEpoch, affected)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh %s for checkpoint %d: %w", scoreContext, checkpoint.Epoch, err)
+		}
+
+		snapshot.Checkpoint = marker
+		for _, did := range recomputed {
+			snapshot.Scores[did].Checkpoint = marker
+		}
+		if err := r.engine.publishSnapshot(ctx, snapshot); err != nil {
+			return nil, err
+		}
+
+		results = append(results, &ScoreFreshness{
+			Context:         scoreContext,
+			Epoch:           epoch,
+			Checkpoint:      *marker,
+			SnapshotVersion: snapshot.Version,
+			Events:          len(events),
+			Recomputed:      len(recomputed),
+		})
+	}
+
+	completed := time.Now()
+	checkpointTime := checkpoint.Timestamp
+	if checkpointTime.IsZero() {
+		checkpointTime = received
+	}
+	latency := completed.Sub(checkpointTime)
+
+	r.mu.RLock()
+	freshness := make(map[string]*ScoreFreshness, len(r.freshness)+len(results))
+	for scoreContext, marker := range r.freshness {
+		freshness[scoreContext] = marker
+	}
+	r.mu.RUnlock()
+	for _, result := range results {
+		result.CheckpointTime = checkpointTime
+		result.CompletedAt = completed
+		result.LatencyMS = latency.Milliseconds()
+		freshness[result.Context] = result
+	}
+	// Until the checkpoint is saved it is not applied; the next one
+	// covers its events again
+	if err := r.save(ctx, checkpoint, frontier, freshness); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.last = checkpoint
+	r.frontier = frontier
+	r.freshness = freshness
+	r.processed++
+	r.latencies = append(r.latencies, latency)
+	if window := max(r.config.LatencyWindow, 1); len(r.latencies) > window {
+		r.latencies = r.latencies[len(r.latencies)-window:]
+	}
+	r.mu.Unlock()
+
+	if r.publisher != nil {
+		for _, freshness := range results {
+			// Freshness is also served over HTTP, so a failed announcement
+			// only delays it
+			r.publisher.PublishFreshness(ctx, freshness)
+		}
+	}
+
+	return results, nil
+}
+
+// Freshness returns the latest marker for a context, or nil before the
+// first checkpoint
+func (r *CheckpointRecomputer) Freshness(context string) *ScoreFreshness {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.freshness[context]
+}
+
+// Stats returns checkpoint latency statistics
+func (r *CheckpointRecomputer) Stats() *RecomputeStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := &RecomputeStats{Checkpoints: r.processed, Failures: r.failures, LastError: r.lastError}
+	if len(r.latencies) == 0 {
+		return stats
+	}
+	stats.LastLatencyMS = r.latencies[len(r.latencies)-1].Milliseconds()
+
+	sorted := append([]time.Duration{}, r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (len(sorted)*95 + 99) / 100 // Nearest rank
+	stats.P95LatencyMS = sorted[rank-1].Milliseconds()
+	return stats
+}
+
+func (r *CheckpointRecomputer) epochOf(checkpoint *consensus.Checkpoint) int64 {
+	if r.config.EpochOf != nil {
+		return r.config.EpochOf(checkpoint)
+	}
+	return checkpoint.Timestamp.Unix() / 86400
+}
+
+// refresh returns the context's snapshot with the affected DIDs brought up
+// to date, and the DIDs that were re-solved. The first checkpoint of a
+// score epoch solves it in full, carrying over the previous epoch's DIDs.
+func (r *CheckpointRecomputer) refresh(ctx context.Context, context string, epoch, previousEpoch int64, affected []string) (*ScoreSnapshot, []string, error) {
+	current, err := r.engine.GetSnapshot(ctx, context, epoch)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	if current == nil {
+		if detector, ok := r.engine.graphAnalyzer.(CommunityDetector); ok && len(affected) > 0 {
+			detector.InvalidateCommunities(context, epoch)
+		}
+		dids := append([]string{}, affected...)
+		if previousEpoch >= 0 && previousEpoch != epoch {
+			previous, err := r.engine.GetSnapshot(ctx, context, previousEpoch)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get snapshot: %w", err)
+			}
+			if previous != nil {
+				dids = append(dids, sortedScoreDIDs(previous.Scores)...)
+			}
+		}
+		if len(dids) == 0 {
+			if lister, ok := r.engine.dataProvider.(DIDLister); ok {
+				if dids, err = lister.ListDIDs(ctx, context, epoch); err != nil {
+					return nil, nil, fmt.Errorf("failed to list DIDs: %w", err)
+				}
+			}
+		}
+
+		snapshot, err := r.engine.solve(ctx, context, epoch, dids, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return snapshot, sortedScoreDIDs(snapshot.Scores), nil
+	}
+
+	if affected, err = r.withGraphChanges(ctx, context, epoch, current, affected); err != nil {
+		return nil, nil, err
+	}
+	dirty, err := r.dirtyDIDs(ctx, context, epoch, current, affected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged := &ScoreSnapshot{
+		Context:    context,
+		Epoch:      epoch,
+		Scores:     make(map[string]*Score, len(current.Scores)),
+		Iterations: current.Iterations,
+		Residual:   current.Residual,
+		Converged:  current.Converged,
+	}
+	pinned := make(map[string]*Score, len(current.Scores))
+	for did, score := range current.Scores {
+		pinned[did] = score
+	}
+	for _, did := range dirty {
+		delete(pinned, did)
+	}
+	for did, score := range pinned {
+		merged.Scores[did] = score
+	}
+
+	if len(dirty) > 0 {
+		partial, err := r.engine.solve(ctx, context, epoch, dirty, pinned)
+		if err != nil {
+			return nil, nil, err
+		}
+		for did, score := range partial.Scores {
+			merged.Scores[did] = score
+		}
+		merged.Iterations = partial.Iterations
+		merged.Residual = partial.Residual
+		merged.Converged = partial.Converged
+		dirty = sortedScoreDIDs(partial.Scores)
+	}
+	sealSnapshot(merged)
+
+	return merged, dirty, nil
+}
+
+// withGraphChanges re-runs community and collusion detection after new
+// events. DIDs whose community moved and DIDs receiving a vouch whose
+// collusion penalty changed join the affected ones, since the weights of
+// their vouches depend on it.
+func (r *CheckpointRecomputer) withGraphChanges(ctx context.Context, context string, epoch int64, snapshot *ScoreSnapshot, affected []string) ([]string, error) {
+	detector, ok := r.engine.graphAnalyzer.(CommunityDetector)
+	if !ok || len(affected) == 0 {
+		return affected, nil
+	}
+
+	penalizer, _ := r.engine.graphAnalyzer.(VouchPenalizer)
+	penalties := func() (*VouchPenalties, error) {
+		if penalizer == nil {
+			return &VouchPenalties{}, nil
+		}
+		penalties, err := penalizer.VouchPenalties(ctx, context, epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect collusion: %w", err)
+		}
+		return penalties, nil
+	}
+
+	before, err := detector.DetectCommunities(ctx, context, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect communities: %w", err)
+	}
+	penalizedBefore, err := penalties()
+	if err != nil {
+		return nil, err
+	}
+	detector.InvalidateCommunities(context, epoch)
+	after, err := detector.DetectCommunities(ctx, context, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect communities: %w", err)
+	}
+	penalizedAfter, err := penalties()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := append([]string{}, affected...)
+	for did, community := range after.Communities {
+		if before.Community(did) != community {
+			changed = append(changed, did)
+		}
+	}
+	for _, pair := range []struct{ from, to *VouchPenalties }{{penalizedBefore, penalizedAfter}, {penalizedAfter, penalizedBefore}} {
+		for key, factor := range pair.from.Factors {
+			if other, ok := pair.to.Factors[key]; !ok || other != factor {
+				changed = append(changed, key[strings.LastIndex(key, "|")+1:])
+			}
+		}
+	}
+	return changed, nil
+}
+
+// dirtyDIDs extends the affected DIDs with everyone downstream of them
+// through vouches, in sorted order
+func (r *CheckpointRecomputer) dirtyDIDs(ctx context.Context, context string, epoch int64, snapshot *ScoreSnapshot, affected []string) ([]string, error) {
+	vouchees, err := r.voucheeIndex(ctx, context, epoch, snapshot, affected)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty := make(map[string]bool, len(affected))
+	queue := append([]string{}, affected...)
+	for len(queue) > 0 {
+		did := queue[0]
+		queue = queue[1:]
+		if dirty[did] {
+			continue
+		}
+		dirty[did] = true
+
+		next, err := vouchees(did)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, next...)
+	}
+
+	result := make([]string, 0, len(dirty))
+	for did := range dirty {
+		result = append(result, did)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// voucheeIndex returns a lookup of who each DID vouches for, from the data
+// provider when it can answer directly or else by inverting the vouches of
+// the DIDs the snapshot and the new events know about
+func (r *CheckpointRecomputer) voucheeIndex(ctx context.Context, context string, epoch int64, snapshot *ScoreSnapshot, affected []string) (func(did string) ([]string, error), error) {
+	if provider, ok := r.engine.dataProvider.(VoucheeProvider); ok {
+		return func(did string) ([]string, error) {
+			vouchees, err := provider.GetVouchees(ctx, did, context, epoch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get vouchees of %s: %w", did, err)
+			}
+			return vouchees, nil
+		}, nil
+	}
+
+	known := make(map[string]bool, len(snapshot.Scores)+len(affected))
+	for did := range snapshot.Scores {
+		known[did] = true
+	}
+	for _, did := range affected {
+		known[did] = true
+	}
+
+	index := make(map[string][]string)
+	for did := range known {
+		vouches, err := r.engine.dataProvider.GetVouches(ctx, did, context, epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vouches for %s: %w", did, err)
+		}
+		for _, vouch := range vouches {
+			index[vouch.FromDID] = append(index[vouch.FromDID], did)
+		}
+	}
+	return func(did string) ([]string, error) {
+		return index[did], nil
+	}, nil
+}
+
+// eventDIDs returns the DIDs named by events, in sorted order
+func eventDIDs(events []log.EventReference) []string {
+	seen := make(map[string]bool)
+	for _, event := range events {
+		for _, did := range []string{event.From, event.To} {
+			if did != "" {
+				seen[did] = true
+			}
+		}
+	}
+	dids := make([]string, 0, len(seen))
+	for did := range seen {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	return dids
+}
+
+// decodeEventLeaves returns the event references among log leaves. Leaves
+// appended without an event reference are skipped.
+func decodeEventLeaves(leaves []log.Leaf) []log.EventReference {
+	events := make([]log.EventReference, 0, len(leaves))
+	for _, leaf := range leaves {
+		var event log.EventReference
+		if err := json.Unmarshal(leaf.LeafValue, &event); err != nil || event.CID == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// TransparencyLogEventSource reads checkpoint events from an in-process
+// transparency log
+type TransparencyLogEventSource struct {
+	log log.TransparencyLog
+}
+
+// NewTransparencyLogEventSource creates an event source over a log
+func NewTransparencyLogEventSource(transparencyLog log.TransparencyLog) *TransparencyLogEventSource {
+	return &TransparencyLogEventSource{log: transparencyLog}
+}
+
+// LeavesBetween implements CheckpointEventSource
+func (s *TransparencyLogEventSource) LeavesBetween(ctx context.Context, fromSize, toSize int64) ([]log.Leaf, error) {
+	if toSize <= fromSize {
+		return nil, nil
+	}
+	return s.log.GetLeavesByRange(ctx, fromSize, toSize-1)
+}
+
+// ConsistencyProof implements CheckpointEventSource
+func (s *TransparencyLogEventSource) ConsistencyProof(ctx context.Context, fromSize, toSize int64) (*log.ConsistencyProof, error) {
+	return s.log.GetConsistencyProof(ctx, fromSize, toSize)
+}
+
+// HTTPLogEventSource reads checkpoint events from a log node's
+// /v1/log/leaves and /v1/log/consistency endpoints
+type HTTPLogEventSource struct {
+	baseURL  string
+	client   *http.Client
+	pageSize int64
+}
+
+// NewHTTPLogEventSource creates an event source for a log node
+func NewHTTPLogEventSource(baseURL string) *HTTPLogEventSource {
+	return &HTTPLogEventSource{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		pageSize: 1000,
+	}
+}
+
+// LeavesBetween implements CheckpointEventSource
+func (s *HTTPLogEventSource) LeavesBetween(ctx context.Context, fromSize, toSize int64) ([]log.Leaf, error) {
+	var leaves []log.Leaf
+	for start := fromSize; start < toSize; {
+		end := min(start+s.pageSize, toSize) - 1 // The endpoint's end is inclusive
+		query := url.Values{}
+		query.Set("start", fmt.Sprint(start))
+		query.Set("end", fmt.Sprint(end))
+
+		var page struct {
+			Leaves []log.Leaf `json:"leaves"`
+		}
+		if err := getJSON(ctx, s.client, s.baseURL+"/v1/log/leaves?"+query.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("failed to fetch leaves %d-%d: %w", start, end, err)
+		}
+		if len(page.Leaves) == 0 {
+			return nil, fmt.Errorf("log returned no leaves from %d", start)
+		}
+		if int64(len(page.Leaves)) > end-start+1 {
+			return nil, fmt.Errorf("log returned more leaves than requested from %d", start)
+		}
+		leaves = append(leaves, page.Leaves...)
+		start += int64(len(page.Leaves))
+	}
+	return leaves, nil
+}
+
+// ConsistencyProof implements CheckpointEventSource
+func (s *HTTPLogEventSource) ConsistencyProof(ctx context.Context, fromSize, toSize int64) (*log.ConsistencyProof, error) {
+	query := url.Values{}
+	query.Set("from_size", fmt.Sprint(fromSize))
+	query.Set("to_size", fmt.Sprint(toSize))
+
+	var proof log.ConsistencyProof
+	if err := getJSON(ctx, s.client, s.baseURL+"/v1/log/consistency?"+query.Encode(), &proof); err != nil {
+		return nil, fmt.Errorf("failed to fetch consistency proof %d-%d: %w", fromSize, toSize, err)
+	}
+	return &proof, nil
+}
+
+// HTTPCheckpointSource polls a P2P bridge for the latest checkpoint on the
+// checkpoints/epoch topic
+type HTTPCheckpointSource struct {
+	baseURL  string
+	client   *http.Client
+	interval time.Duration
+}
+
+// NewHTTPCheckpointSource creates a checkpoint source for a P2P bridge
+func NewHTTPCheckpointSource(baseURL string, interval time.Duration) *HTTPCheckpointSource {
+	return &HTTPCheckpointSource{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+	}
+}
+
+// SubscribeCheckpoints implements CheckpointSource. Each checkpoint that
+// differs from the last one seen is delivered once. The bridge caches
+// whatever was gossiped, so the recomputer rather than the source decides
+// which checkpoints are genuine and new; tracking only the highest epoch
+// would let one forged epoch hide every later checkpoint.
+func (s *HTTPCheckpointSource) SubscribeCheckpoints(ctx context.Context) (<-chan *consensus.Checkpoint, error) {
+	checkpoints := make(chan *consensus.Checkpoint)
+	go func() {
+		defer close(checkpoints)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		var last *consensus.Checkpoint
+		for {
+			var checkpoint consensus.Checkpoint
+			// The bridge answers 404 until a checkpoint has been gossiped
+			if err := getJSON(ctx, s.client, s.baseURL+"/v1/checkpoints/latest", &checkpoint); err == nil &&
+				(last == nil || checkpoint.Epoch != last.Epoch || !bytes.Equal(checkpoint.Root, last.Root)) {
+				last = &checkpoint
+				select {
+				case checkpoints <- &checkpoint:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return checkpoints, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, target string, out interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
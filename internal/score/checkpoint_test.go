@@ -0,0 +1,493 @@
+package score
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/log"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// appendEvent records an event in the log and returns the new tree size
+func appendEvent(t *testing.T, transparencyLog log.TransparencyLog, eventType, from, to string) int64 {
+	t.Helper()
+	value, err := json.Marshal(&log.EventReference{
+		CID:  fmt.Sprintf("cid-%s-%s-%s", eventType, from, to),
+		Type: eventType,
+		From: from,
+		To:   to,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode event: %v", err)
+	}
+	result, err := transparencyLog.AppendLeaves(context.Background(), []log.Leaf{{LeafValue: value}})
+	if err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+	return result.TreeSize
+}
+
+// testCommittee signs checkpoints for a two of three committee
+type testCommittee struct {
+	verifier   *consensus.CommitteeCheckpointVerifier
+	members    []string
+	keys       map[string]*consensus.BLSPrivateKey
+	aggregator *consensus.DefaultBLSAggregator
+}
+
+func newTestCommittee(t *testing.T) *testCommittee {
+	t.Helper()
+	committee := &testCommittee{
+		keys:       make(map[string]*consensus.BLSPrivateKey),
+		aggregator: consensus.NewDefaultBLSAggregator(),
+	}
+	members := make(map[string]*consensus.BLSPublicKey)
+	for i := 0; i < 3; i++ {
+		privateKey, publicKey, err := committee.aggregator.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("GenerateKeyPair failed: %v", err)
+		}
+		member := fmt.Sprintf("did:key:member%d", i)
+		committee.members = append(committee.members, member)
+		committee.keys[member] = privateKey
+		members[member] = publicKey
+	}
+
+	verifier, err := consensus.NewCommitteeCheckpointVerifier(&consensus.CheckpointCommittee{Members: members, Threshold: 2})
+	if err != nil {
+		t.Fatalf("NewCommitteeCheckpointVerifier failed: %v", err)
+	}
+	committee.verifier = verifier
+	return committee
+}
+
+// sign returns a checkpoint over a root signed by the given members
+func (c *testCommittee) sign(t *testing.T, epoch int64, root []byte, treeSize int64, signers ...string) *consensus.Checkpoint {
+	t.Helper()
+	if signers == nil {
+		signers = c.members[:2]
+	}
+	var partials []consensus.PartialSignature
+	for _, signer := range signers {
+		signature, err := c.aggregator.SignMessage(root, c.keys[signer])
+		if err != nil {
+			t.Fatalf("SignMessage failed: %v", err)
+		}
+		partials = append(partials, consensus.PartialSignature{SignerDID: signer, Signature: signature, Root: root, Epoch: epoch})
+	}
+	signature, err := c.aggregator.AggregateSignatures(partials, len(partials))
+	if err != nil {
+		t.Fatalf("AggregateSignatures failed: %v", err)
+	}
+	return &consensus.Checkpoint{
+		Root:      root,
+		Epoch:     epoch,
+		TreeSize:  treeSize,
+		Signers:   signers,
+		Signature: signature,
+		Timestamp: time.Now(),
+	}
+}
+
+// checkpoint returns a signed checkpoint over the log's current tree
+func (c *testCommittee) checkpoint(t *testing.T, transparencyLog log.TransparencyLog, epoch int64) *consensus.Checkpoint {
+	t.Helper()
+	head, err := transparencyLog.GetSignedTreeHead(context.Background())
+	if err != nil {
+		t.Fatalf("GetSignedTreeHead failed: %v", err)
+	}
+	return c.sign(t, epoch, head.RootHash, head.TreeSize)
+}
+
+// expectFullSolve checks a snapshot against solving the same DIDs from
+// scratch
+func expectFullSolve(t *testing.T, provider *TestDataProvider, snapshot *ScoreSnapshot) {
+	t.Helper()
+	engine := NewDeterministicEngine(DefaultScoreConfig(), provider, nil, nil,
+		NewExponentialDecayFunction(), NewTestValidator(), nil)
+	config := DefaultPropagationConfig()
+	config.Tolerance = 0
+	engine.SetPropagation(config)
+
+	full, err := engine.solve(context.Background(), snapshot.Context, snapshot.Epoch, sortedScoreDIDs(snapshot.Scores), nil)
+	if err != nil {
+		t.Fatalf("Full solve failed: %v", err)
+	}
+	if len(full.Scores) != len(snapshot.Scores) {
+		t.Fatalf("Expected %d DIDs, got %d", len(full.Scores), len(snapshot.Scores))
+	}
+	for did, score := range full.Scores {
+		if snapshot.Scores[did].Value != score.Value {
+			t.Errorf("Score of %s: incremental %f, full solve %f", did, snapshot.Scores[did].Value, score.Value)
+		}
+	}
+}
+
+func TestCheckpointRecomputer_Incremental(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	config := DefaultPropagationConfig()
+	config.Tolerance = 0
+	engine.SetPropagation(config)
+	ctx := context.Background()
+
+	if _, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol", "did:key:bob"}); err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	transparencyLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	committee := newTestCommittee(t)
+	recomputer := NewCheckpointRecomputer(engine, NewTransparencyLogEventSource(transparencyLog), committee.verifier, &CheckpointRecomputerConfig{
+		Contexts:      []string{"test_context"},
+		Deadline:      time.Minute,
+		LatencyWindow: 10,
+		EpochOf:       func(*consensus.Checkpoint) int64 { return 100 },
+	})
+
+	// A new voucher for carol only dirties carol and the voucher
+	provider.AddVouch(&VouchData{FromDID: "did:key:dave", ToDID: "did:key:carol", Context: "test_context", Strength: 6, Epoch: 100})
+	size := appendEvent(t, transparencyLog, "vouch", "did:key:dave", "did:key:carol")
+	first := committee.checkpoint(t, transparencyLog, 1)
+	results, err := recomputer.ProcessCheckpoint(ctx, first)
+	if err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Recomputed != 2 || results[0].Events != 1 {
+		t.Fatalf("Expected carol and dave recomputed from one event, got %+v", results)
+	}
+
+	snapshot, err := engine.GetSnapshot(ctx, "test_context", 100)
+	if err != nil || snapshot == nil {
+		t.Fatalf("Expected a snapshot, got %v", err)
+	}
+	expectFullSolve(t, provider, snapshot)
+	if snapshot.Checkpoint == nil || snapshot.Checkpoint.Root != hex.EncodeToString(first.Root) || snapshot.Checkpoint.TreeSize != size {
+		t.Errorf("Expected the snapshot to be marked with the checkpoint, got %+v", snapshot.Checkpoint)
+	}
+	if marker := snapshot.Scores["did:key:carol"].Checkpoint; marker == nil || marker.Epoch != 1 {
+		t.Errorf("Expected carol's score to be marked with checkpoint 1, got %+v", marker)
+	}
+	if marker := snapshot.Scores["did:key:alice"].Checkpoint; marker != nil {
+		t.Errorf("Expected alice's score to keep its earlier computation, got %+v", marker)
+	}
+
+	// New evidence for alice dirties everyone downstream of her
+	provider.AddKYC(&KYCData{DID: "did:key:alice", Context: "test_context", Type: "kyc_level_3", Level: 3, IssuerDID: "did:key:kyc", Weight: 20, Epoch: 100})
+	size = appendEvent(t, transparencyLog, "attestation", "did:key:kyc", "did:key:alice")
+	second := committee.checkpoint(t, transparencyLog, 2)
+	results, err = recomputer.ProcessCheckpoint(ctx, second)
+	if err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+	// alice, bob, carol and the issuer, but not stranger or dave
+	if results[0].Recomputed != 4 {
+		t.Errorf("Expected 4 DIDs recomputed, got %d", results[0].Recomputed)
+	}
+	snapshot, _ = engine.GetSnapshot(ctx, "test_context", 100)
+	expectFullSolve(t, provider, snapshot)
+	if marker := snapshot.Scores["did:key:stranger"].Checkpoint; marker != nil {
+		t.Errorf("Expected stranger to be left alone, got %+v", marker)
+	}
+
+	// Reads see the refreshed score and the checkpoint it came from
+	score, err := engine.ComputeScore(ctx, "did:key:bob", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ComputeScore failed: %v", err)
+	}
+	if score.Checkpoint == nil || score.Checkpoint.Epoch != 2 {
+		t.Errorf("Expected bob's score to carry checkpoint 2, got %+v", score.Checkpoint)
+	}
+
+	// Replayed checkpoints are ignored
+	if results, err := recomputer.ProcessCheckpoint(ctx, second); err != nil || results != nil {
+		t.Errorf("Expected a replayed checkpoint to be ignored, got %v, %v", results, err)
+	}
+
+	freshness := recomputer.Freshness("test_context")
+	if freshness == nil || freshness.Checkpoint.Epoch != 2 || freshness.SnapshotVersion != snapshot.Version {
+		t.Errorf("Expected freshness for checkpoint 2, got %+v", freshness)
+	}
+	if stats := recomputer.Stats(); stats.Checkpoints != 2 || stats.P95LatencyMS < stats.LastLatencyMS {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestCheckpointRecomputer_NewEpoch(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	ctx := context.Background()
+
+	transparencyLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	epochs := map[int64]int64{1: 100, 2: 101}
+	committee := newTestCommittee(t)
+	recomputer := NewCheckpointRecomputer(engine, NewTransparencyLogEventSource(transparencyLog), committee.verifier, &CheckpointRecomputerConfig{
+		Contexts:      []string{"test_context"},
+		Deadline:      time.Minute,
+		LatencyWindow: 10,
+		EpochOf:       func(checkpoint *consensus.Checkpoint) int64 { return epochs[checkpoint.Epoch] },
+	})
+
+	size := appendEvent(t, transparencyLog, "vouch", "did:key:alice", "did:key:carol")
+	if _, err := recomputer.ProcessCheckpoint(ctx, committee.checkpoint(t, transparencyLog, 1)); err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+
+	// The first checkpoint of an epoch carries over every DID already scored
+	results, err := recomputer.ProcessCheckpoint(ctx, committee.checkpoint(t, transparencyLog, 2))
+	if err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+	if results[0].Epoch != 101 || results[0].Events != 0 {
+		t.Errorf("Expected an empty checkpoint for epoch 101, got %+v", results[0])
+	}
+	snapshot, _ := engine.GetSnapshot(ctx, "test_context", 101)
+	if snapshot == nil || snapshot.Scores["did:key:carol"] == nil || snapshot.Scores["did:key:stranger"] == nil {
+		t.Fatalf("Expected epoch 101 to be solved over the earlier DIDs")
+	}
+	if snapshot.Scores["did:key:carol"].Checkpoint == nil {
+		t.Error("Expected a full solve to mark every score")
+	}
+
+	if _, err := recomputer.ProcessCheckpoint(ctx, committee.sign(t, 3, []byte{3}, size-1)); err == nil {
+		t.Error("Expected a shrinking log to be rejected")
+	}
+}
+
+func TestHTTPLogEventSource_Paging(t *testing.T) {
+	transparencyLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		appendEvent(t, transparencyLog, "vouch", fmt.Sprintf("did:key:%d", i), "did:key:target")
+	}
+	if _, err := transparencyLog.AppendLeaves(context.Background(), []log.Leaf{{LeafValue: []byte("raw")}}); err != nil {
+		t.Fatalf("AppendLeaves failed: %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path == "/v1/log/consistency" {
+			from, _ := strconv.ParseInt(query.Get("from_size"), 10, 64)
+			to, _ := strconv.ParseInt(query.Get("to_size"), 10, 64)
+			proof, err := transparencyLog.GetConsistencyProof(r.Context(), from, to)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(proof)
+			return
+		}
+
+		requests++
+		start, _ := strconv.ParseInt(query.Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(query.Get("end"), 10, 64)
+		leaves, err := transparencyLog.GetLeavesByRange(r.Context(), start, end)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"leaves": leaves})
+	}))
+	defer server.Close()
+
+	source := NewHTTPLogEventSource(server.URL)
+	source.pageSize = 2
+	leaves, err := source.LeavesBetween(context.Background(), 1, 6)
+	if err != nil {
+		t.Fatalf("LeavesBetween failed: %v", err)
+	}
+	if len(leaves) != 5 || leaves[0].LeafIndex != 1 || leaves[4].LeafIndex != 5 {
+		t.Errorf("Expected leaves 1-5, got %d leaves", len(leaves))
+	}
+	// Leaves that are not event references are skipped
+	if events := decodeEventLeaves(leaves); len(events) != 4 || events[0].From != "did:key:1" || events[3].From != "did:key:4" {
+		t.Errorf("Expected events 1-4, got %+v", events)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 pages, got %d", requests)
+	}
+
+	proof, err := source.ConsistencyProof(context.Background(), 2, 6)
+	if err != nil {
+		t.Fatalf("ConsistencyProof failed: %v", err)
+	}
+	if proof.FirstTreeSize != 2 || proof.SecondTreeSize != 6 || len(proof.ProofPath) == 0 {
+		t.Errorf("Unexpected consistency proof %+v", proof)
+	}
+}
+
+func TestCheckpointRecomputer_Verification(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	ctx := context.Background()
+
+	transparencyLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	committee := newTestCommittee(t)
+	var logged []string
+	recomputer := NewCheckpointRecomputer(engine, NewTransparencyLogEventSource(transparencyLog), committee.verifier, &CheckpointRecomputerConfig{
+		Contexts:      []string{"test_context"},
+		Deadline:      time.Minute,
+		LatencyWindow: 10,
+		MaxClockSkew:  time.Minute,
+		EpochOf:       func(*consensus.Checkpoint) int64 { return 100 },
+		Logf: func(format string, args ...interface{}) {
+			logged = append(logged, fmt.Sprintf(format, args...))
+		},
+	})
+
+	appendEvent(t, transparencyLog, "vouch", "did:key:alice", "did:key:carol")
+	valid := committee.checkpoint(t, transparencyLog, 1)
+
+	forged := *valid
+	forged.Epoch = 1 << 40
+	outsider := committee.sign(t, 1<<40, valid.Root, valid.TreeSize)
+	outsider.Signers = []string{committee.members[0], "did:key:outsider"}
+	future := committee.sign(t, 1, valid.Root, valid.TreeSize)
+	future.Timestamp = time.Now().Add(time.Hour)
+
+	rejected := map[string]*consensus.Checkpoint{
+		"unsigned":            {Root: valid.Root, Epoch: 1 << 40, TreeSize: valid.TreeSize},
+		"below threshold":     committee.sign(t, 1, valid.Root, valid.TreeSize, committee.members[0]),
+		"repeated signer":     committee.sign(t, 1, valid.Root, valid.TreeSize, committee.members[0], committee.members[0]),
+		"outside committee":   outsider,
+		"timestamp in future": future,
+		"root not of leaves":  committee.sign(t, 1, []byte("forged root"), valid.TreeSize),
+	}
+	for name, checkpoint := range rejected {
+		if _, err := recomputer.ProcessCheckpoint(ctx, checkpoint); err == nil {
+			t.Errorf("Expected a checkpoint with %s to be rejected", name)
+		}
+	}
+	if recomputer.Stats().Checkpoints != 0 {
+		t.Fatal("Expected no rejected checkpoint to be applied")
+	}
+
+	// Failures are logged and counted, and do not hold back later
+	// checkpoints
+	source := make(chanCheckpointSource, 2)
+	source <- rejected["unsigned"]
+	source <- valid
+	close(source)
+	if err := recomputer.Run(ctx, source); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	stats := recomputer.Stats()
+	if stats.Checkpoints != 1 || stats.Failures != 1 || stats.LastError == "" || len(logged) != 1 {
+		t.Errorf("Expected one failure logged and one checkpoint applied, got %+v, %v", stats, logged)
+	}
+
+	// A checkpoint over a log that rewrote history does not extend the last
+	// one, even when the committee signed it
+	forkedLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	appendEvent(t, forkedLog, "vouch", "did:key:mallory", "did:key:carol")
+	appendEvent(t, forkedLog, "vouch", "did:key:alice", "did:key:bob")
+	recomputer.events = NewTransparencyLogEventSource(forkedLog)
+	if _, err := recomputer.ProcessCheckpoint(ctx, committee.checkpoint(t, forkedLog, 2)); err == nil {
+		t.Error("Expected a forked log to be rejected")
+	}
+
+	recomputer.events = NewTransparencyLogEventSource(transparencyLog)
+	appendEvent(t, transparencyLog, "vouch", "did:key:alice", "did:key:bob")
+	if _, err := recomputer.ProcessCheckpoint(ctx, committee.checkpoint(t, transparencyLog, 2)); err != nil {
+		t.Errorf("Expected the extended log to be accepted, got %v", err)
+	}
+}
+
+func TestCheckpointRecomputer_StateStore(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	ctx := context.Background()
+
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	transparencyLog, err := log.NewMemoryTransparencyLog(nil)
+	if err != nil {
+		t.Fatalf("Failed to create log: %v", err)
+	}
+	committee := newTestCommittee(t)
+	newRecomputer := func() *CheckpointRecomputer {
+		recomputer := NewCheckpointRecomputer(engine, NewTransparencyLogEventSource(transparencyLog), committee.verifier, &CheckpointRecomputerConfig{
+			Contexts:      []string{"test_context"},
+			Deadline:      time.Minute,
+			LatencyWindow: 10,
+			EpochOf:       func(*consensus.Checkpoint) int64 { return 100 },
+		})
+		if err := recomputer.SetStateStore(stateStore); err != nil {
+			t.Fatalf("SetStateStore failed: %v", err)
+		}
+		return recomputer
+	}
+
+	appendEvent(t, transparencyLog, "vouch", "did:key:alice", "did:key:carol")
+	appendEvent(t, transparencyLog, "vouch", "did:key:bob", "did:key:carol")
+	first := committee.checkpoint(t, transparencyLog, 1)
+	if _, err := newRecomputer().ProcessCheckpoint(ctx, first); err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+
+	// A restarted recomputer resumes from the saved checkpoint
+	restarted := newRecomputer()
+	if freshness := restarted.Freshness("test_context"); freshness == nil || freshness.Checkpoint.Epoch != 1 {
+		t.Errorf("Expected freshness for checkpoint 1 after a restart, got %+v", freshness)
+	}
+	if results, err := restarted.ProcessCheckpoint(ctx, first); err != nil || results != nil {
+		t.Errorf("Expected the saved checkpoint to be ignored, got %v, %v", results, err)
+	}
+
+	appendEvent(t, transparencyLog, "vouch", "did:key:alice", "did:key:bob")
+	results, err := restarted.ProcessCheckpoint(ctx, committee.checkpoint(t, transparencyLog, 2))
+	if err != nil {
+		t.Fatalf("ProcessCheckpoint failed: %v", err)
+	}
+	if results[0].Events != 1 {
+		t.Errorf("Expected only the event since checkpoint 1 to be read, got %d", results[0].Events)
+	}
+
+	// A saved tree that does not match its checkpoint is refused
+	corrupt, err := json.Marshal(&recomputerState{Checkpoint: first, Frontier: &log.Frontier{}})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if err := stateStore.PutState(ctx, checkpointRecomputerStateKey, corrupt); err != nil {
+		t.Fatalf("PutState failed: %v", err)
+	}
+	recomputer := NewCheckpointRecomputer(engine, NewTransparencyLogEventSource(transparencyLog), committee.verifier, nil)
+	if err := recomputer.SetStateStore(stateStore); err == nil {
+		t.Error("Expected a mismatched tree to be refused")
+	}
+}
+
+// chanCheckpointSource delivers checkpoints from a channel
+type chanCheckpointSource chan *consensus.Checkpoint
+
+func (s chanCheckpointSource) SubscribeCheckpoints(ctx context.Context) (<-chan *consensus.Checkpoint, error) {
+	return s, nil
+}
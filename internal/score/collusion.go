@@ -0,0 +1,469 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Detectors reported on CollusionCluster.Detector
+const (
+	DetectorDenseSubgraph   = "dense_subgraph"
+	DetectorReciprocalCycle = "reciprocal_cycle"
+	DetectorVelocity        = "velocity"
+	DetectorFastCore        = "fast_core"
+)
+
+// TemporalDetectionConfig tunes the detectors that look at when vouches
+// were made, not just at the graph they form
+type TemporalDetectionConfig struct {
+	Window         int64 `json:"window"`           // Epochs the edges of one detection must fall within
+	MaxCycleLength int   `json:"max_cycle_length"` // Longest reciprocal cycle looked for, 2 or 3
+	MinOutDegree   int   `json:"min_out_degree"`   // Vouches given within a window that count as a burst
+	MaxInDegree    int   `json:"max_in_degree"`    // Vouches received within the same window, at most
+	MinCoreSize    int   `json:"min_core_size"`    // Vouchers and vouchees on each side of a core
+	MaxEdges       int   `json:"max_edges"`        // Bound on the edges scanned
+	MaxDetections  int   `json:"max_detections"`   // Bound on detections per detector
+}
+
+// DefaultTemporalDetectionConfig returns the default detector settings
+func DefaultTemporalDetectionConfig() *TemporalDetectionConfig {
+	return &TemporalDetectionConfig{
+		Window:         7,
+		MaxCycleLength: 3,
+		MinOutDegree:   10,
+		MaxInDegree:    1,
+		MinCoreSize:    3,
+		MaxEdges:       1000000,
+		MaxDetections:  1000,
+	}
+}
+
+// CollusionRule maps detections to reduced vouch weights. Detections never
+// ban a DID; they only scale down q_ij of the evidence edges.
+type CollusionRule struct {
+	Detector      string  `json:"detector"`
+	MinConfidence float64 `json:"min_confidence"`
+	WeightFactor  float64 `json:"weight_factor"` // Multiplier on q_ij, in (0, 1]
+}
+
+// DefaultCollusionRules returns the default detection rules. Reciprocal
+// vouching is common between honest peers, so it is penalized least.
+func DefaultCollusionRules() []CollusionRule {
+	return []CollusionRule{
+		{Detector: DetectorReciprocalCycle, MinConfidence: 0.5, WeightFactor: 0.8},
+		{Detector: DetectorVelocity, MinConfidence: 0.5, WeightFactor: 0.5},
+		{Detector: DetectorFastCore, MinConfidence: 0.5, WeightFactor: 0.4},
+		{Detector: DetectorDenseSubgraph, MinConfidence: 0.8, WeightFactor: 0.7},
+	}
+}
+
+// VouchPenalties are the q_ij multipliers that collusion rules put on
+// individual vouches
+type VouchPenalties struct {
+	Context    string             `json:"context"`
+	Epoch      int64              `json:"epoch"`
+	Factors    map[string]float64 `json:"factors"` // from|to -> multiplier
+	Detections int                `json:"detections"`
+}
+
+// VouchPenalizer is implemented by graph analyzers that reduce the weight of
+// vouches implicated in collusion
+type VouchPenalizer interface {
+	// VouchPenalties returns the penalties in force for a context and
+	// epoch, over its complete vouch graph, and caches them for scoring
+	VouchPenalties(ctx context.Context, context string, epoch int64) (*VouchPenalties, error)
+
+	// PreviewVouchPenalties returns the same penalties without caching them
+	PreviewVouchPenalties(ctx context.Context, context string, epoch int64) (*VouchPenalties, error)
+}
+
+// Factor returns the multiplier on a vouch, one when it is not penalized
+func (p *VouchPenalties) Factor(from, to string) float64 {
+	if factor, ok := p.Factors[vouchKey(from, to)]; ok {
+		return factor
+	}
+	return 1.0
+}
+
+func vouchKey(from, to string) string {
+	return from + "|" + to
+}
+
+// applyCollusionRules turns detections into vouch penalties. An edge caught
+// by several rules takes the strongest reduction rather than their product,
+// so overlapping detectors do not compound.
+func applyCollusionRules(clusters []*CollusionCluster, rules []CollusionRule, context string, epoch int64) *VouchPenalties {
+	penalties := &VouchPenalties{
+		Context: context,
+		Epoch:   epoch,
+		Factors: make(map[string]float64),
+	}
+	for _, cluster := range clusters {
+		applied := false
+		for _, rule := range rules {
+			if rule.Detector != cluster.Detector || cluster.Confidence < rule.MinConfidence {
+				continue
+			}
+			applied = true
+			for _, edge := range cluster.Evidence {
+				key := vouchKey(edge.From, edge.To)
+				if factor, ok := penalties.Factors[key]; !ok || rule.WeightFactor < factor {
+					penalties.Factors[key] = rule.WeightFactor
+				}
+			}
+		}
+		if applied {
+			penalties.Detections++
+		}
+	}
+	return penalties
+}
+
+// temporalEdges indexes the latest vouch between each ordered pair of DIDs
+type temporalEdges struct {
+	edges map[string]VouchEdge
+	out   map[string][]string // Sorted vouchees
+	in    map[string][]VouchEdge
+	nodes []string
+}
+
+func newTemporalEdges(graph *VouchGraph) *temporalEdges {
+	t := &temporalEdges{
+		edges: make(map[string]VouchEdge),
+		out:   make(map[string][]string),
+		in:    make(map[string][]VouchEdge),
+	}
+	for _, edge := range graph.Edges {
+		key := vouchKey(edge.From, edge.To)
+		if existing, ok := t.edges[key]; !ok || edge.Epoch > existing.Epoch {
+			t.edges[key] = edge
+		}
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0, len(t.edges))
+	for key := range t.edges {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		edge := t.edges[key]
+		t.out[edge.From] = append(t.out[edge.From], edge.To)
+		t.in[edge.To] = append(t.in[edge.To], edge)
+		for _, did := range []string{edge.From, edge.To} {
+			if !seen[did] {
+				seen[did] = true
+				t.nodes = append(t.nodes, did)
+			}
+		}
+	}
+	for did := range t.out {
+		sort.Strings(t.out[did])
+	}
+	sort.Strings(t.nodes)
+	return t
+}
+
+func (t *temporalEdges) edge(from, to string) (VouchEdge, bool) {
+	edge, ok := t.edges[vouchKey(from, to)]
+	return edge, ok
+}
+
+// epochSpread returns the epochs between the earliest and latest edge
+func epochSpread(edges []VouchEdge) (int64, int64) {
+	first, last := edges[0].Epoch, edges[0].Epoch
+	for _, edge := range edges[1:] {
+		first = min(first, edge.Epoch)
+		last = max(last, edge.Epoch)
+	}
+	return first, last
+}
+
+// newEvidenceCluster builds a cluster from the edges that implicate it
+func newEvidenceCluster(detector, context string, epoch int64, evidence []VouchEdge, confidence float64) *CollusionCluster {
+	sort.Slice(evidence, func(i, j int) bool {
+		if evidence[i].From != evidence[j].From {
+			return evidence[i].From < evidence[j].From
+		}
+		return evidence[i].To < evidence[j].To
+	})
+
+	seen := make(map[string]bool)
+	var members []string
+	volume := 0.0
+	for _, edge := range evidence {
+		for _, did := range []string{edge.From, edge.To} {
+			if !seen[did] {
+				seen[did] = true
+				members = append(members, did)
+			}
+		}
+		volume += edge.Weight
+	}
+	sort.Strings(members)
+
+	density := 0.0
+	if n := len(members); n > 1 {
+		density = float64(len(evidence)) / float64(n*(n-1))
+	}
+	first, last := epochSpread(evidence)
+	return &CollusionCluster{
+		Context:     context,
+		Epoch:       epoch,
+		Members:     members,
+		Density:     density,
+		VouchVolume: volume,
+		Confidence:  confidence,
+		Detector:    detector,
+		Evidence:    evidence,
+		FirstEpoch:  first,
+		LastEpoch:   last,
+	}
+}
+
+// windowConfidence is one for edges made in the same epoch, falling towards
+// zero as they spread over the whole window
+func windowConfidence(first, last, window int64) float64 {
+	return 1.0 - float64(last-first)/float64(window+1)
+}
+
+// detectReciprocalCycles finds vouch cycles of two or three DIDs whose edges
+// were all made within the window. Cycles sharing a DID are reported as one
+// cluster.
+func detectReciprocalCycles(edges *temporalEdges, config *TemporalDetectionConfig, context string, epoch int64) []*CollusionCluster {
+	type cycle struct {
+		edges      []VouchEdge
+		confidence float64
+	}
+	var cycles []cycle
+	record := func(path []VouchEdge) {
+		first, last := epochSpread(path)
+		if last-first <= config.Window {
+			cycles = append(cycles, cycle{edges: path, confidence: windowConfidence(first, last, config.Window)})
+		}
+	}
+
+	// Each cycle is found once, from its lowest DID
+	for _, a := range edges.nodes {
+		for _, b := range edges.out[a] {
+			if b <= a {
+				continue
+			}
+			ab, _ := edges.edge(a, b)
+			if ba, ok := edges.edge(b, a); ok {
+				record([]VouchEdge{ab, ba})
+			}
+			if config.MaxCycleLength < 3 {
+				continue
+			}
+			for _, c := range edges.out[b] {
+				if c <= a || c == b {
+					continue
+				}
+				if ca, ok := edges.edge(c, a); ok {
+					bc, _ := edges.edge(b, c)
+					record([]VouchEdge{ab, bc, ca})
+				}
+			}
+		}
+		if len(cycles) >= config.MaxDetections {
+			break
+		}
+	}
+
+	// Union cycles that share DIDs
+	parent := make(map[string]string)
+	var find func(did string) string
+	find = func(did string) string {
+		if parent[did] == "" || parent[did] == did {
+			parent[did] = did
+			return did
+		}
+		root := find(parent[did])
+		parent[did] = root
+		return root
+	}
+	for _, c := range cycles {
+		for _, edge := range c.edges {
+			a, b := find(edge.From), find(edge.To)
+			if a != b {
+				// The lower DID becomes the root so grouping is order-free
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	groups := make(map[string]map[string]VouchEdge)
+	confidence := make(map[string]float64)
+	for _, c := range cycles {
+		root := find(c.edges[0].From)
+		if groups[root] == nil {
+			groups[root] = make(map[string]VouchEdge)
+		}
+		for _, edge := range c.edges {
+			groups[root][vouchKey(edge.From, edge.To)] = edge
+		}
+		confidence[root] = max(confidence[root], c.confidence)
+	}
+
+	roots := make([]string, 0, len(groups))
+	for root := range groups {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	clusters := make([]*CollusionCluster, 0, len(roots))
+	for _, root := range roots {
+		evidence := make([]VouchEdge, 0, len(groups[root]))
+		for _, edge := range groups[root] {
+			evidence = append(evidence, edge)
+		}
+		clusters = append(clusters, newEvidenceCluster(DetectorReciprocalCycle, context, epoch, evidence, confidence[root]))
+	}
+	return clusters
+}
+
+// detectVelocity finds DIDs that gave many vouches within one window while
+// receiving almost none in it, the pattern of a freshly minted sybil
+// spraying trust
+func detectVelocity(edges *temporalEdges, config *TemporalDetectionConfig, context string, epoch int64) []*CollusionCluster {
+	var clusters []*CollusionCluster
+	for _, did := range edges.nodes {
+		if len(edges.out[did]) < config.MinOutDegree {
+			continue
+		}
+		out := make([]VouchEdge, 0, len(edges.out[did]))
+		for _, to := range edges.out[did] {
+			edge, _ := edges.edge(did, to)
+			out = append(out, edge)
+		}
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Epoch < out[j].Epoch })
+
+		// Slide a window over the outgoing edges and keep the busiest one
+		// that also qualifies on in-degree
+		var burst []VouchEdge
+		var burstIn int
+		for start, end := 0, 0; start < len(out); start++ {
+			for end < len(out) && out[end].Epoch-out[start].Epoch <= config.Window {
+				end++
+			}
+			count := end - start
+			if count < config.MinOutDegree || count <= len(burst) {
+				continue
+			}
+			in := 0
+			for _, edge := range edges.in[did] {
+				if edge.Epoch >= out[start].Epoch && edge.Epoch <= out[start].Epoch+config.Window {
+					in++
+				}
+			}
+			if in <= config.MaxInDegree {
+				burst = append([]VouchEdge{}, out[start:end]...)
+				burstIn = in
+			}
+		}
+		if burst == nil {
+			continue
+		}
+
+		confidence := float64(len(burst)-burstIn) / float64(len(burst))
+		clusters = append(clusters, newEvidenceCluster(DetectorVelocity, context, epoch, burst, confidence))
+		if len(clusters) >= config.MaxDetections {
+			break
+		}
+	}
+	return clusters
+}
+
+// detectFastCores finds dense bipartite cores, at least MinCoreSize vouchers
+// who all vouched for the same MinCoreSize vouchees, whose edges all
+// appeared within one window. Cores are grown greedily from each voucher in
+// DID order by intersecting vouchee sets.
+func detectFastCores(edges *temporalEdges, config *TemporalDetectionConfig, context string, epoch int64) []*CollusionCluster {
+	k := config.MinCoreSize
+	var vouchers []string
+	for _, did := range edges.nodes {
+		if len(edges.out[did]) >= k {
+			vouchers = append(vouchers, did)
+		}
+	}
+
+	var clusters []*CollusionCluster
+	reported := make(map[string]bool)
+	for _, seed := range vouchers {
+		core := []string{seed}
+		targets := edges.out[seed]
+		for _, other := range vouchers {
+			if other == seed {
+				continue
+			}
+			if shared := intersectSorted(targets, edges.out[other]); len(shared) >= k {
+				core = append(core, other)
+				targets = shared
+			}
+		}
+		if len(core) < k {
+			continue
+		}
+		sort.Strings(core)
+
+		key := strings.Join(core, ",") + "->" + strings.Join(targets, ",")
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+
+		evidence := make([]VouchEdge, 0, len(core)*len(targets))
+		for _, from := range core {
+			for _, to := range targets {
+				edge, _ := edges.edge(from, to)
+				evidence = append(evidence, edge)
+			}
+		}
+		first, last := epochSpread(evidence)
+		if last-first > config.Window {
+			continue
+		}
+
+		size := float64(len(core)*len(targets)) / float64(2*k*k)
+		confidence := (min(size, 1.0) + windowConfidence(first, last, config.Window)) / 2.0
+		clusters = append(clusters, newEvidenceCluster(DetectorFastCore, context, epoch, evidence, confidence))
+		if len(clusters) >= config.MaxDetections {
+			break
+		}
+	}
+	return clusters
+}
+
+// intersectSorted returns the elements common to two sorted slices
+func intersectSorted(a, b []string) []string {
+	var result []string
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// detectTemporal runs every temporal detector over the vouch edge stream
+func detectTemporal(graph *VouchGraph, config *TemporalDetectionConfig, context string, epoch int64) ([]*CollusionCluster, error) {
+	if len(graph.Edges) > config.MaxEdges {
+		return nil, fmt.Errorf("vouch graph has %d edges, more than the %d scanned", len(graph.Edges), config.MaxEdges)
+	}
+	edges := newTemporalEdges(graph)
+
+	var clusters []*CollusionCluster
+	clusters = append(clusters, detectReciprocalCycles(edges, config, context, epoch)...)
+	clusters = append(clusters, detectVelocity(edges, config, context, epoch)...)
+	clusters = append(clusters, detectFastCores(edges, config, context, epoch)...)
+	return clusters, nil
+}
@@ -0,0 +1,177 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// edgeGraph builds a vouch graph from (from, to, epoch) triples
+func edgeGraph(edges ...VouchEdge) *VouchGraph {
+	graph := &VouchGraph{Nodes: make(map[string]bool), AdjList: make(map[string][]VouchEdge)}
+	for _, edge := range edges {
+		edge.Weight, edge.Strength = 10, 10
+		graph.Nodes[edge.From] = true
+		graph.Nodes[edge.To] = true
+		graph.Edges = append(graph.Edges, edge)
+	}
+	return graph
+}
+
+func clustersBy(clusters []*CollusionCluster, detector string) []*CollusionCluster {
+	var result []*CollusionCluster
+	for _, cluster := range clusters {
+		if cluster.Detector == detector {
+			result = append(result, cluster)
+		}
+	}
+	return result
+}
+
+func TestDetectTemporal_ReciprocalCycles(t *testing.T) {
+	graph := edgeGraph(
+		// A mutual pair made in the same epoch
+		VouchEdge{From: "did:key:a", To: "did:key:b", Epoch: 10},
+		VouchEdge{From: "did:key:b", To: "did:key:a", Epoch: 10},
+		// A triangle made over three epochs, sharing b
+		VouchEdge{From: "did:key:b", To: "did:key:c", Epoch: 11},
+		VouchEdge{From: "did:key:c", To: "did:key:d", Epoch: 12},
+		VouchEdge{From: "did:key:d", To: "did:key:b", Epoch: 13},
+		// A pair whose vouches are months apart
+		VouchEdge{From: "did:key:x", To: "did:key:y", Epoch: 10},
+		VouchEdge{From: "did:key:y", To: "did:key:x", Epoch: 90},
+	)
+
+	clusters, err := detectTemporal(graph, DefaultTemporalDetectionConfig(), "test_context", 100)
+	if err != nil {
+		t.Fatalf("detectTemporal failed: %v", err)
+	}
+	cycles := clustersBy(clusters, DetectorReciprocalCycle)
+	if len(cycles) != 1 {
+		t.Fatalf("Expected the pair and triangle as one cluster, got %d", len(cycles))
+	}
+	cycle := cycles[0]
+	if len(cycle.Members) != 4 || len(cycle.Evidence) != 5 {
+		t.Errorf("Expected 4 members and 5 evidence edges, got %v and %d", cycle.Members, len(cycle.Evidence))
+	}
+	if cycle.FirstEpoch != 10 || cycle.LastEpoch != 13 || cycle.Confidence != 1.0 {
+		t.Errorf("Unexpected cycle window %d-%d, confidence %f", cycle.FirstEpoch, cycle.LastEpoch, cycle.Confidence)
+	}
+
+	config := DefaultTemporalDetectionConfig()
+	config.MaxCycleLength = 2
+	clusters, _ = detectTemporal(graph, config, "test_context", 100)
+	if cycles := clustersBy(clusters, DetectorReciprocalCycle); len(cycles) != 1 || len(cycles[0].Evidence) != 2 {
+		t.Errorf("Expected only the mutual pair without triangles, got %+v", cycles)
+	}
+}
+
+func TestDetectTemporal_Velocity(t *testing.T) {
+	var edges []VouchEdge
+	for i := 0; i < 12; i++ {
+		// A burst from a DID nobody vouches for
+		edges = append(edges, VouchEdge{From: "did:key:sybil", To: fmt.Sprintf("did:key:s%02d", i), Epoch: 50 + int64(i%3)})
+		// The same number of vouches spread over months
+		edges = append(edges, VouchEdge{From: "did:key:elder", To: fmt.Sprintf("did:key:e%02d", i), Epoch: int64(10 * i)})
+	}
+	edges = append(edges, VouchEdge{From: "did:key:e00", To: "did:key:elder", Epoch: 51})
+
+	clusters, err := detectTemporal(edgeGraph(edges...), DefaultTemporalDetectionConfig(), "test_context", 200)
+	if err != nil {
+		t.Fatalf("detectTemporal failed: %v", err)
+	}
+	bursts := clustersBy(clusters, DetectorVelocity)
+	if len(bursts) != 1 {
+		t.Fatalf("Expected one burst, got %d", len(bursts))
+	}
+	if bursts[0].Members[len(bursts[0].Members)-1] != "did:key:sybil" || len(bursts[0].Evidence) != 12 {
+		t.Errorf("Expected the sybil's 12 vouches, got %v", bursts[0].Members)
+	}
+	if bursts[0].Confidence != 1.0 {
+		t.Errorf("Expected full confidence without incoming vouches, got %f", bursts[0].Confidence)
+	}
+}
+
+func TestDetectTemporal_FastCores(t *testing.T) {
+	var edges []VouchEdge
+	for _, from := range []string{"did:key:v1", "did:key:v2", "did:key:v3"} {
+		for _, to := range []string{"did:key:t1", "did:key:t2", "did:key:t3"} {
+			edges = append(edges, VouchEdge{From: from, To: to, Epoch: 20})
+		}
+		// Vouches outside the core are not evidence
+		edges = append(edges, VouchEdge{From: from, To: "did:key:other" + from[8:], Epoch: 20})
+	}
+
+	clusters, err := detectTemporal(edgeGraph(edges...), DefaultTemporalDetectionConfig(), "test_context", 30)
+	if err != nil {
+		t.Fatalf("detectTemporal failed: %v", err)
+	}
+	cores := clustersBy(clusters, DetectorFastCore)
+	if len(cores) != 1 {
+		t.Fatalf("Expected one core, got %d", len(cores))
+	}
+	if len(cores[0].Members) != 6 || len(cores[0].Evidence) != 9 {
+		t.Errorf("Expected a 3x3 core, got %v", cores[0].Members)
+	}
+
+	// The same core built slowly is not flagged
+	for i := range edges {
+		edges[i].Epoch = int64(10 * i)
+	}
+	clusters, _ = detectTemporal(edgeGraph(edges...), DefaultTemporalDetectionConfig(), "test_context", 200)
+	if cores := clustersBy(clusters, DetectorFastCore); len(cores) != 0 {
+		t.Errorf("Expected a slowly formed core to pass, got %d", len(cores))
+	}
+}
+
+func TestNetworkGraphAnalyzer_VouchPenalties(t *testing.T) {
+	penalties := applyCollusionRules([]*CollusionCluster{
+		{Detector: DetectorReciprocalCycle, Confidence: 0.9, Evidence: []VouchEdge{{From: "a", To: "b"}, {From: "b", To: "a"}}},
+		{Detector: DetectorFastCore, Confidence: 0.9, Evidence: []VouchEdge{{From: "a", To: "b"}}},
+		{Detector: DetectorVelocity, Confidence: 0.2, Evidence: []VouchEdge{{From: "c", To: "d"}}},
+	}, DefaultCollusionRules(), "test_context", 100)
+
+	// The strongest rule wins rather than compounding
+	if factor := penalties.Factor("a", "b"); factor != 0.4 {
+		t.Errorf("Expected the fast core factor 0.4, got %f", factor)
+	}
+	if factor := penalties.Factor("b", "a"); factor != 0.8 {
+		t.Errorf("Expected the cycle factor 0.8, got %f", factor)
+	}
+	if factor := penalties.Factor("c", "d"); factor != 1.0 {
+		t.Errorf("Expected a low-confidence detection to be ignored, got %f", factor)
+	}
+	if penalties.Detections != 2 {
+		t.Errorf("Expected 2 detections applied, got %d", penalties.Detections)
+	}
+
+	// The engine passes penalized vouches on at reduced weight
+	provider := NewTestDataProvider().(*TestDataProvider)
+	addCliques(provider, false)
+	analyzer := NewNetworkGraphAnalyzer(provider).(*NetworkGraphAnalyzer)
+	ctx := context.Background()
+
+	penalized, err := analyzer.VouchPenalties(ctx, "test_context", 100)
+	if err != nil {
+		t.Fatalf("VouchPenalties failed: %v", err)
+	}
+	if factor := penalized.Factor("did:key:b1", "did:key:b0"); factor >= 1.0 {
+		t.Errorf("Expected the mutual vouches in the clique to be penalized, got %f", factor)
+	}
+
+	engine := NewDeterministicEngine(DefaultScoreConfig(), provider, nil, analyzer,
+		NewExponentialDecayFunction(), NewTestValidator(), nil)
+	before, err := engine.solve(ctx, "test_context", 100, []string{"did:key:b0"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	analyzer.SetCollusionRules(nil)
+	after, err := engine.solve(ctx, "test_context", 100, []string{"did:key:b0"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	if before.Scores["did:key:b0"].Components.V >= after.Scores["did:key:b0"].Components.V {
+		t.Errorf("Expected penalties to lower V: %f with rules, %f without",
+			before.Scores["did:key:b0"].Components.V, after.Scores["did:key:b0"].Components.V)
+	}
+}
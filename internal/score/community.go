@@ -0,0 +1,420 @@
+package score
+
+import (
+	"context"
+	"sort"
+)
+
+// CommunityPartition is the community structure of a context's vouch graph
+// at an epoch, as found by the Leiden algorithm
+type CommunityPartition struct {
+	Context     string            `json:"context"`
+	Epoch       int64             `json:"epoch"`
+	Communities map[string]string `json:"communities"` // DID -> community ID
+	Sizes       map[string]int    `json:"sizes"`       // Community ID -> members
+	Modularity  float64           `json:"modularity"`
+	Levels      int               `json:"levels"` // Aggregation levels run
+	Nodes       int               `json:"nodes"`
+	Edges       int               `json:"edges"`
+
+	graph *VouchGraph
+}
+
+// CommunityDetector is implemented by graph analyzers that partition the
+// vouch graph into communities
+type CommunityDetector interface {
+	// DetectCommunities returns the partition of the context's complete
+	// vouch graph for an epoch, and caches it for scoring
+	DetectCommunities(ctx context.Context, context string, epoch int64) (*CommunityPartition, error)
+
+	// PreviewCommunities returns the same partition without caching it, for
+	// readers that must not change what scoring sees
+	PreviewCommunities(ctx context.Context, context string, epoch int64) (*CommunityPartition, error)
+
+	// InvalidateCommunities drops the cached partition after the vouch
+	// graph changes within an epoch
+	InvalidateCommunities(context string, epoch int64)
+}
+
+// Community returns the community of a DID. DIDs outside the graph form
+// communities of their own.
+func (p *CommunityPartition) Community(did string) string {
+	if community, ok := p.Communities[did]; ok {
+		return community
+	}
+	return communityID(did)
+}
+
+func communityID(did string) string {
+	return "community:" + did
+}
+
+// communityLink is an undirected edge to a node
+type communityLink struct {
+	node   int
+	weight Fixed
+}
+
+// communityGraph is an undirected weighted graph over node indices. Weights
+// are Fixed so that every comparison of modularity gains, and so the
+// partition, is the same on every platform.
+type communityGraph struct {
+	links  [][]communityLink // Sorted by node, without self-links
+	self   []Fixed           // A_ii: twice the weight inside an aggregated node
+	degree []Fixed           // k_i
+	total  Fixed             // 2m
+}
+
+// maxMoveSweeps bounds the local moving phase. Each sweep that moves a node
+// raises modularity, so the bound is only reached on pathological graphs.
+const maxMoveSweeps = 64
+
+// newCommunityGraph builds a graph from weights keyed by node pairs with the
+// lower index first
+func newCommunityGraph(size int, weights map[[2]int]Fixed) *communityGraph {
+	g := &communityGraph{
+		links:  make([][]communityLink, size),
+		self:   make([]Fixed, size),
+		degree: make([]Fixed, size),
+	}
+	for pair, weight := range weights {
+		if pair[0] == pair[1] {
+			g.self[pair[0]] += 2 * weight
+			g.degree[pair[0]] += 2 * weight
+			continue
+		}
+		g.links[pair[0]] = append(g.links[pair[0]], communityLink{node: pair[1], weight: weight})
+		g.links[pair[1]] = append(g.links[pair[1]], communityLink{node: pair[0], weight: weight})
+		g.degree[pair[0]] += weight
+		g.degree[pair[1]] += weight
+	}
+	for i := range g.links {
+		sort.Slice(g.links[i], func(a, b int) bool { return g.links[i][a].node < g.links[i][b].node })
+		g.total += g.degree[i]
+	}
+	return g
+}
+
+// gain is the modularity gain of joining a community, up to the constant
+// factor 1/m: the weight to the community less what a random graph with the
+// same degrees would give
+func (g *communityGraph) gain(weightTo, communityDegree, nodeDegree Fixed) Fixed {
+	return weightTo - communityDegree.MulDiv(nodeDegree, g.total)
+}
+
+// weightsTo sums a node's links by the community of the other end
+func (g *communityGraph) weightsTo(node int, membership []int) (map[int]Fixed, []int) {
+	weights := make(map[int]Fixed)
+	var communities []int
+	for _, link := range g.links[node] {
+		community := membership[link.node]
+		if _, ok := weights[community]; !ok {
+			communities = append(communities, community)
+		}
+		weights[community] += link.weight
+	}
+	sort.Ints(communities)
+	return weights, communities
+}
+
+// moveNodes is the local moving phase: nodes are visited in index order and
+// moved to the neighbouring community with the largest strictly positive
+// improvement, ties going to the lower community, until a sweep moves
+// nothing. It reports whether any node moved.
+func (g *communityGraph) moveNodes(membership []int) bool {
+	communityDegree := make([]Fixed, len(membership))
+	for node, community := range membership {
+		communityDegree[community] += g.degree[node]
+	}
+
+	moved := false
+	for sweep := 0; sweep < maxMoveSweeps; sweep++ {
+		changed := false
+		for node := range membership {
+			current := membership[node]
+			weights, communities := g.weightsTo(node, membership)
+			communityDegree[current] -= g.degree[node]
+
+			best := current
+			bestGain := g.gain(weights[current], communityDegree[current], g.degree[node])
+			for _, community := range communities {
+				if gain := g.gain(weights[community], communityDegree[community], g.degree[node]); gain > bestGain {
+					best, bestGain = community, gain
+				}
+			}
+
+			communityDegree[best] += g.degree[node]
+			if best != current {
+				membership[node] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		moved = true
+	}
+	return moved
+}
+
+// refine splits each community into well-connected subcommunities, the step
+// that distinguishes Leiden from Louvain. Every node starts alone and, if it
+// is well connected to its community, merges into the well-connected
+// subcommunity of that community with the largest positive gain. Leiden
+// picks among improving merges at random; the best one is taken here so the
+// result is deterministic.
+func (g *communityGraph) refine(membership []int) []int {
+	size := len(membership)
+	communityDegree := make([]Fixed, size)
+	for node, community := range membership {
+		communityDegree[community] += g.degree[node]
+	}
+
+	refined := make([]int, size)
+	refinedDegree := make([]Fixed, size)
+	external := make([]Fixed, size) // Weight from a subcommunity to the rest of its community
+	alone := make([]bool, size)
+	for node := range refined {
+		refined[node] = node
+		refinedDegree[node] = g.degree[node]
+		alone[node] = true
+		for _, link := range g.links[node] {
+			if membership[link.node] == membership[node] {
+				external[node] += link.weight
+			}
+		}
+	}
+
+	wellConnected := func(external, degree, community Fixed) bool {
+		return external >= degree.MulDiv(community-degree, g.total)
+	}
+
+	for node := range refined {
+		community := membership[node]
+		if !alone[node] || !wellConnected(external[node], g.degree[node], communityDegree[community]) {
+			continue
+		}
+
+		weights := make(map[int]Fixed)
+		var candidates []int
+		for _, link := range g.links[node] {
+			if membership[link.node] != community {
+				continue
+			}
+			target := refined[link.node]
+			if _, ok := weights[target]; !ok {
+				candidates = append(candidates, target)
+			}
+			weights[target] += link.weight
+		}
+		sort.Ints(candidates)
+
+		best, bestGain := node, Fixed(0)
+		for _, target := range candidates {
+			if target == node || !wellConnected(external[target], refinedDegree[target], communityDegree[community]) {
+				continue
+			}
+			if gain := g.gain(weights[target], refinedDegree[target], g.degree[node]); gain > bestGain {
+				best, bestGain = target, gain
+			}
+		}
+		if best == node {
+			continue
+		}
+
+		refined[node] = best
+		refinedDegree[best] += g.degree[node]
+		external[best] += external[node] - 2*weights[best]
+		alone[node] = false
+		alone[best] = false
+	}
+	return refined
+}
+
+// aggregate collapses each subcommunity into a node, numbered in order of
+// their lowest member, and returns the node each old node became
+func (g *communityGraph) aggregate(refined []int) (*communityGraph, []int) {
+	index := make([]int, len(refined))
+	numbers := make(map[int]int)
+	for node, community := range refined {
+		number, ok := numbers[community]
+		if !ok {
+			number = len(numbers)
+			numbers[community] = number
+		}
+		index[node] = number
+	}
+
+	weights := make(map[[2]int]Fixed)
+	for node, links := range g.links {
+		from := index[node]
+		if g.self[node] != 0 {
+			weights[[2]int{from, from}] += g.self[node] / 2
+		}
+		for _, link := range links {
+			if link.node < node {
+				continue
+			}
+			to := index[link.node]
+			weights[[2]int{min(from, to), max(from, to)}] += link.weight
+		}
+	}
+	return newCommunityGraph(len(numbers), weights), index
+}
+
+// modularity returns Q = sum over communities of in/2m - (tot/2m)^2
+func (g *communityGraph) modularity(membership []int) Fixed {
+	if g.total == 0 {
+		return 0
+	}
+	inside := make(map[int]Fixed)
+	degree := make(map[int]Fixed)
+	for node, community := range membership {
+		inside[community] += g.self[node]
+		degree[community] += g.degree[node]
+		for _, link := range g.links[node] {
+			if membership[link.node] == community {
+				inside[community] += link.weight
+			}
+		}
+	}
+
+	communities := make([]int, 0, len(degree))
+	for community := range degree {
+		communities = append(communities, community)
+	}
+	sort.Ints(communities)
+
+	var q Fixed
+	for _, community := range communities {
+		share := degree[community].Div(g.total)
+		q += inside[community].Div(g.total) - share.Mul(share)
+	}
+	return q
+}
+
+// leiden partitions the graph, returning each node's community numbered by
+// its lowest node, and the aggregation levels run
+func leiden(g *communityGraph) ([]int, int) {
+	size := len(g.degree)
+	nodes := make([]int, size) // Original node -> node of the current graph
+	partition := make([]int, size)
+	for node := range nodes {
+		nodes[node] = node
+		partition[node] = node
+	}
+
+	current, levels := g, 0
+	for {
+		levels++
+		if !current.moveNodes(partition) {
+			break
+		}
+
+		aggregated, index := current.aggregate(current.refine(partition))
+		if len(aggregated.degree) == len(current.degree) {
+			break
+		}
+
+		// Aggregated nodes start in the community their members were moved to
+		next := make([]int, len(aggregated.degree))
+		for node, community := range partition {
+			next[index[node]] = community
+		}
+		renumber(next)
+		for original := range nodes {
+			nodes[original] = index[nodes[original]]
+		}
+		current, partition = aggregated, next
+	}
+
+	membership := make([]int, size)
+	for original := range membership {
+		membership[original] = partition[nodes[original]]
+	}
+	first := make(map[int]int)
+	for node, community := range membership {
+		if _, ok := first[community]; !ok {
+			first[community] = node
+		}
+		membership[node] = first[community]
+	}
+	return membership, levels
+}
+
+// renumber maps community labels onto 0..k-1 in order of first appearance
+func renumber(membership []int) {
+	numbers := make(map[int]int)
+	for node, community := range membership {
+		number, ok := numbers[community]
+		if !ok {
+			number = len(numbers)
+			numbers[community] = number
+		}
+		membership[node] = number
+	}
+}
+
+// partitionGraph runs Leiden over a vouch graph. Both directions of a vouch
+// count towards the same undirected edge.
+func partitionGraph(graph *VouchGraph, context string, epoch int64) *CommunityPartition {
+	dids := make([]string, 0, len(graph.Nodes))
+	for did := range graph.Nodes {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	index := make(map[string]int, len(dids))
+	for i, did := range dids {
+		index[did] = i
+	}
+
+	weights := make(map[[2]int]Fixed)
+	for _, edge := range graph.Edges {
+		from, to := index[edge.From], index[edge.To]
+		weights[[2]int{min(from, to), max(from, to)}] += FixedFromFloat(edge.Strength)
+	}
+
+	g := newCommunityGraph(len(dids), weights)
+	membership, levels := leiden(g)
+
+	partition := &CommunityPartition{
+		Context:     context,
+		Epoch:       epoch,
+		Communities: make(map[string]string, len(dids)),
+		Sizes:       make(map[string]int),
+		Modularity:  g.modularity(membership).Float64(),
+		Levels:      levels,
+		Nodes:       len(dids),
+		Edges:       len(graph.Edges),
+		graph:       graph,
+	}
+	for i, did := range dids {
+		// Communities are named after their lowest DID
+		community := communityID(dids[membership[i]])
+		partition.Communities[did] = community
+		partition.Sizes[community]++
+	}
+	return partition
+}
+
+// vouchDiversityWeights returns the diversity part of q_ij for each voucher
+// of a DID. A voucher sharing a community with n-1 of the DID's other
+// vouchers is weighted 1 - penalty*(1 - 1/n), so a bloc counts for less than
+// the same number of vouchers from distinct communities.
+func vouchDiversityWeights(partition *CommunityPartition, vouches []*VouchData, penalty Fixed) map[string]Fixed {
+	members := make(map[string]map[string]bool)
+	for _, vouch := range vouches {
+		community := partition.Community(vouch.FromDID)
+		if members[community] == nil {
+			members[community] = make(map[string]bool)
+		}
+		members[community][vouch.FromDID] = true
+	}
+
+	weights := make(map[string]Fixed, len(vouches))
+	for _, vouch := range vouches {
+		n := FixedFromInt(int64(len(members[partition.Community(vouch.FromDID)])))
+		weights[vouch.FromDID] = FixedOne - penalty.Mul(FixedOne-FixedOne.Div(n))
+	}
+	return weights
+}
@@ -0,0 +1,216 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// addCliques adds two groups of four DIDs who all vouch for each other,
+// joined by a single weak vouch
+func addCliques(provider *TestDataProvider, reverse bool) {
+	var vouches []*VouchData
+	for _, group := range []string{"a", "b"} {
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				if i != j {
+					vouches = append(vouches, &VouchData{
+						FromDID:  fmt.Sprintf("did:key:%s%d", group, i),
+						ToDID:    fmt.Sprintf("did:key:%s%d", group, j),
+						Strength: 10,
+						Epoch:    100,
+					})
+				}
+			}
+		}
+	}
+	vouches = append(vouches, &VouchData{FromDID: "did:key:a0", ToDID: "did:key:b0", Strength: 1, Epoch: 100})
+
+	for k := range vouches {
+		vouch := vouches[k]
+		if reverse {
+			vouch = vouches[len(vouches)-1-k]
+		}
+		vouch.Context = "test_context"
+		provider.AddVouch(vouch)
+	}
+}
+
+func TestNetworkGraphAnalyzer_DetectCommunities(t *testing.T) {
+	provider := NewTestDataProvider().(*TestDataProvider)
+	addCliques(provider, false)
+	analyzer := NewNetworkGraphAnalyzer(provider).(*NetworkGraphAnalyzer)
+	ctx := context.Background()
+
+	partition, err := analyzer.DetectCommunities(ctx, "test_context", 100)
+	if err != nil {
+		t.Fatalf("DetectCommunities failed: %v", err)
+	}
+	// The whole epoch is partitioned, including the provider's default
+	// voucher1 -> test123 pair
+	if partition.Nodes != 10 || partition.Edges != 26 {
+		t.Fatalf("Expected the epoch graph to have 10 DIDs and 26 vouches, got %d and %d",
+			partition.Nodes, partition.Edges)
+	}
+	if len(partition.Sizes) != 3 {
+		t.Fatalf("Expected three communities, got %v", partition.Communities)
+	}
+	for i := 0; i < 4; i++ {
+		for group, community := range map[string]string{"a": "community:did:key:a0", "b": "community:did:key:b0"} {
+			did := fmt.Sprintf("did:key:%s%d", group, i)
+			if partition.Communities[did] != community {
+				t.Errorf("Expected %s in %s, got %s", did, community, partition.Communities[did])
+			}
+		}
+	}
+	// Two equal halves joined by a weak link put Q just under 1/2; the
+	// separate pair lifts it to about 0.55
+	if partition.Modularity < 0.54 || partition.Modularity >= 0.56 {
+		t.Errorf("Expected modularity of about 0.55, got %f", partition.Modularity)
+	}
+	if partition.Community("did:key:nobody") != "community:did:key:nobody" {
+		t.Errorf("Expected an unknown DID to be its own community")
+	}
+
+	// The same graph built in another order partitions identically
+	reversed := NewTestDataProvider().(*TestDataProvider)
+	addCliques(reversed, true)
+	other, err := NewNetworkGraphAnalyzer(reversed).(*NetworkGraphAnalyzer).DetectCommunities(ctx, "test_context", 100)
+	if err != nil {
+		t.Fatalf("DetectCommunities failed: %v", err)
+	}
+	if other.Modularity != partition.Modularity {
+		t.Errorf("Expected identical modularity, got %f and %f", other.Modularity, partition.Modularity)
+	}
+	for did, community := range partition.Communities {
+		if other.Communities[did] != community {
+			t.Errorf("Community of %s differs: %s vs %s", did, community, other.Communities[did])
+		}
+	}
+
+	// Reads do not cache a partition
+	preview, err := NewNetworkGraphAnalyzer(provider).(*NetworkGraphAnalyzer).PreviewCommunities(ctx, "test_context", 100)
+	if err != nil {
+		t.Fatalf("PreviewCommunities failed: %v", err)
+	}
+	if preview.Modularity != partition.Modularity {
+		t.Errorf("Expected the preview to match, got modularity %f", preview.Modularity)
+	}
+	if cached, _ := analyzer.PreviewCommunities(ctx, "test_context", 100); cached != partition {
+		t.Error("Expected the preview to return the cached partition")
+	}
+
+	// Partitions are cached per epoch until invalidated
+	again, _ := analyzer.DetectCommunities(ctx, "test_context", 100)
+	if again != partition {
+		t.Error("Expected the cached partition to be reused")
+	}
+	analyzer.InvalidateCommunities("test_context", 100)
+	if again, _ = analyzer.DetectCommunities(ctx, "test_context", 100); again == partition {
+		t.Error("Expected the partition to be rebuilt after invalidation")
+	}
+}
+
+func TestCommunityGraph_Leiden(t *testing.T) {
+	// A ring of six triangles, each joined to the next by one edge
+	weights := make(map[[2]int]Fixed)
+	for c := 0; c < 6; c++ {
+		base := 3 * c
+		weights[[2]int{base, base + 1}] = FixedOne
+		weights[[2]int{base, base + 2}] = FixedOne
+		weights[[2]int{base + 1, base + 2}] = FixedOne
+		next := (base + 3) % 18
+		weights[[2]int{min(base+2, next), max(base+2, next)}] = FixedOne
+	}
+	g := newCommunityGraph(18, weights)
+	membership, _ := leiden(g)
+
+	for node, community := range membership {
+		if community != node/3*3 {
+			t.Errorf("Expected node %d in triangle %d, got %d", node, node/3*3, community)
+		}
+	}
+	// Q = 6 * (6/48 - (8/48)^2), each term rounded to six places
+	if q := g.modularity(membership).Float64(); math.Abs(q-(0.75-6*64/(48.0*48.0))) > 6e-6 {
+		t.Errorf("Unexpected modularity %f", q)
+	}
+}
+
+func TestVouchDiversityWeights(t *testing.T) {
+	provider := NewTestDataProvider().(*TestDataProvider)
+	addCliques(provider, false)
+	analyzer := NewNetworkGraphAnalyzer(provider).(*NetworkGraphAnalyzer)
+	ctx := context.Background()
+
+	partition, err := analyzer.DetectCommunities(ctx, "test_context", 100)
+	if err != nil {
+		t.Fatalf("DetectCommunities failed: %v", err)
+	}
+	vouches, _ := provider.GetVouches(ctx, "did:key:b0", "test_context", 100)
+	weights := vouchDiversityWeights(partition, vouches, FixedFromFloat(0.2))
+
+	// Three vouchers from b0's own clique and one from the other
+	if w := weights["did:key:b1"]; w != 866667 {
+		t.Errorf("Expected 1 - 0.2*(2/3) for a bloc voucher, got %s", w)
+	}
+	if w := weights["did:key:a0"]; w != FixedOne {
+		t.Errorf("Expected full weight for the lone outside voucher, got %s", w)
+	}
+
+	diversity, err := analyzer.ComputeDiversity(ctx, "did:key:b0", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ComputeDiversity failed: %v", err)
+	}
+	// Entropy of a 3:1 split over two communities
+	if diversity < 0.81 || diversity > 0.82 {
+		t.Errorf("Expected diversity of about 0.811, got %f", diversity)
+	}
+
+	// Diversity-weighted vouches lower V against unweighted ones
+	weighted := NewDeterministicEngine(DefaultScoreConfig(), provider, nil, analyzer,
+		NewExponentialDecayFunction(), NewTestValidator(), nil)
+	plain := NewDeterministicEngine(DefaultScoreConfig(), provider, nil, nil,
+		NewExponentialDecayFunction(), NewTestValidator(), nil)
+	withWeights, err := weighted.solve(ctx, "test_context", 100, []string{"did:key:b0"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	without, err := plain.solve(ctx, "test_context", 100, []string{"did:key:b0"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	if withWeights.Scores["did:key:b0"].Components.V >= without.Scores["did:key:b0"].Components.V {
+		t.Errorf("Expected bloc vouches to count for less: %f vs %f",
+			withWeights.Scores["did:key:b0"].Components.V, without.Scores["did:key:b0"].Components.V)
+	}
+}
+
+// unlistedProvider hides the DID listing of a data provider
+type unlistedProvider struct {
+	DataProvider
+}
+
+func TestNetworkGraphAnalyzer_RequiresDIDLister(t *testing.T) {
+	provider := NewTestDataProvider().(*TestDataProvider)
+	addCliques(provider, false)
+	analyzer := NewNetworkGraphAnalyzer(unlistedProvider{provider}).(*NetworkGraphAnalyzer)
+	ctx := context.Background()
+
+	if _, err := analyzer.DetectCommunities(ctx, "test_context", 100); err == nil {
+		t.Fatal("Expected partitioning to fail without a DID lister")
+	}
+	if _, err := analyzer.PreviewCommunities(ctx, "test_context", 100); err == nil {
+		t.Fatal("Expected previews to fail without a DID lister")
+	}
+	if len(analyzer.partitions) != 0 {
+		t.Errorf("Expected nothing cached, got %d partitions", len(analyzer.partitions))
+	}
+
+	// Scoring does not fall back to unweighted vouches
+	engine := NewDeterministicEngine(DefaultScoreConfig(), unlistedProvider{provider}, nil, analyzer,
+		NewExponentialDecayFunction(), NewTestValidator(), nil)
+	if _, err := engine.solve(ctx, "test_context", 100, []string{"did:key:b0"}, nil); err == nil {
+		t.Error("Expected solving to fail when communities cannot be detected")
+	}
+}
@@ -15,54 +15,57 @@ func NewExponentialDecayFunction() DecayFunction {
 // ApplyDecay implements DecayFunction.ApplyDecay
 // Uses exponential decay: value * (0.5)^(elapsedEpochs / halfLife)
 func (d *ExponentialDecayFunction) ApplyDecay(value float64, elapsedEpochs int64, halfLife int64) float64 {
-	if elapsedEpochs <= 0 || halfLife <= 0 {
-		return value
-	}
-	
-	// Calculate decay factor: (0.5)^(elapsed / halfLife)
-	decayFactor := math.Pow(0.5, float64(elapsedEpochs)/float64(halfLife))
-	
-	return value * decayFactor
+	return d.ApplyDecayFixed(FixedFromFloat(value), elapsedEpochs, halfLife).Float64()
 }
 
 // ApplyInactivityDecay implements DecayFunction.ApplyInactivityDecay
 // Uses exponential decay for inactivity: value * (1 - decayRate)^inactiveEpochs
 func (d *ExponentialDecayFunction) ApplyInactivityDecay(value float64, inactiveEpochs int64, decayRate float64) float64 {
+	return d.ApplyInactivityDecayFixed(FixedFromFloat(value), inactiveEpochs, FixedFromFloat(decayRate)).Float64()
+}
+
+// ComputeTimeBonus implements DecayFunction.ComputeTimeBonus
+// Computes bounded logarithmic growth: min(maxGrowth, log(1 + age) * activity_factor)
+func (d *ExponentialDecayFunction) ComputeTimeBonus(firstActivity, lastActivity int64, currentEpoch int64, maxGrowth float64) float64 {
+	return d.ComputeTimeBonusFixed(firstActivity, lastActivity, currentEpoch, FixedFromFloat(maxGrowth)).Float64()
+}
+
+// ApplyDecayFixed implements FixedDecayFunction.ApplyDecayFixed
+func (d *ExponentialDecayFunction) ApplyDecayFixed(value Fixed, elapsedEpochs int64, halfLife int64) Fixed {
+	return FixedHalfLifeDecay(value, elapsedEpochs, halfLife)
+}
+
+// ApplyInactivityDecayFixed implements FixedDecayFunction.ApplyInactivityDecayFixed
+func (d *ExponentialDecayFunction) ApplyInactivityDecayFixed(value Fixed, inactiveEpochs int64, decayRate Fixed) Fixed {
 	if inactiveEpochs <= 0 || decayRate <= 0 {
 		return value
 	}
 	
 	// Ensure decay rate is bounded [0, 1]
-	if decayRate > 1.0 {
-		decayRate = 1.0
+	if decayRate > FixedOne {
+		decayRate = FixedOne
 	}
 	
 	// Calculate decay factor: (1 - decayRate)^inactiveEpochs
-	decayFactor := math.Pow(1.0-decayRate, float64(inactiveEpochs))
-	
-	return value * decayFactor
+	return value.mulQ62(powQ62(fixedFractionQ62(FixedOne-decayRate), inactiveEpochs))
 }
 
-// ComputeTimeBonus implements DecayFunction.ComputeTimeBonus
-// Computes bounded logarithmic growth: min(maxGrowth, log(1 + age) * activity_factor)
-func (d *ExponentialDecayFunction) ComputeTimeBonus(firstActivity, lastActivity int64, currentEpoch int64, maxGrowth float64) float64 {
+// ComputeTimeBonusFixed implements FixedDecayFunction.ComputeTimeBonusFixed
+func (d *ExponentialDecayFunction) ComputeTimeBonusFixed(firstActivity, lastActivity int64, currentEpoch int64, maxGrowth Fixed) Fixed {
 	if firstActivity < 0 || currentEpoch <= firstActivity {
 		return 0
 	}
 	
-	// Calculate age in epochs
-	ageEpochs := float64(currentEpoch - firstActivity)
-	
 	// Calculate activity factor (how recently they were active)
 	activityEpochs := currentEpoch - lastActivity
-	activityFactor := 1.0
+	activityFactor := FixedOne
 	if activityEpochs > 0 {
-		// Reduce bonus for inactivity
-		activityFactor = 1.0 / (1.0 + float64(activityEpochs)*0.1)
+		// Reduce bonus for inactivity: 1 / (1 + activityEpochs/10)
+		activityFactor = FixedOne.Div(FixedOne + FixedFromInt(activityEpochs).Div(FixedFromInt(10)))
 	}
 	
 	// Logarithmic growth with activity weighting
-	timeBonus := math.Log(1.0+ageEpochs) * activityFactor
+	timeBonus := FixedLn(1 + currentEpoch - firstActivity).Mul(activityFactor)
 	
 	// Apply maximum growth bound
 	if timeBonus > maxGrowth {
@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
-	"math"
 	"sort"
 	"time"
 
@@ -21,6 +20,13 @@ type DeterministicEngine struct {
 	decayFunc     DecayFunction
 	validator     ScoreValidator
 	signer        crypto.Signer
+
+	issuerReputation IssuerReputationProvider
+	bonds            BondProvider
+	propagation      *PropagationConfig
+	snapshots        ScoreSnapshotStore
+	history          ScoreHistory
+	explanation      *ExplanationConfig
 }
 
 // NewDeterministicEngine creates a new deterministic scoring engine
@@ -45,12 +51,60 @@ func NewDeterministicEngine(
 		decayFunc:     decayFunc,
 		validator:     validator,
 		signer:        signer,
+		propagation:   DefaultPropagationConfig(),
+		explanation:   DefaultExplanationConfig(),
+	}
+}
+
+// SetIssuerReputation weights KYC and attestation issuers by w(issuer) from
+// the given provider instead of the reputation carried on the input data
+func (e *DeterministicEngine) SetIssuerReputation(provider IssuerReputationProvider) {
+	e.issuerReputation = provider
+}
+
+// SetBondProvider makes vouchers forfeit part of V for each of their bonds
+// slashed by an upheld report against a vouchee
+func (e *DeterministicEngine) SetBondProvider(provider BondProvider) {
+	e.bonds = provider
+}
+
+// SetPropagation sets how voucher scores are solved for
+func (e *DeterministicEngine) SetPropagation(config *PropagationConfig) {
+	if config == nil {
+		config = DefaultPropagationConfig()
 	}
+	e.propagation = config
+}
+
+// SetExplanation sets the evidence score explanations price
+// counterfactuals with
+func (e *DeterministicEngine) SetExplanation(config *ExplanationConfig) {
+	if config == nil {
+		config = DefaultExplanationConfig()
+	}
+	e.explanation = config
+}
+
+// SetSnapshotStore persists solved epochs and makes their snapshots the
+// scores per-DID queries return
+func (e *DeterministicEngine) SetSnapshotStore(store ScoreSnapshotStore) {
+	e.snapshots = store
+}
+
+// SetScoreHistory records the scores of every published snapshot in a
+// score history
+func (e *DeterministicEngine) SetScoreHistory(history ScoreHistory) {
+	e.history = history
 }
 
 // ComputeScore implements Engine.ComputeScore
 func (e *DeterministicEngine) ComputeScore(ctx context.Context, did, context string, epoch int64) (*Score, error) {
-	// Check for cached score first
+	// The solved epoch snapshot is authoritative
+	if score := e.snapshotScore(ctx, did, context, epoch); score != nil {
+		return score, nil
+	}
+	
+	// Check for cached score next
 	if cachedScore, err := e.dataProvider.GetScore(ctx, did, context, epoch); err == nil && cachedScore != nil {
 		return cachedScore, nil
 	}
@@ -70,16 +124,7 @@ func (e *DeterministicEngine) ComputeScore(ctx context.Context, did, context str
 	
 	// Apply the scoring formula: S_i^c = α*K_i^c + β*A_i^c + γ*V_i^c - δ*R_i^c + τ*T_i^c
 	factors := e.config.Factors
-	totalScore := factors.Alpha*components.K +
-		factors.Beta*components.A +
-		factors.Gamma*components.V -
-		factors.Delta*components.R +
-		factors.Tau*components.T
-	
-	// Ensure score is non-negative
-	if totalScore < 0 {
-		totalScore = 0
-	}
+	totalScore := e.combineComponents(components).Float64()
 	
 	// Create score object
 	score := &Score{
@@ -112,52 +157,87 @@ func (e *DeterministicEngine) ComputeScore(ctx context.Context, did, context str
 	return score, nil
 }
 
-// computeComponents computes all score components
+// computeComponents returns a DID's components from the epoch snapshot, or
+// solves the DID together with its vouchers when no snapshot covers it
 func (e *DeterministicEngine) computeComponents(ctx context.Context, did, context string, epoch int64) (*ScoreComponents, error) {
+	if score := e.snapshotScore(ctx, did, context, epoch); score != nil {
+		components := score.Components
+		return &components, nil
+	}
+	return e.solveComponents(ctx, did, context, epoch)
+}
+
+// solveComponents computes a DID's components from fresh data. V depends on
+// the vouchers' scores, so everyone upstream of the DID is solved with it.
+func (e *DeterministicEngine) solveComponents(ctx context.Context, did, context string, epoch int64) (*ScoreComponents, error) {
+	snapshot, err := e.solve(ctx, context, epoch, []string{did}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("V factor computation failed: %w", err)
+	}
+	components := snapshot.Scores[did].Components
+	return &components, nil
+}
+
+// computeStaticComponents computes the components that do not depend on
+// other DIDs' scores, leaving V at zero
+func (e *DeterministicEngine) computeStaticComponents(ctx context.Context, did, context string, epoch int64) (*ScoreComponents, error) {
 	var components ScoreComponents
-	var err error
+	
+	if e.issuerReputation != nil {
+		components.IssuerWeights = make(map[string]float64)
+	}
 	
 	// Compute K factor (PoP/KYC)
-	components.K, err = e.computeKFactor(ctx, did, context, epoch)
+	k, err := e.computeKFactor(ctx, did, context, epoch, components.IssuerWeights)
 	if err != nil {
 		return nil, fmt.Errorf("K factor computation failed: %w", err)
 	}
+	components.K = k.Float64()
 	
 	// Compute A factor (Attestations)
-	components.A, err = e.computeAFactor(ctx, did, context, epoch)
+	a, err := e.computeAFactor(ctx, did, context, epoch, components.IssuerWeights)
 	if err != nil {
 		return nil, fmt.Errorf("A factor computation failed: %w", err)
 	}
-	
-	// Compute V factor (Vouches)
-	components.V, err = e.computeVFactor(ctx, did, context, epoch)
-	if err != nil {
-		return nil, fmt.Errorf("V factor computation failed: %w", err)
-	}
+	components.A = a.Float64()
 	
 	// Compute R factor (Reports)
-	components.R, err = e.computeRFactor(ctx, did, context, epoch)
+	r, err := e.computeRFactor(ctx, did, context, epoch)
 	if err != nil {
 		return nil, fmt.Errorf("R factor computation failed: %w", err)
 	}
+	components.R = r.Float64()
 	
 	// Compute T factor (Time)
-	components.T, err = e.computeTFactor(ctx, did, context, epoch)
+	t, err := e.computeTFactor(ctx, did, context, epoch)
 	if err != nil {
 		return nil, fmt.Errorf("T factor computation failed: %w", err)
 	}
+	components.T = t.Float64()
+	
+	if len(components.IssuerWeights) == 0 {
+		components.IssuerWeights = nil
+	}
 	
 	return &components, nil
 }
 
-// computeKFactor computes the PoP/KYC factor
-func (e *DeterministicEngine) computeKFactor(ctx context.Context, did, context string, epoch int64) (float64, error) {
+// computeKFactor computes the PoP/KYC factor, weighting each credential by
+// its issuer's reputation when a provider is configured
+func (e *DeterministicEngine) computeKFactor(ctx context.Context, did, context string, epoch int64, issuerWeights map[string]float64) (Fixed, error) {
+	_, terms, err := e.kycTerms(ctx, did, context, epoch, issuerWeights)
+	return sumFixed(terms), err
+}
+
+// kycTerms returns a DID's unexpired KYC credentials with what each adds to K
+func (e *DeterministicEngine) kycTerms(ctx context.Context, did, context string, epoch int64, issuerWeights map[string]float64) ([]*KYCData, []Fixed, error) {
 	kycData, err := e.dataProvider.GetKYCData(ctx, did, context, epoch)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get KYC data: %w", err)
+		return nil, nil, fmt.Errorf("failed to get KYC data: %w", err)
 	}
 	
-	var totalWeight float64
+	var credentials []*KYCData
+	var terms []Fixed
 	now := time.Now()
 	
 	for _, kyc := range kycData {
@@ -168,87 +248,73 @@ func (e *DeterministicEngine) computeKFactor(ctx context.Context, did, context s
 		
 		// Apply decay based on age
 		ageInEpochs := epoch - kyc.Epoch
-		decayedWeight := e.decayFunc.ApplyDecay(kyc.Weight, ageInEpochs, e.config.VouchHalfLife)
+		decayedWeight := e.applyDecay(FixedFromFloat(kyc.Weight), ageInEpochs, e.config.VouchHalfLife)
 		
-		totalWeight += decayedWeight
+		if e.issuerReputation != nil {
+			issuerWeight, err := e.issuerWeight(ctx, kyc.IssuerDID, epoch, issuerWeights)
+			if err != nil {
+				return nil, nil, err
+			}
+			decayedWeight = decayedWeight.Mul(FixedFromFloat(issuerWeight))
+		}
+		
+		credentials = append(credentials, kyc)
+		terms = append(terms, decayedWeight)
 	}
 	
-	return totalWeight, nil
+	return credentials, terms, nil
 }
 
 // computeAFactor computes the attestations factor
-func (e *DeterministicEngine) computeAFactor(ctx context.Context, did, context string, epoch int64) (float64, error) {
+func (e *DeterministicEngine) computeAFactor(ctx context.Context, did, context string, epoch int64, issuerWeights map[string]float64) (Fixed, error) {
+	_, terms, err := e.attestationTerms(ctx, did, context, epoch, issuerWeights)
+	return sumFixed(terms), err
+}
+
+// attestationTerms returns a DID's attestations with what each adds to A
+func (e *DeterministicEngine) attestationTerms(ctx context.Context, did, context string, epoch int64, issuerWeights map[string]float64) ([]*AttestationData, []Fixed, error) {
 	attestations, err := e.dataProvider.GetAttestations(ctx, did, context, epoch)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get attestations: %w", err)
+		return nil, nil, fmt.Errorf("failed to get attestations: %w", err)
 	}
 	
-	var totalWeight float64
+	terms := make([]Fixed, len(attestations))
 	
-	for _, att := range attestations {
+	for i, att := range attestations {
 		// Weight attestation by issuer reputation and inherent weight
-		weightedValue := att.Weight * att.IssuerReputation
+		reputation := att.IssuerReputation
+		if e.issuerReputation != nil {
+			reputation, err = e.issuerWeight(ctx, att.IssuerDID, epoch, issuerWeights)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		weightedValue := FixedFromFloat(att.Weight).Mul(FixedFromFloat(reputation))
 		
 		// Apply decay based on age
 		ageInEpochs := epoch - att.Epoch
-		decayedWeight := e.decayFunc.ApplyDecay(weightedValue, ageInEpochs, e.config.VouchHalfLife)
-		
-		totalWeight += decayedWeight
+		terms[i] = e.applyDecay(weightedValue, ageInEpochs, e.config.VouchHalfLife)
 	}
 	
-	return totalWeight, nil
+	return attestations, terms, nil
 }
 
-// computeVFactor computes the vouches factor with concave aggregation
-func (e *DeterministicEngine) computeVFactor(ctx context.Context, did, context string, epoch int64) (float64, error) {
-	vouches, err := e.dataProvider.GetVouches(ctx, did, context, epoch)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get vouches: %w", err)
-	}
-	
-	var weightedSum float64
-	
-	for _, vouch := range vouches {
-		// Get the voucher's score (with cap)
-		voucherScore, err := e.getVoucherScore(ctx, vouch.FromDID, context, epoch)
-		if err != nil {
-			// If we can't get the voucher's score, use a default low value
-			voucherScore = 1.0
-		}
-		
-		// Apply vouch cap
-		cappedScore := math.Min(voucherScore, e.config.VouchCap)
-		
-		// Apply vouch strength and decay
-		ageInEpochs := epoch - vouch.Epoch
-		decayedStrength := e.decayFunc.ApplyDecay(vouch.Strength, ageInEpochs, e.config.VouchHalfLife)
-		
-		weightedSum += cappedScore * decayedStrength
-	}
-	
-	// Apply concave aggregation (square root)
-	concaveValue := math.Sqrt(weightedSum)
-	
-	// Apply diversity penalty if graph analyzer is available
-	if e.graphAnalyzer != nil {
-		diversity, err := e.graphAnalyzer.ComputeDiversity(ctx, did, context, epoch)
-		if err == nil {
-			diversityMultiplier := 1.0 - (e.config.DiversityPenalty * (1.0 - diversity))
-			concaveValue *= diversityMultiplier
-		}
-	}
-	
-	return concaveValue, nil
+// computeRFactor computes the reports factor
+func (e *DeterministicEngine) computeRFactor(ctx context.Context, did, context string, epoch int64) (Fixed, error) {
+	_, terms, err := e.reportTerms(ctx, did, context, epoch)
+	return sumFixed(terms), err
 }
 
-// computeRFactor computes the reports factor
-func (e *DeterministicEngine) computeRFactor(ctx context.Context, did, context string, epoch int64) (float64, error) {
+// reportTerms returns the upheld reports against a DID with what each adds
+// to R
+func (e *DeterministicEngine) reportTerms(ctx context.Context, did, context string, epoch int64) ([]*ReportData, []Fixed, error) {
 	reports, err := e.dataProvider.GetReports(ctx, did, context, epoch)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get reports: %w", err)
+		return nil, nil, fmt.Errorf("failed to get reports: %w", err)
 	}
 	
-	var totalPenalty float64
+	var upheld []*ReportData
+	var terms []Fixed
 	
 	for _, report := range reports {
 		// Only consider adjudicated and upheld reports
@@ -256,21 +322,25 @@ func (e *DeterministicEngine) computeRFactor(ctx context.Context, did, context s
 			continue
 		}
 		
-		// Apply severity weighting
-		severityWeight := report.Severity
-		
-		// Apply decay based on age
+		// Apply severity weighting and decay based on age
 		ageInEpochs := epoch - report.Epoch
-		decayedWeight := e.decayFunc.ApplyDecay(severityWeight, ageInEpochs, e.config.ReportHalfLife)
-		
-		totalPenalty += decayedWeight
+		upheld = append(upheld, report)
+		terms = append(terms, e.applyDecay(FixedFromFloat(report.Severity), ageInEpochs, e.config.ReportHalfLife))
 	}
 	
-	return totalPenalty, nil
+	return upheld, terms, nil
+}
+
+func sumFixed(terms []Fixed) Fixed {
+	var total Fixed
+	for _, term := range terms {
+		total += term
+	}
+	return total
 }
 
 // computeTFactor computes the time factor
-func (e *DeterministicEngine) computeTFactor(ctx context.Context, did, context string, epoch int64) (float64, error) {
+func (e *DeterministicEngine) computeTFactor(ctx context.Context, did, context string, epoch int64) (Fixed, error) {
 	timeData, err := e.dataProvider.GetTimeData(ctx, did, context, epoch)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get time data: %w", err)
@@ -284,33 +354,77 @@ func (e *DeterministicEngine) computeTFactor(ctx context.Context, did, context s
 	firstEpoch := timeData.FirstActivity.Unix() / 86400 // Convert to epoch days
 	lastEpoch := timeData.LastActivity.Unix() / 86400
 	
-	ageBonus := e.decayFunc.ComputeTimeBonus(firstEpoch, lastEpoch, epoch, e.config.TimeMaxGrowth)
+	ageBonus := e.timeBonus(firstEpoch, lastEpoch, epoch)
 	
 	// Apply inactivity decay
 	inactiveEpochs := epoch - lastEpoch
 	if inactiveEpochs > 0 {
-		ageBonus = e.decayFunc.ApplyInactivityDecay(ageBonus, inactiveEpochs, e.config.TimeInactivityDecay)
+		ageBonus = e.inactivityDecay(ageBonus, inactiveEpochs)
 	}
 	
 	return ageBonus, nil
 }
 
-// getVoucherScore gets a score for a voucher, handling circular dependencies
-func (e *DeterministicEngine) getVoucherScore(ctx context.Context, voucherDID, context string, epoch int64) (float64, error) {
-	// Try to get cached score first
-	if score, err := e.dataProvider.GetScore(ctx, voucherDID, context, epoch); err == nil && score != nil {
-		return score.Value, nil
+// applyDecay applies the decay function in fixed point, converting at the
+// boundary for decay functions that only work on float64
+func (e *DeterministicEngine) applyDecay(value Fixed, elapsedEpochs, halfLife int64) Fixed {
+	if decay, ok := e.decayFunc.(FixedDecayFunction); ok {
+		return decay.ApplyDecayFixed(value, elapsedEpochs, halfLife)
+	}
+	return FixedFromFloat(e.decayFunc.ApplyDecay(value.Float64(), elapsedEpochs, halfLife))
+}
+
+// inactivityDecay applies the configured inactivity decay in fixed point
+func (e *DeterministicEngine) inactivityDecay(value Fixed, inactiveEpochs int64) Fixed {
+	if decay, ok := e.decayFunc.(FixedDecayFunction); ok {
+		return decay.ApplyInactivityDecayFixed(value, inactiveEpochs, FixedFromFloat(e.config.TimeInactivityDecay))
+	}
+	return FixedFromFloat(e.decayFunc.ApplyInactivityDecay(value.Float64(), inactiveEpochs, e.config.TimeInactivityDecay))
+}
+
+// timeBonus computes the bounded time bonus in fixed point
+func (e *DeterministicEngine) timeBonus(firstEpoch, lastEpoch, epoch int64) Fixed {
+	if decay, ok := e.decayFunc.(FixedDecayFunction); ok {
+		return decay.ComputeTimeBonusFixed(firstEpoch, lastEpoch, epoch, FixedFromFloat(e.config.TimeMaxGrowth))
+	}
+	return FixedFromFloat(e.decayFunc.ComputeTimeBonus(firstEpoch, lastEpoch, epoch, e.config.TimeMaxGrowth))
+}
+
+// staticValue returns α*K + β*A - δ*R + τ*T, the part of the score that
+// does not depend on vouchers
+func (e *DeterministicEngine) staticValue(components *ScoreComponents) Fixed {
+	factors := e.config.Factors
+	return FixedFromFloat(factors.Alpha).Mul(FixedFromFloat(components.K)) +
+		FixedFromFloat(factors.Beta).Mul(FixedFromFloat(components.A)) -
+		FixedFromFloat(factors.Delta).Mul(FixedFromFloat(components.R)) +
+		FixedFromFloat(factors.Tau).Mul(FixedFromFloat(components.T))
+}
+
+// combineComponents applies the scoring formula in fixed point, clamping
+// the score at zero
+func (e *DeterministicEngine) combineComponents(components *ScoreComponents) Fixed {
+	return e.withVouches(e.staticValue(components), FixedFromFloat(components.V))
+}
+
+// withVouches adds γ*V to the static part of a score, clamping at zero
+func (e *DeterministicEngine) withVouches(static, v Fixed) Fixed {
+	return max(static+FixedFromFloat(e.config.Factors.Gamma).Mul(v), 0)
+}
+
+// issuerWeight looks up w(issuer) for the epoch and records it
+func (e *DeterministicEngine) issuerWeight(ctx context.Context, issuerDID string, epoch int64, issuerWeights map[string]float64) (float64, error) {
+	if weight, exists := issuerWeights[issuerDID]; exists {
+		return weight, nil
 	}
 	
-	// For bootstrapping, use a simple heuristic based on voucher data
-	vouches, err := e.dataProvider.GetVouches(ctx, voucherDID, context, epoch)
+	weight, err := e.issuerReputation.IssuerWeight(ctx, issuerDID, epoch)
 	if err != nil {
-		return 1.0, nil // Default score
+		return 0, fmt.Errorf("failed to get reputation of issuer %s: %w", issuerDID, err)
 	}
-	
-	// Simple score approximation based on vouch count
-	baseScore := 10.0 + float64(len(vouches))*2.0
-	return math.Min(baseScore, e.config.VouchCap), nil
+	if issuerWeights != nil {
+		issuerWeights[issuerDID] = weight
+	}
+	return weight, nil
 }
 
 // ComputeScores implements Engine.ComputeScores
@@ -330,22 +444,13 @@ func (e *DeterministicEngine) ComputeScores(ctx context.Context, dids []string,
 
 // RecomputeScore implements Engine.RecomputeScore
 func (e *DeterministicEngine) RecomputeScore(ctx context.Context, did, context string, epoch int64) (*Score, error) {
-	// Force recomputation by not checking cache
-	components, err := e.computeComponents(ctx, did, context, epoch)
+	// Force recomputation by not checking cache or snapshot
+	components, err := e.solveComponents(ctx, did, context, epoch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compute components: %w", err)
 	}
 	
-	factors := e.config.Factors
-	totalScore := factors.Alpha*components.K +
-		factors.Beta*components.A +
-		factors.Gamma*components.V -
-		factors.Delta*components.R +
-		factors.Tau*components.T
-	
-	if totalScore < 0 {
-		totalScore = 0
-	}
+	totalScore := e.combineComponents(components).Float64()
 	
 	score := &Score{
 		DID:        did,
@@ -392,8 +497,7 @@ func (e *DeterministicEngine) GetProof(ctx context.Context, score *Score) (*Scor
 	}
 	
 	// Create canonical representation for signing
-	canonical := fmt.Sprintf("%s|%s|%.6f|%d|%s", 
-		score.DID, score.Context, score.Value, score.Epoch, inputHash)
+	canonical := scoreProofCanonical(score, inputHash)
 	
 	// Sign the canonical representation
 	signature, err := e.signer.Sign([]byte(canonical))
@@ -416,10 +520,18 @@ func (e *DeterministicEngine) GetProof(ctx context.Context, score *Score) (*Scor
 
 // VerifyProof implements Engine.VerifyProof
 func (e *DeterministicEngine) VerifyProof(ctx context.Context, proof *ScoreProof) error {
+	return VerifyScoreProof(proof)
+}
+
+// VerifyScoreProof checks a score proof's signature against the public key it
+// carries. Callers that trust a specific scorer must compare that key too.
+func VerifyScoreProof(proof *ScoreProof) error {
+	if proof == nil || proof.Score == nil {
+		return fmt.Errorf("proof has no score")
+	}
+	
 	// Recreate canonical representation
-	canonical := fmt.Sprintf("%s|%s|%.6f|%d|%s", 
-		proof.Score.DID, proof.Score.Context, proof.Score.Value, 
-		proof.Score.Epoch, proof.InputHash)
+	canonical := scoreProofCanonical(proof.Score, proof.InputHash)
 	
 	// Verify signature
 	verifier := crypto.NewEd25519Verifier()
@@ -430,6 +542,13 @@ func (e *DeterministicEngine) VerifyProof(ctx context.Context, proof *ScoreProof
 	return nil
 }
 
+// scoreProofCanonical is the signed form of a score. The value is written
+// from its fixed-point representation, so it has exactly six decimals.
+func scoreProofCanonical(score *Score, inputHash string) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%s",
+		score.DID, score.Context, FixedFromFloat(score.Value), score.Epoch, inputHash)
+}
+
 // computeInputHash creates a deterministic hash of all input data
 func (e *DeterministicEngine) computeInputHash(ctx context.Context, did, context string, epoch int64) (string, error) {
 	hasher := sha256.New()
@@ -483,6 +602,29 @@ func (e *DeterministicEngine) computeInputHash(ctx context.Context, did, context
 		}
 	}
 	
+	// Hash issuer weights, which scale the K and A factors
+	if e.issuerReputation != nil {
+		issuers := make(map[string]bool)
+		for _, a := range attestations {
+			issuers[a.IssuerDID] = true
+		}
+		for _, k := range kycData {
+			issuers[k.IssuerDID] = true
+		}
+		sortedIssuers := make([]string, 0, len(issuers))
+		for issuer := range issuers {
+			sortedIssuers = append(sortedIssuers, issuer)
+		}
+		sort.Strings(sortedIssuers)
+		for _, issuer := range sortedIssuers {
+			weight, err := e.issuerReputation.IssuerWeight(ctx, issuer, epoch)
+			if err != nil {
+				return "", fmt.Errorf("failed to get reputation of issuer %s: %w", issuer, err)
+			}
+			hasher.Write([]byte(fmt.Sprintf("w:%s:%.6f", issuer, weight)))
+		}
+	}
+	
 	// Hash time data
 	timeData, err := e.dataProvider.GetTimeData(ctx, did, context, epoch)
 	if err == nil && timeData != nil {
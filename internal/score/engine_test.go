@@ -2,6 +2,7 @@ package score
 
 import (
 	"context"
+	"sort"
 	"testing"
 	"time"
 
@@ -494,6 +495,48 @@ func (t *TestDataProvider) GetTimeData(ctx context.Context, did, context string,
 	return nil, nil
 }
 
+// ListDIDs implements DIDLister with every DID the data is about
+func (t *TestDataProvider) ListDIDs(ctx context.Context, context string, epoch int64) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, vouch := range t.vouches {
+		if vouch.Context == context && vouch.Epoch <= epoch {
+			seen[vouch.FromDID], seen[vouch.ToDID] = true, true
+		}
+	}
+	for _, att := range t.attestations {
+		if att.Context == context && att.Epoch <= epoch {
+			seen[att.DID] = true
+		}
+	}
+	for _, report := range t.reports {
+		if report.Context == context && report.Epoch <= epoch {
+			seen[report.ReportedDID] = true
+		}
+	}
+	for _, kyc := range t.kycData {
+		if kyc.Context == context && kyc.Epoch <= epoch {
+			seen[kyc.DID] = true
+		}
+	}
+	dids := make([]string, 0, len(seen))
+	for did := range seen {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	return dids, nil
+}
+
+// GetVouchees implements VoucheeProvider
+func (t *TestDataProvider) GetVouchees(ctx context.Context, did, context string, maxEpoch int64) ([]string, error) {
+	var vouchees []string
+	for _, vouch := range t.vouches {
+		if vouch.FromDID == did && vouch.Context == context && vouch.Epoch <= maxEpoch {
+			vouchees = append(vouchees, vouch.ToDID)
+		}
+	}
+	return vouchees, nil
+}
+
 func (t *TestDataProvider) GetScore(ctx context.Context, did, context string, epoch int64) (*Score, error) {
 	key := did + ":" + context + ":" + string(rune(epoch))
 	if score, exists := t.scores[key]; exists {
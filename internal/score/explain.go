@@ -0,0 +1,415 @@
+package score
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExplanationConfig sets the hypothetical evidence counterfactuals are
+// priced with
+type ExplanationConfig struct {
+	KYCWeight         float64 `json:"kyc_weight"`         // Weight of an additional KYC credential
+	AttestationWeight float64 `json:"attestation_weight"` // Weight of an additional attestation
+	VouchStrength     float64 `json:"vouch_strength"`     // Strength of an additional vouch
+}
+
+// DefaultExplanationConfig returns the default counterfactual evidence
+func DefaultExplanationConfig() *ExplanationConfig {
+	return &ExplanationConfig{
+		KYCWeight:         20,
+		AttestationWeight: 10,
+		VouchStrength:     10,
+	}
+}
+
+// ScoreExplainer explains how a score is made up and how it could change
+type ScoreExplainer interface {
+	// ExplainScore breaks a DID's score down to the evidence behind it
+	ExplainScore(ctx context.Context, did, context string, epoch int64) (*ScoreExplanation, error)
+}
+
+// FactorContributions are the weighted terms of the score: αK, βA, γV, -δR
+// and τT
+type FactorContributions struct {
+	K float64 `json:"k"`
+	A float64 `json:"a"`
+	V float64 `json:"v"`
+	R float64 `json:"r"`
+	T float64 `json:"t"`
+}
+
+// VouchContribution is one incoming vouch and what it adds to V
+type VouchContribution struct {
+	VoucherDID      string  `json:"voucher_did"`
+	Epoch           int64   `json:"epoch"`
+	Strength        float64 `json:"strength"`
+	DecayedStrength float64 `json:"decayed_strength"`
+	DiversityWeight float64 `json:"diversity_weight"` // Community weight of the voucher
+	CollusionFactor float64 `json:"collusion_factor"` // Penalty from collusion detection
+	Weight          float64 `json:"weight"`           // q_ij: decayed strength times both multipliers
+	VoucherScore    float64 `json:"voucher_score"`
+	PassedScore     float64 `json:"passed_score"` // Damped and capped voucher score
+	Term            float64 `json:"term"`         // Passed score times weight, summed under V's root
+	MarginalEffect  float64 `json:"marginal_effect"`
+}
+
+// CredentialContribution is one KYC credential or attestation and what it
+// adds to K or A
+type CredentialContribution struct {
+	Kind           string  `json:"kind"` // "kyc" or "attestation"
+	Type           string  `json:"type"`
+	IssuerDID      string  `json:"issuer_did"`
+	Epoch          int64   `json:"epoch"`
+	Weight         float64 `json:"weight"`
+	IssuerWeight   float64 `json:"issuer_weight"`
+	DecayedWeight  float64 `json:"decayed_weight"`
+	MarginalEffect float64 `json:"marginal_effect"`
+}
+
+// ReportContribution is one upheld report and what it adds to R
+type ReportContribution struct {
+	ReporterDID     string  `json:"reporter_did"`
+	CaseID          string  `json:"case_id,omitempty"`
+	Epoch           int64   `json:"epoch"`
+	Severity        float64 `json:"severity"`
+	DecayedSeverity float64 `json:"decayed_severity"`
+	MarginalEffect  float64 `json:"marginal_effect"`
+}
+
+// VouchMultipliers are the multipliers applied to V as a whole
+type VouchMultipliers struct {
+	Diversity    float64  `json:"diversity"`   // From the analyzer when vouches are not weighted one by one
+	BondFactor   float64  `json:"bond_factor"` // Share of V kept after slashed bonds
+	SlashedBonds []string `json:"slashed_bonds,omitempty"`
+}
+
+// ScoreDelta attributes the change from the previous epoch to the factors
+type ScoreDelta struct {
+	PreviousEpoch int64               `json:"previous_epoch"`
+	PreviousScore float64             `json:"previous_score"`
+	Change        float64             `json:"change"`
+	Contributions FactorContributions `json:"contributions"`
+	Clamping      float64             `json:"clamping"` // Change not explained by the factors because scores stop at zero
+}
+
+// Counterfactual is the score change a single action would bring, all else
+// being equal
+type Counterfactual struct {
+	Action      string  `json:"action"`
+	Description string  `json:"description"`
+	ScoreChange float64 `json:"score_change"`
+}
+
+// ScoreExplanation lists the evidence behind a score with the marginal
+// effect of each item: how much higher the score is with it than without
+// it, holding everything else fixed. Reports have negative effects.
+type ScoreExplanation struct {
+	DID             string                    `json:"did"`
+	Context         string                    `json:"context"`
+	Epoch           int64                     `json:"epoch"`
+	Score           float64                   `json:"score"`
+	Components      ScoreComponents           `json:"components"`
+	Contributions   FactorContributions       `json:"contributions"`
+	Vouches         []*VouchContribution      `json:"vouches"`
+	Credentials     []*CredentialContribution `json:"credentials"`
+	Reports         []*ReportContribution     `json:"reports"`
+	Multipliers     VouchMultipliers          `json:"multipliers"`
+	Delta           *ScoreDelta               `json:"delta,omitempty"`
+	Counterfactuals []*Counterfactual         `json:"counterfactuals"`
+}
+
+// explainedScore is a DID's node in its solved vouch graph
+type explainedScore struct {
+	node       *propagationNode
+	scores     []Fixed
+	components ScoreComponents
+	v          Fixed
+	value      Fixed
+}
+
+// bondPreviewer reads slashed bonds without sealing the epoch
+type bondPreviewer interface {
+	PreviewSlashedBonds(ctx context.Context, voucherDID, context string, epoch int64) ([]*VouchBond, error)
+}
+
+// issuerWeightPreviewer reads issuer weights without sealing the epoch
+type issuerWeightPreviewer interface {
+	PreviewIssuerWeight(ctx context.Context, issuerDID string, epoch int64) (float64, error)
+}
+
+// previewBonds serves SlashedBonds from a non-sealing read
+type previewBonds struct {
+	bonds bondPreviewer
+}
+
+func (p previewBonds) SlashedBonds(ctx context.Context, voucherDID, context string, epoch int64) ([]*VouchBond, error) {
+	return p.bonds.PreviewSlashedBonds(ctx, voucherDID, context, epoch)
+}
+
+// previewIssuerWeights serves IssuerWeight from a non-sealing read
+type previewIssuerWeights struct {
+	weights issuerWeightPreviewer
+}
+
+func (p previewIssuerWeights) IssuerWeight(ctx context.Context, issuerDID string, epoch int64) (float64, error) {
+	return p.weights.PreviewIssuerWeight(ctx, issuerDID, epoch)
+}
+
+// preview returns a copy of the engine that reads bonds and issuer weights
+// without sealing epochs, for reads that must not freeze the evidence
+// later verdicts and adjudications still change
+func (e *DeterministicEngine) preview() *DeterministicEngine {
+	view := *e
+	if bonds, ok := e.bonds.(bondPreviewer); ok {
+		view.bonds = previewBonds{bonds: bonds}
+	}
+	if weights, ok := e.issuerReputation.(issuerWeightPreviewer); ok {
+		view.issuerReputation = previewIssuerWeights{weights: weights}
+	}
+	return &view
+}
+
+// ExplainScore implements ScoreExplainer. The score is solved from fresh
+// data over the DID and everyone vouching for it, the same way an epoch
+// solve computes it, but from views that leave the epoch unsealed. The
+// change since the previous epoch is taken from its stored snapshot.
+func (e *DeterministicEngine) ExplainScore(ctx context.Context, did, context string, epoch int64) (*ScoreExplanation, error) {
+	return e.preview().explain(ctx, did, context, epoch)
+}
+
+// explain builds the explanation on an engine whose providers do not seal
+func (e *DeterministicEngine) explain(ctx context.Context, did, context string, epoch int64) (*ScoreExplanation, error) {
+	current, err := e.explainedScore(ctx, did, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+	node, value := current.node, current.value
+
+	explanation := &ScoreExplanation{
+		DID:           did,
+		Context:       context,
+		Epoch:         epoch,
+		Score:         value.Float64(),
+		Components:    current.components,
+		Contributions: e.contributions(&current.components),
+		Vouches:       []*VouchContribution{},
+		Credentials:   []*CredentialContribution{},
+		Reports:       []*ReportContribution{},
+		Multipliers: VouchMultipliers{
+			Diversity:    node.diversity.Float64(),
+			BondFactor:   node.bond.Float64(),
+			SlashedBonds: node.slashed,
+		},
+	}
+
+	// Each vouch is taken out of the sum under the root on its own
+	sum := e.vouchSum(node, current.scores)
+	for _, edge := range node.edges {
+		passed := e.passedScore(current.scores[edge.from])
+		term := passed.Mul(edge.strength)
+		without := e.withVouches(node.base, (sum - term).Sqrt().Mul(node.diversity).Mul(node.bond))
+		explanation.Vouches = append(explanation.Vouches, &VouchContribution{
+			VoucherDID:      edge.vouch.FromDID,
+			Epoch:           edge.vouch.Epoch,
+			Strength:        edge.vouch.Strength,
+			DecayedStrength: edge.decayed.Float64(),
+			DiversityWeight: edge.diversity.Float64(),
+			CollusionFactor: edge.collusion.Float64(),
+			Weight:          edge.strength.Float64(),
+			VoucherScore:    current.scores[edge.from].Float64(),
+			PassedScore:     passed.Float64(),
+			Term:            term.Float64(),
+			MarginalEffect:  (value - without).Float64(),
+		})
+	}
+
+	// K, A and R are linear, so each item moves the static part by its
+	// weighted term
+	factors := e.config.Factors
+	staticEffect := func(delta Fixed) float64 {
+		return (value - e.withVouches(node.base-delta, current.v)).Float64()
+	}
+
+	kycs, terms, err := e.kycTerms(ctx, did, context, epoch, nil)
+	if err != nil {
+		return nil, fmt.Errorf("K factor computation failed: %w", err)
+	}
+	for i, kyc := range kycs {
+		issuerWeight := 1.0
+		if e.issuerReputation != nil {
+			issuerWeight = current.components.IssuerWeights[kyc.IssuerDID]
+		}
+		explanation.Credentials = append(explanation.Credentials, &CredentialContribution{
+			Kind:           "kyc",
+			Type:           kyc.Type,
+			IssuerDID:      kyc.IssuerDID,
+			Epoch:          kyc.Epoch,
+			Weight:         kyc.Weight,
+			IssuerWeight:   issuerWeight,
+			DecayedWeight:  terms[i].Float64(),
+			MarginalEffect: staticEffect(FixedFromFloat(factors.Alpha).Mul(terms[i])),
+		})
+	}
+
+	attestations, terms, err := e.attestationTerms(ctx, did, context, epoch, nil)
+	if err != nil {
+		return nil, fmt.Errorf("A factor computation failed: %w", err)
+	}
+	for i, att := range attestations {
+		issuerWeight := att.IssuerReputation
+		if e.issuerReputation != nil {
+			issuerWeight = current.components.IssuerWeights[att.IssuerDID]
+		}
+		explanation.Credentials = append(explanation.Credentials, &CredentialContribution{
+			Kind:           "attestation",
+			Type:           att.Type,
+			IssuerDID:      att.IssuerDID,
+			Epoch:          att.Epoch,
+			Weight:         att.Weight,
+			IssuerWeight:   issuerWeight,
+			DecayedWeight:  terms[i].Float64(),
+			MarginalEffect: staticEffect(FixedFromFloat(factors.Beta).Mul(terms[i])),
+		})
+	}
+
+	reports, terms, err := e.reportTerms(ctx, did, context, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("R factor computation failed: %w", err)
+	}
+	for i, report := range reports {
+		explanation.Reports = append(explanation.Reports, &ReportContribution{
+			ReporterDID:     report.ReporterDID,
+			CaseID:          report.CaseID,
+			Epoch:           report.Epoch,
+			Severity:        report.Severity,
+			DecayedSeverity: terms[i].Float64(),
+			MarginalEffect:  staticEffect(-FixedFromFloat(factors.Delta).Mul(terms[i])),
+		})
+	}
+
+	if explanation.Delta, err = e.explainDelta(ctx, explanation, epoch-1); err != nil {
+		return nil, err
+	}
+	if explanation.Counterfactuals, err = e.counterfactuals(ctx, current, epoch); err != nil {
+		return nil, err
+	}
+
+	return explanation, nil
+}
+
+// explainedScore solves a DID's score and keeps the graph it was solved on
+func (e *DeterministicEngine) explainedScore(ctx context.Context, did, context string, epoch int64) (*explainedScore, error) {
+	nodes, err := e.buildPropagationGraph(ctx, context, epoch, []string{did}, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.propagate(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		if node.did != did {
+			continue
+		}
+		components, v, value := e.nodeScore(node, result.scores)
+		return &explainedScore{node: node, scores: result.scores, components: components, v: v, value: value}, nil
+	}
+	return nil, fmt.Errorf("%s missing from its own vouch graph", did)
+}
+
+// contributions weights the components by the score factors
+func (e *DeterministicEngine) contributions(components *ScoreComponents) FactorContributions {
+	factors := e.config.Factors
+	return FactorContributions{
+		K: FixedFromFloat(factors.Alpha).Mul(FixedFromFloat(components.K)).Float64(),
+		A: FixedFromFloat(factors.Beta).Mul(FixedFromFloat(components.A)).Float64(),
+		V: FixedFromFloat(factors.Gamma).Mul(FixedFromFloat(components.V)).Float64(),
+		R: -FixedFromFloat(factors.Delta).Mul(FixedFromFloat(components.R)).Float64(),
+		T: FixedFromFloat(factors.Tau).Mul(FixedFromFloat(components.T)).Float64(),
+	}
+}
+
+// explainDelta attributes the change since an earlier epoch to the factors,
+// against the score published in that epoch's snapshot. Without one there
+// is nothing to compare with and no delta.
+func (e *DeterministicEngine) explainDelta(ctx context.Context, explanation *ScoreExplanation, previousEpoch int64) (*ScoreDelta, error) {
+	previous := e.snapshotScore(ctx, explanation.DID, explanation.Context, previousEpoch)
+	if previous == nil {
+		return nil, nil
+	}
+
+	before := e.contributions(&previous.Components)
+	after := explanation.Contributions
+	delta := &ScoreDelta{
+		PreviousEpoch: previousEpoch,
+		PreviousScore: previous.Value,
+		Change:        explanation.Score - previous.Value,
+		Contributions: FactorContributions{
+			K: after.K - before.K,
+			A: after.A - before.A,
+			V: after.V - before.V,
+			R: after.R - before.R,
+			T: after.T - before.T,
+		},
+	}
+	attributed := delta.Contributions.K + delta.Contributions.A + delta.Contributions.V +
+		delta.Contributions.R + delta.Contributions.T
+	delta.Clamping = FixedFromFloat(delta.Change - attributed).Float64()
+
+	return delta, nil
+}
+
+// counterfactuals prices a few simple actions against the current score
+func (e *DeterministicEngine) counterfactuals(ctx context.Context, current *explainedScore, epoch int64) ([]*Counterfactual, error) {
+	config := e.explanation
+	factors := e.config.Factors
+	node, value := current.node, current.value
+
+	// New credentials come from an issuer with no track record yet
+	issuerWeight := FixedOne
+	if e.issuerReputation != nil {
+		weight, err := e.issuerReputation.IssuerWeight(ctx, "", epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default issuer weight: %w", err)
+		}
+		issuerWeight = FixedFromFloat(weight)
+	}
+	withStatic := func(delta Fixed) float64 {
+		return (e.withVouches(node.base+delta, current.v) - value).Float64()
+	}
+
+	kyc := e.applyDecay(FixedFromFloat(config.KYCWeight), 0, e.config.VouchHalfLife).Mul(issuerWeight)
+	attestation := e.applyDecay(FixedFromFloat(config.AttestationWeight).Mul(issuerWeight), 0, e.config.VouchHalfLife)
+
+	// A new vouch from a peer scoring the same, outside any flagged cluster
+	vouch := e.passedScore(value).Mul(e.applyDecay(FixedFromFloat(config.VouchStrength), 0, e.config.VouchHalfLife))
+	withVouch := e.withVouches(node.base, (e.vouchSum(node, current.scores) + vouch).Sqrt().Mul(node.diversity).Mul(node.bond))
+
+	counterfactuals := []*Counterfactual{
+		{
+			Action:      "add_kyc",
+			Description: fmt.Sprintf("Add a KYC credential of weight %g", config.KYCWeight),
+			ScoreChange: withStatic(FixedFromFloat(factors.Alpha).Mul(kyc)),
+		},
+		{
+			Action:      "add_attestation",
+			Description: fmt.Sprintf("Add an attestation of weight %g", config.AttestationWeight),
+			ScoreChange: withStatic(FixedFromFloat(factors.Beta).Mul(attestation)),
+		},
+		{
+			Action:      "add_vouch",
+			Description: fmt.Sprintf("Receive a vouch of strength %g from someone with the same score", config.VouchStrength),
+			ScoreChange: (withVouch - value).Float64(),
+		},
+	}
+	if r := FixedFromFloat(current.components.R); r > 0 {
+		counterfactuals = append(counterfactuals, &Counterfactual{
+			Action:      "overturn_reports",
+			Description: "Have every upheld report overturned on appeal",
+			ScoreChange: withStatic(FixedFromFloat(factors.Delta).Mul(r)),
+		})
+	}
+
+	return counterfactuals, nil
+}
@@ -0,0 +1,188 @@
+package score
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeterministicEngine_ExplainScore(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	provider.AddAttestation(&AttestationData{
+		DID: "did:key:bob", Context: "test_context", Type: "employment",
+		IssuerDID: "did:key:employer", IssuerReputation: 0.5, Weight: 10, Epoch: 100,
+	})
+	provider.AddReport(&ReportData{
+		ReporterDID: "did:key:dave", ReportedDID: "did:key:bob", Context: "test_context",
+		Severity: 0.5, Adjudicated: true, Upheld: true, CaseID: "case-1", Epoch: 99,
+	})
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	ctx := context.Background()
+
+	previous, err := engine.SolveEpoch(ctx, "test_context", 99, []string{"did:key:bob"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	explanation, err := engine.ExplainScore(ctx, "did:key:bob", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ExplainScore failed: %v", err)
+	}
+	snapshot, err := engine.solve(ctx, "test_context", 100, []string{"did:key:bob"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	if explanation.Score != snapshot.Scores["did:key:bob"].Value {
+		t.Errorf("Expected the solved score %f, got %f", snapshot.Scores["did:key:bob"].Value, explanation.Score)
+	}
+
+	contributions := explanation.Contributions
+	if total := contributions.K + contributions.A + contributions.V + contributions.R + contributions.T; math.Abs(total-explanation.Score) > 1e-5 {
+		t.Errorf("Expected the weighted factors to add up to %f, got %f", explanation.Score, total)
+	}
+
+	// Both vouchers, whose terms make up V
+	if len(explanation.Vouches) != 2 {
+		t.Fatalf("Expected 2 vouches, got %d", len(explanation.Vouches))
+	}
+	var terms float64
+	for _, vouch := range explanation.Vouches {
+		terms += vouch.Term
+		if vouch.MarginalEffect <= 0 || vouch.DiversityWeight != 1 || vouch.CollusionFactor != 1 {
+			t.Errorf("Unexpected contribution from %s: %+v", vouch.VoucherDID, vouch)
+		}
+	}
+	if v := math.Sqrt(terms); math.Abs(v-explanation.Components.V) > 1e-5 {
+		t.Errorf("Expected V = sqrt of the vouch terms %f, got %f", v, explanation.Components.V)
+	}
+
+	if len(explanation.Credentials) != 1 || explanation.Credentials[0].Kind != "attestation" {
+		t.Fatalf("Expected bob's attestation, got %+v", explanation.Credentials)
+	}
+	// β times weight 10 at issuer reputation 0.5
+	if effect := explanation.Credentials[0].MarginalEffect; math.Abs(effect-1.0) > 1e-6 {
+		t.Errorf("Expected the attestation to add 1.0, got %f", effect)
+	}
+	if len(explanation.Reports) != 1 || explanation.Reports[0].MarginalEffect >= 0 || explanation.Reports[0].CaseID != "case-1" {
+		t.Errorf("Expected the report to lower the score, got %+v", explanation.Reports)
+	}
+
+	// The move since the published previous epoch is attributed to the
+	// factors
+	delta := explanation.Delta
+	if delta == nil || delta.PreviousEpoch != 99 {
+		t.Fatalf("Expected a delta against epoch 99, got %+v", delta)
+	}
+	if delta.PreviousScore != previous.Scores["did:key:bob"].Value {
+		t.Errorf("Expected the delta against the stored score %f, got %f", previous.Scores["did:key:bob"].Value, delta.PreviousScore)
+	}
+	attributed := delta.Contributions.K + delta.Contributions.A + delta.Contributions.V +
+		delta.Contributions.R + delta.Contributions.T + delta.Clamping
+	if math.Abs(attributed-delta.Change) > 1e-5 || delta.Contributions.A <= 0 {
+		t.Errorf("Expected the delta to be fully attributed, got %+v", delta)
+	}
+
+	actions := make(map[string]float64)
+	for _, counterfactual := range explanation.Counterfactuals {
+		actions[counterfactual.Action] = counterfactual.ScoreChange
+	}
+	if math.Abs(actions["overturn_reports"]+explanation.Reports[0].MarginalEffect) > 1e-6 || actions["add_vouch"] <= 0 {
+		t.Errorf("Unexpected counterfactuals %v", actions)
+	}
+}
+
+func TestDeterministicEngine_ExplainCounterfactualKYC(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	ctx := context.Background()
+
+	// Nobody downstream of carol feeds back into her score, so the
+	// counterfactual is exact
+	explanation, err := engine.ExplainScore(ctx, "did:key:carol", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ExplainScore failed: %v", err)
+	}
+	var gain float64
+	for _, counterfactual := range explanation.Counterfactuals {
+		if counterfactual.Action == "add_kyc" {
+			gain = counterfactual.ScoreChange
+		}
+		if counterfactual.Action == "overturn_reports" {
+			t.Error("Expected no report counterfactual without reports")
+		}
+	}
+
+	provider.AddKYC(&KYCData{
+		DID: "did:key:carol", Context: "test_context", Type: "kyc_level_1", Level: 1,
+		IssuerDID: "did:key:kyc", Weight: DefaultExplanationConfig().KYCWeight, Epoch: 100,
+	})
+	after, err := engine.ExplainScore(ctx, "did:key:carol", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ExplainScore failed: %v", err)
+	}
+	if gain <= 0 || math.Abs(after.Score-explanation.Score-gain) > 1e-6 {
+		t.Errorf("Expected the KYC credential to add %f, got %f", gain, after.Score-explanation.Score)
+	}
+	if len(after.Credentials) != 1 || math.Abs(after.Credentials[0].MarginalEffect-gain) > 1e-6 {
+		t.Errorf("Expected the new credential's effect to match, got %+v", after.Credentials)
+	}
+}
+
+func TestDeterministicEngine_ExplainScoreWithoutPreviousSnapshot(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+
+	// Epoch 99 was never published, so there is nothing to diff against
+	explanation, err := engine.ExplainScore(context.Background(), "did:key:bob", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ExplainScore failed: %v", err)
+	}
+	if explanation.Delta != nil {
+		t.Errorf("Expected no delta without a stored snapshot, got %+v", explanation.Delta)
+	}
+}
+
+func TestDeterministicEngine_ExplainScoreDoesNotSeal(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	ledger := NewBondLedger(provider, &BondConfig{Fraction: 0.5, Window: 3})
+	registry := NewIssuerReputationRegistry(DefaultIssuerReputationConfig())
+	engine.SetBondProvider(ledger)
+	engine.SetIssuerReputation(registry)
+	ctx := context.Background()
+
+	// Explaining a far-off epoch reads bonds and issuer weights for it
+	if _, err := engine.ExplainScore(ctx, "did:key:alice", "test_context", 1000000); err != nil {
+		t.Fatalf("ExplainScore failed: %v", err)
+	}
+
+	// Verdicts and adjudications for earlier epochs are still accepted
+	if _, err := ledger.RecordVerdict(ctx, &ReportData{
+		CaseID: "case-1", ReportedDID: "did:key:carol", Context: "test_context",
+		Adjudicated: true, Upheld: true, Epoch: 101,
+	}, 101); err != nil {
+		t.Errorf("Expected the verdict to be recorded, got %v", err)
+	}
+	if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+		IssuerDID: "did:key:kyc", CredentialID: "cred-1", Outcome: AdjudicationConfirmed, Epoch: 101,
+	}); err != nil {
+		t.Errorf("Expected the adjudication to be recorded, got %v", err)
+	}
+}
+
+func TestHTTPService_ExplanationRejectsFutureEpoch(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	service := NewHTTPService(engine, nil, nil, DefaultScoreConfig(), 0)
+	next := time.Now().Unix()/86400 + 1
+
+	for _, epoch := range []string{fmt.Sprint(next), "-1", "soon"} {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/score/did:key:bob/explanation?context=test_context&epoch="+epoch, nil)
+		recorder := httptest.NewRecorder()
+		service.server.Handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected epoch %s to be rejected, got %d", epoch, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package score
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Fixed is a signed fixed-point number with six decimal places, the
+// precision scores are published and signed at. Scores are computed on
+// Fixed values rather than float64 so that every platform produces the same
+// bits: float64 code may be fused into multiply-adds on some architectures
+// and math.Exp, math.Log and math.Pow are not correctly rounded.
+//
+// Rounding is specified as follows: conversions from float64, Mul and Div
+// round half away from zero, Sqrt truncates, and results that do not fit
+// saturate at the int64 bounds. Sums are exact, so they do not depend on the
+// order terms are added in.
+type Fixed int64
+
+const (
+	// FixedScale is the number of Fixed units in one
+	FixedScale = 1000000
+
+	// FixedOne is 1.0 as a Fixed
+	FixedOne Fixed = FixedScale
+)
+
+// Decay, logarithms and powers are evaluated on unsigned fractions with 62
+// fractional bits before being rounded to Fixed
+const (
+	q62One uint64 = 1 << 62
+	q62Ln2 uint64 = 3196577161300663915 // round(ln(2) * 2^62)
+)
+
+// halvingRoots[k] is 2^(-2^-(k+1)) in Q62. The table is built with integer
+// square roots, so it is identical on every platform.
+var halvingRoots = func() [48]uint64 {
+	var roots [48]uint64
+	root := q62One / 2
+	for k := range roots {
+		root = sqrtQ62(root)
+		roots[k] = root
+	}
+	return roots
+}()
+
+// FixedFromFloat converts a float64 to the nearest Fixed, rounding half away
+// from zero. NaN converts to zero.
+func FixedFromFloat(value float64) Fixed {
+	if math.IsNaN(value) {
+		return 0
+	}
+	scaled := math.Round(value * FixedScale)
+	if scaled >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if scaled <= math.MinInt64 {
+		return math.MinInt64
+	}
+	return Fixed(scaled)
+}
+
+// FixedFromInt converts an integer to a Fixed
+func FixedFromInt(value int64) Fixed {
+	if value > math.MaxInt64/FixedScale {
+		return math.MaxInt64
+	}
+	if value < math.MinInt64/FixedScale {
+		return math.MinInt64
+	}
+	return Fixed(value * FixedScale)
+}
+
+// Float64 returns the value as a float64. Every Fixed below 2^53 units
+// converts exactly back through FixedFromFloat.
+func (f Fixed) Float64() float64 {
+	return float64(f) / FixedScale
+}
+
+// String formats the value with exactly six decimal places
+func (f Fixed) String() string {
+	sign := ""
+	if f < 0 {
+		sign = "-"
+	}
+	abs := fixedAbs(f)
+	return fmt.Sprintf("%s%d.%06d", sign, abs/FixedScale, abs%FixedScale)
+}
+
+// Mul returns f*g rounded half away from zero
+func (f Fixed) Mul(g Fixed) Fixed {
+	hi, lo := bits.Mul64(fixedAbs(f), fixedAbs(g))
+	return fixedSigned(divRound(hi, lo, FixedScale), (f < 0) != (g < 0))
+}
+
+// Div returns f/g rounded half away from zero, or zero when g is zero
+func (f Fixed) Div(g Fixed) Fixed {
+	if g == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(fixedAbs(f), FixedScale)
+	return fixedSigned(divRound(hi, lo, fixedAbs(g)), (f < 0) != (g < 0))
+}
+
+// MulDiv returns f*g/h rounded half away from zero, without rounding or
+// overflowing the intermediate product, or zero when h is zero
+func (f Fixed) MulDiv(g, h Fixed) Fixed {
+	if h == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(fixedAbs(f), fixedAbs(g))
+	return fixedSigned(divRound(hi, lo, fixedAbs(h)), (f < 0) != (g < 0) != (h < 0))
+}
+
+// Sqrt returns the square root of f truncated to six places, or zero when f
+// is negative
+func (f Fixed) Sqrt() Fixed {
+	if f <= 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(f), FixedScale)
+	return Fixed(isqrt128(hi, lo))
+}
+
+// mulQ62 scales f by a Q62 fraction, rounding half away from zero
+func (f Fixed) mulQ62(fraction uint64) Fixed {
+	hi, lo := bits.Mul64(fixedAbs(f), fraction)
+	lo, carry := bits.Add64(lo, 1<<61, 0)
+	hi += carry
+	if hi>>62 != 0 {
+		return fixedSigned(math.MaxUint64, f < 0)
+	}
+	return fixedSigned(hi<<2|lo>>62, f < 0)
+}
+
+func fixedAbs(f Fixed) uint64 {
+	if f < 0 {
+		return uint64(-f)
+	}
+	return uint64(f)
+}
+
+func fixedSigned(abs uint64, negative bool) Fixed {
+	if negative {
+		if abs > 1<<63 {
+			return math.MinInt64
+		}
+		return Fixed(-int64(abs))
+	}
+	if abs > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return Fixed(abs)
+}
+
+// divRound divides a 128-bit value, rounding half up and saturating
+func divRound(hi, lo, divisor uint64) uint64 {
+	lo, carry := bits.Add64(lo, divisor/2, 0)
+	hi += carry
+	if hi >= divisor {
+		return math.MaxUint64
+	}
+	quotient, _ := bits.Div64(hi, lo, divisor)
+	return quotient
+}
+
+// isqrt128 returns floor(sqrt(hi*2^64 + lo))
+func isqrt128(hi, lo uint64) uint64 {
+	if hi == 0 {
+		return isqrt64(lo)
+	}
+
+	// Newton's method from an upper bound decreases monotonically to the
+	// floor of the root. The root exceeds hi, so every division fits.
+	x := uint64(math.MaxUint64)
+	if length := 64 + bits.Len64(hi); length < 127 {
+		x = 1 << uint((length+1)/2)
+	}
+	for {
+		quotient, _ := bits.Div64(hi, lo, x)
+		next := x>>1 + quotient>>1 + (x & quotient & 1)
+		if next >= x {
+			return x
+		}
+		x = next
+	}
+}
+
+// isqrt64 returns floor(sqrt(n))
+func isqrt64(n uint64) uint64 {
+	if n < 2 {
+		return n
+	}
+	x := uint64(1) << uint((bits.Len64(n)+1)/2)
+	for {
+		next := (x + n/x) >> 1
+		if next >= x {
+			return x
+		}
+		x = next
+	}
+}
+
+// mulQ62 multiplies two Q62 fractions no greater than two, rounding half up
+func mulQ62(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	lo, carry := bits.Add64(lo, 1<<61, 0)
+	hi += carry
+	return hi<<2 | lo>>62
+}
+
+// sqrtQ62 returns the square root of a Q62 fraction
+func sqrtQ62(x uint64) uint64 {
+	return isqrt128(x>>2, x<<62)
+}
+
+// fixedFractionQ62 converts a Fixed in [0, 1] to Q62
+func fixedFractionQ62(f Fixed) uint64 {
+	hi, lo := bits.Mul64(uint64(f), q62One)
+	quotient, _ := bits.Div64(hi, lo, FixedScale)
+	return quotient
+}
+
+// halfLifeQ62 returns 0.5^(elapsed/halfLife) in Q62. Whole half-lives are
+// shifts; the remainder is expanded into binary digits by long division
+// and each set digit multiplies in the matching root of two.
+func halfLifeQ62(elapsed, halfLife int64) uint64 {
+	if elapsed <= 0 || halfLife <= 0 {
+		return q62One
+	}
+	halvings := elapsed / halfLife
+	if halvings > 62 {
+		return 0
+	}
+
+	factor := q62One
+	remainder, divisor := uint64(elapsed%halfLife), uint64(halfLife)
+	for k := 0; k < len(halvingRoots) && remainder != 0; k++ {
+		remainder <<= 1
+		if remainder >= divisor {
+			remainder -= divisor
+			factor = mulQ62(factor, halvingRoots[k])
+		}
+	}
+
+	if halvings == 0 {
+		return factor
+	}
+	return (factor + 1<<uint(halvings-1)) >> uint(halvings)
+}
+
+// powQ62 raises a Q62 fraction no greater than one to a non-negative power
+func powQ62(base uint64, exponent int64) uint64 {
+	result := q62One
+	for exponent > 0 && result != 0 {
+		if exponent&1 == 1 {
+			result = mulQ62(result, base)
+		}
+		base = mulQ62(base, base)
+		exponent >>= 1
+	}
+	return result
+}
+
+// log2Q62 returns log2(n) for n >= 1 as a 128-bit Q62 value. The integer
+// part comes from the bit length and the fraction from repeated squaring
+// of the mantissa.
+func log2Q62(n uint64) (hi, lo uint64) {
+	exponent := uint64(bits.Len64(n) - 1)
+	var mantissa uint64
+	if exponent <= 62 {
+		mantissa = n << (62 - exponent)
+	} else {
+		mantissa = n >> (exponent - 62)
+	}
+
+	var fraction uint64
+	for bit := q62One >> 1; bit != 0; bit >>= 1 {
+		mantissa = mulQ62(mantissa, mantissa)
+		if mantissa >= 2*q62One {
+			mantissa >>= 1
+			fraction |= bit
+		}
+	}
+	return exponent >> 2, exponent<<62 | fraction
+}
+
+// q62ToFixed rounds a non-negative 128-bit Q62 value to a Fixed
+func q62ToFixed(hi, lo uint64) Fixed {
+	integer := hi<<2 | lo>>62
+	if hi>>62 != 0 || integer > math.MaxInt64/FixedScale {
+		return math.MaxInt64
+	}
+	fraction := FixedOne.mulQ62(lo & (q62One - 1))
+	return Fixed(integer)*FixedOne + fraction
+}
+
+// FixedLog2 returns log2(n) for a positive integer, or zero otherwise
+func FixedLog2(n int64) Fixed {
+	if n <= 0 {
+		return 0
+	}
+	return q62ToFixed(log2Q62(uint64(n)))
+}
+
+// FixedLn returns the natural logarithm of a positive integer, or zero
+// otherwise
+func FixedLn(n int64) Fixed {
+	if n <= 0 {
+		return 0
+	}
+	logHi, logLo := log2Q62(uint64(n))
+
+	// ln(n) = log2(n) * ln(2), keeping 62 fractional bits throughout
+	hi, lo := bits.Mul64(logHi<<2|logLo>>62, q62Ln2)
+	lo, carry := bits.Add64(lo, mulQ62(logLo&(q62One-1), q62Ln2), 0)
+	hi += carry
+	return q62ToFixed(hi, lo)
+}
+
+// FixedHalfLifeDecay returns value * 0.5^(elapsed/halfLife). Values are
+// unchanged when nothing has elapsed or the half-life is not positive.
+func FixedHalfLifeDecay(value Fixed, elapsed, halfLife int64) Fixed {
+	return value.mulQ62(halfLifeQ62(elapsed, halfLife))
+}
@@ -0,0 +1,156 @@
+package score
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFixed_Rounding(t *testing.T) {
+	half := FixedFromFloat(0.5)
+	unit := Fixed(1)
+
+	tests := []struct {
+		name     string
+		got      Fixed
+		expected Fixed
+	}{
+		{"mul rounds half up", unit.Mul(half), 1},
+		{"mul rounds half away from zero", (-unit).Mul(half), -1},
+		{"mul", FixedFromFloat(1.5).Mul(FixedFromFloat(-2.25)), FixedFromFloat(-3.375)},
+		{"div rounds down", FixedOne.Div(FixedFromInt(3)), 333333},
+		{"div rounds up", FixedFromInt(2).Div(FixedFromInt(3)), 666667},
+		{"div negative", FixedFromInt(-2).Div(FixedFromInt(3)), -666667},
+		{"div by zero", FixedOne.Div(0), 0},
+		{"from float rounds", FixedFromFloat(0.1234565), 123457},
+		{"from float saturates", FixedFromFloat(1e300), math.MaxInt64},
+		{"from int", FixedFromInt(42), 42000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, tt.got)
+			}
+		})
+	}
+
+	if s := FixedFromFloat(-1.5).String(); s != "-1.500000" {
+		t.Errorf("Expected -1.500000, got %s", s)
+	}
+	if s := Fixed(7).String(); s != "0.000007" {
+		t.Errorf("Expected 0.000007, got %s", s)
+	}
+}
+
+func TestFixed_Sqrt(t *testing.T) {
+	if got := FixedFromInt(4).Sqrt(); got != FixedFromInt(2) {
+		t.Errorf("Expected 2, got %s", got)
+	}
+	// Truncated, not rounded: sqrt(2) = 1.4142135...
+	if got := FixedFromInt(2).Sqrt(); got != 1414213 {
+		t.Errorf("Expected 1.414213, got %s", got)
+	}
+	// Large enough that the scaled radicand needs 128 bits
+	if got := FixedFromInt(1000000000000).Sqrt(); got != FixedFromInt(1000000) {
+		t.Errorf("Expected 1000000, got %s", got)
+	}
+	if got := FixedFromInt(-4).Sqrt(); got != 0 {
+		t.Errorf("Expected 0 for a negative value, got %s", got)
+	}
+
+	for _, value := range []float64{0.000001, 0.5, 3, 17.25, 1234.5678, 98765.4321} {
+		got := FixedFromFloat(value).Sqrt().Float64()
+		if diff := math.Sqrt(value) - got; diff < 0 || diff >= 1e-6 {
+			t.Errorf("sqrt(%f): expected %f truncated, got %f", value, math.Sqrt(value), got)
+		}
+	}
+}
+
+func TestFixed_Decay(t *testing.T) {
+	hundred := FixedFromInt(100)
+
+	// Whole half-lives are exact
+	if got := FixedHalfLifeDecay(hundred, 10, 10); got != FixedFromInt(50) {
+		t.Errorf("Expected 50, got %s", got)
+	}
+	if got := FixedHalfLifeDecay(hundred, 20, 10); got != FixedFromInt(25) {
+		t.Errorf("Expected 25, got %s", got)
+	}
+	if got := FixedHalfLifeDecay(hundred, -3, 10); got != hundred {
+		t.Errorf("Expected no decay for negative elapsed, got %s", got)
+	}
+
+	// 100 * 2^-0.3 = 81.2252396...
+	if got := FixedHalfLifeDecay(hundred, 3, 10); got != 81225240 {
+		t.Errorf("Expected 81.225240, got %s", got)
+	}
+
+	for elapsed := int64(0); elapsed < 60; elapsed++ {
+		got := FixedHalfLifeDecay(hundred, elapsed, 7).Float64()
+		expected := 100 * math.Pow(0.5, float64(elapsed)/7)
+		if math.Abs(got-expected) > 1e-6 {
+			t.Errorf("Decay after %d epochs: expected %f, got %f", elapsed, expected, got)
+		}
+	}
+
+	decay := &ExponentialDecayFunction{}
+	if got := decay.ApplyInactivityDecayFixed(hundred, 7, FixedFromFloat(0.1)); got != 47829690 {
+		t.Errorf("Expected 100 * 0.9^7 = 47.829690, got %s", got)
+	}
+}
+
+func TestFixed_Logarithms(t *testing.T) {
+	if got := FixedLog2(1024); got != FixedFromInt(10) {
+		t.Errorf("Expected log2(1024) = 10, got %s", got)
+	}
+	// log2(3) = 1.5849625...
+	if got := FixedLog2(3); got != 1584963 {
+		t.Errorf("Expected log2(3) = 1.584963, got %s", got)
+	}
+	// ln(101) = 4.6151205...
+	if got := FixedLn(101); got != 4615121 {
+		t.Errorf("Expected ln(101) = 4.615121, got %s", got)
+	}
+	if got := FixedLn(1); got != 0 {
+		t.Errorf("Expected ln(1) = 0, got %s", got)
+	}
+}
+
+func TestDeterministicEngine_FixedPointScores(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	ctx := context.Background()
+
+	config := DefaultPropagationConfig()
+	config.Tolerance = 0
+	engine.SetPropagation(config)
+	snapshot, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol", "did:key:bob"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	// Scores and components are exact six-place values
+	for did, score := range snapshot.Scores {
+		for name, value := range map[string]float64{
+			"value": score.Value,
+			"K":     score.Components.K,
+			"V":     score.Components.V,
+		} {
+			if FixedFromFloat(value).Float64() != value {
+				t.Errorf("%s of %s is not a fixed-point value: %v", name, did, value)
+			}
+		}
+	}
+
+	// The fixed-point iteration lands exactly on its fixed point
+	if !snapshot.Converged || snapshot.Residual != 0 {
+		t.Errorf("Expected an exact fixed point, got residual %g after %d iterations",
+			snapshot.Residual, snapshot.Iterations)
+	}
+
+	canonical := scoreProofCanonical(&Score{DID: "did:key:alice", Context: "test_context", Value: 12.5, Epoch: 100}, "hash")
+	if !strings.Contains(canonical, "|12.500000|") {
+		t.Errorf("Expected the value signed with six places, got %s", canonical)
+	}
+}
@@ -4,27 +4,63 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 )
 
 // NetworkGraphAnalyzer implements GraphAnalyzer using network analysis algorithms
 type NetworkGraphAnalyzer struct {
 	dataProvider DataProvider
+	maxNodes     int
+	detection    *TemporalDetectionConfig
+	rules        []CollusionRule
+	partitions   map[string]*CommunityPartition // context|epoch -> partition
+	penalties    map[string]*VouchPenalties     // context|epoch -> penalties
+	mu           sync.Mutex
 }
 
 // NewNetworkGraphAnalyzer creates a new network graph analyzer
 func NewNetworkGraphAnalyzer(dataProvider DataProvider) GraphAnalyzer {
 	return &NetworkGraphAnalyzer{
 		dataProvider: dataProvider,
+		maxNodes:     100000,
+		detection:    DefaultTemporalDetectionConfig(),
+		rules:        DefaultCollusionRules(),
+		partitions:   make(map[string]*CommunityPartition),
+		penalties:    make(map[string]*VouchPenalties),
 	}
 }
 
+// SetTemporalDetection configures the temporal collusion detectors; nil
+// restores the defaults
+func (g *NetworkGraphAnalyzer) SetTemporalDetection(config *TemporalDetectionConfig) {
+	if config == nil {
+		config = DefaultTemporalDetectionConfig()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	
+	g.detection = config
+	g.penalties = make(map[string]*VouchPenalties)
+}
+
+// SetCollusionRules replaces the rules mapping detections to vouch
+// penalties
+func (g *NetworkGraphAnalyzer) SetCollusionRules(rules []CollusionRule) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	
+	g.rules = append([]CollusionRule{}, rules...)
+	g.penalties = make(map[string]*VouchPenalties)
+}
+
 // VouchEdge represents an edge in the vouch graph
 type VouchEdge struct {
-	From     string
-	To       string
-	Weight   float64
-	Strength float64
-	Epoch    int64
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Weight   float64 `json:"weight"`
+	Strength float64 `json:"strength"`
+	Epoch    int64   `json:"epoch"`
 }
 
 // VouchGraph represents the vouch graph for analysis
@@ -34,28 +70,209 @@ type VouchGraph struct {
 	AdjList map[string][]VouchEdge // Adjacency list representation
 }
 
-// buildVouchGraph constructs the vouch graph from vouches data
+// buildVouchGraph constructs the vouch graph of a whole context and epoch:
+// every DID the data provider lists, plus everyone reachable from them
+// through vouches, followed both ways when the provider can list vouchees.
+// Partial graphs would make communities depend on which DIDs were asked
+// about, so a provider that cannot list DIDs is an error.
 func (g *NetworkGraphAnalyzer) buildVouchGraph(ctx context.Context, context string, epoch int64) (*VouchGraph, error) {
+	lister, ok := g.dataProvider.(DIDLister)
+	if !ok {
+		return nil, fmt.Errorf("the data provider cannot list DIDs, so the vouch graph cannot be built")
+	}
+	queue, err := lister.ListDIDs(ctx, context, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DIDs: %w", err)
+	}
+	queue = append([]string{}, queue...)
+	sort.Strings(queue)
+	vouchees, _ := g.dataProvider.(VoucheeProvider)
+	
 	graph := &VouchGraph{
 		Nodes:   make(map[string]bool),
 		Edges:   make([]VouchEdge, 0),
 		AdjList: make(map[string][]VouchEdge),
 	}
-	
-	// This is a simplified approach - in practice, we'd need to get all vouches
-	// For now, we'll build incrementally as we encounter DIDs
-	// This would typically be pre-computed and cached
+	for len(queue) > 0 {
+		did := queue[0]
+		queue = queue[1:]
+		if graph.Nodes[did] {
+			continue
+		}
+		if len(graph.Nodes) >= g.maxNodes {
+			return nil, fmt.Errorf("vouch graph exceeds %d DIDs", g.maxNodes)
+		}
+		graph.Nodes[did] = true
+		
+		// Each vouch is seen once, from the DID it is addressed to
+		vouches, err := g.dataProvider.GetVouches(ctx, did, context, epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vouches for %s: %w", did, err)
+		}
+		for _, vouch := range vouches {
+			edge := VouchEdge{
+				From:     vouch.FromDID,
+				To:       did,
+				Weight:   vouch.Strength,
+				Strength: vouch.Strength,
+				Epoch:    vouch.Epoch,
+			}
+			graph.Edges = append(graph.Edges, edge)
+			graph.AdjList[edge.From] = append(graph.AdjList[edge.From], edge)
+			graph.AdjList[edge.To] = append(graph.AdjList[edge.To], edge)
+			queue = append(queue, vouch.FromDID)
+		}
+		
+		if vouchees != nil {
+			next, err := vouchees.GetVouchees(ctx, did, context, epoch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get vouchees of %s: %w", did, err)
+			}
+			queue = append(queue, next...)
+		}
+	}
 	
 	return graph, nil
 }
 
-// DetectCollusion implements GraphAnalyzer.DetectCollusion
-func (g *NetworkGraphAnalyzer) DetectCollusion(ctx context.Context, context string, epoch int64) ([]*CollusionCluster, error) {
+// DetectCommunities implements CommunityDetector. The partition of the
+// complete epoch graph is cached per context and epoch for scoring.
+func (g *NetworkGraphAnalyzer) DetectCommunities(ctx context.Context, context string, epoch int64) (*CommunityPartition, error) {
+	key := snapshotKey(context, epoch)
+	g.mu.Lock()
+	cached := g.partitions[key]
+	g.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	
+	partition, err := g.partition(ctx, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+	
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// Keep whichever partition was cached first, so every caller sees the
+	// same one
+	if cached := g.partitions[key]; cached != nil {
+		return cached, nil
+	}
+	g.partitions[key] = partition
+	return partition, nil
+}
+
+// PreviewCommunities implements CommunityDetector. It returns the cached
+// partition, or partitions the epoch without caching the result.
+func (g *NetworkGraphAnalyzer) PreviewCommunities(ctx context.Context, context string, epoch int64) (*CommunityPartition, error) {
+	g.mu.Lock()
+	cached := g.partitions[snapshotKey(context, epoch)]
+	g.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	return g.partition(ctx, context, epoch)
+}
+
+// partition builds and partitions the epoch graph
+func (g *NetworkGraphAnalyzer) partition(ctx context.Context, context string, epoch int64) (*CommunityPartition, error) {
 	graph, err := g.buildVouchGraph(ctx, context, epoch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build vouch graph: %w", err)
 	}
+	return partitionGraph(graph, context, epoch), nil
+}
+
+// InvalidateCommunities implements CommunityDetector
+func (g *NetworkGraphAnalyzer) InvalidateCommunities(context string, epoch int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	
+	key := snapshotKey(context, epoch)
+	delete(g.partitions, key)
+	delete(g.penalties, key)
+}
+
+// VouchPenalties implements VouchPenalizer. Detectors run over the graph of
+// the community partition, and the result is cached with it.
+func (g *NetworkGraphAnalyzer) VouchPenalties(ctx context.Context, context string, epoch int64) (*VouchPenalties, error) {
+	key := snapshotKey(context, epoch)
+	g.mu.Lock()
+	cached := g.penalties[key]
+	g.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	
+	partition, err := g.DetectCommunities(ctx, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+	penalties, err := g.penalize(partition, context, epoch)
+	if err != nil {
+		return nil, err
+	}
 	
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// A partition invalidated meanwhile takes its penalties with it
+	if g.partitions[key] != partition {
+		return penalties, nil
+	}
+	if cached := g.penalties[key]; cached != nil {
+		return cached, nil
+	}
+	g.penalties[key] = penalties
+	return penalties, nil
+}
+
+// PreviewVouchPenalties implements VouchPenalizer. It returns the cached
+// penalties, or finds them without caching anything.
+func (g *NetworkGraphAnalyzer) PreviewVouchPenalties(ctx context.Context, context string, epoch int64) (*VouchPenalties, error) {
+	g.mu.Lock()
+	cached := g.penalties[snapshotKey(context, epoch)]
+	g.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	
+	partition, err := g.PreviewCommunities(ctx, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return g.penalize(partition, context, epoch)
+}
+
+// penalize runs the collusion detectors over a partition's graph and maps
+// what they find to vouch penalties
+func (g *NetworkGraphAnalyzer) penalize(partition *CommunityPartition, context string, epoch int64) (*VouchPenalties, error) {
+	g.mu.Lock()
+	rules, detection := g.rules, g.detection
+	g.mu.Unlock()
+	
+	clusters, err := g.detectClusters(partition.graph, context, epoch, detection)
+	if err != nil {
+		return nil, err
+	}
+	return applyCollusionRules(clusters, rules, context, epoch), nil
+}
+
+// DetectCollusion implements GraphAnalyzer.DetectCollusion
+func (g *NetworkGraphAnalyzer) DetectCollusion(ctx context.Context, context string, epoch int64) ([]*CollusionCluster, error) {
+	partition, err := g.PreviewCommunities(ctx, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+	
+	g.mu.Lock()
+	detection := g.detection
+	g.mu.Unlock()
+	return g.detectClusters(partition.graph, context, epoch, detection)
+}
+
+// detectClusters runs the static dense subgraph search and the temporal
+// detectors over a graph
+func (g *NetworkGraphAnalyzer) detectClusters(graph *VouchGraph, context string, epoch int64, detection *TemporalDetectionConfig) ([]*CollusionCluster, error) {
 	// Find dense subgraphs that might indicate collusion
 	denseSubgraphs, err := g.findDenseSubgraphs(graph, 0.7) // 70% density threshold
 	if err != nil {
@@ -73,12 +290,38 @@ func (g *NetworkGraphAnalyzer) DetectCollusion(ctx context.Context, context stri
 				Density:     subgraph.Density,
 				VouchVolume: g.calculateVouchVolume(subgraph.Nodes, graph),
 				Confidence:  g.calculateCollusionConfidence(subgraph),
+				Detector:    DetectorDenseSubgraph,
+				Evidence:    g.subgraphEdges(subgraph.Nodes, graph),
+			}
+			if len(cluster.Evidence) > 0 {
+				cluster.FirstEpoch, cluster.LastEpoch = epochSpread(cluster.Evidence)
 			}
 			clusters = append(clusters, cluster)
 		}
 	}
 	
-	return clusters, nil
+	temporal, err := detectTemporal(graph, detection, context, epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run temporal detectors: %w", err)
+	}
+	
+	return append(clusters, temporal...), nil
+}
+
+// subgraphEdges returns the vouches within a group
+func (g *NetworkGraphAnalyzer) subgraphEdges(nodes []string, graph *VouchGraph) []VouchEdge {
+	nodeSet := make(map[string]bool)
+	for _, node := range nodes {
+		nodeSet[node] = true
+	}
+	
+	var edges []VouchEdge
+	for _, edge := range graph.Edges {
+		if nodeSet[edge.From] && nodeSet[edge.To] {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
 }
 
 // ComputeDiversity implements GraphAnalyzer.ComputeDiversity
@@ -99,8 +342,8 @@ func (g *NetworkGraphAnalyzer) ComputeDiversity(ctx context.Context, did, contex
 
 // computeShannnonDiversity computes diversity using Shannon entropy
 func (g *NetworkGraphAnalyzer) computeShannnonDiversity(ctx context.Context, targetDID string, vouches []*VouchData, context string, epoch int64) (float64, error) {
-	// Group vouchers by community/cluster
-	communities, err := g.identifyCommunities(ctx, vouches, context, epoch)
+	// Group vouchers by community
+	partition, err := g.PreviewCommunities(ctx, context, epoch)
 	if err != nil {
 		return 1.0, nil // Default to high diversity on error
 	}
@@ -110,88 +353,28 @@ func (g *NetworkGraphAnalyzer) computeShannnonDiversity(ctx context.Context, tar
 	totalVouches := len(vouches)
 	
 	for _, vouch := range vouches {
-		community := communities[vouch.FromDID]
-		if community == "" {
-			community = "unknown"
-		}
-		communityVouches[community]++
+		communityVouches[partition.Community(vouch.FromDID)]++
 	}
 	
-	// Calculate Shannon entropy
-	entropy := 0.0
+	// Calculate Shannon entropy in fixed point, using
+	// -p*log2(p) = p*(log2(total) - log2(count)). Fixed sums are exact, so
+	// map order does not matter.
+	var entropy Fixed
+	total := int64(totalVouches)
 	for _, count := range communityVouches {
 		if count > 0 {
-			p := float64(count) / float64(totalVouches)
-			entropy -= p * math.Log2(p)
+			p := FixedFromInt(int64(count)).Div(FixedFromInt(total))
+			entropy += p.Mul(FixedLog2(total) - FixedLog2(int64(count)))
 		}
 	}
 	
 	// Normalize entropy to [0, 1]
-	maxEntropy := math.Log2(float64(len(communityVouches)))
+	maxEntropy := FixedLog2(int64(len(communityVouches)))
 	if maxEntropy == 0 {
 		return 1.0, nil
 	}
 	
-	return entropy / maxEntropy, nil
-}
-
-// identifyCommunities identifies which community each DID belongs to
-func (g *NetworkGraphAnalyzer) identifyCommunities(ctx context.Context, vouches []*VouchData, context string, epoch int64) (map[string]string, error) {
-	// Simplified community detection - in practice would use more sophisticated algorithms
-	// like Louvain method, modularity optimization, etc.
-	
-	communities := make(map[string]string)
-	
-	// Simple clustering based on mutual vouching patterns
-	for _, vouch := range vouches {
-		if _, exists := communities[vouch.FromDID]; !exists {
-			// Check if this voucher is part of an existing community
-			community := g.findBestCommunity(ctx, vouch.FromDID, context, epoch, communities)
-			if community == "" {
-				// Create new community
-				community = fmt.Sprintf("community_%s", vouch.FromDID[:8])
-			}
-			communities[vouch.FromDID] = community
-		}
-	}
-	
-	return communities, nil
-}
-
-// findBestCommunity finds the best community for a DID based on connections
-func (g *NetworkGraphAnalyzer) findBestCommunity(ctx context.Context, did, context string, epoch int64, communities map[string]string) string {
-	// Get vouches from this DID
-	outgoingVouches, err := g.getOutgoingVouches(ctx, did, context, epoch)
-	if err != nil {
-		return ""
-	}
-	
-	// Count connections to existing communities
-	communityConnections := make(map[string]int)
-	for _, vouch := range outgoingVouches {
-		if community, exists := communities[vouch.ToDID]; exists {
-			communityConnections[community]++
-		}
-	}
-	
-	// Return community with most connections
-	bestCommunity := ""
-	maxConnections := 0
-	for community, connections := range communityConnections {
-		if connections > maxConnections {
-			maxConnections = connections
-			bestCommunity = community
-		}
-	}
-	
-	return bestCommunity
-}
-
-// getOutgoingVouches gets vouches made by a DID
-func (g *NetworkGraphAnalyzer) getOutgoingVouches(ctx context.Context, fromDID, context string, epoch int64) ([]*VouchData, error) {
-	// This would typically query the data provider for vouches WHERE from_did = fromDID
-	// For now, return empty slice as this requires additional data provider methods
-	return []*VouchData{}, nil
+	return min(entropy.Div(maxEntropy), FixedOne).Float64(), nil
 }
 
 // GetCommunityOverlap implements GraphAnalyzer.GetCommunityOverlap
@@ -205,29 +388,47 @@ func (g *NetworkGraphAnalyzer) GetCommunityOverlap(ctx context.Context, did, con
 		return 0.0, nil // No overlap possible
 	}
 	
-	communities, err := g.identifyCommunities(ctx, vouches, context, epoch)
+	partition, err := g.PreviewCommunities(ctx, context, epoch)
 	if err != nil {
 		return 0.0, err
 	}
 	
-	// Calculate overlap coefficient between communities
+	// Calculate overlap coefficient between the neighbour sets of the
+	// vouchers' communities
 	totalOverlap := 0.0
 	comparisons := 0
 	
 	communityMembers := make(map[string][]string)
-	for voucher, community := range communities {
-		communityMembers[community] = append(communityMembers[community], voucher)
+	for _, vouch := range vouches {
+		community := partition.Community(vouch.FromDID)
+		communityMembers[community] = append(communityMembers[community], vouch.FromDID)
+	}
+	
+	neighbors := make(map[string][]string)
+	for community, members := range communityMembers {
+		seen := make(map[string]bool)
+		for _, member := range members {
+			for _, edge := range partition.graph.AdjList[member] {
+				for _, neighbor := range []string{edge.From, edge.To} {
+					if neighbor != member && neighbor != did && !seen[neighbor] {
+						seen[neighbor] = true
+						neighbors[community] = append(neighbors[community], neighbor)
+					}
+				}
+			}
+		}
 	}
 	
-	// Compare each pair of communities
+	// Compare each pair of communities in a fixed order
 	communityList := make([]string, 0, len(communityMembers))
 	for community := range communityMembers {
 		communityList = append(communityList, community)
 	}
+	sort.Strings(communityList)
 	
 	for i := 0; i < len(communityList); i++ {
 		for j := i + 1; j < len(communityList); j++ {
-			overlap := g.calculateCommunityOverlap(communityMembers[communityList[i]], communityMembers[communityList[j]])
+			overlap := g.calculateCommunityOverlap(neighbors[communityList[i]], neighbors[communityList[j]])
 			totalOverlap += overlap
 			comparisons++
 		}
@@ -274,12 +475,12 @@ func (g *NetworkGraphAnalyzer) calculateCommunityOverlap(community1, community2
 
 // GetDenseSubgraphs implements GraphAnalyzer.GetDenseSubgraphs
 func (g *NetworkGraphAnalyzer) GetDenseSubgraphs(ctx context.Context, context string, epoch int64, threshold float64) ([]*DenseSubgraph, error) {
-	graph, err := g.buildVouchGraph(ctx, context, epoch)
+	partition, err := g.PreviewCommunities(ctx, context, epoch)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build vouch graph: %w", err)
+		return nil, err
 	}
 	
-	return g.findDenseSubgraphs(graph, threshold)
+	return g.findDenseSubgraphs(partition.graph, threshold)
 }
 
 // findDenseSubgraphs finds dense subgraphs using a greedy approach
@@ -292,6 +493,7 @@ func (g *NetworkGraphAnalyzer) findDenseSubgraphs(graph *VouchGraph, densityThre
 	for node := range graph.Nodes {
 		nodes = append(nodes, node)
 	}
+	sort.Strings(nodes)
 	
 	// Find dense subgraphs starting from each unvisited node
 	for _, node := range nodes {
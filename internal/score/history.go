@@ -0,0 +1,279 @@
+package score
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// ScoreHistory keeps the score of a DID per context and epoch, so trust can
+// be followed over time. Recording an epoch again replaces it.
+type ScoreHistory interface {
+	// RecordScore saves a score under its DID, context and epoch
+	RecordScore(ctx context.Context, score *Score) error
+
+	// GetScoreHistory returns a DID's scores in an inclusive epoch range,
+	// oldest first
+	GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*Score, error)
+}
+
+// MemoryScoreHistory keeps score history in memory
+type MemoryScoreHistory struct {
+	scores map[string]map[int64]*Score // did|context -> epoch -> score
+	mu     sync.RWMutex
+}
+
+// NewMemoryScoreHistory creates an empty in-memory score history
+func NewMemoryScoreHistory() *MemoryScoreHistory {
+	return &MemoryScoreHistory{
+		scores: make(map[string]map[int64]*Score),
+	}
+}
+
+// RecordScore implements ScoreHistory
+func (h *MemoryScoreHistory) RecordScore(ctx context.Context, score *Score) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := score.DID + "|" + score.Context
+	if h.scores[key] == nil {
+		h.scores[key] = make(map[int64]*Score)
+	}
+	record := *score
+	h.scores[key][score.Epoch] = &record
+	return nil
+}
+
+// GetScoreHistory implements ScoreHistory
+func (h *MemoryScoreHistory) GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*Score, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var scores []*Score
+	for epoch, score := range h.scores[did+"|"+context] {
+		if epoch >= fromEpoch && epoch <= toEpoch {
+			record := *score
+			scores = append(scores, &record)
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Epoch < scores[j].Epoch
+	})
+	return scores, nil
+}
+
+// PersistentScoreHistory keeps score history in a RocksDB or SQLite store
+type PersistentScoreHistory struct {
+	store store.ScoreHistoryStore
+}
+
+// NewPersistentScoreHistory creates a score history backed by a store
+func NewPersistentScoreHistory(historyStore store.ScoreHistoryStore) *PersistentScoreHistory {
+	return &PersistentScoreHistory{store: historyStore}
+}
+
+// RecordScore implements ScoreHistory
+func (h *PersistentScoreHistory) RecordScore(ctx context.Context, score *Score) error {
+	data, err := json.Marshal(score)
+	if err != nil {
+		return fmt.Errorf("failed to encode score: %w", err)
+	}
+
+	return h.store.StoreScoreRecord(ctx, &store.ScoreRecord{
+		DID:       score.DID,
+		Context:   score.Context,
+		Epoch:     score.Epoch,
+		Value:     score.Value,
+		Timestamp: score.Timestamp,
+		Data:      data,
+	})
+}
+
+// GetScoreHistory implements ScoreHistory
+func (h *PersistentScoreHistory) GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*Score, error) {
+	records, err := h.store.GetScoreHistory(ctx, did, context, fromEpoch, toEpoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read score history: %w", err)
+	}
+
+	scores := make([]*Score, 0, len(records))
+	for _, record := range records {
+		score := &Score{}
+		if len(record.Data) > 0 {
+			if err := json.Unmarshal(record.Data, score); err != nil {
+				return nil, fmt.Errorf("failed to decode score for epoch %d: %w", record.Epoch, err)
+			}
+		}
+		// The indexed columns are authoritative
+		score.DID, score.Context, score.Epoch = record.DID, record.Context, record.Epoch
+		score.Value, score.Timestamp = record.Value, record.Timestamp
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// defaultHistoryEpochs is how far back a history query reaches when no
+// start epoch is given
+const defaultHistoryEpochs = 90
+
+// DownsampleMode chooses how the scores within a bucket of epochs are
+// reduced to one point
+type DownsampleMode string
+
+const (
+	// DownsampleLast keeps the latest score in each bucket
+	DownsampleLast DownsampleMode = "last"
+	// DownsampleMean averages the scores in each bucket
+	DownsampleMean DownsampleMode = "mean"
+	// DownsampleMin keeps the lowest score in each bucket
+	DownsampleMin DownsampleMode = "min"
+	// DownsampleMax keeps the highest score in each bucket
+	DownsampleMax DownsampleMode = "max"
+)
+
+// ScoreDiff is the change from the previous point of a series
+type ScoreDiff struct {
+	Epochs int64   `json:"epochs"` // Epochs since the previous point
+	Value  float64 `json:"value"`
+	K      float64 `json:"k"`
+	A      float64 `json:"a"`
+	V      float64 `json:"v"`
+	R      float64 `json:"r"`
+	T      float64 `json:"t"`
+}
+
+// ScorePoint is one bucket of a score series. Epoch is the first epoch of
+// the bucket.
+type ScorePoint struct {
+	Epoch      int64           `json:"epoch"`
+	Value      float64         `json:"value"`
+	Components ScoreComponents `json:"components"`
+	Samples    int             `json:"samples"`        // Scores in the bucket
+	Diff       *ScoreDiff      `json:"diff,omitempty"` // Absent on the first point
+}
+
+// ScoreSeries is a DID's score over an epoch range, one point per bucket of
+// Step epochs that has any scores
+type ScoreSeries struct {
+	DID     string         `json:"did"`
+	Context string         `json:"context"`
+	From    int64          `json:"from"`
+	To      int64          `json:"to"`
+	Step    int64          `json:"step"`
+	Mode    DownsampleMode `json:"mode"`
+	Points  []*ScorePoint  `json:"points"`
+}
+
+// BuildScoreSeries downsamples scores of one DID into buckets of step epochs
+// starting at from, and diffs each point against the one before. Scores
+// outside [from, to] are ignored.
+func BuildScoreSeries(did, context string, scores []*Score, from, to, step int64, mode DownsampleMode) (*ScoreSeries, error) {
+	if to < from {
+		return nil, fmt.Errorf("epoch range %d-%d is empty", from, to)
+	}
+	if step <= 0 {
+		step = 1
+	}
+	if mode == "" {
+		mode = DownsampleLast
+	}
+	switch mode {
+	case DownsampleLast, DownsampleMean, DownsampleMin, DownsampleMax:
+	default:
+		return nil, fmt.Errorf("unknown downsample mode %q", mode)
+	}
+
+	buckets := make(map[int64][]*Score)
+	for _, score := range scores {
+		if score.Epoch < from || score.Epoch > to {
+			continue
+		}
+		bucket := from + (score.Epoch-from)/step*step
+		buckets[bucket] = append(buckets[bucket], score)
+	}
+	starts := make([]int64, 0, len(buckets))
+	for start := range buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	series := &ScoreSeries{
+		DID:     did,
+		Context: context,
+		From:    from,
+		To:      to,
+		Step:    step,
+		Mode:    mode,
+		Points:  make([]*ScorePoint, 0, len(starts)),
+	}
+	var previous *ScorePoint
+	for _, start := range starts {
+		point := downsample(buckets[start], mode)
+		point.Epoch = start
+		if previous != nil {
+			point.Diff = &ScoreDiff{
+				Epochs: point.Epoch - previous.Epoch,
+				Value:  fixedDiff(point.Value, previous.Value),
+				K:      fixedDiff(point.Components.K, previous.Components.K),
+				A:      fixedDiff(point.Components.A, previous.Components.A),
+				V:      fixedDiff(point.Components.V, previous.Components.V),
+				R:      fixedDiff(point.Components.R, previous.Components.R),
+				T:      fixedDiff(point.Components.T, previous.Components.T),
+			}
+		}
+		series.Points = append(series.Points, point)
+		previous = point
+	}
+
+	return series, nil
+}
+
+// downsample reduces the scores of a bucket to one point. Ties go to the
+// later epoch.
+func downsample(scores []*Score, mode DownsampleMode) *ScorePoint {
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Epoch < scores[j].Epoch })
+
+	if mode == DownsampleMean {
+		var value, k, a, v, r, t Fixed
+		for _, score := range scores {
+			value += FixedFromFloat(score.Value)
+			k += FixedFromFloat(score.Components.K)
+			a += FixedFromFloat(score.Components.A)
+			v += FixedFromFloat(score.Components.V)
+			r += FixedFromFloat(score.Components.R)
+			t += FixedFromFloat(score.Components.T)
+		}
+		n := FixedFromInt(int64(len(scores)))
+		mean := func(sum Fixed) float64 { return sum.Div(n).Float64() }
+		return &ScorePoint{
+			Value: mean(value),
+			Components: ScoreComponents{
+				K: mean(k), A: mean(a), V: mean(v), R: mean(r), T: mean(t),
+			},
+			Samples: len(scores),
+		}
+	}
+
+	chosen := scores[len(scores)-1]
+	for _, score := range scores {
+		if (mode == DownsampleMin && score.Value <= chosen.Value) ||
+			(mode == DownsampleMax && score.Value >= chosen.Value) {
+			chosen = score
+		}
+	}
+	return &ScorePoint{
+		Value:      chosen.Value,
+		Components: chosen.Components,
+		Samples:    len(scores),
+	}
+}
+
+// fixedDiff subtracts at the engine's precision so diffs carry no float
+// noise
+func fixedDiff(current, previous float64) float64 {
+	return (FixedFromFloat(current) - FixedFromFloat(previous)).Float64()
+}
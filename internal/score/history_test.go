@@ -0,0 +1,136 @@
+package score
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+func TestDeterministicEngine_RecordsSnapshotHistory(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	history := NewMemoryScoreHistory()
+	engine.SetScoreHistory(history)
+	ctx := context.Background()
+
+	// Scores computed outside a solved epoch are not history
+	if _, err := engine.ComputeScore(ctx, "did:key:alice", "test_context", 99); err != nil {
+		t.Fatalf("ComputeScore failed: %v", err)
+	}
+	if scores, _ := history.GetScoreHistory(ctx, "did:key:alice", "test_context", 99, 99); len(scores) != 0 {
+		t.Fatalf("Expected no history for an unsolved epoch, got %d scores", len(scores))
+	}
+
+	dids := []string{"did:key:alice", "did:key:bob", "did:key:carol"}
+	for _, epoch := range []int64{100, 101, 102, 103} {
+		if _, err := engine.SolveEpoch(ctx, "test_context", epoch, dids); err != nil {
+			t.Fatalf("SolveEpoch %d failed: %v", epoch, err)
+		}
+	}
+	// A recomputed epoch replaces the earlier record
+	if _, err := engine.SolveEpoch(ctx, "test_context", 101, dids); err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	scores, err := history.GetScoreHistory(ctx, "did:key:alice", "test_context", 101, 103)
+	if err != nil {
+		t.Fatalf("GetScoreHistory failed: %v", err)
+	}
+	if len(scores) != 3 || scores[0].Epoch != 101 || scores[2].Epoch != 103 {
+		t.Fatalf("Expected epochs 101-103 in order, got %d scores", len(scores))
+	}
+	stored, _ := provider.GetScore(ctx, "did:key:alice", "test_context", 103)
+	if stored == nil || stored.Value != scores[2].Value {
+		t.Errorf("Expected the history to match the stored score, got %+v", scores[2])
+	}
+	if other, _ := history.GetScoreHistory(ctx, "did:key:alice", "other", 0, 200); len(other) != 0 {
+		t.Errorf("Expected no history in another context, got %d scores", len(other))
+	}
+}
+
+func TestBuildScoreSeries(t *testing.T) {
+	var scores []*Score
+	for epoch, value := range map[int64]float64{10: 1, 11: 3, 12: 2, 14: 6, 20: 9} {
+		scores = append(scores, &Score{
+			DID: "did:key:alice", Context: "default", Epoch: epoch, Value: value,
+			Components: ScoreComponents{K: value / 2, V: value / 2},
+		})
+	}
+
+	series, err := BuildScoreSeries("did:key:alice", "default", scores, 10, 15, 3, "")
+	if err != nil {
+		t.Fatalf("BuildScoreSeries failed: %v", err)
+	}
+	if series.Mode != DownsampleLast || len(series.Points) != 2 {
+		t.Fatalf("Expected two buckets of the last score, got %+v", series)
+	}
+	first, second := series.Points[0], series.Points[1]
+	if first.Epoch != 10 || first.Value != 2 || first.Samples != 3 || first.Diff != nil {
+		t.Errorf("Unexpected first point %+v", first)
+	}
+	if second.Epoch != 13 || second.Value != 6 || second.Samples != 1 {
+		t.Errorf("Unexpected second point %+v", second)
+	}
+	if diff := second.Diff; diff == nil || diff.Epochs != 3 || diff.Value != 4 || diff.K != 2 || diff.A != 0 {
+		t.Errorf("Unexpected diff %+v", second.Diff)
+	}
+
+	expected := map[DownsampleMode]float64{DownsampleMean: 2, DownsampleMin: 1, DownsampleMax: 3}
+	for mode, value := range expected {
+		series, err := BuildScoreSeries("did:key:alice", "default", scores, 10, 12, 3, mode)
+		if err != nil {
+			t.Fatalf("BuildScoreSeries %s failed: %v", mode, err)
+		}
+		if len(series.Points) != 1 || math.Abs(series.Points[0].Value-value) > 1e-9 {
+			t.Errorf("Expected %s to give %f, got %+v", mode, value, series.Points)
+		}
+	}
+
+	if _, err := BuildScoreSeries("did:key:alice", "default", scores, 10, 20, 1, "median"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+	if _, err := BuildScoreSeries("did:key:alice", "default", scores, 20, 10, 1, ""); err == nil {
+		t.Error("Expected an empty range to be rejected")
+	}
+}
+
+func TestPersistentScoreHistory(t *testing.T) {
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	sqliteStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	history := NewPersistentScoreHistory(sqliteStore)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, epoch := range []int64{5, 6} {
+		if err := history.RecordScore(ctx, &Score{
+			DID: "did:key:alice", Context: "default", Epoch: epoch, Value: float64(epoch),
+			Components: ScoreComponents{K: 1.5, SlashedBonds: []string{"did:key:carol"}},
+			Timestamp:  now,
+		}); err != nil {
+			t.Fatalf("RecordScore failed: %v", err)
+		}
+	}
+
+	scores, err := history.GetScoreHistory(ctx, "did:key:alice", "default", 6, 10)
+	if err != nil {
+		t.Fatalf("GetScoreHistory failed: %v", err)
+	}
+	if len(scores) != 1 {
+		t.Fatalf("Expected one score, got %d", len(scores))
+	}
+	score := scores[0]
+	if score.Epoch != 6 || score.Value != 6 || !score.Timestamp.Equal(now) {
+		t.Errorf("Unexpected score %+v", score)
+	}
+	if score.Components.K != 1.5 || len(score.Components.SlashedBonds) != 1 {
+		t.Errorf("Expected the components to round-trip, got %+v", score.Components)
+	}
+}
@@ -97,6 +97,20 @@ type DecayFunction interface {
 	ComputeTimeBonus(firstActivity, lastActivity int64, currentEpoch int64, maxGrowth float64) float64
 }
 
+// FixedDecayFunction is implemented by decay functions that compute in
+// fixed point. The engine uses these methods when they are available so
+// that scores replay bit for bit on any platform.
+type FixedDecayFunction interface {
+	// ApplyDecayFixed applies exponential decay to a value based on time elapsed
+	ApplyDecayFixed(value Fixed, elapsedEpochs int64, halfLife int64) Fixed
+	
+	// ApplyInactivityDecayFixed applies decay for inactivity
+	ApplyInactivityDecayFixed(value Fixed, inactiveEpochs int64, decayRate Fixed) Fixed
+	
+	// ComputeTimeBonusFixed computes time-based bonus with bounded growth
+	ComputeTimeBonusFixed(firstActivity, lastActivity int64, currentEpoch int64, maxGrowth Fixed) Fixed
+}
+
 // ScoreValidator defines the interface for score validation
 type ScoreValidator interface {
 	// ValidateInputData validates all input data for score computation
@@ -120,6 +134,13 @@ type CollusionCluster struct {
 	Density     float64  `json:"density"`      // Graph density
 	VouchVolume float64  `json:"vouch_volume"` // Total vouch volume
 	Confidence  float64  `json:"confidence"`   // Detection confidence
+	
+	// Detector that found the cluster, the vouches implicating it and the
+	// epochs they were made in
+	Detector   string      `json:"detector,omitempty"`
+	Evidence   []VouchEdge `json:"evidence,omitempty"`
+	FirstEpoch int64       `json:"first_epoch,omitempty"`
+	LastEpoch  int64       `json:"last_epoch,omitempty"`
 }
 
 // DenseSubgraph represents a dense subgraph in the vouch network
@@ -161,4 +182,16 @@ type BatchComputeRequest struct {
 type BatchComputeResponse struct {
 	Responses []*ComputeResponse `json:"responses"`
 	Errors    []string          `json:"errors,omitempty"`
-}
\ No newline at end of file
+}
+
+// SolveEpochRequest represents a request to solve the scores of an epoch
+type SolveEpochRequest struct {
+	Context string   `json:"context"`
+	DIDs    []string `json:"dids,omitempty"` // Defaults to every DID the data provider lists
+}
+
+// FreshnessResponse reports which checkpoint a context's scores reflect
+type FreshnessResponse struct {
+	Freshness *ScoreFreshness `json:"freshness,omitempty"` // Absent before the first checkpoint
+	Stats     *RecomputeStats `json:"stats"`
+}
@@ -0,0 +1,46 @@
+package score
+
+import (
+	"fmt"
+
+	"github.com/ParichayaHQ/credence/internal/vc"
+)
+
+// KYCDataFromMdoc turns a verified mdoc into KYC evidence for subjectDID.
+// A mobile driving licence counts as level 3 and any other document type as
+// level 2, one level higher when the holder's device authenticated the
+// presentation. A zero weight defaults to 10 per level. The issuer is the
+// IACA the document signer chains to, and the evidence expires with the
+// mobile security object.
+func KYCDataFromMdoc(mdoc *vc.VerifiedMdoc, subjectDID, context string, epoch int64, weight float64) (*KYCData, error) {
+	if mdoc == nil || mdoc.MSO == nil {
+		return nil, fmt.Errorf("mdoc has not been verified")
+	}
+	if subjectDID == "" {
+		return nil, fmt.Errorf("subject DID is required")
+	}
+
+	level := 2
+	if mdoc.DocType == vc.MdocDocTypeMDL {
+		level = 3
+	}
+	if mdoc.DeviceAuthenticated {
+		level++
+	}
+	if weight == 0 {
+		weight = float64(10 * level)
+	}
+
+	validUntil := mdoc.MSO.ValidityInfo.ValidUntil
+	return &KYCData{
+		DID:       subjectDID,
+		Context:   context,
+		Type:      "kyc",
+		Level:     level,
+		IssuerDID: mdoc.IssuerID,
+		Weight:    weight,
+		Timestamp: mdoc.MSO.ValidityInfo.Signed,
+		Epoch:     epoch,
+		ExpiresAt: &validUntil,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package score
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/vc"
+)
+
+func TestKYCDataFromMdoc(t *testing.T) {
+	signed := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	mdoc := &vc.VerifiedMdoc{
+		DocType:  vc.MdocDocTypeMDL,
+		IssuerID: "x509:sha256:abcd",
+		MSO: &vc.MobileSecurityObject{
+			DocType: vc.MdocDocTypeMDL,
+			ValidityInfo: vc.ValidityInfo{
+				Signed:     signed,
+				ValidFrom:  signed,
+				ValidUntil: signed.AddDate(1, 0, 0),
+			},
+		},
+		DeviceAuthenticated: true,
+	}
+
+	kyc, err := KYCDataFromMdoc(mdoc, "did:key:holder", "general", 42, 0)
+	if err != nil {
+		t.Fatalf("KYCDataFromMdoc failed: %v", err)
+	}
+	if kyc.Level != 4 || kyc.Weight != 40 {
+		t.Errorf("Expected a device-authenticated mDL at level 4 weighing 40, got level %d weight %f", kyc.Level, kyc.Weight)
+	}
+	if kyc.Type != "kyc" || kyc.IssuerDID != mdoc.IssuerID || kyc.Epoch != 42 {
+		t.Errorf("Unexpected KYC data: %+v", kyc)
+	}
+	if kyc.ExpiresAt == nil || !kyc.ExpiresAt.Equal(signed.AddDate(1, 0, 0)) {
+		t.Errorf("Expected evidence to expire with the MSO, got %v", kyc.ExpiresAt)
+	}
+
+	mdoc.DocType = "org.iso.23220.photoid.1"
+	mdoc.DeviceAuthenticated = false
+	kyc, err = KYCDataFromMdoc(mdoc, "did:key:holder", "general", 42, 15)
+	if err != nil {
+		t.Fatalf("KYCDataFromMdoc failed: %v", err)
+	}
+	if kyc.Level != 2 || kyc.Weight != 15 {
+		t.Errorf("Expected level 2 with the given weight, got level %d weight %f", kyc.Level, kyc.Weight)
+	}
+
+	if _, err := KYCDataFromMdoc(&vc.VerifiedMdoc{}, "did:key:holder", "general", 42, 0); err == nil {
+		t.Error("Expected an unverified mdoc to be rejected")
+	}
+}
@@ -0,0 +1,435 @@
+package score
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// migrationRegistryStateKey is where the registry keeps its statements in a
+// state store
+const migrationRegistryStateKey = "score/migrations"
+
+// MigrationRegistry tracks verified key migration statements so that a DID
+// recovered onto a new key keeps the trust accumulated by its predecessor.
+// Each DID migrates at most once and receives at most one migration, so a
+// lineage is a single chain that no two DIDs can pool their trust into.
+type MigrationRegistry struct {
+	keyManager   did.KeyManager
+	statements   map[string]*did.KeyMigrationStatement // old DID -> statement
+	predecessors map[string][]string                   // new DID -> old DIDs
+	state        store.StateStore
+	mu           sync.RWMutex
+}
+
+// NewMigrationRegistry creates a new migration registry
+func NewMigrationRegistry(keyManager did.KeyManager) *MigrationRegistry {
+	if keyManager == nil {
+		keyManager = did.NewDefaultKeyManager()
+	}
+
+	return &MigrationRegistry{
+		keyManager:   keyManager,
+		statements:   make(map[string]*did.KeyMigrationStatement),
+		predecessors: make(map[string][]string),
+	}
+}
+
+// SetStateStore loads the statements kept in a state store and records new
+// ones in it, so migrations survive a restart
+func (r *MigrationRegistry) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), migrationRegistryStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if data != nil {
+		var statements []*did.KeyMigrationStatement
+		if err := json.Unmarshal(data, &statements); err != nil {
+			return fmt.Errorf("failed to decode migrations: %w", err)
+		}
+		for _, statement := range statements {
+			if err := r.check(statement); err != nil {
+				return fmt.Errorf("stored migration from %s: %w", statement.OldDID, err)
+			}
+			r.add(statement)
+		}
+	}
+
+	r.state = stateStore
+	return nil
+}
+
+// Register verifies and records a migration statement
+func (r *MigrationRegistry) Register(ctx context.Context, statement *did.KeyMigrationStatement) error {
+	if err := did.VerifyKeyMigration(r.keyManager, statement); err != nil {
+		return fmt.Errorf("invalid migration statement: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.statements[statement.OldDID]; exists && existing.NewDID == statement.NewDID {
+		return nil
+	}
+	if err := r.check(statement); err != nil {
+		return err
+	}
+
+	r.add(statement)
+	if err := r.save(ctx); err != nil {
+		r.remove(statement)
+		return err
+	}
+	return nil
+}
+
+// check validates a statement against the recorded ones. The caller holds
+// the lock.
+func (r *MigrationRegistry) check(statement *did.KeyMigrationStatement) error {
+	if existing, exists := r.statements[statement.OldDID]; exists {
+		return fmt.Errorf("DID %s already migrated to %s", statement.OldDID, existing.NewDID)
+	}
+	if existing := r.predecessors[statement.NewDID]; len(existing) > 0 {
+		return fmt.Errorf("DID %s already succeeds %s", statement.NewDID, existing[0])
+	}
+
+	// Reject statements that would make the lineage cyclic
+	for current := statement.NewDID; current != ""; {
+		if current == statement.OldDID {
+			return fmt.Errorf("migration from %s to %s creates a cycle", statement.OldDID, statement.NewDID)
+		}
+		next, exists := r.statements[current]
+		if !exists {
+			break
+		}
+		current = next.NewDID
+	}
+	return nil
+}
+
+// add indexes a checked statement. The caller holds the lock.
+func (r *MigrationRegistry) add(statement *did.KeyMigrationStatement) {
+	r.statements[statement.OldDID] = statement
+	r.predecessors[statement.NewDID] = append(r.predecessors[statement.NewDID], statement.OldDID)
+	sort.Strings(r.predecessors[statement.NewDID])
+}
+
+// remove undoes add. The caller holds the lock.
+func (r *MigrationRegistry) remove(statement *did.KeyMigrationStatement) {
+	delete(r.statements, statement.OldDID)
+	delete(r.predecessors, statement.NewDID)
+}
+
+// save writes the statements to the state store, if there is one. The caller
+// holds the lock.
+func (r *MigrationRegistry) save(ctx context.Context) error {
+	if r.state == nil {
+		return nil
+	}
+
+	oldDIDs := make([]string, 0, len(r.statements))
+	for oldDID := range r.statements {
+		oldDIDs = append(oldDIDs, oldDID)
+	}
+	sort.Strings(oldDIDs)
+	statements := make([]*did.KeyMigrationStatement, 0, len(oldDIDs))
+	for _, oldDID := range oldDIDs {
+		statements = append(statements, r.statements[oldDID])
+	}
+
+	data, err := json.Marshal(statements)
+	if err != nil {
+		return fmt.Errorf("failed to encode migrations: %w", err)
+	}
+	if err := r.state.PutState(ctx, migrationRegistryStateKey, data); err != nil {
+		return &transientError{fmt.Errorf("failed to save migrations: %w", err)}
+	}
+	return nil
+}
+
+// Retired reports whether a DID has migrated to a successor
+func (r *MigrationRegistry) Retired(didStr string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, exists := r.statements[didStr]
+	return exists
+}
+
+// GetStatement returns the migration statement for a retired DID
+func (r *MigrationRegistry) GetStatement(oldDID string) (*did.KeyMigrationStatement, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statement, exists := r.statements[oldDID]
+	return statement, exists
+}
+
+// Successor returns the current DID for a possibly retired DID
+func (r *MigrationRegistry) Successor(didStr string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	current := didStr
+	for {
+		statement, exists := r.statements[current]
+		if !exists {
+			return current
+		}
+		current = statement.NewDID
+	}
+}
+
+// Lineage returns all transitive predecessors of a DID in deterministic order
+func (r *MigrationRegistry) Lineage(didStr string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var lineage []string
+	queue := append([]string{}, r.predecessors[didStr]...)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		lineage = append(lineage, current)
+		queue = append(queue, r.predecessors[current]...)
+	}
+
+	sort.Strings(lineage)
+	return lineage
+}
+
+// MigrationAwareDataProvider merges scoring inputs recorded against
+// predecessor DIDs into their successor. Vouches, attestations, reports and
+// tenure carry over; KYC data does not, since it is bound to the old key.
+// A retired DID keeps only its reports, so its trust is not counted twice,
+// and vouches it gave count as given by its successor.
+type MigrationAwareDataProvider struct {
+	DataProvider
+	registry *MigrationRegistry
+}
+
+// NewMigrationAwareDataProvider wraps a data provider with migration support
+func NewMigrationAwareDataProvider(provider DataProvider, registry *MigrationRegistry) *MigrationAwareDataProvider {
+	return &MigrationAwareDataProvider{
+		DataProvider: provider,
+		registry:     registry,
+	}
+}
+
+// GetVouches returns vouches for the DID and its predecessors, with each
+// voucher replaced by its current DID. When a voucher vouched several times
+// across the lineage, or under several of its own DIDs, only the most recent
+// vouch counts.
+func (p *MigrationAwareDataProvider) GetVouches(ctx context.Context, didStr, context string, maxEpoch int64) ([]*VouchData, error) {
+	if p.registry.Retired(didStr) {
+		return nil, nil
+	}
+
+	vouches, err := p.DataProvider.GetVouches(ctx, didStr, context, maxEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := p.registry.Lineage(didStr)
+	members := map[string]bool{didStr: true}
+	for _, predecessor := range lineage {
+		members[predecessor] = true
+	}
+
+	latest := make(map[string]*VouchData)
+	order := make([]string, 0)
+	add := func(vouch *VouchData) {
+		from := p.registry.Successor(vouch.FromDID)
+		// Vouches between DIDs of the same lineage are not counted
+		if members[vouch.FromDID] || members[from] {
+			return
+		}
+		existing, exists := latest[from]
+		if !exists {
+			order = append(order, from)
+		}
+		if !exists || vouch.Epoch > existing.Epoch {
+			merged := *vouch
+			merged.FromDID = from
+			merged.ToDID = didStr
+			latest[from] = &merged
+		}
+	}
+
+	for _, vouch := range vouches {
+		add(vouch)
+	}
+	for _, predecessor := range lineage {
+		inherited, err := p.DataProvider.GetVouches(ctx, predecessor, context, maxEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vouches for predecessor %s: %w", predecessor, err)
+		}
+		for _, vouch := range inherited {
+			add(vouch)
+		}
+	}
+
+	merged := make([]*VouchData, 0, len(order))
+	for _, from := range order {
+		merged = append(merged, latest[from])
+	}
+	return merged, nil
+}
+
+// ListDIDs implements DIDLister with the current DIDs of everyone the
+// wrapped provider lists. Retired DIDs are listed under their successor.
+func (p *MigrationAwareDataProvider) ListDIDs(ctx context.Context, context string, epoch int64) ([]string, error) {
+	lister, ok := p.DataProvider.(DIDLister)
+	if !ok {
+		return nil, fmt.Errorf("the wrapped data provider cannot list DIDs")
+	}
+	dids, err := lister.ListDIDs(ctx, context, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(dids))
+	current := make([]string, 0, len(dids))
+	for _, didStr := range dids {
+		successor := p.registry.Successor(didStr)
+		if !seen[successor] {
+			seen[successor] = true
+			current = append(current, successor)
+		}
+	}
+	sort.Strings(current)
+	return current, nil
+}
+
+// GetVouchees implements VoucheeProvider with whom the DID and its
+// predecessors vouched for, by their current DIDs. A retired DID vouches
+// for no one; its vouches count as its successor's.
+func (p *MigrationAwareDataProvider) GetVouchees(ctx context.Context, didStr, context string, maxEpoch int64) ([]string, error) {
+	provider, ok := p.DataProvider.(VoucheeProvider)
+	if !ok {
+		return nil, fmt.Errorf("the wrapped data provider cannot list vouchees")
+	}
+	if p.registry.Retired(didStr) {
+		return nil, nil
+	}
+
+	lineage := append([]string{didStr}, p.registry.Lineage(didStr)...)
+	members := make(map[string]bool, len(lineage))
+	for _, member := range lineage {
+		members[member] = true
+	}
+
+	seen := make(map[string]bool)
+	var vouchees []string
+	for _, member := range lineage {
+		next, err := provider.GetVouchees(ctx, member, context, maxEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vouchees of %s: %w", member, err)
+		}
+		for _, vouchee := range next {
+			successor := p.registry.Successor(vouchee)
+			if members[vouchee] || members[successor] || seen[successor] {
+				continue
+			}
+			seen[successor] = true
+			vouchees = append(vouchees, successor)
+		}
+	}
+	return vouchees, nil
+}
+
+// GetAttestations returns attestations for the DID and its predecessors
+func (p *MigrationAwareDataProvider) GetAttestations(ctx context.Context, didStr, context string, maxEpoch int64) ([]*AttestationData, error) {
+	if p.registry.Retired(didStr) {
+		return nil, nil
+	}
+
+	attestations, err := p.DataProvider.GetAttestations(ctx, didStr, context, maxEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, predecessor := range p.registry.Lineage(didStr) {
+		inherited, err := p.DataProvider.GetAttestations(ctx, predecessor, context, maxEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attestations for predecessor %s: %w", predecessor, err)
+		}
+		attestations = append(attestations, inherited...)
+	}
+	return attestations, nil
+}
+
+// GetReports returns reports against the DID and its predecessors, so a
+// migration cannot be used to shed negative history
+func (p *MigrationAwareDataProvider) GetReports(ctx context.Context, didStr, context string, maxEpoch int64) ([]*ReportData, error) {
+	reports, err := p.DataProvider.GetReports(ctx, didStr, context, maxEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, predecessor := range p.registry.Lineage(didStr) {
+		inherited, err := p.DataProvider.GetReports(ctx, predecessor, context, maxEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reports for predecessor %s: %w", predecessor, err)
+		}
+		reports = append(reports, inherited...)
+	}
+	return reports, nil
+}
+
+// GetKYCData returns the DID's KYC data, or none once it has retired
+func (p *MigrationAwareDataProvider) GetKYCData(ctx context.Context, didStr, context string, maxEpoch int64) ([]*KYCData, error) {
+	if p.registry.Retired(didStr) {
+		return nil, nil
+	}
+	return p.DataProvider.GetKYCData(ctx, didStr, context, maxEpoch)
+}
+
+// GetTimeData returns time data with tenure measured from the earliest
+// activity anywhere in the DID's lineage
+func (p *MigrationAwareDataProvider) GetTimeData(ctx context.Context, didStr, context string, maxEpoch int64) (*TimeData, error) {
+	if p.registry.Retired(didStr) {
+		return nil, nil
+	}
+
+	timeData, err := p.DataProvider.GetTimeData(ctx, didStr, context, maxEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := p.registry.Lineage(didStr)
+	if timeData != nil && len(lineage) > 0 {
+		copied := *timeData
+		timeData = &copied
+	}
+
+	for _, predecessor := range lineage {
+		inherited, err := p.DataProvider.GetTimeData(ctx, predecessor, context, maxEpoch)
+		if err != nil || inherited == nil {
+			continue
+		}
+		if timeData == nil {
+			merged := *inherited
+			merged.DID = didStr
+			timeData = &merged
+			continue
+		}
+		if !inherited.FirstActivity.IsZero() && (timeData.FirstActivity.IsZero() || inherited.FirstActivity.Before(timeData.FirstActivity)) {
+			timeData.FirstActivity = inherited.FirstActivity
+		}
+		if inherited.LastActivity.After(timeData.LastActivity) {
+			timeData.LastActivity = inherited.LastActivity
+		}
+		timeData.ActivityCount += inherited.ActivityCount
+	}
+	return timeData, nil
+}
@@ -0,0 +1,305 @@
+package score
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+func newMigrationTestDID(t *testing.T, keyManager did.KeyManager) (string, interface{}) {
+	privateKey, err := keyManager.GenerateKey(did.KeyTypeEd25519)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	result, err := did.NewKeyMethodResolver(keyManager).Create(context.Background(), &did.CreationOptions{
+		KeyType:    did.KeyTypeEd25519,
+		PrivateKey: privateKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create DID: %v", err)
+	}
+
+	return result.DID, privateKey
+}
+
+func newMigrationStatement(t *testing.T, keyManager did.KeyManager, oldKey, newKey interface{}, oldDID, newDID string) *did.KeyMigrationStatement {
+	statement := &did.KeyMigrationStatement{
+		Type:     did.KeyMigrationStatementType,
+		OldDID:   oldDID,
+		NewDID:   newDID,
+		IssuedAt: time.Now(),
+	}
+	if err := did.SignKeyMigration(keyManager, statement, oldKey, newKey); err != nil {
+		t.Fatalf("Failed to sign migration: %v", err)
+	}
+	return statement
+}
+
+func TestMigrationRegistry_Register(t *testing.T) {
+	keyManager := did.NewDefaultKeyManager()
+	registry := NewMigrationRegistry(keyManager)
+	ctx := context.Background()
+
+	oldDID, oldKey := newMigrationTestDID(t, keyManager)
+	newDID, newKey := newMigrationTestDID(t, keyManager)
+	otherDID, otherKey := newMigrationTestDID(t, keyManager)
+
+	statement := newMigrationStatement(t, keyManager, oldKey, newKey, oldDID, newDID)
+	if err := registry.Register(ctx, statement); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	if successor := registry.Successor(oldDID); successor != newDID {
+		t.Errorf("Expected successor %s, got %s", newDID, successor)
+	}
+
+	// A DID can only migrate once
+	conflicting := newMigrationStatement(t, keyManager, oldKey, otherKey, oldDID, otherDID)
+	if err := registry.Register(ctx, conflicting); err == nil {
+		t.Error("Expected conflicting migration to be rejected")
+	}
+
+	// Migrating back would create a cycle
+	cyclic := newMigrationStatement(t, keyManager, newKey, oldKey, newDID, oldDID)
+	if err := registry.Register(ctx, cyclic); err == nil {
+		t.Error("Expected cyclic migration to be rejected")
+	}
+
+	// Tampered statements fail verification
+	tampered := newMigrationStatement(t, keyManager, newKey, otherKey, newDID, otherDID)
+	tampered.Reason = "changed after signing"
+	if err := registry.Register(ctx, tampered); err == nil {
+		t.Error("Expected tampered migration to be rejected")
+	}
+
+	// Two DIDs cannot pool their trust into one successor
+	merging := newMigrationStatement(t, keyManager, otherKey, newKey, otherDID, newDID)
+	if err := registry.Register(ctx, merging); err == nil {
+		t.Error("Expected a second migration onto the same DID to be rejected")
+	}
+	if registry.Retired(otherDID) || len(registry.Lineage(newDID)) != 1 {
+		t.Error("Expected the rejected migration to leave no trace")
+	}
+}
+
+func TestMigrationRegistry_GuardianAttestations(t *testing.T) {
+	keyManager := did.NewDefaultKeyManager()
+	registry := NewMigrationRegistry(keyManager)
+	ctx := context.Background()
+
+	oldDID, oldKey := newMigrationTestDID(t, keyManager)
+	newDID, newKey := newMigrationTestDID(t, keyManager)
+	guardianA, guardianAKey := newMigrationTestDID(t, keyManager)
+	guardianB, guardianBKey := newMigrationTestDID(t, keyManager)
+
+	attest := func(guardian string, guardianKey interface{}, threshold int) *did.GuardianAttestation {
+		attestation := &did.GuardianAttestation{
+			OldDID:      oldDID,
+			NewDID:      newDID,
+			GuardianDID: guardian,
+			Threshold:   threshold,
+			IssuedAt:    time.Now(),
+		}
+		if err := did.SignGuardianAttestation(keyManager, attestation, guardianKey); err != nil {
+			t.Fatalf("Failed to sign attestation: %v", err)
+		}
+		return attestation
+	}
+	recovery := func(guardians []string, attestations ...*did.GuardianAttestation) *did.KeyMigrationStatement {
+		statement := &did.KeyMigrationStatement{
+			Type:         did.KeyMigrationStatementType,
+			OldDID:       oldDID,
+			NewDID:       newDID,
+			Reason:       did.MigrationReasonSocialRecovery,
+			Guardians:    guardians,
+			IssuedAt:     time.Now(),
+			Attestations: attestations,
+		}
+		if err := did.SignKeyMigration(keyManager, statement, oldKey, newKey); err != nil {
+			t.Fatalf("Failed to sign migration: %v", err)
+		}
+		return statement
+	}
+
+	// Naming guardians is not enough; each must have attested
+	if err := registry.Register(ctx, recovery([]string{guardianA, guardianB})); err == nil {
+		t.Error("Expected a recovery without attestations to be rejected")
+	}
+
+	// A guardian's attestation does not stand in for another's
+	forged := attest(guardianA, guardianAKey, 2)
+	forged.GuardianDID = guardianB
+	if err := registry.Register(ctx, recovery([]string{guardianA, guardianB}, attest(guardianA, guardianAKey, 2), forged)); err == nil {
+		t.Error("Expected a forged attestation to be rejected")
+	}
+
+	// One guardian falls short of the threshold
+	if err := registry.Register(ctx, recovery([]string{guardianA}, attest(guardianA, guardianAKey, 2))); err == nil {
+		t.Error("Expected a recovery short of its threshold to be rejected")
+	}
+	if err := registry.Register(ctx, recovery([]string{guardianA}, attest(guardianA, guardianAKey, 1))); err == nil {
+		t.Error("Expected a single-guardian threshold to be rejected")
+	}
+	if registry.Retired(oldDID) {
+		t.Fatal("Expected rejected recoveries to leave no trace")
+	}
+
+	statement := recovery([]string{guardianA, guardianB}, attest(guardianA, guardianAKey, 2), attest(guardianB, guardianBKey, 2))
+	if err := registry.Register(ctx, statement); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if successor := registry.Successor(oldDID); successor != newDID {
+		t.Errorf("Expected successor %s, got %s", newDID, successor)
+	}
+}
+
+func TestMigrationRegistry_Persistence(t *testing.T) {
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	keyManager := did.NewDefaultKeyManager()
+	ctx := context.Background()
+	oldDID, oldKey := newMigrationTestDID(t, keyManager)
+	newDID, newKey := newMigrationTestDID(t, keyManager)
+	latestDID, latestKey := newMigrationTestDID(t, keyManager)
+
+	registry := NewMigrationRegistry(keyManager)
+	if err := registry.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	for _, statement := range []*did.KeyMigrationStatement{
+		newMigrationStatement(t, keyManager, oldKey, newKey, oldDID, newDID),
+		newMigrationStatement(t, keyManager, newKey, latestKey, newDID, latestDID),
+	} {
+		if err := registry.Register(ctx, statement); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	restarted := NewMigrationRegistry(keyManager)
+	if err := restarted.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	if successor := restarted.Successor(oldDID); successor != latestDID {
+		t.Errorf("Expected successor %s after restart, got %s", latestDID, successor)
+	}
+	if lineage := restarted.Lineage(latestDID); len(lineage) != 2 {
+		t.Errorf("Expected two predecessors after restart, got %v", lineage)
+	}
+	if _, exists := restarted.GetStatement(newDID); !exists {
+		t.Error("Expected the statement to be restored")
+	}
+}
+
+func TestMigrationAwareDataProvider_CarriesOverHistory(t *testing.T) {
+	keyManager := did.NewDefaultKeyManager()
+	registry := NewMigrationRegistry(keyManager)
+	ctx := context.Background()
+
+	oldDID, oldKey := newMigrationTestDID(t, keyManager)
+	newDID, newKey := newMigrationTestDID(t, keyManager)
+	if err := registry.Register(ctx, newMigrationStatement(t, keyManager, oldKey, newKey, oldDID, newDID)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	base := NewTestDataProvider().(*TestDataProvider)
+	contextName := "test_context"
+
+	base.AddVouch(&VouchData{FromDID: "did:key:alice", ToDID: oldDID, Context: contextName, Strength: 10, Epoch: 5})
+	base.AddVouch(&VouchData{FromDID: "did:key:bob", ToDID: oldDID, Context: contextName, Strength: 10, Epoch: 6})
+	base.AddVouch(&VouchData{FromDID: "did:key:bob", ToDID: newDID, Context: contextName, Strength: 12, Epoch: 20})
+	base.AddReport(&ReportData{ReporterDID: "did:key:carol", ReportedDID: oldDID, Context: contextName, Severity: 0.5, Epoch: 7})
+
+	firstActivity := time.Now().AddDate(-2, 0, 0)
+	base.SetTimeData(oldDID, contextName, &TimeData{DID: oldDID, Context: contextName, FirstActivity: firstActivity, ActivityCount: 30})
+	base.SetTimeData(newDID, contextName, &TimeData{DID: newDID, Context: contextName, FirstActivity: time.Now(), ActivityCount: 2})
+
+	provider := NewMigrationAwareDataProvider(base, registry)
+
+	vouches, err := provider.GetVouches(ctx, newDID, contextName, 100)
+	if err != nil {
+		t.Fatalf("GetVouches failed: %v", err)
+	}
+	if len(vouches) != 2 {
+		t.Fatalf("Expected 2 vouches after merging, got %d", len(vouches))
+	}
+	for _, vouch := range vouches {
+		if vouch.ToDID != newDID {
+			t.Errorf("Expected vouch to target %s, got %s", newDID, vouch.ToDID)
+		}
+		if vouch.FromDID == "did:key:bob" && vouch.Epoch != 20 {
+			t.Errorf("Expected latest vouch from bob, got epoch %d", vouch.Epoch)
+		}
+	}
+
+	reports, err := provider.GetReports(ctx, newDID, contextName, 100)
+	if err != nil {
+		t.Fatalf("GetReports failed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Errorf("Expected predecessor report to carry over, got %d", len(reports))
+	}
+
+	timeData, err := provider.GetTimeData(ctx, newDID, contextName, 100)
+	if err != nil {
+		t.Fatalf("GetTimeData failed: %v", err)
+	}
+	if !timeData.FirstActivity.Equal(firstActivity) {
+		t.Errorf("Expected tenure from predecessor, got %v", timeData.FirstActivity)
+	}
+	if timeData.ActivityCount != 32 {
+		t.Errorf("Expected combined activity count 32, got %d", timeData.ActivityCount)
+	}
+
+	// The retired DID no longer scores on its own
+	if vouches, err := provider.GetVouches(ctx, oldDID, contextName, 100); err != nil || len(vouches) != 0 {
+		t.Errorf("Expected no vouches for the retired DID, got %d, %v", len(vouches), err)
+	}
+	if timeData, err := provider.GetTimeData(ctx, oldDID, contextName, 100); err != nil || timeData != nil {
+		t.Errorf("Expected no time data for the retired DID, got %+v, %v", timeData, err)
+	}
+	if reports, err := provider.GetReports(ctx, oldDID, contextName, 100); err != nil || len(reports) != 1 {
+		t.Errorf("Expected reports against the retired DID to remain, got %d, %v", len(reports), err)
+	}
+
+	// Vouches the retired DID gave count as its successor's, and only once
+	base.AddVouch(&VouchData{FromDID: oldDID, ToDID: "did:key:dave", Context: contextName, Strength: 10, Epoch: 8})
+	base.AddVouch(&VouchData{FromDID: newDID, ToDID: "did:key:dave", Context: contextName, Strength: 10, Epoch: 9})
+	vouches, err = provider.GetVouches(ctx, "did:key:dave", contextName, 100)
+	if err != nil {
+		t.Fatalf("GetVouches failed: %v", err)
+	}
+	if len(vouches) != 1 || vouches[0].FromDID != newDID || vouches[0].Epoch != 9 {
+		t.Errorf("Expected one vouch from %s, got %+v", newDID, vouches)
+	}
+
+	// The graph sees the lineage as its current DID
+	dids, err := provider.ListDIDs(ctx, contextName, 100)
+	if err != nil {
+		t.Fatalf("ListDIDs failed: %v", err)
+	}
+	for _, listed := range dids {
+		if listed == oldDID {
+			t.Errorf("Expected the retired DID to be listed as %s, got %v", newDID, dids)
+		}
+	}
+	vouchees, err := provider.GetVouchees(ctx, newDID, contextName, 100)
+	if err != nil {
+		t.Fatalf("GetVouchees failed: %v", err)
+	}
+	if len(vouchees) != 1 || vouchees[0] != "did:key:dave" {
+		t.Errorf("Expected the lineage to vouch for dave once, got %v", vouchees)
+	}
+	if vouchees, _ := provider.GetVouchees(ctx, oldDID, contextName, 100); len(vouchees) != 0 {
+		t.Errorf("Expected the retired DID to vouch for no one, got %v", vouchees)
+	}
+}
@@ -0,0 +1,516 @@
+package score
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PropagationConfig controls the joint solve of the scores in a context.
+// A vouch passes on (1-Damping)*PriorScore + Damping*S_j of the voucher's
+// score S_j, which keeps the iteration a contraction and gives vouchers
+// with no evidence of their own a small, fixed weight.
+type PropagationConfig struct {
+	Damping       float64 `json:"damping"`        // Share of the voucher's score passed on, in [0, 1)
+	PriorScore    float64 `json:"prior_score"`    // Score passed on in place of the rest
+	Tolerance     float64 `json:"tolerance"`      // Largest change between iterations at convergence
+	MaxIterations int     `json:"max_iterations"` // Iterations before the solve stops unconverged
+	MaxDIDs       int     `json:"max_dids"`       // Bound on the DIDs reached through vouches
+}
+
+// DefaultPropagationConfig returns the default propagation configuration
+func DefaultPropagationConfig() *PropagationConfig {
+	return &PropagationConfig{
+		Damping:       0.85,
+		PriorScore:    10.0,
+		Tolerance:     1e-6,
+		MaxIterations: 200,
+		MaxDIDs:       100000,
+	}
+}
+
+// ErrSnapshotNotConverged is returned instead of publishing a snapshot whose
+// solve did not converge
+var ErrSnapshotNotConverged = errors.New("score snapshot did not converge")
+
+// ScoreSnapshot is the jointly solved set of scores for a context and epoch
+type ScoreSnapshot struct {
+	Context    string            `json:"context"`
+	Epoch      int64             `json:"epoch"`
+	Scores     map[string]*Score `json:"scores"`
+	Iterations int               `json:"iterations"`
+	Residual   float64           `json:"residual"` // Largest change in the last iteration
+	Converged  bool              `json:"converged"`
+	Version    string            `json:"version"`              // Hash of the scores
+	Checkpoint *CheckpointMarker `json:"checkpoint,omitempty"` // Log checkpoint the scores include
+}
+
+// ScoreSnapshotStore persists the authoritative snapshot of each epoch
+type ScoreSnapshotStore interface {
+	// StoreSnapshot persists a snapshot, replacing any earlier one for the
+	// same context and epoch
+	StoreSnapshot(ctx context.Context, snapshot *ScoreSnapshot) error
+
+	// GetSnapshot returns the snapshot for a context and epoch, or nil if
+	// none was solved
+	GetSnapshot(ctx context.Context, context string, epoch int64) (*ScoreSnapshot, error)
+}
+
+// DIDLister is implemented by data providers that can enumerate the DIDs
+// active in a context, so a whole epoch can be solved without a DID list
+type DIDLister interface {
+	ListDIDs(ctx context.Context, context string, epoch int64) ([]string, error)
+}
+
+// EpochSolver solves and serves epoch score snapshots
+type EpochSolver interface {
+	// SolveEpoch jointly computes and persists the scores of a context
+	SolveEpoch(ctx context.Context, context string, epoch int64, dids []string) (*ScoreSnapshot, error)
+
+	// GetSnapshot returns the persisted snapshot of an epoch, or nil
+	GetSnapshot(ctx context.Context, context string, epoch int64) (*ScoreSnapshot, error)
+}
+
+// MemorySnapshotStore keeps score snapshots in memory
+type MemorySnapshotStore struct {
+	snapshots map[string]*ScoreSnapshot // context|epoch -> snapshot
+	mu        sync.RWMutex
+}
+
+// NewMemorySnapshotStore creates an empty in-memory snapshot store
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{
+		snapshots: make(map[string]*ScoreSnapshot),
+	}
+}
+
+// StoreSnapshot implements ScoreSnapshotStore
+func (m *MemorySnapshotStore) StoreSnapshot(ctx context.Context, snapshot *ScoreSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.snapshots[snapshotKey(snapshot.Context, snapshot.Epoch)] = snapshot
+	return nil
+}
+
+// GetSnapshot implements ScoreSnapshotStore
+func (m *MemorySnapshotStore) GetSnapshot(ctx context.Context, context string, epoch int64) (*ScoreSnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.snapshots[snapshotKey(context, epoch)], nil
+}
+
+func snapshotKey(context string, epoch int64) string {
+	return fmt.Sprintf("%s|%d", context, epoch)
+}
+
+// propagationEdge is a vouch into a DID, resolved to the voucher's index
+type propagationEdge struct {
+	from      int
+	strength  Fixed // Decayed strength times the diversity and collusion weights
+	vouch     *VouchData
+	decayed   Fixed
+	diversity Fixed
+	collusion Fixed
+}
+
+// propagationNode holds the parts of a DID's score that do not depend on
+// other scores
+type propagationNode struct {
+	did        string
+	components ScoreComponents
+	base       Fixed // α*K + β*A - δ*R + τ*T
+	diversity  Fixed // Multiplier applied to V
+	bond       Fixed // Share of V kept after slashed bonds
+	slashed    []string
+	edges      []propagationEdge
+	pinned     bool // Held at base instead of being solved
+}
+
+// SolveEpoch implements EpochSolver. The DIDs given, or every DID the data
+// provider lists when none are, are solved together with everyone vouching
+// for them, and the result becomes the snapshot per-DID queries read.
+func (e *DeterministicEngine) SolveEpoch(ctx context.Context, context string, epoch int64, dids []string) (*ScoreSnapshot, error) {
+	if len(dids) == 0 {
+		lister, ok := e.dataProvider.(DIDLister)
+		if !ok {
+			return nil, fmt.Errorf("no DIDs given and the data provider cannot list them")
+		}
+		var err error
+		if dids, err = lister.ListDIDs(ctx, context, epoch); err != nil {
+			return nil, fmt.Errorf("failed to list DIDs: %w", err)
+		}
+	}
+
+	snapshot, err := e.solve(ctx, context, epoch, dids, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.publishSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// publishSnapshot persists a snapshot and replaces the cached per-DID scores
+// with it. A solve that ran out of iterations is not a fixed point, so it
+// never becomes authoritative.
+func (e *DeterministicEngine) publishSnapshot(ctx context.Context, snapshot *ScoreSnapshot) error {
+	if !snapshot.Converged {
+		return fmt.Errorf("%w: %s epoch %d after %d iterations, residual %g",
+			ErrSnapshotNotConverged, snapshot.Context, snapshot.Epoch, snapshot.Iterations, snapshot.Residual)
+	}
+	if e.snapshots != nil {
+		if err := e.snapshots.StoreSnapshot(ctx, snapshot); err != nil {
+			return fmt.Errorf("failed to store snapshot: %w", err)
+		}
+	}
+
+	// Replace whatever was cached per DID so every read agrees with the
+	// snapshot
+	for _, did := range sortedScoreDIDs(snapshot.Scores) {
+		if err := e.dataProvider.StoreScore(ctx, snapshot.Scores[did]); err != nil {
+			return fmt.Errorf("failed to store score for %s: %w", did, err)
+		}
+	}
+
+	// Only solved epochs become history, never scores cached on the way
+	if e.history != nil {
+		for _, did := range sortedScoreDIDs(snapshot.Scores) {
+			if err := e.history.RecordScore(ctx, snapshot.Scores[did]); err != nil {
+				return fmt.Errorf("failed to record score history for %s: %w", did, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetSnapshot implements EpochSolver
+func (e *DeterministicEngine) GetSnapshot(ctx context.Context, context string, epoch int64) (*ScoreSnapshot, error) {
+	if e.snapshots == nil {
+		return nil, nil
+	}
+	return e.snapshots.GetSnapshot(ctx, context, epoch)
+}
+
+// snapshotScore returns a DID's score from the epoch snapshot, if any
+func (e *DeterministicEngine) snapshotScore(ctx context.Context, did, context string, epoch int64) *Score {
+	if e.snapshots == nil {
+		return nil
+	}
+	snapshot, err := e.snapshots.GetSnapshot(ctx, context, epoch)
+	if err != nil || snapshot == nil {
+		return nil
+	}
+	return snapshot.Scores[did]
+}
+
+// solve iterates the scoring formula over the DIDs and their vouchers until
+// no score moves by more than the tolerance. Each iteration reads only the
+// previous one and visits DIDs in sorted order, so the result does not
+// depend on the order DIDs are given in or on what was cached before. The
+// iteration runs in fixed point: every step is monotone and starts below the
+// fixed point, so it settles on exactly the same values everywhere.
+//
+// Pinned DIDs are held at the given scores and not expanded, so a few DIDs
+// can be re-solved against an existing snapshot; only the unpinned DIDs are
+// returned.
+func (e *DeterministicEngine) solve(ctx context.Context, context string, epoch int64, dids []string, pinned map[string]*Score) (*ScoreSnapshot, error) {
+	nodes, err := e.buildPropagationGraph(ctx, context, epoch, dids, pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	factors := e.config.Factors
+	result, err := e.propagate(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	current := result.scores
+
+	snapshot := &ScoreSnapshot{
+		Context:    context,
+		Epoch:      epoch,
+		Scores:     make(map[string]*Score, len(nodes)),
+		Iterations: result.iterations,
+		Residual:   result.residual.Float64(),
+		Converged:  result.converged,
+	}
+
+	now := time.Now()
+	for _, node := range nodes {
+		if node.pinned {
+			continue
+		}
+		components, _, value := e.nodeScore(node, current)
+
+		if e.validator != nil {
+			if err := e.validator.ValidateScoreRange(value.Float64()); err != nil {
+				return nil, fmt.Errorf("score validation failed for %s: %w", node.did, err)
+			}
+			if err := e.validator.ValidateComponents(&components, &factors); err != nil {
+				return nil, fmt.Errorf("component validation failed for %s: %w", node.did, err)
+			}
+		}
+
+		snapshot.Scores[node.did] = &Score{
+			DID:        node.did,
+			Context:    context,
+			Value:      value.Float64(),
+			Epoch:      epoch,
+			Timestamp:  now,
+			Components: components,
+			ComputedBy: "deterministic-engine",
+			Version:    "1.0.0",
+		}
+	}
+	sealSnapshot(snapshot)
+
+	return snapshot, nil
+}
+
+// nodeScore returns a DID's components, V and score given the scores of
+// its vouchers
+func (e *DeterministicEngine) nodeScore(node *propagationNode, scores []Fixed) (ScoreComponents, Fixed, Fixed) {
+	components := node.components
+	full := e.vouchFactor(node, scores)
+	v := full.Mul(node.bond)
+	components.V = v.Float64()
+	if len(node.slashed) > 0 {
+		components.BondSlash = (full - v).Float64()
+		components.SlashedBonds = node.slashed
+	}
+	return components, v, e.withVouches(node.base, v)
+}
+
+// propagationResult is where the iteration over a vouch graph settled
+type propagationResult struct {
+	scores     []Fixed
+	iterations int
+	residual   Fixed
+	converged  bool
+}
+
+// propagate iterates the scoring formula over the nodes until no score
+// moves by more than the tolerance
+func (e *DeterministicEngine) propagate(ctx context.Context, nodes []*propagationNode) (*propagationResult, error) {
+	config := e.propagation
+	tolerance := FixedFromFloat(config.Tolerance)
+
+	current := make([]Fixed, len(nodes))
+	for i, node := range nodes {
+		current[i] = max(node.base, 0)
+	}
+
+	iterations := 0
+	var residual Fixed
+	converged := len(nodes) == 0
+	next := make([]Fixed, len(nodes))
+	for !converged && iterations < config.MaxIterations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		iterations++
+
+		residual = 0
+		for i := range nodes {
+			if nodes[i].pinned {
+				next[i] = current[i]
+				continue
+			}
+			next[i] = e.withVouches(nodes[i].base, e.vouchFactor(nodes[i], current).Mul(nodes[i].bond))
+			residual = max(residual, next[i]-current[i], current[i]-next[i])
+		}
+		current, next = next, current
+		converged = residual <= tolerance
+	}
+
+	return &propagationResult{scores: current, iterations: iterations, residual: residual, converged: converged}, nil
+}
+
+// passedScore is the share of a voucher's score a vouch passes on: damped
+// towards the prior and capped
+func (e *DeterministicEngine) passedScore(score Fixed) Fixed {
+	damping := FixedFromFloat(e.propagation.Damping)
+	prior := (FixedOne - damping).Mul(FixedFromFloat(e.propagation.PriorScore))
+	return min(prior+damping.Mul(score), FixedFromFloat(e.config.VouchCap))
+}
+
+// vouchSum is the sum under V's square root given the voucher scores
+func (e *DeterministicEngine) vouchSum(node *propagationNode, scores []Fixed) Fixed {
+	var weightedSum Fixed
+	for _, edge := range node.edges {
+		weightedSum += e.passedScore(scores[edge.from]).Mul(edge.strength)
+	}
+	return weightedSum
+}
+
+// vouchFactor is V before any slashed bonds are taken off
+func (e *DeterministicEngine) vouchFactor(node *propagationNode, scores []Fixed) Fixed {
+	return e.vouchSum(node, scores).Sqrt().Mul(node.diversity)
+}
+
+// sealSnapshot sets a snapshot's version from its scores
+func sealSnapshot(snapshot *ScoreSnapshot) {
+	hasher := sha256.New()
+	for _, did := range sortedScoreDIDs(snapshot.Scores) {
+		hasher.Write([]byte(fmt.Sprintf("%s:%s;", did, FixedFromFloat(snapshot.Scores[did].Value))))
+	}
+	hasher.Write([]byte(fmt.Sprintf("iterations:%d;converged:%t", snapshot.Iterations, snapshot.Converged)))
+	snapshot.Version = hex.EncodeToString(hasher.Sum(nil))
+}
+
+// buildPropagationGraph collects the DIDs and everyone transitively vouching
+// for them, in sorted order, with the score inputs that do not change
+// between iterations. Pinned DIDs end the walk: their vouchers are already
+// reflected in the pinned score.
+func (e *DeterministicEngine) buildPropagationGraph(ctx context.Context, context string, epoch int64, dids []string, pinned map[string]*Score) ([]*propagationNode, error) {
+	vouchesTo := make(map[string][]*VouchData)
+	queue := append([]string{}, dids...)
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		did := queue[0]
+		queue = queue[1:]
+		if _, seen := vouchesTo[did]; seen {
+			continue
+		}
+		if len(vouchesTo) >= e.propagation.MaxDIDs {
+			return nil, fmt.Errorf("vouch graph exceeds %d DIDs", e.propagation.MaxDIDs)
+		}
+		if pinned[did] != nil {
+			vouchesTo[did] = []*VouchData{}
+			continue
+		}
+
+		vouches, err := e.dataProvider.GetVouches(ctx, did, context, epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get vouches for %s: %w", did, err)
+		}
+		if vouches == nil {
+			vouches = []*VouchData{}
+		}
+		vouchesTo[did] = vouches
+		for _, vouch := range vouches {
+			if _, seen := vouchesTo[vouch.FromDID]; !seen {
+				queue = append(queue, vouch.FromDID)
+			}
+		}
+	}
+
+	order := make([]string, 0, len(vouchesTo))
+	for did := range vouchesTo {
+		order = append(order, did)
+	}
+	sort.Strings(order)
+	index := make(map[string]int, len(order))
+	for i, did := range order {
+		index[did] = i
+	}
+
+	// Community detection gives each vouch its own diversity weight;
+	// otherwise the analyzer's diversity scales V as a whole
+	var partition *CommunityPartition
+	if detector, ok := e.graphAnalyzer.(CommunityDetector); ok {
+		var err error
+		if partition, err = detector.DetectCommunities(ctx, context, epoch); err != nil {
+			return nil, fmt.Errorf("failed to detect communities: %w", err)
+		}
+	}
+	// Vouches implicated in collusion pass on less
+	var penalties *VouchPenalties
+	if penalizer, ok := e.graphAnalyzer.(VouchPenalizer); ok {
+		var err error
+		if penalties, err = penalizer.VouchPenalties(ctx, context, epoch); err != nil {
+			return nil, fmt.Errorf("failed to detect collusion: %w", err)
+		}
+	}
+	penalty := FixedFromFloat(e.config.DiversityPenalty)
+
+	nodes := make([]*propagationNode, len(order))
+	for i, did := range order {
+		node := &propagationNode{did: did, diversity: FixedOne, bond: FixedOne}
+		if score := pinned[did]; score != nil {
+			node.pinned = true
+			node.base = FixedFromFloat(score.Value)
+			nodes[i] = node
+			continue
+		}
+		components, err := e.computeStaticComponents(ctx, did, context, epoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute components for %s: %w", did, err)
+		}
+		node.components = *components
+		node.base = e.staticValue(components)
+
+		// Each slashed bond forfeits its fraction of what remains
+		if e.bonds != nil {
+			bonds, err := e.bonds.SlashedBonds(ctx, did, context, epoch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get bonds for %s: %w", did, err)
+			}
+			for _, bond := range bonds {
+				node.bond = node.bond.Mul(max(FixedOne-FixedFromFloat(bond.Fraction), 0))
+				node.slashed = append(node.slashed, bond.VoucheeDID)
+			}
+		}
+
+		var weights map[string]Fixed
+		if partition != nil {
+			weights = vouchDiversityWeights(partition, vouchesTo[did], penalty)
+		} else if e.graphAnalyzer != nil {
+			if diversity, err := e.graphAnalyzer.ComputeDiversity(ctx, did, context, epoch); err == nil {
+				node.diversity = FixedOne - penalty.Mul(FixedOne-FixedFromFloat(diversity))
+			}
+		}
+
+		// Sort so the weighted sums add up in the same order every time
+		vouches := append([]*VouchData{}, vouchesTo[did]...)
+		sort.Slice(vouches, func(a, b int) bool {
+			if vouches[a].FromDID != vouches[b].FromDID {
+				return vouches[a].FromDID < vouches[b].FromDID
+			}
+			if vouches[a].Epoch != vouches[b].Epoch {
+				return vouches[a].Epoch < vouches[b].Epoch
+			}
+			return vouches[a].Strength < vouches[b].Strength
+		})
+		for _, vouch := range vouches {
+			edge := propagationEdge{
+				from:      index[vouch.FromDID],
+				vouch:     vouch,
+				decayed:   e.applyDecay(FixedFromFloat(vouch.Strength), epoch-vouch.Epoch, e.config.VouchHalfLife),
+				diversity: FixedOne,
+				collusion: FixedOne,
+			}
+			edge.strength = edge.decayed
+			if weights != nil {
+				edge.diversity = weights[vouch.FromDID]
+				edge.strength = edge.strength.Mul(edge.diversity)
+			}
+			if penalties != nil {
+				edge.collusion = FixedFromFloat(penalties.Factor(vouch.FromDID, did))
+				edge.strength = edge.strength.Mul(edge.collusion)
+			}
+			node.edges = append(node.edges, edge)
+		}
+		nodes[i] = node
+	}
+
+	return nodes, nil
+}
+
+func sortedScoreDIDs(scores map[string]*Score) []string {
+	dids := make([]string, 0, len(scores))
+	for did := range scores {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	return dids
+}
@@ -0,0 +1,208 @@
+package score
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// newPropagationEngine builds an engine over a small vouch graph: alice and
+// bob vouch for each other, alice vouches for carol and an unknown DID
+// vouches for bob
+func newPropagationEngine() (*DeterministicEngine, *TestDataProvider) {
+	provider := NewTestDataProvider().(*TestDataProvider)
+	for _, vouch := range []*VouchData{
+		{FromDID: "did:key:alice", ToDID: "did:key:bob", Strength: 10, Epoch: 98},
+		{FromDID: "did:key:bob", ToDID: "did:key:alice", Strength: 8, Epoch: 99},
+		{FromDID: "did:key:alice", ToDID: "did:key:carol", Strength: 12, Epoch: 100},
+		{FromDID: "did:key:stranger", ToDID: "did:key:bob", Strength: 5, Epoch: 100},
+	} {
+		vouch.Context = "test_context"
+		vouch.Timestamp = time.Now()
+		provider.AddVouch(vouch)
+	}
+	provider.AddKYC(&KYCData{
+		DID:       "did:key:alice",
+		Context:   "test_context",
+		Type:      "kyc_level_2",
+		Level:     2,
+		IssuerDID: "did:key:kyc",
+		Weight:    40.0,
+		Epoch:     95,
+	})
+
+	engine := NewDeterministicEngine(
+		DefaultScoreConfig(),
+		provider,
+		nil,
+		nil,
+		NewExponentialDecayFunction(),
+		NewTestValidator(),
+		nil,
+	)
+	return engine, provider
+}
+
+func TestDeterministicEngine_SolveEpochFixedPoint(t *testing.T) {
+	engine, _ := newPropagationEngine()
+	engine.SetSnapshotStore(NewMemorySnapshotStore())
+	ctx := context.Background()
+
+	snapshot, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+	if !snapshot.Converged {
+		t.Fatalf("Expected convergence, residual %g after %d iterations", snapshot.Residual, snapshot.Iterations)
+	}
+
+	// Everyone upstream of carol is solved with her
+	for _, did := range []string{"did:key:alice", "did:key:bob", "did:key:carol", "did:key:stranger"} {
+		if snapshot.Scores[did] == nil {
+			t.Fatalf("Expected %s in the snapshot", did)
+		}
+	}
+
+	// At the fixed point each V is the formula applied to the solved scores
+	config := DefaultPropagationConfig()
+	passed := func(did string) float64 {
+		return (1-config.Damping)*config.PriorScore + config.Damping*snapshot.Scores[did].Value
+	}
+	decay := NewExponentialDecayFunction()
+	halfLife := DefaultScoreConfig().VouchHalfLife
+	expectedBobV := math.Sqrt(passed("did:key:alice")*decay.ApplyDecay(10, 2, halfLife) +
+		passed("did:key:stranger")*decay.ApplyDecay(5, 0, halfLife))
+	if v := snapshot.Scores["did:key:bob"].Components.V; math.Abs(v-expectedBobV) > 1e-4 {
+		t.Errorf("Expected bob's V %f, got %f", expectedBobV, v)
+	}
+
+	// A voucher with no evidence passes on only the damped prior
+	if value := snapshot.Scores["did:key:stranger"].Value; value != 0 {
+		t.Errorf("Expected stranger to score 0, got %f", value)
+	}
+	if snapshot.Scores["did:key:alice"].Value <= snapshot.Scores["did:key:carol"].Value {
+		t.Errorf("Expected alice's KYC to put her above carol")
+	}
+}
+
+func TestDeterministicEngine_SolveEpochDeterministic(t *testing.T) {
+	ctx := context.Background()
+
+	engine1, _ := newPropagationEngine()
+	first, err := engine1.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol", "did:key:bob"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	// A stale cached voucher score and a different DID order must not change
+	// the result
+	engine2, provider := newPropagationEngine()
+	provider.StoreScore(ctx, &Score{DID: "did:key:alice", Context: "test_context", Epoch: 100, Value: 95})
+	second, err := engine2.SolveEpoch(ctx, "test_context", 100, []string{"did:key:bob", "did:key:carol"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	if first.Version != second.Version {
+		t.Errorf("Expected identical snapshots, got %s and %s", first.Version, second.Version)
+	}
+	for did, score := range first.Scores {
+		if second.Scores[did].Value != score.Value {
+			t.Errorf("Score of %s differs: %f vs %f", did, score.Value, second.Scores[did].Value)
+		}
+	}
+
+	// Solving one DID on its own reaches the same fixed point
+	recomputed, err := engine1.RecomputeScore(ctx, "did:key:carol", "test_context", 100)
+	if err != nil {
+		t.Fatalf("RecomputeScore failed: %v", err)
+	}
+	if math.Abs(recomputed.Value-first.Scores["did:key:carol"].Value) > 1e-4 {
+		t.Errorf("Expected per-DID solve %f to match the epoch solve %f",
+			recomputed.Value, first.Scores["did:key:carol"].Value)
+	}
+}
+
+func TestDeterministicEngine_SnapshotIsAuthoritative(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	store := NewMemorySnapshotStore()
+	engine.SetSnapshotStore(store)
+	ctx := context.Background()
+
+	snapshot, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol"})
+	if err != nil {
+		t.Fatalf("SolveEpoch failed: %v", err)
+	}
+
+	stored, err := engine.GetSnapshot(ctx, "test_context", 100)
+	if err != nil || stored == nil || stored.Version != snapshot.Version {
+		t.Fatalf("Expected the snapshot to be persisted")
+	}
+
+	// Overwrite the per-DID cache; reads still come from the snapshot
+	provider.StoreScore(ctx, &Score{DID: "did:key:carol", Context: "test_context", Epoch: 100, Value: 1})
+	score, err := engine.ComputeScore(ctx, "did:key:carol", "test_context", 100)
+	if err != nil {
+		t.Fatalf("ComputeScore failed: %v", err)
+	}
+	if score.Value != snapshot.Scores["did:key:carol"].Value {
+		t.Errorf("Expected snapshot score %f, got %f", snapshot.Scores["did:key:carol"].Value, score.Value)
+	}
+
+	components, err := engine.GetFactors(ctx, "did:key:carol", "test_context", 100)
+	if err != nil {
+		t.Fatalf("GetFactors failed: %v", err)
+	}
+	if components.V != snapshot.Scores["did:key:carol"].Components.V {
+		t.Errorf("Expected snapshot V %f, got %f", snapshot.Scores["did:key:carol"].Components.V, components.V)
+	}
+}
+
+func TestDeterministicEngine_SolveEpochBounds(t *testing.T) {
+	engine, provider := newPropagationEngine()
+	ctx := context.Background()
+
+	engine.SetPropagation(&PropagationConfig{
+		Damping:       0.85,
+		PriorScore:    10,
+		Tolerance:     1e-9,
+		MaxIterations: 1,
+		MaxDIDs:       100,
+	})
+	snapshot, err := engine.solve(ctx, "test_context", 100, []string{"did:key:carol"}, nil)
+	if err != nil {
+		t.Fatalf("solve failed: %v", err)
+	}
+	if snapshot.Converged || snapshot.Iterations != 1 {
+		t.Errorf("Expected to stop unconverged after 1 iteration, got %d (converged %t)",
+			snapshot.Iterations, snapshot.Converged)
+	}
+	// An unconverged solve is never published
+	if _, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol"}); !errors.Is(err, ErrSnapshotNotConverged) {
+		t.Errorf("Expected SolveEpoch to refuse an unconverged snapshot, got %v", err)
+	}
+	if published, _ := engine.GetSnapshot(ctx, "test_context", 100); published != nil {
+		t.Errorf("Expected no snapshot to be published, got version %s", published.Version)
+	}
+
+	engine.SetPropagation(&PropagationConfig{
+		Damping:       0.85,
+		PriorScore:    10,
+		Tolerance:     1e-6,
+		MaxIterations: 100,
+		MaxDIDs:       2,
+	})
+	if _, err := engine.SolveEpoch(ctx, "test_context", 100, []string{"did:key:carol"}); err == nil {
+		t.Error("Expected the DID bound to be enforced")
+	}
+
+	if _, err := engine.SolveEpoch(ctx, "test_context", 100, nil); err == nil {
+		t.Error("Expected the DID bound to apply to listed DIDs")
+	}
+	engine.dataProvider = unlistedProvider{provider}
+	if _, err := engine.SolveEpoch(ctx, "test_context", 100, nil); err == nil {
+		t.Error("Expected an error without DIDs or a DID lister")
+	}
+}
@@ -0,0 +1,339 @@
+package score
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// AdjudicationOutcome classifies how an adjudicated dispute reflects on the
+// issuer of the credential involved
+type AdjudicationOutcome string
+
+const (
+	// AdjudicationConfirmed means the credential held up under dispute
+	AdjudicationConfirmed AdjudicationOutcome = "confirmed"
+	// AdjudicationFalsePositive means the issuer attested to a subject that
+	// was found to be fraudulent
+	AdjudicationFalsePositive AdjudicationOutcome = "false_positive"
+	// AdjudicationFalseNegative means the issuer revoked or refused a subject
+	// that was found to be legitimate
+	AdjudicationFalseNegative AdjudicationOutcome = "false_negative"
+)
+
+// IssuerAdjudication records the outcome of an adjudicated dispute over a
+// credential signed by an issuer
+type IssuerAdjudication struct {
+	IssuerDID    string              `json:"issuer_did"`
+	CredentialID string              `json:"credential_id"`
+	SubjectDID   string              `json:"subject_did,omitempty"`
+	Outcome      AdjudicationOutcome `json:"outcome"`
+	Epoch        int64               `json:"epoch"`
+}
+
+// IssuerReputationConfig controls how adjudications move an issuer's weight
+type IssuerReputationConfig struct {
+	InitialWeight        float64 `json:"initial_weight"`         // Weight of an issuer with no history
+	MinWeight            float64 `json:"min_weight"`             // Lower bound of w(issuer)
+	MaxWeight            float64 `json:"max_weight"`             // Upper bound of w(issuer)
+	ConfirmedReward      float64 `json:"confirmed_reward"`       // Gain per confirmed credential
+	FalsePositivePenalty float64 `json:"false_positive_penalty"` // Loss per fraudulent subject
+	FalseNegativePenalty float64 `json:"false_negative_penalty"` // Loss per wrongful revocation
+	HalfLife             int64   `json:"half_life"`              // Half-life of an adjudication in epochs
+}
+
+// DefaultIssuerReputationConfig returns a conservative configuration: new
+// issuers start well below full weight and false positives cost far more
+// than a confirmation earns
+func DefaultIssuerReputationConfig() *IssuerReputationConfig {
+	return &IssuerReputationConfig{
+		InitialWeight:        0.4,
+		MinWeight:            0.2,
+		MaxWeight:            1.0,
+		ConfirmedReward:      0.02,
+		FalsePositivePenalty: 0.15,
+		FalseNegativePenalty: 0.05,
+		HalfLife:             90,
+	}
+}
+
+// IssuerReputationProvider supplies w(issuer) for an epoch
+type IssuerReputationProvider interface {
+	// IssuerWeight returns the issuer's weight in [0.2, 1.0] for an epoch
+	IssuerWeight(ctx context.Context, issuerDID string, epoch int64) (float64, error)
+}
+
+// IssuerReputationSnapshot is the set of issuer weights in effect for an epoch
+type IssuerReputationSnapshot struct {
+	Epoch   int64              `json:"epoch"`
+	Weights map[string]float64 `json:"weights"`
+	Default float64            `json:"default"` // Weight of issuers not listed
+	Version string             `json:"version"` // Hash of the weights
+}
+
+// Weight returns an issuer's weight in the snapshot
+func (s *IssuerReputationSnapshot) Weight(issuerDID string) float64 {
+	if weight, exists := s.Weights[issuerDID]; exists {
+		return weight
+	}
+	return s.Default
+}
+
+// IssuerReputationRegistry derives issuer weights from adjudication history.
+// The weights for an epoch only depend on adjudications from earlier epochs,
+// so once an epoch has been read its snapshot is fixed and scores computed
+// against it stay reproducible.
+type IssuerReputationRegistry struct {
+	config        *IssuerReputationConfig
+	adjudications map[string][]*IssuerAdjudication // issuer -> adjudications
+	recorded      map[string]bool                  // issuer|credential
+	snapshots     map[int64]*IssuerReputationSnapshot
+	sealed        int64 // Highest epoch whose snapshot has been read
+	state         store.StateStore
+	mu            sync.RWMutex
+}
+
+// issuerReputationStateKey is where the registry keeps its adjudications in
+// a state store
+const issuerReputationStateKey = "score/issuer-reputation"
+
+// issuerReputationState is the persisted form of the registry
+type issuerReputationState struct {
+	Adjudications []*IssuerAdjudication `json:"adjudications"`
+	Sealed        int64                 `json:"sealed"`
+}
+
+// NewIssuerReputationRegistry creates a new issuer reputation registry
+func NewIssuerReputationRegistry(config *IssuerReputationConfig) *IssuerReputationRegistry {
+	if config == nil {
+		config = DefaultIssuerReputationConfig()
+	}
+
+	return &IssuerReputationRegistry{
+		config:        config,
+		adjudications: make(map[string][]*IssuerAdjudication),
+		recorded:      make(map[string]bool),
+		snapshots:     make(map[int64]*IssuerReputationSnapshot),
+		sealed:        math.MinInt64,
+	}
+}
+
+// SetStateStore loads the registry from a state store and saves every
+// adjudication to it from then on
+func (r *IssuerReputationRegistry) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), issuerReputationStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load issuer reputation: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if data != nil {
+		var state issuerReputationState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode issuer reputation: %w", err)
+		}
+		for _, adjudication := range state.Adjudications {
+			r.recorded[adjudication.IssuerDID+"|"+adjudication.CredentialID] = true
+			r.adjudications[adjudication.IssuerDID] = append(r.adjudications[adjudication.IssuerDID], adjudication)
+		}
+		for _, history := range r.adjudications {
+			sortAdjudications(history)
+		}
+		if state.Sealed > r.sealed {
+			r.sealed = state.Sealed
+		}
+	}
+
+	r.state = stateStore
+	return nil
+}
+
+// save writes the registry to its state store, if it has one. The caller
+// holds the lock.
+func (r *IssuerReputationRegistry) save(ctx context.Context) error {
+	if r.state == nil {
+		return nil
+	}
+
+	issuers := make([]string, 0, len(r.adjudications))
+	for issuer := range r.adjudications {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+	state := &issuerReputationState{Sealed: r.sealed}
+	for _, issuer := range issuers {
+		state.Adjudications = append(state.Adjudications, r.adjudications[issuer]...)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode issuer reputation: %w", err)
+	}
+	if err := r.state.PutState(ctx, issuerReputationStateKey, data); err != nil {
+		return &transientError{fmt.Errorf("failed to save issuer reputation: %w", err)}
+	}
+	return nil
+}
+
+// RecordAdjudication adds an adjudication outcome. Each credential counts
+// once, and outcomes cannot be back-dated into an epoch whose weights are
+// already in use.
+func (r *IssuerReputationRegistry) RecordAdjudication(ctx context.Context, adjudication *IssuerAdjudication) error {
+	if adjudication.IssuerDID == "" || adjudication.CredentialID == "" {
+		return fmt.Errorf("adjudication requires an issuer and a credential")
+	}
+	switch adjudication.Outcome {
+	case AdjudicationConfirmed, AdjudicationFalsePositive, AdjudicationFalseNegative:
+	default:
+		return fmt.Errorf("unknown adjudication outcome %q", adjudication.Outcome)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Weights for epoch e use adjudications before e
+	if adjudication.Epoch < r.sealed {
+		return fmt.Errorf("epoch %d is before sealed epoch %d", adjudication.Epoch, r.sealed)
+	}
+
+	key := adjudication.IssuerDID + "|" + adjudication.CredentialID
+	if r.recorded[key] {
+		return fmt.Errorf("credential %s from %s already adjudicated", adjudication.CredentialID, adjudication.IssuerDID)
+	}
+	r.recorded[key] = true
+
+	// Keep each history in a canonical order so History does not depend on
+	// the order outcomes arrived in
+	record := *adjudication
+	history := append(r.adjudications[record.IssuerDID], &record)
+	sortAdjudications(history)
+	r.adjudications[record.IssuerDID] = history
+
+	return r.save(ctx)
+}
+
+// sortAdjudications orders an issuer's history by epoch and credential
+func sortAdjudications(history []*IssuerAdjudication) {
+	sort.Slice(history, func(i, j int) bool {
+		if history[i].Epoch != history[j].Epoch {
+			return history[i].Epoch < history[j].Epoch
+		}
+		return history[i].CredentialID < history[j].CredentialID
+	})
+}
+
+// IssuerWeight implements IssuerReputationProvider
+func (r *IssuerReputationRegistry) IssuerWeight(ctx context.Context, issuerDID string, epoch int64) (float64, error) {
+	return r.Snapshot(epoch).Weight(issuerDID), nil
+}
+
+// PreviewIssuerWeight returns an issuer's weight for an epoch without
+// sealing it
+func (r *IssuerReputationRegistry) PreviewIssuerWeight(ctx context.Context, issuerDID string, epoch int64) (float64, error) {
+	return r.Preview(epoch).Weight(issuerDID), nil
+}
+
+// Snapshot returns the issuer weights in effect for an epoch and seals it
+func (r *IssuerReputationRegistry) Snapshot(epoch int64) *IssuerReputationSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if epoch > r.sealed {
+		r.sealed = epoch
+	}
+	if snapshot, exists := r.snapshots[epoch]; exists {
+		return snapshot
+	}
+
+	snapshot := r.computeSnapshot(epoch)
+	r.snapshots[epoch] = snapshot
+	return snapshot
+}
+
+// Preview returns the issuer weights an epoch would have without sealing
+// it, so later adjudications may still change them
+func (r *IssuerReputationRegistry) Preview(epoch int64) *IssuerReputationSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if snapshot, exists := r.snapshots[epoch]; exists {
+		return snapshot
+	}
+	return r.computeSnapshot(epoch)
+}
+
+// computeSnapshot computes the weights of every known issuer for an epoch
+func (r *IssuerReputationRegistry) computeSnapshot(epoch int64) *IssuerReputationSnapshot {
+	issuers := make([]string, 0, len(r.adjudications))
+	for issuer := range r.adjudications {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+
+	snapshot := &IssuerReputationSnapshot{
+		Epoch:   epoch,
+		Weights: make(map[string]float64, len(issuers)),
+		Default: r.clamp(r.config.InitialWeight),
+	}
+	hasher := sha256.New()
+	for _, issuer := range issuers {
+		weight := r.computeWeight(r.adjudications[issuer], epoch)
+		snapshot.Weights[issuer] = weight
+		hasher.Write([]byte(fmt.Sprintf("%s:%.6f;", issuer, weight)))
+	}
+	hasher.Write([]byte(fmt.Sprintf("default:%.6f", snapshot.Default)))
+	snapshot.Version = hex.EncodeToString(hasher.Sum(nil))
+
+	return snapshot
+}
+
+// History returns the adjudications recorded for an issuer
+func (r *IssuerReputationRegistry) History(issuerDID string) []*IssuerAdjudication {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	history := make([]*IssuerAdjudication, len(r.adjudications[issuerDID]))
+	copy(history, r.adjudications[issuerDID])
+	return history
+}
+
+// computeWeight applies decayed adjudication outcomes to the initial weight.
+// Decay and sums are taken in fixed point so every scorer derives the same
+// weights.
+func (r *IssuerReputationRegistry) computeWeight(adjudications []*IssuerAdjudication, epoch int64) float64 {
+	var confirmed, falsePositives, falseNegatives Fixed
+	for _, adjudication := range adjudications {
+		if adjudication.Epoch >= epoch {
+			continue
+		}
+		decay := FixedHalfLifeDecay(FixedOne, epoch-adjudication.Epoch, r.config.HalfLife)
+		switch adjudication.Outcome {
+		case AdjudicationConfirmed:
+			confirmed += decay
+		case AdjudicationFalsePositive:
+			falsePositives += decay
+		case AdjudicationFalseNegative:
+			falseNegatives += decay
+		}
+	}
+
+	weight := FixedFromFloat(r.config.InitialWeight) +
+		FixedFromFloat(r.config.ConfirmedReward).Mul(confirmed) -
+		FixedFromFloat(r.config.FalsePositivePenalty).Mul(falsePositives) -
+		FixedFromFloat(r.config.FalseNegativePenalty).Mul(falseNegatives)
+
+	return r.clamp(weight.Float64())
+}
+
+func (r *IssuerReputationRegistry) clamp(weight float64) float64 {
+	return math.Max(r.config.MinWeight, math.Min(r.config.MaxWeight, weight))
+}
@@ -0,0 +1,224 @@
+package score
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+func TestIssuerReputationRegistry_Weights(t *testing.T) {
+	registry := NewIssuerReputationRegistry(&IssuerReputationConfig{
+		InitialWeight:        0.4,
+		MinWeight:            0.2,
+		MaxWeight:            1.0,
+		ConfirmedReward:      0.1,
+		FalsePositivePenalty: 0.15,
+		FalseNegativePenalty: 0.05,
+	})
+	ctx := context.Background()
+
+	// Unknown issuers start at the conservative initial weight
+	if weight := registry.Preview(10).Weight("did:key:new"); weight != 0.4 {
+		t.Errorf("Expected initial weight 0.4, got %f", weight)
+	}
+
+	for i, credential := range []string{"c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8"} {
+		if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+			IssuerDID:    "did:key:bank",
+			CredentialID: credential,
+			Outcome:      AdjudicationConfirmed,
+			Epoch:        int64(i),
+		}); err != nil {
+			t.Fatalf("RecordAdjudication failed: %v", err)
+		}
+	}
+	for _, credential := range []string{"f1", "f2", "f3"} {
+		if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+			IssuerDID:    "did:key:mill",
+			CredentialID: credential,
+			Outcome:      AdjudicationFalsePositive,
+			Epoch:        5,
+		}); err != nil {
+			t.Fatalf("RecordAdjudication failed: %v", err)
+		}
+	}
+
+	snapshot := registry.Preview(20)
+	if weight := snapshot.Weight("did:key:bank"); weight != 1.0 {
+		t.Errorf("Expected confirmations to be capped at 1.0, got %f", weight)
+	}
+	if weight := snapshot.Weight("did:key:mill"); weight != 0.2 {
+		t.Errorf("Expected false positives to be floored at 0.2, got %f", weight)
+	}
+
+	// Adjudications only count from the following epoch
+	if weight := registry.Preview(3).Weight("did:key:bank"); math.Abs(weight-0.7) > 1e-9 {
+		t.Errorf("Expected three confirmations before epoch 3, got %f", weight)
+	}
+	if weight := registry.Preview(5).Weight("did:key:mill"); weight != 0.4 {
+		t.Errorf("Expected no penalty before epoch 6, got %f", weight)
+	}
+
+	if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+		IssuerDID:    "did:key:bank",
+		CredentialID: "c1",
+		Outcome:      AdjudicationFalseNegative,
+		Epoch:        9,
+	}); err == nil {
+		t.Error("Expected a credential to be adjudicated only once")
+	}
+	if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+		IssuerDID:    "did:key:bank",
+		CredentialID: "c9",
+		Outcome:      "unknown",
+		Epoch:        9,
+	}); err == nil {
+		t.Error("Expected an unknown outcome to be rejected")
+	}
+}
+
+func TestIssuerReputationRegistry_Sealing(t *testing.T) {
+	registry := NewIssuerReputationRegistry(nil)
+	ctx := context.Background()
+	record := func(credential string, outcome AdjudicationOutcome, epoch int64) error {
+		return registry.RecordAdjudication(ctx, &IssuerAdjudication{
+			IssuerDID:    "did:key:bank",
+			CredentialID: credential,
+			Outcome:      outcome,
+			Epoch:        epoch,
+		})
+	}
+
+	if err := record("a", AdjudicationFalsePositive, 3); err != nil {
+		t.Fatalf("RecordAdjudication failed: %v", err)
+	}
+
+	sealed := registry.Snapshot(5)
+
+	// History before the sealed epoch cannot change
+	if err := record("b", AdjudicationFalsePositive, 4); err == nil {
+		t.Error("Expected back-dated adjudication to be rejected")
+	}
+
+	// Outcomes in the sealed epoch only affect later epochs
+	if err := record("c", AdjudicationFalsePositive, 5); err != nil {
+		t.Fatalf("RecordAdjudication failed: %v", err)
+	}
+	if again := registry.Snapshot(5); again.Version != sealed.Version {
+		t.Error("Expected sealed snapshot to be unchanged")
+	}
+	if next := registry.Snapshot(6); next.Weight("did:key:bank") >= sealed.Weight("did:key:bank") {
+		t.Errorf("Expected weight to drop after another false positive: %f -> %f",
+			sealed.Weight("did:key:bank"), next.Weight("did:key:bank"))
+	}
+}
+
+func TestIssuerReputationRegistry_Persistence(t *testing.T) {
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+	ctx := context.Background()
+
+	registry := NewIssuerReputationRegistry(nil)
+	if err := registry.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	for _, credential := range []string{"b", "a"} {
+		if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+			IssuerDID:    "did:key:bank",
+			CredentialID: credential,
+			Outcome:      AdjudicationFalsePositive,
+			Epoch:        3,
+		}); err != nil {
+			t.Fatalf("RecordAdjudication failed: %v", err)
+		}
+	}
+	expected := registry.Preview(4)
+
+	restored := NewIssuerReputationRegistry(nil)
+	if err := restored.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	if snapshot := restored.Preview(4); snapshot.Version != expected.Version {
+		t.Errorf("Expected the restored weights to match, got %+v", snapshot.Weights)
+	}
+	if history := restored.History("did:key:bank"); len(history) != 2 || history[0].CredentialID != "a" {
+		t.Errorf("Expected the history in canonical order, got %+v", history)
+	}
+	if err := restored.RecordAdjudication(ctx, &IssuerAdjudication{
+		IssuerDID:    "did:key:bank",
+		CredentialID: "a",
+		Outcome:      AdjudicationConfirmed,
+		Epoch:        4,
+	}); err == nil {
+		t.Error("Expected a restored credential to stay adjudicated")
+	}
+}
+
+func TestDeterministicEngine_IssuerReputation(t *testing.T) {
+	config := DefaultScoreConfig()
+	dataProvider := NewTestDataProvider()
+	engine := NewDeterministicEngine(config, dataProvider, nil, nil, NewExponentialDecayFunction(), nil, nil)
+
+	ctx := context.Background()
+	baseline, err := engine.GetFactors(ctx, "did:key:test123", "test_context", 100)
+	if err != nil {
+		t.Fatalf("GetFactors failed: %v", err)
+	}
+	if baseline.IssuerWeights != nil {
+		t.Errorf("Expected no issuer weights without a registry, got %v", baseline.IssuerWeights)
+	}
+
+	registry := NewIssuerReputationRegistry(nil)
+	if err := registry.RecordAdjudication(ctx, &IssuerAdjudication{
+		IssuerDID:    "did:key:kyc",
+		CredentialID: "kyc-1",
+		Outcome:      AdjudicationFalsePositive,
+		Epoch:        50,
+	}); err != nil {
+		t.Fatalf("RecordAdjudication failed: %v", err)
+	}
+	engine.SetIssuerReputation(registry)
+
+	weighted, err := engine.GetFactors(ctx, "did:key:test123", "test_context", 100)
+	if err != nil {
+		t.Fatalf("GetFactors failed: %v", err)
+	}
+
+	snapshot := registry.Snapshot(100)
+	kycWeight := snapshot.Weight("did:key:kyc")
+	if got := weighted.IssuerWeights["did:key:kyc"]; got != kycWeight {
+		t.Errorf("Expected KYC issuer weight %f in factors, got %f", kycWeight, got)
+	}
+	if got := weighted.IssuerWeights["did:key:employer"]; got != snapshot.Default {
+		t.Errorf("Expected attestation issuer at the default weight %f, got %f", snapshot.Default, got)
+	}
+	// Factors are rounded to six places, so allow for two roundings
+	if math.Abs(weighted.K-baseline.K*kycWeight) > 2e-6 {
+		t.Errorf("Expected K scaled by w(issuer): %f * %f != %f", baseline.K, kycWeight, weighted.K)
+	}
+	// The registry replaces the reputation carried on the attestation
+	if math.Abs(weighted.A-baseline.A/0.9*snapshot.Default) > 2e-6 {
+		t.Errorf("Expected A reweighted by w(issuer), got %f from %f", weighted.A, baseline.A)
+	}
+
+	// Issuer weights are part of the proof's input hash
+	withWeights, err := engine.computeInputHash(ctx, "did:key:test123", "test_context", 100)
+	if err != nil {
+		t.Fatalf("computeInputHash failed: %v", err)
+	}
+	engine.SetIssuerReputation(nil)
+	withoutWeights, err := engine.computeInputHash(ctx, "did:key:test123", "test_context", 100)
+	if err != nil {
+		t.Fatalf("computeInputHash failed: %v", err)
+	}
+	if withWeights == withoutWeights {
+		t.Error("Expected issuer weights to change the input hash")
+	}
+}
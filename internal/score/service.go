@@ -3,11 +3,13 @@ package score
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/ParichayaHQ/credence/internal/did"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
@@ -19,6 +21,12 @@ type HTTPService struct {
 	graphAnalyzer GraphAnalyzer
 	enforcer      *BudgetEnforcer
 	config        *ScoreConfig
+	migrations    *MigrationRegistry
+	reputation    *IssuerReputationRegistry
+	bonds         *BondLedger
+	solver        EpochSolver
+	recomputer    *CheckpointRecomputer
+	history       ScoreHistory
 	server        *http.Server
 }
 
@@ -73,6 +81,8 @@ func (s *HTTPService) setupRoutes() *mux.Router {
 	api.HandleFunc("/score/{did}/recompute", s.handleRecomputeScore).Methods("POST")
 	api.HandleFunc("/scores/batch", s.handleBatchScore).Methods("POST")
 	api.HandleFunc("/score/{did}/factors", s.handleGetFactors).Methods("GET")
+	api.HandleFunc("/score/{did}/explanation", s.handleGetExplanation).Methods("GET")
+	api.HandleFunc("/score/{did}/history", s.handleGetScoreHistory).Methods("GET")
 	api.HandleFunc("/score/{did}/proof", s.handleGetProof).Methods("GET")
 	api.HandleFunc("/proof/verify", s.handleVerifyProof).Methods("POST")
 	
@@ -86,6 +96,23 @@ func (s *HTTPService) setupRoutes() *mux.Router {
 	api.HandleFunc("/analysis/collusion", s.handleDetectCollusion).Methods("GET")
 	api.HandleFunc("/analysis/diversity/{did}", s.handleGetDiversity).Methods("GET")
 	api.HandleFunc("/analysis/dense-subgraphs", s.handleGetDenseSubgraphs).Methods("GET")
+	api.HandleFunc("/analysis/communities", s.handleGetCommunities).Methods("GET")
+	api.HandleFunc("/analysis/penalties", s.handleGetVouchPenalties).Methods("GET")
+	
+	// DID migration endpoints
+	api.HandleFunc("/migrations", s.handleRegisterMigration).Methods("POST")
+	api.HandleFunc("/migrations/{did}", s.handleGetMigration).Methods("GET")
+	
+	// Issuer reputation endpoints
+	api.HandleFunc("/issuers/{did}/reputation", s.handleGetIssuerReputation).Methods("GET")
+	
+	// Vouch bond endpoints
+	api.HandleFunc("/bonds/{did}", s.handleGetBonds).Methods("GET")
+	
+	// Epoch solve endpoints
+	api.HandleFunc("/epochs/{epoch}/solve", s.handleSolveEpoch).Methods("POST")
+	api.HandleFunc("/epochs/{epoch}/snapshot", s.handleGetSnapshot).Methods("GET")
+	api.HandleFunc("/freshness", s.handleGetFreshness).Methods("GET")
 	
 	// Configuration endpoints
 	api.HandleFunc("/config", s.handleGetConfig).Methods("GET")
@@ -97,6 +124,36 @@ func (s *HTTPService) setupRoutes() *mux.Router {
 	return r
 }
 
+// SetMigrationRegistry enables the DID migration endpoints
+func (s *HTTPService) SetMigrationRegistry(registry *MigrationRegistry) {
+	s.migrations = registry
+}
+
+// SetIssuerReputation enables the issuer reputation endpoints
+func (s *HTTPService) SetIssuerReputation(registry *IssuerReputationRegistry) {
+	s.reputation = registry
+}
+
+// SetBondLedger enables the vouch bond endpoints
+func (s *HTTPService) SetBondLedger(ledger *BondLedger) {
+	s.bonds = ledger
+}
+
+// SetEpochSolver enables the epoch solve endpoints
+func (s *HTTPService) SetEpochSolver(solver EpochSolver) {
+	s.solver = solver
+}
+
+// SetCheckpointRecomputer enables the score freshness endpoint
+func (s *HTTPService) SetCheckpointRecomputer(recomputer *CheckpointRecomputer) {
+	s.recomputer = recomputer
+}
+
+// SetScoreHistory enables the score history endpoint
+func (s *HTTPService) SetScoreHistory(history ScoreHistory) {
+	s.history = history
+}
+
 // Start starts the HTTP service
 func (s *HTTPService) Start() error {
 	return s.server.ListenAndServe()
@@ -117,12 +174,10 @@ func (s *HTTPService) handleGetScore(w http.ResponseWriter, r *http.Request) {
 		context = "default"
 	}
 	
-	epochStr := r.URL.Query().Get("epoch")
-	epoch := time.Now().Unix() / 86400 // Default to current epoch (day)
-	if epochStr != "" {
-		if e, err := strconv.ParseInt(epochStr, 10, 64); err == nil {
-			epoch = e
-		}
+	epoch, err := requestEpoch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	
 	includeProof := r.URL.Query().Get("include_proof") == "true"
@@ -243,12 +298,10 @@ func (s *HTTPService) handleGetFactors(w http.ResponseWriter, r *http.Request) {
 		context = "default"
 	}
 	
-	epochStr := r.URL.Query().Get("epoch")
-	epoch := time.Now().Unix() / 86400
-	if epochStr != "" {
-		if e, err := strconv.ParseInt(epochStr, 10, 64); err == nil {
-			epoch = e
-		}
+	epoch, err := requestEpoch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 	
 	factors, err := s.engine.GetFactors(r.Context(), did, context, epoch)
@@ -261,6 +314,117 @@ func (s *HTTPService) handleGetFactors(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(factors)
 }
 
+// requestEpoch reads the epoch query parameter, defaulting to the current
+// epoch. Reading a score fixes the evidence of its epoch, so epochs that
+// have not started yet are rejected.
+func requestEpoch(r *http.Request) (int64, error) {
+	current := time.Now().Unix() / 86400
+	epochStr := r.URL.Query().Get("epoch")
+	if epochStr == "" {
+		return current, nil
+	}
+	epoch, err := strconv.ParseInt(epochStr, 10, 64)
+	if err != nil || epoch < 0 {
+		return 0, fmt.Errorf("Invalid epoch")
+	}
+	if epoch > current {
+		return 0, fmt.Errorf("Epoch %d has not started", epoch)
+	}
+	return epoch, nil
+}
+
+// handleGetExplanation handles GET /api/v1/score/{did}/explanation
+func (s *HTTPService) handleGetExplanation(w http.ResponseWriter, r *http.Request) {
+	explainer, ok := s.engine.(ScoreExplainer)
+	if !ok {
+		http.Error(w, "Score explanations not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	did := mux.Vars(r)["did"]
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	epoch, err := requestEpoch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	explanation, err := explainer.ExplainScore(r.Context(), did, context, epoch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to explain score: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
+
+// handleGetScoreHistory handles GET /api/v1/score/{did}/history
+func (s *HTTPService) handleGetScoreHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		http.Error(w, "Score history not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	did := mux.Vars(r)["did"]
+	query := r.URL.Query()
+	
+	context := query.Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	to := time.Now().Unix() / 86400
+	if toStr := query.Get("to"); toStr != "" {
+		e, err := strconv.ParseInt(toStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid to epoch", http.StatusBadRequest)
+			return
+		}
+		to = e
+	}
+	
+	from := to - defaultHistoryEpochs
+	if fromStr := query.Get("from"); fromStr != "" {
+		e, err := strconv.ParseInt(fromStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid from epoch", http.StatusBadRequest)
+			return
+		}
+		from = e
+	}
+	
+	var step int64 = 1
+	if stepStr := query.Get("step"); stepStr != "" {
+		e, err := strconv.ParseInt(stepStr, 10, 64)
+		if err != nil || e <= 0 {
+			http.Error(w, "Invalid step", http.StatusBadRequest)
+			return
+		}
+		step = e
+	}
+	
+	scores, err := s.history.GetScoreHistory(r.Context(), did, context, from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get score history: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	series, err := BuildScoreSeries(did, context, scores, from, to, step, DownsampleMode(query.Get("mode")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
 // handleGetProof handles GET /api/v1/score/{did}/proof
 func (s *HTTPService) handleGetProof(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -535,6 +699,12 @@ func (s *HTTPService) handleGetDiversity(w http.ResponseWriter, r *http.Request)
 		"epoch":     epoch,
 		"diversity": diversity,
 	}
+	if detector, ok := s.graphAnalyzer.(CommunityDetector); ok {
+		if partition, err := detector.PreviewCommunities(r.Context(), context, epoch); err == nil {
+			result["community"] = partition.Community(did)
+			result["modularity"] = partition.Modularity
+		}
+	}
 	
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -578,6 +748,69 @@ func (s *HTTPService) handleGetDenseSubgraphs(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(subgraphs)
 }
 
+// handleGetCommunities handles GET /api/v1/analysis/communities
+func (s *HTTPService) handleGetCommunities(w http.ResponseWriter, r *http.Request) {
+	detector, ok := s.graphAnalyzer.(CommunityDetector)
+	if !ok {
+		http.Error(w, "Community detection not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	epochStr := r.URL.Query().Get("epoch")
+	epoch := time.Now().Unix() / 86400
+	if epochStr != "" {
+		if e, err := strconv.ParseInt(epochStr, 10, 64); err == nil {
+			epoch = e
+		}
+	}
+	
+	// Reads never change the partition scoring uses
+	partition, err := detector.PreviewCommunities(r.Context(), context, epoch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to detect communities: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(partition)
+}
+
+// handleGetVouchPenalties handles GET /api/v1/analysis/penalties
+func (s *HTTPService) handleGetVouchPenalties(w http.ResponseWriter, r *http.Request) {
+	penalizer, ok := s.graphAnalyzer.(VouchPenalizer)
+	if !ok {
+		http.Error(w, "Collusion penalties not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	epochStr := r.URL.Query().Get("epoch")
+	epoch := time.Now().Unix() / 86400
+	if epochStr != "" {
+		if e, err := strconv.ParseInt(epochStr, 10, 64); err == nil {
+			epoch = e
+		}
+	}
+	
+	penalties, err := penalizer.PreviewVouchPenalties(r.Context(), context, epoch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute vouch penalties: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(penalties)
+}
+
 // handleGetConfig handles GET /api/v1/config
 func (s *HTTPService) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -612,6 +845,196 @@ func (s *HTTPService) handleUpdateConfig(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(result)
 }
 
+// handleRegisterMigration handles POST /api/v1/migrations
+func (s *HTTPService) handleRegisterMigration(w http.ResponseWriter, r *http.Request) {
+	if s.migrations == nil {
+		http.Error(w, "Migration registry not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	var statement did.KeyMigrationStatement
+	if err := json.NewDecoder(r.Body).Decode(&statement); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	
+	if err := s.migrations.Register(r.Context(), &statement); err != nil {
+		status := http.StatusBadRequest
+		var transient *transientError
+		if errors.As(err, &transient) {
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, fmt.Sprintf("Failed to register migration: %v", err), status)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&statement)
+}
+
+// handleGetMigration handles GET /api/v1/migrations/{did}
+func (s *HTTPService) handleGetMigration(w http.ResponseWriter, r *http.Request) {
+	if s.migrations == nil {
+		http.Error(w, "Migration registry not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	didStr := mux.Vars(r)["did"]
+	
+	response := map[string]interface{}{
+		"did":          didStr,
+		"successor":    s.migrations.Successor(didStr),
+		"predecessors": s.migrations.Lineage(didStr),
+	}
+	if statement, exists := s.migrations.GetStatement(didStr); exists {
+		response["statement"] = statement
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetIssuerReputation handles GET /api/v1/issuers/{did}/reputation
+func (s *HTTPService) handleGetIssuerReputation(w http.ResponseWriter, r *http.Request) {
+	if s.reputation == nil {
+		http.Error(w, "Issuer reputation not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	issuerDID := mux.Vars(r)["did"]
+	
+	epochStr := r.URL.Query().Get("epoch")
+	epoch := time.Now().Unix() / 86400
+	if epochStr != "" {
+		if e, err := strconv.ParseInt(epochStr, 10, 64); err == nil {
+			epoch = e
+		}
+	}
+	
+	// Reading must not seal the epoch against further adjudications
+	snapshot := s.reputation.Preview(epoch)
+	response := map[string]interface{}{
+		"issuer":        issuerDID,
+		"epoch":         epoch,
+		"weight":        snapshot.Weight(issuerDID),
+		"version":       snapshot.Version,
+		"adjudications": s.reputation.History(issuerDID),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleGetBonds handles GET /api/v1/bonds/{did}
+func (s *HTTPService) handleGetBonds(w http.ResponseWriter, r *http.Request) {
+	if s.bonds == nil {
+		http.Error(w, "Vouch bonds not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	// Listing bonds does not seal an epoch against further verdicts
+	voucherDID := mux.Vars(r)["did"]
+	response := map[string]interface{}{
+		"voucher": voucherDID,
+		"context": context,
+		"bonds":   s.bonds.Bonds(voucherDID, context),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleSolveEpoch handles POST /api/v1/epochs/{epoch}/solve
+func (s *HTTPService) handleSolveEpoch(w http.ResponseWriter, r *http.Request) {
+	if s.solver == nil {
+		http.Error(w, "Epoch solver not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	epoch, err := strconv.ParseInt(mux.Vars(r)["epoch"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid epoch", http.StatusBadRequest)
+		return
+	}
+	
+	var request SolveEpochRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if request.Context == "" {
+		request.Context = "default"
+	}
+	
+	snapshot, err := s.solver.SolveEpoch(r.Context(), request.Context, epoch, request.DIDs)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to solve epoch: %v", err), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// handleGetSnapshot handles GET /api/v1/epochs/{epoch}/snapshot
+func (s *HTTPService) handleGetSnapshot(w http.ResponseWriter, r *http.Request) {
+	if s.solver == nil {
+		http.Error(w, "Epoch solver not available", http.StatusServiceUnavailable)
+		return
+	}
+	
+	epoch, err := strconv.ParseInt(mux.Vars(r)["epoch"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid epoch", http.StatusBadRequest)
+		return
+	}
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	snapshot, err := s.solver.GetSnapshot(r.Context(), context, epoch)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get snapshot: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if snapshot == nil {
+		http.Error(w, "Epoch has not been solved", http.StatusNotFound)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// handleGetFreshness handles GET /api/v1/freshness
+func (s *HTTPService) handleGetFreshness(w http.ResponseWriter, r *http.Request) {
+	if s.recomputer == nil {
+		http.Error(w, "Checkpoint recomputation not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	
+	context := r.URL.Query().Get("context")
+	if context == "" {
+		context = "default"
+	}
+	
+	response := &FreshnessResponse{
+		Freshness: s.recomputer.Freshness(context),
+		Stats:     s.recomputer.Stats(),
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // handleHealth handles GET /api/v1/health
 func (s *HTTPService) handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
@@ -18,6 +18,10 @@ type Score struct {
 	// Metadata
 	ComputedBy string `json:"computed_by"`
 	Version    string `json:"version"`
+	
+	// Log checkpoint the score was computed from, when kept fresh by a
+	// CheckpointRecomputer
+	Checkpoint *CheckpointMarker `json:"checkpoint,omitempty"`
 }
 
 // ScoreComponents breaks down the score into its constituent factors
@@ -27,6 +31,15 @@ type ScoreComponents struct {
 	V float64 `json:"v"` // Vouches factor (with sqrt aggregation)
 	R float64 `json:"r"` // Reports factor (negative)
 	T float64 `json:"t"` // Time factor
+
+	// IssuerWeights records w(issuer) for each KYC and attestation issuer
+	// that contributed to K and A
+	IssuerWeights map[string]float64 `json:"issuer_weights,omitempty"`
+
+	// BondSlash is the part of V forfeited to slashed vouch bonds, and
+	// SlashedBonds the vouchees whose abuse slashed them
+	BondSlash    float64  `json:"bond_slash,omitempty"`
+	SlashedBonds []string `json:"slashed_bonds,omitempty"`
 }
 
 // ScoreFactors defines the weights for each component in the scoring algorithm
@@ -79,6 +92,7 @@ type ReportData struct {
 	Severity    float64   `json:"severity"` // 0.0 to 1.0
 	Adjudicated bool      `json:"adjudicated"`
 	Upheld      bool      `json:"upheld"`
+	CaseID      string    `json:"case_id,omitempty"` // Adjudication case, for linking appeals
 	Timestamp   time.Time `json:"timestamp"`
 	Epoch       int64     `json:"epoch"`
 }
@@ -0,0 +1,406 @@
+package statuslist
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// StatusListDelta records the bits that changed in a status list between two
+// published versions. Deltas chain by version and by the digest of the bitmap
+// they apply to, so a verifier holding any earlier bitmap can catch up
+// without downloading the whole list again.
+type StatusListDelta struct {
+	ListID      string `json:"listId"`
+	Issuer      string `json:"issuer"`
+	Purpose     string `json:"statusPurpose"`
+	Epoch       string `json:"epoch"`       // Publication epoch (StatusEpochFormat)
+	FromVersion uint64 `json:"fromVersion"` // Version the delta applies to
+	Version     uint64 `json:"version"`     // Version after applying the delta
+	BaseDigest  string `json:"baseDigest"`  // BitmapDigest at FromVersion
+	Digest      string `json:"digest"`      // BitmapDigest at Version
+	Length      int    `json:"length"`      // Bit length at Version
+	Set         []int  `json:"set,omitempty"`
+	Cleared     []int  `json:"cleared,omitempty"`
+	Signature   string `json:"signature,omitempty"` // Issuer's Ed25519 signature
+}
+
+// DeltaKeyResolver returns the Ed25519 key an issuer signs deltas and
+// revocation announcements with
+type DeltaKeyResolver func(issuer string) (ed25519.PublicKey, error)
+
+// DeltaSigner signs with the key controlling a DID, as wallet.DefaultWallet
+// does. Publishers use it to sign each delta as the issuer of its list.
+type DeltaSigner interface {
+	SignAsDID(did string, data []byte) ([]byte, error)
+}
+
+// DIDKeyResolver resolves delta signing keys for did:key issuers
+func DIDKeyResolver(issuer string) (ed25519.PublicKey, error) {
+	publicKey, keyType, err := did.PublicKeyFromDIDKey(issuer)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok || keyType != did.KeyTypeEd25519 {
+		return nil, NewStatusListError(ErrorPermissionDenied, "issuer key is not Ed25519")
+	}
+	return edKey, nil
+}
+
+// BitmapDigest returns the hex SHA-256 of a bitmap's bytes. The compressed
+// encoding does not preserve the bit length, so only the bytes are hashed.
+func BitmapDigest(bitString *BitString) string {
+	sum := sha256.Sum256(bitString.bits)
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffBitStrings returns the indexes set and cleared going from base to next
+func DiffBitStrings(base, next *BitString) (set, cleared []int) {
+	length := base.Length()
+	if next.Length() > length {
+		length = next.Length()
+	}
+
+	for index := 0; index < length; index++ {
+		before := index < base.Length() && bitAt(base, index)
+		after := index < next.Length() && bitAt(next, index)
+		switch {
+		case after && !before:
+			set = append(set, index)
+		case before && !after:
+			cleared = append(cleared, index)
+		}
+	}
+	return set, cleared
+}
+
+func bitAt(bitString *BitString, index int) bool {
+	return bitString.bits[index/8]&(1<<uint(index%8)) != 0
+}
+
+// NewStatusListDelta builds the unsigned delta taking a list from base to
+// next
+func NewStatusListDelta(list *StatusList2021, epoch string, fromVersion uint64, base, next *BitString) *StatusListDelta {
+	set, cleared := DiffBitStrings(base, next)
+	return &StatusListDelta{
+		ListID:      list.ID,
+		Issuer:      list.Issuer,
+		Purpose:     list.CredentialSubject.StatusPurpose,
+		Epoch:       epoch,
+		FromVersion: fromVersion,
+		Version:     fromVersion + 1,
+		BaseDigest:  BitmapDigest(base),
+		Digest:      BitmapDigest(next),
+		Length:      next.Length(),
+		Set:         set,
+		Cleared:     cleared,
+	}
+}
+
+// signingBytes is the JSON encoding of the delta without its signature
+func (d *StatusListDelta) signingBytes() ([]byte, error) {
+	unsigned := *d
+	unsigned.Signature = ""
+	return json.Marshal(&unsigned)
+}
+
+// Sign signs the delta with the issuer's key
+func (d *StatusListDelta) Sign(signer crypto.Signer) error {
+	data, err := d.signingBytes()
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode delta", err.Error())
+	}
+	signature, err := signer.Sign(data)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to sign delta", err.Error())
+	}
+	d.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// SignAsIssuer signs the delta with the key of the DID that issued its list
+func (d *StatusListDelta) SignAsIssuer(signer DeltaSigner) error {
+	data, err := d.signingBytes()
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode delta", err.Error())
+	}
+	signature, err := signer.SignAsDID(d.Issuer, data)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to sign delta", err.Error())
+	}
+	d.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// Verify checks the delta's signature against the issuer's key
+func (d *StatusListDelta) Verify(publicKey ed25519.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(d.Signature)
+	if err != nil || d.Signature == "" {
+		return NewStatusListError(ErrorPermissionDenied, "delta is not signed")
+	}
+	data, err := d.signingBytes()
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode delta", err.Error())
+	}
+	if !crypto.NewEd25519Verifier().Verify(publicKey, data, signature) {
+		return NewStatusListError(ErrorPermissionDenied, "invalid delta signature")
+	}
+	return nil
+}
+
+// VerifyIssuer checks that the delta's list belongs to the issuer it names
+// and that the issuer signed it
+func (d *StatusListDelta) VerifyIssuer(keys DeltaKeyResolver) error {
+	if statusListIssuer(d.ListID) != d.Issuer {
+		return NewStatusListError(ErrorPermissionDenied,
+			fmt.Sprintf("status list %s does not belong to %s", d.ListID, d.Issuer))
+	}
+	publicKey, err := keys(d.Issuer)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to resolve issuer key", err.Error())
+	}
+	return d.Verify(publicKey)
+}
+
+// Apply returns base with the delta applied. base must be the bitmap the
+// delta was computed from, and the result must match the delta's digest.
+func (d *StatusListDelta) Apply(base *BitString) (*BitString, error) {
+	if BitmapDigest(base) != d.BaseDigest {
+		return nil, NewStatusListError(ErrorInvalidStatusList,
+			fmt.Sprintf("delta %d->%d does not apply to this bitmap", d.FromVersion, d.Version))
+	}
+
+	next := base.Clone()
+	if err := next.Expand(d.Length); err != nil {
+		return nil, err
+	}
+	for _, index := range d.Set {
+		if err := next.Set(index, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, index := range d.Cleared {
+		if err := next.Set(index, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if BitmapDigest(next) != d.Digest {
+		return nil, NewStatusListError(ErrorInvalidStatusList,
+			fmt.Sprintf("delta %d->%d produced an unexpected bitmap", d.FromVersion, d.Version))
+	}
+	return next, nil
+}
+
+// DeltaSource serves the deltas published for a status list
+type DeltaSource interface {
+	// FetchDeltas returns the deltas of a list from a version onwards,
+	// ordered by version
+	FetchDeltas(listID string, fromVersion uint64) ([]*StatusListDelta, error)
+}
+
+// DeltaSink receives the signed deltas a publisher produces, as DeltaLog and
+// HTTPDeltaClient do
+type DeltaSink interface {
+	PublishDelta(ctx context.Context, delta *StatusListDelta) error
+}
+
+// DeltaLog keeps the published deltas of each status list
+type DeltaLog struct {
+	mutex  sync.RWMutex
+	deltas map[string][]*StatusListDelta // listID -> deltas by version
+	state  store.StateStore
+}
+
+// deltaLogStateKey is where a delta log keeps its deltas in a state store
+const deltaLogStateKey = "statuslist/deltas"
+
+// NewDeltaLog creates an empty delta log
+func NewDeltaLog() *DeltaLog {
+	return &DeltaLog{
+		deltas: make(map[string][]*StatusListDelta),
+	}
+}
+
+// SetStateStore loads the deltas kept in a state store and saves every
+// appended delta to it from then on
+func (l *DeltaLog) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), deltaLogStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load status list deltas: %w", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if data != nil {
+		deltas := make(map[string][]*StatusListDelta)
+		if err := json.Unmarshal(data, &deltas); err != nil {
+			return fmt.Errorf("failed to decode status list deltas: %w", err)
+		}
+		l.deltas = deltas
+	}
+
+	l.state = stateStore
+	return nil
+}
+
+// Append adds the next delta of a list. Deltas must continue the chain; a
+// delta that is already in the log is accepted again, so publishers can
+// retry.
+func (l *DeltaLog) Append(delta *StatusListDelta) error {
+	return l.PublishDelta(context.Background(), delta)
+}
+
+// PublishDelta implements DeltaSink
+func (l *DeltaLog) PublishDelta(ctx context.Context, delta *StatusListDelta) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if delta.Version != delta.FromVersion+1 {
+		return NewStatusListError(ErrorInvalidStatusList, "delta must advance the version by one")
+	}
+	chain := l.deltas[delta.ListID]
+	if len(chain) > 0 {
+		last := chain[len(chain)-1]
+		// Versions in a chain are consecutive
+		if delta.FromVersion >= chain[0].FromVersion && delta.Version <= last.Version {
+			existing := chain[delta.FromVersion-chain[0].FromVersion]
+			if existing.BaseDigest == delta.BaseDigest && existing.Digest == delta.Digest {
+				return nil
+			}
+		}
+		if delta.FromVersion != last.Version || delta.BaseDigest != last.Digest {
+			return NewStatusListError(ErrorInvalidStatusList,
+				fmt.Sprintf("delta %d->%d does not continue version %d", delta.FromVersion, delta.Version, last.Version))
+		}
+	}
+
+	l.deltas[delta.ListID] = append(chain, delta)
+	if err := l.save(ctx); err != nil {
+		l.deltas[delta.ListID] = chain
+		return err
+	}
+	return nil
+}
+
+// save writes the deltas to the state store. The caller holds the lock.
+func (l *DeltaLog) save(ctx context.Context) error {
+	if l.state == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(l.deltas)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode status list deltas", err.Error())
+	}
+	if err := l.state.PutState(ctx, deltaLogStateKey, data); err != nil {
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to save status list deltas", err.Error())
+	}
+	return nil
+}
+
+// FetchDeltas implements DeltaSource
+func (l *DeltaLog) FetchDeltas(listID string, fromVersion uint64) ([]*StatusListDelta, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	chain := l.deltas[listID]
+	start := sort.Search(len(chain), func(i int) bool {
+		return chain[i].FromVersion >= fromVersion
+	})
+	return append([]*StatusListDelta{}, chain[start:]...), nil
+}
+
+// ByEpoch returns the deltas of a list published in an epoch
+func (l *DeltaLog) ByEpoch(listID, epoch string) []*StatusListDelta {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var deltas []*StatusListDelta
+	for _, delta := range l.deltas[listID] {
+		if delta.Epoch == epoch {
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
+
+// HTTPDeltaClient publishes and fetches deltas through a full node's
+// /v1/status/deltas endpoint
+type HTTPDeltaClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPDeltaClient creates a delta client for the full node at baseURL
+func NewHTTPDeltaClient(baseURL string, timeout time.Duration) *HTTPDeltaClient {
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &HTTPDeltaClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// PublishDelta implements DeltaSink
+func (c *HTTPDeltaClient) PublishDelta(ctx context.Context, delta *StatusListDelta) error {
+	body, err := json.Marshal(delta)
+	if err != nil {
+		return fmt.Errorf("failed to encode delta: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/status/deltas", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("HTTP error: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// FetchDeltas implements DeltaSource. The deltas are checked by whoever
+// applies them.
+func (c *HTTPDeltaClient) FetchDeltas(listID string, fromVersion uint64) ([]*StatusListDelta, error) {
+	target := c.baseURL + "/v1/status/deltas?list=" + url.QueryEscape(listID) +
+		"&from=" + strconv.FormatUint(fromVersion, 10)
+	body, err := httpGet(context.Background(), c.client, target)
+	if err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to fetch deltas", err.Error())
+	}
+
+	var deltas []*StatusListDelta
+	if err := json.Unmarshal(body, &deltas); err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorEncodingError, "invalid delta response", err.Error())
+	}
+	return deltas, nil
+}
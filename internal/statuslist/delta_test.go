@@ -0,0 +1,264 @@
+package statuslist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+)
+
+func TestStatusListDelta_ApplyAndVerify(t *testing.T) {
+	keyPair, err := crypto.NewEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("NewEd25519KeyPair failed: %v", err)
+	}
+	signer := crypto.NewEd25519Signer(keyPair)
+
+	base := NewBitString(64)
+	base.Set(2, true)
+	base.Set(40, true)
+	next := base.Clone()
+	next.Set(2, false)
+	next.Set(7, true)
+	next.Set(100, true) // grows the list
+
+	list := &StatusList2021{ID: "list-1", Issuer: "did:key:issuer"}
+	delta := NewStatusListDelta(list, "2025-03", 4, base, next)
+	if len(delta.Set) != 2 || delta.Set[0] != 7 || delta.Set[1] != 100 {
+		t.Errorf("unexpected set indexes %v", delta.Set)
+	}
+	if len(delta.Cleared) != 1 || delta.Cleared[0] != 2 {
+		t.Errorf("unexpected cleared indexes %v", delta.Cleared)
+	}
+	if delta.Version != 5 {
+		t.Errorf("expected version 5, got %d", delta.Version)
+	}
+
+	if err := delta.Verify(signer.PublicKey()); err == nil {
+		t.Error("expected an unsigned delta to be rejected")
+	}
+	if err := delta.Sign(signer); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	if err := delta.Verify(signer.PublicKey()); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+
+	applied, err := delta.Apply(base)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !applied.Equals(next) {
+		t.Error("expected the delta to reproduce the next bitmap")
+	}
+	if _, err := delta.Apply(next); err == nil {
+		t.Error("expected the delta to reject a bitmap it was not computed from")
+	}
+
+	delta.Set = append(delta.Set, 9)
+	if err := delta.Verify(signer.PublicKey()); err == nil {
+		t.Error("expected a tampered delta to fail verification")
+	}
+}
+
+func TestDeltaStatusListProvider_CatchesUp(t *testing.T) {
+	keyPair, err := crypto.NewEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("NewEd25519KeyPair failed: %v", err)
+	}
+	signer := crypto.NewEd25519Signer(keyPair)
+	issuer := "did:key:issuer"
+
+	f := newPublisherFixture(t)
+	log := NewDeltaLog()
+	f.publisher.SetDeltaSink(log, issuerKeySigner{signer})
+
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	publish := func() {
+		t.Helper()
+		if err := f.publisher.MarkChanged(context.Background(), issuer); err != nil {
+			t.Fatalf("MarkChanged failed: %v", err)
+		}
+		if _, err := f.publisher.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+	}
+	publish()
+
+	// The verifier downloads the full list once
+	fetched, err := f.manager.provider.FetchStatusList(list.ID)
+	if err != nil {
+		t.Fatalf("FetchStatusList failed: %v", err)
+	}
+	snapshotProvider := NewInMemoryStatusListProvider()
+	snapshotProvider.StoreStatusList(fetched)
+	base := &countingProvider{StatusListProvider: snapshotProvider}
+
+	resolver := func(did string) (ed25519.PublicKey, error) {
+		if did != issuer {
+			return nil, errors.New("unknown issuer")
+		}
+		return signer.PublicKey(), nil
+	}
+	provider := NewDeltaStatusListProvider(base, log, resolver)
+	if _, err := provider.FetchStatusList(list.ID); err != nil {
+		t.Fatalf("FetchStatusList failed: %v", err)
+	}
+
+	if err := f.manager.RevokeCredential(list.ID, 11); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	publish()
+	f.now = f.now.AddDate(0, 1, 0)
+	if err := f.manager.RevokeCredential(list.ID, 12); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	publish()
+
+	if deltas := log.ByEpoch(list.ID, "2025-04"); len(deltas) != 1 || deltas[0].Version != 3 {
+		t.Fatalf("expected the April delta to take the list to version 3, got %v", deltas)
+	}
+
+	updated, err := provider.FetchStatusList(list.ID)
+	if err != nil {
+		t.Fatalf("FetchStatusList failed: %v", err)
+	}
+	if base.fetches != 1 {
+		t.Errorf("expected the full list to be downloaded once, got %d", base.fetches)
+	}
+	if provider.Version(list.ID) != 3 {
+		t.Errorf("expected version 3, got %d", provider.Version(list.ID))
+	}
+	bits, err := FromCompressedBase64(updated.CredentialSubject.EncodedList)
+	if err != nil {
+		t.Fatalf("FromCompressedBase64 failed: %v", err)
+	}
+	if !isSet(t, bits, 11) || !isSet(t, bits, 12) {
+		t.Error("expected both revocations to be applied")
+	}
+
+	// A forged delta is not applied; the full list is fetched again instead
+	forged := NewStatusListDelta(list, "2025-04", 3, bits, bits)
+	forged.Set = []int{20}
+	forged.Signature = "Zm9yZ2Vk"
+	log.deltas[list.ID] = append(log.deltas[list.ID], forged)
+	if _, err := provider.FetchStatusList(list.ID); err != nil {
+		t.Fatalf("FetchStatusList failed: %v", err)
+	}
+	if base.fetches != 2 {
+		t.Errorf("expected a refetch after a bad delta, got %d fetches", base.fetches)
+	}
+}
+
+type countingProvider struct {
+	StatusListProvider
+	fetches int
+}
+
+func (p *countingProvider) FetchStatusList(url string) (*StatusList2021, error) {
+	p.fetches++
+	return p.StatusListProvider.FetchStatusList(url)
+}
+
+// issuerKeySigner signs deltas for every issuer with one key
+type issuerKeySigner struct {
+	signer *crypto.Ed25519Signer
+}
+
+func (s issuerKeySigner) SignAsDID(did string, data []byte) ([]byte, error) {
+	return s.signer.Sign(data)
+}
+
+func TestDeltaLog_RetriesAndHTTP(t *testing.T) {
+	keyPair, err := crypto.NewEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("NewEd25519KeyPair failed: %v", err)
+	}
+	signer := crypto.NewEd25519Signer(keyPair)
+	issuer := "did:key:issuer"
+	resolver := func(did string) (ed25519.PublicKey, error) {
+		if did != issuer {
+			return nil, errors.New("unknown issuer")
+		}
+		return signer.PublicKey(), nil
+	}
+
+	list := &StatusList2021{ID: issuer + statusListIDSeparator + "1", Issuer: issuer}
+	base := NewBitString(64)
+	next := base.Clone()
+	next.Set(3, true)
+	delta := NewStatusListDelta(list, "2025-03", 1, base, next)
+	if err := delta.SignAsIssuer(issuerKeySigner{signer}); err != nil {
+		t.Fatalf("SignAsIssuer failed: %v", err)
+	}
+	if err := delta.VerifyIssuer(resolver); err != nil {
+		t.Fatalf("VerifyIssuer failed: %v", err)
+	}
+	foreign := *delta
+	foreign.ListID = "did:key:other" + statusListIDSeparator + "1"
+	if err := foreign.VerifyIssuer(resolver); err == nil {
+		t.Error("expected a delta for another issuer's list to be rejected")
+	}
+
+	// A full node stores published deltas and serves them back
+	log := NewDeltaLog()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var published StatusListDelta
+			if err := json.NewDecoder(r.Body).Decode(&published); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := published.VerifyIssuer(resolver); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := log.PublishDelta(r.Context(), &published); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		from, _ := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+		deltas, _ := log.FetchDeltas(r.URL.Query().Get("list"), from)
+		json.NewEncoder(w).Encode(deltas)
+	}))
+	defer server.Close()
+
+	client := NewHTTPDeltaClient(server.URL+"/", 0)
+	if err := client.PublishDelta(context.Background(), delta); err != nil {
+		t.Fatalf("PublishDelta failed: %v", err)
+	}
+	// A retried delta is accepted again; a conflicting one is not
+	if err := client.PublishDelta(context.Background(), delta); err != nil {
+		t.Errorf("expected a retried delta to be accepted, got %v", err)
+	}
+	conflicting := NewStatusListDelta(list, "2025-03", 1, base, base)
+	if err := conflicting.SignAsIssuer(issuerKeySigner{signer}); err != nil {
+		t.Fatalf("SignAsIssuer failed: %v", err)
+	}
+	if err := client.PublishDelta(context.Background(), conflicting); err == nil {
+		t.Error("expected a delta forking the chain to be rejected")
+	}
+
+	fetched, err := client.FetchDeltas(list.ID, 1)
+	if err != nil {
+		t.Fatalf("FetchDeltas failed: %v", err)
+	}
+	if len(fetched) != 1 || fetched[0].Digest != delta.Digest {
+		t.Fatalf("expected the published delta back, got %v", fetched)
+	}
+	if err := fetched[0].VerifyIssuer(resolver); err != nil {
+		t.Errorf("expected the fetched delta to verify, got %v", err)
+	}
+}
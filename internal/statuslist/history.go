@@ -0,0 +1,283 @@
+package statuslist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// DefaultHistoryLookback is how many epochs StatusAt searches back for the
+// last publication before the requested epoch
+const DefaultHistoryLookback = 120
+
+// BlobReader reads content-addressed blobs, as store.BlobStore does
+type BlobReader interface {
+	Get(ctx context.Context, cid string) ([]byte, error)
+}
+
+// StatusListLookup returns the bitmap CID an issuer published for an epoch,
+// as store.StatusListStore does
+type StatusListLookup interface {
+	GetStatusList(ctx context.Context, issuer, epoch string) (string, error)
+}
+
+// AnnouncementLookup returns the announcement an issuer signed for the
+// snapshot indexed under an epoch, as AnnouncementStore does
+type AnnouncementLookup interface {
+	GetAnnouncement(ctx context.Context, issuer, epoch string) (*events.RevocationAnnounceEvent, error)
+}
+
+// announcementStateKey is where an announcement store keeps an issuer's
+// announcement for an epoch
+func announcementStateKey(issuer, epoch string) string {
+	return "statuslist/announcements/" + issuer + "|" + epoch
+}
+
+// AnnouncementStore keeps the signed announcement behind every snapshot a
+// full node indexes, so the index can be checked against the issuer
+type AnnouncementStore struct {
+	state store.StateStore
+	blobs BlobReader
+	keys  DeltaKeyResolver
+}
+
+// NewAnnouncementStore creates an announcement store. keys defaults to
+// DIDKeyResolver.
+func NewAnnouncementStore(state store.StateStore, blobs BlobReader, keys DeltaKeyResolver) *AnnouncementStore {
+	if keys == nil {
+		keys = DIDKeyResolver
+	}
+
+	return &AnnouncementStore{
+		state: state,
+		blobs: blobs,
+		keys:  keys,
+	}
+}
+
+// StoreAnnouncement verifies an announcement and keeps it as the record of
+// the issuer's snapshot for an epoch. The announced snapshot must already be
+// stored and be the issuer's snapshot for that epoch.
+func (s *AnnouncementStore) StoreAnnouncement(ctx context.Context, epoch string, announcement *events.RevocationAnnounceEvent) error {
+	if err := VerifyAnnouncement(announcement, s.keys); err != nil {
+		return err
+	}
+
+	data, err := s.blobs.Get(ctx, announcement.BitmapCID)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return NewStatusListError(ErrorListNotFound, "announced snapshot is not stored")
+		}
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to read announced snapshot", err.Error())
+	}
+	if _, err := verifySnapshot(data, announcement.BitmapCID, announcement.Issuer, epoch); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(announcement)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode announcement", err.Error())
+	}
+	if err := s.state.PutState(ctx, announcementStateKey(announcement.Issuer, epoch), encoded); err != nil {
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to save announcement", err.Error())
+	}
+	return nil
+}
+
+// GetAnnouncement implements AnnouncementLookup
+func (s *AnnouncementStore) GetAnnouncement(ctx context.Context, issuer, epoch string) (*events.RevocationAnnounceEvent, error) {
+	data, err := s.state.GetState(ctx, announcementStateKey(issuer, epoch))
+	if err != nil {
+		return nil, err
+	}
+
+	var announcement events.RevocationAnnounceEvent
+	if err := json.Unmarshal(data, &announcement); err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorEncodingError, "invalid stored announcement", err.Error())
+	}
+	return &announcement, nil
+}
+
+// verifySnapshot decodes a snapshot blob and checks that it has the expected
+// CID and is the issuer's snapshot for the epoch
+func verifySnapshot(data []byte, bitmapCID, issuer, epoch string) (*StatusListSnapshot, error) {
+	if cid, err := events.GenerateCID(data); err != nil || cid != bitmapCID {
+		return nil, NewStatusListError(ErrorInvalidStatusList, "snapshot does not match its CID")
+	}
+	snapshot, err := DecodeStatusListSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Issuer != issuer || snapshot.Epoch != epoch {
+		return nil, NewStatusListError(ErrorInvalidStatusList, "snapshot does not match its index entry")
+	}
+	return snapshot, nil
+}
+
+// HistoricalStatus is the status of an entry as of an epoch, together with
+// the published bitmap it was read from
+type HistoricalStatus struct {
+	StatusResult
+
+	// Epoch is the epoch that was asked about
+	Epoch string `json:"epoch"`
+
+	// PublishedEpoch is the epoch of the snapshot in effect at Epoch
+	PublishedEpoch string `json:"publishedEpoch"`
+
+	// BitmapCID is the CID of the snapshot blob holding the bitmap
+	BitmapCID string `json:"bitmapCid"`
+
+	// Version is the list version in that snapshot
+	Version uint64 `json:"version"`
+}
+
+// StatusListHistory answers status queries against the snapshots issuers
+// published per epoch. Index entries are only trusted once the issuer's
+// signed announcement of the indexed snapshot checks out.
+type StatusListHistory struct {
+	index         StatusListLookup
+	announcements AnnouncementLookup
+	blobs         BlobReader
+	keys          DeltaKeyResolver
+	lookback      int
+}
+
+// NewStatusListHistory creates a history over published snapshots. keys
+// defaults to DIDKeyResolver. lookback limits how many epochs are searched
+// and defaults to DefaultHistoryLookback.
+func NewStatusListHistory(index StatusListLookup, announcements AnnouncementLookup, blobs BlobReader, keys DeltaKeyResolver, lookback int) *StatusListHistory {
+	if keys == nil {
+		keys = DIDKeyResolver
+	}
+	if lookback <= 0 {
+		lookback = DefaultHistoryLookback
+	}
+
+	return &StatusListHistory{
+		index:         index,
+		announcements: announcements,
+		blobs:         blobs,
+		keys:          keys,
+		lookback:      lookback,
+	}
+}
+
+// StatusAt returns the status an entry had at the end of an epoch. Issuers
+// only publish in epochs where a list changed, so the latest snapshot at or
+// before the epoch is used.
+func (h *StatusListHistory) StatusAt(ctx context.Context, issuer string, entry *StatusListEntry, epoch string) (*HistoricalStatus, error) {
+	if entry == nil || entry.StatusListCredential == "" {
+		return nil, NewStatusListError(ErrorInvalidEntry, "status list credential cannot be empty")
+	}
+	index, err := strconv.Atoi(entry.StatusListIndex)
+	if err != nil || index < 0 {
+		return nil, NewStatusListError(ErrorInvalidIndex, "invalid status list index")
+	}
+
+	publishedEpoch, bitmapCID, err := h.findSnapshot(ctx, issuer, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.blobs.Get(ctx, bitmapCID)
+	if err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to fetch status list snapshot", err.Error())
+	}
+	snapshot, err := verifySnapshot(data, bitmapCID, issuer, publishedEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range snapshot.Lists {
+		if list.ID != entry.StatusListCredential {
+			continue
+		}
+		if entry.StatusPurpose != "" && entry.StatusPurpose != list.Purpose {
+			return nil, NewStatusListError(ErrorInvalidEntry, "status purpose mismatch")
+		}
+
+		bits, err := FromCompressedBase64(list.EncodedList)
+		if err != nil {
+			return nil, err
+		}
+		// Bits past the end of the list were never set
+		status := false
+		if index < bits.Length() {
+			if status, err = bits.Get(index); err != nil {
+				return nil, err
+			}
+		}
+
+		return &HistoricalStatus{
+			StatusResult: StatusResult{
+				Valid:   !status,
+				Status:  status,
+				Purpose: StatusPurpose(list.Purpose),
+				Index:   index,
+				ListID:  list.ID,
+			},
+			Epoch:          epoch,
+			PublishedEpoch: publishedEpoch,
+			BitmapCID:      bitmapCID,
+			Version:        list.Version,
+		}, nil
+	}
+
+	return nil, NewStatusListError(ErrorListNotFound,
+		fmt.Sprintf("status list was not published by %s as of %s", issuer, epoch))
+}
+
+// findSnapshot walks back from an epoch to the issuer's latest publication
+// and checks it against the issuer's announcement
+func (h *StatusListHistory) findSnapshot(ctx context.Context, issuer, epoch string) (string, string, error) {
+	at, err := time.Parse(StatusEpochFormat, epoch)
+	if err != nil {
+		return "", "", NewStatusListErrorWithDetails(ErrorInvalidEntry, "invalid epoch", err.Error())
+	}
+
+	for i := 0; i < h.lookback; i++ {
+		candidate := at.AddDate(0, -i, 0).Format(StatusEpochFormat)
+		bitmapCID, err := h.index.GetStatusList(ctx, issuer, candidate)
+		if err == nil {
+			if err := h.verifyAnnounced(ctx, issuer, candidate, bitmapCID); err != nil {
+				return "", "", err
+			}
+			return candidate, bitmapCID, nil
+		}
+		if !store.IsNotFound(err) {
+			return "", "", NewStatusListErrorWithDetails(ErrorNetworkError, "failed to look up status list", err.Error())
+		}
+	}
+
+	return "", "", NewStatusListError(ErrorListNotFound,
+		fmt.Sprintf("no status list published by %s as of %s", issuer, epoch))
+}
+
+// verifyAnnounced checks that the issuer announced the indexed snapshot for
+// the epoch
+func (h *StatusListHistory) verifyAnnounced(ctx context.Context, issuer, epoch, bitmapCID string) error {
+	announcement, err := h.announcements.GetAnnouncement(ctx, issuer, epoch)
+	if err != nil {
+		if store.IsNotFound(err) {
+			return NewStatusListError(ErrorPermissionDenied,
+				fmt.Sprintf("status list indexed for %s in %s was not announced", issuer, epoch))
+		}
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to look up announcement", err.Error())
+	}
+	if announcement.Issuer != issuer {
+		return NewStatusListError(ErrorPermissionDenied, "announcement belongs to another issuer")
+	}
+	if err := VerifyAnnouncement(announcement, h.keys); err != nil {
+		return err
+	}
+	if announcement.BitmapCID != bitmapCID {
+		return NewStatusListError(ErrorPermissionDenied, "indexed snapshot differs from the announced one")
+	}
+	return nil
+}
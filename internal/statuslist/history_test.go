@@ -0,0 +1,165 @@
+package statuslist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+func TestStatusListHistory_StatusAt(t *testing.T) {
+	f := newPublisherFixture(t)
+	issuer := "did:key:issuer"
+	ctx := context.Background()
+	issuerSigner := newMirrorSigner(t)
+	f.publisher.signer = &keySigner{signer: issuerSigner, now: f.now}
+	resolver := func(did string) (ed25519.PublicKey, error) {
+		if did != issuer {
+			return nil, errors.New("unknown issuer")
+		}
+		return issuerSigner.PublicKey(), nil
+	}
+
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 4); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if _, err := f.publisher.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	marchCID := f.index.entries[0].cid
+
+	// Nothing changes in April; the next publication is in May
+	f.now = f.now.AddDate(0, 2, 0)
+	if err := f.manager.RevokeCredential(list.ID, 5); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if _, err := f.publisher.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	history := NewStatusListHistory(f.index, f.index, f.blobs, resolver, 0)
+	entry := func(index string) *StatusListEntry {
+		return &StatusListEntry{
+			StatusPurpose:        string(StatusPurposeRevocation),
+			StatusListIndex:      index,
+			StatusListCredential: list.ID,
+		}
+	}
+
+	status, err := history.StatusAt(ctx, issuer, entry("5"), "2025-04")
+	if err != nil {
+		t.Fatalf("StatusAt failed: %v", err)
+	}
+	if status.Status || !status.Valid {
+		t.Error("expected index 5 to be valid in April")
+	}
+	if status.PublishedEpoch != "2025-03" || status.BitmapCID != marchCID || status.Version != 1 {
+		t.Errorf("expected the March snapshot to prove April, got %s %s v%d",
+			status.PublishedEpoch, status.BitmapCID, status.Version)
+	}
+
+	status, err = history.StatusAt(ctx, issuer, entry("5"), "2025-05")
+	if err != nil {
+		t.Fatalf("StatusAt failed: %v", err)
+	}
+	if !status.Status || status.PublishedEpoch != "2025-05" || status.Version != 2 {
+		t.Errorf("expected index 5 revoked by the May snapshot, got %+v", status)
+	}
+
+	status, err = history.StatusAt(ctx, issuer, entry("4"), "2025-12")
+	if err != nil {
+		t.Fatalf("StatusAt failed: %v", err)
+	}
+	if !status.Status {
+		t.Error("expected index 4 to stay revoked")
+	}
+
+	if _, err := history.StatusAt(ctx, issuer, entry("4"), "2025-02"); err == nil {
+		t.Error("expected no status before the first publication")
+	}
+	mismatched := entry("4")
+	mismatched.StatusPurpose = string(StatusPurposeSuspension)
+	if _, err := history.StatusAt(ctx, issuer, mismatched, "2025-03"); err == nil {
+		t.Error("expected a purpose mismatch to be rejected")
+	}
+	if _, err := history.StatusAt(ctx, issuer, entry("4"), "March"); err == nil {
+		t.Error("expected an invalid epoch to be rejected")
+	}
+
+	// Index entries the issuer did not announce are not trusted
+	f.index.StoreStatusList(ctx, issuer, "2025-07", marchCID)
+	if _, err := history.StatusAt(ctx, issuer, entry("5"), "2025-08"); err == nil {
+		t.Error("expected an unannounced index entry to be rejected")
+	}
+	forged := *f.index.announcements[issuer+"|2025-05"]
+	forged.BitmapCID = marchCID
+	forged.PayloadCID = marchCID
+	f.index.announcements[issuer+"|2025-07"] = &forged
+	if _, err := history.StatusAt(ctx, issuer, entry("5"), "2025-08"); err == nil {
+		t.Error("expected an announcement with a forged signature to be rejected")
+	}
+}
+
+func TestAnnouncementStore_StoreAnnouncement(t *testing.T) {
+	issuer := "did:key:issuer"
+	ctx := context.Background()
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	f := newPublisherFixture(t)
+	issuerSigner := newMirrorSigner(t)
+	f.publisher.signer = &keySigner{signer: issuerSigner, now: f.now}
+	resolver := func(did string) (ed25519.PublicKey, error) {
+		if did != issuer {
+			return nil, errors.New("unknown issuer")
+		}
+		return issuerSigner.PublicKey(), nil
+	}
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 1); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	announcements, err := f.publisher.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	announcement := announcements[0]
+
+	announced := NewAnnouncementStore(stateStore, f.blobs, resolver)
+	if err := announced.StoreAnnouncement(ctx, "2025-04", announcement); err == nil {
+		t.Error("expected an announcement replayed under another epoch to be rejected")
+	}
+	unsigned := *announcement
+	unsigned.Signature = ""
+	if err := announced.StoreAnnouncement(ctx, "2025-03", &unsigned); err == nil {
+		t.Error("expected an unsigned announcement to be rejected")
+	}
+	if _, err := announced.GetAnnouncement(ctx, issuer, "2025-03"); !store.IsNotFound(err) {
+		t.Fatalf("expected no announcement to be stored, got %v", err)
+	}
+
+	if err := announced.StoreAnnouncement(ctx, "2025-03", announcement); err != nil {
+		t.Fatalf("StoreAnnouncement failed: %v", err)
+	}
+	stored, err := announced.GetAnnouncement(ctx, issuer, "2025-03")
+	if err != nil {
+		t.Fatalf("GetAnnouncement failed: %v", err)
+	}
+	if stored.BitmapCID != announcement.BitmapCID || stored.Signature != announcement.Signature {
+		t.Errorf("expected the announcement to round-trip, got %+v", stored)
+	}
+}
@@ -1,8 +1,10 @@
 package statuslist
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,11 @@ type DefaultStatusListManager struct {
 	cache           StatusListCache
 	keyManager      did.KeyManager
 	credentialSigner vc.CredentialIssuer
+	publisher       *StatusListPublisher
+	
+	// Mirror fallback for when the issuer's list cannot be fetched
+	mirrorPolicy *MirrorReceiptPolicy
+	mirrorClient MirrorClient
 	
 	// Index allocation tracking
 	indexMutex sync.RWMutex
@@ -45,6 +52,36 @@ func NewDefaultStatusListManager(
 	}
 }
 
+// SetPublisher publishes status changes to the network through the given
+// publisher
+func (m *DefaultStatusListManager) SetPublisher(publisher *StatusListPublisher) {
+	m.publisher = publisher
+}
+
+// SetMirrorPolicy lets CheckStatus fall back to mirrored snapshots when a
+// status list cannot be fetched. client defaults to an HTTPMirrorClient.
+func (m *DefaultStatusListManager) SetMirrorPolicy(policy *MirrorReceiptPolicy, client MirrorClient) {
+	if client == nil {
+		client = NewHTTPMirrorClient(0)
+	}
+	m.mirrorPolicy = policy
+	m.mirrorClient = client
+}
+
+// statusListIDSeparator separates the issuer from the rest of a status list
+// ID
+const statusListIDSeparator = "/status-lists/"
+
+// statusListIssuer returns the issuer a status list ID was created under,
+// or "" when the ID does not name one
+func statusListIssuer(listID string) string {
+	issuer, _, found := strings.Cut(listID, statusListIDSeparator)
+	if !found {
+		return ""
+	}
+	return issuer
+}
+
 // CreateStatusList creates a new status list credential
 func (m *DefaultStatusListManager) CreateStatusList(issuer string, purpose StatusPurpose, size int) (*StatusList2021, error) {
 	if issuer == "" {
@@ -71,7 +108,7 @@ func (m *DefaultStatusListManager) CreateStatusList(issuer string, purpose Statu
 	}
 	
 	// Generate unique ID for the status list
-	listID := fmt.Sprintf("%s/status-lists/%s/%d", issuer, purpose, time.Now().Unix())
+	listID := fmt.Sprintf("%s%s%s/%d", issuer, statusListIDSeparator, purpose, time.Now().Unix())
 	
 	// Create the status list credential
 	statusList := &StatusList2021{
@@ -169,6 +206,14 @@ func (m *DefaultStatusListManager) UpdateStatus(listID string, index int, status
 		return err
 	}
 	
+	// Queue the issuer for publication before storing, so a batch for an
+	// earlier epoch flushed here does not pick up this change
+	if m.publisher != nil {
+		if err := m.publisher.MarkChanged(context.Background(), statusList.Issuer); err != nil {
+			return err
+		}
+	}
+	
 	// Update the status list
 	statusList.CredentialSubject.EncodedList = encodedList
 	statusList.IssuanceDate = time.Now().UTC().Format(time.RFC3339)
@@ -209,7 +254,10 @@ func (m *DefaultStatusListManager) CheckStatus(entry *StatusListEntry) (*StatusR
 	// Get the status list
 	statusList, err := m.GetStatusList(entry.StatusListCredential)
 	if err != nil {
-		return nil, err
+		if m.mirrorPolicy == nil || m.mirrorPolicy.Threshold <= 0 {
+			return nil, err
+		}
+		return checkMirrors(context.Background(), m.mirrorPolicy, m.mirrorClient, entry, index, time.Now())
 	}
 	
 	// Verify the purpose matches
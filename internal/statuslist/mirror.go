@@ -0,0 +1,571 @@
+package statuslist
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/store"
+	"github.com/ParichayaHQ/credence/pkg/types"
+)
+
+// SignMirrorReceipt signs a receipt with the mirror's key and records the
+// key in the receipt
+func SignMirrorReceipt(receipt *types.MirrorReceipt, signer crypto.Signer) error {
+	receipt.Mirror = signer.PublicKeyBase64()
+	data, err := receiptSigningBytes(receipt)
+	if err != nil {
+		return err
+	}
+	signature, err := signer.SignBase64(data)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to sign receipt", err.Error())
+	}
+	receipt.Signature = signature
+	return nil
+}
+
+// VerifyMirrorReceipt checks a receipt's signature against the mirror key it
+// names. Whether that mirror is trusted is up to the caller.
+func VerifyMirrorReceipt(receipt *types.MirrorReceipt) error {
+	data, err := receiptSigningBytes(receipt)
+	if err != nil {
+		return err
+	}
+	valid, err := crypto.NewEd25519Verifier().VerifyBase64(receipt.Mirror, receipt.Signature, data)
+	if err != nil || !valid {
+		return NewStatusListError(ErrorPermissionDenied, "invalid mirror receipt signature")
+	}
+	return nil
+}
+
+func receiptSigningBytes(receipt *types.MirrorReceipt) ([]byte, error) {
+	unsigned := *receipt
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode receipt", err.Error())
+	}
+	return data, nil
+}
+
+// MirrorStore is the storage a mirror keeps bitmaps in, as store.FullNode
+// provides
+type MirrorStore interface {
+	BlobWriter
+	BlobReader
+	StatusListIndex
+}
+
+// SnapshotFetcher downloads the snapshot blob an announcement refers to
+type SnapshotFetcher interface {
+	FetchSnapshot(ctx context.Context, announcement *events.RevocationAnnounceEvent) ([]byte, error)
+}
+
+// MirroredStatusList is a mirrored snapshot together with the mirror's
+// receipt for it
+type MirroredStatusList struct {
+	Receipt  *types.MirrorReceipt `json:"receipt"`
+	Snapshot []byte               `json:"snapshot"`
+}
+
+// StatusListMirror copies issuers' status list snapshots and vouches for them
+// with signed, timestamped receipts, so verifiers can still check status
+// while an issuer's own endpoint is unreachable
+type StatusListMirror struct {
+	storage MirrorStore
+	fetcher SnapshotFetcher
+	keys    DeltaKeyResolver
+	signer  crypto.Signer
+	now     func() time.Time
+
+	mutex    sync.RWMutex
+	receipts map[string]*types.MirrorReceipt // issuer|epoch -> receipt
+	issued   map[string]time.Time            // issuer|epoch -> announcement time
+	lists    map[string]string               // listID -> issuer|epoch of its latest snapshot
+	state    store.StateStore
+}
+
+// mirrorStateKey is where a mirror keeps its receipts in a state store
+const mirrorStateKey = "statuslist/mirror"
+
+// mirrorState is the persisted form of a mirror. The snapshots themselves
+// are in the mirror's blob store.
+type mirrorState struct {
+	Receipts map[string]*types.MirrorReceipt `json:"receipts"`
+	Issued   map[string]time.Time            `json:"issued"`
+	Lists    map[string]string               `json:"lists"`
+}
+
+// NewStatusListMirror creates a status list mirror. keys defaults to
+// DIDKeyResolver.
+func NewStatusListMirror(storage MirrorStore, fetcher SnapshotFetcher, keys DeltaKeyResolver, signer crypto.Signer) *StatusListMirror {
+	if keys == nil {
+		keys = DIDKeyResolver
+	}
+
+	return &StatusListMirror{
+		storage:  storage,
+		fetcher:  fetcher,
+		keys:     keys,
+		signer:   signer,
+		now:      time.Now,
+		receipts: make(map[string]*types.MirrorReceipt),
+		issued:   make(map[string]time.Time),
+		lists:    make(map[string]string),
+	}
+}
+
+// SetStateStore loads the mirror's receipts from a state store and saves
+// every receipt to it from then on
+func (m *StatusListMirror) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), mirrorStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load mirror receipts: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if data != nil {
+		var state mirrorState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode mirror receipts: %w", err)
+		}
+		for key, receipt := range state.Receipts {
+			m.receipts[key] = receipt
+		}
+		for key, issued := range state.Issued {
+			m.issued[key] = issued
+		}
+		for listID, key := range state.Lists {
+			if _, exists := m.receipts[key]; exists {
+				m.lists[listID] = key
+			}
+		}
+	}
+
+	m.state = stateStore
+	return nil
+}
+
+// save writes the receipts to the state store. The caller holds the lock.
+func (m *StatusListMirror) save(ctx context.Context) error {
+	if m.state == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&mirrorState{Receipts: m.receipts, Issued: m.issued, Lists: m.lists})
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode mirror receipts", err.Error())
+	}
+	if err := m.state.PutState(ctx, mirrorStateKey, data); err != nil {
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to save mirror receipts", err.Error())
+	}
+	return nil
+}
+
+// Mirror fetches and verifies the snapshot an issuer announced, stores it and
+// returns a signed receipt for it. Every list in the snapshot must belong to
+// the announcing issuer.
+func (m *StatusListMirror) Mirror(ctx context.Context, announcement *events.RevocationAnnounceEvent) (*types.MirrorReceipt, error) {
+	if err := m.verifyAnnouncement(announcement); err != nil {
+		return nil, err
+	}
+
+	data, err := m.fetcher.FetchSnapshot(ctx, announcement)
+	if err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to fetch snapshot", err.Error())
+	}
+	if cid, err := events.GenerateCID(data); err != nil || cid != announcement.BitmapCID {
+		return nil, NewStatusListError(ErrorInvalidStatusList, "snapshot does not match the announced CID")
+	}
+	snapshot, err := DecodeStatusListSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Issuer != announcement.Issuer {
+		return nil, NewStatusListError(ErrorInvalidStatusList, "snapshot belongs to another issuer")
+	}
+	for _, list := range snapshot.Lists {
+		if statusListIssuer(list.ID) != snapshot.Issuer {
+			return nil, NewStatusListError(ErrorPermissionDenied,
+				fmt.Sprintf("status list %s does not belong to %s", list.ID, snapshot.Issuer))
+		}
+	}
+
+	key := snapshot.Issuer + "|" + snapshot.Epoch
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// Replayed announcements must not roll an epoch back
+	if issued, exists := m.issued[key]; exists && announcement.IssuedAt.Before(issued) {
+		return nil, NewStatusListError(ErrorInvalidStatusList, "a newer snapshot is already mirrored for this epoch")
+	}
+
+	if _, err := m.storage.Store(ctx, data); err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to store snapshot", err.Error())
+	}
+	if err := m.storage.StoreStatusList(ctx, snapshot.Issuer, snapshot.Epoch, announcement.BitmapCID); err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to record snapshot", err.Error())
+	}
+
+	receipt := &types.MirrorReceipt{
+		Issuer:    snapshot.Issuer,
+		Epoch:     snapshot.Epoch,
+		BitmapCID: announcement.BitmapCID,
+		Timestamp: m.now().UTC(),
+	}
+	if err := SignMirrorReceipt(receipt, m.signer); err != nil {
+		return nil, err
+	}
+
+	m.receipts[key] = receipt
+	m.issued[key] = announcement.IssuedAt
+	for _, list := range snapshot.Lists {
+		if current, exists := m.lists[list.ID]; !exists || m.receipts[current].Epoch <= snapshot.Epoch {
+			m.lists[list.ID] = key
+		}
+	}
+	if err := m.save(ctx); err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+// verifyAnnouncement checks that an announcement is a revocation
+// announcement signed by the issuer over the bitmap CID
+func (m *StatusListMirror) verifyAnnouncement(announcement *events.RevocationAnnounceEvent) error {
+	return VerifyAnnouncement(announcement, m.keys)
+}
+
+// VerifyAnnouncement checks that an announcement is a revocation
+// announcement its issuer signed over the bitmap CID
+func VerifyAnnouncement(announcement *events.RevocationAnnounceEvent, keys DeltaKeyResolver) error {
+	if announcement == nil || announcement.Type != events.EventTypeRevocationAnnounce {
+		return NewStatusListError(ErrorInvalidEntry, "not a revocation announcement")
+	}
+	if announcement.From != announcement.Issuer || announcement.PayloadCID != announcement.BitmapCID {
+		return NewStatusListError(ErrorInvalidEntry, "announcement does not sign its issuer and bitmap")
+	}
+
+	publicKey, err := keys(announcement.Issuer)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to resolve issuer key", err.Error())
+	}
+	canonical, err := events.CanonicalizeEvent(announcement.Event.ToSignable())
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to canonicalize announcement", err.Error())
+	}
+	signature, err := base64.StdEncoding.DecodeString(announcement.Signature)
+	if err != nil || !crypto.NewEd25519Verifier().Verify(publicKey, canonical, signature) {
+		return NewStatusListError(ErrorPermissionDenied, "invalid announcement signature")
+	}
+	return nil
+}
+
+// Receipt returns the receipt for an issuer's snapshot in an epoch
+func (m *StatusListMirror) Receipt(issuer, epoch string) (*types.MirrorReceipt, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	receipt, exists := m.receipts[issuer+"|"+epoch]
+	return receipt, exists
+}
+
+// Lookup returns the latest mirrored snapshot containing a status list
+func (m *StatusListMirror) Lookup(ctx context.Context, listID string) (*MirroredStatusList, error) {
+	m.mutex.RLock()
+	key, exists := m.lists[listID]
+	receipt := m.receipts[key]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return nil, NewStatusListError(ErrorListNotFound, "status list is not mirrored")
+	}
+
+	data, err := m.storage.Get(ctx, receipt.BitmapCID)
+	if err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError, "failed to read mirrored snapshot", err.Error())
+	}
+	return &MirroredStatusList{Receipt: receipt, Snapshot: data}, nil
+}
+
+// HTTPSnapshotFetcher downloads snapshots from the full node named in the
+// announcement's status list URI
+type HTTPSnapshotFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPSnapshotFetcher creates an HTTP snapshot fetcher
+func NewHTTPSnapshotFetcher(timeout time.Duration) *HTTPSnapshotFetcher {
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &HTTPSnapshotFetcher{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// FetchSnapshot implements SnapshotFetcher
+func (f *HTTPSnapshotFetcher) FetchSnapshot(ctx context.Context, announcement *events.RevocationAnnounceEvent) ([]byte, error) {
+	separator := strings.Index(announcement.StatusListURI, "/v1/status/")
+	if separator <= 0 {
+		return nil, fmt.Errorf("status list URI %q is not served by a full node", announcement.StatusListURI)
+	}
+	blobURL := announcement.StatusListURI[:separator] + "/v1/blobs/" + url.PathEscape(announcement.BitmapCID)
+
+	return httpGet(ctx, f.client, blobURL)
+}
+
+// MirrorEndpoint is a mirror a verifier trusts
+type MirrorEndpoint struct {
+	URL       string `json:"url"`
+	PublicKey string `json:"publicKey"` // Base64 Ed25519 key the mirror signs receipts with
+}
+
+// MirrorReceiptPolicy decides when mirrored snapshots may stand in for an
+// issuer's own status list
+type MirrorReceiptPolicy struct {
+	// Mirrors are the trusted mirrors
+	Mirrors []MirrorEndpoint `json:"mirrors"`
+
+	// Threshold is how many mirrors must vouch for the same bitmap
+	Threshold int `json:"threshold"`
+
+	// MaxAge discards receipts older than this when set
+	MaxAge time.Duration `json:"maxAge"`
+}
+
+// MirrorClient fetches a mirrored status list from a mirror
+type MirrorClient interface {
+	FetchMirrored(ctx context.Context, mirrorURL, listID string) (*MirroredStatusList, error)
+}
+
+// HTTPMirrorClient queries full nodes' /v1/status/mirror endpoint
+type HTTPMirrorClient struct {
+	client *http.Client
+}
+
+// NewHTTPMirrorClient creates an HTTP mirror client
+func NewHTTPMirrorClient(timeout time.Duration) *HTTPMirrorClient {
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &HTTPMirrorClient{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// MirrorAnnouncement implements AnnouncementMirror by posting the
+// announcement to the mirror's /v1/status/mirror endpoint. The receipt must
+// be signed and cover the announced bitmap.
+func (c *HTTPMirrorClient) MirrorAnnouncement(ctx context.Context, mirrorURL string, announcement *events.RevocationAnnounceEvent) (*types.MirrorReceipt, error) {
+	body, err := json.Marshal(announcement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode announcement: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(mirrorURL, "/")+"/v1/status/mirror", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("HTTP error: %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+	var receipt types.MirrorReceipt
+	if err := json.NewDecoder(response.Body).Decode(&receipt); err != nil {
+		return nil, fmt.Errorf("invalid mirror receipt: %w", err)
+	}
+	if err := VerifyMirrorReceipt(&receipt); err != nil {
+		return nil, err
+	}
+	if receipt.Issuer != announcement.Issuer || receipt.BitmapCID != announcement.BitmapCID {
+		return nil, fmt.Errorf("mirror receipt does not cover the announced bitmap")
+	}
+	return &receipt, nil
+}
+
+// FetchMirrored implements MirrorClient
+func (c *HTTPMirrorClient) FetchMirrored(ctx context.Context, mirrorURL, listID string) (*MirroredStatusList, error) {
+	body, err := httpGet(ctx, c.client, strings.TrimRight(mirrorURL, "/")+"/v1/status/mirror?list="+url.QueryEscape(listID))
+	if err != nil {
+		return nil, err
+	}
+
+	var mirrored MirroredStatusList
+	if err := json.Unmarshal(body, &mirrored); err != nil {
+		return nil, fmt.Errorf("invalid mirror response: %w", err)
+	}
+	if mirrored.Receipt == nil {
+		return nil, fmt.Errorf("mirror response has no receipt")
+	}
+	return &mirrored, nil
+}
+
+func httpGet(ctx context.Context, client *http.Client, target string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error: %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// checkMirrors resolves an entry's status from mirrored snapshots. At least
+// Threshold trusted mirrors must have signed receipts for the same bitmap,
+// published by the issuer the list belongs to; when several bitmaps
+// qualify, the one from the latest epoch wins.
+func checkMirrors(ctx context.Context, policy *MirrorReceiptPolicy, client MirrorClient, entry *StatusListEntry, index int, now time.Time) (*StatusResult, error) {
+	issuer := statusListIssuer(entry.StatusListCredential)
+	if issuer == "" {
+		return nil, NewStatusListError(ErrorInvalidEntry, "status list does not name its issuer")
+	}
+
+	type candidate struct {
+		snapshot *StatusListSnapshot
+		list     *PublishedStatusList
+		receipts []types.MirrorReceipt
+	}
+	candidates := make(map[string]*candidate)
+	counted := make(map[string]bool)
+	var failures []string
+
+	for _, mirror := range policy.Mirrors {
+		// A mirror listed twice still only counts once
+		if counted[mirror.PublicKey] {
+			continue
+		}
+		mirrored, err := client.FetchMirrored(ctx, mirror.URL, entry.StatusListCredential)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", mirror.URL, err))
+			continue
+		}
+		receipt := mirrored.Receipt
+		if receipt.Mirror != mirror.PublicKey {
+			failures = append(failures, mirror.URL+": receipt signed by an unexpected key")
+			continue
+		}
+		if err := VerifyMirrorReceipt(receipt); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", mirror.URL, err))
+			continue
+		}
+		if policy.MaxAge > 0 && now.Sub(receipt.Timestamp) > policy.MaxAge {
+			failures = append(failures, mirror.URL+": receipt expired")
+			continue
+		}
+		if cid, err := events.GenerateCID(mirrored.Snapshot); err != nil || cid != receipt.BitmapCID {
+			failures = append(failures, mirror.URL+": snapshot does not match receipt")
+			continue
+		}
+		counted[mirror.PublicKey] = true
+
+		if existing, exists := candidates[receipt.BitmapCID]; exists {
+			existing.receipts = append(existing.receipts, *receipt)
+			continue
+		}
+		snapshot, err := DecodeStatusListSnapshot(mirrored.Snapshot)
+		if err != nil || snapshot.Issuer != receipt.Issuer || snapshot.Epoch != receipt.Epoch {
+			failures = append(failures, mirror.URL+": snapshot does not match receipt")
+			continue
+		}
+		if snapshot.Issuer != issuer {
+			failures = append(failures, mirror.URL+": snapshot published by another issuer")
+			continue
+		}
+		for i := range snapshot.Lists {
+			if snapshot.Lists[i].ID == entry.StatusListCredential {
+				candidates[receipt.BitmapCID] = &candidate{
+					snapshot: snapshot,
+					list:     &snapshot.Lists[i],
+					receipts: []types.MirrorReceipt{*receipt},
+				}
+				break
+			}
+		}
+	}
+
+	var best *candidate
+	for cid, c := range candidates {
+		if len(c.receipts) < policy.Threshold {
+			continue
+		}
+		if best == nil || newerSnapshot(c.snapshot, c.list, cid, best.snapshot, best.list, best.receipts[0].BitmapCID) {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, NewStatusListErrorWithDetails(ErrorNetworkError,
+			fmt.Sprintf("fewer than %d mirrors vouch for the status list", policy.Threshold), strings.Join(failures, "; "))
+	}
+
+	if entry.StatusPurpose != "" && entry.StatusPurpose != best.list.Purpose {
+		return nil, NewStatusListError(ErrorInvalidEntry, "status purpose mismatch")
+	}
+	bits, err := FromCompressedBase64(best.list.EncodedList)
+	if err != nil {
+		return nil, err
+	}
+	status := false
+	if index < bits.Length() {
+		if status, err = bits.Get(index); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StatusResult{
+		Valid:   !status,
+		Status:  status,
+		Purpose: StatusPurpose(best.list.Purpose),
+		Index:   index,
+		ListID:  best.list.ID,
+		Proof: &types.StatusListProof{
+			Issuer:    best.snapshot.Issuer,
+			BitmapCID: best.receipts[0].BitmapCID,
+			ListEpoch: best.snapshot.Epoch,
+			Receipts:  best.receipts,
+		},
+	}, nil
+}
+
+// newerSnapshot orders mirrored snapshots by epoch, then list version, then
+// CID so the choice does not depend on map order
+func newerSnapshot(a *StatusListSnapshot, aList *PublishedStatusList, aCID string, b *StatusListSnapshot, bList *PublishedStatusList, bCID string) bool {
+	if a.Epoch != b.Epoch {
+		return a.Epoch > b.Epoch
+	}
+	if aList.Version != bList.Version {
+		return aList.Version > bList.Version
+	}
+	return aCID < bCID
+}
@@ -0,0 +1,393 @@
+package statuslist
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/store"
+	"github.com/ParichayaHQ/credence/pkg/types"
+)
+
+// keySigner signs network events with an issuer's Ed25519 key
+type keySigner struct {
+	signer *crypto.Ed25519Signer
+	now    time.Time
+}
+
+func (s *keySigner) SignNetworkEvent(eventType events.EventType, from, to, context, payloadCID string) (*events.Event, error) {
+	event := &events.Event{
+		Type:       eventType,
+		From:       from,
+		To:         to,
+		Context:    context,
+		Epoch:      s.now.Format(StatusEpochFormat),
+		PayloadCID: payloadCID,
+		Nonce:      "nonce",
+		IssuedAt:   s.now,
+	}
+	canonical, err := events.CanonicalizeEvent(event.ToSignable())
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.signer.Sign(canonical)
+	if err != nil {
+		return nil, err
+	}
+	event.Signature = base64.StdEncoding.EncodeToString(signature)
+	return event, nil
+}
+
+type mirrorStorage struct {
+	*memoryBlobs
+	*memoryIndex
+}
+
+func newMirrorStorage() *mirrorStorage {
+	return &mirrorStorage{&memoryBlobs{blobs: make(map[string][]byte)}, &memoryIndex{}}
+}
+
+// blobFetcher serves snapshots straight from the issuer's blob store
+type blobFetcher struct {
+	blobs *memoryBlobs
+}
+
+func (f *blobFetcher) FetchSnapshot(ctx context.Context, announcement *events.RevocationAnnounceEvent) ([]byte, error) {
+	return f.blobs.Get(ctx, announcement.BitmapCID)
+}
+
+// localMirrors answers mirror queries from in-process mirrors
+type localMirrors map[string]*StatusListMirror
+
+func (m localMirrors) FetchMirrored(ctx context.Context, mirrorURL, listID string) (*MirroredStatusList, error) {
+	mirror, exists := m[mirrorURL]
+	if !exists {
+		return nil, errors.New("mirror unreachable")
+	}
+	return mirror.Lookup(ctx, listID)
+}
+
+func newMirrorSigner(t *testing.T) *crypto.Ed25519Signer {
+	t.Helper()
+	keyPair, err := crypto.NewEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("NewEd25519KeyPair failed: %v", err)
+	}
+	return crypto.NewEd25519Signer(keyPair)
+}
+
+func TestStatusListMirror_Receipts(t *testing.T) {
+	issuer := "did:key:issuer"
+	issuerSigner := newMirrorSigner(t)
+	signer := &keySigner{signer: issuerSigner, now: time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC)}
+	resolver := func(did string) (ed25519.PublicKey, error) {
+		if did != issuer {
+			return nil, errors.New("unknown issuer")
+		}
+		return issuerSigner.PublicKey(), nil
+	}
+
+	f := newPublisherFixture(t)
+	f.publisher.signer = signer
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 8); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	announcements, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	announcement := announcements[0]
+
+	storage := newMirrorStorage()
+	mirrorSigner := newMirrorSigner(t)
+	mirror := NewStatusListMirror(storage, &blobFetcher{f.blobs}, resolver, mirrorSigner)
+
+	receipt, err := mirror.Mirror(context.Background(), announcement)
+	if err != nil {
+		t.Fatalf("Mirror failed: %v", err)
+	}
+	if receipt.Issuer != issuer || receipt.Epoch != "2025-03" || receipt.BitmapCID != announcement.BitmapCID {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+	if receipt.Mirror != mirrorSigner.PublicKeyBase64() {
+		t.Errorf("expected receipt to name the mirror key")
+	}
+	if err := VerifyMirrorReceipt(receipt); err != nil {
+		t.Errorf("VerifyMirrorReceipt failed: %v", err)
+	}
+	if cid, err := storage.GetStatusList(context.Background(), issuer, "2025-03"); err != nil || cid != announcement.BitmapCID {
+		t.Errorf("expected the mirror to index the bitmap, got %s %v", cid, err)
+	}
+	if stored, exists := mirror.Receipt(issuer, "2025-03"); !exists || stored != receipt {
+		t.Error("expected the receipt to be kept for the epoch")
+	}
+
+	tampered := *receipt
+	tampered.BitmapCID = "bafyother"
+	if err := VerifyMirrorReceipt(&tampered); err == nil {
+		t.Error("expected a tampered receipt to fail verification")
+	}
+
+	forged := *announcement
+	forged.Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+	if _, err := mirror.Mirror(context.Background(), &forged); err == nil {
+		t.Error("expected an unsigned announcement to be rejected")
+	}
+
+	// A replay of an older announcement cannot replace a newer one
+	if err := f.manager.RevokeCredential(list.ID, 9); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	signer.now = signer.now.Add(time.Hour)
+	newer, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, err := mirror.Mirror(context.Background(), newer[0]); err != nil {
+		t.Fatalf("Mirror failed: %v", err)
+	}
+	if _, err := mirror.Mirror(context.Background(), announcement); err == nil {
+		t.Error("expected a replayed announcement to be rejected")
+	}
+}
+
+func TestDefaultStatusListManager_MirrorFallback(t *testing.T) {
+	issuer := "did:key:issuer"
+	issuerSigner := newMirrorSigner(t)
+	resolver := func(string) (ed25519.PublicKey, error) { return issuerSigner.PublicKey(), nil }
+
+	f := newPublisherFixture(t)
+	f.publisher.signer = &keySigner{signer: issuerSigner, now: f.now}
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 3); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	announcements, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	mirrors := localMirrors{}
+	policy := &MirrorReceiptPolicy{Threshold: 2, MaxAge: 24 * time.Hour}
+	for _, url := range []string{"https://m1", "https://m2", "https://m3"} {
+		mirrorSigner := newMirrorSigner(t)
+		mirror := NewStatusListMirror(newMirrorStorage(), &blobFetcher{f.blobs}, resolver, mirrorSigner)
+		if _, err := mirror.Mirror(context.Background(), announcements[0]); err != nil {
+			t.Fatalf("Mirror failed: %v", err)
+		}
+		mirrors[url] = mirror
+		policy.Mirrors = append(policy.Mirrors, MirrorEndpoint{URL: url, PublicKey: mirrorSigner.PublicKeyBase64()})
+	}
+
+	// The verifier cannot reach the issuer's list at all
+	verifier := NewDefaultStatusListManager(nil, NewInMemoryStatusListProvider(), nil, nil, nil)
+	entry := &StatusListEntry{
+		StatusPurpose:        string(StatusPurposeRevocation),
+		StatusListIndex:      "3",
+		StatusListCredential: list.ID,
+	}
+	if _, err := verifier.CheckStatus(entry); err == nil {
+		t.Fatal("expected CheckStatus to fail without mirrors")
+	}
+
+	verifier.SetMirrorPolicy(policy, mirrors)
+	delete(mirrors, "https://m2")
+	result, err := verifier.CheckStatus(entry)
+	if err != nil {
+		t.Fatalf("CheckStatus failed: %v", err)
+	}
+	if !result.Status || result.Valid {
+		t.Error("expected the mirrored bitmap to show the revocation")
+	}
+	if result.Proof == nil || len(result.Proof.Receipts) != 2 || result.Proof.BitmapCID != announcements[0].BitmapCID {
+		t.Fatalf("expected a proof with two receipts, got %+v", result.Proof)
+	}
+	if result.Proof.Issuer != issuer || result.Proof.ListEpoch != "2025-03" {
+		t.Errorf("unexpected proof %+v", result.Proof)
+	}
+
+	// Receipts from keys outside the policy do not count
+	policy.Mirrors[2].PublicKey = newMirrorSigner(t).PublicKeyBase64()
+	if _, err := verifier.CheckStatus(entry); err == nil {
+		t.Error("expected a single trusted receipt to fall short of the threshold")
+	}
+}
+
+// announcementMirrors hands announcements to in-process mirrors
+type announcementMirrors map[string]*StatusListMirror
+
+func (m announcementMirrors) MirrorAnnouncement(ctx context.Context, mirrorURL string, announcement *events.RevocationAnnounceEvent) (*types.MirrorReceipt, error) {
+	mirror, exists := m[mirrorURL]
+	if !exists {
+		return nil, errors.New("mirror unreachable")
+	}
+	return mirror.Mirror(ctx, announcement)
+}
+
+// staticMirror serves the same mirrored snapshot for every list
+type staticMirror struct {
+	mirrored *MirroredStatusList
+}
+
+func (m staticMirror) FetchMirrored(ctx context.Context, mirrorURL, listID string) (*MirroredStatusList, error) {
+	return m.mirrored, nil
+}
+
+func TestStatusListMirror_RejectsForeignLists(t *testing.T) {
+	issuer := "did:key:issuer"
+	issuerSigner := newMirrorSigner(t)
+	resolver := func(string) (ed25519.PublicKey, error) { return issuerSigner.PublicKey(), nil }
+
+	f := newPublisherFixture(t)
+	f.publisher.signer = &keySigner{signer: issuerSigner, now: f.now}
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+
+	// The issuer slips another issuer's list ID into its own snapshot
+	forged := *list
+	forged.ID = "did:key:victim/status-lists/revocation/1"
+	if err := f.manager.provider.StoreStatusList(&forged); err != nil {
+		t.Fatalf("StoreStatusList failed: %v", err)
+	}
+	if err := f.publisher.MarkChanged(context.Background(), issuer); err != nil {
+		t.Fatalf("MarkChanged failed: %v", err)
+	}
+	announcements, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	mirror := NewStatusListMirror(newMirrorStorage(), &blobFetcher{f.blobs}, resolver, newMirrorSigner(t))
+	if _, err := mirror.Mirror(context.Background(), announcements[0]); err == nil {
+		t.Fatal("expected a snapshot with another issuer's list to be rejected")
+	}
+	if _, err := mirror.Lookup(context.Background(), forged.ID); err == nil {
+		t.Error("expected the foreign list not to be mirrored")
+	}
+}
+
+func TestCheckMirrors_RequiresListIssuer(t *testing.T) {
+	listID := "did:key:victim/status-lists/revocation/1"
+	bits := NewBitString(16)
+	if err := bits.Set(3, true); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	encoded, err := bits.ToCompressedBase64(DefaultStatusListConfig().CompressionLevel)
+	if err != nil {
+		t.Fatalf("ToCompressedBase64 failed: %v", err)
+	}
+
+	mirrorSigner := newMirrorSigner(t)
+	mirrored := func(issuer string) staticMirror {
+		snapshot := &StatusListSnapshot{
+			Issuer: issuer,
+			Epoch:  "2025-03",
+			Lists:  []PublishedStatusList{{ID: listID, Purpose: string(StatusPurposeRevocation), Version: 1, EncodedList: encoded}},
+		}
+		data, err := snapshot.Encode()
+		if err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+		cid, err := events.GenerateCID(data)
+		if err != nil {
+			t.Fatalf("GenerateCID failed: %v", err)
+		}
+		receipt := &types.MirrorReceipt{Issuer: issuer, Epoch: "2025-03", BitmapCID: cid, Timestamp: time.Now()}
+		if err := SignMirrorReceipt(receipt, mirrorSigner); err != nil {
+			t.Fatalf("SignMirrorReceipt failed: %v", err)
+		}
+		return staticMirror{&MirroredStatusList{Receipt: receipt, Snapshot: data}}
+	}
+
+	policy := &MirrorReceiptPolicy{
+		Mirrors:   []MirrorEndpoint{{URL: "https://m1", PublicKey: mirrorSigner.PublicKeyBase64()}},
+		Threshold: 1,
+	}
+	entry := &StatusListEntry{StatusPurpose: string(StatusPurposeRevocation), StatusListIndex: "3", StatusListCredential: listID}
+
+	// A trusted mirror vouching for another issuer's snapshot is not enough
+	if _, err := checkMirrors(context.Background(), policy, mirrored("did:key:attacker"), entry, 3, time.Now()); err == nil {
+		t.Error("expected a snapshot from another issuer to be ignored")
+	}
+	result, err := checkMirrors(context.Background(), policy, mirrored("did:key:victim"), entry, 3, time.Now())
+	if err != nil {
+		t.Fatalf("checkMirrors failed: %v", err)
+	}
+	if !result.Status {
+		t.Error("expected the issuer's own snapshot to show the revocation")
+	}
+}
+
+func TestStatusListMirror_Persistence(t *testing.T) {
+	issuer := "did:key:issuer"
+	issuerSigner := newMirrorSigner(t)
+	resolver := func(string) (ed25519.PublicKey, error) { return issuerSigner.PublicKey(), nil }
+
+	f := newPublisherFixture(t)
+	f.publisher.signer = &keySigner{signer: issuerSigner, now: f.now}
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 5); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	// The publisher hands its announcement to the mirror directly
+	storage := newMirrorStorage()
+	mirrorSigner := newMirrorSigner(t)
+	mirror := NewStatusListMirror(storage, &blobFetcher{f.blobs}, resolver, mirrorSigner)
+	if err := mirror.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	f.publisher.SetMirrors([]string{"https://m1", "https://m2"}, announcementMirrors{"https://m1": mirror})
+	announcements, err := f.publisher.Flush(context.Background())
+	if err == nil {
+		t.Error("expected the unreachable mirror to be reported")
+	}
+	if len(announcements) != 1 || len(f.publisher.Pending()) != 0 {
+		t.Fatalf("expected the announcement to go out regardless, got %d announcements", len(announcements))
+	}
+	receipt, exists := mirror.Receipt(issuer, "2025-03")
+	if !exists {
+		t.Fatal("expected the mirror to hold a receipt")
+	}
+
+	// A restarted mirror serves the same receipt
+	restarted := NewStatusListMirror(storage, &blobFetcher{f.blobs}, resolver, mirrorSigner)
+	if err := restarted.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	mirrored, err := restarted.Lookup(context.Background(), list.ID)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if *mirrored.Receipt != *receipt {
+		t.Errorf("expected the receipt %+v after restart, got %+v", receipt, mirrored.Receipt)
+	}
+	if _, err := restarted.Mirror(context.Background(), announcements[0]); err != nil {
+		t.Errorf("expected the same announcement to be accepted again, got %v", err)
+	}
+}
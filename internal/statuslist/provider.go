@@ -350,4 +350,159 @@ func (p *CompositeStatusListProvider) ListStatusLists() ([]string, error) {
 	}
 	
 	return p.writer.ListStatusLists()
-}
\ No newline at end of file
+}
+
+// DeltaStatusListProvider keeps a base copy of each status list and brings it
+// up to date with signed deltas, so only changed indexes are downloaded once
+// a list has been fetched in full
+type DeltaStatusListProvider struct {
+	base             StatusListProvider
+	deltas           DeltaSource
+	keys             DeltaKeyResolver
+	compressionLevel int
+
+	cache map[string]*deltaCachedList
+	mutex sync.Mutex
+}
+
+type deltaCachedList struct {
+	list    *StatusList2021
+	bits    *BitString
+	version uint64 // 0 until a delta has been applied
+}
+
+// NewDeltaStatusListProvider creates a provider that fetches full lists from
+// base and applies deltas from the given source. keys defaults to
+// DIDKeyResolver.
+func NewDeltaStatusListProvider(base StatusListProvider, deltas DeltaSource, keys DeltaKeyResolver) *DeltaStatusListProvider {
+	if keys == nil {
+		keys = DIDKeyResolver
+	}
+
+	return &DeltaStatusListProvider{
+		base:             base,
+		deltas:           deltas,
+		keys:             keys,
+		compressionLevel: DefaultStatusListConfig().CompressionLevel,
+		cache:            make(map[string]*deltaCachedList),
+	}
+}
+
+// FetchStatusList returns the cached list with any newer deltas applied. The
+// full list is fetched from the base provider the first time, and again
+// whenever the deltas cannot be applied to the cached copy.
+func (p *DeltaStatusListProvider) FetchStatusList(url string) (*StatusList2021, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	cached, exists := p.cache[url]
+	if exists {
+		if err := p.catchUp(cached); err == nil {
+			return p.cloneStatusList(cached.list), nil
+		}
+		delete(p.cache, url)
+	}
+
+	list, err := p.base.FetchStatusList(url)
+	if err != nil {
+		return nil, err
+	}
+	bits, err := FromCompressedBase64(list.CredentialSubject.EncodedList)
+	if err != nil {
+		return nil, err
+	}
+
+	// The full list stands on its own if its deltas cannot be applied
+	cached = &deltaCachedList{list: list, bits: bits}
+	_ = p.catchUp(cached)
+	p.cache[url] = cached
+
+	return p.cloneStatusList(cached.list), nil
+}
+
+// catchUp applies the deltas published after the cached version. A freshly
+// fetched list has no known version, so the chain is joined at the latest
+// delta whose base matches its bitmap.
+func (p *DeltaStatusListProvider) catchUp(cached *deltaCachedList) error {
+	deltas, err := p.deltas.FetchDeltas(cached.list.ID, cached.version)
+	if err != nil {
+		return err
+	}
+
+	if cached.version == 0 {
+		digest := BitmapDigest(cached.bits)
+		start := len(deltas)
+		for i := len(deltas) - 1; i >= 0; i-- {
+			if deltas[i].BaseDigest == digest {
+				start = i
+				break
+			}
+		}
+		deltas = deltas[start:]
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	publicKey, err := p.keys(cached.list.Issuer)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorPermissionDenied, "failed to resolve issuer key", err.Error())
+	}
+
+	bits := cached.bits
+	version := cached.version
+	for _, delta := range deltas {
+		if delta.ListID != cached.list.ID || delta.Issuer != cached.list.Issuer {
+			return NewStatusListError(ErrorInvalidStatusList, "delta belongs to another status list")
+		}
+		if version != 0 && delta.FromVersion != version {
+			return NewStatusListError(ErrorInvalidStatusList,
+				fmt.Sprintf("missing deltas between versions %d and %d", version, delta.FromVersion))
+		}
+		if err := delta.Verify(publicKey); err != nil {
+			return err
+		}
+		if bits, err = delta.Apply(bits); err != nil {
+			return err
+		}
+		version = delta.Version
+	}
+
+	encodedList, err := bits.ToCompressedBase64(p.compressionLevel)
+	if err != nil {
+		return err
+	}
+	cached.list.CredentialSubject.EncodedList = encodedList
+	cached.bits = bits
+	cached.version = version
+	return nil
+}
+
+// Version returns the delta version a cached list is at, or 0 when it is
+// not cached or no delta has been applied to it yet
+func (p *DeltaStatusListProvider) Version(url string) uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if cached, exists := p.cache[url]; exists {
+		return cached.version
+	}
+	return 0
+}
+
+// StoreStatusList stores through the base provider
+func (p *DeltaStatusListProvider) StoreStatusList(list *StatusList2021) error {
+	return p.base.StoreStatusList(list)
+}
+
+// ListStatusLists lists through the base provider
+func (p *DeltaStatusListProvider) ListStatusLists() ([]string, error) {
+	return p.base.ListStatusLists()
+}
+
+func (p *DeltaStatusListProvider) cloneStatusList(list *StatusList2021) *StatusList2021 {
+	clone := *list
+	clone.Context = append([]string{}, list.Context...)
+	clone.Type = append([]string{}, list.Type...)
+	return &clone
+}
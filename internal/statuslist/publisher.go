@@ -0,0 +1,545 @@
+package statuslist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/store"
+	"github.com/ParichayaHQ/credence/pkg/types"
+)
+
+// StatusEpochFormat is the layout of publication epochs. It matches the
+// epoch of network events so announcements and stored bitmaps line up.
+const StatusEpochFormat = "2006-01"
+
+// revocationTopicPrefix is the gossip topic prefix for revocation
+// announcements (p2p.TopicRevocationPrefix)
+const revocationTopicPrefix = "revocations/"
+
+// RevocationTopic returns the gossip topic an issuer's announcements use
+func RevocationTopic(issuer string) string {
+	return revocationTopicPrefix + issuer
+}
+
+// BlobWriter stores content-addressed blobs, as store.BlobStore does
+type BlobWriter interface {
+	Store(ctx context.Context, data []byte) (string, error)
+}
+
+// StatusListIndex records the bitmap CID an issuer published for an epoch,
+// as store.StatusListStore does
+type StatusListIndex interface {
+	StoreStatusList(ctx context.Context, issuer, epoch, bitmapCID string) error
+}
+
+// AnnouncementIndex records the snapshot an issuer published for an epoch
+// together with the issuer's signed announcement of it, as the full node's
+// /v1/status/{issuer}/{epoch} endpoint does
+type AnnouncementIndex interface {
+	StoreAnnouncement(ctx context.Context, epoch string, announcement *events.RevocationAnnounceEvent) error
+}
+
+// Gossip publishes messages on a pubsub topic, as p2p.P2PHost does
+type Gossip interface {
+	Publish(ctx context.Context, topic string, data []byte) error
+}
+
+// EventSigner signs network events on behalf of a DID it controls, as
+// wallet.DefaultWallet does
+type EventSigner interface {
+	SignNetworkEvent(eventType events.EventType, from, to, context, payloadCID string) (*events.Event, error)
+}
+
+// PublishedStatusList is one encoded status list bitmap in a snapshot
+type PublishedStatusList struct {
+	ID          string `json:"id"`
+	Purpose     string `json:"statusPurpose"`
+	Version     uint64 `json:"version"` // Increases each time the bitmap changes
+	EncodedList string `json:"encodedList"`
+}
+
+// StatusListSnapshot is the blob an issuer publishes for an epoch: the
+// bitmaps of all of its status lists, ordered by list ID
+type StatusListSnapshot struct {
+	Issuer string                `json:"issuer"`
+	Epoch  string                `json:"epoch"`
+	Lists  []PublishedStatusList `json:"lists"`
+}
+
+// Encode returns the JSON encoding of the snapshot. Fields and lists have a
+// fixed order, so identical bitmaps always produce the same CID.
+func (s *StatusListSnapshot) Encode() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// DecodeStatusListSnapshot parses a published snapshot blob
+func DecodeStatusListSnapshot(data []byte) (*StatusListSnapshot, error) {
+	var snapshot StatusListSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, NewStatusListErrorWithDetails(ErrorEncodingError, "invalid status list snapshot", err.Error())
+	}
+	return &snapshot, nil
+}
+
+// AnnouncementMirror hands an announcement to a mirror and returns its
+// receipt, as HTTPMirrorClient does
+type AnnouncementMirror interface {
+	MirrorAnnouncement(ctx context.Context, mirrorURL string, announcement *events.RevocationAnnounceEvent) (*types.MirrorReceipt, error)
+}
+
+// PublisherConfig configures how status lists are announced
+type PublisherConfig struct {
+	// Context is the network context announcements are made in
+	Context string `json:"context"`
+
+	// BaseURL is the full node serving /v1/status/{issuer}/{epoch}; it is
+	// used for the announced status list URI when set
+	BaseURL string `json:"baseUrl"`
+}
+
+// DefaultPublisherConfig returns default publisher configuration
+func DefaultPublisherConfig() *PublisherConfig {
+	return &PublisherConfig{
+		Context: "general",
+	}
+}
+
+// StatusListPublisher pushes issuer status lists to the network. Changes are
+// batched per epoch: marking an issuer changed only queues it, and Flush
+// publishes one snapshot and announcement per queued issuer. When the epoch
+// rolls over, the previous epoch's batch is flushed before new changes are
+// queued, so every epoch records the final bitmap of that epoch.
+type StatusListPublisher struct {
+	config   *PublisherConfig
+	provider StatusListProvider
+	blobs    BlobWriter
+	index    AnnouncementIndex
+	gossip   Gossip
+	signer   EventSigner
+	now      func() time.Time
+
+	// Optional delta publication
+	deltas      DeltaSink
+	deltaSigner DeltaSigner
+
+	// Mirrors every announcement is handed to
+	mirrors      []string
+	mirrorClient AnnouncementMirror
+
+	mu        sync.Mutex
+	epoch     string                      // epoch of the pending batch
+	dirty     map[string]bool             // issuers with unpublished changes
+	published map[string]*publishedBitmap // listID -> last published bitmap
+	state     store.StateStore
+}
+
+// publisherStateKey is where a publisher keeps its progress in a state store
+const publisherStateKey = "statuslist/publisher"
+
+// publisherState is the persisted form of a publisher: the pending batch and
+// the last published version of every list
+type publisherState struct {
+	Epoch     string                         `json:"epoch"`
+	Dirty     []string                       `json:"dirty"`
+	Published map[string]*publishedListState `json:"published"`
+}
+
+type publishedListState struct {
+	Version     uint64 `json:"version"`
+	EncodedList string `json:"encodedList"`
+}
+
+// publishedBitmap is the last version of a list that was published
+type publishedBitmap struct {
+	version uint64
+	bits    *BitString
+}
+
+// listUpdate is a list version about to be published, with the delta from
+// the previous version when there is one
+type listUpdate struct {
+	listID string
+	state  *publishedBitmap
+	delta  *StatusListDelta
+}
+
+// NewStatusListPublisher creates a status list publisher. gossip may be nil
+// when only the bitmap and its index entry should be written.
+func NewStatusListPublisher(
+	config *PublisherConfig,
+	provider StatusListProvider,
+	blobs BlobWriter,
+	index AnnouncementIndex,
+	gossip Gossip,
+	signer EventSigner,
+) *StatusListPublisher {
+	if config == nil {
+		config = DefaultPublisherConfig()
+	}
+
+	return &StatusListPublisher{
+		config:    config,
+		provider:  provider,
+		blobs:     blobs,
+		index:     index,
+		gossip:    gossip,
+		signer:    signer,
+		now:       time.Now,
+		dirty:     make(map[string]bool),
+		published: make(map[string]*publishedBitmap),
+	}
+}
+
+// SetDeltaSink publishes a signed delta to sink for every list whose bitmap
+// changed since its last publication. The signer must hold the issuers'
+// keys.
+func (p *StatusListPublisher) SetDeltaSink(sink DeltaSink, signer DeltaSigner) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deltas = sink
+	p.deltaSigner = signer
+}
+
+// SetStateStore loads the pending batch and the published list versions
+// from a state store and saves them there from then on, so versions keep
+// increasing across restarts
+func (p *StatusListPublisher) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), publisherStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load publisher state: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if data != nil {
+		var state publisherState
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("failed to decode publisher state: %w", err)
+		}
+		for listID, list := range state.Published {
+			bits, err := FromCompressedBase64(list.EncodedList)
+			if err != nil {
+				return fmt.Errorf("failed to decode published list %s: %w", listID, err)
+			}
+			p.published[listID] = &publishedBitmap{version: list.Version, bits: bits}
+		}
+		for _, issuer := range state.Dirty {
+			p.dirty[issuer] = true
+		}
+		if state.Epoch != "" {
+			p.epoch = state.Epoch
+		}
+	}
+
+	p.state = stateStore
+	return nil
+}
+
+// save writes the publisher's progress to the state store. The caller holds
+// the lock.
+func (p *StatusListPublisher) save(ctx context.Context) error {
+	if p.state == nil {
+		return nil
+	}
+
+	state := &publisherState{
+		Epoch:     p.epoch,
+		Dirty:     make([]string, 0, len(p.dirty)),
+		Published: make(map[string]*publishedListState, len(p.published)),
+	}
+	for issuer := range p.dirty {
+		state.Dirty = append(state.Dirty, issuer)
+	}
+	sort.Strings(state.Dirty)
+	for listID, published := range p.published {
+		encoded, err := published.bits.ToCompressedBase64(DefaultStatusListConfig().CompressionLevel)
+		if err != nil {
+			return err
+		}
+		state.Published[listID] = &publishedListState{Version: published.version, EncodedList: encoded}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return NewStatusListErrorWithDetails(ErrorEncodingError, "failed to encode publisher state", err.Error())
+	}
+	if err := p.state.PutState(ctx, publisherStateKey, data); err != nil {
+		return NewStatusListErrorWithDetails(ErrorNetworkError, "failed to save publisher state", err.Error())
+	}
+	return nil
+}
+
+// SetMirrors hands every announcement to the given mirrors, full nodes
+// serving /v1/status/mirror, so verifiers can fall back on their receipts.
+// client defaults to an HTTPMirrorClient.
+func (p *StatusListPublisher) SetMirrors(mirrorURLs []string, client AnnouncementMirror) {
+	if client == nil {
+		client = NewHTTPMirrorClient(0)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.mirrors = mirrorURLs
+	p.mirrorClient = client
+}
+
+// MarkChanged queues an issuer for publication in the current epoch
+func (p *StatusListPublisher) MarkChanged(ctx context.Context, issuer string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	epoch := p.now().UTC().Format(StatusEpochFormat)
+	if p.epoch != "" && p.epoch != epoch && len(p.dirty) > 0 {
+		// Close out the previous epoch before queueing into the new one
+		if _, err := p.flushLocked(ctx); err != nil {
+			return err
+		}
+	}
+
+	p.epoch = epoch
+	p.dirty[issuer] = true
+	return p.save(ctx)
+}
+
+// Pending returns the issuers queued for publication
+func (p *StatusListPublisher) Pending() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	issuers := make([]string, 0, len(p.dirty))
+	for issuer := range p.dirty {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+	return issuers
+}
+
+// Flush publishes every queued issuer and returns the announcements made.
+// Issuers that fail stay queued for the next flush. Mirrors that reject an
+// announcement are reported too, but the issuer is not queued again; the
+// mirror picks up its next announcement.
+func (p *StatusListPublisher) Flush(ctx context.Context) ([]*events.RevocationAnnounceEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.flushLocked(ctx)
+}
+
+// Run flushes the pending batch every interval until the context is done
+func (p *StatusListPublisher) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Flush(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (p *StatusListPublisher) flushLocked(ctx context.Context) ([]*events.RevocationAnnounceEvent, error) {
+	issuers := make([]string, 0, len(p.dirty))
+	for issuer := range p.dirty {
+		issuers = append(issuers, issuer)
+	}
+	sort.Strings(issuers)
+
+	var announcements []*events.RevocationAnnounceEvent
+	var failures []string
+	for _, issuer := range issuers {
+		announcement, err := p.publish(ctx, issuer, p.epoch)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", issuer, err))
+			continue
+		}
+		delete(p.dirty, issuer)
+		announcements = append(announcements, announcement)
+		failures = append(failures, p.mirror(ctx, announcement)...)
+	}
+	if err := p.save(ctx); err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	if len(failures) > 0 {
+		return announcements, NewStatusListErrorWithDetails(ErrorNetworkError,
+			"failed to publish status lists", strings.Join(failures, "; "))
+	}
+	return announcements, nil
+}
+
+// publish stores the issuer's snapshot, indexes it under the epoch and
+// announces it
+func (p *StatusListPublisher) publish(ctx context.Context, issuer, epoch string) (*events.RevocationAnnounceEvent, error) {
+	snapshot, updates, err := p.snapshot(issuer, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := snapshot.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	bitmapCID, err := p.blobs.Store(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store bitmap: %w", err)
+	}
+
+	signed, err := p.signer.SignNetworkEvent(events.EventTypeRevocationAnnounce, issuer, "", p.config.Context, bitmapCID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign announcement: %w", err)
+	}
+
+	announcement := &events.RevocationAnnounceEvent{
+		Event:         *signed,
+		Issuer:        issuer,
+		StatusListURI: p.statusListURI(issuer, epoch),
+		BitmapCID:     bitmapCID,
+	}
+
+	if err := p.index.StoreAnnouncement(ctx, epoch, announcement); err != nil {
+		return nil, fmt.Errorf("failed to record bitmap: %w", err)
+	}
+
+	if err := p.commit(ctx, updates); err != nil {
+		return nil, err
+	}
+
+	if p.gossip != nil {
+		message, err := json.Marshal(announcement)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode announcement: %w", err)
+		}
+		if err := p.gossip.Publish(ctx, RevocationTopic(issuer), message); err != nil {
+			return nil, fmt.Errorf("failed to gossip announcement: %w", err)
+		}
+	}
+
+	return announcement, nil
+}
+
+// mirror hands an announcement to every mirror and describes the ones that
+// failed
+func (p *StatusListPublisher) mirror(ctx context.Context, announcement *events.RevocationAnnounceEvent) []string {
+	var failures []string
+	for _, mirrorURL := range p.mirrors {
+		if _, err := p.mirrorClient.MirrorAnnouncement(ctx, mirrorURL, announcement); err != nil {
+			failures = append(failures, fmt.Sprintf("mirror %s: %v", mirrorURL, err))
+		}
+	}
+	return failures
+}
+
+// snapshot collects the current bitmaps of all of an issuer's lists and the
+// versions they will be published as
+func (p *StatusListPublisher) snapshot(issuer, epoch string) (*StatusListSnapshot, []*listUpdate, error) {
+	listIDs, err := p.provider.ListStatusLists()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list status lists: %w", err)
+	}
+	sort.Strings(listIDs)
+
+	snapshot := &StatusListSnapshot{
+		Issuer: issuer,
+		Epoch:  epoch,
+		Lists:  []PublishedStatusList{},
+	}
+	var updates []*listUpdate
+	for _, listID := range listIDs {
+		list, err := p.provider.FetchStatusList(listID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch status list %s: %w", listID, err)
+		}
+		if list.Issuer != issuer {
+			continue
+		}
+
+		update, err := p.nextVersion(list, epoch)
+		if err != nil {
+			return nil, nil, err
+		}
+		if update != nil {
+			updates = append(updates, update)
+		}
+
+		var version uint64
+		if update != nil {
+			version = update.state.version
+		} else {
+			version = p.published[list.ID].version
+		}
+		snapshot.Lists = append(snapshot.Lists, PublishedStatusList{
+			ID:          list.ID,
+			Purpose:     list.CredentialSubject.StatusPurpose,
+			Version:     version,
+			EncodedList: list.CredentialSubject.EncodedList,
+		})
+	}
+
+	if len(snapshot.Lists) == 0 {
+		return nil, nil, NewStatusListError(ErrorListNotFound, "issuer has no status lists")
+	}
+	return snapshot, updates, nil
+}
+
+// nextVersion returns the update publishing a list's current bitmap, or nil
+// when it has not changed since it was last published
+func (p *StatusListPublisher) nextVersion(list *StatusList2021, epoch string) (*listUpdate, error) {
+	bits, err := FromCompressedBase64(list.CredentialSubject.EncodedList)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := p.published[list.ID]
+	if previous == nil {
+		return &listUpdate{listID: list.ID, state: &publishedBitmap{version: 1, bits: bits}}, nil
+	}
+	if BitmapDigest(previous.bits) == BitmapDigest(bits) {
+		return nil, nil
+	}
+
+	update := &listUpdate{listID: list.ID, state: &publishedBitmap{version: previous.version + 1, bits: bits}}
+	if p.deltas != nil {
+		update.delta = NewStatusListDelta(list, epoch, previous.version, previous.bits, bits)
+		if err := update.delta.SignAsIssuer(p.deltaSigner); err != nil {
+			return nil, err
+		}
+	}
+	return update, nil
+}
+
+// commit records the published versions once their snapshot is stored
+func (p *StatusListPublisher) commit(ctx context.Context, updates []*listUpdate) error {
+	for _, update := range updates {
+		if update.delta != nil {
+			if err := p.deltas.PublishDelta(ctx, update.delta); err != nil {
+				return err
+			}
+		}
+		p.published[update.listID] = update.state
+	}
+	return p.save(ctx)
+}
+
+// statusListURI is where verifiers can look up the announced bitmap
+func (p *StatusListPublisher) statusListURI(issuer, epoch string) string {
+	if p.config.BaseURL != "" {
+		return strings.TrimRight(p.config.BaseURL, "/") + "/v1/status/" + url.PathEscape(issuer) + "/" + url.PathEscape(epoch)
+	}
+	return issuer + "/status-lists"
+}
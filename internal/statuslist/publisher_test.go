@@ -0,0 +1,372 @@
+package statuslist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+type memoryBlobs struct {
+	blobs map[string][]byte
+	fail  bool
+}
+
+func (b *memoryBlobs) Store(ctx context.Context, data []byte) (string, error) {
+	if b.fail {
+		return "", errors.New("blob store unavailable")
+	}
+	cid, err := events.GenerateCID(data)
+	if err != nil {
+		return "", err
+	}
+	b.blobs[cid] = data
+	return cid, nil
+}
+
+func (b *memoryBlobs) Get(ctx context.Context, cid string) ([]byte, error) {
+	data, exists := b.blobs[cid]
+	if !exists {
+		return nil, store.ErrNotFoundCID(cid)
+	}
+	return data, nil
+}
+
+type indexEntry struct {
+	issuer, epoch, cid string
+}
+
+type memoryIndex struct {
+	entries       []indexEntry
+	announcements map[string]*events.RevocationAnnounceEvent
+}
+
+func (i *memoryIndex) StoreStatusList(ctx context.Context, issuer, epoch, bitmapCID string) error {
+	i.entries = append(i.entries, indexEntry{issuer, epoch, bitmapCID})
+	return nil
+}
+
+func (i *memoryIndex) StoreAnnouncement(ctx context.Context, epoch string, announcement *events.RevocationAnnounceEvent) error {
+	if i.announcements == nil {
+		i.announcements = make(map[string]*events.RevocationAnnounceEvent)
+	}
+	i.announcements[announcement.Issuer+"|"+epoch] = announcement
+	return i.StoreStatusList(ctx, announcement.Issuer, epoch, announcement.BitmapCID)
+}
+
+func (i *memoryIndex) GetAnnouncement(ctx context.Context, issuer, epoch string) (*events.RevocationAnnounceEvent, error) {
+	announcement, exists := i.announcements[issuer+"|"+epoch]
+	if !exists {
+		return nil, store.ErrNotFound
+	}
+	return announcement, nil
+}
+
+func (i *memoryIndex) GetStatusList(ctx context.Context, issuer, epoch string) (string, error) {
+	for j := len(i.entries) - 1; j >= 0; j-- {
+		if i.entries[j].issuer == issuer && i.entries[j].epoch == epoch {
+			return i.entries[j].cid, nil
+		}
+	}
+	return "", store.ErrNotFound
+}
+
+type recordingGossip struct {
+	topics   []string
+	messages [][]byte
+}
+
+func (g *recordingGossip) Publish(ctx context.Context, topic string, data []byte) error {
+	g.topics = append(g.topics, topic)
+	g.messages = append(g.messages, data)
+	return nil
+}
+
+type fakeSigner struct{}
+
+func (fakeSigner) SignNetworkEvent(eventType events.EventType, from, to, context, payloadCID string) (*events.Event, error) {
+	return &events.Event{
+		Type:       eventType,
+		From:       from,
+		To:         to,
+		Context:    context,
+		PayloadCID: payloadCID,
+		Signature:  "signed:" + payloadCID,
+	}, nil
+}
+
+func isSet(t *testing.T, bits *BitString, index int) bool {
+	t.Helper()
+	set, err := bits.Get(index)
+	if err != nil {
+		t.Fatalf("Get(%d) failed: %v", index, err)
+	}
+	return set
+}
+
+type publisherFixture struct {
+	manager   *DefaultStatusListManager
+	publisher *StatusListPublisher
+	blobs     *memoryBlobs
+	index     *memoryIndex
+	gossip    *recordingGossip
+	now       time.Time
+}
+
+func newPublisherFixture(t *testing.T) *publisherFixture {
+	t.Helper()
+
+	provider := NewInMemoryStatusListProvider()
+	f := &publisherFixture{
+		manager: NewDefaultStatusListManager(nil, provider, nil, nil, nil),
+		blobs:   &memoryBlobs{blobs: make(map[string][]byte)},
+		index:   &memoryIndex{},
+		gossip:  &recordingGossip{},
+		now:     time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC),
+	}
+	f.publisher = NewStatusListPublisher(&PublisherConfig{Context: "general", BaseURL: "http://node:8080/"},
+		provider, f.blobs, f.index, f.gossip, fakeSigner{})
+	f.publisher.now = func() time.Time { return f.now }
+	f.manager.SetPublisher(f.publisher)
+	return f
+}
+
+func TestStatusListPublisher_BatchesPerIssuer(t *testing.T) {
+	f := newPublisherFixture(t)
+	issuer := "did:key:issuer"
+
+	revocations, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	suspensions, err := f.manager.CreateStatusList(issuer, StatusPurposeSuspension, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+
+	if err := f.manager.RevokeCredential(revocations.ID, 3); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(revocations.ID, 9); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if err := f.manager.SuspendCredential(suspensions.ID, 1); err != nil {
+		t.Fatalf("SuspendCredential failed: %v", err)
+	}
+
+	// Changes are only queued until the batch is flushed
+	if pending := f.publisher.Pending(); len(pending) != 1 || pending[0] != issuer {
+		t.Fatalf("expected issuer to be queued once, got %v", pending)
+	}
+	if len(f.gossip.messages) != 0 {
+		t.Fatalf("expected nothing gossiped before flush, got %d messages", len(f.gossip.messages))
+	}
+
+	announcements, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(announcements) != 1 {
+		t.Fatalf("expected one announcement, got %d", len(announcements))
+	}
+	announcement := announcements[0]
+
+	if len(f.index.entries) != 1 {
+		t.Fatalf("expected one index entry, got %d", len(f.index.entries))
+	}
+	entry := f.index.entries[0]
+	if entry.issuer != issuer || entry.epoch != "2025-03" || entry.cid != announcement.BitmapCID {
+		t.Errorf("unexpected index entry %+v", entry)
+	}
+
+	if announcement.Type != events.EventTypeRevocationAnnounce || announcement.From != issuer ||
+		announcement.PayloadCID != announcement.BitmapCID || announcement.Signature == "" {
+		t.Errorf("announcement not signed over the bitmap: %+v", announcement.Event)
+	}
+	expectedURI := "http://node:8080/v1/status/did:key:issuer/2025-03"
+	if announcement.StatusListURI != expectedURI {
+		t.Errorf("expected status list URI %s, got %s", expectedURI, announcement.StatusListURI)
+	}
+
+	if len(f.gossip.topics) != 1 || f.gossip.topics[0] != "revocations/did:key:issuer" {
+		t.Fatalf("expected one message on the issuer's topic, got %v", f.gossip.topics)
+	}
+	var gossiped events.RevocationAnnounceEvent
+	if err := json.Unmarshal(f.gossip.messages[0], &gossiped); err != nil {
+		t.Fatalf("failed to decode gossiped announcement: %v", err)
+	}
+	if gossiped.BitmapCID != announcement.BitmapCID {
+		t.Errorf("gossiped CID %s does not match %s", gossiped.BitmapCID, announcement.BitmapCID)
+	}
+
+	// The stored blob carries every list of the issuer in its final state
+	snapshot, err := DecodeStatusListSnapshot(f.blobs.blobs[announcement.BitmapCID])
+	if err != nil {
+		t.Fatalf("DecodeStatusListSnapshot failed: %v", err)
+	}
+	if snapshot.Issuer != issuer || snapshot.Epoch != "2025-03" || len(snapshot.Lists) != 2 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	for _, list := range snapshot.Lists {
+		bits, err := FromCompressedBase64(list.EncodedList)
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", list.ID, err)
+		}
+		switch list.Purpose {
+		case string(StatusPurposeRevocation):
+			if !isSet(t, bits, 3) || !isSet(t, bits, 9) || isSet(t, bits, 1) {
+				t.Errorf("unexpected revocation bits in %s", list.ID)
+			}
+		case string(StatusPurposeSuspension):
+			if !isSet(t, bits, 1) || isSet(t, bits, 3) {
+				t.Errorf("unexpected suspension bits in %s", list.ID)
+			}
+		default:
+			t.Errorf("unexpected purpose %s", list.Purpose)
+		}
+	}
+
+	if pending := f.publisher.Pending(); len(pending) != 0 {
+		t.Errorf("expected empty batch after flush, got %v", pending)
+	}
+
+	// Unchanged bitmaps produce the same blob
+	if err := f.publisher.MarkChanged(context.Background(), issuer); err != nil {
+		t.Fatalf("MarkChanged failed: %v", err)
+	}
+	again, err := f.publisher.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if again[0].BitmapCID != announcement.BitmapCID {
+		t.Errorf("expected deterministic bitmap CID, got %s and %s", announcement.BitmapCID, again[0].BitmapCID)
+	}
+}
+
+func TestStatusListPublisher_EpochRollover(t *testing.T) {
+	f := newPublisherFixture(t)
+	issuer := "did:key:issuer"
+
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 5); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+
+	// The first change of a new epoch closes out the previous one
+	f.now = f.now.AddDate(0, 0, 3)
+	if err := f.manager.RevokeCredential(list.ID, 6); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+
+	if len(f.index.entries) != 1 || f.index.entries[0].epoch != "2025-03" {
+		t.Fatalf("expected the March batch to be flushed, got %+v", f.index.entries)
+	}
+	snapshot, err := DecodeStatusListSnapshot(f.blobs.blobs[f.index.entries[0].cid])
+	if err != nil {
+		t.Fatalf("DecodeStatusListSnapshot failed: %v", err)
+	}
+	bits, err := FromCompressedBase64(snapshot.Lists[0].EncodedList)
+	if err != nil {
+		t.Fatalf("FromCompressedBase64 failed: %v", err)
+	}
+	if !isSet(t, bits, 5) || isSet(t, bits, 6) {
+		t.Error("expected the March bitmap to exclude the April revocation")
+	}
+
+	if _, err := f.publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(f.index.entries) != 2 || f.index.entries[1].epoch != "2025-04" {
+		t.Fatalf("expected an April entry, got %+v", f.index.entries)
+	}
+}
+
+func TestStatusListPublisher_FailuresStayQueued(t *testing.T) {
+	f := newPublisherFixture(t)
+	issuer := "did:key:issuer"
+
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 0); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	// An issuer without lists fails on its own without blocking others
+	if err := f.publisher.MarkChanged(context.Background(), "did:key:unknown"); err != nil {
+		t.Fatalf("MarkChanged failed: %v", err)
+	}
+
+	f.blobs.fail = true
+	if _, err := f.publisher.Flush(context.Background()); err == nil {
+		t.Fatal("expected flush to fail while the blob store is down")
+	}
+	if pending := f.publisher.Pending(); len(pending) != 2 {
+		t.Fatalf("expected both issuers to stay queued, got %v", pending)
+	}
+
+	f.blobs.fail = false
+	announcements, err := f.publisher.Flush(context.Background())
+	if err == nil {
+		t.Fatal("expected the issuer without lists to fail")
+	}
+	if len(announcements) != 1 || announcements[0].Issuer != issuer {
+		t.Fatalf("expected the issuer to be published, got %v", announcements)
+	}
+	if pending := f.publisher.Pending(); len(pending) != 1 || pending[0] != "did:key:unknown" {
+		t.Errorf("expected only the failing issuer to stay queued, got %v", pending)
+	}
+}
+
+func TestStatusListPublisher_Persistence(t *testing.T) {
+	issuer := "did:key:issuer"
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer stateStore.Close()
+
+	f := newPublisherFixture(t)
+	if err := f.publisher.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	list, err := f.manager.CreateStatusList(issuer, StatusPurposeRevocation, 1024)
+	if err != nil {
+		t.Fatalf("CreateStatusList failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 1); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+	if _, err := f.publisher.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := f.manager.RevokeCredential(list.ID, 2); err != nil {
+		t.Fatalf("RevokeCredential failed: %v", err)
+	}
+
+	// A restarted publisher resumes the queue and the list's version
+	restarted := NewStatusListPublisher(&PublisherConfig{Context: "general"},
+		f.manager.provider, f.blobs, f.index, f.gossip, fakeSigner{})
+	restarted.now = func() time.Time { return f.now }
+	if err := restarted.SetStateStore(stateStore); err != nil {
+		t.Fatalf("SetStateStore failed: %v", err)
+	}
+	if pending := restarted.Pending(); len(pending) != 1 || pending[0] != issuer {
+		t.Fatalf("expected the issuer to stay queued across the restart, got %v", pending)
+	}
+	if _, err := restarted.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if version := restarted.published[list.ID].version; version != 2 {
+		t.Errorf("expected the list to be published as version 2, got %d", version)
+	}
+}
@@ -2,6 +2,8 @@ package statuslist
 
 import (
 	"time"
+
+	"github.com/ParichayaHQ/credence/pkg/types"
 )
 
 // StatusList2021 represents a W3C StatusList 2021 credential
@@ -101,6 +103,10 @@ type StatusResult struct {
 	
 	// LastUpdated is when the status was last updated
 	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+	
+	// Proof carries the mirror receipts when the status was read from
+	// mirrors instead of the issuer
+	Proof *types.StatusListProof `json:"proof,omitempty"`
 }
 
 // StatusListConfig contains configuration for status list management
@@ -117,6 +117,12 @@ func DefaultConfig() *Config {
 					CompressionType:       "lz4",
 					BloomFilterBitsPerKey: 10,
 				},
+				"scores": {
+					WriteBufferSize:       16,
+					MaxWriteBufferNumber:  2,
+					CompressionType:       "zstd",
+					BloomFilterBitsPerKey: 10,
+				},
 			},
 		},
 		
@@ -20,6 +20,7 @@ type FullNode struct {
 	eventStore      EventStore
 	checkpointStore CheckpointStore
 	statusStore     StatusListStore
+	stateStore      StateStore
 	blobStore       BlobStore
 	
 	// State
@@ -60,12 +61,14 @@ func (fn *FullNode) initializeStorageBackends() error {
 		fn.eventStore = sqliteStore
 		fn.checkpointStore = sqliteStore
 		fn.statusStore = sqliteStore
+		fn.stateStore = sqliteStore
 	} else {
 		fn.rocksdb = rocksdb
 		// Use RocksDB for events, checkpoints, and status lists
 		fn.eventStore = rocksdb
 		fn.checkpointStore = rocksdb
 		fn.statusStore = rocksdb
+		fn.stateStore = rocksdb
 	}
 	
 	// Initialize blob storage backend
@@ -266,6 +269,30 @@ func (fn *FullNode) GetStatusList(ctx context.Context, issuer, epoch string) (st
 	return fn.statusStore.GetStatusList(ctx, issuer, epoch)
 }
 
+// PutState implements StateStore.PutState
+func (fn *FullNode) PutState(ctx context.Context, key string, data []byte) error {
+	fn.mu.RLock()
+	defer fn.mu.RUnlock()
+	
+	if fn.closed {
+		return ErrClosed
+	}
+	
+	return fn.stateStore.PutState(ctx, key, data)
+}
+
+// GetState implements StateStore.GetState
+func (fn *FullNode) GetState(ctx context.Context, key string) ([]byte, error) {
+	fn.mu.RLock()
+	defer fn.mu.RUnlock()
+	
+	if fn.closed {
+		return nil, ErrClosed
+	}
+	
+	return fn.stateStore.GetState(ctx, key)
+}
+
 // StoreEventAndBlob atomically stores both an event and its associated blob data
 func (fn *FullNode) StoreEventAndBlob(ctx context.Context, event *events.Event, blobData []byte) (string, error) {
 	fn.mu.RLock()
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ParichayaHQ/credence/internal/events"
@@ -76,6 +77,21 @@ type StatusListStore interface {
 	Close() error
 }
 
+// ScoreHistoryStore keeps one score record per DID, context and epoch
+type ScoreHistoryStore interface {
+	// StoreScoreRecord saves a score, replacing any earlier record for the
+	// same DID, context and epoch. Negative epochs are rejected with
+	// ErrInvalidEpoch.
+	StoreScoreRecord(ctx context.Context, record *ScoreRecord) error
+	
+	// GetScoreHistory returns a DID's records in an inclusive epoch range,
+	// oldest first
+	GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*ScoreRecord, error)
+	
+	// Close cleanly shuts down the store
+	Close() error
+}
+
 // Direction indicates query direction for event indexing
 type Direction int
 
@@ -104,6 +120,36 @@ type Checkpoint struct {
 	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
+// StateStore keeps named state documents across restarts, such as the
+// registries a scorer derives from the log
+type StateStore interface {
+	// PutState saves a document, replacing any earlier one under the key
+	PutState(ctx context.Context, key string, data []byte) error
+	
+	// GetState returns the document under a key, or a not-found error
+	GetState(ctx context.Context, key string) ([]byte, error)
+	
+	// Close cleanly shuts down the store
+	Close() error
+}
+
+// ScorerStore is everything a scorer persists
+type ScorerStore interface {
+	ScoreHistoryStore
+	StateStore
+}
+
+// ScoreRecord is a computed trust score as of an epoch. Data holds the
+// scorer's full encoding of the score.
+type ScoreRecord struct {
+	DID       string          `json:"did"`
+	Context   string          `json:"context"`
+	Epoch     int64           `json:"epoch"`
+	Value     float64         `json:"value"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
 // FullNodeStore combines all storage interfaces for a full node
 type FullNodeStore interface {
 	BlobStore
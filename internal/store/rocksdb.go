@@ -52,6 +52,7 @@ const (
 	CFCheckpoints = "checkpoints"
 	CFStatusLists = "statuslists"
 	CFBlobs       = "blobs"
+	CFScores      = "scores"
 )
 
 // Key prefixes for different data types
@@ -63,6 +64,8 @@ const (
 	PrefixCheckpoint = "chk:"
 	PrefixStatus     = "status:"
 	PrefixBlob       = "blob:"
+	PrefixScore      = "score:"
+	PrefixState      = "state:"
 )
 
 // NewRocksDBStore creates a new RocksDB-backed store
@@ -94,7 +97,7 @@ func (s *RocksDBStore) open() error {
 	s.applyConfig()
 	
 	// Define column families
-	cfNames := []string{CFDefault, CFEvents, CFIndex, CFCheckpoints, CFStatusLists, CFBlobs}
+	cfNames := []string{CFDefault, CFEvents, CFIndex, CFCheckpoints, CFStatusLists, CFBlobs, CFScores}
 	cfOpts := make([]*grocksdb.Options, len(cfNames))
 	
 	for i, name := range cfNames {
@@ -704,6 +707,121 @@ func (s *RocksDBStore) GetStatusList(ctx context.Context, issuer, epoch string)
 	return string(value.Data()), nil
 }
 
+// scoreKeyPrefix is the key prefix of a DID's scores in a context. Epochs
+// are zero-padded after it so keys sort by epoch.
+func scoreKeyPrefix(did, context string) string {
+	return fmt.Sprintf("%s%s|%s|", PrefixScore, did, context)
+}
+
+// StoreScoreRecord implements ScoreHistoryStore.StoreScoreRecord
+func (s *RocksDBStore) StoreScoreRecord(ctx context.Context, record *ScoreRecord) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return ErrClosed
+	}
+	if record.Epoch < 0 {
+		return fmt.Errorf("%w: negative epoch %d", ErrInvalidEpoch, record.Epoch)
+	}
+	
+	data, err := json.Marshal(record)
+	if err != nil {
+		return ErrDatabase("marshal_score", err)
+	}
+	
+	key := fmt.Sprintf("%s%020d", scoreKeyPrefix(record.DID, record.Context), record.Epoch)
+	err = s.db.PutCF(s.writeOpts, s.cfs[CFScores], []byte(key), data)
+	if err != nil {
+		return ErrDatabaseKey("put", key, err)
+	}
+	
+	s.stats.LastActivity = time.Now()
+	
+	return nil
+}
+
+// GetScoreHistory implements ScoreHistoryStore.GetScoreHistory
+func (s *RocksDBStore) GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*ScoreRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return nil, ErrClosed
+	}
+	
+	var records []*ScoreRecord
+	
+	iterator := s.db.NewIteratorCF(s.readOpts, s.cfs[CFScores])
+	defer iterator.Close()
+	
+	prefix := scoreKeyPrefix(did, context)
+	iterator.Seek([]byte(fmt.Sprintf("%s%020d", prefix, max(fromEpoch, 0))))
+	
+	for ; iterator.Valid(); iterator.Next() {
+		key := string(iterator.Key().Data())
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		
+		var record ScoreRecord
+		if err := json.Unmarshal(iterator.Value().Data(), &record); err != nil {
+			return nil, ErrDatabaseKey("unmarshal_score", key, err)
+		}
+		
+		if record.Epoch > toEpoch {
+			break
+		}
+		
+		records = append(records, &record)
+	}
+	
+	return records, nil
+}
+
+// PutState implements StateStore.PutState
+func (s *RocksDBStore) PutState(ctx context.Context, key string, data []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return ErrClosed
+	}
+	
+	stateKey := PrefixState + key
+	if err := s.db.PutCF(s.writeOpts, s.cfs[CFDefault], []byte(stateKey), data); err != nil {
+		return ErrDatabaseKey("put", stateKey, err)
+	}
+	
+	s.stats.LastActivity = time.Now()
+	
+	return nil
+}
+
+// GetState implements StateStore.GetState
+func (s *RocksDBStore) GetState(ctx context.Context, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return nil, ErrClosed
+	}
+	
+	stateKey := PrefixState + key
+	value, err := s.db.GetCF(s.readOpts, s.cfs[CFDefault], []byte(stateKey))
+	if err != nil {
+		return nil, ErrDatabaseKey("get", stateKey, err)
+	}
+	defer value.Free()
+	
+	if !value.Exists() {
+		return nil, ErrDatabaseKey("get", stateKey, ErrNotFound)
+	}
+	
+	// The value is freed on return
+	return append([]byte(nil), value.Data()...), nil
+}
+
 // Close closes the RocksDB store
 func (s *RocksDBStore) Close() error {
 	s.mu.Lock()
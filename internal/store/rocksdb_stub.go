@@ -80,4 +80,20 @@ func (s *RocksDBStore) GetStatusList(ctx context.Context, issuer, epoch string)
 
 func (s *RocksDBStore) Close() error {
 	return nil
-}
\ No newline at end of file
+}
+
+func (s *RocksDBStore) StoreScoreRecord(ctx context.Context, record *ScoreRecord) error {
+	return fmt.Errorf("RocksDB not available")
+}
+
+func (s *RocksDBStore) GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*ScoreRecord, error) {
+	return nil, fmt.Errorf("RocksDB not available")
+}
+
+func (s *RocksDBStore) PutState(ctx context.Context, key string, data []byte) error {
+	return fmt.Errorf("RocksDB not available")
+}
+
+func (s *RocksDBStore) GetState(ctx context.Context, key string) ([]byte, error) {
+	return nil, fmt.Errorf("RocksDB not available")
+}
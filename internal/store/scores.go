@@ -0,0 +1,35 @@
+package store
+
+import (
+	"fmt"
+)
+
+// NewScoreHistoryStore opens the score history in RocksDB, falling back to
+// SQLite where RocksDB support is not compiled in
+func NewScoreHistoryStore(config *Config) (ScoreHistoryStore, error) {
+	rocksdb, err := NewRocksDBStore(config)
+	if err == nil {
+		return rocksdb, nil
+	}
+
+	sqliteStore, sqliteErr := NewSQLiteStore(config)
+	if sqliteErr != nil {
+		return nil, fmt.Errorf("failed to open score history - RocksDB: %v, SQLite: %v", err, sqliteErr)
+	}
+	return sqliteStore, nil
+}
+
+// NewScorerStore opens the scorer's score history together with its state,
+// from the same backend NewScoreHistoryStore picks
+func NewScorerStore(config *Config) (ScorerStore, error) {
+	historyStore, err := NewScoreHistoryStore(config)
+	if err != nil {
+		return nil, err
+	}
+	scorerStore, ok := historyStore.(ScorerStore)
+	if !ok {
+		historyStore.Close()
+		return nil, fmt.Errorf("score history store %T cannot keep scorer state", historyStore)
+	}
+	return scorerStore, nil
+}
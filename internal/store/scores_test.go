@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreHistoryStore(t *testing.T) {
+	config := DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+
+	history, err := NewScoreHistoryStore(config)
+	require.NoError(t, err)
+	defer history.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, epoch := range []int64{12, 3, 7, 10} {
+		require.NoError(t, history.StoreScoreRecord(ctx, &ScoreRecord{
+			DID:       "did:key:alice",
+			Context:   "default",
+			Epoch:     epoch,
+			Value:     float64(epoch),
+			Timestamp: now,
+			Data:      json.RawMessage(fmt.Sprintf(`{"epoch":%d}`, epoch)),
+		}))
+	}
+	// Other DIDs and contexts are kept apart
+	require.NoError(t, history.StoreScoreRecord(ctx, &ScoreRecord{DID: "did:key:bob", Context: "default", Epoch: 7, Value: 1, Timestamp: now}))
+	require.NoError(t, history.StoreScoreRecord(ctx, &ScoreRecord{DID: "did:key:alice", Context: "finance", Epoch: 7, Value: 1, Timestamp: now}))
+
+	t.Run("RangeQuery", func(t *testing.T) {
+		records, err := history.GetScoreHistory(ctx, "did:key:alice", "default", 5, 12)
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []int64{7, 10, 12}, []int64{records[0].Epoch, records[1].Epoch, records[2].Epoch})
+		assert.Equal(t, 10.0, records[1].Value)
+		assert.JSONEq(t, `{"epoch":10}`, string(records[1].Data))
+		assert.True(t, records[1].Timestamp.Equal(now))
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		require.NoError(t, history.StoreScoreRecord(ctx, &ScoreRecord{DID: "did:key:alice", Context: "default", Epoch: 7, Value: 70, Timestamp: now}))
+
+		records, err := history.GetScoreHistory(ctx, "did:key:alice", "default", 7, 7)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, 70.0, records[0].Value)
+		assert.Empty(t, records[0].Data)
+	})
+
+	t.Run("NegativeEpoch", func(t *testing.T) {
+		err := history.StoreScoreRecord(ctx, &ScoreRecord{DID: "did:key:alice", Context: "default", Epoch: -1, Value: 1, Timestamp: now})
+		assert.ErrorIs(t, err, ErrInvalidEpoch)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		records, err := history.GetScoreHistory(ctx, "did:key:carol", "default", 0, 100)
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+}
+
+func TestScorerStore_State(t *testing.T) {
+	config := DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+
+	scorerStore, err := NewScorerStore(config)
+	require.NoError(t, err)
+	defer scorerStore.Close()
+
+	ctx := context.Background()
+	_, err = scorerStore.GetState(ctx, "bonds")
+	assert.True(t, IsNotFound(err))
+
+	require.NoError(t, scorerStore.PutState(ctx, "bonds", []byte(`{"v":1}`)))
+	require.NoError(t, scorerStore.PutState(ctx, "bonds", []byte(`{"v":2}`)))
+	data, err := scorerStore.GetState(ctx, "bonds")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"v":2}`, string(data))
+}
@@ -81,6 +81,21 @@ func (s *SQLiteStore) initSchema() error {
 			bitmap_cid TEXT,
 			PRIMARY KEY (issuer, epoch)
 		);
+		
+		CREATE TABLE IF NOT EXISTS score_history (
+			did TEXT,
+			context TEXT,
+			epoch INTEGER,
+			value REAL,
+			timestamp DATETIME,
+			data TEXT,
+			PRIMARY KEY (did, context, epoch)
+		);
+		
+		CREATE TABLE IF NOT EXISTS state (
+			key TEXT PRIMARY KEY,
+			data BLOB
+		);
 	`
 	
 	_, err := s.db.Exec(schema)
@@ -445,6 +460,112 @@ func (s *SQLiteStore) GetStatusList(ctx context.Context, issuer, epoch string) (
 	return bitmapCID, nil
 }
 
+// StoreScoreRecord implements ScoreHistoryStore.StoreScoreRecord
+func (s *SQLiteStore) StoreScoreRecord(ctx context.Context, record *ScoreRecord) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return ErrClosed
+	}
+	if record.Epoch < 0 {
+		return fmt.Errorf("%w: negative epoch %d", ErrInvalidEpoch, record.Epoch)
+	}
+	
+	query := `
+		INSERT OR REPLACE INTO score_history (did, context, epoch, value, timestamp, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	
+	_, err := s.db.ExecContext(ctx, query,
+		record.DID,
+		record.Context,
+		record.Epoch,
+		record.Value,
+		record.Timestamp,
+		string(record.Data),
+	)
+	
+	if err != nil {
+		return fmt.Errorf("failed to store score record: %w", err)
+	}
+	
+	return nil
+}
+
+// GetScoreHistory implements ScoreHistoryStore.GetScoreHistory
+func (s *SQLiteStore) GetScoreHistory(ctx context.Context, did, context string, fromEpoch, toEpoch int64) ([]*ScoreRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return nil, ErrClosed
+	}
+	
+	query := "SELECT epoch, value, timestamp, data FROM score_history WHERE did = ? AND context = ? AND epoch >= ? AND epoch <= ? ORDER BY epoch"
+	
+	rows, err := s.db.QueryContext(ctx, query, did, context, fromEpoch, toEpoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get score history: %w", err)
+	}
+	defer rows.Close()
+	
+	var result []*ScoreRecord
+	for rows.Next() {
+		record := ScoreRecord{DID: did, Context: context}
+		var data string
+		
+		if err := rows.Scan(&record.Epoch, &record.Value, &record.Timestamp, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan score row: %w", err)
+		}
+		if data != "" {
+			record.Data = json.RawMessage(data)
+		}
+		
+		result = append(result, &record)
+	}
+	
+	return result, rows.Err()
+}
+
+// PutState implements StateStore.PutState
+func (s *SQLiteStore) PutState(ctx context.Context, key string, data []byte) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return ErrClosed
+	}
+	
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO state (key, data) VALUES (?, ?)", key, data)
+	if err != nil {
+		return fmt.Errorf("failed to store state %s: %w", key, err)
+	}
+	
+	return nil
+}
+
+// GetState implements StateStore.GetState
+func (s *SQLiteStore) GetState(ctx context.Context, key string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if s.closed {
+		return nil, ErrClosed
+	}
+	
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM state WHERE key = ?", key).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrDatabaseKey("get", key, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get state %s: %w", key, err)
+	}
+	
+	return data, nil
+}
+
 // Close implements the Close method for all interfaces
 func (s *SQLiteStore) Close() error {
 	s.mu.Lock()
@@ -0,0 +1,493 @@
+package vc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// CBOR major types
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+)
+
+// CBOR tags used by COSE and ISO 18013-5
+const (
+	CBORTagDateTime     = 0
+	CBORTagEncodedCBOR  = 24
+	CBORTagCOSESign1    = 18
+	CBORTagCOSEMac0     = 17
+	CBORTagFullDate     = 1004
+	maxCBORNestingDepth = 64
+)
+
+// CBORTag is a tagged CBOR data item. Raw holds the encoded tag as it
+// appeared in the input, so digests over embedded CBOR (tag 24) are computed
+// on the exact bytes that were signed; a tag with Raw set re-encodes to it.
+type CBORTag struct {
+	Number  uint64
+	Content interface{}
+	Raw     []byte
+}
+
+// cborUndefined is the CBOR undefined simple value
+type cborUndefined struct{}
+
+// UnmarshalCBOR decodes a single CBOR data item. Integers decode to int64
+// (uint64 when out of range), byte strings to []byte, text to string,
+// arrays to []interface{}, maps to map[interface{}]interface{} and tags to
+// CBORTag.
+func UnmarshalCBOR(data []byte) (interface{}, error) {
+	d := &cborDecoder{data: data}
+	item, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("cbor: %d bytes of trailing data", len(d.data)-d.pos)
+	}
+	return item, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("cbor: unexpected end of data")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// header reads an initial byte and its argument. indefinite is set for
+// additional information 31.
+func (d *cborDecoder) header() (major byte, info byte, arg uint64, indefinite bool, err error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+	major, info = b>>5, b&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		raw, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, false, err
+		}
+		switch len(raw) {
+		case 1:
+			arg = uint64(raw[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(raw))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(raw))
+		case 8:
+			arg = binary.BigEndian.Uint64(raw)
+		}
+		return major, info, arg, false, nil
+	case info == 31:
+		return major, info, 0, true, nil
+	}
+	return 0, 0, 0, false, fmt.Errorf("cbor: reserved additional information %d", info)
+}
+
+func (d *cborDecoder) isBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == 0xff {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORNestingDepth {
+		return nil, fmt.Errorf("cbor: nesting deeper than %d", maxCBORNestingDepth)
+	}
+
+	start := d.pos
+	major, info, arg, indefinite, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major == cborUnsigned || major == cborNegative || major == cborTag) {
+		return nil, fmt.Errorf("cbor: indefinite length not allowed for major type %d", major)
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer out of range")
+		}
+		return -1 - int64(arg), nil
+
+	case cborBytes, cborText:
+		var content []byte
+		if indefinite {
+			var buf bytes.Buffer
+			for !d.isBreak() {
+				chunkMajor, _, n, chunkIndefinite, err := d.header()
+				if err != nil {
+					return nil, err
+				}
+				if chunkMajor != major || chunkIndefinite {
+					return nil, fmt.Errorf("cbor: invalid indefinite-length string chunk")
+				}
+				chunk, err := d.read(n)
+				if err != nil {
+					return nil, err
+				}
+				buf.Write(chunk)
+			}
+			content = buf.Bytes()
+		} else {
+			chunk, err := d.read(arg)
+			if err != nil {
+				return nil, err
+			}
+			content = append([]byte(nil), chunk...)
+		}
+		if major == cborText {
+			return string(content), nil
+		}
+		return content, nil
+
+	case cborArray:
+		var items []interface{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.isBreak() {
+				break
+			}
+			if !indefinite && arg > uint64(len(d.data)-d.pos) {
+				return nil, fmt.Errorf("cbor: array length exceeds data")
+			}
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		return items, nil
+
+	case cborMap:
+		m := make(map[interface{}]interface{})
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.isBreak() {
+				break
+			}
+			if !indefinite && arg > uint64(len(d.data)-d.pos) {
+				return nil, fmt.Errorf("cbor: map length exceeds data")
+			}
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, uint64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", key)
+			}
+			if _, dup := m[key]; dup {
+				return nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+
+	case cborTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if arg == CBORTagEncodedCBOR {
+			if _, ok := content.([]byte); !ok {
+				return nil, fmt.Errorf("cbor: tag 24 must wrap a byte string")
+			}
+		}
+		return CBORTag{Number: arg, Content: content, Raw: append([]byte(nil), d.data[start:d.pos]...)}, nil
+
+	default: // cborSimple
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		case 23:
+			return cborUndefined{}, nil
+		case 25:
+			return halfToFloat(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+	}
+}
+
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var value float64
+	switch exp {
+	case 0:
+		value = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
+
+// MarshalCBOR encodes a value with core deterministic encoding: shortest
+// arguments, definite lengths and map keys sorted by their encoding
+func MarshalCBOR(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeCBOR(&buf, value, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCBORHeader(buf *bytes.Buffer, major byte, arg uint64) {
+	m := major << 5
+	switch {
+	case arg < 24:
+		buf.WriteByte(m | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.WriteByte(m | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(m | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+func encodeCBOR(buf *bytes.Buffer, value interface{}, depth int) error {
+	if depth > maxCBORNestingDepth {
+		return fmt.Errorf("cbor: nesting deeper than %d", maxCBORNestingDepth)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case cborUndefined:
+		buf.WriteByte(0xf7)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case int:
+		return encodeCBOR(buf, int64(v), depth)
+	case uint:
+		writeCBORHeader(buf, cborUnsigned, uint64(v))
+	case int64:
+		if v >= 0 {
+			writeCBORHeader(buf, cborUnsigned, uint64(v))
+		} else {
+			writeCBORHeader(buf, cborNegative, uint64(-1-v))
+		}
+	case uint64:
+		writeCBORHeader(buf, cborUnsigned, v)
+	case float64:
+		buf.WriteByte(cborSimple<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case []byte:
+		writeCBORHeader(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHeader(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case time.Time:
+		return encodeCBOR(buf, CBORTag{Number: CBORTagDateTime, Content: v.UTC().Format(time.RFC3339)}, depth)
+	case []interface{}:
+		writeCBORHeader(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buf, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case []string:
+		writeCBORHeader(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := encodeCBOR(buf, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		return encodeCBORMap(buf, len(v), func(visit func(key, value interface{})) {
+			for key, value := range v {
+				visit(key, value)
+			}
+		}, depth)
+	case map[string]interface{}:
+		return encodeCBORMap(buf, len(v), func(visit func(key, value interface{})) {
+			for key, value := range v {
+				visit(key, value)
+			}
+		}, depth)
+	case CBORTag:
+		if len(v.Raw) > 0 {
+			buf.Write(v.Raw)
+			return nil
+		}
+		writeCBORHeader(buf, cborTag, v.Number)
+		return encodeCBOR(buf, v.Content, depth+1)
+	default:
+		return fmt.Errorf("cbor: cannot encode %T", value)
+	}
+	return nil
+}
+
+func encodeCBORMap(buf *bytes.Buffer, size int, entries func(func(key, value interface{})), depth int) error {
+	type entry struct {
+		key   []byte
+		value interface{}
+	}
+	sorted := make([]entry, 0, size)
+	var err error
+	entries(func(key, value interface{}) {
+		if err != nil {
+			return
+		}
+		var keyBuf bytes.Buffer
+		if err = encodeCBOR(&keyBuf, key, depth+1); err == nil {
+			sorted = append(sorted, entry{key: keyBuf.Bytes(), value: value})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].key, sorted[j].key) < 0 })
+
+	writeCBORHeader(buf, cborMap, uint64(len(sorted)))
+	for _, e := range sorted {
+		buf.Write(e.key)
+		if err := encodeCBOR(buf, e.value, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodedCBOR wraps a value as embedded CBOR (tag 24 around its encoding)
+func EncodedCBOR(value interface{}) (CBORTag, error) {
+	encoded, err := MarshalCBOR(value)
+	if err != nil {
+		return CBORTag{}, err
+	}
+	tag := CBORTag{Number: CBORTagEncodedCBOR, Content: encoded}
+	if tag.Raw, err = MarshalCBOR(tag); err != nil {
+		return CBORTag{}, err
+	}
+	return tag, nil
+}
+
+// decodeEmbeddedCBOR decodes the content of a tag 24 item
+func decodeEmbeddedCBOR(item interface{}) (interface{}, error) {
+	tag, ok := item.(CBORTag)
+	if !ok || tag.Number != CBORTagEncodedCBOR {
+		return nil, fmt.Errorf("expected embedded CBOR (tag 24)")
+	}
+	return UnmarshalCBOR(tag.Content.([]byte))
+}
+
+// cborToJSON converts a decoded CBOR value to the generic JSON form
+// presentation definitions and scoring evaluate: byte strings become
+// base64url text and dates their RFC 3339 or full-date text
+func cborToJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case []byte:
+		return base64.RawURLEncoding.EncodeToString(v)
+	case CBORTag:
+		return cborToJSON(v.Content)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = cborToJSON(item)
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = cborToJSON(item)
+		}
+		return result
+	case cborUndefined:
+		return nil
+	}
+	return value
+}
+
+// cborInt returns a decoded CBOR integer
+func cborInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,95 @@
+//go:build ignore
+
+// gen refreshes the bundled JSON-LD contexts from their publishers. Each
+// context in manifest.json is fetched from its URL and stored byte for byte,
+// and its digest is taken over the published document, so a pinned context
+// always matches what its publisher serves. Run it with go generate from
+// internal/vc.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/vc"
+)
+
+const (
+	manifestPath = "contexts/manifest.json"
+	maxSize      = 1 << 20
+)
+
+func main() {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var entries []vc.SchemaManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Fatalf("failed to parse %s: %v", manifestPath, err)
+	}
+
+	// Fetch every context before writing any, so a failed run leaves the
+	// bundle and its manifest consistent
+	client := &http.Client{Timeout: 30 * time.Second}
+	documents := make([][]byte, len(entries))
+	for i := range entries {
+		if documents[i], err = fetch(client, entries[i].ID); err != nil {
+			log.Fatalf("%s: %v", entries[i].ID, err)
+		}
+	}
+	for i := range entries {
+		if err := os.WriteFile(filepath.Join(filepath.Dir(manifestPath), entries[i].File), documents[i], 0o644); err != nil {
+			log.Fatal(err)
+		}
+		entries[i].DigestSRI = vc.ComputeDigestSRI(documents[i])
+		log.Printf("%s %s", entries[i].ID, entries[i].DigestSRI)
+	}
+
+	var manifest bytes.Buffer
+	encoder := json.NewEncoder(&manifest)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(manifestPath, manifest.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func fetch(client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/ld+json, application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("publisher returned %s", resp.Status)
+	}
+
+	document, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(document) > maxSize {
+		return nil, fmt.Errorf("context exceeds %d bytes", maxSize)
+	}
+	if !json.Valid(document) {
+		return nil, fmt.Errorf("publisher did not return JSON")
+	}
+	return document, nil
+}
@@ -0,0 +1,400 @@
+package vc
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053)
+const (
+	COSEAlgES256 = -7
+	COSEAlgES384 = -35
+	COSEAlgES512 = -36
+	COSEAlgEdDSA = -8
+)
+
+// COSE header labels
+const (
+	coseHeaderAlg     = 1
+	coseHeaderKID     = 4
+	coseHeaderX5Chain = 33
+)
+
+// COSE key parameters (RFC 9052 section 7)
+const (
+	coseKeyKty     = 1
+	coseKeyCrv     = -1
+	coseKeyX       = -2
+	coseKeyY       = -3
+	coseKtyOKP     = 1
+	coseKtyEC2     = 2
+	coseCrvP256    = 1
+	coseCrvP384    = 2
+	coseCrvP521    = 3
+	coseCrvEd25519 = 6
+)
+
+// COSESign1 is a COSE_Sign1 message. Protected holds the serialized
+// protected header exactly as signed.
+type COSESign1 struct {
+	Protected   []byte
+	Unprotected map[interface{}]interface{}
+	Payload     []byte
+	Signature   []byte
+
+	protected map[interface{}]interface{}
+}
+
+// ParseCOSESign1 reads a decoded COSE_Sign1, tagged or untagged. A nil
+// payload is a detached payload.
+func ParseCOSESign1(item interface{}) (*COSESign1, error) {
+	if tag, ok := item.(CBORTag); ok {
+		if tag.Number != CBORTagCOSESign1 {
+			return nil, fmt.Errorf("unexpected tag %d for COSE_Sign1", tag.Number)
+		}
+		item = tag.Content
+	}
+
+	parts, ok := item.([]interface{})
+	if !ok || len(parts) != 4 {
+		return nil, fmt.Errorf("COSE_Sign1 must be an array of four elements")
+	}
+
+	msg := &COSESign1{}
+	if msg.Protected, ok = parts[0].([]byte); !ok {
+		return nil, fmt.Errorf("COSE_Sign1 protected header must be a byte string")
+	}
+	if msg.Unprotected, ok = parts[1].(map[interface{}]interface{}); !ok {
+		return nil, fmt.Errorf("COSE_Sign1 unprotected header must be a map")
+	}
+	switch payload := parts[2].(type) {
+	case []byte:
+		msg.Payload = payload
+	case nil:
+	default:
+		return nil, fmt.Errorf("COSE_Sign1 payload must be a byte string or nil")
+	}
+	if msg.Signature, ok = parts[3].([]byte); !ok {
+		return nil, fmt.Errorf("COSE_Sign1 signature must be a byte string")
+	}
+
+	msg.protected = map[interface{}]interface{}{}
+	if len(msg.Protected) > 0 {
+		header, err := UnmarshalCBOR(msg.Protected)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COSE_Sign1 protected header: %w", err)
+		}
+		if msg.protected, ok = header.(map[interface{}]interface{}); !ok {
+			return nil, fmt.Errorf("COSE_Sign1 protected header must be a map")
+		}
+	}
+	return msg, nil
+}
+
+// header returns a header parameter, preferring the protected bucket
+func (m *COSESign1) header(label int64) (interface{}, bool) {
+	if value, ok := m.protected[label]; ok {
+		return value, true
+	}
+	value, ok := m.Unprotected[label]
+	return value, ok
+}
+
+// Algorithm returns the protected alg header
+func (m *COSESign1) Algorithm() (int64, error) {
+	value, ok := m.protected[int64(coseHeaderAlg)]
+	if !ok {
+		return 0, fmt.Errorf("COSE_Sign1 has no protected alg header")
+	}
+	alg, ok := cborInt(value)
+	if !ok {
+		return 0, fmt.Errorf("COSE_Sign1 alg must be an integer")
+	}
+	return alg, nil
+}
+
+// X5Chain returns the certificates of the x5chain header, leaf first
+func (m *COSESign1) X5Chain() ([]*x509.Certificate, error) {
+	value, ok := m.header(coseHeaderX5Chain)
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 has no x5chain header")
+	}
+
+	var ders [][]byte
+	switch v := value.(type) {
+	case []byte:
+		ders = [][]byte{v}
+	case []interface{}:
+		for _, item := range v {
+			der, ok := item.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("x5chain entries must be byte strings")
+			}
+			ders = append(ders, der)
+		}
+	default:
+		return nil, fmt.Errorf("x5chain must be a byte string or array")
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(ders))
+	for _, der := range ders {
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x5chain certificate: %w", err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("x5chain is empty")
+	}
+	return certificates, nil
+}
+
+// sigStructure builds the Sig_structure a COSE_Sign1 signature covers
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	if protected == nil {
+		protected = []byte{}
+	}
+	return MarshalCBOR([]interface{}{"Signature1", protected, []byte{}, payload})
+}
+
+// Verify checks the signature with key. detachedPayload is used when the
+// message's payload is detached.
+func (m *COSESign1) Verify(key crypto.PublicKey, detachedPayload []byte) error {
+	alg, err := m.Algorithm()
+	if err != nil {
+		return err
+	}
+	payload := m.Payload
+	if payload == nil {
+		if detachedPayload == nil {
+			return fmt.Errorf("COSE_Sign1 payload is detached but none was supplied")
+		}
+		payload = detachedPayload
+	}
+	toBeSigned, err := sigStructure(m.Protected, payload)
+	if err != nil {
+		return err
+	}
+	return verifyCOSESignature(alg, key, toBeSigned, m.Signature)
+}
+
+func coseHash(alg int64) (hash.Hash, int, error) {
+	switch alg {
+	case COSEAlgES256:
+		return sha256.New(), 32, nil
+	case COSEAlgES384:
+		return sha512.New384(), 48, nil
+	case COSEAlgES512:
+		return sha512.New(), 66, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported COSE algorithm %d", alg)
+}
+
+func verifyCOSESignature(alg int64, key crypto.PublicKey, toBeSigned, signature []byte) error {
+	if alg == COSEAlgEdDSA {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("EdDSA requires an Ed25519 key, got %T", key)
+		}
+		if !ed25519.Verify(pub, toBeSigned, signature) {
+			return fmt.Errorf("COSE signature verification failed")
+		}
+		return nil
+	}
+
+	h, size, err := coseHash(alg)
+	if err != nil {
+		return err
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("algorithm %d requires an ECDSA key, got %T", alg, key)
+	}
+	if (pub.Curve.Params().BitSize+7)/8 != size {
+		return fmt.Errorf("algorithm %d does not match the %s key", alg, pub.Curve.Params().Name)
+	}
+	if len(signature) != 2*size {
+		return fmt.Errorf("ECDSA signature must be %d bytes", 2*size)
+	}
+	h.Write(toBeSigned)
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+		return fmt.Errorf("COSE signature verification failed")
+	}
+	return nil
+}
+
+// SignCOSESign1 creates a COSE_Sign1 with the alg in its protected header.
+// A detached message signs payload but carries a nil payload.
+func SignCOSESign1(alg int64, key crypto.Signer, unprotected map[interface{}]interface{}, payload []byte, detached bool) (*COSESign1, error) {
+	protectedHeader := map[interface{}]interface{}{int64(coseHeaderAlg): alg}
+	protected, err := MarshalCBOR(protectedHeader)
+	if err != nil {
+		return nil, err
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signCOSE(alg, key, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+
+	if unprotected == nil {
+		unprotected = map[interface{}]interface{}{}
+	}
+	msg := &COSESign1{Protected: protected, Unprotected: unprotected, Payload: payload, Signature: signature, protected: protectedHeader}
+	if detached {
+		msg.Payload = nil
+	}
+	return msg, nil
+}
+
+// signCOSE produces a signature in the COSE (and JWS) encoding: Ed25519
+// bytes, or fixed-width r||s for ECDSA
+func signCOSE(alg int64, key crypto.Signer, toBeSigned []byte) ([]byte, error) {
+	if alg == COSEAlgEdDSA {
+		return key.Sign(rand.Reader, toBeSigned, crypto.Hash(0))
+	}
+
+	h, size, err := coseHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("algorithm %d requires an ECDSA key", alg)
+	}
+	h.Write(toBeSigned)
+	r, s, err := ecdsa.Sign(rand.Reader, priv, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+// CBOR returns the untagged COSE_Sign1 array for encoding
+func (m *COSESign1) CBOR() []interface{} {
+	var payload interface{}
+	if m.Payload != nil {
+		payload = m.Payload
+	}
+	return []interface{}{m.Protected, m.Unprotected, payload, m.Signature}
+}
+
+// COSEKeyToPublicKey converts an EC2 or OKP COSE_Key to a public key
+func COSEKeyToPublicKey(value interface{}) (crypto.PublicKey, error) {
+	key, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("COSE_Key must be a map")
+	}
+	kty, _ := cborInt(key[int64(coseKeyKty)])
+	crv, _ := cborInt(key[int64(coseKeyCrv)])
+	x, _ := key[int64(coseKeyX)].([]byte)
+
+	switch kty {
+	case coseKtyOKP:
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("unsupported OKP COSE_Key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	case coseKtyEC2:
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch crv {
+		case coseCrvP256:
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case coseCrvP384:
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case coseCrvP521:
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC2 curve %d", crv)
+		}
+		y, ok := key[int64(coseKeyY)].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("compressed EC2 COSE_Keys are not supported")
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("EC2 coordinates must be %d bytes", size)
+		}
+		// ecdh rejects points that are not on the curve
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid EC2 COSE_Key: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported COSE_Key type %d", kty)
+}
+
+// PublicKeyToCOSEKey converts an ECDSA or Ed25519 public key to a COSE_Key
+func PublicKeyToCOSEKey(key crypto.PublicKey) (map[interface{}]interface{}, error) {
+	switch pub := key.(type) {
+	case ed25519.PublicKey:
+		return map[interface{}]interface{}{
+			int64(coseKeyKty): int64(coseKtyOKP),
+			int64(coseKeyCrv): int64(coseCrvEd25519),
+			int64(coseKeyX):   []byte(pub),
+		}, nil
+	case *ecdsa.PublicKey:
+		var crv int64
+		switch pub.Curve {
+		case elliptic.P256():
+			crv = coseCrvP256
+		case elliptic.P384():
+			crv = coseCrvP384
+		case elliptic.P521():
+			crv = coseCrvP521
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return nil, err
+		}
+		uncompressed := ecdhKey.Bytes()
+		size := (len(uncompressed) - 1) / 2
+		return map[interface{}]interface{}{
+			int64(coseKeyKty): int64(coseKtyEC2),
+			int64(coseKeyCrv): crv,
+			int64(coseKeyX):   uncompressed[1 : 1+size],
+			int64(coseKeyY):   uncompressed[1+size:],
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// coseAlgorithmName returns the JOSE name of a COSE algorithm, as
+// presentation definitions list them
+func coseAlgorithmName(alg int64) string {
+	switch alg {
+	case COSEAlgES256:
+		return "ES256"
+	case COSEAlgES384:
+		return "ES384"
+	case COSEAlgES512:
+		return "ES512"
+	case COSEAlgEdDSA:
+		return "EdDSA"
+	}
+	return fmt.Sprint(alg)
+}
@@ -0,0 +1,201 @@
+package vc
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Base contexts of the VC data model versions
+const (
+	CredentialsContextV1 = "https://www.w3.org/2018/credentials/v1"
+	CredentialsContextV2 = "https://www.w3.org/ns/credentials/v2"
+)
+
+// DataModelVersion identifies the W3C VC data model a document follows
+type DataModelVersion string
+
+const (
+	DataModelV1 DataModelVersion = "1.1"
+	DataModelV2 DataModelVersion = "2.0"
+)
+
+// Media types of secured credentials and presentations (VC-JOSE-COSE)
+const (
+	MediaTypeVCJWT   = "application/vc+jwt"
+	MediaTypeVPJWT   = "application/vp+jwt"
+	MediaTypeVCSDJWT = "application/vc+sd-jwt"
+	MediaTypeVPSDJWT = "application/vp+sd-jwt"
+	MediaTypeDCSDJWT = "application/dc+sd-jwt"
+	MediaTypeVCCOSE  = "application/vc+cose"
+	MediaTypeVPCOSE  = "application/vp+cose"
+)
+
+// JOSE typ values of credentials and presentations secured with VC-JOSE
+const (
+	VCJWTType = "vc+jwt"
+	VPJWTType = "vp+jwt"
+)
+
+// Types of enveloped credentials and presentations
+const (
+	EnvelopedCredentialType   = "EnvelopedVerifiableCredential"
+	EnvelopedPresentationType = "EnvelopedVerifiablePresentation"
+)
+
+// EnvelopedCredential wraps a credential or presentation secured by an
+// enveloping proof, such as a JWT or COSE message, in a VCDM 2.0 document.
+// The ID is a data: URL holding the media type and the secured bytes.
+type EnvelopedCredential struct {
+	Context []string `json:"@context"`
+	ID      string   `json:"id"`
+	Type    string   `json:"type"`
+}
+
+// NewEnvelopedCredential envelopes a secured credential of the given media
+// type
+func NewEnvelopedCredential(mediaType, secured string) *EnvelopedCredential {
+	return &EnvelopedCredential{
+		Context: []string{CredentialsContextV2},
+		ID:      "data:" + mediaType + "," + secured,
+		Type:    EnvelopedCredentialType,
+	}
+}
+
+// NewEnvelopedPresentation envelopes a secured presentation of the given
+// media type
+func NewEnvelopedPresentation(mediaType, secured string) *EnvelopedCredential {
+	envelope := NewEnvelopedCredential(mediaType, secured)
+	envelope.Type = EnvelopedPresentationType
+	return envelope
+}
+
+// Open returns the media type and the secured content of the envelope
+func (e *EnvelopedCredential) Open() (string, string, error) {
+	if len(e.Context) == 0 || e.Context[0] != CredentialsContextV2 {
+		return "", "", NewVCError(ErrorInvalidContext, "first @context must be "+CredentialsContextV2)
+	}
+	if e.Type != EnvelopedCredentialType && e.Type != EnvelopedPresentationType {
+		return "", "", NewVCError(ErrorInvalidCredential, "unknown envelope type "+e.Type)
+	}
+	return parseDataURL(e.ID)
+}
+
+// parseDataURL splits an RFC 2397 data: URL into its media type, without
+// parameters, and its decoded content
+func parseDataURL(dataURL string) (string, string, error) {
+	rest, ok := strings.CutPrefix(dataURL, "data:")
+	if !ok {
+		return "", "", NewVCError(ErrorInvalidCredential, "envelope id must be a data: URL")
+	}
+	header, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", NewVCError(ErrorInvalidCredential, "malformed data: URL")
+	}
+
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	encoded := false
+	for _, param := range params[1:] {
+		if param == "base64" {
+			encoded = true
+		}
+	}
+
+	if encoded {
+		content, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", "", NewVCErrorWithDetails(ErrorInvalidCredential, "malformed data: URL", err.Error())
+		}
+		return mediaType, string(content), nil
+	}
+	content, err := url.PathUnescape(data)
+	if err != nil {
+		return "", "", NewVCErrorWithDetails(ErrorInvalidCredential, "malformed data: URL", err.Error())
+	}
+	return mediaType, content, nil
+}
+
+// contextVersion returns the data model of a base context
+func contextVersion(contexts []string) (DataModelVersion, bool) {
+	if len(contexts) == 0 {
+		return "", false
+	}
+	switch contexts[0] {
+	case CredentialsContextV1:
+		return DataModelV1, true
+	case CredentialsContextV2:
+		return DataModelV2, true
+	}
+	return "", false
+}
+
+// DataModelVersion returns the data model the credential's first context
+// declares, or "" when it declares neither
+func (vc *VerifiableCredential) DataModelVersion() DataModelVersion {
+	version, _ := contextVersion(vc.Context)
+	return version
+}
+
+// DataModelVersion returns the data model the presentation's first context
+// declares, or "" when it declares neither
+func (vp *VerifiablePresentation) DataModelVersion() DataModelVersion {
+	version, _ := contextVersion(vp.Context)
+	return version
+}
+
+// ValidityStart returns when the credential becomes valid: validFrom under
+// VCDM 2.0, issuanceDate under 1.1. ok is false when it is not set.
+func (vc *VerifiableCredential) ValidityStart() (time.Time, bool, error) {
+	value := vc.IssuanceDate
+	if vc.DataModelVersion() == DataModelV2 {
+		value = vc.ValidFrom
+	}
+	return parseValidityTime(value)
+}
+
+// ValidityEnd returns when the credential stops being valid: validUntil
+// under VCDM 2.0, expirationDate under 1.1. ok is false when it is not set.
+func (vc *VerifiableCredential) ValidityEnd() (time.Time, bool, error) {
+	value := vc.ExpirationDate
+	if vc.DataModelVersion() == DataModelV2 {
+		value = vc.ValidUntil
+	}
+	return parseValidityTime(value)
+}
+
+func parseValidityTime(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, NewVCErrorWithDetails(ErrorInvalidCredential, "invalid date-time "+value, err.Error())
+	}
+	return t, true, nil
+}
+
+// checkValidityPeriod rejects a credential outside its validity period at
+// now
+func checkValidityPeriod(credential *VerifiableCredential, now time.Time) error {
+	start, hasStart, err := credential.ValidityStart()
+	if err != nil {
+		return err
+	}
+	end, hasEnd, err := credential.ValidityEnd()
+	if err != nil {
+		return err
+	}
+
+	if hasStart && hasEnd && end.Before(start) {
+		return NewVCError(ErrorInvalidCredential, "credential validity ends before it starts")
+	}
+	if hasStart && now.Before(start) {
+		return NewVCError(ErrorInvalidCredential, "credential not yet valid")
+	}
+	if hasEnd && !now.Before(end) {
+		return NewVCError(ErrorExpiredCredential, "credential has expired")
+	}
+	return nil
+}
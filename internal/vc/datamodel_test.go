@@ -0,0 +1,182 @@
+package vc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+)
+
+func v2TestCredential() *VerifiableCredential {
+	return &VerifiableCredential{
+		Context:           []string{CredentialsContextV2, "https://www.w3.org/ns/credentials/examples/v2"},
+		Type:              []string{"VerifiableCredential", "ExampleDegreeCredential"},
+		Issuer:            "did:example:issuer",
+		ValidFrom:         "2024-01-01T00:00:00Z",
+		ValidUntil:        "2030-01-01T00:00:00Z",
+		CredentialSubject: map[string]interface{}{"id": "did:example:holder"},
+		Proof:             map[string]interface{}{"type": "DataIntegrityProof"},
+	}
+}
+
+func TestDataModel_Structure(t *testing.T) {
+	verifier := &DefaultCredentialVerifier{}
+
+	credential := v2TestCredential()
+	assert.Equal(t, DataModelV2, credential.DataModelVersion())
+	require.NoError(t, verifier.validateCredentialStructure(credential))
+
+	// validFrom is optional in 2.0, unlike issuanceDate in 1.1
+	credential.ValidFrom = ""
+	assert.NoError(t, verifier.validateCredentialStructure(credential))
+
+	// The 1.1 date properties are not part of the 2.0 context
+	credential = v2TestCredential()
+	credential.IssuanceDate = "2024-01-01T00:00:00Z"
+	assert.Error(t, verifier.validateCredentialStructure(credential))
+
+	// Nor are the 2.0 ones part of 1.1
+	credential = v2TestCredential()
+	credential.Context = []string{CredentialsContextV1}
+	credential.IssuanceDate = "2024-01-01T00:00:00Z"
+	assert.Error(t, verifier.validateCredentialStructure(credential))
+	credential.ValidFrom, credential.ValidUntil = "", ""
+	assert.NoError(t, verifier.validateCredentialStructure(credential))
+	assert.Equal(t, DataModelV1, credential.DataModelVersion())
+
+	credential = v2TestCredential()
+	credential.ValidUntil = "next year"
+	assert.Error(t, verifier.validateCredentialStructure(credential))
+
+	credential.Context = []string{"https://example.org/credentials"}
+	assert.Error(t, verifier.validateCredentialStructure(credential))
+}
+
+func TestDataModel_ValidityPeriod(t *testing.T) {
+	verifier := NewDefaultCredentialVerifier(did.NewDefaultKeyManager(), did.NewMultiDIDResolver())
+	credential := v2TestCredential()
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	result, err := verifier.VerifyCredential(credential, &VerificationOptions{Now: &now})
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+	assert.Equal(t, "2.0", result.Details["dataModel"])
+
+	before := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	result, err = verifier.VerifyCredential(credential, &VerificationOptions{Now: &before})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "not yet valid")
+
+	after := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err = verifier.VerifyCredential(credential, &VerificationOptions{Now: &after})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "expired")
+
+	// 1.1 credentials expire through expirationDate
+	credential.Context = []string{CredentialsContextV1}
+	credential.ValidFrom, credential.ValidUntil = "", ""
+	credential.IssuanceDate = "2024-01-01T00:00:00Z"
+	credential.ExpirationDate = "2030-01-01T00:00:00Z"
+	result, err = verifier.VerifyCredential(credential, &VerificationOptions{Now: &after})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	result, err = verifier.VerifyCredential(credential, &VerificationOptions{Now: &now})
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+	assert.Equal(t, "1.1", result.Details["dataModel"])
+}
+
+func TestDataModel_EnvelopedCredential(t *testing.T) {
+	keyManager := did.NewDefaultKeyManager()
+	keyResolver := did.NewKeyMethodResolver(keyManager)
+	issuer, err := keyResolver.Create(context.Background(), nil)
+	require.NoError(t, err)
+	resolver := did.NewMultiDIDResolver()
+	require.NoError(t, resolver.RegisterMethod("key", keyResolver))
+
+	processor := NewJWTCredentialProcessor(keyManager, resolver)
+	verifier := NewDefaultCredentialVerifier(keyManager, resolver)
+
+	template := &CredentialTemplate{
+		Context:           []string{CredentialsContextV2},
+		Type:              []string{"VerifiableCredential", "ExampleDegreeCredential"},
+		Issuer:            issuer.DID,
+		CredentialSubject: map[string]interface{}{"id": "did:example:holder", "degree": "BSc"},
+		ValidUntil:        "2099-01-01T00:00:00Z",
+	}
+	options := &IssuanceOptions{KeyID: issuer.DIDDocument.VerificationMethod[0].ID, Algorithm: "EdDSA"}
+
+	token, err := processor.CreateJWTCredential(template, options, issuer.PrivateKey)
+	require.NoError(t, err)
+
+	parsed, err := processor.ParseJWTCredential(token)
+	require.NoError(t, err)
+	assert.Equal(t, VCJWTType, parsed.Header.Type)
+	assert.Equal(t, "vc", parsed.Header.ContentType)
+	assert.Equal(t, issuer.DID, parsed.Issuer)
+	assert.NotEmpty(t, parsed.VC.ValidFrom)
+	assert.Empty(t, parsed.VC.IssuanceDate)
+
+	// The envelope round-trips through JSON as a presentation would carry it
+	data, err := json.Marshal(NewEnvelopedCredential(MediaTypeVCJWT, token))
+	require.NoError(t, err)
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &envelope))
+
+	result, err := verifier.VerifyAny(envelope, nil)
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.Error)
+	assert.Equal(t, MediaTypeVCJWT, result.Details["mediaType"])
+	assert.Equal(t, "2.0", result.Details["dataModel"])
+	assert.Equal(t, "BSc", result.Credential.CredentialSubject.(map[string]interface{})["degree"])
+
+	// A 1.1 JWT cannot be enveloped as a 2.0 credential
+	template.Context = []string{CredentialsContextV1}
+	legacy, err := processor.CreateJWTCredential(template, options, issuer.PrivateKey)
+	require.NoError(t, err)
+	result, err = verifier.VerifyEnveloped(NewEnvelopedCredential(MediaTypeVCJWT, legacy), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+
+	// Media types must match the envelope type
+	result, err = verifier.VerifyEnveloped(NewEnvelopedPresentation(MediaTypeVCJWT, token), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	result, err = verifier.VerifyEnveloped(NewEnvelopedCredential(MediaTypeVCCOSE, "d28443a10126"), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+
+	// A presentation may carry enveloped credentials
+	presentation := &VerifiablePresentation{
+		Context:              []string{CredentialsContextV2},
+		Type:                 []string{"VerifiablePresentation"},
+		VerifiableCredential: []interface{}{envelope},
+		Proof:                map[string]interface{}{"type": "DataIntegrityProof"},
+	}
+	result, err = verifier.VerifyPresentation(presentation, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+}
+
+func TestDataModel_ParseDataURL(t *testing.T) {
+	mediaType, content, err := parseDataURL("data:application/vc+jwt,eyJhbGciOiJFUzI1NiJ9.e30.sig")
+	require.NoError(t, err)
+	assert.Equal(t, MediaTypeVCJWT, mediaType)
+	assert.Equal(t, "eyJhbGciOiJFUzI1NiJ9.e30.sig", content)
+
+	mediaType, content, err = parseDataURL("data:Application/VC+SD-JWT;base64," + base64.StdEncoding.EncodeToString([]byte("jwt~d~")))
+	require.NoError(t, err)
+	assert.Equal(t, MediaTypeVCSDJWT, mediaType)
+	assert.Equal(t, "jwt~d~", content)
+
+	_, _, err = parseDataURL("https://example.com/credential")
+	assert.Error(t, err)
+}
@@ -0,0 +1,1494 @@
+package vc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxRemoteContexts bounds the chain of remote contexts a document may
+// pull in, guarding against context cycles and amplification
+const maxRemoteContexts = 32
+
+// JSONLDError is a JSON-LD processing error, with one of the error codes of
+// the JSON-LD 1.1 API
+type JSONLDError struct {
+	Code    string
+	Message string
+}
+
+func (e *JSONLDError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func jsonldErrorf(code, format string, args ...interface{}) error {
+	return &JSONLDError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// JSONLDOptions tunes JSON-LD processing
+type JSONLDOptions struct {
+	// Base is the document's base IRI
+	Base string
+	// SafeMode fails expansion when a property or type does not expand to
+	// an absolute IRI instead of dropping it, so that no signed data is
+	// silently lost
+	SafeMode bool
+}
+
+// JSONLDProcessor implements JSON-LD 1.1 expansion, compaction and RDF
+// dataset canonicalization (URDNA2015). Contexts are resolved only through
+// its document loader.
+type JSONLDProcessor struct {
+	loader DocumentLoader
+}
+
+// NewJSONLDProcessor creates a processor resolving contexts with loader
+func NewJSONLDProcessor(loader DocumentLoader) *JSONLDProcessor {
+	return &JSONLDProcessor{loader: loader}
+}
+
+var jsonldKeywords = map[string]bool{
+	"@base": true, "@container": true, "@context": true, "@default": true, "@direction": true,
+	"@embed": true, "@explicit": true, "@graph": true, "@id": true, "@import": true,
+	"@included": true, "@index": true, "@json": true, "@language": true, "@list": true,
+	"@nest": true, "@none": true, "@omitDefault": true, "@prefix": true, "@preserve": true,
+	"@propagate": true, "@protected": true, "@requireAll": true, "@reverse": true, "@set": true,
+	"@type": true, "@value": true, "@version": true, "@vocab": true,
+}
+
+var keywordForm = regexp.MustCompile(`^@[a-zA-Z]+$`)
+
+var iriScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+
+func isKeyword(s string) bool {
+	return jsonldKeywords[s]
+}
+
+func isAbsoluteIRI(s string) bool {
+	return iriScheme.MatchString(s)
+}
+
+func isBlankNode(s string) bool {
+	return strings.HasPrefix(s, "_:")
+}
+
+// termDefinition is a term of an active context. An empty id with nullID
+// set maps the term to null, which removes it from expanded output.
+type termDefinition struct {
+	id          string
+	nullID      bool
+	reverse     bool
+	typ         string
+	language    *string
+	hasLanguage bool
+	direction   *string
+	hasDir      bool
+	container   []string
+	context     interface{}
+	hasContext  bool
+	baseURL     string
+	protected   bool
+	prefix      bool
+	index       string
+	nest        string
+}
+
+func (d *termDefinition) hasContainer(container string) bool {
+	return d != nil && containsString(d.container, container)
+}
+
+// sameAs compares definitions ignoring the protected flag, as protected
+// term redefinition allows identical definitions
+func (d *termDefinition) sameAs(other *termDefinition) bool {
+	a, b := *d, *other
+	a.protected, b.protected = false, false
+	a.baseURL, b.baseURL = "", ""
+	return reflect.DeepEqual(a, b)
+}
+
+// jsonldContext is an active context
+type jsonldContext struct {
+	base         string
+	originalBase string
+	vocab        *string
+	language     *string
+	direction    *string
+	terms        map[string]*termDefinition
+	previous     *jsonldContext
+	inverse      map[string]map[string]map[string]map[string]string
+}
+
+func newJSONLDContext(base string) *jsonldContext {
+	return &jsonldContext{base: base, originalBase: base, terms: make(map[string]*termDefinition)}
+}
+
+func (c *jsonldContext) clone() *jsonldContext {
+	clone := *c
+	clone.terms = make(map[string]*termDefinition, len(c.terms))
+	for term, def := range c.terms {
+		clone.terms[term] = def
+	}
+	clone.inverse = nil
+	return &clone
+}
+
+func (c *jsonldContext) term(term string) *termDefinition {
+	if c == nil {
+		return nil
+	}
+	return c.terms[term]
+}
+
+// contextState carries what one expansion or compaction needs besides the
+// active context
+type contextState struct {
+	processor *JSONLDProcessor
+	options   JSONLDOptions
+	documents map[string]interface{}
+}
+
+func (p *JSONLDProcessor) newState(options *JSONLDOptions) *contextState {
+	state := &contextState{processor: p, documents: make(map[string]interface{})}
+	if options != nil {
+		state.options = *options
+	}
+	return state
+}
+
+// loadContext returns the @context of the remote context document at url
+func (s *contextState) loadContext(contextURL string) (interface{}, error) {
+	if context, ok := s.documents[contextURL]; ok {
+		return context, nil
+	}
+	if s.processor.loader == nil {
+		return nil, jsonldErrorf("loading remote context failed", "no document loader for %s", contextURL)
+	}
+
+	remote, err := s.processor.loader.LoadDocument(contextURL)
+	if err != nil {
+		return nil, jsonldErrorf("loading remote context failed", "%s: %v", contextURL, err)
+	}
+	document, ok := remote.Document.(map[string]interface{})
+	if !ok {
+		return nil, jsonldErrorf("invalid remote context", "%s is not a JSON object", contextURL)
+	}
+	context, ok := document["@context"]
+	if !ok {
+		return nil, jsonldErrorf("invalid remote context", "%s has no @context", contextURL)
+	}
+	s.documents[contextURL] = context
+	return context, nil
+}
+
+// processContext implements the JSON-LD 1.1 context processing algorithm
+func (s *contextState) processContext(active *jsonldContext, local interface{}, baseURL string, remote []string, overrideProtected, propagate, validateScoped bool) (*jsonldContext, error) {
+	result := active.clone()
+
+	if m, ok := local.(map[string]interface{}); ok {
+		if value, ok := m["@propagate"]; ok {
+			b, ok := value.(bool)
+			if !ok {
+				return nil, jsonldErrorf("invalid @propagate value", "@propagate must be a boolean")
+			}
+			propagate = b
+		}
+	}
+	if !propagate && result.previous == nil {
+		result.previous = active
+	}
+
+	for _, context := range asList(local) {
+		switch ctx := context.(type) {
+		case nil:
+			if !overrideProtected {
+				for term, def := range result.terms {
+					if def != nil && def.protected {
+						return nil, jsonldErrorf("invalid context nullification", "context nullification would remove protected term %s", term)
+					}
+				}
+			}
+			previous := result
+			result = newJSONLDContext(active.originalBase)
+			if !propagate {
+				result.previous = previous
+			}
+			continue
+
+		case string:
+			contextURL, err := resolveIRI(baseURL, ctx)
+			if err != nil {
+				return nil, jsonldErrorf("loading document failed", "invalid context URL %s", ctx)
+			}
+			if !validateScoped && containsString(remote, contextURL) {
+				continue
+			}
+			if len(remote) >= maxRemoteContexts {
+				return nil, jsonldErrorf("context overflow", "more than %d remote contexts", maxRemoteContexts)
+			}
+			loaded, err := s.loadContext(contextURL)
+			if err != nil {
+				return nil, err
+			}
+			nested := append(append([]string(nil), remote...), contextURL)
+			result, err = s.processContext(result, loaded, contextURL, nested, false, true, validateScoped)
+			if err != nil {
+				return nil, err
+			}
+			continue
+
+		case map[string]interface{}:
+			var err error
+			if result, err = s.processContextDefinition(result, ctx, baseURL, remote, overrideProtected); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, jsonldErrorf("invalid local context", "context must be an object, string or null")
+		}
+	}
+	return result, nil
+}
+
+func (s *contextState) processContextDefinition(result *jsonldContext, ctx map[string]interface{}, baseURL string, remote []string, overrideProtected bool) (*jsonldContext, error) {
+	if version, ok := ctx["@version"]; ok {
+		if n, ok := jsonldNumber(version); !ok || n != 1.1 {
+			return nil, jsonldErrorf("invalid @version value", "@version must be 1.1")
+		}
+	}
+
+	if value, ok := ctx["@import"]; ok {
+		importURL, ok := value.(string)
+		if !ok {
+			return nil, jsonldErrorf("invalid @import value", "@import must be a string")
+		}
+		resolved, err := resolveIRI(baseURL, importURL)
+		if err != nil {
+			return nil, jsonldErrorf("invalid @import value", "invalid @import URL %s", importURL)
+		}
+		imported, err := s.loadContext(resolved)
+		if err != nil {
+			return nil, err
+		}
+		importMap, ok := imported.(map[string]interface{})
+		if !ok {
+			return nil, jsonldErrorf("invalid remote context", "imported context %s must be an object", resolved)
+		}
+		if _, ok := importMap["@import"]; ok {
+			return nil, jsonldErrorf("invalid context entry", "imported context %s contains @import", resolved)
+		}
+		merged := make(map[string]interface{}, len(importMap)+len(ctx))
+		for key, value := range importMap {
+			merged[key] = value
+		}
+		for key, value := range ctx {
+			merged[key] = value
+		}
+		delete(merged, "@import")
+		ctx = merged
+	}
+
+	if value, ok := ctx["@base"]; ok && len(remote) == 0 {
+		switch base := value.(type) {
+		case nil:
+			result.base = ""
+		case string:
+			if isAbsoluteIRI(base) {
+				result.base = base
+			} else {
+				resolved, err := resolveIRI(result.base, base)
+				if err != nil || result.base == "" {
+					return nil, jsonldErrorf("invalid base IRI", "cannot resolve @base %s", base)
+				}
+				result.base = resolved
+			}
+		default:
+			return nil, jsonldErrorf("invalid base IRI", "@base must be a string or null")
+		}
+	}
+
+	if value, ok := ctx["@vocab"]; ok {
+		switch vocab := value.(type) {
+		case nil:
+			result.vocab = nil
+		case string:
+			expanded, err := s.expandIRI(result, vocab, true, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			if !isAbsoluteIRI(expanded) && !isBlankNode(expanded) && expanded != "" {
+				return nil, jsonldErrorf("invalid vocab mapping", "@vocab %s is not an IRI", vocab)
+			}
+			result.vocab = &expanded
+		default:
+			return nil, jsonldErrorf("invalid vocab mapping", "@vocab must be a string or null")
+		}
+	}
+
+	if value, ok := ctx["@language"]; ok {
+		switch language := value.(type) {
+		case nil:
+			result.language = nil
+		case string:
+			lower := strings.ToLower(language)
+			result.language = &lower
+		default:
+			return nil, jsonldErrorf("invalid default language", "@language must be a string or null")
+		}
+	}
+
+	if value, ok := ctx["@direction"]; ok {
+		switch direction := value.(type) {
+		case nil:
+			result.direction = nil
+		case string:
+			if direction != "ltr" && direction != "rtl" {
+				return nil, jsonldErrorf("invalid base direction", "@direction must be ltr or rtl")
+			}
+			result.direction = &direction
+		default:
+			return nil, jsonldErrorf("invalid base direction", "@direction must be a string or null")
+		}
+	}
+
+	protected := false
+	if value, ok := ctx["@protected"]; ok {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, jsonldErrorf("invalid @protected value", "@protected must be a boolean")
+		}
+		protected = b
+	}
+
+	defined := make(map[string]bool)
+	for _, term := range sortedKeys(ctx) {
+		switch term {
+		case "@base", "@direction", "@import", "@language", "@propagate", "@protected", "@version", "@vocab":
+			continue
+		}
+		if err := s.createTermDefinition(result, ctx, term, defined, baseURL, protected, overrideProtected, remote); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+var termDefinitionKeys = map[string]bool{
+	"@id": true, "@reverse": true, "@container": true, "@context": true, "@direction": true,
+	"@index": true, "@language": true, "@nest": true, "@prefix": true, "@protected": true, "@type": true,
+}
+
+// createTermDefinition implements the JSON-LD 1.1 create term definition
+// algorithm
+func (s *contextState) createTermDefinition(active *jsonldContext, local map[string]interface{}, term string, defined map[string]bool, baseURL string, protectedDefault, overrideProtected bool, remote []string) error {
+	if done, ok := defined[term]; ok {
+		if done {
+			return nil
+		}
+		return jsonldErrorf("cyclic IRI mapping", "term %s is defined cyclically", term)
+	}
+	if term == "" {
+		return jsonldErrorf("invalid term definition", "empty term")
+	}
+	defined[term] = false
+
+	value := local[term]
+	if term == "@type" {
+		m, ok := value.(map[string]interface{})
+		if !ok || len(m) == 0 {
+			return jsonldErrorf("keyword redefinition", "@type may only be redefined as a @set container")
+		}
+		for key, v := range m {
+			switch {
+			case key == "@container" && v == "@set":
+			case key == "@protected":
+			default:
+				return jsonldErrorf("keyword redefinition", "@type may only be redefined as a @set container")
+			}
+		}
+	} else if isKeyword(term) {
+		return jsonldErrorf("keyword redefinition", "%s cannot be redefined", term)
+	} else if keywordForm.MatchString(term) {
+		// Reserved for future keywords; ignored
+		defined[term] = true
+		return nil
+	}
+
+	previous, hadPrevious := active.terms[term]
+	delete(active.terms, term)
+
+	simpleTerm := false
+	var definition map[string]interface{}
+	switch v := value.(type) {
+	case nil:
+		definition = map[string]interface{}{"@id": nil}
+	case string:
+		definition = map[string]interface{}{"@id": v}
+		simpleTerm = true
+	case map[string]interface{}:
+		definition = v
+	default:
+		return jsonldErrorf("invalid term definition", "definition of %s must be a string, object or null", term)
+	}
+
+	for key := range definition {
+		if !termDefinitionKeys[key] {
+			return jsonldErrorf("invalid term definition", "unknown entry %s in definition of %s", key, term)
+		}
+	}
+
+	def := &termDefinition{protected: protectedDefault, baseURL: baseURL}
+	if value, ok := definition["@protected"]; ok {
+		b, ok := value.(bool)
+		if !ok {
+			return jsonldErrorf("invalid @protected value", "@protected of %s must be a boolean", term)
+		}
+		def.protected = b
+	}
+
+	if value, ok := definition["@type"]; ok {
+		typ, ok := value.(string)
+		if !ok {
+			return jsonldErrorf("invalid type mapping", "@type of %s must be a string", term)
+		}
+		expanded, err := s.expandIRI(active, typ, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+		switch {
+		case expanded == "@id", expanded == "@json", expanded == "@none", expanded == "@vocab":
+		case isAbsoluteIRI(expanded):
+		default:
+			return jsonldErrorf("invalid type mapping", "@type of %s is not an IRI", term)
+		}
+		def.typ = expanded
+	}
+
+	if value, ok := definition["@reverse"]; ok {
+		if _, ok := definition["@id"]; ok {
+			return jsonldErrorf("invalid reverse property", "%s has both @id and @reverse", term)
+		}
+		if _, ok := definition["@nest"]; ok {
+			return jsonldErrorf("invalid reverse property", "%s has both @nest and @reverse", term)
+		}
+		reverse, ok := value.(string)
+		if !ok {
+			return jsonldErrorf("invalid IRI mapping", "@reverse of %s must be a string", term)
+		}
+		if keywordForm.MatchString(reverse) {
+			defined[term] = true
+			return nil
+		}
+		expanded, err := s.expandIRI(active, reverse, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+		if !isAbsoluteIRI(expanded) && !isBlankNode(expanded) {
+			return jsonldErrorf("invalid IRI mapping", "@reverse of %s is not an IRI", term)
+		}
+		def.id = expanded
+		if value, ok := definition["@container"]; ok {
+			switch value {
+			case nil, "@set", "@index":
+				if value != nil {
+					def.container = []string{value.(string)}
+				}
+			default:
+				return jsonldErrorf("invalid reverse property", "reverse property %s must use a @set or @index container", term)
+			}
+		}
+		def.reverse = true
+		return s.finishTermDefinition(active, term, def, previous, hadPrevious, defined, overrideProtected)
+	}
+
+	if value, ok := definition["@id"]; ok && value != term {
+		switch id := value.(type) {
+		case nil:
+			def.nullID = true
+		case string:
+			if !isKeyword(id) && keywordForm.MatchString(id) {
+				defined[term] = true
+				return nil
+			}
+			expanded, err := s.expandIRI(active, id, false, true, local, defined)
+			if err != nil {
+				return err
+			}
+			if !isKeyword(expanded) && !isAbsoluteIRI(expanded) && !isBlankNode(expanded) {
+				return jsonldErrorf("invalid IRI mapping", "%s does not map to an IRI", term)
+			}
+			if expanded == "@context" {
+				return jsonldErrorf("invalid keyword alias", "@context cannot be aliased")
+			}
+			def.id = expanded
+
+			if strings.Contains(strings.Trim(term, ":"), ":") || strings.Contains(term, "/") {
+				defined[term] = true
+				termIRI, err := s.expandIRI(active, term, false, true, local, defined)
+				if err != nil {
+					return err
+				}
+				if termIRI != def.id {
+					return jsonldErrorf("invalid IRI mapping", "term %s looks like an IRI other than its mapping", term)
+				}
+			}
+			if !strings.ContainsAny(term, ":/") && simpleTerm && (strings.ContainsAny(def.id[len(def.id)-1:], ":/?#[]@") || isBlankNode(def.id)) {
+				def.prefix = true
+			}
+		default:
+			return jsonldErrorf("invalid IRI mapping", "@id of %s must be a string or null", term)
+		}
+	} else if i := strings.Index(term, ":"); i > 0 {
+		prefix, suffix := term[:i], term[i+1:]
+		if _, ok := local[prefix]; ok {
+			if err := s.createTermDefinition(active, local, prefix, defined, baseURL, protectedDefault, overrideProtected, remote); err != nil {
+				return err
+			}
+		}
+		if prefixDef := active.terms[prefix]; prefixDef != nil && !prefixDef.nullID {
+			def.id = prefixDef.id + suffix
+		} else {
+			def.id = term
+		}
+	} else if strings.Contains(term, "/") {
+		expanded, err := s.expandIRI(active, term, false, true, nil, nil)
+		if err != nil {
+			return err
+		}
+		if !isAbsoluteIRI(expanded) {
+			return jsonldErrorf("invalid IRI mapping", "%s is not an IRI", term)
+		}
+		def.id = expanded
+	} else if term == "@type" {
+		def.id = "@type"
+	} else if active.vocab != nil {
+		def.id = *active.vocab + term
+	} else {
+		return jsonldErrorf("invalid IRI mapping", "%s has no IRI mapping and there is no @vocab", term)
+	}
+
+	if value, ok := definition["@container"]; ok {
+		var containers []string
+		for _, c := range asList(value) {
+			container, ok := c.(string)
+			if !ok {
+				return jsonldErrorf("invalid container mapping", "@container of %s must be strings", term)
+			}
+			switch container {
+			case "@graph", "@id", "@index", "@language", "@list", "@set", "@type":
+			default:
+				return jsonldErrorf("invalid container mapping", "unknown container %s", container)
+			}
+			containers = append(containers, container)
+		}
+		if containsString(containers, "@list") && len(containers) > 1 {
+			return jsonldErrorf("invalid container mapping", "@list cannot be combined with other containers")
+		}
+		sort.Strings(containers)
+		def.container = containers
+		if containsString(containers, "@type") {
+			if def.typ == "" {
+				def.typ = "@id"
+			}
+			if def.typ != "@id" && def.typ != "@vocab" {
+				return jsonldErrorf("invalid type mapping", "type maps require @id or @vocab typing")
+			}
+		}
+	}
+
+	if value, ok := definition["@index"]; ok {
+		index, ok := value.(string)
+		if !def.hasContainer("@index") || !ok || isKeyword(index) {
+			return jsonldErrorf("invalid term definition", "invalid @index of %s", term)
+		}
+		def.index = index
+	}
+
+	if value, ok := definition["@context"]; ok {
+		// Validate the scoped context now; it is applied during expansion
+		if _, err := s.processContext(active, value, baseURL, remote, true, true, false); err != nil {
+			return jsonldErrorf("invalid scoped context", "%s: %v", term, err)
+		}
+		def.context = value
+		def.hasContext = true
+	}
+
+	if value, ok := definition["@language"]; ok && def.typ == "" {
+		switch language := value.(type) {
+		case nil:
+		case string:
+			lower := strings.ToLower(language)
+			def.language = &lower
+		default:
+			return jsonldErrorf("invalid language mapping", "@language of %s must be a string or null", term)
+		}
+		def.hasLanguage = true
+	}
+
+	if value, ok := definition["@direction"]; ok && def.typ == "" {
+		switch direction := value.(type) {
+		case nil:
+		case string:
+			if direction != "ltr" && direction != "rtl" {
+				return jsonldErrorf("invalid base direction", "@direction of %s must be ltr or rtl", term)
+			}
+			def.direction = &direction
+		default:
+			return jsonldErrorf("invalid base direction", "@direction of %s must be a string or null", term)
+		}
+		def.hasDir = true
+	}
+
+	if value, ok := definition["@nest"]; ok {
+		nest, ok := value.(string)
+		if !ok || (isKeyword(nest) && nest != "@nest") {
+			return jsonldErrorf("invalid @nest value", "invalid @nest of %s", term)
+		}
+		def.nest = nest
+	}
+
+	if value, ok := definition["@prefix"]; ok {
+		prefix, ok := value.(bool)
+		if strings.ContainsAny(term, ":/") {
+			return jsonldErrorf("invalid term definition", "%s cannot be a prefix", term)
+		}
+		if !ok {
+			return jsonldErrorf("invalid @prefix value", "@prefix of %s must be a boolean", term)
+		}
+		if prefix && isKeyword(def.id) {
+			return jsonldErrorf("invalid term definition", "keyword alias %s cannot be a prefix", term)
+		}
+		def.prefix = prefix
+	}
+
+	return s.finishTermDefinition(active, term, def, previous, hadPrevious, defined, overrideProtected)
+}
+
+func (s *contextState) finishTermDefinition(active *jsonldContext, term string, def, previous *termDefinition, hadPrevious bool, defined map[string]bool, overrideProtected bool) error {
+	if !overrideProtected && hadPrevious && previous != nil && previous.protected {
+		if !def.sameAs(previous) {
+			return jsonldErrorf("protected term redefinition", "%s is protected", term)
+		}
+		def = previous
+	}
+	active.terms[term] = def
+	defined[term] = true
+	return nil
+}
+
+// expandIRI implements IRI expansion. It returns "" for values mapped to
+// null.
+func (s *contextState) expandIRI(active *jsonldContext, value string, documentRelative, vocab bool, local map[string]interface{}, defined map[string]bool) (string, error) {
+	if isKeyword(value) {
+		return value, nil
+	}
+	if keywordForm.MatchString(value) {
+		return "", nil
+	}
+
+	if local != nil {
+		if _, ok := local[value]; ok && !defined[value] {
+			if err := s.createTermDefinition(active, local, value, defined, active.base, false, false, nil); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if def, ok := active.terms[value]; ok && def != nil {
+		if def.nullID {
+			return "", nil
+		}
+		if vocab || isKeyword(def.id) {
+			return def.id, nil
+		}
+	}
+
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix, suffix := value[:i], value[i+1:]
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value, nil
+		}
+		if local != nil {
+			if _, ok := local[prefix]; ok && !defined[prefix] {
+				if err := s.createTermDefinition(active, local, prefix, defined, active.base, false, false, nil); err != nil {
+					return "", err
+				}
+			}
+		}
+		if def := active.terms[prefix]; def != nil && !def.nullID && def.prefix {
+			return def.id + suffix, nil
+		}
+		if isAbsoluteIRI(value) {
+			return value, nil
+		}
+	}
+
+	if vocab && active.vocab != nil {
+		return *active.vocab + value, nil
+	}
+	if documentRelative {
+		resolved, err := resolveIRI(active.base, value)
+		if err != nil {
+			return value, nil
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+// resolveIRI resolves a relative IRI reference against base
+func resolveIRI(base, ref string) (string, error) {
+	if base == "" || isAbsoluteIRI(ref) {
+		return ref, nil
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+// Expand expands a JSON-LD document, returning its expanded form
+func (p *JSONLDProcessor) Expand(document interface{}, options *JSONLDOptions) ([]interface{}, error) {
+	state := p.newState(options)
+	input, err := toJSONDocument(document)
+	if err != nil {
+		return nil, jsonldErrorf("loading document failed", "%v", err)
+	}
+	return state.expandDocument(input)
+}
+
+func (s *contextState) expandDocument(input interface{}) ([]interface{}, error) {
+	active := newJSONLDContext(s.options.Base)
+	expanded, err := s.expand(active, "", input, s.options.Base, false)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := expanded.(map[string]interface{}); ok && len(m) == 1 {
+		if graph, ok := m["@graph"]; ok {
+			expanded = graph
+		}
+	}
+	if expanded == nil {
+		return []interface{}{}, nil
+	}
+	return asList(expanded), nil
+}
+
+// expand implements the JSON-LD 1.1 expansion algorithm. An empty active
+// property stands for null.
+func (s *contextState) expand(active *jsonldContext, activeProperty string, element interface{}, baseURL string, fromMap bool) (interface{}, error) {
+	if element == nil {
+		return nil, nil
+	}
+
+	propertyDef := active.term(activeProperty)
+
+	switch e := element.(type) {
+	case []interface{}:
+		result := make([]interface{}, 0, len(e))
+		for _, item := range e {
+			expanded, err := s.expand(active, activeProperty, item, baseURL, fromMap)
+			if err != nil {
+				return nil, err
+			}
+			if propertyDef.hasContainer("@list") {
+				if list, ok := expanded.([]interface{}); ok {
+					expanded = map[string]interface{}{"@list": list}
+				}
+			}
+			if list, ok := expanded.([]interface{}); ok {
+				result = append(result, list...)
+			} else if expanded != nil {
+				result = append(result, expanded)
+			}
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		return s.expandMap(active, activeProperty, e, baseURL, fromMap)
+	}
+
+	// Scalars
+	if activeProperty == "" || activeProperty == "@graph" {
+		return nil, nil
+	}
+	if propertyDef != nil && propertyDef.hasContext {
+		scoped, err := s.processContext(active, propertyDef.context, propertyDef.baseURL, nil, false, true, true)
+		if err != nil {
+			return nil, err
+		}
+		active = scoped
+	}
+	return s.expandValue(active, activeProperty, element)
+}
+
+func (s *contextState) expandMap(active *jsonldContext, activeProperty string, element map[string]interface{}, baseURL string, fromMap bool) (interface{}, error) {
+	propertyDef := active.term(activeProperty)
+
+	// Term-scoped contexts that do not propagate end at node objects
+	if active.previous != nil && !fromMap {
+		revert := true
+		keys := sortedKeys(element)
+		for _, key := range keys {
+			expanded, err := s.expandIRI(active, key, false, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			if expanded == "@value" || (expanded == "@id" && len(keys) == 1) {
+				revert = false
+				break
+			}
+		}
+		if revert {
+			active = active.previous
+		}
+	}
+
+	var err error
+	if propertyDef != nil && propertyDef.hasContext {
+		if active, err = s.processContext(active, propertyDef.context, propertyDef.baseURL, nil, true, true, true); err != nil {
+			return nil, err
+		}
+	}
+	if context, ok := element["@context"]; ok {
+		if active, err = s.processContext(active, context, baseURL, nil, false, true, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// Type-scoped contexts apply to the node's properties but not to nested
+	// nodes
+	typeScoped := active
+	inputType := ""
+	for _, key := range sortedKeys(element) {
+		expanded, err := s.expandIRI(active, key, false, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if expanded != "@type" {
+			continue
+		}
+		var types []string
+		for _, t := range asList(element[key]) {
+			if str, ok := t.(string); ok {
+				types = append(types, str)
+			}
+		}
+		sort.Strings(types)
+		for _, term := range types {
+			if def := typeScoped.term(term); def != nil && def.hasContext {
+				if active, err = s.processContext(active, def.context, def.baseURL, nil, false, false, true); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(types) > 0 {
+			last := asList(element[key])
+			if str, ok := last[len(last)-1].(string); ok {
+				if inputType, err = s.expandIRI(active, str, false, true, nil, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+	if err := s.expandObject(active, typeScoped, activeProperty, element, result, baseURL, inputType); err != nil {
+		return nil, err
+	}
+
+	if value, ok := result["@value"]; ok {
+		for key := range result {
+			switch key {
+			case "@direction", "@index", "@language", "@type", "@value":
+			default:
+				return nil, jsonldErrorf("invalid value object", "value object has unexpected entry %s", key)
+			}
+		}
+		_, hasLanguage := result["@language"]
+		_, hasDirection := result["@direction"]
+		typ, hasType := result["@type"]
+		if hasType && (hasLanguage || hasDirection) {
+			return nil, jsonldErrorf("invalid value object", "value object has both @type and @language")
+		}
+		if typ == "@json" {
+			return result, nil
+		}
+		if value == nil {
+			return nil, nil
+		}
+		if _, ok := value.(string); !ok && (hasLanguage || hasDirection) {
+			return nil, jsonldErrorf("invalid language-tagged value", "language-tagged value must be a string")
+		}
+		if isMapOrList(value) {
+			return nil, jsonldErrorf("invalid value object value", "@value must be a scalar")
+		}
+		if hasType {
+			t, ok := typ.(string)
+			if !ok || !isAbsoluteIRI(t) {
+				return nil, jsonldErrorf("invalid typed value", "@type of a value object must be an IRI")
+			}
+		}
+	} else if typ, ok := result["@type"]; ok {
+		if _, ok := typ.([]interface{}); !ok {
+			result["@type"] = []interface{}{typ}
+		}
+	} else if _, hasSet := result["@set"]; hasSet || result["@list"] != nil {
+		for key := range result {
+			if key != "@set" && key != "@list" && key != "@index" {
+				return nil, jsonldErrorf("invalid set or list object", "unexpected entry %s", key)
+			}
+		}
+		if set, ok := result["@set"]; ok {
+			return set, nil
+		}
+	}
+
+	if _, ok := result["@language"]; ok && len(result) == 1 {
+		return nil, nil
+	}
+
+	if activeProperty == "" || activeProperty == "@graph" {
+		_, hasValue := result["@value"]
+		_, hasList := result["@list"]
+		_, hasID := result["@id"]
+		if len(result) == 0 || hasValue || hasList || (hasID && len(result) == 1) {
+			return nil, nil
+		}
+	}
+	return result, nil
+}
+
+func (s *contextState) expandObject(active, typeScoped *jsonldContext, activeProperty string, element, result map[string]interface{}, baseURL, inputType string) error {
+	var nests []string
+
+	for _, key := range sortedKeys(element) {
+		value := element[key]
+		if key == "@context" {
+			continue
+		}
+
+		expandedProperty, err := s.expandIRI(active, key, false, true, nil, nil)
+		if err != nil {
+			return err
+		}
+		if expandedProperty == "" || (!strings.Contains(expandedProperty, ":") && !isKeyword(expandedProperty)) {
+			if s.options.SafeMode {
+				return jsonldErrorf("invalid property", "%s does not expand to an IRI", key)
+			}
+			continue
+		}
+
+		if isKeyword(expandedProperty) {
+			if activeProperty == "@reverse" {
+				return jsonldErrorf("invalid reverse property map", "keyword %s in a reverse map", key)
+			}
+			if _, ok := result[expandedProperty]; ok && expandedProperty != "@included" && expandedProperty != "@type" {
+				return jsonldErrorf("colliding keywords", "%s appears more than once", expandedProperty)
+			}
+
+			var expandedValue interface{}
+			switch expandedProperty {
+			case "@id":
+				id, ok := value.(string)
+				if !ok {
+					return jsonldErrorf("invalid @id value", "@id must be a string")
+				}
+				if expandedValue, err = s.expandIRI(active, id, true, false, nil, nil); err != nil {
+					return err
+				}
+
+			case "@type":
+				var types []interface{}
+				for _, t := range asList(value) {
+					str, ok := t.(string)
+					if !ok {
+						return jsonldErrorf("invalid type value", "@type must be a string or array of strings")
+					}
+					expanded, err := s.expandIRI(typeScoped, str, true, true, nil, nil)
+					if err != nil {
+						return err
+					}
+					if s.options.SafeMode && !isAbsoluteIRI(expanded) && !isBlankNode(expanded) && !isKeyword(expanded) {
+						return jsonldErrorf("invalid type value", "type %s does not expand to an IRI", str)
+					}
+					types = append(types, expanded)
+				}
+				if _, isList := value.([]interface{}); isList {
+					expandedValue = types
+				} else if len(types) == 1 {
+					expandedValue = types[0]
+				}
+				if existing, ok := result["@type"]; ok {
+					expandedValue = append(asList(existing), asList(expandedValue)...)
+				}
+
+			case "@graph":
+				expanded, err := s.expand(active, "@graph", value, baseURL, false)
+				if err != nil {
+					return err
+				}
+				expandedValue = asList(expanded)
+
+			case "@included":
+				expanded, err := s.expand(active, "", value, baseURL, false)
+				if err != nil {
+					return err
+				}
+				included := asList(expanded)
+				for _, item := range included {
+					if !isNodeObject(item) {
+						return jsonldErrorf("invalid @included value", "@included must contain node objects")
+					}
+				}
+				if existing, ok := result["@included"]; ok {
+					included = append(asList(existing), included...)
+				}
+				expandedValue = included
+
+			case "@value":
+				if inputType == "@json" {
+					expandedValue = value
+					result["@value"] = value
+					continue
+				}
+				if isMapOrList(value) {
+					return jsonldErrorf("invalid value object value", "@value must be a scalar or null")
+				}
+				result["@value"] = value
+				continue
+
+			case "@language":
+				language, ok := value.(string)
+				if !ok {
+					return jsonldErrorf("invalid language-tagged string", "@language must be a string")
+				}
+				expandedValue = strings.ToLower(language)
+
+			case "@direction":
+				if value != "ltr" && value != "rtl" {
+					return jsonldErrorf("invalid base direction", "@direction must be ltr or rtl")
+				}
+				expandedValue = value
+
+			case "@index":
+				if _, ok := value.(string); !ok {
+					return jsonldErrorf("invalid @index value", "@index must be a string")
+				}
+				expandedValue = value
+
+			case "@list":
+				if activeProperty == "" || activeProperty == "@graph" {
+					continue
+				}
+				expanded, err := s.expand(active, activeProperty, value, baseURL, false)
+				if err != nil {
+					return err
+				}
+				expandedValue = asList(expanded)
+
+			case "@set":
+				if expandedValue, err = s.expand(active, activeProperty, value, baseURL, false); err != nil {
+					return err
+				}
+
+			case "@reverse":
+				if _, ok := value.(map[string]interface{}); !ok {
+					return jsonldErrorf("invalid @reverse value", "@reverse must be an object")
+				}
+				expanded, err := s.expand(active, "@reverse", value, baseURL, false)
+				if err != nil {
+					return err
+				}
+				reversed, _ := expanded.(map[string]interface{})
+				if inner, ok := reversed["@reverse"].(map[string]interface{}); ok {
+					for property, items := range inner {
+						addValue(result, property, items, true)
+					}
+				}
+				reverseMap, _ := result["@reverse"].(map[string]interface{})
+				for property, items := range reversed {
+					if property == "@reverse" {
+						continue
+					}
+					if reverseMap == nil {
+						reverseMap = make(map[string]interface{})
+					}
+					for _, item := range asList(items) {
+						if isValueObject(item) || isListObject(item) {
+							return jsonldErrorf("invalid reverse property value", "reverse property values must be node objects")
+						}
+						addValue(reverseMap, property, item, true)
+					}
+				}
+				if reverseMap != nil {
+					result["@reverse"] = reverseMap
+				}
+				continue
+
+			case "@nest":
+				nests = append(nests, key)
+				continue
+
+			default:
+				continue
+			}
+
+			if expandedValue != nil {
+				result[expandedProperty] = expandedValue
+			}
+			continue
+		}
+
+		def := active.term(key)
+		var expandedValue interface{}
+
+		switch {
+		case def != nil && def.typ == "@json":
+			expandedValue = map[string]interface{}{"@value": value, "@type": "@json"}
+
+		case def.hasContainer("@language") && isMap(value):
+			direction := active.direction
+			if def.hasDir {
+				direction = def.direction
+			}
+			var items []interface{}
+			languages := value.(map[string]interface{})
+			for _, language := range sortedKeys(languages) {
+				expandedLanguage, err := s.expandIRI(active, language, false, true, nil, nil)
+				if err != nil {
+					return err
+				}
+				for _, item := range asList(languages[language]) {
+					if item == nil {
+						continue
+					}
+					str, ok := item.(string)
+					if !ok {
+						return jsonldErrorf("invalid language map value", "language map values must be strings")
+					}
+					v := map[string]interface{}{"@value": str}
+					if language != "@none" && expandedLanguage != "@none" {
+						v["@language"] = strings.ToLower(language)
+					}
+					if direction != nil {
+						v["@direction"] = *direction
+					}
+					items = append(items, v)
+				}
+			}
+			expandedValue = items
+
+		case (def.hasContainer("@index") || def.hasContainer("@type") || def.hasContainer("@id")) && isMap(value):
+			items, err := s.expandIndexMap(active, key, def, value.(map[string]interface{}), baseURL)
+			if err != nil {
+				return err
+			}
+			expandedValue = items
+
+		default:
+			if expandedValue, err = s.expand(active, key, value, baseURL, false); err != nil {
+				return err
+			}
+		}
+
+		if expandedValue == nil {
+			continue
+		}
+
+		if def.hasContainer("@list") && !isListObject(expandedValue) {
+			expandedValue = map[string]interface{}{"@list": asList(expandedValue)}
+		}
+		if def.hasContainer("@graph") && !def.hasContainer("@id") && !def.hasContainer("@index") {
+			var graphs []interface{}
+			for _, item := range asList(expandedValue) {
+				graphs = append(graphs, map[string]interface{}{"@graph": asList(item)})
+			}
+			expandedValue = graphs
+		}
+
+		if def != nil && def.reverse {
+			reverseMap, _ := result["@reverse"].(map[string]interface{})
+			if reverseMap == nil {
+				reverseMap = make(map[string]interface{})
+				result["@reverse"] = reverseMap
+			}
+			for _, item := range asList(expandedValue) {
+				if isValueObject(item) || isListObject(item) {
+					return jsonldErrorf("invalid reverse property value", "reverse property values must be node objects")
+				}
+				addValue(reverseMap, expandedProperty, item, true)
+			}
+			continue
+		}
+		addValue(result, expandedProperty, expandedValue, true)
+	}
+
+	for _, nestKey := range nests {
+		for _, nested := range asList(element[nestKey]) {
+			nestedMap, ok := nested.(map[string]interface{})
+			if !ok {
+				return jsonldErrorf("invalid @nest value", "@nest values must be objects")
+			}
+			for key := range nestedMap {
+				expanded, err := s.expandIRI(active, key, false, true, nil, nil)
+				if err != nil {
+					return err
+				}
+				if expanded == "@value" {
+					return jsonldErrorf("invalid @nest value", "@nest values cannot be value objects")
+				}
+			}
+			if err := s.expandObject(active, typeScoped, activeProperty, nestedMap, result, baseURL, inputType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *contextState) expandIndexMap(active *jsonldContext, key string, def *termDefinition, value map[string]interface{}, baseURL string) ([]interface{}, error) {
+	indexKey := def.index
+	if indexKey == "" {
+		indexKey = "@index"
+	}
+
+	var items []interface{}
+	for _, index := range sortedKeys(value) {
+		mapContext := active
+		if def.hasContainer("@id") || def.hasContainer("@type") {
+			if active.previous != nil {
+				mapContext = active.previous
+			}
+		}
+		if def.hasContainer("@type") {
+			if indexDef := mapContext.term(index); indexDef != nil && indexDef.hasContext {
+				var err error
+				if mapContext, err = s.processContext(mapContext, indexDef.context, indexDef.baseURL, nil, false, true, true); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			mapContext = active
+		}
+
+		expandedIndex, err := s.expandIRI(active, index, false, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded, err := s.expand(mapContext, key, asList(value[index]), baseURL, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range asList(expanded) {
+			if def.hasContainer("@graph") && !isGraphObject(item) {
+				item = map[string]interface{}{"@graph": asList(item)}
+			}
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, jsonldErrorf("invalid index map", "index map values must be objects")
+			}
+
+			switch {
+			case def.hasContainer("@index") && indexKey != "@index" && expandedIndex != "@none":
+				reexpanded, err := s.expandValue(active, indexKey, index)
+				if err != nil {
+					return nil, err
+				}
+				expandedIndexKey, err := s.expandIRI(active, indexKey, false, true, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+				values := append([]interface{}{reexpanded}, asList(itemMap[expandedIndexKey])...)
+				itemMap[expandedIndexKey] = values
+				if isValueObject(itemMap) && len(itemMap) > 1 {
+					return nil, jsonldErrorf("invalid value object", "property-valued index on a value object")
+				}
+			case def.hasContainer("@index") && itemMap["@index"] == nil && expandedIndex != "@none":
+				itemMap["@index"] = index
+			case def.hasContainer("@id") && itemMap["@id"] == nil && expandedIndex != "@none":
+				id, err := s.expandIRI(active, index, true, false, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+				itemMap["@id"] = id
+			case def.hasContainer("@type") && expandedIndex != "@none":
+				itemMap["@type"] = append([]interface{}{expandedIndex}, asList(itemMap["@type"])...)
+			}
+			items = append(items, itemMap)
+		}
+	}
+	return items, nil
+}
+
+// expandValue implements value expansion
+func (s *contextState) expandValue(active *jsonldContext, activeProperty string, value interface{}) (interface{}, error) {
+	def := active.term(activeProperty)
+	if str, ok := value.(string); ok && def != nil {
+		switch def.typ {
+		case "@id":
+			id, err := s.expandIRI(active, str, true, false, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"@id": id}, nil
+		case "@vocab":
+			id, err := s.expandIRI(active, str, true, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"@id": id}, nil
+		}
+	}
+
+	result := map[string]interface{}{"@value": value}
+	if def != nil && def.typ != "" && def.typ != "@id" && def.typ != "@vocab" && def.typ != "@none" {
+		result["@type"] = def.typ
+		return result, nil
+	}
+	if _, ok := value.(string); ok {
+		language := active.language
+		if def != nil && def.hasLanguage {
+			language = def.language
+		}
+		if language != nil {
+			result["@language"] = *language
+		}
+		direction := active.direction
+		if def != nil && def.hasDir {
+			direction = def.direction
+		}
+		if direction != nil {
+			result["@direction"] = *direction
+		}
+	}
+	return result, nil
+}
+
+// JSON-LD value helpers
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	if value == nil {
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func isMap(value interface{}) bool {
+	_, ok := value.(map[string]interface{})
+	return ok
+}
+
+func isMapOrList(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+func isValueObject(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, has := m["@value"]
+	return has
+}
+
+func isListObject(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, has := m["@list"]
+	return has
+}
+
+func isGraphObject(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if _, has := m["@graph"]; !has {
+		return false
+	}
+	for key := range m {
+		if key != "@graph" && key != "@id" && key != "@index" && key != "@context" {
+			return false
+		}
+	}
+	return true
+}
+
+func isSimpleGraphObject(value interface{}) bool {
+	if !isGraphObject(value) {
+		return false
+	}
+	_, hasID := value.(map[string]interface{})["@id"]
+	return !hasID
+}
+
+func isNodeObject(value interface{}) bool {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasValue := m["@value"]
+	_, hasList := m["@list"]
+	_, hasSet := m["@set"]
+	return !hasValue && !hasList && !hasSet
+}
+
+// addValue appends value to the property's values, as an array when
+// asArray is set or there are several values
+func addValue(object map[string]interface{}, property string, value interface{}, asArray bool) {
+	if list, ok := value.([]interface{}); ok {
+		if len(list) == 0 && asArray {
+			if _, exists := object[property]; !exists {
+				object[property] = []interface{}{}
+			}
+		}
+		for _, item := range list {
+			addValue(object, property, item, asArray)
+		}
+		return
+	}
+
+	existing, exists := object[property]
+	if !exists {
+		if asArray {
+			object[property] = []interface{}{value}
+		} else {
+			object[property] = value
+		}
+		return
+	}
+	object[property] = append(asList(existing), value)
+}
+
+func jsonldNumber(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,906 @@
+package vc
+
+import (
+	"sort"
+	"strings"
+)
+
+// Compact expands document and compacts it against context, which may be
+// a context URL, a context object or a list of either
+func (p *JSONLDProcessor) Compact(document, context interface{}, options *JSONLDOptions) (map[string]interface{}, error) {
+	state := p.newState(options)
+	input, err := toJSONDocument(document)
+	if err != nil {
+		return nil, jsonldErrorf("loading document failed", "%v", err)
+	}
+	expanded, err := state.expandDocument(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := context.(map[string]interface{}); ok {
+		if inner, ok := m["@context"]; ok {
+			context = inner
+		}
+	}
+	if context, err = toJSONDocument(context); err != nil {
+		return nil, jsonldErrorf("invalid local context", "%v", err)
+	}
+	active, err := state.processContext(newJSONLDContext(state.options.Base), context, state.options.Base, nil, false, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	compacted, err := state.compact(active, "", expanded, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	switch c := compacted.(type) {
+	case map[string]interface{}:
+		result = c
+	case []interface{}:
+		result = make(map[string]interface{})
+		if len(c) > 0 {
+			alias, err := state.compactIRI(active, "@graph", nil, true, false)
+			if err != nil {
+				return nil, err
+			}
+			result[alias] = c
+		}
+	default:
+		result = make(map[string]interface{})
+	}
+
+	if context != nil && !isEmptyContext(context) {
+		result["@context"] = context
+	}
+	return result, nil
+}
+
+func isEmptyContext(context interface{}) bool {
+	switch c := context.(type) {
+	case map[string]interface{}:
+		return len(c) == 0
+	case []interface{}:
+		return len(c) == 0
+	}
+	return false
+}
+
+// inverseContext builds, once per active context, the index compaction
+// uses to pick the best term for an IRI: IRI -> container -> @type or
+// @language -> type or language value -> term
+func (c *jsonldContext) inverseContext() map[string]map[string]map[string]map[string]string {
+	if c.inverse != nil {
+		return c.inverse
+	}
+
+	defaultLanguage := "@none"
+	if c.language != nil {
+		defaultLanguage = *c.language
+	}
+
+	terms := make([]string, 0, len(c.terms))
+	for term := range c.terms {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) < len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+
+	setDefault := func(m map[string]string, key, term string) {
+		if _, ok := m[key]; !ok {
+			m[key] = term
+		}
+	}
+
+	result := make(map[string]map[string]map[string]map[string]string)
+	for _, term := range terms {
+		def := c.terms[term]
+		if def == nil || def.nullID {
+			continue
+		}
+
+		container := "@none"
+		if len(def.container) > 0 {
+			container = strings.Join(def.container, "")
+		}
+
+		containerMap := result[def.id]
+		if containerMap == nil {
+			containerMap = make(map[string]map[string]map[string]string)
+			result[def.id] = containerMap
+		}
+		typeLanguageMap := containerMap[container]
+		if typeLanguageMap == nil {
+			typeLanguageMap = map[string]map[string]string{
+				"@language": {},
+				"@type":     {},
+				"@any":      {"@none": term},
+			}
+			containerMap[container] = typeLanguageMap
+		}
+		languageMap := typeLanguageMap["@language"]
+		typeMap := typeLanguageMap["@type"]
+
+		switch {
+		case def.reverse:
+			setDefault(typeMap, "@reverse", term)
+		case def.typ == "@none":
+			setDefault(languageMap, "@any", term)
+			setDefault(typeMap, "@any", term)
+		case def.typ != "":
+			setDefault(typeMap, def.typ, term)
+		case def.hasLanguage && def.hasDir:
+			key := "@null"
+			switch {
+			case def.language != nil && def.direction != nil:
+				key = *def.language + "_" + *def.direction
+			case def.language != nil:
+				key = *def.language
+			case def.direction != nil:
+				key = "_" + *def.direction
+			}
+			setDefault(languageMap, key, term)
+		case def.hasLanguage:
+			key := "@null"
+			if def.language != nil {
+				key = *def.language
+			}
+			setDefault(languageMap, key, term)
+		case def.hasDir:
+			key := "@none"
+			if def.direction != nil {
+				key = "_" + *def.direction
+			}
+			setDefault(languageMap, key, term)
+		case c.direction != nil:
+			key := "_" + *c.direction
+			if c.language != nil {
+				key = *c.language + key
+			}
+			setDefault(languageMap, key, term)
+			setDefault(languageMap, "@none", term)
+			setDefault(typeMap, "@none", term)
+		default:
+			setDefault(languageMap, defaultLanguage, term)
+			setDefault(languageMap, "@none", term)
+			setDefault(typeMap, "@none", term)
+		}
+	}
+
+	c.inverse = result
+	return result
+}
+
+// selectTerm implements term selection
+func (c *jsonldContext) selectTerm(iri string, containers []string, typeLanguage string, preferred []string) string {
+	containerMap := c.inverseContext()[iri]
+	for _, container := range containers {
+		typeLanguageMap, ok := containerMap[container]
+		if !ok {
+			continue
+		}
+		valueMap := typeLanguageMap[typeLanguage]
+		for _, item := range preferred {
+			if term, ok := valueMap[item]; ok {
+				return term
+			}
+		}
+	}
+	return ""
+}
+
+// compactIRI implements IRI compaction; value is the expanded value the IRI
+// is a property of, used to choose among terms
+func (s *contextState) compactIRI(active *jsonldContext, iri string, value interface{}, vocab, reverse bool) (string, error) {
+	if iri == "" {
+		return "", nil
+	}
+
+	if vocab {
+		if _, ok := active.inverseContext()[iri]; ok {
+			if term, err := s.selectTermFor(active, iri, value, reverse); err != nil || term != "" {
+				return term, err
+			}
+		}
+	}
+
+	if vocab && active.vocab != nil && *active.vocab != "" && strings.HasPrefix(iri, *active.vocab) && len(iri) > len(*active.vocab) {
+		suffix := iri[len(*active.vocab):]
+		if _, ok := active.terms[suffix]; !ok {
+			return suffix, nil
+		}
+	}
+
+	compactIRI := ""
+	for term, def := range active.terms {
+		if def == nil || def.nullID || def.id == iri || !def.prefix || !strings.HasPrefix(iri, def.id) {
+			continue
+		}
+		candidate := term + ":" + iri[len(def.id):]
+		if compactIRI != "" && (len(candidate) > len(compactIRI) || (len(candidate) == len(compactIRI) && candidate >= compactIRI)) {
+			continue
+		}
+		if candidateDef, ok := active.terms[candidate]; !ok || (candidateDef != nil && candidateDef.id == iri && value == nil) {
+			compactIRI = candidate
+		}
+	}
+	if compactIRI != "" {
+		return compactIRI, nil
+	}
+
+	if i := strings.Index(iri, ":"); i > 0 && !strings.HasPrefix(iri[i+1:], "//") {
+		if def := active.terms[iri[:i]]; def != nil && def.prefix {
+			return "", jsonldErrorf("IRI confused with prefix", "%s would expand through the term %s", iri, iri[:i])
+		}
+	}
+
+	if !vocab {
+		return relativizeIRI(active.base, iri), nil
+	}
+	return iri, nil
+}
+
+// selectTermFor chooses the preferred containers and type or language of
+// value and selects a term for iri
+func (s *contextState) selectTermFor(active *jsonldContext, iri string, value interface{}, reverse bool) (string, error) {
+	defaultLanguage := "@none"
+	if active.language != nil {
+		defaultLanguage = *active.language
+	}
+
+	valueMap, _ := value.(map[string]interface{})
+	_, hasIndex := valueMap["@index"]
+
+	var containers []string
+	typeLanguage := "@language"
+	typeLanguageValue := "@null"
+
+	if hasIndex && !isGraphObject(value) {
+		containers = append(containers, "@index", "@index@set")
+	}
+
+	switch {
+	case reverse:
+		typeLanguage = "@type"
+		typeLanguageValue = "@reverse"
+		containers = append(containers, "@set")
+
+	case isListObject(value):
+		if !hasIndex {
+			containers = append(containers, "@list")
+		}
+		list := asList(valueMap["@list"])
+		commonType, commonLanguage := "", ""
+		if len(list) == 0 {
+			commonLanguage = defaultLanguage
+		}
+		for _, item := range list {
+			itemLanguage, itemType := "@none", "@none"
+			if isValueObject(item) {
+				itemMap := item.(map[string]interface{})
+				if direction, ok := itemMap["@direction"].(string); ok {
+					language, _ := itemMap["@language"].(string)
+					itemLanguage = language + "_" + direction
+				} else if language, ok := itemMap["@language"].(string); ok {
+					itemLanguage = language
+				} else if typ, ok := itemMap["@type"].(string); ok {
+					itemType = typ
+				} else {
+					itemLanguage = "@null"
+				}
+			} else {
+				itemType = "@id"
+			}
+
+			if commonLanguage == "" {
+				commonLanguage = itemLanguage
+			} else if commonLanguage != itemLanguage && isValueObject(item) {
+				commonLanguage = "@none"
+			}
+			if commonType == "" {
+				commonType = itemType
+			} else if commonType != itemType {
+				commonType = "@none"
+			}
+			if commonLanguage == "@none" && commonType == "@none" {
+				break
+			}
+		}
+		if commonLanguage == "" {
+			commonLanguage = "@none"
+		}
+		if commonType == "" {
+			commonType = "@none"
+		}
+		if commonType != "@none" {
+			typeLanguage = "@type"
+			typeLanguageValue = commonType
+		} else {
+			typeLanguageValue = commonLanguage
+		}
+
+	case isGraphObject(value):
+		_, hasID := valueMap["@id"]
+		if hasIndex {
+			containers = append(containers, "@graph@index", "@graph@index@set")
+		}
+		if hasID {
+			containers = append(containers, "@graph@id", "@graph@id@set")
+		}
+		containers = append(containers, "@graph", "@graph@set", "@set")
+		if !hasIndex {
+			containers = append(containers, "@graph@index", "@graph@index@set")
+		}
+		if !hasID {
+			containers = append(containers, "@graph@id", "@graph@id@set")
+		}
+		containers = append(containers, "@index", "@index@set")
+		typeLanguage = "@type"
+		typeLanguageValue = "@id"
+
+	default:
+		if isValueObject(value) {
+			language, hasLanguage := valueMap["@language"].(string)
+			direction, hasDirection := valueMap["@direction"].(string)
+			switch {
+			case (hasLanguage || hasDirection) && !hasIndex:
+				typeLanguageValue = language
+				if hasDirection {
+					typeLanguageValue = language + "_" + direction
+				}
+				containers = append(containers, "@language", "@language@set")
+			case valueMap["@type"] != nil:
+				typeLanguage = "@type"
+				typeLanguageValue, _ = valueMap["@type"].(string)
+			}
+		} else {
+			typeLanguage = "@type"
+			typeLanguageValue = "@id"
+			containers = append(containers, "@id", "@id@set", "@type", "@set@type")
+		}
+		containers = append(containers, "@set")
+	}
+
+	containers = append(containers, "@none")
+	if !hasIndex {
+		containers = append(containers, "@index", "@index@set")
+	}
+	if isValueObject(value) && len(valueMap) == 1 {
+		containers = append(containers, "@language", "@language@set")
+	}
+
+	var preferred []string
+	if typeLanguageValue == "@reverse" {
+		preferred = append(preferred, "@reverse")
+	}
+	if id, ok := valueMap["@id"].(string); ok && (typeLanguageValue == "@id" || typeLanguageValue == "@reverse") {
+		compactedID, err := s.compactIRI(active, id, nil, true, false)
+		if err != nil {
+			return "", err
+		}
+		if def := active.terms[compactedID]; def != nil && def.id == id {
+			preferred = append(preferred, "@vocab", "@id", "@none")
+		} else {
+			preferred = append(preferred, "@id", "@vocab", "@none")
+		}
+	} else {
+		preferred = append(preferred, typeLanguageValue, "@none")
+		if isListObject(value) && len(asList(valueMap["@list"])) == 0 {
+			typeLanguage = "@any"
+		}
+	}
+	preferred = append(preferred, "@any")
+	if i := strings.Index(typeLanguageValue, "_"); i >= 0 {
+		preferred = append(preferred, typeLanguageValue[i:])
+	}
+
+	return active.selectTerm(iri, containers, typeLanguage, preferred), nil
+}
+
+// relativizeIRI makes iri relative to base when it lies under base's path
+func relativizeIRI(base, iri string) string {
+	if base == "" {
+		return iri
+	}
+	if iri == base {
+		return ""
+	}
+	dir := base[:strings.LastIndex(base, "/")+1]
+	if dir == "" || !strings.HasPrefix(iri, dir) {
+		return iri
+	}
+	relative := iri[len(dir):]
+	if relative == "" || strings.Contains(strings.SplitN(relative, "/", 2)[0], ":") {
+		return iri
+	}
+	return relative
+}
+
+// compactValue implements value compaction. It returns element unchanged
+// when the value cannot be reduced to a scalar.
+func (s *contextState) compactValue(active *jsonldContext, activeProperty string, element map[string]interface{}) (interface{}, error) {
+	def := active.term(activeProperty)
+
+	language := active.language
+	if def != nil && def.hasLanguage {
+		language = def.language
+	}
+	direction := active.direction
+	if def != nil && def.hasDir {
+		direction = def.direction
+	}
+
+	_, hasIndex := element["@index"]
+	indexOK := !hasIndex || def.hasContainer("@index")
+
+	if id, ok := element["@id"].(string); ok {
+		if len(element) == 1 || (len(element) == 2 && hasIndex && def.hasContainer("@index")) {
+			switch {
+			case def != nil && def.typ == "@id":
+				return s.compactIRI(active, id, nil, false, false)
+			case def != nil && def.typ == "@vocab":
+				return s.compactIRI(active, id, nil, true, false)
+			}
+		}
+		return element, nil
+	}
+
+	value := element["@value"]
+	typ, hasType := element["@type"].(string)
+	elementLanguage, hasLanguage := element["@language"].(string)
+	elementDirection, hasDirection := element["@direction"].(string)
+
+	switch {
+	case hasType && def != nil && def.typ == typ:
+		if indexOK || typ == "@json" {
+			return value, nil
+		}
+	case def != nil && def.typ == "@none", hasType:
+		return element, nil
+	case !isString(value):
+		if indexOK && !hasLanguage && !hasDirection {
+			return value, nil
+		}
+	case hasLanguage || hasDirection:
+		if indexOK && equalFoldPtr(language, elementLanguage, hasLanguage) && equalFoldPtr(direction, elementDirection, hasDirection) {
+			return value, nil
+		}
+	default:
+		if indexOK && language == nil && direction == nil {
+			return value, nil
+		}
+	}
+	return element, nil
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func equalFoldPtr(expected *string, actual string, has bool) bool {
+	if expected == nil {
+		return !has
+	}
+	return has && strings.EqualFold(*expected, actual)
+}
+
+// compact implements the JSON-LD 1.1 compaction algorithm over an expanded
+// element
+func (s *contextState) compact(active *jsonldContext, activeProperty string, element interface{}, compactArrays bool) (interface{}, error) {
+	switch e := element.(type) {
+	case []interface{}:
+		result := make([]interface{}, 0, len(e))
+		for _, item := range e {
+			compacted, err := s.compact(active, activeProperty, item, compactArrays)
+			if err != nil {
+				return nil, err
+			}
+			if compacted != nil {
+				result = append(result, compacted)
+			}
+		}
+		def := active.term(activeProperty)
+		if len(result) == 1 && compactArrays && activeProperty != "@graph" && activeProperty != "@set" && !def.hasContainer("@list") && !def.hasContainer("@set") {
+			return result[0], nil
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		return s.compactMap(active, activeProperty, e, compactArrays)
+	}
+	return element, nil
+}
+
+func (s *contextState) compactMap(active *jsonldContext, activeProperty string, element map[string]interface{}, compactArrays bool) (interface{}, error) {
+	if active.previous != nil {
+		_, hasID := element["@id"]
+		if !isValueObject(element) && !(hasID && len(element) == 1) {
+			active = active.previous
+		}
+	}
+
+	var err error
+	if def := active.term(activeProperty); def != nil && def.hasContext {
+		if active, err = s.processContext(active, def.context, def.baseURL, nil, true, true, true); err != nil {
+			return nil, err
+		}
+	}
+
+	_, hasID := element["@id"]
+	if isValueObject(element) || hasID {
+		compacted, err := s.compactValue(active, activeProperty, element)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := compacted.(map[string]interface{}); !ok {
+			return compacted, nil
+		}
+		if def := active.term(activeProperty); def != nil && def.typ == "@json" {
+			return compacted, nil
+		}
+	}
+
+	if isListObject(element) && active.term(activeProperty).hasContainer("@list") {
+		return s.compact(active, activeProperty, element["@list"], compactArrays)
+	}
+
+	insideReverse := activeProperty == "@reverse"
+	result := make(map[string]interface{})
+
+	typeScoped := active
+	if types, ok := element["@type"]; ok {
+		var compactedTypes []string
+		for _, t := range asList(types) {
+			compacted, err := s.compactIRI(typeScoped, t.(string), nil, true, false)
+			if err != nil {
+				return nil, err
+			}
+			compactedTypes = append(compactedTypes, compacted)
+		}
+		sort.Strings(compactedTypes)
+		for _, term := range compactedTypes {
+			if def := typeScoped.term(term); def != nil && def.hasContext {
+				if active, err = s.processContext(active, def.context, def.baseURL, nil, false, false, true); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, expandedProperty := range sortedKeys(element) {
+		expandedValue := element[expandedProperty]
+
+		switch expandedProperty {
+		case "@id":
+			compacted, err := s.compactIRI(active, expandedValue.(string), nil, false, false)
+			if err != nil {
+				return nil, err
+			}
+			alias, err := s.compactIRI(active, "@id", nil, true, false)
+			if err != nil {
+				return nil, err
+			}
+			result[alias] = compacted
+			continue
+
+		case "@type":
+			var compacted []interface{}
+			for _, t := range asList(expandedValue) {
+				c, err := s.compactIRI(typeScoped, t.(string), nil, true, false)
+				if err != nil {
+					return nil, err
+				}
+				compacted = append(compacted, c)
+			}
+			alias, err := s.compactIRI(active, "@type", nil, true, false)
+			if err != nil {
+				return nil, err
+			}
+			asArray := active.term(alias).hasContainer("@set") || !compactArrays
+			var value interface{} = compacted
+			if len(compacted) == 1 && !asArray {
+				value = compacted[0]
+			}
+			result[alias] = value
+			continue
+
+		case "@reverse":
+			compacted, err := s.compact(active, "@reverse", expandedValue, compactArrays)
+			if err != nil {
+				return nil, err
+			}
+			reverseMap, _ := compacted.(map[string]interface{})
+			for _, property := range sortedKeys(reverseMap) {
+				if def := active.term(property); def != nil && def.reverse {
+					asArray := def.hasContainer("@set") || !compactArrays
+					addValue(result, property, reverseMap[property], asArray)
+					delete(reverseMap, property)
+				}
+			}
+			if len(reverseMap) > 0 {
+				alias, err := s.compactIRI(active, "@reverse", nil, true, false)
+				if err != nil {
+					return nil, err
+				}
+				result[alias] = reverseMap
+			}
+			continue
+
+		case "@preserve":
+			continue
+
+		case "@index":
+			if active.term(activeProperty).hasContainer("@index") {
+				continue
+			}
+			fallthrough
+
+		case "@direction", "@language", "@value":
+			alias, err := s.compactIRI(active, expandedProperty, nil, true, false)
+			if err != nil {
+				return nil, err
+			}
+			result[alias] = expandedValue
+			continue
+		}
+
+		values := asList(expandedValue)
+		if len(values) == 0 {
+			itemActiveProperty, err := s.compactIRI(active, expandedProperty, expandedValue, true, insideReverse)
+			if err != nil {
+				return nil, err
+			}
+			nestResult, err := s.nestResult(active, result, itemActiveProperty)
+			if err != nil {
+				return nil, err
+			}
+			addValue(nestResult, itemActiveProperty, []interface{}{}, true)
+		}
+
+		for _, expandedItem := range values {
+			if err := s.compactItem(active, result, expandedProperty, expandedItem, insideReverse, compactArrays); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// nestResult returns the object compacted values of itemActiveProperty are
+// added to, honouring @nest
+func (s *contextState) nestResult(active *jsonldContext, result map[string]interface{}, itemActiveProperty string) (map[string]interface{}, error) {
+	def := active.term(itemActiveProperty)
+	if def == nil || def.nest == "" {
+		return result, nil
+	}
+	nestTerm := def.nest
+	if nestTerm != "@nest" {
+		expanded, err := s.expandIRI(active, nestTerm, false, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if expanded != "@nest" {
+			return nil, jsonldErrorf("invalid @nest value", "%s does not alias @nest", nestTerm)
+		}
+	}
+	nested, ok := result[nestTerm].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		result[nestTerm] = nested
+	}
+	return nested, nil
+}
+
+func (s *contextState) compactItem(active *jsonldContext, result map[string]interface{}, expandedProperty string, expandedItem interface{}, insideReverse, compactArrays bool) error {
+	itemActiveProperty, err := s.compactIRI(active, expandedProperty, expandedItem, true, insideReverse)
+	if err != nil {
+		return err
+	}
+	nestResult, err := s.nestResult(active, result, itemActiveProperty)
+	if err != nil {
+		return err
+	}
+
+	def := active.term(itemActiveProperty)
+	asArray := def.hasContainer("@set") || itemActiveProperty == "@graph" || itemActiveProperty == "@list" || !compactArrays
+
+	item, _ := expandedItem.(map[string]interface{})
+	inner := expandedItem
+	if isListObject(expandedItem) {
+		inner = item["@list"]
+	} else if isGraphObject(expandedItem) {
+		inner = item["@graph"]
+	}
+	compactedItem, err := s.compact(active, itemActiveProperty, inner, compactArrays)
+	if err != nil {
+		return err
+	}
+
+	alias := func(keyword string) (string, error) {
+		return s.compactIRI(active, keyword, nil, true, false)
+	}
+
+	switch {
+	case isListObject(expandedItem):
+		compactedItem = asList(compactedItem)
+		if def.hasContainer("@list") {
+			nestResult[itemActiveProperty] = compactedItem
+			return nil
+		}
+		listAlias, err := alias("@list")
+		if err != nil {
+			return err
+		}
+		wrapped := map[string]interface{}{listAlias: compactedItem}
+		if index, ok := item["@index"]; ok {
+			indexAlias, err := alias("@index")
+			if err != nil {
+				return err
+			}
+			wrapped[indexAlias] = index
+		}
+		addValue(nestResult, itemActiveProperty, wrapped, asArray)
+
+	case isGraphObject(expandedItem):
+		switch {
+		case def.hasContainer("@graph") && def.hasContainer("@id"):
+			mapObject := mapObjectFor(nestResult, itemActiveProperty)
+			id, _ := item["@id"].(string)
+			if id == "" {
+				id = "@none"
+			}
+			mapKey, err := s.compactIRI(active, id, nil, id == "@none", false)
+			if err != nil {
+				return err
+			}
+			addValue(mapObject, mapKey, compactedItem, asArray)
+
+		case def.hasContainer("@graph") && def.hasContainer("@index") && isSimpleGraphObject(expandedItem):
+			mapObject := mapObjectFor(nestResult, itemActiveProperty)
+			mapKey, _ := item["@index"].(string)
+			if mapKey == "" {
+				mapKey = "@none"
+			}
+			addValue(mapObject, mapKey, compactedItem, asArray)
+
+		case def.hasContainer("@graph") && isSimpleGraphObject(expandedItem):
+			if list, ok := compactedItem.([]interface{}); ok && len(list) > 1 {
+				includedAlias, err := alias("@included")
+				if err != nil {
+					return err
+				}
+				compactedItem = map[string]interface{}{includedAlias: list}
+			}
+			addValue(nestResult, itemActiveProperty, compactedItem, asArray)
+
+		default:
+			graphAlias, err := alias("@graph")
+			if err != nil {
+				return err
+			}
+			wrapped := map[string]interface{}{graphAlias: asList(compactedItem)}
+			if id, ok := item["@id"].(string); ok {
+				idAlias, err := alias("@id")
+				if err != nil {
+					return err
+				}
+				if wrapped[idAlias], err = s.compactIRI(active, id, nil, false, false); err != nil {
+					return err
+				}
+			}
+			if index, ok := item["@index"]; ok {
+				indexAlias, err := alias("@index")
+				if err != nil {
+					return err
+				}
+				wrapped[indexAlias] = index
+			}
+			addValue(nestResult, itemActiveProperty, wrapped, asArray)
+		}
+
+	case !def.hasContainer("@graph") && (def.hasContainer("@language") || def.hasContainer("@index") || def.hasContainer("@id") || def.hasContainer("@type")):
+		mapObject := mapObjectFor(nestResult, itemActiveProperty)
+		mapKey := ""
+
+		switch {
+		case def.hasContainer("@language"):
+			if m, ok := compactedItem.(map[string]interface{}); ok && isValueObject(expandedItem) {
+				compactedItem = m["@value"]
+			}
+			mapKey, _ = item["@language"].(string)
+
+		case def.hasContainer("@index") && (def.index == "" || def.index == "@index"):
+			mapKey, _ = item["@index"].(string)
+
+		case def.hasContainer("@index"):
+			containerKey, err := s.compactIRI(active, def.index, nil, true, false)
+			if err != nil {
+				return err
+			}
+			if m, ok := compactedItem.(map[string]interface{}); ok {
+				keys := asList(m[containerKey])
+				if len(keys) > 0 {
+					if key, ok := keys[0].(string); ok {
+						mapKey = key
+						keys = keys[1:]
+					}
+				}
+				switch len(keys) {
+				case 0:
+					delete(m, containerKey)
+				case 1:
+					m[containerKey] = keys[0]
+				default:
+					m[containerKey] = keys
+				}
+			}
+
+		case def.hasContainer("@id"):
+			containerKey, err := alias("@id")
+			if err != nil {
+				return err
+			}
+			if m, ok := compactedItem.(map[string]interface{}); ok {
+				mapKey, _ = m[containerKey].(string)
+				delete(m, containerKey)
+			}
+
+		case def.hasContainer("@type"):
+			containerKey, err := alias("@type")
+			if err != nil {
+				return err
+			}
+			if m, ok := compactedItem.(map[string]interface{}); ok {
+				types := asList(m[containerKey])
+				if len(types) > 0 {
+					mapKey, _ = types[0].(string)
+					types = types[1:]
+				}
+				switch len(types) {
+				case 0:
+					delete(m, containerKey)
+				case 1:
+					m[containerKey] = types[0]
+				default:
+					m[containerKey] = types
+				}
+				if len(m) == 1 {
+					if id, ok := item["@id"].(string); ok {
+						if compactedItem, err = s.compact(active, itemActiveProperty, map[string]interface{}{"@id": id}, compactArrays); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+
+		if mapKey == "" {
+			var err error
+			if mapKey, err = alias("@none"); err != nil {
+				return err
+			}
+		}
+		addValue(mapObject, mapKey, compactedItem, asArray)
+
+	default:
+		addValue(nestResult, itemActiveProperty, compactedItem, asArray)
+	}
+	return nil
+}
+
+func mapObjectFor(result map[string]interface{}, property string) map[string]interface{} {
+	mapObject, ok := result[property].(map[string]interface{})
+	if !ok {
+		mapObject = make(map[string]interface{})
+		result[property] = mapObject
+	}
+	return mapObject
+}
@@ -0,0 +1,234 @@
+package vc
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxContextDocumentSize limits context documents fetched over HTTPS
+const maxContextDocumentSize = 1 << 20
+
+// contextBundle holds the pinned JSON-LD contexts shipped with the verifier,
+// listed with their digests in contexts/manifest.json. The files are the
+// documents their publishers serve, byte for byte, and the digests are taken
+// over them; go generate refreshes both from the publishers.
+//
+//go:generate go run ./contexts/gen.go
+//go:embed contexts/*.jsonld contexts/manifest.json
+var contextBundle embed.FS
+
+// RemoteDocument is a document returned by a DocumentLoader
+type RemoteDocument struct {
+	DocumentURL string      `json:"documentUrl"`
+	Document    interface{} `json:"document"`
+	ContextURL  string      `json:"contextUrl,omitempty"`
+}
+
+// DocumentLoader resolves the remote documents, typically contexts, a
+// JSON-LD document references
+type DocumentLoader interface {
+	LoadDocument(url string) (*RemoteDocument, error)
+}
+
+// PinnedDocumentLoader serves JSON-LD contexts from a set of documents
+// pinned to SRI digests, starting with the bundled contexts. Contexts not
+// pinned are rejected unless their URL is allow-listed, in which case they
+// are fetched over HTTPS once and cached; verification therefore never
+// reveals to a context host which credentials are being checked.
+type PinnedDocumentLoader struct {
+	client *http.Client
+
+	mu        sync.RWMutex
+	documents map[string]interface{}
+	allowed   []string
+	cache     map[string]interface{}
+}
+
+// NewPinnedDocumentLoader creates a loader serving the bundled contexts. It
+// fails if a bundled context does not match its pinned digest.
+func NewPinnedDocumentLoader() (*PinnedDocumentLoader, error) {
+	loader := &PinnedDocumentLoader{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		documents: make(map[string]interface{}),
+		cache:     make(map[string]interface{}),
+	}
+	if err := loader.loadManifestFS(contextBundle, "contexts/manifest.json"); err != nil {
+		return nil, fmt.Errorf("bundled JSON-LD contexts: %w", err)
+	}
+	return loader, nil
+}
+
+// Pin adds a context document under url after checking it against an SRI
+// digest such as "sha384-..."
+func (l *PinnedDocumentLoader) Pin(url string, document []byte, digestSRI string) error {
+	if url == "" {
+		return fmt.Errorf("context URL is required")
+	}
+	if err := VerifyDigestSRI(document, digestSRI); err != nil {
+		return fmt.Errorf("context %s: %w", url, err)
+	}
+	parsed, err := decodeJSONDocument(document)
+	if err != nil {
+		return fmt.Errorf("context %s is not valid JSON: %w", url, err)
+	}
+	if _, ok := parsed.(map[string]interface{}); !ok {
+		return fmt.Errorf("context %s is not a JSON object", url)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.documents[url] = parsed
+	return nil
+}
+
+// LoadManifest pins the contexts listed in a JSON manifest, a list of
+// SchemaManifestEntry whose files are relative to the manifest
+func (l *PinnedDocumentLoader) LoadManifest(manifestPath string) error {
+	dir, file := filepath.Split(manifestPath)
+	if dir == "" {
+		dir = "."
+	}
+	return l.loadManifestFS(os.DirFS(dir), file)
+}
+
+func (l *PinnedDocumentLoader) loadManifestFS(fsys fs.FS, manifestPath string) error {
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read context manifest: %w", err)
+	}
+
+	var entries []SchemaManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse context manifest: %w", err)
+	}
+
+	dir := path.Dir(manifestPath)
+	for _, entry := range entries {
+		if entry.DigestSRI == "" {
+			return fmt.Errorf("context %s is not pinned to a digest", entry.ID)
+		}
+		document, err := fs.ReadFile(fsys, path.Join(dir, entry.File))
+		if err != nil {
+			return fmt.Errorf("failed to read context %s: %w", entry.ID, err)
+		}
+		if err := l.Pin(entry.ID, document, entry.DigestSRI); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllowRemote allow-lists contexts that may be fetched when not pinned. An
+// entry ending in "/" allows every URL under it; other entries must match
+// exactly. Only https URLs are ever fetched.
+func (l *PinnedDocumentLoader) AllowRemote(urls ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.allowed = append(l.allowed, urls...)
+}
+
+// URLs lists the pinned context URLs
+func (l *PinnedDocumentLoader) URLs() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	urls := make([]string, 0, len(l.documents))
+	for url := range l.documents {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// LoadDocument implements DocumentLoader
+func (l *PinnedDocumentLoader) LoadDocument(url string) (*RemoteDocument, error) {
+	l.mu.RLock()
+	document, pinned := l.documents[url]
+	if !pinned {
+		document, pinned = l.cache[url]
+	}
+	allowed := l.isAllowed(url)
+	l.mu.RUnlock()
+
+	if pinned {
+		return &RemoteDocument{DocumentURL: url, Document: document}, nil
+	}
+	if !allowed {
+		return nil, fmt.Errorf("context %s is not pinned or allow-listed", url)
+	}
+	if !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("context %s must be fetched over https", url)
+	}
+
+	document, err := l.fetch(url)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.cache[url] = document
+	l.mu.Unlock()
+
+	return &RemoteDocument{DocumentURL: url, Document: document}, nil
+}
+
+func (l *PinnedDocumentLoader) isAllowed(url string) bool {
+	for _, allowed := range l.allowed {
+		if url == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(url, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch retrieves an allow-listed context over HTTPS
+func (l *PinnedDocumentLoader) fetch(url string) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context URL %s: %w", url, err)
+	}
+	req.Header.Set("Accept", "application/ld+json, application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch context from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("context fetch failed with status %d", resp.StatusCode)
+	}
+	if resp.Request != nil && resp.Request.URL.Scheme != "https" {
+		return nil, fmt.Errorf("context %s redirected away from https", url)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxContextDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read context response: %w", err)
+	}
+	if len(body) > maxContextDocumentSize {
+		return nil, fmt.Errorf("context %s exceeds %d bytes", url, maxContextDocumentSize)
+	}
+
+	document, err := decodeJSONDocument(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse context JSON: %w", err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("context %s is not a JSON object", url)
+	}
+	return document, nil
+}
@@ -0,0 +1,842 @@
+package vc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RDF and XSD IRIs used when converting JSON-LD to RDF
+const (
+	rdfType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	rdfFirst      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	rdfRest       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	rdfNil        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+	rdfLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+	rdfJSON       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON"
+	xsdBoolean    = "http://www.w3.org/2001/XMLSchema#boolean"
+	xsdDouble     = "http://www.w3.org/2001/XMLSchema#double"
+	xsdInteger    = "http://www.w3.org/2001/XMLSchema#integer"
+	xsdString     = "http://www.w3.org/2001/XMLSchema#string"
+)
+
+// maxCanonicalizationWork bounds the permutations URDNA2015 explores, so a
+// crafted dataset of many indistinguishable blank nodes cannot stall
+// verification
+const maxCanonicalizationWork = 1 << 16
+
+// RDFTermKind distinguishes the kinds of RDF terms
+type RDFTermKind int
+
+const (
+	RDFTermIRI RDFTermKind = iota
+	RDFTermBlankNode
+	RDFTermLiteral
+)
+
+// RDFTerm is an IRI, blank node or literal. Blank node values carry their
+// "_:" prefix.
+type RDFTerm struct {
+	Kind     RDFTermKind
+	Value    string
+	Datatype string
+	Language string
+}
+
+// Quad is an RDF statement; Graph is nil for the default graph
+type Quad struct {
+	Subject   RDFTerm
+	Predicate RDFTerm
+	Object    RDFTerm
+	Graph     *RDFTerm
+}
+
+// ToRDF converts a JSON-LD document to an RDF dataset
+func (p *JSONLDProcessor) ToRDF(document interface{}, options *JSONLDOptions) ([]Quad, error) {
+	expanded, err := p.Expand(document, options)
+	if err != nil {
+		return nil, err
+	}
+	return expandedToRDF(expanded), nil
+}
+
+// Canonicalize converts a JSON-LD document to RDF and returns its URDNA2015
+// canonical N-Quads, the form Data Integrity proofs sign
+func (p *JSONLDProcessor) Canonicalize(document interface{}, options *JSONLDOptions) (string, error) {
+	quads, err := p.ToRDF(document, options)
+	if err != nil {
+		return "", err
+	}
+	return CanonicalizeDataset(quads)
+}
+
+// blankNodeIssuer issues sequential blank node identifiers, remembering
+// the order in which existing identifiers were relabelled
+type blankNodeIssuer struct {
+	prefix  string
+	counter int
+	issued  map[string]string
+	order   []string
+}
+
+func newBlankNodeIssuer(prefix string) *blankNodeIssuer {
+	return &blankNodeIssuer{prefix: prefix, issued: make(map[string]string)}
+}
+
+func (i *blankNodeIssuer) issue(existing string) string {
+	if id, ok := i.issued[existing]; ok && existing != "" {
+		return id
+	}
+	id := i.prefix + strconv.Itoa(i.counter)
+	i.counter++
+	if existing != "" {
+		i.issued[existing] = id
+		i.order = append(i.order, existing)
+	}
+	return id
+}
+
+func (i *blankNodeIssuer) has(existing string) bool {
+	_, ok := i.issued[existing]
+	return ok
+}
+
+func (i *blankNodeIssuer) clone() *blankNodeIssuer {
+	clone := &blankNodeIssuer{prefix: i.prefix, counter: i.counter, issued: make(map[string]string, len(i.issued))}
+	for existing, id := range i.issued {
+		clone.issued[existing] = id
+	}
+	clone.order = append([]string(nil), i.order...)
+	return clone
+}
+
+// nodeMapGenerator flattens expanded JSON-LD into graph name -> subject ->
+// node, relabelling blank nodes
+type nodeMapGenerator struct {
+	issuer  *blankNodeIssuer
+	nodeMap map[string]map[string]map[string]interface{}
+}
+
+func (g *nodeMapGenerator) graph(name string) map[string]map[string]interface{} {
+	graph := g.nodeMap[name]
+	if graph == nil {
+		graph = make(map[string]map[string]interface{})
+		g.nodeMap[name] = graph
+	}
+	return graph
+}
+
+// addUnique appends value to the node's property unless an equal value is
+// already there
+func addUnique(node map[string]interface{}, property string, value interface{}) {
+	values := asList(node[property])
+	for _, existing := range values {
+		if jsonEqual(existing, value) {
+			return
+		}
+	}
+	node[property] = append(values, value)
+}
+
+func (g *nodeMapGenerator) generate(element interface{}, activeGraph string, activeSubject interface{}, activeProperty string, list map[string]interface{}) {
+	if items, ok := element.([]interface{}); ok {
+		for _, item := range items {
+			g.generate(item, activeGraph, activeSubject, activeProperty, list)
+		}
+		return
+	}
+
+	elem, ok := element.(map[string]interface{})
+	if !ok {
+		return
+	}
+	graph := g.graph(activeGraph)
+	var subjectNode map[string]interface{}
+	if subject, ok := activeSubject.(string); ok {
+		subjectNode = graph[subject]
+	}
+
+	if isValueObject(elem) {
+		value := make(map[string]interface{}, len(elem))
+		for key, v := range elem {
+			value[key] = v
+		}
+		if types, ok := value["@type"].(string); ok && isBlankNode(types) {
+			value["@type"] = g.issuer.issue(types)
+		}
+		if list == nil {
+			addUnique(subjectNode, activeProperty, value)
+		} else {
+			list["@list"] = append(asList(list["@list"]), value)
+		}
+		return
+	}
+
+	if isListObject(elem) {
+		result := map[string]interface{}{"@list": []interface{}{}}
+		g.generate(elem["@list"], activeGraph, activeSubject, activeProperty, result)
+		if list == nil {
+			subjectNode[activeProperty] = append(asList(subjectNode[activeProperty]), result)
+		} else {
+			list["@list"] = append(asList(list["@list"]), result)
+		}
+		return
+	}
+
+	id, _ := elem["@id"].(string)
+	if id == "" || isBlankNode(id) {
+		id = g.issuer.issue(id)
+	}
+	node := graph[id]
+	if node == nil {
+		node = map[string]interface{}{"@id": id}
+		graph[id] = node
+	}
+
+	if reference, ok := activeSubject.(map[string]interface{}); ok {
+		addUnique(node, activeProperty, reference)
+	} else if activeProperty != "" {
+		reference := map[string]interface{}{"@id": id}
+		if list == nil {
+			addUnique(subjectNode, activeProperty, reference)
+		} else {
+			list["@list"] = append(asList(list["@list"]), reference)
+		}
+	}
+
+	for _, t := range asList(elem["@type"]) {
+		typ, _ := t.(string)
+		if isBlankNode(typ) {
+			typ = g.issuer.issue(typ)
+		}
+		addUnique(node, "@type", typ)
+	}
+	if index, ok := elem["@index"]; ok {
+		node["@index"] = index
+	}
+
+	if reverse, ok := elem["@reverse"].(map[string]interface{}); ok {
+		referenced := map[string]interface{}{"@id": id}
+		for _, property := range sortedKeys(reverse) {
+			for _, value := range asList(reverse[property]) {
+				g.generate(value, activeGraph, referenced, property, nil)
+			}
+		}
+	}
+	if graphValue, ok := elem["@graph"]; ok {
+		g.generate(graphValue, id, nil, "", nil)
+	}
+	if included, ok := elem["@included"]; ok {
+		g.generate(included, activeGraph, nil, "", nil)
+	}
+
+	for _, property := range sortedKeys(elem) {
+		switch property {
+		case "@id", "@type", "@index", "@reverse", "@graph", "@included":
+			continue
+		}
+		name := property
+		if isBlankNode(name) {
+			name = g.issuer.issue(name)
+		}
+		if _, ok := node[name]; !ok {
+			node[name] = []interface{}{}
+		}
+		g.generate(elem[property], activeGraph, id, name, nil)
+	}
+}
+
+// expandedToRDF implements the deserialize JSON-LD to RDF algorithm
+func expandedToRDF(expanded []interface{}) []Quad {
+	generator := &nodeMapGenerator{
+		issuer:  newBlankNodeIssuer("_:b"),
+		nodeMap: map[string]map[string]map[string]interface{}{"@default": {}},
+	}
+	generator.generate(expanded, "@default", nil, "", nil)
+
+	var quads []Quad
+	graphNames := make([]string, 0, len(generator.nodeMap))
+	for name := range generator.nodeMap {
+		graphNames = append(graphNames, name)
+	}
+	sort.Strings(graphNames)
+
+	for _, graphName := range graphNames {
+		var graphTerm *RDFTerm
+		if graphName != "@default" {
+			term, ok := resourceTerm(graphName)
+			if !ok {
+				continue
+			}
+			graphTerm = &term
+		}
+
+		graph := generator.nodeMap[graphName]
+		subjects := make([]string, 0, len(graph))
+		for subject := range graph {
+			subjects = append(subjects, subject)
+		}
+		sort.Strings(subjects)
+
+		for _, subject := range subjects {
+			subjectTerm, ok := resourceTerm(subject)
+			if !ok {
+				continue
+			}
+			node := graph[subject]
+			for _, property := range sortedKeys(node) {
+				if property == "@type" {
+					for _, t := range asList(node[property]) {
+						typeTerm, ok := resourceTerm(t.(string))
+						if !ok {
+							continue
+						}
+						quads = append(quads, Quad{Subject: subjectTerm, Predicate: RDFTerm{Kind: RDFTermIRI, Value: rdfType}, Object: typeTerm, Graph: graphTerm})
+					}
+					continue
+				}
+				if isKeyword(property) || isBlankNode(property) || !isAbsoluteIRI(property) {
+					continue
+				}
+				predicate := RDFTerm{Kind: RDFTermIRI, Value: property}
+				for _, item := range asList(node[property]) {
+					var listQuads []Quad
+					object, ok := objectToRDF(item, generator.issuer, graphTerm, &listQuads)
+					if !ok {
+						continue
+					}
+					quads = append(quads, Quad{Subject: subjectTerm, Predicate: predicate, Object: object, Graph: graphTerm})
+					quads = append(quads, listQuads...)
+				}
+			}
+		}
+	}
+	return quads
+}
+
+// resourceTerm returns the IRI or blank node term of an identifier, and
+// false for relative IRIs, which have no RDF representation
+func resourceTerm(id string) (RDFTerm, bool) {
+	if isBlankNode(id) {
+		return RDFTerm{Kind: RDFTermBlankNode, Value: id}, true
+	}
+	if isAbsoluteIRI(id) {
+		return RDFTerm{Kind: RDFTermIRI, Value: id}, true
+	}
+	return RDFTerm{}, false
+}
+
+func objectToRDF(item interface{}, issuer *blankNodeIssuer, graph *RDFTerm, listQuads *[]Quad) (RDFTerm, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return RDFTerm{}, false
+	}
+
+	if isListObject(m) {
+		return listToRDF(asList(m["@list"]), issuer, graph, listQuads), true
+	}
+	if !isValueObject(m) {
+		id, _ := m["@id"].(string)
+		return resourceTerm(id)
+	}
+
+	value := m["@value"]
+	datatype, _ := m["@type"].(string)
+	if datatype != "" && datatype != "@json" && !isAbsoluteIRI(datatype) {
+		return RDFTerm{}, false
+	}
+
+	literal := RDFTerm{Kind: RDFTermLiteral, Datatype: datatype}
+	if datatype == "@json" {
+		literal.Value = canonicalJSON(value)
+		literal.Datatype = rdfJSON
+		return literal, true
+	}
+
+	switch v := value.(type) {
+	case bool:
+		literal.Value = strconv.FormatBool(v)
+		if literal.Datatype == "" {
+			literal.Datatype = xsdBoolean
+		}
+	case json.Number, float64, int:
+		f, _ := jsonldNumber(v)
+		if f != math.Trunc(f) || math.Abs(f) >= 1e21 || datatype == xsdDouble {
+			literal.Value = canonicalDouble(f)
+			if literal.Datatype == "" {
+				literal.Datatype = xsdDouble
+			}
+		} else {
+			literal.Value = strconv.FormatFloat(f, 'f', -1, 64)
+			if literal.Datatype == "" {
+				literal.Datatype = xsdInteger
+			}
+		}
+	case string:
+		literal.Value = v
+		if language, ok := m["@language"].(string); ok {
+			literal.Datatype = rdfLangString
+			literal.Language = strings.ToLower(language)
+		} else if literal.Datatype == "" {
+			literal.Datatype = xsdString
+		}
+	default:
+		return RDFTerm{}, false
+	}
+	return literal, true
+}
+
+func listToRDF(list []interface{}, issuer *blankNodeIssuer, graph *RDFTerm, listQuads *[]Quad) RDFTerm {
+	if len(list) == 0 {
+		return RDFTerm{Kind: RDFTermIRI, Value: rdfNil}
+	}
+
+	nodes := make([]RDFTerm, len(list))
+	for i := range list {
+		nodes[i] = RDFTerm{Kind: RDFTermBlankNode, Value: issuer.issue("")}
+	}
+	for i, item := range list {
+		var embedded []Quad
+		if object, ok := objectToRDF(item, issuer, graph, &embedded); ok {
+			*listQuads = append(*listQuads, Quad{Subject: nodes[i], Predicate: RDFTerm{Kind: RDFTermIRI, Value: rdfFirst}, Object: object, Graph: graph})
+			*listQuads = append(*listQuads, embedded...)
+		}
+		rest := RDFTerm{Kind: RDFTermIRI, Value: rdfNil}
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+		*listQuads = append(*listQuads, Quad{Subject: nodes[i], Predicate: RDFTerm{Kind: RDFTermIRI, Value: rdfRest}, Object: rest, Graph: graph})
+	}
+	return nodes[0]
+}
+
+// canonicalDouble renders a double in the canonical xsd:double lexical
+// form, such as 1.1E0
+func canonicalDouble(f float64) string {
+	formatted := strconv.FormatFloat(f, 'E', -1, 64)
+	mantissa, exponent, _ := strings.Cut(formatted, "E")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	exp, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(exp)
+}
+
+// canonicalJSON serialises a JSON literal with sorted keys and no
+// insignificant whitespace (RFC 8785 for the values JSON-LD carries)
+func canonicalJSON(value interface{}) string {
+	var b strings.Builder
+	writeCanonicalJSON(&b, value)
+	return b.String()
+}
+
+func writeCanonicalJSON(b *strings.Builder, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		b.WriteByte('{')
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			encoded, _ := json.Marshal(key)
+			b.Write(encoded)
+			b.WriteByte(':')
+			writeCanonicalJSON(b, v[key])
+		}
+		b.WriteByte('}')
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonicalJSON(b, item)
+		}
+		b.WriteByte(']')
+	case json.Number, float64, int:
+		f, _ := jsonldNumber(v)
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	default:
+		encoded, _ := json.Marshal(v)
+		b.Write(encoded)
+	}
+}
+
+// SerializeNQuads renders quads as N-Quads in the given order
+func SerializeNQuads(quads []Quad) string {
+	var b strings.Builder
+	for _, quad := range quads {
+		b.WriteString(quad.String())
+	}
+	return b.String()
+}
+
+// String renders the quad as one N-Quads line
+func (q Quad) String() string {
+	var b strings.Builder
+	b.WriteString(q.Subject.String())
+	b.WriteByte(' ')
+	b.WriteString(q.Predicate.String())
+	b.WriteByte(' ')
+	b.WriteString(q.Object.String())
+	if q.Graph != nil {
+		b.WriteByte(' ')
+		b.WriteString(q.Graph.String())
+	}
+	b.WriteString(" .\n")
+	return b.String()
+}
+
+// String renders the term in N-Quads syntax
+func (t RDFTerm) String() string {
+	switch t.Kind {
+	case RDFTermIRI:
+		return "<" + t.Value + ">"
+	case RDFTermBlankNode:
+		return t.Value
+	}
+
+	literal := `"` + escapeNQuadsLiteral(t.Value) + `"`
+	switch {
+	case t.Datatype == rdfLangString:
+		return literal + "@" + t.Language
+	case t.Datatype != "" && t.Datatype != xsdString:
+		return literal + "^^<" + t.Datatype + ">"
+	}
+	return literal
+}
+
+// escapeNQuadsLiteral escapes a literal as canonical N-Quads requires
+func escapeNQuadsLiteral(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// urdna2015 holds the state of one run of the URDNA2015 canonicalization
+// algorithm
+type urdna2015 struct {
+	quads        []Quad
+	blankToQuads map[string][]int
+	canonical    *blankNodeIssuer
+	firstDegree  map[string]string
+	work         int
+}
+
+type hashPathResult struct {
+	hash   string
+	issuer *blankNodeIssuer
+}
+
+// newURDNA2015 indexes the quads mentioning each blank node of a dataset
+func newURDNA2015(quads []Quad) *urdna2015 {
+	c := &urdna2015{
+		quads:        quads,
+		blankToQuads: make(map[string][]int),
+		canonical:    newBlankNodeIssuer("_:c14n"),
+		firstDegree:  make(map[string]string),
+	}
+
+	for i, quad := range quads {
+		for _, term := range quadBlankNodes(quad) {
+			// A quad naming a blank node twice is still one of its quads
+			mentions := c.blankToQuads[term]
+			if len(mentions) == 0 || mentions[len(mentions)-1] != i {
+				c.blankToQuads[term] = append(mentions, i)
+			}
+		}
+	}
+	return c
+}
+
+// CanonicalizeDataset relabels the blank nodes of a dataset with URDNA2015
+// and returns its sorted canonical N-Quads
+func CanonicalizeDataset(quads []Quad) (string, error) {
+	c := newURDNA2015(quads)
+
+	hashToBlank := make(map[string][]string)
+	for blank := range c.blankToQuads {
+		hash := c.hashFirstDegree(blank)
+		hashToBlank[hash] = append(hashToBlank[hash], blank)
+	}
+	hashes := make([]string, 0, len(hashToBlank))
+	for hash := range hashToBlank {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	var shared []string
+	for _, hash := range hashes {
+		blanks := hashToBlank[hash]
+		if len(blanks) > 1 {
+			shared = append(shared, hash)
+			continue
+		}
+		c.canonical.issue(blanks[0])
+	}
+
+	for _, hash := range shared {
+		blanks := hashToBlank[hash]
+		sort.Strings(blanks)
+		var results []hashPathResult
+		for _, blank := range blanks {
+			if c.canonical.has(blank) {
+				continue
+			}
+			issuer := newBlankNodeIssuer("_:b")
+			issuer.issue(blank)
+			result, err := c.hashNDegree(blank, issuer)
+			if err != nil {
+				return "", err
+			}
+			results = append(results, result)
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].hash < results[j].hash })
+		for _, result := range results {
+			for _, existing := range result.issuer.order {
+				c.canonical.issue(existing)
+			}
+		}
+	}
+
+	lines := make([]string, len(quads))
+	for i, quad := range quads {
+		lines[i] = c.relabel(quad).String()
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, ""), nil
+}
+
+func quadBlankNodes(quad Quad) []string {
+	var blanks []string
+	if quad.Subject.Kind == RDFTermBlankNode {
+		blanks = append(blanks, quad.Subject.Value)
+	}
+	if quad.Object.Kind == RDFTermBlankNode {
+		blanks = append(blanks, quad.Object.Value)
+	}
+	if quad.Graph != nil && quad.Graph.Kind == RDFTermBlankNode {
+		blanks = append(blanks, quad.Graph.Value)
+	}
+	return blanks
+}
+
+func (c *urdna2015) relabel(quad Quad) Quad {
+	rename := func(term RDFTerm) RDFTerm {
+		if term.Kind == RDFTermBlankNode {
+			term.Value = c.canonical.issue(term.Value)
+		}
+		return term
+	}
+	quad.Subject = rename(quad.Subject)
+	quad.Object = rename(quad.Object)
+	if quad.Graph != nil {
+		graph := rename(*quad.Graph)
+		quad.Graph = &graph
+	}
+	return quad
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFirstDegree hashes the quads mentioning blank, with blank labelled
+// _:a and every other blank node _:z
+func (c *urdna2015) hashFirstDegree(blank string) string {
+	if hash, ok := c.firstDegree[blank]; ok {
+		return hash
+	}
+
+	rename := func(term RDFTerm) RDFTerm {
+		if term.Kind == RDFTermBlankNode {
+			if term.Value == blank {
+				term.Value = "_:a"
+			} else {
+				term.Value = "_:z"
+			}
+		}
+		return term
+	}
+
+	var lines []string
+	for _, i := range c.blankToQuads[blank] {
+		quad := c.quads[i]
+		quad.Subject = rename(quad.Subject)
+		quad.Object = rename(quad.Object)
+		if quad.Graph != nil {
+			graph := rename(*quad.Graph)
+			quad.Graph = &graph
+		}
+		lines = append(lines, quad.String())
+	}
+	sort.Strings(lines)
+
+	hash := sha256Hex(strings.Join(lines, ""))
+	c.firstDegree[blank] = hash
+	return hash
+}
+
+func (c *urdna2015) hashRelatedBlankNode(related string, quad Quad, issuer *blankNodeIssuer, position string) string {
+	var identifier string
+	if id, ok := c.canonical.issued[related]; ok {
+		identifier = id
+	} else if id, ok := issuer.issued[related]; ok {
+		identifier = id
+	} else {
+		identifier = c.hashFirstDegree(related)
+	}
+
+	input := position
+	if position != "g" {
+		input += "<" + quad.Predicate.Value + ">"
+	}
+	return sha256Hex(input + identifier)
+}
+
+// hashNDegree implements the hash N-degree quads algorithm
+func (c *urdna2015) hashNDegree(blank string, issuer *blankNodeIssuer) (hashPathResult, error) {
+	hashToRelated := make(map[string][]string)
+	for _, i := range c.blankToQuads[blank] {
+		quad := c.quads[i]
+		add := func(term RDFTerm, position string) {
+			if term.Kind == RDFTermBlankNode && term.Value != blank {
+				hash := c.hashRelatedBlankNode(term.Value, quad, issuer, position)
+				hashToRelated[hash] = append(hashToRelated[hash], term.Value)
+			}
+		}
+		add(quad.Subject, "s")
+		add(quad.Object, "o")
+		if quad.Graph != nil {
+			add(*quad.Graph, "g")
+		}
+	}
+
+	relatedHashes := make([]string, 0, len(hashToRelated))
+	for hash := range hashToRelated {
+		relatedHashes = append(relatedHashes, hash)
+	}
+	sort.Strings(relatedHashes)
+
+	var data strings.Builder
+	for _, relatedHash := range relatedHashes {
+		data.WriteString(relatedHash)
+
+		chosenPath := ""
+		var chosenIssuer *blankNodeIssuer
+		var permutationErr error
+
+		permute(hashToRelated[relatedHash], func(permutation []string) bool {
+			c.work++
+			if c.work > maxCanonicalizationWork {
+				permutationErr = fmt.Errorf("dataset exceeds the canonicalization work limit")
+				return false
+			}
+
+			issuerCopy := issuer.clone()
+			path := ""
+			var recursion []string
+			longer := func() bool {
+				return chosenPath != "" && len(path) >= len(chosenPath) && path > chosenPath
+			}
+
+			for _, related := range permutation {
+				if id, ok := c.canonical.issued[related]; ok {
+					path += id
+				} else {
+					if !issuerCopy.has(related) {
+						recursion = append(recursion, related)
+					}
+					path += issuerCopy.issue(related)
+				}
+				if longer() {
+					return true
+				}
+			}
+
+			for _, related := range recursion {
+				result, err := c.hashNDegree(related, issuerCopy)
+				if err != nil {
+					permutationErr = err
+					return false
+				}
+				path += issuerCopy.issue(related)
+				path += "<" + result.hash + ">"
+				issuerCopy = result.issuer
+				if longer() {
+					return true
+				}
+			}
+
+			if chosenPath == "" || path < chosenPath {
+				chosenPath = path
+				chosenIssuer = issuerCopy
+			}
+			return true
+		})
+		if permutationErr != nil {
+			return hashPathResult{}, permutationErr
+		}
+
+		data.WriteString(chosenPath)
+		issuer = chosenIssuer
+	}
+
+	return hashPathResult{hash: sha256Hex(data.String()), issuer: issuer}, nil
+}
+
+// permute calls visit with every permutation of items, in lexicographic
+// order, until visit returns false
+func permute(items []string, visit func([]string) bool) {
+	perm := append([]string(nil), items...)
+	sort.Strings(perm)
+	for {
+		if !visit(append([]string(nil), perm...)) {
+			return
+		}
+		i := len(perm) - 2
+		for i >= 0 && perm[i] >= perm[i+1] {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		j := len(perm) - 1
+		for perm[j] <= perm[i] {
+			j--
+		}
+		perm[i], perm[j] = perm[j], perm[i]
+		for l, r := i+1, len(perm)-1; l < r; l, r = l+1, r-1 {
+			perm[l], perm[r] = perm[r], perm[l]
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package vc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+)
+
+func newTestJSONLDProcessor(t *testing.T) *JSONLDProcessor {
+	loader, err := NewPinnedDocumentLoader()
+	require.NoError(t, err)
+	return NewJSONLDProcessor(loader)
+}
+
+func TestJSONLD_PinnedLoader(t *testing.T) {
+	loader, err := NewPinnedDocumentLoader()
+	require.NoError(t, err)
+
+	for _, url := range []string{
+		CredentialsContextV1,
+		CredentialsContextV2,
+		"https://www.w3.org/ns/did/v1",
+		"https://w3id.org/security/suites/ed25519-2020/v1",
+		"https://w3id.org/vc/status-list/2021/v1",
+	} {
+		assert.Contains(t, loader.URLs(), url)
+		document, err := loader.LoadDocument(url)
+		require.NoError(t, err, url)
+		assert.Contains(t, document.Document, "@context")
+	}
+
+	// Unknown contexts are never fetched unless allow-listed
+	_, err = loader.LoadDocument("https://example.com/contexts/v1")
+	assert.ErrorContains(t, err, "not pinned or allow-listed")
+
+	// Pinning checks the digest
+	document := []byte(`{"@context": {"ex": "https://example.com/vocab#"}}`)
+	assert.Error(t, loader.Pin("https://example.com/contexts/v1", document, ComputeDigestSRI([]byte("other"))))
+	require.NoError(t, loader.Pin("https://example.com/contexts/v1", document, ComputeDigestSRI(document)))
+	_, err = loader.LoadDocument("https://example.com/contexts/v1")
+	assert.NoError(t, err)
+}
+
+func TestJSONLD_AllowListedRemoteContext(t *testing.T) {
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/ld+json")
+		w.Write([]byte(`{"@context": {"degree": "https://example.com/vocab#degree"}}`))
+	}))
+	defer server.Close()
+
+	loader, err := NewPinnedDocumentLoader()
+	require.NoError(t, err)
+	loader.client = server.Client()
+
+	_, err = loader.LoadDocument(server.URL + "/v1")
+	assert.Error(t, err)
+
+	loader.AllowRemote(server.URL + "/")
+	for i := 0; i < 2; i++ {
+		document, err := loader.LoadDocument(server.URL + "/v1")
+		require.NoError(t, err)
+		assert.Contains(t, document.Document, "@context")
+	}
+	assert.Equal(t, 1, requests, "remote contexts are cached")
+
+	// Only https is fetched, even when allow-listed
+	loader.AllowRemote("http://example.com/")
+	_, err = loader.LoadDocument("http://example.com/v1")
+	assert.ErrorContains(t, err, "https")
+}
+
+func TestJSONLD_Expand(t *testing.T) {
+	processor := newTestJSONLDProcessor(t)
+
+	credential := map[string]interface{}{
+		"@context":          []interface{}{CredentialsContextV1},
+		"type":              []interface{}{"VerifiableCredential"},
+		"issuer":            "did:example:issuer",
+		"issuanceDate":      "2024-01-01T00:00:00Z",
+		"credentialSubject": map[string]interface{}{"id": "did:example:holder", "degree": "BSc"},
+	}
+
+	expanded, err := processor.Expand(credential, nil)
+	require.NoError(t, err)
+	require.Len(t, expanded, 1)
+	node := expanded[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{"https://www.w3.org/2018/credentials#VerifiableCredential"}, node["@type"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"@id": "did:example:issuer"}}, node["https://www.w3.org/2018/credentials#issuer"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"@value": "2024-01-01T00:00:00Z",
+		"@type":  "http://www.w3.org/2001/XMLSchema#dateTime",
+	}}, node["https://www.w3.org/2018/credentials#issuanceDate"])
+
+	// The 1.1 context has no @vocab, so undefined terms are dropped, or
+	// rejected in safe mode
+	subject := node["https://www.w3.org/2018/credentials#credentialSubject"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"@id": "did:example:holder"}, subject)
+	_, err = processor.Expand(credential, &JSONLDOptions{SafeMode: true})
+	assert.ErrorContains(t, err, "degree")
+
+	// Protected terms cannot be redefined
+	credential["@context"] = []interface{}{CredentialsContextV1, map[string]interface{}{"proof": "https://example.com/proof"}}
+	_, err = processor.Expand(credential, nil)
+	assert.ErrorContains(t, err, "protected term redefinition")
+
+	// Unknown contexts fail expansion
+	credential["@context"] = []interface{}{CredentialsContextV1, "https://example.com/contexts/v1"}
+	_, err = processor.Expand(credential, nil)
+	assert.ErrorContains(t, err, "loading remote context failed")
+}
+
+func TestJSONLD_ExpandScopedContexts(t *testing.T) {
+	processor := newTestJSONLDProcessor(t)
+
+	document := map[string]interface{}{
+		"@context": map[string]interface{}{
+			"@vocab": "https://example.com/vocab#",
+			"Person": map[string]interface{}{
+				"@id":      "https://schema.org/Person",
+				"@context": map[string]interface{}{"name": "https://schema.org/name"},
+			},
+			"labels": map[string]interface{}{"@id": "https://example.com/vocab#labels", "@container": "@language"},
+			"tags":   map[string]interface{}{"@id": "https://example.com/vocab#tags", "@container": "@list"},
+		},
+		"@type":  "Person",
+		"name":   "Alice",
+		"labels": map[string]interface{}{"en": "Friend", "de": "Freundin"},
+		"tags":   []interface{}{"b", "a"},
+		"knows":  map[string]interface{}{"name": "Bob"},
+	}
+
+	expanded, err := processor.Expand(document, nil)
+	require.NoError(t, err)
+	node := expanded[0].(map[string]interface{})
+
+	// The type-scoped context applies to the node but not to nested nodes
+	assert.Equal(t, []interface{}{map[string]interface{}{"@value": "Alice"}}, node["https://schema.org/name"])
+	knows := node["https://example.com/vocab#knows"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, knows, "https://example.com/vocab#name")
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"@value": "Freundin", "@language": "de"},
+		map[string]interface{}{"@value": "Friend", "@language": "en"},
+	}, node["https://example.com/vocab#labels"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"@list": []interface{}{
+		map[string]interface{}{"@value": "b"},
+		map[string]interface{}{"@value": "a"},
+	}}}, node["https://example.com/vocab#tags"])
+}
+
+func TestJSONLD_CompactRoundTrip(t *testing.T) {
+	processor := newTestJSONLDProcessor(t)
+
+	context := map[string]interface{}{
+		"name":  "https://schema.org/name",
+		"knows": map[string]interface{}{"@id": "https://schema.org/knows", "@type": "@id"},
+		"tags":  map[string]interface{}{"@id": "https://example.com/vocab#tags", "@container": "@list"},
+		"ex":    "https://example.com/vocab#",
+	}
+	document := map[string]interface{}{
+		"@context":    context,
+		"@id":         "https://example.com/alice",
+		"name":        "Alice",
+		"knows":       "https://example.com/bob",
+		"tags":        []interface{}{"b", "a"},
+		"ex:nickname": "Al",
+	}
+
+	compacted, err := processor.Compact(document, context, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"@context":    context,
+		"@id":         "https://example.com/alice",
+		"name":        "Alice",
+		"knows":       "https://example.com/bob",
+		"tags":        []interface{}{"b", "a"},
+		"ex:nickname": "Al",
+	}, compacted)
+
+	// Compacting against the credentials context uses its terms
+	credential := map[string]interface{}{
+		"@context":     []interface{}{CredentialsContextV1},
+		"type":         "VerifiableCredential",
+		"issuer":       "did:example:issuer",
+		"issuanceDate": "2024-01-01T00:00:00Z",
+	}
+	compacted, err = processor.Compact(credential, CredentialsContextV1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "VerifiableCredential", compacted["type"])
+	assert.Equal(t, "did:example:issuer", compacted["issuer"])
+	assert.Equal(t, "2024-01-01T00:00:00Z", compacted["issuanceDate"])
+}
+
+func TestJSONLD_Canonicalize(t *testing.T) {
+	processor := newTestJSONLDProcessor(t)
+
+	nquads, err := processor.Canonicalize(map[string]interface{}{
+		"@id":                   "https://example.com/a",
+		"https://example.com/p": "line\nbreak \"quoted\"",
+		"https://example.com/n": 5,
+		"https://example.com/f": 1.5,
+		"https://example.com/b": true,
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"<https://example.com/a> <https://example.com/b> \"true\"^^<http://www.w3.org/2001/XMLSchema#boolean> .\n"+
+		"<https://example.com/a> <https://example.com/f> \"1.5E0\"^^<http://www.w3.org/2001/XMLSchema#double> .\n"+
+		"<https://example.com/a> <https://example.com/n> \"5\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n"+
+		"<https://example.com/a> <https://example.com/p> \"line\\nbreak \\\"quoted\\\"\" .\n", nquads)
+
+	// Two blank nodes pointing at each other are indistinguishable, so the
+	// n-degree hash decides their labels
+	cycle := func(first, second string) interface{} {
+		return []interface{}{
+			map[string]interface{}{"@id": first, "https://example.com/p": map[string]interface{}{"@id": second}},
+			map[string]interface{}{"@id": second, "https://example.com/p": map[string]interface{}{"@id": first}},
+		}
+	}
+	nquads, err = processor.Canonicalize(cycle("_:x", "_:y"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"_:c14n0 <https://example.com/p> _:c14n1 .\n"+
+		"_:c14n1 <https://example.com/p> _:c14n0 .\n", nquads)
+	relabelled, err := processor.Canonicalize(cycle("_:q", "_:a"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, nquads, relabelled)
+
+	// Canonical form does not depend on blank node labels or input order
+	graph := func(subject, object string, reverse bool) interface{} {
+		nodes := []interface{}{
+			map[string]interface{}{"@id": subject, "https://example.com/name": "s", "https://example.com/ref": map[string]interface{}{"@id": object}},
+			map[string]interface{}{"@id": object, "https://example.com/name": "o"},
+		}
+		if reverse {
+			nodes[0], nodes[1] = nodes[1], nodes[0]
+		}
+		return nodes
+	}
+	first, err := processor.Canonicalize(graph("_:s", "_:o", false), nil)
+	require.NoError(t, err)
+	second, err := processor.Canonicalize(graph("_:b7", "_:b2", true), nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.NotContains(t, first, "_:s")
+}
+
+// parseTestNQuads reads the N-Quads subset used by the canonicalization
+// vectors: IRIs, blank nodes and simple literals, one quad per line
+func parseTestNQuads(t *testing.T, nquads string) []Quad {
+	var quads []Quad
+	for _, line := range strings.Split(strings.TrimSpace(nquads), "\n") {
+		fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(line), " ."))
+		require.True(t, len(fields) == 3 || len(fields) == 4, "malformed quad %q", line)
+
+		terms := make([]RDFTerm, len(fields))
+		for i, field := range fields {
+			switch {
+			case strings.HasPrefix(field, "<") && strings.HasSuffix(field, ">"):
+				terms[i] = RDFTerm{Kind: RDFTermIRI, Value: field[1 : len(field)-1]}
+			case strings.HasPrefix(field, "_:"):
+				terms[i] = RDFTerm{Kind: RDFTermBlankNode, Value: field}
+			case strings.HasPrefix(field, `"`) && strings.HasSuffix(field, `"`):
+				terms[i] = RDFTerm{Kind: RDFTermLiteral, Value: field[1 : len(field)-1], Datatype: xsdString}
+			default:
+				t.Fatalf("unsupported term %q", field)
+			}
+		}
+		quad := Quad{Subject: terms[0], Predicate: terms[1], Object: terms[2]}
+		if len(terms) == 4 {
+			quad.Graph = &terms[3]
+		}
+		quads = append(quads, quad)
+	}
+	return quads
+}
+
+func TestJSONLD_CanonicalizationVectors(t *testing.T) {
+	// The worked examples of the W3C RDF Dataset Canonicalization
+	// recommendation, with the first degree hashes it lists for each blank
+	// node
+	vectors := []struct {
+		name        string
+		input       string
+		expected    string
+		firstDegree map[string]string
+	}{
+		{
+			name: "unique hashes",
+			input: `
+<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .`,
+			expected: `<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+			firstDegree: map[string]string{
+				"_:e0": "21d1dd5ba21f3dee9d76c0c00c260fa6f5d5d65315099e553026f4828d0dc77a",
+				"_:e1": "6fa0b9bdb376852b5743ff39ca4cbf7ea14d34966b2828478fbf222e7c764473",
+			},
+		},
+		{
+			// e0 and e1 share a first degree hash, so the n-degree hash
+			// orders them
+			name: "shared hashes",
+			input: `
+<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#q> _:e1 .
+_:e0 <http://example.com/#p> _:e2 .
+_:e1 <http://example.com/#p> _:e3 .
+_:e2 <http://example.com/#r> _:e3 .`,
+			expected: `<http://example.com/#p> <http://example.com/#q> _:c14n2 .
+<http://example.com/#p> <http://example.com/#q> _:c14n3 .
+_:c14n0 <http://example.com/#r> _:c14n1 .
+_:c14n2 <http://example.com/#p> _:c14n1 .
+_:c14n3 <http://example.com/#p> _:c14n0 .
+`,
+			firstDegree: map[string]string{
+				"_:e0": "3b26142829b8887d011d779079a243bd61ab53c3990d550320a17b59ade6ba36",
+				"_:e1": "3b26142829b8887d011d779079a243bd61ab53c3990d550320a17b59ade6ba36",
+				"_:e2": "15973d39de079913dac841ac4fa8c4781c0febfba5e83e5c6e250869587f8659",
+				"_:e3": "7e790a99273eed1dc57e43205d37ce232252c85b26ca4a6ff74ff3b5aea7bccd",
+			},
+		},
+	}
+
+	for _, vector := range vectors {
+		t.Run(vector.name, func(t *testing.T) {
+			quads := parseTestNQuads(t, vector.input)
+			nquads, err := CanonicalizeDataset(quads)
+			require.NoError(t, err)
+			assert.Equal(t, vector.expected, nquads)
+
+			c := newURDNA2015(quads)
+			for blank, hash := range vector.firstDegree {
+				assert.Equal(t, hash, c.hashFirstDegree(blank), blank)
+			}
+
+			// Neither blank node labels nor quad order may matter
+			relabelled := strings.NewReplacer("_:e0", "_:z3", "_:e1", "_:a", "_:e2", "_:e1", "_:e3", "_:q").Replace(vector.input)
+			reordered := parseTestNQuads(t, relabelled)
+			for i, j := 0, len(reordered)-1; i < j; i, j = i+1, j-1 {
+				reordered[i], reordered[j] = reordered[j], reordered[i]
+			}
+			nquads, err = CanonicalizeDataset(reordered)
+			require.NoError(t, err)
+			assert.Equal(t, vector.expected, nquads)
+		})
+	}
+
+	t.Run("self reference", func(t *testing.T) {
+		// A quad naming a blank node twice is one of its quads, not two
+		quads := parseTestNQuads(t, `
+_:e0 <http://example.com/#p> _:e0 .
+_:e0 <http://example.com/#q> _:e1 .
+_:e1 <http://example.com/#q> _:e0 .`)
+		assert.Equal(t, sha256Hex(""+
+			"_:a <http://example.com/#p> _:a .\n"+
+			"_:a <http://example.com/#q> _:z .\n"+
+			"_:z <http://example.com/#q> _:a .\n"), newURDNA2015(quads).hashFirstDegree("_:e0"))
+
+		nquads, err := CanonicalizeDataset(quads)
+		require.NoError(t, err)
+		assert.Equal(t, ""+
+			"_:c14n0 <http://example.com/#p> _:c14n0 .\n"+
+			"_:c14n0 <http://example.com/#q> _:c14n1 .\n"+
+			"_:c14n1 <http://example.com/#q> _:c14n0 .\n", nquads)
+	})
+}
+
+func TestJSONLD_CanonicalizeCredential(t *testing.T) {
+	processor := newTestJSONLDProcessor(t)
+
+	credential := v2TestCredential()
+	credential.Proof = nil
+	nquads, err := processor.Canonicalize(credential, &JSONLDOptions{SafeMode: true})
+	require.NoError(t, err)
+	assert.Contains(t, nquads, `<https://www.w3.org/2018/credentials#validFrom> "2024-01-01T00:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime>`)
+	assert.Contains(t, nquads, `<http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://www.w3.org/ns/credentials/examples#ExampleDegreeCredential>`)
+	assert.Equal(t, 6, strings.Count(nquads, "\n"))
+}
+
+func TestJSONLD_CanonicalizationWorkLimit(t *testing.T) {
+	// A clique of indistinguishable blank nodes needs factorially many
+	// permutations
+	var quads []Quad
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			if i == j {
+				continue
+			}
+			quads = append(quads, Quad{
+				Subject:   RDFTerm{Kind: RDFTermBlankNode, Value: "_:n" + string(rune('a'+i))},
+				Predicate: RDFTerm{Kind: RDFTermIRI, Value: "https://example.com/p"},
+				Object:    RDFTerm{Kind: RDFTermBlankNode, Value: "_:n" + string(rune('a'+j))},
+			})
+		}
+	}
+	_, err := CanonicalizeDataset(quads)
+	assert.ErrorContains(t, err, "work limit")
+}
+
+func TestJSONLD_VerifierExpansion(t *testing.T) {
+	verifier := NewDefaultCredentialVerifier(did.NewDefaultKeyManager(), did.NewMultiDIDResolver())
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	options := &VerificationOptions{Now: &now, ExpandJSONLD: true}
+
+	result, err := verifier.VerifyCredential(v2TestCredential(), options)
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+
+	// A 1.1 credential using terms no context defines
+	credential := &VerifiableCredential{
+		Context:           []string{CredentialsContextV1},
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            "did:example:issuer",
+		IssuanceDate:      "2024-01-01T00:00:00Z",
+		CredentialSubject: map[string]interface{}{"id": "did:example:holder", "degree": "BSc"},
+		Proof:             map[string]interface{}{"type": "Ed25519Signature2018", "jws": "eyJ..."},
+	}
+	result, err = verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "JSON-LD expansion failed")
+
+	result, err = verifier.VerifyCredential(credential, &VerificationOptions{Now: &now})
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+
+	// Contexts outside the pinned bundle are rejected
+	credential = v2TestCredential()
+	credential.Context = append(credential.Context, "https://example.com/contexts/v1")
+	result, err = verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "not pinned")
+}
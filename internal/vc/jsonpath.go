@@ -0,0 +1,795 @@
+package vc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONPath is a compiled JSONPath query (RFC 9535). Filters may be written
+// either as ?<expr> or in the older ?(<expr>) form used by Presentation
+// Exchange definitions.
+type JSONPath struct {
+	expression string
+	relative   bool
+	segments   []jsonPathSegment
+}
+
+// JSONPathNode is a value selected by a query and its location
+type JSONPathNode struct {
+	// Path is the normalized path of the node, e.g. $['credentialSubject']['name']
+	Path string `json:"path"`
+	// Tokens are the member names (string) and array indices (int) of Path
+	Tokens []interface{} `json:"-"`
+	Value  interface{}   `json:"value"`
+}
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelectorKind int
+
+const (
+	jsonPathName jsonPathSelectorKind = iota
+	jsonPathIndex
+	jsonPathWildcard
+	jsonPathSlice
+	jsonPathFilter
+)
+
+type jsonPathSelector struct {
+	kind             jsonPathSelectorKind
+	name             string
+	index            int
+	start, end, step *int
+	filter           jsonPathExpr
+}
+
+// CompileJSONPath parses a JSONPath expression
+func CompileJSONPath(expression string) (*JSONPath, error) {
+	p := &jsonPathParser{src: expression}
+	path, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", expression, err)
+	}
+	if path.relative {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", expression)
+	}
+	if p.pos != len(p.src) {
+		return nil, fmt.Errorf("invalid JSONPath %q: unexpected %q at offset %d", expression, p.src[p.pos:], p.pos)
+	}
+	path.expression = expression
+	return path, nil
+}
+
+// QueryJSONPath compiles expression and evaluates it against document
+func QueryJSONPath(expression string, document interface{}) ([]JSONPathNode, error) {
+	path, err := CompileJSONPath(expression)
+	if err != nil {
+		return nil, err
+	}
+	return path.Query(document), nil
+}
+
+// String returns the source expression
+func (jp *JSONPath) String() string {
+	return jp.expression
+}
+
+// Query returns the nodes the path selects from document, in document
+// order. Object members are visited in sorted key order.
+func (jp *JSONPath) Query(document interface{}) []JSONPathNode {
+	return jp.query(document, document)
+}
+
+func (jp *JSONPath) query(root, current interface{}) []JSONPathNode {
+	nodes := []JSONPathNode{{Path: "$", Value: current}}
+	if !jp.relative {
+		nodes[0].Value = root
+	}
+
+	for _, segment := range jp.segments {
+		var next []JSONPathNode
+		for _, node := range nodes {
+			if segment.descendant {
+				for _, descendant := range jsonPathDescendants(node) {
+					next = append(next, segment.apply(root, descendant)...)
+				}
+			} else {
+				next = append(next, segment.apply(root, node)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (s jsonPathSegment) apply(root interface{}, node JSONPathNode) []JSONPathNode {
+	var selected []JSONPathNode
+	for _, selector := range s.selectors {
+		selected = append(selected, selector.apply(root, node)...)
+	}
+	return selected
+}
+
+func (sel jsonPathSelector) apply(root interface{}, node JSONPathNode) []JSONPathNode {
+	switch sel.kind {
+	case jsonPathName:
+		if object, ok := node.Value.(map[string]interface{}); ok {
+			if value, ok := object[sel.name]; ok {
+				return []JSONPathNode{node.child(sel.name, value)}
+			}
+		}
+	case jsonPathIndex:
+		if array, ok := node.Value.([]interface{}); ok {
+			i := sel.index
+			if i < 0 {
+				i += len(array)
+			}
+			if i >= 0 && i < len(array) {
+				return []JSONPathNode{node.child(i, array[i])}
+			}
+		}
+	case jsonPathWildcard:
+		return jsonPathChildren(node)
+	case jsonPathSlice:
+		if array, ok := node.Value.([]interface{}); ok {
+			var selected []JSONPathNode
+			for _, i := range sliceIndices(len(array), sel.start, sel.end, sel.step) {
+				selected = append(selected, node.child(i, array[i]))
+			}
+			return selected
+		}
+	case jsonPathFilter:
+		var selected []JSONPathNode
+		for _, child := range jsonPathChildren(node) {
+			if jsonPathTest(sel.filter, root, child.Value) {
+				selected = append(selected, child)
+			}
+		}
+		return selected
+	}
+	return nil
+}
+
+func (n JSONPathNode) child(token interface{}, value interface{}) JSONPathNode {
+	tokens := append(n.Tokens[:len(n.Tokens):len(n.Tokens)], token)
+	var path string
+	switch t := token.(type) {
+	case string:
+		path = n.Path + "['" + escapeJSONPathName(t) + "']"
+	case int:
+		path = n.Path + "[" + strconv.Itoa(t) + "]"
+	}
+	return JSONPathNode{Path: path, Tokens: tokens, Value: value}
+}
+
+func jsonPathChildren(node JSONPathNode) []JSONPathNode {
+	var children []JSONPathNode
+	switch value := node.Value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			children = append(children, node.child(key, value[key]))
+		}
+	case []interface{}:
+		for i, item := range value {
+			children = append(children, node.child(i, item))
+		}
+	}
+	return children
+}
+
+// jsonPathDescendants returns node and all of its descendants, parents first
+func jsonPathDescendants(node JSONPathNode) []JSONPathNode {
+	nodes := []JSONPathNode{node}
+	for _, child := range jsonPathChildren(node) {
+		nodes = append(nodes, jsonPathDescendants(child)...)
+	}
+	return nodes
+}
+
+func sliceIndices(length int, start, end, step *int) []int {
+	s := 1
+	if step != nil {
+		s = *step
+	}
+	if s == 0 {
+		return nil
+	}
+
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	clamp := func(i, lower, upper int) int {
+		if i < lower {
+			return lower
+		}
+		if i > upper {
+			return upper
+		}
+		return i
+	}
+
+	var indices []int
+	if s > 0 {
+		lower, upper := 0, length
+		if start != nil {
+			lower = clamp(normalize(*start), 0, length)
+		}
+		if end != nil {
+			upper = clamp(normalize(*end), 0, length)
+		}
+		for i := lower; i < upper; i += s {
+			indices = append(indices, i)
+		}
+	} else {
+		upper, lower := length-1, -1
+		if start != nil {
+			upper = clamp(normalize(*start), -1, length-1)
+		}
+		if end != nil {
+			lower = clamp(normalize(*end), -1, length-1)
+		}
+		for i := upper; i > lower; i += s {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func escapeJSONPathName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch r {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Filter expressions
+
+type jsonPathExpr interface{}
+
+type jsonPathOr struct{ terms []jsonPathExpr }
+type jsonPathAnd struct{ terms []jsonPathExpr }
+type jsonPathNot struct{ expr jsonPathExpr }
+type jsonPathLiteral struct{ value interface{} }
+type jsonPathQuery struct{ path *JSONPath }
+
+type jsonPathComparison struct {
+	op          string
+	left, right jsonPathExpr
+}
+
+type jsonPathFunction struct {
+	name string
+	args []jsonPathExpr
+}
+
+// jsonPathNothing marks the absence of a value, e.g. a query that selected
+// no node
+type jsonPathNothing struct{}
+
+// jsonPathTest evaluates a filter expression in test position
+func jsonPathTest(expr jsonPathExpr, root, current interface{}) bool {
+	switch e := expr.(type) {
+	case jsonPathOr:
+		for _, term := range e.terms {
+			if jsonPathTest(term, root, current) {
+				return true
+			}
+		}
+		return false
+	case jsonPathAnd:
+		for _, term := range e.terms {
+			if !jsonPathTest(term, root, current) {
+				return false
+			}
+		}
+		return true
+	case jsonPathNot:
+		return !jsonPathTest(e.expr, root, current)
+	case jsonPathComparison:
+		return jsonPathCompare(e.op, jsonPathValue(e.left, root, current), jsonPathValue(e.right, root, current))
+	case jsonPathQuery:
+		return len(e.path.query(root, current)) > 0
+	case jsonPathFunction:
+		result, _ := jsonPathValue(e, root, current).(bool)
+		return result
+	case jsonPathLiteral:
+		result, _ := e.value.(bool)
+		return result
+	}
+	return false
+}
+
+// jsonPathValue evaluates a filter expression to a value or jsonPathNothing
+func jsonPathValue(expr jsonPathExpr, root, current interface{}) interface{} {
+	switch e := expr.(type) {
+	case jsonPathLiteral:
+		return e.value
+	case jsonPathQuery:
+		nodes := e.path.query(root, current)
+		if len(nodes) != 1 {
+			return jsonPathNothing{}
+		}
+		return nodes[0].Value
+	case jsonPathFunction:
+		return jsonPathCall(e, root, current)
+	}
+	return jsonPathNothing{}
+}
+
+func jsonPathCall(f jsonPathFunction, root, current interface{}) interface{} {
+	switch f.name {
+	case "length":
+		switch v := jsonPathValue(f.args[0], root, current).(type) {
+		case string:
+			return json.Number(strconv.Itoa(utf8.RuneCountInString(v)))
+		case []interface{}:
+			return json.Number(strconv.Itoa(len(v)))
+		case map[string]interface{}:
+			return json.Number(strconv.Itoa(len(v)))
+		}
+	case "count":
+		if query, ok := f.args[0].(jsonPathQuery); ok {
+			return json.Number(strconv.Itoa(len(query.path.query(root, current))))
+		}
+	case "value":
+		return jsonPathValue(f.args[0], root, current)
+	case "match", "search":
+		value, ok := jsonPathValue(f.args[0], root, current).(string)
+		pattern, isString := jsonPathValue(f.args[1], root, current).(string)
+		if !ok || !isString {
+			return false
+		}
+		if f.name == "match" {
+			pattern = "^(?:" + pattern + ")$"
+		}
+		re, err := regexp.Compile(pattern)
+		return err == nil && re.MatchString(value)
+	}
+	return jsonPathNothing{}
+}
+
+func jsonPathCompare(op string, left, right interface{}) bool {
+	switch op {
+	case "==":
+		return jsonPathEqual(left, right)
+	case "!=":
+		return !jsonPathEqual(left, right)
+	case "<":
+		return jsonPathLess(left, right)
+	case ">":
+		return jsonPathLess(right, left)
+	case "<=":
+		return jsonPathLess(left, right) || jsonPathEqual(left, right)
+	case ">=":
+		return jsonPathLess(right, left) || jsonPathEqual(left, right)
+	}
+	return false
+}
+
+func jsonPathEqual(left, right interface{}) bool {
+	_, leftNothing := left.(jsonPathNothing)
+	_, rightNothing := right.(jsonPathNothing)
+	if leftNothing || rightNothing {
+		return leftNothing && rightNothing
+	}
+	return jsonEqual(left, right)
+}
+
+func jsonPathLess(left, right interface{}) bool {
+	if l, ok := jsonNumber(left); ok {
+		if r, ok := jsonNumber(right); ok {
+			return l.Cmp(r) < 0
+		}
+		return false
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return l < r
+		}
+	}
+	return false
+}
+
+// Parser
+
+type jsonPathParser struct {
+	src string
+	pos int
+}
+
+func (p *jsonPathParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *jsonPathParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n\r", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// parseQuery parses an absolute ($) or, inside filters, relative (@) query
+func (p *jsonPathParser) parseQuery() (*JSONPath, error) {
+	path := &JSONPath{}
+	switch {
+	case p.consume("$"):
+	case p.consume("@"):
+		path.relative = true
+	default:
+		return nil, fmt.Errorf("expected $ or @ at offset %d", p.pos)
+	}
+
+	for {
+		switch {
+		case p.consume(".."):
+			segment, err := p.parseChildSegment()
+			if err != nil {
+				return nil, err
+			}
+			segment.descendant = true
+			path.segments = append(path.segments, segment)
+		case p.consume("."):
+			if p.peek() == '[' {
+				return nil, fmt.Errorf("unexpected [ after . at offset %d", p.pos)
+			}
+			segment, err := p.parseChildSegment()
+			if err != nil {
+				return nil, err
+			}
+			path.segments = append(path.segments, segment)
+		case p.peek() == '[':
+			segment, err := p.parseChildSegment()
+			if err != nil {
+				return nil, err
+			}
+			path.segments = append(path.segments, segment)
+		default:
+			return path, nil
+		}
+	}
+}
+
+// parseChildSegment parses *, a member name or a bracketed selector list
+func (p *jsonPathParser) parseChildSegment() (jsonPathSegment, error) {
+	if p.consume("*") {
+		return jsonPathSegment{selectors: []jsonPathSelector{{kind: jsonPathWildcard}}}, nil
+	}
+	if p.consume("[") {
+		return p.parseBracketed()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '@' || r == '$' || r >= 0x80) {
+			break
+		}
+		p.pos += size
+	}
+	if start == p.pos {
+		return jsonPathSegment{}, fmt.Errorf("expected member name at offset %d", p.pos)
+	}
+	return jsonPathSegment{selectors: []jsonPathSelector{{kind: jsonPathName, name: p.src[start:p.pos]}}}, nil
+}
+
+func (p *jsonPathParser) parseBracketed() (jsonPathSegment, error) {
+	var segment jsonPathSegment
+	for {
+		p.skipSpace()
+		selector, err := p.parseSelector()
+		if err != nil {
+			return segment, err
+		}
+		segment.selectors = append(segment.selectors, selector)
+
+		p.skipSpace()
+		if p.consume("]") {
+			return segment, nil
+		}
+		if !p.consume(",") {
+			return segment, fmt.Errorf("expected , or ] at offset %d", p.pos)
+		}
+	}
+}
+
+func (p *jsonPathParser) parseSelector() (jsonPathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		return jsonPathSelector{kind: jsonPathName, name: name}, err
+	case c == '*':
+		p.pos++
+		return jsonPathSelector{kind: jsonPathWildcard}, nil
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.parseOr()
+		return jsonPathSelector{kind: jsonPathFilter, filter: expr}, err
+	default:
+		return p.parseIndexOrSlice()
+	}
+}
+
+func (p *jsonPathParser) parseIndexOrSlice() (jsonPathSelector, error) {
+	var parts [3]*int
+	part := 0
+	for {
+		p.skipSpace()
+		if n, ok := p.parseInt(); ok {
+			parts[part] = &n
+		}
+		p.skipSpace()
+		if p.peek() != ':' || part == 2 {
+			break
+		}
+		p.pos++
+		part++
+	}
+
+	if part == 0 {
+		if parts[0] == nil {
+			return jsonPathSelector{}, fmt.Errorf("expected selector at offset %d", p.pos)
+		}
+		return jsonPathSelector{kind: jsonPathIndex, index: *parts[0]}, nil
+	}
+	return jsonPathSelector{kind: jsonPathSlice, start: parts[0], end: parts[1], step: parts[2]}, nil
+}
+
+func (p *jsonPathParser) parseInt() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+// parseString parses a single- or double-quoted string literal
+func (p *jsonPathParser) parseString() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.src) {
+				return "", fmt.Errorf("unterminated escape")
+			}
+			escaped := p.src[p.pos]
+			p.pos++
+			switch escaped {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					return "", fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("invalid unicode escape")
+				}
+				p.pos += 4
+				b.WriteRune(rune(code))
+			default:
+				b.WriteByte(escaped)
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *jsonPathParser) parseOr() (jsonPathExpr, error) {
+	var terms []jsonPathExpr
+	for {
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return jsonPathOr{terms: terms}, nil
+}
+
+func (p *jsonPathParser) parseAnd() (jsonPathExpr, error) {
+	var terms []jsonPathExpr
+	for {
+		term, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return jsonPathAnd{terms: terms}, nil
+}
+
+func (p *jsonPathParser) parseBasic() (jsonPathExpr, error) {
+	p.skipSpace()
+
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		expr, err := p.parseBasic()
+		return jsonPathNot{expr: expr}, err
+	}
+
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, fmt.Errorf("expected ) at offset %d", p.pos)
+		}
+		return p.parseComparisonTail(expr)
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseComparisonTail(left)
+}
+
+func (p *jsonPathParser) parseComparisonTail(left jsonPathExpr) (jsonPathExpr, error) {
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.skipSpace()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return jsonPathComparison{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *jsonPathParser) parseOperand() (jsonPathExpr, error) {
+	p.skipSpace()
+	c := p.peek()
+
+	switch {
+	case c == '$' || c == '@':
+		path, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPathQuery{path: path}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		return jsonPathLiteral{value: s}, err
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		number := p.src[start:p.pos]
+		if _, ok := new(big.Rat).SetString(number); !ok {
+			return nil, fmt.Errorf("invalid number %q", number)
+		}
+		return jsonPathLiteral{value: json.Number(number)}, nil
+	case p.consume("true"):
+		return jsonPathLiteral{value: true}, nil
+	case p.consume("false"):
+		return jsonPathLiteral{value: false}, nil
+	case p.consume("null"):
+		return jsonPathLiteral{value: nil}, nil
+	}
+
+	// Function call
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' || p.src[p.pos] == '_') {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	arity := map[string]int{"length": 1, "count": 1, "value": 1, "match": 2, "search": 2}
+	expected, known := arity[name]
+	if !known || !p.consume("(") {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.src[start:], start)
+	}
+
+	var args []jsonPathExpr
+	for {
+		p.skipSpace()
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipSpace()
+		if p.consume(")") {
+			break
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expected , or ) at offset %d", p.pos)
+		}
+	}
+	if len(args) != expected {
+		return nil, fmt.Errorf("%s() takes %d arguments", name, expected)
+	}
+	return jsonPathFunction{name: name, args: args}, nil
+}
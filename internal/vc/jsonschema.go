@@ -0,0 +1,1175 @@
+package vc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// JSONSchemaDraft202012 is the $schema URI of JSON Schema draft 2020-12
+	JSONSchemaDraft202012 = "https://json-schema.org/draft/2020-12/schema"
+	// JSONSchemaDraft7 is the $schema URI of JSON Schema draft-07
+	JSONSchemaDraft7 = "http://json-schema.org/draft-07/schema#"
+)
+
+// maxSchemaDepth bounds nested evaluation so that reference cycles which never
+// consume instance data fail instead of recursing forever
+const maxSchemaDepth = 256
+
+type schemaDraft int
+
+const (
+	draft7 schemaDraft = iota + 1
+	draft202012
+)
+
+func parseSchemaDraft(uri string) (schemaDraft, error) {
+	switch strings.TrimSuffix(uri, "#") {
+	case "https://json-schema.org/draft/2020-12/schema":
+		return draft202012, nil
+	case "http://json-schema.org/draft-07/schema", "https://json-schema.org/draft-07/schema":
+		return draft7, nil
+	default:
+		return 0, fmt.Errorf("unsupported $schema %q", uri)
+	}
+}
+
+// schemaRef is a schema node together with the base URI and dialect in
+// effect inside it
+type schemaRef struct {
+	node  interface{}
+	base  string
+	draft schemaDraft
+}
+
+// enterSchema applies node's $schema and $id on top of the parent's scope
+func enterSchema(parent schemaRef, node interface{}) (schemaRef, error) {
+	ref := schemaRef{node: node, base: parent.base, draft: parent.draft}
+
+	switch m := node.(type) {
+	case bool:
+		return ref, nil
+	case map[string]interface{}:
+		if dialect, ok := m["$schema"].(string); ok {
+			draft, err := parseSchemaDraft(dialect)
+			if err != nil {
+				return ref, err
+			}
+			ref.draft = draft
+		}
+		if id, ok := m["$id"].(string); ok && !(ref.draft == draft7 && strings.HasPrefix(id, "#")) {
+			ref.base, _ = splitSchemaFragment(resolveSchemaURI(parent.base, id))
+		}
+		return ref, nil
+	default:
+		return ref, fmt.Errorf("schema must be an object or a boolean")
+	}
+}
+
+// forEachSubschema calls fn for every keyword of m whose value is a schema,
+// with the keyword location of that schema relative to m
+func forEachSubschema(m map[string]interface{}, draft schemaDraft, fn func(location string, node interface{}) error) error {
+	single := []string{"additionalProperties", "contains", "propertyNames", "not", "if", "then", "else"}
+	lists := []string{"allOf", "anyOf", "oneOf"}
+	maps := []string{"properties", "patternProperties"}
+
+	if draft == draft7 {
+		single = append(single, "additionalItems")
+		maps = append(maps, "definitions", "dependencies")
+	} else {
+		single = append(single, "unevaluatedItems", "unevaluatedProperties")
+		lists = append(lists, "prefixItems")
+		maps = append(maps, "$defs", "definitions", "dependentSchemas")
+	}
+
+	for _, keyword := range single {
+		if node, ok := m[keyword]; ok {
+			if err := fn("/"+keyword, node); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch items := m["items"].(type) {
+	case []interface{}:
+		for i, node := range items {
+			if err := fn("/items/"+strconv.Itoa(i), node); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := fn("/items", items); err != nil {
+			return err
+		}
+	}
+
+	for _, keyword := range lists {
+		list, _ := m[keyword].([]interface{})
+		for i, node := range list {
+			if err := fn("/"+keyword+"/"+strconv.Itoa(i), node); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, keyword := range maps {
+		entries, _ := m[keyword].(map[string]interface{})
+		for _, name := range sortedKeys(entries) {
+			node := entries[name]
+			// draft-07 dependencies mix schemas and property lists
+			if _, isList := node.([]interface{}); isList && keyword == "dependencies" {
+				continue
+			}
+			if err := fn("/"+keyword+"/"+escapeJSONPointer(name), node); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// schemaIndex maps absolute URIs to the schema resources and anchors of the
+// documents loaded for an evaluation
+type schemaIndex struct {
+	resources      map[string]schemaRef
+	anchors        map[string]schemaRef
+	dynamicAnchors map[string]schemaRef
+}
+
+func newSchemaIndex() *schemaIndex {
+	return &schemaIndex{
+		resources:      make(map[string]schemaRef),
+		anchors:        make(map[string]schemaRef),
+		dynamicAnchors: make(map[string]schemaRef),
+	}
+}
+
+// add indexes a schema document retrieved from uri and returns its root
+func (ix *schemaIndex) add(uri string, document interface{}, draft schemaDraft) (schemaRef, error) {
+	uri, _ = splitSchemaFragment(uri)
+	root, err := enterSchema(schemaRef{base: uri, draft: draft}, document)
+	if err != nil {
+		return root, err
+	}
+
+	ix.resources[uri] = root
+	if err := ix.walk(root); err != nil {
+		return root, err
+	}
+	return root, nil
+}
+
+func (ix *schemaIndex) walk(ref schemaRef) error {
+	m, ok := ref.node.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if id, ok := m["$id"].(string); ok {
+		if ref.draft == draft7 && strings.HasPrefix(id, "#") {
+			ix.anchors[ref.base+id] = ref
+		} else {
+			ix.resources[ref.base] = ref
+		}
+	}
+	if ref.draft == draft202012 {
+		if anchor, ok := m["$anchor"].(string); ok {
+			ix.anchors[ref.base+"#"+anchor] = ref
+		}
+		if anchor, ok := m["$dynamicAnchor"].(string); ok {
+			ix.anchors[ref.base+"#"+anchor] = ref
+			ix.dynamicAnchors[ref.base+"#"+anchor] = ref
+		}
+	}
+
+	return forEachSubschema(m, ref.draft, func(location string, node interface{}) error {
+		child, err := enterSchema(ref, node)
+		if err != nil {
+			return fmt.Errorf("invalid schema at %s: %w", location, err)
+		}
+		return ix.walk(child)
+	})
+}
+
+// schemaEvaluator validates instances against indexed schemas, loading
+// referenced documents on demand
+type schemaEvaluator struct {
+	index         *schemaIndex
+	load          func(uri string) (interface{}, error)
+	defaultDraft  schemaDraft
+	assertFormats bool
+	regexps       map[string]*regexp.Regexp
+}
+
+func newSchemaEvaluator(index *schemaIndex, load func(uri string) (interface{}, error)) *schemaEvaluator {
+	return &schemaEvaluator{
+		index:         index,
+		load:          load,
+		defaultDraft:  draft202012,
+		assertFormats: true,
+		regexps:       make(map[string]*regexp.Regexp),
+	}
+}
+
+// schemaEvalResult holds the errors of an evaluation and the properties and
+// items it evaluated, which unevaluatedProperties/unevaluatedItems consult
+type schemaEvalResult struct {
+	errors   []SchemaValidationError
+	props    map[string]bool
+	items    map[int]bool
+	allItems bool
+}
+
+func (r *schemaEvalResult) valid() bool {
+	return len(r.errors) == 0
+}
+
+// absorb adds another evaluation of the same instance to r
+func (r *schemaEvalResult) absorb(other *schemaEvalResult) {
+	r.errors = append(r.errors, other.errors...)
+	r.mergeAnnotations(other)
+}
+
+func (r *schemaEvalResult) mergeAnnotations(other *schemaEvalResult) {
+	for name := range other.props {
+		r.markProperty(name)
+	}
+	for i := range other.items {
+		r.markItem(i)
+	}
+	r.allItems = r.allItems || other.allItems
+}
+
+func (r *schemaEvalResult) markProperty(name string) {
+	if r.props == nil {
+		r.props = make(map[string]bool)
+	}
+	r.props[name] = true
+}
+
+func (r *schemaEvalResult) markItem(i int) {
+	if r.items == nil {
+		r.items = make(map[int]bool)
+	}
+	r.items[i] = true
+}
+
+func (e *schemaEvaluator) fail(result *schemaEvalResult, instancePath []string, schemaPath, code, format string, args ...interface{}) {
+	result.errors = append(result.errors, SchemaValidationError{
+		Field:      instanceField(instancePath),
+		Path:       instancePointer(instancePath),
+		SchemaPath: schemaPath,
+		Code:       code,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// validate evaluates instance against schema. dynamicScope lists the base
+// URIs of the schema resources entered so far, outermost first.
+func (e *schemaEvaluator) validate(schema schemaRef, instance interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) *schemaEvalResult {
+	result := &schemaEvalResult{}
+
+	if depth > maxSchemaDepth {
+		e.fail(result, instancePath, schemaPath, "$ref", "schema nesting exceeds %d levels; references may be circular", maxSchemaDepth)
+		return result
+	}
+
+	switch m := schema.node.(type) {
+	case bool:
+		if !m {
+			e.fail(result, instancePath, schemaPath, "false", "no value is allowed here")
+		}
+		return result
+	case map[string]interface{}:
+		if len(dynamicScope) == 0 || dynamicScope[len(dynamicScope)-1] != schema.base {
+			dynamicScope = append(dynamicScope[:len(dynamicScope):len(dynamicScope)], schema.base)
+		}
+
+		if ref, ok := m["$ref"].(string); ok {
+			e.applyRef(result, schema, ref, false, instance, instancePath, schemaPath+"/$ref", dynamicScope, depth)
+			// In draft-07 $ref replaces every sibling keyword
+			if schema.draft == draft7 {
+				return result
+			}
+		}
+		if ref, ok := m["$dynamicRef"].(string); ok && schema.draft == draft202012 {
+			e.applyRef(result, schema, ref, true, instance, instancePath, schemaPath+"/$dynamicRef", dynamicScope, depth)
+		}
+
+		e.validateGeneric(result, m, instance, instancePath, schemaPath)
+		e.validateCombinators(result, schema, m, instance, instancePath, schemaPath, dynamicScope, depth)
+
+		switch value := instance.(type) {
+		case map[string]interface{}:
+			e.validateObject(result, schema, m, value, instancePath, schemaPath, dynamicScope, depth)
+		case []interface{}:
+			e.validateArray(result, schema, m, value, instancePath, schemaPath, dynamicScope, depth)
+		case string:
+			e.validateString(result, m, value, instancePath, schemaPath)
+		default:
+			if number, ok := jsonNumber(instance); ok {
+				e.validateNumber(result, m, number, instancePath, schemaPath)
+			}
+		}
+		return result
+	default:
+		e.fail(result, instancePath, schemaPath, "schema", "schema must be an object or a boolean")
+		return result
+	}
+}
+
+// subschema evaluates a subschema located at location below schema
+func (e *schemaEvaluator) subschema(schema schemaRef, node interface{}, instance interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) *schemaEvalResult {
+	child, err := enterSchema(schema, node)
+	if err != nil {
+		result := &schemaEvalResult{}
+		e.fail(result, instancePath, schemaPath, "schema", "%v", err)
+		return result
+	}
+	return e.validate(child, instance, instancePath, schemaPath, dynamicScope, depth+1)
+}
+
+func (e *schemaEvaluator) applyRef(result *schemaEvalResult, schema schemaRef, ref string, dynamic bool, instance interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) {
+	uri := resolveSchemaURI(schema.base, ref)
+	target, err := e.resolve(uri)
+	if err != nil {
+		e.fail(result, instancePath, schemaPath, "$ref", "cannot resolve %s: %v", ref, err)
+		return
+	}
+
+	// A $dynamicRef whose initial target declares the matching
+	// $dynamicAnchor resolves to the outermost resource in the dynamic scope
+	// that declares it too
+	if _, fragment := splitSchemaFragment(uri); dynamic && fragment != "" && !strings.HasPrefix(fragment, "/") {
+		if m, ok := target.node.(map[string]interface{}); ok && m["$dynamicAnchor"] == fragment {
+			for _, scope := range dynamicScope {
+				if outer, ok := e.index.dynamicAnchors[scope+"#"+fragment]; ok {
+					target = outer
+					break
+				}
+			}
+		}
+	}
+
+	result.absorb(e.validate(target, instance, instancePath, schemaPath, dynamicScope, depth+1))
+}
+
+// resolve finds the schema an absolute URI points at, loading its document
+// if it has not been seen yet
+func (e *schemaEvaluator) resolve(uri string) (schemaRef, error) {
+	documentURI, fragment := splitSchemaFragment(uri)
+
+	root, ok := e.index.resources[documentURI]
+	if !ok {
+		if e.load == nil {
+			return schemaRef{}, fmt.Errorf("schema %s is not available", documentURI)
+		}
+		document, err := e.load(documentURI)
+		if err != nil {
+			return schemaRef{}, err
+		}
+		if root, err = e.index.add(documentURI, document, e.defaultDraft); err != nil {
+			return schemaRef{}, err
+		}
+	}
+
+	if fragment == "" {
+		return root, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		anchored, ok := e.index.anchors[documentURI+"#"+fragment]
+		if !ok {
+			return schemaRef{}, fmt.Errorf("anchor %q not found in %s", fragment, documentURI)
+		}
+		return anchored, nil
+	}
+
+	// Walk the JSON pointer, picking up $id changes along the way
+	current := root
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = unescapeJSONPointer(token)
+		var next interface{}
+		switch node := current.node.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return schemaRef{}, fmt.Errorf("pointer %s not found in %s", fragment, documentURI)
+			}
+			next = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return schemaRef{}, fmt.Errorf("pointer %s not found in %s", fragment, documentURI)
+			}
+			next = node[index]
+		default:
+			return schemaRef{}, fmt.Errorf("pointer %s not found in %s", fragment, documentURI)
+		}
+
+		if child, ok := next.(map[string]interface{}); ok {
+			entered, err := enterSchema(current, child)
+			if err != nil {
+				return schemaRef{}, err
+			}
+			current = entered
+		} else {
+			current = schemaRef{node: next, base: current.base, draft: current.draft}
+		}
+	}
+	return current, nil
+}
+
+// validateGeneric applies the keywords that hold for every instance type
+func (e *schemaEvaluator) validateGeneric(result *schemaEvalResult, m map[string]interface{}, instance interface{}, instancePath []string, schemaPath string) {
+	if expected, ok := m["type"]; ok {
+		var types []string
+		switch t := expected.(type) {
+		case string:
+			types = []string{t}
+		case []interface{}:
+			for _, v := range t {
+				if s, ok := v.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+		if !matchesJSONType(instance, types) {
+			e.fail(result, instancePath, schemaPath+"/type", "type", "expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(instance))
+		}
+	}
+
+	if allowed, ok := m["enum"].([]interface{}); ok {
+		found := false
+		for _, value := range allowed {
+			if jsonEqual(instance, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			e.fail(result, instancePath, schemaPath+"/enum", "enum", "value is not one of the allowed values")
+		}
+	}
+
+	if expected, ok := m["const"]; ok && !jsonEqual(instance, expected) {
+		e.fail(result, instancePath, schemaPath+"/const", "const", "value must be %s", compactJSON(expected))
+	}
+}
+
+func (e *schemaEvaluator) validateCombinators(result *schemaEvalResult, schema schemaRef, m map[string]interface{}, instance interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) {
+	if list, ok := m["allOf"].([]interface{}); ok {
+		for i, node := range list {
+			result.absorb(e.subschema(schema, node, instance, instancePath, schemaPath+"/allOf/"+strconv.Itoa(i), dynamicScope, depth))
+		}
+	}
+
+	if list, ok := m["anyOf"].([]interface{}); ok {
+		matched := false
+		for i, node := range list {
+			branch := e.subschema(schema, node, instance, instancePath, schemaPath+"/anyOf/"+strconv.Itoa(i), dynamicScope, depth)
+			if branch.valid() {
+				matched = true
+				result.mergeAnnotations(branch)
+			}
+		}
+		if !matched {
+			e.fail(result, instancePath, schemaPath+"/anyOf", "anyOf", "value does not match any of the %d allowed schemas", len(list))
+		}
+	}
+
+	if list, ok := m["oneOf"].([]interface{}); ok {
+		var matches []int
+		for i, node := range list {
+			branch := e.subschema(schema, node, instance, instancePath, schemaPath+"/oneOf/"+strconv.Itoa(i), dynamicScope, depth)
+			if branch.valid() {
+				matches = append(matches, i)
+				if len(matches) == 1 {
+					result.mergeAnnotations(branch)
+				}
+			}
+		}
+		switch len(matches) {
+		case 1:
+		case 0:
+			e.fail(result, instancePath, schemaPath+"/oneOf", "oneOf", "value does not match any of the %d schemas", len(list))
+		default:
+			e.fail(result, instancePath, schemaPath+"/oneOf", "oneOf", "value matches schemas %v but must match exactly one", matches)
+		}
+	}
+
+	if node, ok := m["not"]; ok {
+		if e.subschema(schema, node, instance, instancePath, schemaPath+"/not", dynamicScope, depth).valid() {
+			e.fail(result, instancePath, schemaPath+"/not", "not", "value must not match the schema")
+		}
+	}
+
+	if node, ok := m["if"]; ok {
+		condition := e.subschema(schema, node, instance, instancePath, schemaPath+"/if", dynamicScope, depth)
+		if condition.valid() {
+			result.mergeAnnotations(condition)
+			if then, ok := m["then"]; ok {
+				result.absorb(e.subschema(schema, then, instance, instancePath, schemaPath+"/then", dynamicScope, depth))
+			}
+		} else if otherwise, ok := m["else"]; ok {
+			result.absorb(e.subschema(schema, otherwise, instance, instancePath, schemaPath+"/else", dynamicScope, depth))
+		}
+	}
+}
+
+func (e *schemaEvaluator) validateObject(result *schemaEvalResult, schema schemaRef, m map[string]interface{}, object map[string]interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) {
+	names := sortedKeys(object)
+
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := object[name]; !present {
+					e.fail(result, instancePath, schemaPath+"/required", "required", "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	if limit, ok := schemaInt(m["minProperties"]); ok && len(object) < limit {
+		e.fail(result, instancePath, schemaPath+"/minProperties", "minProperties", "must have at least %d properties", limit)
+	}
+	if limit, ok := schemaInt(m["maxProperties"]); ok && len(object) > limit {
+		e.fail(result, instancePath, schemaPath+"/maxProperties", "maxProperties", "must have at most %d properties", limit)
+	}
+
+	// Properties evaluated by properties/patternProperties of this schema,
+	// which additionalProperties skips
+	local := make(map[string]bool)
+
+	if properties, ok := m["properties"].(map[string]interface{}); ok {
+		for _, name := range names {
+			node, ok := properties[name]
+			if !ok {
+				continue
+			}
+			local[name] = true
+			result.markProperty(name)
+			result.absorb(e.subschema(schema, node, object[name], appendPath(instancePath, name), schemaPath+"/properties/"+escapeJSONPointer(name), dynamicScope, depth))
+		}
+	}
+
+	if patterns, ok := m["patternProperties"].(map[string]interface{}); ok {
+		for _, pattern := range sortedKeys(patterns) {
+			re, err := e.regexp(pattern)
+			if err != nil {
+				e.fail(result, instancePath, schemaPath+"/patternProperties", "schema", "invalid pattern %q: %v", pattern, err)
+				continue
+			}
+			for _, name := range names {
+				if !re.MatchString(name) {
+					continue
+				}
+				local[name] = true
+				result.markProperty(name)
+				result.absorb(e.subschema(schema, patterns[pattern], object[name], appendPath(instancePath, name), schemaPath+"/patternProperties/"+escapeJSONPointer(pattern), dynamicScope, depth))
+			}
+		}
+	}
+
+	if node, ok := m["additionalProperties"]; ok {
+		for _, name := range names {
+			if local[name] {
+				continue
+			}
+			result.markProperty(name)
+			result.absorb(e.subschema(schema, node, object[name], appendPath(instancePath, name), schemaPath+"/additionalProperties", dynamicScope, depth))
+		}
+	}
+
+	if node, ok := m["propertyNames"]; ok {
+		for _, name := range names {
+			result.errors = append(result.errors, e.subschema(schema, node, name, appendPath(instancePath, name), schemaPath+"/propertyNames", dynamicScope, depth).errors...)
+		}
+	}
+
+	dependentRequired := func(keyword, name string, list []interface{}) {
+		if _, present := object[name]; !present {
+			return
+		}
+		for _, r := range list {
+			if required, ok := r.(string); ok {
+				if _, present := object[required]; !present {
+					e.fail(result, instancePath, schemaPath+"/"+keyword+"/"+escapeJSONPointer(name), keyword, "property %q is required when %q is present", required, name)
+				}
+			}
+		}
+	}
+	dependentSchema := func(keyword, name string, node interface{}) {
+		if _, present := object[name]; present {
+			result.absorb(e.subschema(schema, node, object, instancePath, schemaPath+"/"+keyword+"/"+escapeJSONPointer(name), dynamicScope, depth))
+		}
+	}
+
+	if schema.draft == draft7 {
+		if dependencies, ok := m["dependencies"].(map[string]interface{}); ok {
+			for _, name := range sortedKeys(dependencies) {
+				if list, ok := dependencies[name].([]interface{}); ok {
+					dependentRequired("dependencies", name, list)
+				} else {
+					dependentSchema("dependencies", name, dependencies[name])
+				}
+			}
+		}
+		return
+	}
+
+	if dependencies, ok := m["dependentRequired"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependencies) {
+			list, _ := dependencies[name].([]interface{})
+			dependentRequired("dependentRequired", name, list)
+		}
+	}
+	if dependencies, ok := m["dependentSchemas"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(dependencies) {
+			dependentSchema("dependentSchemas", name, dependencies[name])
+		}
+	}
+
+	if node, ok := m["unevaluatedProperties"]; ok {
+		for _, name := range names {
+			if result.props[name] {
+				continue
+			}
+			result.absorb(e.subschema(schema, node, object[name], appendPath(instancePath, name), schemaPath+"/unevaluatedProperties", dynamicScope, depth))
+		}
+		for _, name := range names {
+			result.markProperty(name)
+		}
+	}
+}
+
+func (e *schemaEvaluator) validateArray(result *schemaEvalResult, schema schemaRef, m map[string]interface{}, array []interface{}, instancePath []string, schemaPath string, dynamicScope []string, depth int) {
+	if limit, ok := schemaInt(m["minItems"]); ok && len(array) < limit {
+		e.fail(result, instancePath, schemaPath+"/minItems", "minItems", "must have at least %d items", limit)
+	}
+	if limit, ok := schemaInt(m["maxItems"]); ok && len(array) > limit {
+		e.fail(result, instancePath, schemaPath+"/maxItems", "maxItems", "must have at most %d items", limit)
+	}
+	if unique, _ := m["uniqueItems"].(bool); unique {
+	duplicates:
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if jsonEqual(array[i], array[j]) {
+					e.fail(result, instancePath, schemaPath+"/uniqueItems", "uniqueItems", "items %d and %d are equal", i, j)
+					break duplicates
+				}
+			}
+		}
+	}
+
+	item := func(i int, node interface{}, location string) {
+		result.markItem(i)
+		result.absorb(e.subschema(schema, node, array[i], appendPath(instancePath, strconv.Itoa(i)), location, dynamicScope, depth))
+	}
+
+	// Tuple items, then the schema that applies to the rest
+	var prefix []interface{}
+	prefixKeyword, restKeyword := "prefixItems", "items"
+	if schema.draft == draft7 {
+		prefixKeyword, restKeyword = "items", "additionalItems"
+	}
+	prefix, _ = m[prefixKeyword].([]interface{})
+	for i := 0; i < len(prefix) && i < len(array); i++ {
+		item(i, prefix[i], schemaPath+"/"+prefixKeyword+"/"+strconv.Itoa(i))
+	}
+
+	rest, hasRest := m[restKeyword]
+	if schema.draft == draft7 {
+		if _, isTuple := m["items"].([]interface{}); !isTuple {
+			// A single draft-07 items schema applies to every item
+			rest, hasRest = m["items"]
+			restKeyword = "items"
+		}
+	}
+	if _, isList := rest.([]interface{}); hasRest && !isList {
+		for i := len(prefix); i < len(array); i++ {
+			item(i, rest, schemaPath+"/"+restKeyword)
+		}
+		result.allItems = true
+	}
+
+	if node, ok := m["contains"]; ok {
+		var matched []int
+		for i := range array {
+			if e.subschema(schema, node, array[i], appendPath(instancePath, strconv.Itoa(i)), schemaPath+"/contains", dynamicScope, depth).valid() {
+				matched = append(matched, i)
+			}
+		}
+
+		minimum, maximum := 1, -1
+		if schema.draft == draft202012 {
+			if limit, ok := schemaInt(m["minContains"]); ok {
+				minimum = limit
+			}
+			if limit, ok := schemaInt(m["maxContains"]); ok {
+				maximum = limit
+			}
+			for _, i := range matched {
+				result.markItem(i)
+			}
+		}
+
+		if len(matched) < minimum {
+			if minimum == 1 {
+				e.fail(result, instancePath, schemaPath+"/contains", "contains", "no item matches the contains schema")
+			} else {
+				e.fail(result, instancePath, schemaPath+"/minContains", "minContains", "%d items match the contains schema, at least %d required", len(matched), minimum)
+			}
+		}
+		if maximum >= 0 && len(matched) > maximum {
+			e.fail(result, instancePath, schemaPath+"/maxContains", "maxContains", "%d items match the contains schema, at most %d allowed", len(matched), maximum)
+		}
+	}
+
+	if node, ok := m["unevaluatedItems"]; ok && schema.draft == draft202012 {
+		if !result.allItems {
+			for i := range array {
+				if !result.items[i] {
+					item(i, node, schemaPath+"/unevaluatedItems")
+				}
+			}
+		}
+		result.allItems = true
+	}
+}
+
+func (e *schemaEvaluator) validateString(result *schemaEvalResult, m map[string]interface{}, value string, instancePath []string, schemaPath string) {
+	length := utf8.RuneCountInString(value)
+	if limit, ok := schemaInt(m["minLength"]); ok && length < limit {
+		e.fail(result, instancePath, schemaPath+"/minLength", "minLength", "must be at least %d characters long", limit)
+	}
+	if limit, ok := schemaInt(m["maxLength"]); ok && length > limit {
+		e.fail(result, instancePath, schemaPath+"/maxLength", "maxLength", "must be at most %d characters long", limit)
+	}
+
+	if pattern, ok := m["pattern"].(string); ok {
+		re, err := e.regexp(pattern)
+		if err != nil {
+			e.fail(result, instancePath, schemaPath+"/pattern", "schema", "invalid pattern %q: %v", pattern, err)
+		} else if !re.MatchString(value) {
+			e.fail(result, instancePath, schemaPath+"/pattern", "pattern", "does not match pattern %q", pattern)
+		}
+	}
+
+	if format, ok := m["format"].(string); ok && e.assertFormats {
+		if check, known := schemaFormats[format]; known && !check(value) {
+			e.fail(result, instancePath, schemaPath+"/format", "format", "is not a valid %s", format)
+		}
+	}
+
+	// formatMinimum and friends bound date and date-time values; Presentation
+	// Exchange filters use them for expiry and age checks
+	if format, ok := m["format"].(string); ok {
+		if instant, ok := parseFormattedTime(format, value); ok {
+			bound := func(keyword string, fails func(cmp int) bool, message string) {
+				limitValue, ok := m[keyword].(string)
+				if !ok {
+					return
+				}
+				limit, ok := parseFormattedTime(format, limitValue)
+				if ok && fails(instant.Compare(limit)) {
+					e.fail(result, instancePath, schemaPath+"/"+keyword, keyword, "must be %s %s", message, limitValue)
+				}
+			}
+			bound("formatMinimum", func(cmp int) bool { return cmp < 0 }, "on or after")
+			bound("formatMaximum", func(cmp int) bool { return cmp > 0 }, "on or before")
+			bound("formatExclusiveMinimum", func(cmp int) bool { return cmp <= 0 }, "after")
+			bound("formatExclusiveMaximum", func(cmp int) bool { return cmp >= 0 }, "before")
+		}
+	}
+}
+
+// parseFormattedTime parses a date or date-time string for comparison
+func parseFormattedTime(format, value string) (time.Time, bool) {
+	var layout string
+	switch format {
+	case "date":
+		layout = "2006-01-02"
+	case "date-time":
+		layout = time.RFC3339Nano
+		value = strings.ToUpper(value)
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.Parse(layout, value)
+	return t, err == nil
+}
+
+func (e *schemaEvaluator) validateNumber(result *schemaEvalResult, m map[string]interface{}, value *big.Rat, instancePath []string, schemaPath string) {
+	compare := func(keyword string, fails func(cmp int) bool, message string) {
+		limit, ok := jsonNumber(m[keyword])
+		if ok && fails(value.Cmp(limit)) {
+			e.fail(result, instancePath, schemaPath+"/"+keyword, keyword, "must be %s %s", message, compactJSON(m[keyword]))
+		}
+	}
+	compare("minimum", func(cmp int) bool { return cmp < 0 }, ">=")
+	compare("maximum", func(cmp int) bool { return cmp > 0 }, "<=")
+	compare("exclusiveMinimum", func(cmp int) bool { return cmp <= 0 }, ">")
+	compare("exclusiveMaximum", func(cmp int) bool { return cmp >= 0 }, "<")
+
+	if divisor, ok := jsonNumber(m["multipleOf"]); ok && divisor.Sign() > 0 {
+		if !new(big.Rat).Quo(value, divisor).IsInt() {
+			e.fail(result, instancePath, schemaPath+"/multipleOf", "multipleOf", "must be a multiple of %s", compactJSON(m["multipleOf"]))
+		}
+	}
+}
+
+func (e *schemaEvaluator) regexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := e.regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	e.regexps[pattern] = re
+	return re, nil
+}
+
+// schemaFormats are the format assertions checked when format validation is
+// enabled; unknown formats are ignored as the specification requires
+var schemaFormats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, strings.ToUpper(s))
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"time": func(s string) bool {
+		_, err := time.Parse("15:04:05.999999999Z07:00", strings.ToUpper(s))
+		return err == nil
+	},
+	"duration": func(s string) bool {
+		return durationPattern.MatchString(s) && s != "P" && !strings.HasSuffix(s, "T")
+	},
+	"email":     isEmail,
+	"idn-email": isEmail,
+	"hostname":  isHostname,
+	"idn-hostname": func(s string) bool {
+		return s != "" && len(s) <= 253
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		return strings.Contains(s, ":") && net.ParseIP(s) != nil
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"iri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"uri-reference": func(s string) bool {
+		_, err := url.Parse(s)
+		return err == nil
+	},
+	"iri-reference": func(s string) bool {
+		_, err := url.Parse(s)
+		return err == nil
+	},
+	"uri-template": func(s string) bool {
+		depth := 0
+		for _, r := range s {
+			switch r {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth < 0 || depth > 1 {
+				return false
+			}
+		}
+		return depth == 0
+	},
+	"uuid": func(s string) bool {
+		return uuidPattern.MatchString(s)
+	},
+	"regex": func(s string) bool {
+		_, err := regexp.Compile(s)
+		return err == nil
+	},
+	"json-pointer": isJSONPointer,
+	"relative-json-pointer": func(s string) bool {
+		match := relativePointerPattern.FindStringSubmatch(s)
+		return match != nil && (match[2] == "#" || isJSONPointer(match[2]))
+	},
+}
+
+var (
+	durationPattern        = regexp.MustCompile(`^P(\d+W|(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+(\.\d+)?S)?)?)$`)
+	uuidPattern            = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnameLabelPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	relativePointerPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)(.*)$`)
+)
+
+func isEmail(s string) bool {
+	address, err := mail.ParseAddress(s)
+	return err == nil && address.Address == s && address.Name == ""
+}
+
+func isHostname(s string) bool {
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(s, "."), ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+func isJSONPointer(s string) bool {
+	if s == "" {
+		return true
+	}
+	if !strings.HasPrefix(s, "/") {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && (i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1')) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeJSONDocument decodes JSON keeping numbers exact
+func decodeJSONDocument(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	return document, nil
+}
+
+// toJSONDocument converts a Go value to the generic form schemas evaluate
+func toJSONDocument(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONDocument(data)
+}
+
+func jsonNumber(value interface{}) (*big.Rat, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(n.String())
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(n) == nil {
+			return nil, false
+		}
+		return r, true
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	default:
+		return nil, false
+	}
+}
+
+func schemaInt(value interface{}) (int, bool) {
+	number, ok := jsonNumber(value)
+	if !ok || !number.IsInt() || !number.Num().IsInt64() {
+		return 0, false
+	}
+	return int(number.Num().Int64()), true
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if number, ok := jsonNumber(value); ok {
+		if number.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesJSONType(value interface{}, types []string) bool {
+	actual := jsonTypeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares JSON values, treating numbers by value
+func jsonEqual(a, b interface{}) bool {
+	an, aNumber := jsonNumber(a)
+	bn, bNumber := jsonNumber(b)
+	if aNumber || bNumber {
+		return aNumber && bNumber && an.Cmp(bn) == 0
+	}
+
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		bv, ok := b.(bool)
+		return ok && av == bv
+	case string:
+		bv, ok := b.(string)
+		return ok && av == bv
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func compactJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func resolveSchemaURI(base, ref string) string {
+	if strings.HasPrefix(ref, "#") {
+		document, _ := splitSchemaFragment(base)
+		return document + ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// splitSchemaFragment splits a URI into its document part and its decoded
+// fragment
+func splitSchemaFragment(uri string) (string, string) {
+	index := strings.Index(uri, "#")
+	if index < 0 {
+		return uri, ""
+	}
+	fragment := uri[index+1:]
+	if decoded, err := url.PathUnescape(fragment); err == nil {
+		fragment = decoded
+	}
+	return uri[:index], fragment
+}
+
+func appendPath(path []string, token string) []string {
+	return append(path[:len(path):len(path)], token)
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func unescapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// instancePointer renders an instance location as a JSON pointer
+func instancePointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteString("/")
+		b.WriteString(escapeJSONPointer(token))
+	}
+	return b.String()
+}
+
+// instanceField renders an instance location as a JSONPath expression, e.g.
+// $.credentialSubject.degrees[0].name. Array indices cannot be told apart
+// from numeric property names in the token list, so both render as [n].
+func instanceField(path []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, token := range path {
+		if _, err := strconv.Atoi(token); err == nil {
+			b.WriteString("[" + token + "]")
+		} else if identifierPattern.MatchString(token) {
+			b.WriteString("." + token)
+		} else {
+			b.WriteString("[" + strconv.Quote(token) + "]")
+		}
+	}
+	return b.String()
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_@$][A-Za-z0-9_@$-]*$`)
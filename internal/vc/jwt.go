@@ -1,9 +1,9 @@
 package vc
 
 import (
+	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"strings"
 	"time"
 
@@ -53,6 +53,18 @@ func (p *JWTCredentialProcessor) ParseJWTCredential(token string) (*JWTCredentia
 		return nil, NewVCErrorWithDetails(ErrorInvalidJWT, "failed to parse claims", err.Error())
 	}
 
+	// A vc+jwt payload is the credential itself rather than a vc claim
+	if header.Type == VCJWTType {
+		var credential VerifiableCredential
+		if err := json.Unmarshal(claimsBytes, &credential); err != nil {
+			return nil, NewVCErrorWithDetails(ErrorInvalidJWT, "failed to parse credential", err.Error())
+		}
+		jwtCred.VC = &credential
+		if jwtCred.Issuer == "" {
+			jwtCred.Issuer = getIssuerID(credential.Issuer)
+		}
+	}
+
 	jwtCred.Header = header
 	jwtCred.Token = token
 
@@ -88,6 +100,18 @@ func (p *JWTCredentialProcessor) ParseJWTPresentation(token string) (*JWTPresent
 		return nil, NewVCErrorWithDetails(ErrorInvalidJWT, "failed to parse claims", err.Error())
 	}
 
+	// A vp+jwt payload is the presentation itself rather than a vp claim
+	if header.Type == VPJWTType {
+		var presentation VerifiablePresentation
+		if err := json.Unmarshal(claimsBytes, &presentation); err != nil {
+			return nil, NewVCErrorWithDetails(ErrorInvalidJWT, "failed to parse presentation", err.Error())
+		}
+		jwtPres.VP = &presentation
+		if jwtPres.Issuer == "" {
+			jwtPres.Issuer = presentation.Holder
+		}
+	}
+
 	jwtPres.Header = header
 	jwtPres.Token = token
 
@@ -104,8 +128,12 @@ func (p *JWTCredentialProcessor) CreateJWTCredential(template *CredentialTemplat
 		return "", NewVCError(ErrorInvalidCredential, "options cannot be nil")
 	}
 
-	// Create the VC payload
 	now := time.Now()
+	if version, _ := contextVersion(template.Context); version == DataModelV2 {
+		return p.createVCJWT(template, options, privateKey, now)
+	}
+
+	// Create the VC payload
 	vc := &VerifiableCredential{
 		Context:           template.Context,
 		Type:              template.Type,
@@ -156,6 +184,51 @@ func (p *JWTCredentialProcessor) CreateJWTCredential(template *CredentialTemplat
 	return p.signJWT(header, claims, privateKey)
 }
 
+// createVCJWT secures a VCDM 2.0 credential as a vc+jwt, whose payload is
+// the credential
+func (p *JWTCredentialProcessor) createVCJWT(template *CredentialTemplate, options *IssuanceOptions, privateKey interface{}, now time.Time) (string, error) {
+	credential := &VerifiableCredential{
+		Context:           template.Context,
+		Type:              template.Type,
+		Issuer:            template.Issuer,
+		ValidFrom:         template.ValidFrom,
+		ValidUntil:        template.ValidUntil,
+		CredentialSubject: template.CredentialSubject,
+		CredentialStatus:  template.CredentialStatus,
+		CredentialSchema:  template.CredentialSchema,
+	}
+	if credential.ValidFrom == "" {
+		credential.ValidFrom = now.UTC().Format(time.RFC3339)
+	}
+	if credential.ValidUntil == "" {
+		credential.ValidUntil = template.ExpirationDate
+	}
+
+	payload, err := json.Marshal(credential)
+	if err != nil {
+		return "", NewVCErrorWithDetails(ErrorInvalidCredential, "failed to encode credential", err.Error())
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", NewVCErrorWithDetails(ErrorInvalidCredential, "failed to encode credential", err.Error())
+	}
+	claims["iat"] = now.Unix()
+	for key, value := range options.AdditionalClaims {
+		claims[key] = value
+	}
+
+	header := map[string]interface{}{
+		"alg": options.Algorithm,
+		"typ": VCJWTType,
+		"cty": "vc",
+	}
+	if options.KeyID != "" {
+		header["kid"] = options.KeyID
+	}
+
+	return p.signJWT(header, claims, privateKey)
+}
+
 // CreateJWTPresentation creates a JWT presentation from credentials
 func (p *JWTCredentialProcessor) CreateJWTPresentation(credentials []interface{}, options *PresentationOptions, privateKey interface{}) (string, error) {
 	if options == nil {
@@ -219,6 +292,29 @@ func (p *JWTCredentialProcessor) VerifyJWTCredential(token string, options *Veri
 		}, nil
 	}
 
+	if jwtCred.VC == nil {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "JWT does not contain a credential",
+		}, nil
+	}
+
+	// vc+jwt secures a VCDM 2.0 credential, whose issuer signs it directly
+	if jwtCred.Header.Type == VCJWTType {
+		if jwtCred.VC.DataModelVersion() != DataModelV2 {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "vc+jwt credential must use the VCDM 2.0 context",
+			}, nil
+		}
+		if jwtCred.Issuer != getIssuerID(jwtCred.VC.Issuer) {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "iss does not match the credential issuer",
+			}, nil
+		}
+	}
+
 	// Get the issuer's public key
 	issuerDID := jwtCred.Issuer
 	publicKey, err := p.resolvePublicKey(issuerDID, jwtCred.Header.KeyID)
@@ -244,6 +340,18 @@ func (p *JWTCredentialProcessor) VerifyJWTCredential(token string, options *Veri
 			Error:    "time validation failed: " + err.Error(),
 		}, nil
 	}
+	if jwtCred.VC.DataModelVersion() == DataModelV2 {
+		now := time.Now()
+		if options != nil && options.Now != nil {
+			now = *options.Now
+		}
+		if err := checkValidityPeriod(jwtCred.VC, now); err != nil {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "time validation failed: " + err.Error(),
+			}, nil
+		}
+	}
 
 	// Check credential status if requested
 	if options != nil && options.CheckStatus && jwtCred.VC.CredentialStatus != nil {
@@ -262,6 +370,7 @@ func (p *JWTCredentialProcessor) VerifyJWTCredential(token string, options *Veri
 		Details: map[string]interface{}{
 			"issuer":    issuerDID,
 			"algorithm": jwtCred.Header.Algorithm,
+			"dataModel": string(jwtCred.VC.DataModelVersion()),
 		},
 	}, nil
 }
@@ -277,6 +386,13 @@ func (p *JWTCredentialProcessor) VerifyJWTPresentation(token string, options *Ve
 		}, nil
 	}
 
+	if jwtPres.Header.Type == VPJWTType && (jwtPres.VP == nil || jwtPres.VP.DataModelVersion() != DataModelV2) {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "vp+jwt presentation must use the VCDM 2.0 context",
+		}, nil
+	}
+
 	// Get the holder's public key
 	holderDID := jwtPres.Issuer
 	publicKey, err := p.resolvePublicKey(holderDID, jwtPres.Header.KeyID)
@@ -442,7 +558,7 @@ func (p *JWTCredentialProcessor) extractPublicKey(vm *did.VerificationMethod) (i
 
 		// For Ed25519, remove the multicodec prefix
 		if len(decoded) >= 2 && decoded[0] == 0xed && decoded[1] == 0x01 {
-			return decoded[2:], nil
+			return ed25519.PublicKey(decoded[2:]), nil
 		}
 
 		return decoded, nil
@@ -543,18 +659,8 @@ func getCredentialSubjectID(subject interface{}) string {
 	return ""
 }
 
-// Helper function to decode multibase (temporary implementation)
+// MultibaseDecode decodes a multibase string such as a publicKeyMultibase
+// value
 func MultibaseDecode(encoded string) ([]byte, error) {
-	if len(encoded) == 0 {
-		return nil, fmt.Errorf("empty multibase string")
-	}
-
-	// For now, delegate to the did package's encoding functions
-	// In a real implementation, this would be a proper multibase decoder
-	if strings.HasPrefix(encoded, "z") {
-		// Use a placeholder base58 decode
-		return []byte(encoded[1:]), nil
-	}
-	
-	return []byte(encoded), nil
-}
\ No newline at end of file
+	return did.MultibaseDecode(encoded)
+}
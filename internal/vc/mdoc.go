@@ -0,0 +1,655 @@
+package vc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"time"
+)
+
+// ISO 18013-5 mobile driving licence identifiers
+const (
+	MdocDocTypeMDL   = "org.iso.18013.5.1.mDL"
+	MdocNamespaceMDL = "org.iso.18013.5.1"
+)
+
+// MdocDocumentSignerEKU is the ISO 18013-5 extended key usage a document
+// signer certificate must carry
+var MdocDocumentSignerEKU = asn1.ObjectIdentifier{1, 0, 18013, 5, 1, 2}
+
+// IssuerSignedItem is one issuer-signed data element of an mdoc
+type IssuerSignedItem struct {
+	DigestID          uint64
+	Random            []byte
+	ElementIdentifier string
+	ElementValue      interface{}
+
+	// raw is the IssuerSignedItemBytes (tag 24) the MSO digest covers
+	raw CBORTag
+}
+
+// IssuerSigned holds an mdoc's issuer-signed data elements by namespace and
+// the issuer's signature over the mobile security object
+type IssuerSigned struct {
+	NameSpaces map[string][]*IssuerSignedItem
+	IssuerAuth *COSESign1
+}
+
+// ValidityInfo is the validity period of a mobile security object
+type ValidityInfo struct {
+	Signed         time.Time
+	ValidFrom      time.Time
+	ValidUntil     time.Time
+	ExpectedUpdate *time.Time
+}
+
+// MobileSecurityObject is the issuer-signed payload of an mdoc: the digests
+// of its data elements, the device key and the validity period
+type MobileSecurityObject struct {
+	Version         string
+	DigestAlgorithm string
+	DocType         string
+	ValueDigests    map[string]map[uint64][]byte
+	DeviceKey       crypto.PublicKey
+	ValidityInfo    ValidityInfo
+}
+
+// DeviceSigned holds the mdoc's device-signed data and device
+// authentication
+type DeviceSigned struct {
+	// NameSpacesBytes is the DeviceNameSpacesBytes (tag 24) as presented
+	NameSpacesBytes CBORTag
+	DeviceSignature *COSESign1
+	HasDeviceMac    bool
+}
+
+// MdocDocument is one document of a DeviceResponse
+type MdocDocument struct {
+	DocType      string
+	IssuerSigned *IssuerSigned
+	DeviceSigned *DeviceSigned
+}
+
+// DeviceResponse is an mdoc reader's view of what the holder presented
+type DeviceResponse struct {
+	Version   string
+	Documents []*MdocDocument
+	Status    int64
+}
+
+// VerifiedMdoc is the outcome of verifying an mdoc
+type VerifiedMdoc struct {
+	DocType string
+	MSO     *MobileSecurityObject
+	// Claims are the issuer-signed elements by namespace and identifier, in
+	// JSON form
+	Claims map[string]map[string]interface{}
+	// IssuerCertificate is the document signer certificate
+	IssuerCertificate *x509.Certificate
+	// IssuerID identifies the trust anchor (IACA) the document signer
+	// chains to, as x509:sha256:<certificate fingerprint>
+	IssuerID string
+	// Algorithm is the JOSE name of the issuer signature algorithm
+	Algorithm string
+	// DeviceAuthenticated is set when the holder's device signed the
+	// session, binding the mdoc to its presenter
+	DeviceAuthenticated bool
+	IssuerSigned        *IssuerSigned
+}
+
+// ParseIssuerSigned decodes a CBOR IssuerSigned structure
+func ParseIssuerSigned(data []byte) (*IssuerSigned, error) {
+	item, err := UnmarshalCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IssuerSigned: %w", err)
+	}
+	return parseIssuerSigned(item)
+}
+
+func parseIssuerSigned(item interface{}) (*IssuerSigned, error) {
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("IssuerSigned must be a map")
+	}
+
+	issuerAuth, err := ParseCOSESign1(m["issuerAuth"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuerAuth: %w", err)
+	}
+	result := &IssuerSigned{NameSpaces: make(map[string][]*IssuerSignedItem), IssuerAuth: issuerAuth}
+
+	if m["nameSpaces"] == nil {
+		return result, nil
+	}
+	nameSpaces, ok := m["nameSpaces"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("IssuerSigned nameSpaces must be a map")
+	}
+	for key, value := range nameSpaces {
+		namespace, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("namespace names must be text")
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("namespace %s must be an array", namespace)
+		}
+		for _, encoded := range items {
+			signedItem, err := parseIssuerSignedItem(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("namespace %s: %w", namespace, err)
+			}
+			result.NameSpaces[namespace] = append(result.NameSpaces[namespace], signedItem)
+		}
+	}
+	return result, nil
+}
+
+func parseIssuerSignedItem(encoded interface{}) (*IssuerSignedItem, error) {
+	tag, ok := encoded.(CBORTag)
+	if !ok {
+		return nil, fmt.Errorf("IssuerSignedItem must be embedded CBOR")
+	}
+	decoded, err := decodeEmbeddedCBOR(tag)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("IssuerSignedItem must be a map")
+	}
+
+	item := &IssuerSignedItem{raw: tag, ElementValue: m["elementValue"]}
+	digestID, ok := cborInt(m["digestID"])
+	if !ok || digestID < 0 {
+		return nil, fmt.Errorf("IssuerSignedItem digestID must be an unsigned integer")
+	}
+	item.DigestID = uint64(digestID)
+	if item.Random, ok = m["random"].([]byte); !ok || len(item.Random) < 16 {
+		return nil, fmt.Errorf("IssuerSignedItem random must be at least 16 bytes")
+	}
+	if item.ElementIdentifier, ok = m["elementIdentifier"].(string); !ok {
+		return nil, fmt.Errorf("IssuerSignedItem elementIdentifier must be text")
+	}
+	if _, ok := m["elementValue"]; !ok {
+		return nil, fmt.Errorf("IssuerSignedItem %s has no elementValue", item.ElementIdentifier)
+	}
+	return item, nil
+}
+
+// NewIssuerSignedItem creates an item with a fresh random salt
+func NewIssuerSignedItem(digestID uint64, identifier string, value interface{}) (*IssuerSignedItem, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	item := &IssuerSignedItem{DigestID: digestID, Random: random, ElementIdentifier: identifier, ElementValue: value}
+	var err error
+	item.raw, err = EncodedCBOR(map[interface{}]interface{}{
+		"digestID":          digestID,
+		"random":            random,
+		"elementIdentifier": identifier,
+		"elementValue":      value,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Bytes returns the IssuerSignedItemBytes the MSO digest is computed over
+func (i *IssuerSignedItem) Bytes() []byte {
+	return i.raw.Raw
+}
+
+// CBOR returns the IssuerSigned structure for encoding
+func (is *IssuerSigned) CBOR() map[interface{}]interface{} {
+	nameSpaces := make(map[interface{}]interface{}, len(is.NameSpaces))
+	for namespace, items := range is.NameSpaces {
+		encoded := make([]interface{}, len(items))
+		for i, item := range items {
+			encoded[i] = item.raw
+		}
+		nameSpaces[namespace] = encoded
+	}
+	return map[interface{}]interface{}{
+		"nameSpaces": nameSpaces,
+		"issuerAuth": is.IssuerAuth.CBOR(),
+	}
+}
+
+// Encode serializes the IssuerSigned structure
+func (is *IssuerSigned) Encode() ([]byte, error) {
+	return MarshalCBOR(is.CBOR())
+}
+
+// Select returns a copy holding only the named elements of each
+// namespace. The issuer signature still verifies, since the MSO signs each
+// element's digest separately.
+func (is *IssuerSigned) Select(elements map[string][]string) *IssuerSigned {
+	selected := &IssuerSigned{NameSpaces: make(map[string][]*IssuerSignedItem), IssuerAuth: is.IssuerAuth}
+	for namespace, identifiers := range elements {
+		for _, item := range is.NameSpaces[namespace] {
+			if containsString(identifiers, item.ElementIdentifier) {
+				selected.NameSpaces[namespace] = append(selected.NameSpaces[namespace], item)
+			}
+		}
+	}
+	return selected
+}
+
+// ParseDeviceResponse decodes a CBOR DeviceResponse
+func ParseDeviceResponse(data []byte) (*DeviceResponse, error) {
+	item, err := UnmarshalCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DeviceResponse: %w", err)
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("DeviceResponse must be a map")
+	}
+
+	response := &DeviceResponse{}
+	if response.Version, ok = m["version"].(string); !ok {
+		return nil, fmt.Errorf("DeviceResponse has no version")
+	}
+	if response.Status, ok = cborInt(m["status"]); !ok {
+		return nil, fmt.Errorf("DeviceResponse has no status")
+	}
+
+	documents, _ := m["documents"].([]interface{})
+	for i, value := range documents {
+		doc, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("document %d must be a map", i)
+		}
+		document := &MdocDocument{}
+		if document.DocType, ok = doc["docType"].(string); !ok {
+			return nil, fmt.Errorf("document %d has no docType", i)
+		}
+		if document.IssuerSigned, err = parseIssuerSigned(doc["issuerSigned"]); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if deviceSigned, ok := doc["deviceSigned"]; ok {
+			if document.DeviceSigned, err = parseDeviceSigned(deviceSigned); err != nil {
+				return nil, fmt.Errorf("document %d: %w", i, err)
+			}
+		}
+		response.Documents = append(response.Documents, document)
+	}
+	return response, nil
+}
+
+func parseDeviceSigned(item interface{}) (*DeviceSigned, error) {
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("deviceSigned must be a map")
+	}
+
+	deviceSigned := &DeviceSigned{}
+	if deviceSigned.NameSpacesBytes, ok = m["nameSpaces"].(CBORTag); !ok || deviceSigned.NameSpacesBytes.Number != CBORTagEncodedCBOR {
+		return nil, fmt.Errorf("deviceSigned nameSpaces must be embedded CBOR")
+	}
+
+	deviceAuth, ok := m["deviceAuth"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("deviceSigned has no deviceAuth")
+	}
+	if signature, ok := deviceAuth["deviceSignature"]; ok {
+		var err error
+		if deviceSigned.DeviceSignature, err = ParseCOSESign1(signature); err != nil {
+			return nil, fmt.Errorf("invalid deviceSignature: %w", err)
+		}
+	}
+	_, deviceSigned.HasDeviceMac = deviceAuth["deviceMac"]
+	return deviceSigned, nil
+}
+
+func parseMSO(payload []byte) (*MobileSecurityObject, error) {
+	item, err := UnmarshalCBOR(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MSO payload: %w", err)
+	}
+	// The payload is MobileSecurityObjectBytes, embedded CBOR
+	decoded, err := decodeEmbeddedCBOR(item)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MSO payload: %w", err)
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("MSO must be a map")
+	}
+
+	mso := &MobileSecurityObject{ValueDigests: make(map[string]map[uint64][]byte)}
+	mso.Version, _ = m["version"].(string)
+	mso.DigestAlgorithm, _ = m["digestAlgorithm"].(string)
+	if mso.DocType, ok = m["docType"].(string); !ok {
+		return nil, fmt.Errorf("MSO has no docType")
+	}
+
+	valueDigests, ok := m["valueDigests"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("MSO has no valueDigests")
+	}
+	for key, value := range valueDigests {
+		namespace, _ := key.(string)
+		digests, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("MSO digests of %s must be a map", namespace)
+		}
+		mso.ValueDigests[namespace] = make(map[uint64][]byte, len(digests))
+		for id, digest := range digests {
+			digestID, ok := cborInt(id)
+			digestBytes, isBytes := digest.([]byte)
+			if !ok || digestID < 0 || !isBytes {
+				return nil, fmt.Errorf("invalid MSO digest entry in %s", namespace)
+			}
+			mso.ValueDigests[namespace][uint64(digestID)] = digestBytes
+		}
+	}
+
+	deviceKeyInfo, ok := m["deviceKeyInfo"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("MSO has no deviceKeyInfo")
+	}
+	if mso.DeviceKey, err = COSEKeyToPublicKey(deviceKeyInfo["deviceKey"]); err != nil {
+		return nil, fmt.Errorf("invalid MSO device key: %w", err)
+	}
+
+	validity, ok := m["validityInfo"].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("MSO has no validityInfo")
+	}
+	for name, target := range map[string]*time.Time{
+		"signed":     &mso.ValidityInfo.Signed,
+		"validFrom":  &mso.ValidityInfo.ValidFrom,
+		"validUntil": &mso.ValidityInfo.ValidUntil,
+	} {
+		if *target, err = parseTDate(validity[name]); err != nil {
+			return nil, fmt.Errorf("MSO validityInfo %s: %w", name, err)
+		}
+	}
+	if expected, ok := validity["expectedUpdate"]; ok {
+		t, err := parseTDate(expected)
+		if err != nil {
+			return nil, fmt.Errorf("MSO validityInfo expectedUpdate: %w", err)
+		}
+		mso.ValidityInfo.ExpectedUpdate = &t
+	}
+	return mso, nil
+}
+
+// parseTDate reads a tdate, an RFC 3339 date-time under tag 0
+func parseTDate(value interface{}) (time.Time, error) {
+	tag, ok := value.(CBORTag)
+	if !ok || tag.Number != CBORTagDateTime {
+		return time.Time{}, fmt.Errorf("expected a tdate")
+	}
+	text, ok := tag.Content.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("tdate must be text")
+	}
+	return time.Parse(time.RFC3339, text)
+}
+
+func mdocDigest(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "SHA-256":
+		return sha256.New(), nil
+	case "SHA-384":
+		return sha512.New384(), nil
+	case "SHA-512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported MSO digest algorithm %q", algorithm)
+}
+
+// DeviceAuthenticationBytes builds the payload an mdoc's device signature
+// covers: DeviceAuthentication bound to the session transcript, which is
+// passed CBOR-encoded as the reader established it
+func DeviceAuthenticationBytes(sessionTranscript []byte, docType string, nameSpacesBytes CBORTag) ([]byte, error) {
+	transcript, err := UnmarshalCBOR(sessionTranscript)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session transcript: %w", err)
+	}
+	tag, err := EncodedCBOR([]interface{}{"DeviceAuthentication", transcript, docType, nameSpacesBytes})
+	if err != nil {
+		return nil, err
+	}
+	return tag.Raw, nil
+}
+
+// MdocVerifier verifies mdocs against a set of issuing authority (IACA)
+// root certificates
+type MdocVerifier struct {
+	roots *x509.CertPool
+}
+
+// NewMdocVerifier creates a verifier trusting document signers that chain
+// to roots. Without roots, every mdoc is rejected.
+func NewMdocVerifier(roots *x509.CertPool) *MdocVerifier {
+	return &MdocVerifier{roots: roots}
+}
+
+// SetTrustAnchors replaces the IACA root certificates
+func (v *MdocVerifier) SetTrustAnchors(roots *x509.CertPool) {
+	v.roots = roots
+}
+
+// VerifyIssuerSigned checks the issuer signature, the document signer's
+// chain, the MSO validity period and every element's digest
+func (v *MdocVerifier) VerifyIssuerSigned(issuerSigned *IssuerSigned, docType string, now time.Time) (*VerifiedMdoc, error) {
+	if v.roots == nil {
+		return nil, fmt.Errorf("no mdoc trust anchors configured")
+	}
+	issuerAuth := issuerSigned.IssuerAuth
+
+	alg, err := issuerAuth.Algorithm()
+	if err != nil {
+		return nil, err
+	}
+	chain, err := issuerAuth.X5Chain()
+	if err != nil {
+		return nil, err
+	}
+	signer := chain[0]
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range chain[1:] {
+		intermediates.AddCert(certificate)
+	}
+	chains, err := signer.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		// crypto/x509 does not know the ISO 18013-5 extended key usage, so
+		// the chain is built for any usage and the signer's is checked below
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("document signer certificate is not trusted: %w", err)
+	}
+	if !hasExtKeyUsage(signer, MdocDocumentSignerEKU) {
+		return nil, fmt.Errorf("document signer certificate lacks the mDL document signer extended key usage")
+	}
+	anchor := chains[0][len(chains[0])-1]
+
+	if err := issuerAuth.Verify(signer.PublicKey, nil); err != nil {
+		return nil, fmt.Errorf("issuer signature: %w", err)
+	}
+
+	mso, err := parseMSO(issuerAuth.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if docType != "" && mso.DocType != docType {
+		return nil, fmt.Errorf("MSO docType %s does not match document docType %s", mso.DocType, docType)
+	}
+
+	validity := mso.ValidityInfo
+	if validity.Signed.Before(signer.NotBefore) || validity.Signed.After(signer.NotAfter) {
+		return nil, fmt.Errorf("MSO was signed outside the document signer certificate's validity")
+	}
+	if validity.ValidUntil.Before(validity.ValidFrom) {
+		return nil, fmt.Errorf("MSO validity ends before it starts")
+	}
+	if now.Before(validity.ValidFrom) {
+		return nil, fmt.Errorf("mdoc not yet valid")
+	}
+	if !now.Before(validity.ValidUntil) {
+		return nil, fmt.Errorf("mdoc has expired")
+	}
+
+	claims := make(map[string]map[string]interface{})
+	for namespace, items := range issuerSigned.NameSpaces {
+		digests, ok := mso.ValueDigests[namespace]
+		if !ok {
+			return nil, fmt.Errorf("MSO has no digests for namespace %s", namespace)
+		}
+		claims[namespace] = make(map[string]interface{}, len(items))
+		for _, item := range items {
+			h, err := mdocDigest(mso.DigestAlgorithm)
+			if err != nil {
+				return nil, err
+			}
+			h.Write(item.Bytes())
+			expected, ok := digests[item.DigestID]
+			if !ok || !bytes.Equal(h.Sum(nil), expected) {
+				return nil, fmt.Errorf("digest mismatch for %s/%s", namespace, item.ElementIdentifier)
+			}
+			if _, dup := claims[namespace][item.ElementIdentifier]; dup {
+				return nil, fmt.Errorf("duplicate element %s/%s", namespace, item.ElementIdentifier)
+			}
+			claims[namespace][item.ElementIdentifier] = cborToJSON(item.ElementValue)
+		}
+	}
+
+	fingerprint := sha256.Sum256(anchor.Raw)
+	return &VerifiedMdoc{
+		DocType:           mso.DocType,
+		MSO:               mso,
+		Claims:            claims,
+		IssuerCertificate: signer,
+		IssuerID:          "x509:sha256:" + hex.EncodeToString(fingerprint[:]),
+		Algorithm:         coseAlgorithmName(alg),
+		IssuerSigned:      issuerSigned,
+	}, nil
+}
+
+func hasExtKeyUsage(certificate *x509.Certificate, usage asn1.ObjectIdentifier) bool {
+	for _, oid := range certificate.UnknownExtKeyUsage {
+		if oid.Equal(usage) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyDocument verifies a presented document: its issuer-signed data and
+// the device signature over the session transcript. MAC-based device
+// authentication needs the reader's session keys and is not supported.
+func (v *MdocVerifier) VerifyDocument(document *MdocDocument, sessionTranscript []byte, now time.Time) (*VerifiedMdoc, error) {
+	verified, err := v.VerifyIssuerSigned(document.IssuerSigned, document.DocType, now)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceSigned := document.DeviceSigned
+	if deviceSigned == nil {
+		return nil, fmt.Errorf("document %s has no device authentication", document.DocType)
+	}
+	if deviceSigned.DeviceSignature == nil {
+		if deviceSigned.HasDeviceMac {
+			return nil, fmt.Errorf("deviceMac authentication is not supported")
+		}
+		return nil, fmt.Errorf("document %s has no device signature", document.DocType)
+	}
+	if sessionTranscript == nil {
+		return nil, fmt.Errorf("a session transcript is required to verify device authentication")
+	}
+
+	payload, err := DeviceAuthenticationBytes(sessionTranscript, document.DocType, deviceSigned.NameSpacesBytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := deviceSigned.DeviceSignature.Verify(verified.MSO.DeviceKey, payload); err != nil {
+		return nil, fmt.Errorf("device signature: %w", err)
+	}
+	verified.DeviceAuthenticated = true
+	return verified, nil
+}
+
+// VerifyDeviceResponse verifies every document of a DeviceResponse
+func (v *MdocVerifier) VerifyDeviceResponse(response *DeviceResponse, sessionTranscript []byte, now time.Time) ([]*VerifiedMdoc, error) {
+	if response.Status != 0 {
+		return nil, fmt.Errorf("DeviceResponse status %d", response.Status)
+	}
+	if len(response.Documents) == 0 {
+		return nil, fmt.Errorf("DeviceResponse has no documents")
+	}
+
+	verified := make([]*VerifiedMdoc, 0, len(response.Documents))
+	for _, document := range response.Documents {
+		result, err := v.VerifyDocument(document, sessionTranscript, now)
+		if err != nil {
+			return nil, fmt.Errorf("document %s: %w", document.DocType, err)
+		}
+		verified = append(verified, result)
+	}
+	return verified, nil
+}
+
+// Credential projects the mdoc onto a VerifiableCredential so presentation
+// definitions and policies can evaluate it. The subject holds the claims by
+// namespace, which is where mso_mdoc field paths such as
+// $['org.iso.18013.5.1']['family_name'] point.
+func (m *VerifiedMdoc) Credential() *VerifiableCredential {
+	subject := make(map[string]interface{}, len(m.Claims))
+	namespaces := make([]string, 0, len(m.Claims))
+	for namespace := range m.Claims {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		elements := make(map[string]interface{}, len(m.Claims[namespace]))
+		for identifier, value := range m.Claims[namespace] {
+			elements[identifier] = value
+		}
+		subject[namespace] = elements
+	}
+
+	credential := &VerifiableCredential{
+		Context:           []string{CredentialsContextV2},
+		Type:              []string{"VerifiableCredential", m.DocType},
+		Issuer:            m.IssuerID,
+		ValidFrom:         m.MSO.ValidityInfo.ValidFrom.UTC().Format(time.RFC3339),
+		ValidUntil:        m.MSO.ValidityInfo.ValidUntil.UTC().Format(time.RFC3339),
+		CredentialSubject: subject,
+	}
+	if m.IssuerSigned != nil {
+		credential.Mdoc, _ = m.IssuerSigned.Encode()
+	}
+	return credential
+}
+
+// mdocAlgorithm returns the issuer signature algorithm of an mdoc-backed
+// credential
+func mdocAlgorithm(credential *VerifiableCredential) string {
+	issuerSigned, err := ParseIssuerSigned(credential.Mdoc)
+	if err != nil {
+		return ""
+	}
+	alg, err := issuerSigned.IssuerAuth.Algorithm()
+	if err != nil {
+		return ""
+	}
+	return coseAlgorithmName(alg)
+}
@@ -0,0 +1,371 @@
+package vc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mdocIssuer is a test IACA, a document signer it certified and a holder
+// device key
+type mdocIssuer struct {
+	roots     *x509.CertPool
+	signerDER []byte
+	signer    *ecdsa.PrivateKey
+	device    *ecdsa.PrivateKey
+	now       time.Time
+}
+
+func newMdocIssuer(t *testing.T) *mdocIssuer {
+	return newMdocIssuerWithUsages(t, MdocDocumentSignerEKU)
+}
+
+// newMdocIssuerWithUsages certifies the document signer for the given
+// extended key usages
+func newMdocIssuerWithUsages(t *testing.T, usages ...asn1.ObjectIdentifier) *mdocIssuer {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test IACA", Country: []string{"IN"}},
+		NotBefore:             now.AddDate(-1, 0, 0),
+		NotAfter:              now.AddDate(5, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signerTemplate := &x509.Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{CommonName: "Test Document Signer", Country: []string{"IN"}},
+		NotBefore:          now.AddDate(0, -1, 0),
+		NotAfter:           now.AddDate(1, 0, 0),
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		UnknownExtKeyUsage: usages,
+	}
+	signerDER, err := x509.CreateCertificate(rand.Reader, signerTemplate, ca, &signer.PublicKey, caKey)
+	require.NoError(t, err)
+
+	device, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return &mdocIssuer{roots: roots, signerDER: signerDER, signer: signer, device: device, now: now}
+}
+
+// issue signs an mdoc over the elements of each namespace
+func (i *mdocIssuer) issue(t *testing.T, docType string, elements map[string]map[string]interface{}) *IssuerSigned {
+	issuerSigned := &IssuerSigned{NameSpaces: make(map[string][]*IssuerSignedItem)}
+	valueDigests := make(map[interface{}]interface{})
+	for namespace, values := range elements {
+		digests := make(map[interface{}]interface{})
+		var digestID uint64
+		for _, identifier := range sortedKeys(values) {
+			item, err := NewIssuerSignedItem(digestID, identifier, values[identifier])
+			require.NoError(t, err)
+			digest := sha256.Sum256(item.Bytes())
+			digests[digestID] = digest[:]
+			issuerSigned.NameSpaces[namespace] = append(issuerSigned.NameSpaces[namespace], item)
+			digestID++
+		}
+		valueDigests[namespace] = digests
+	}
+
+	deviceKey, err := PublicKeyToCOSEKey(&i.device.PublicKey)
+	require.NoError(t, err)
+	tdate := func(t time.Time) CBORTag {
+		return CBORTag{Number: CBORTagDateTime, Content: t.UTC().Format(time.RFC3339)}
+	}
+	mso, err := EncodedCBOR(map[interface{}]interface{}{
+		"version":         "1.0",
+		"digestAlgorithm": "SHA-256",
+		"docType":         docType,
+		"valueDigests":    valueDigests,
+		"deviceKeyInfo":   map[interface{}]interface{}{"deviceKey": deviceKey},
+		"validityInfo": map[interface{}]interface{}{
+			"signed":     tdate(i.now.Add(-time.Hour)),
+			"validFrom":  tdate(i.now.Add(-time.Hour)),
+			"validUntil": tdate(i.now.AddDate(0, 6, 0)),
+		},
+	})
+	require.NoError(t, err)
+	payload, err := MarshalCBOR(mso)
+	require.NoError(t, err)
+
+	issuerSigned.IssuerAuth, err = SignCOSESign1(COSEAlgES256, i.signer, map[interface{}]interface{}{int64(coseHeaderX5Chain): i.signerDER}, payload, false)
+	require.NoError(t, err)
+	return issuerSigned
+}
+
+// present wraps an mdoc in a DeviceResponse signed by the device over the
+// session transcript
+func (i *mdocIssuer) present(t *testing.T, docType string, issuerSigned *IssuerSigned, sessionTranscript []byte) []byte {
+	nameSpaces, err := EncodedCBOR(map[interface{}]interface{}{})
+	require.NoError(t, err)
+	payload, err := DeviceAuthenticationBytes(sessionTranscript, docType, nameSpaces)
+	require.NoError(t, err)
+	deviceSignature, err := SignCOSESign1(COSEAlgES256, i.device, nil, payload, true)
+	require.NoError(t, err)
+
+	response, err := MarshalCBOR(map[interface{}]interface{}{
+		"version": "1.0",
+		"status":  0,
+		"documents": []interface{}{map[interface{}]interface{}{
+			"docType":      docType,
+			"issuerSigned": issuerSigned.CBOR(),
+			"deviceSigned": map[interface{}]interface{}{
+				"nameSpaces": nameSpaces,
+				"deviceAuth": map[interface{}]interface{}{"deviceSignature": deviceSignature.CBOR()},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	return response
+}
+
+func mdlElements() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		MdocNamespaceMDL: {
+			"family_name": "Sharma",
+			"given_name":  "Asha",
+			"birth_date":  CBORTag{Number: CBORTagFullDate, Content: "1990-05-01"},
+			"age_over_18": true,
+			"portrait":    []byte{0xff, 0xd8, 0xff},
+		},
+	}
+}
+
+func TestCBOR_RoundTrip(t *testing.T) {
+	value := map[interface{}]interface{}{
+		"text":     "ü",
+		int64(-3):  []byte{1, 2, 3},
+		int64(1):   []interface{}{int64(1), int64(-1), uint64(1 << 40), true, nil, 1.5},
+		"nested":   map[interface{}]interface{}{"a": CBORTag{Number: CBORTagDateTime, Content: "2025-06-01T00:00:00Z"}},
+		"embedded": CBORTag{Number: CBORTagEncodedCBOR, Content: []byte{0x01}},
+	}
+	encoded, err := MarshalCBOR(value)
+	require.NoError(t, err)
+	decoded, err := UnmarshalCBOR(encoded)
+	require.NoError(t, err)
+	again, err := MarshalCBOR(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, encoded, again)
+
+	// Deterministic encoding orders keys by their encoded bytes
+	first, err := MarshalCBOR(map[interface{}]interface{}{"b": 1, "a": 2, int64(10): 3})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xa3, 0x0a, 0x03, 0x61, 0x61, 0x02, 0x61, 0x62, 0x01}, first)
+
+	// Indefinite-length items decode
+	decoded, err = UnmarshalCBOR([]byte{0x9f, 0x01, 0x7f, 0x61, 0x61, 0x61, 0x62, 0xff, 0xff})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(1), "ab"}, decoded)
+
+	for name, input := range map[string][]byte{
+		"truncated":     {0x82, 0x01},
+		"duplicate key": {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"trailing":      {0x01, 0x02},
+		"bad tag 24":    {0xd8, 0x18, 0x01},
+	} {
+		_, err := UnmarshalCBOR(input)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMdoc_VerifyIssuerSigned(t *testing.T) {
+	issuer := newMdocIssuer(t)
+	issuerSigned := issuer.issue(t, MdocDocTypeMDL, mdlElements())
+	encoded, err := issuerSigned.Encode()
+	require.NoError(t, err)
+
+	parsed, err := ParseIssuerSigned(encoded)
+	require.NoError(t, err)
+	verifier := NewMdocVerifier(issuer.roots)
+	verified, err := verifier.VerifyIssuerSigned(parsed, MdocDocTypeMDL, issuer.now)
+	require.NoError(t, err)
+
+	assert.Equal(t, MdocDocTypeMDL, verified.DocType)
+	assert.Equal(t, "ES256", verified.Algorithm)
+	assert.Regexp(t, "^x509:sha256:[0-9a-f]{64}$", verified.IssuerID)
+	assert.False(t, verified.DeviceAuthenticated)
+	claims := verified.Claims[MdocNamespaceMDL]
+	assert.Equal(t, "Sharma", claims["family_name"])
+	assert.Equal(t, "1990-05-01", claims["birth_date"])
+	assert.Equal(t, true, claims["age_over_18"])
+
+	credential := verified.Credential()
+	assert.Equal(t, []string{"VerifiableCredential", MdocDocTypeMDL}, credential.Type)
+	assert.NotEmpty(t, credential.Mdoc)
+
+	t.Run("no trust anchors", func(t *testing.T) {
+		_, err := NewMdocVerifier(nil).VerifyIssuerSigned(parsed, "", issuer.now)
+		assert.Error(t, err)
+	})
+
+	t.Run("untrusted chain", func(t *testing.T) {
+		_, err := NewMdocVerifier(newMdocIssuer(t).roots).VerifyIssuerSigned(parsed, "", issuer.now)
+		assert.ErrorContains(t, err, "not trusted")
+	})
+
+	t.Run("document signer without mDL usage", func(t *testing.T) {
+		other := newMdocIssuerWithUsages(t, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4})
+		_, err := NewMdocVerifier(other.roots).VerifyIssuerSigned(other.issue(t, MdocDocTypeMDL, mdlElements()), "", other.now)
+		assert.ErrorContains(t, err, "extended key usage")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := verifier.VerifyIssuerSigned(parsed, "", issuer.now.AddDate(0, 7, 0))
+		assert.ErrorContains(t, err, "expired")
+	})
+
+	t.Run("docType mismatch", func(t *testing.T) {
+		_, err := verifier.VerifyIssuerSigned(parsed, "org.example.other", issuer.now)
+		assert.ErrorContains(t, err, "docType")
+	})
+
+	t.Run("tampered element", func(t *testing.T) {
+		forged, err := NewIssuerSignedItem(0, "age_over_18", true)
+		require.NoError(t, err)
+		tampered := &IssuerSigned{
+			NameSpaces: map[string][]*IssuerSignedItem{MdocNamespaceMDL: {forged}},
+			IssuerAuth: parsed.IssuerAuth,
+		}
+		_, err = verifier.VerifyIssuerSigned(tampered, "", issuer.now)
+		assert.ErrorContains(t, err, "digest mismatch")
+	})
+
+	t.Run("selected elements still verify", func(t *testing.T) {
+		selected := parsed.Select(map[string][]string{MdocNamespaceMDL: {"age_over_18"}})
+		reduced, err := selected.Encode()
+		require.NoError(t, err)
+		reparsed, err := ParseIssuerSigned(reduced)
+		require.NoError(t, err)
+		verified, err := verifier.VerifyIssuerSigned(reparsed, "", issuer.now)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"age_over_18": true}, verified.Claims[MdocNamespaceMDL])
+	})
+}
+
+func TestMdoc_DeviceResponse(t *testing.T) {
+	issuer := newMdocIssuer(t)
+	issuerSigned := issuer.issue(t, MdocDocTypeMDL, mdlElements())
+	transcript, err := MarshalCBOR([]interface{}{nil, nil, []interface{}{"OpenID4VPHandover", []byte("nonce-1")}})
+	require.NoError(t, err)
+	response := issuer.present(t, MdocDocTypeMDL, issuerSigned, transcript)
+
+	verifier := NewDefaultCredentialVerifier(nil, nil)
+	now := issuer.now
+	options := &VerificationOptions{Now: &now, SessionTranscript: transcript}
+
+	result, err := verifier.VerifyAny(response, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified, "mdocs are rejected without trust anchors")
+
+	verifier.SetMdocTrustAnchors(issuer.roots)
+	result, err = verifier.VerifyAny(response, options)
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.Error)
+	assert.Equal(t, FormatMsoMdoc, result.Details["format"])
+	assert.Equal(t, MdocDocTypeMDL, result.Details["docType"])
+	assert.Equal(t, true, result.Details["deviceAuthenticated"])
+	require.NotNil(t, result.Credential)
+
+	t.Run("other session", func(t *testing.T) {
+		other, err := MarshalCBOR([]interface{}{nil, nil, []interface{}{"OpenID4VPHandover", []byte("nonce-2")}})
+		require.NoError(t, err)
+		result, err := verifier.VerifyAny(response, &VerificationOptions{Now: &now, SessionTranscript: other})
+		require.NoError(t, err)
+		assert.False(t, result.Verified)
+		assert.Contains(t, result.Error, "device signature")
+	})
+
+	t.Run("missing session transcript", func(t *testing.T) {
+		result, err := verifier.VerifyAny(response, &VerificationOptions{Now: &now})
+		require.NoError(t, err)
+		assert.False(t, result.Verified)
+	})
+
+	t.Run("bare IssuerSigned", func(t *testing.T) {
+		encoded, err := issuerSigned.Encode()
+		require.NoError(t, err)
+		result, err := verifier.VerifyAny(encoded, options)
+		require.NoError(t, err)
+		require.True(t, result.Verified, result.Error)
+		assert.Equal(t, false, result.Details["deviceAuthenticated"])
+	})
+}
+
+func TestMdoc_PresentationDefinition(t *testing.T) {
+	issuer := newMdocIssuer(t)
+	verified, err := NewMdocVerifier(issuer.roots).VerifyIssuerSigned(issuer.issue(t, MdocDocTypeMDL, mdlElements()), "", issuer.now)
+	require.NoError(t, err)
+	credential := verified.Credential()
+
+	definition := decodeDefinition(t, `{
+	  "id": "mdl-age",
+	  "input_descriptors": [{
+	    "id": "org.iso.18013.5.1.mDL",
+	    "format": {"mso_mdoc": {"alg": ["ES256", "EdDSA"]}},
+	    "constraints": {
+	      "limit_disclosure": "required",
+	      "fields": [
+	        {"path": ["$['org.iso.18013.5.1']['age_over_18']"], "filter": {"const": true}},
+	        {"path": ["$['org.iso.18013.5.1']['family_name']"]}
+	      ]
+	    }
+	  }]
+	}`)
+
+	processor := NewPresentationDefinitionProcessor()
+	credentials := append(peTestCredentials(), credential)
+	result, err := processor.EvaluateCredentials(definition, credentials)
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Errors)
+	require.Len(t, result.Matches, 1)
+	match := result.Matches[0]
+	assert.Equal(t, FormatMsoMdoc, match.Format)
+
+	disclosed, ok := match.Disclosed.(string)
+	require.True(t, ok)
+	reduced, err := base64.RawURLEncoding.DecodeString(disclosed)
+	require.NoError(t, err)
+	parsed, err := ParseIssuerSigned(reduced)
+	require.NoError(t, err)
+	limited, err := NewMdocVerifier(issuer.roots).VerifyIssuerSigned(parsed, "", issuer.now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"age_over_18": true, "family_name": "Sharma"}, limited.Claims[MdocNamespaceMDL])
+
+	t.Run("algorithm not accepted", func(t *testing.T) {
+		definition.InputDescriptors[0].Format = &ClaimFormat{MsoMdoc: &MsoMdocFormat{Alg: []string{"ES384"}}}
+		result, err := processor.EvaluateCredentials(definition, []*VerifiableCredential{credential})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+	})
+
+	t.Run("descriptor names another docType", func(t *testing.T) {
+		definition.InputDescriptors[0].Format = nil
+		definition.InputDescriptors[0].ID = "org.iso.23220.photoid.1"
+		result, err := processor.EvaluateCredentials(definition, []*VerifiableCredential{credential})
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+	})
+}
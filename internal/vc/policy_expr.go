@@ -0,0 +1,1815 @@
+package vc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Trust policy expressions are a sandboxed subset of CEL: literals, lists
+// and maps, member and index access, the usual logical, relational and
+// arithmetic operators, the ternary operator, the has() and comprehension
+// macros (all, exists, exists_one, filter, map) and a fixed function
+// library. Expressions cannot loop unboundedly, allocate without limit or
+// reach anything outside the variables they are given: every evaluation
+// runs against a cost budget, and regular expressions are RE2.
+const (
+	maxExpressionLength = 4096
+	maxExpressionDepth  = 64
+	maxExpressionCost   = 100000
+	maxExpressionString = 1 << 16
+)
+
+// exprType is the static type the checker infers for a subexpression
+type exprType int
+
+const (
+	exprDyn exprType = iota
+	exprNull
+	exprBool
+	exprInt
+	exprDouble
+	exprString
+	exprList
+	exprMap
+	exprTimestamp
+	exprDuration
+)
+
+func (t exprType) String() string {
+	return [...]string{"dyn", "null", "bool", "int", "double", "string", "list", "map", "timestamp", "duration"}[t]
+}
+
+func (t exprType) numeric() bool {
+	return t == exprInt || t == exprDouble
+}
+
+// policyExpressionVariables are the variables trust policy expressions see
+var policyExpressionVariables = map[string]exprType{
+	// credential is the credential under evaluation as a JSON document
+	"credential": exprMap,
+	// issuer is the framework's entry for the credential issuer: id,
+	// trusted, name, trustLevel, allowedCredentialTypes,
+	// restrictedCredentialTypes and metadata
+	"issuer": exprMap,
+	// status reports the credential status check: checked, type, and
+	// revoked, or error when the status could not be determined
+	"status": exprMap,
+	// score is the subject's trust score, null when unknown
+	"score": exprDyn,
+	// credentials are all credentials presented together, including this one
+	"credentials": exprList,
+	// now is the evaluation time
+	"now": exprTimestamp,
+}
+
+// AST nodes
+type (
+	exprNode interface{}
+
+	exprLiteral struct{ value interface{} }
+	exprIdent   struct{ name string }
+	exprSelect  struct {
+		operand exprNode
+		field   string
+	}
+	exprIndex struct{ operand, index exprNode }
+	exprCall  struct {
+		target   exprNode // nil for global functions
+		function string
+		args     []exprNode
+	}
+	exprUnary struct {
+		op      string
+		operand exprNode
+	}
+	exprBinary struct {
+		op          string
+		left, right exprNode
+	}
+	exprTernary    struct{ condition, then, otherwise exprNode }
+	exprCreateList struct{ items []exprNode }
+	exprCreateMap  struct{ keys, values []exprNode }
+	exprHas        struct{ selection *exprSelect }
+	// exprComprehension is one of the all, exists, exists_one, filter and
+	// map macros
+	exprComprehension struct {
+		macro    string
+		target   exprNode
+		variable string
+		body     exprNode
+	}
+)
+
+// PolicyExpression is a parsed and type-checked trust policy expression
+type PolicyExpression struct {
+	source string
+	root   exprNode
+}
+
+// CompilePolicyExpression parses and checks an expression against the
+// trust policy variables. The expression must produce a bool.
+func CompilePolicyExpression(source string) (*PolicyExpression, error) {
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression exceeds %d characters", maxExpressionLength)
+	}
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().offset)
+	}
+
+	checker := &exprChecker{scopes: []map[string]exprType{policyExpressionVariables}}
+	resultType, err := checker.check(root)
+	if err != nil {
+		return nil, err
+	}
+	if resultType != exprBool && resultType != exprDyn {
+		return nil, fmt.Errorf("expression must evaluate to bool, not %s", resultType)
+	}
+	return &PolicyExpression{source: source, root: root}, nil
+}
+
+// String returns the expression source
+func (e *PolicyExpression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression over the given variables. Variable values
+// are JSON-like: nil, bool, numbers, strings, slices and string-keyed maps,
+// plus time.Time and time.Duration.
+func (e *PolicyExpression) Evaluate(variables map[string]interface{}) (bool, error) {
+	activation := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		activation[name] = normalizeExprValue(value)
+	}
+	evaluator := &exprEvaluator{scopes: []map[string]interface{}{activation}, budget: maxExpressionCost}
+	result, err := evaluator.eval(e.root)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression produced %s, not bool", exprTypeOf(result))
+	}
+	return b, nil
+}
+
+// normalizeExprValue converts JSON decoding artefacts into expression
+// values: integral numbers become int64, others float64
+func normalizeExprValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case float32:
+		return float64(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return normalizeExprValue(*v)
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = normalizeExprValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = normalizeExprValue(item)
+		}
+		return m
+	}
+	return value
+}
+
+// Lexer
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenDouble
+	tokenString
+	tokenPunct
+)
+
+type exprToken struct {
+	kind   tokenKind
+	text   string
+	value  interface{}
+	offset int
+}
+
+var exprPunctuation = []string{"||", "&&", "==", "!=", "<=", ">=", "!", "<", ">", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]", "{", "}"}
+
+func lexExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(source); {
+		r, size := utf8.DecodeRuneInString(source[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(source) {
+				r, size := utf8.DecodeRuneInString(source[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[start:i], offset: start})
+
+		case r >= '0' && r <= '9':
+			start := i
+			isDouble := false
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.' || source[i] == 'e' || source[i] == 'E' ||
+				(source[i] == '-' || source[i] == '+') && (source[i-1] == 'e' || source[i-1] == 'E')) {
+				if source[i] == '.' {
+					// A dot not followed by a digit is member access
+					if i+1 >= len(source) || source[i+1] < '0' || source[i+1] > '9' {
+						break
+					}
+					isDouble = true
+				}
+				if source[i] == 'e' || source[i] == 'E' {
+					isDouble = true
+				}
+				i++
+			}
+			text := source[start:i]
+			if isDouble {
+				f, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid number %q", text)
+				}
+				tokens = append(tokens, exprToken{kind: tokenDouble, text: text, value: f, offset: start})
+			} else {
+				n, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid integer %q", text)
+				}
+				tokens = append(tokens, exprToken{kind: tokenInt, text: text, value: n, offset: start})
+			}
+
+		case r == '"' || r == '\'':
+			start := i
+			quote := source[i]
+			i++
+			var b strings.Builder
+			for {
+				if i >= len(source) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				c := source[i]
+				if c == quote {
+					i++
+					break
+				}
+				if c == '\\' {
+					if i+1 >= len(source) {
+						return nil, fmt.Errorf("unterminated string at offset %d", start)
+					}
+					switch source[i+1] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					case '\\', '"', '\'':
+						b.WriteByte(source[i+1])
+					default:
+						return nil, fmt.Errorf("invalid escape \\%c at offset %d", source[i+1], i)
+					}
+					i += 2
+					continue
+				}
+				b.WriteByte(c)
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: source[start:i], value: b.String(), offset: start})
+
+		default:
+			matched := false
+			for _, punct := range exprPunctuation {
+				if strings.HasPrefix(source[i:], punct) {
+					tokens = append(tokens, exprToken{kind: tokenPunct, text: punct, offset: i})
+					i += len(punct)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, text: "end of expression", offset: len(source)}), nil
+}
+
+// Parser
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *exprParser) accept(punct string) bool {
+	if token := p.peek(); token.kind == tokenPunct && token.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(punct string) error {
+	if !p.accept(punct) {
+		token := p.peek()
+		return fmt.Errorf("expected %q but found %q at offset %d", punct, token.text, token.offset)
+	}
+	return nil
+}
+
+func (p *exprParser) parseExpression(depth int) (exprNode, error) {
+	if depth > maxExpressionDepth {
+		return nil, fmt.Errorf("expression nests deeper than %d", maxExpressionDepth)
+	}
+	condition, err := p.parseBinary(0, depth)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return condition, nil
+	}
+	then, err := p.parseBinary(0, depth)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpression(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	return &exprTernary{condition: condition, then: then, otherwise: otherwise}, nil
+}
+
+// exprPrecedence lists binary operators from loosest to tightest binding
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *exprParser) parseBinary(level, depth int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.parseUnary(depth)
+	}
+	left, err := p.parseBinary(level+1, depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		if (token.kind != tokenPunct && !(token.kind == tokenIdent && token.text == "in")) || !containsString(exprPrecedence[level], token.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level+1, depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{op: token.text, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary(depth int) (exprNode, error) {
+	if depth > maxExpressionDepth {
+		return nil, fmt.Errorf("expression nests deeper than %d", maxExpressionDepth)
+	}
+	if token := p.peek(); token.kind == tokenPunct && (token.text == "!" || token.text == "-") {
+		p.next()
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		// Fold negative literals so that -9223372036854775808 stays an int
+		if literal, ok := operand.(*exprLiteral); ok && token.text == "-" {
+			switch v := literal.value.(type) {
+			case int64:
+				return &exprLiteral{value: -v}, nil
+			case float64:
+				return &exprLiteral{value: -v}, nil
+			}
+		}
+		return &exprUnary{op: token.text, operand: operand}, nil
+	}
+	return p.parseMember(depth)
+}
+
+func (p *exprParser) parseMember(depth int) (exprNode, error) {
+	node, err := p.parsePrimary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			token := p.next()
+			if token.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at offset %d", token.offset)
+			}
+			if !p.accept("(") {
+				node = &exprSelect{operand: node, field: token.text}
+				continue
+			}
+			args, err := p.parseArguments(")", depth)
+			if err != nil {
+				return nil, err
+			}
+			if node, err = newExprCall(node, token.text, args); err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			index, err := p.parseExpression(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &exprIndex{operand: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// newExprCall builds a method call, expanding comprehension macros
+func newExprCall(target exprNode, function string, args []exprNode) (exprNode, error) {
+	switch function {
+	case "all", "exists", "exists_one", "filter", "map":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() takes a variable and an expression", function)
+		}
+		variable, ok := args[0].(*exprIdent)
+		if !ok {
+			return nil, fmt.Errorf("the first argument of %s() must be a variable name", function)
+		}
+		return &exprComprehension{macro: function, target: target, variable: variable.name, body: args[1]}, nil
+	}
+	return &exprCall{target: target, function: function, args: args}, nil
+}
+
+func (p *exprParser) parseArguments(closing string, depth int) ([]exprNode, error) {
+	var args []exprNode
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary(depth int) (exprNode, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenInt, tokenDouble, tokenString:
+		return &exprLiteral{value: token.value}, nil
+	case tokenIdent:
+		switch token.text {
+		case "true":
+			return &exprLiteral{value: true}, nil
+		case "false":
+			return &exprLiteral{value: false}, nil
+		case "null":
+			return &exprLiteral{value: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected 'in' at offset %d", token.offset)
+		}
+		if !p.accept("(") {
+			return &exprIdent{name: token.text}, nil
+		}
+		args, err := p.parseArguments(")", depth)
+		if err != nil {
+			return nil, err
+		}
+		if token.text == "has" {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("has() takes one field selection")
+			}
+			selection, ok := args[0].(*exprSelect)
+			if !ok {
+				return nil, fmt.Errorf("has() argument must be a field selection such as a.b")
+			}
+			return &exprHas{selection: selection}, nil
+		}
+		return &exprCall{function: token.text, args: args}, nil
+	case tokenPunct:
+		switch token.text {
+		case "(":
+			inner, err := p.parseExpression(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			items, err := p.parseArguments("]", depth)
+			if err != nil {
+				return nil, err
+			}
+			return &exprCreateList{items: items}, nil
+		case "{":
+			node := &exprCreateMap{}
+			if p.accept("}") {
+				return node, nil
+			}
+			for {
+				key, err := p.parseExpression(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseExpression(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				node.keys = append(node.keys, key)
+				node.values = append(node.values, value)
+				if p.accept("}") {
+					return node, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", token.text, token.offset)
+}
+
+// Checker
+
+type exprChecker struct {
+	scopes []map[string]exprType
+}
+
+func (c *exprChecker) lookup(name string) (exprType, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, ok := c.scopes[i][name]; ok {
+			return t, true
+		}
+	}
+	return exprDyn, false
+}
+
+// exprFunctions are the global functions and their argument counts
+var exprFunctions = map[string]int{
+	"size":      1,
+	"timestamp": 1,
+	"duration":  1,
+	"int":       1,
+	"double":    1,
+	"string":    1,
+	"matches":   2,
+	"issuerID":  1,
+}
+
+// exprMethods are the receiver-style functions and their argument counts
+var exprMethods = map[string]int{
+	"size":       0,
+	"contains":   1,
+	"startsWith": 1,
+	"endsWith":   1,
+	"matches":    1,
+	"lowerAscii": 0,
+	"distinct":   0,
+}
+
+func typeOneOf(t exprType, allowed ...exprType) bool {
+	if t == exprDyn {
+		return true
+	}
+	for _, a := range allowed {
+		if t == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *exprChecker) check(node exprNode) (exprType, error) {
+	switch n := node.(type) {
+	case *exprLiteral:
+		return exprTypeOf(n.value), nil
+
+	case *exprIdent:
+		t, ok := c.lookup(n.name)
+		if !ok {
+			return exprDyn, fmt.Errorf("undeclared reference to '%s'", n.name)
+		}
+		return t, nil
+
+	case *exprSelect:
+		t, err := c.check(n.operand)
+		if err != nil {
+			return exprDyn, err
+		}
+		if !typeOneOf(t, exprMap) {
+			return exprDyn, fmt.Errorf("cannot select field '%s' from %s", n.field, t)
+		}
+		return exprDyn, nil
+
+	case *exprHas:
+		if _, err := c.check(n.selection); err != nil {
+			return exprDyn, err
+		}
+		return exprBool, nil
+
+	case *exprIndex:
+		t, err := c.check(n.operand)
+		if err != nil {
+			return exprDyn, err
+		}
+		index, err := c.check(n.index)
+		if err != nil {
+			return exprDyn, err
+		}
+		switch t {
+		case exprList:
+			if !typeOneOf(index, exprInt) {
+				return exprDyn, fmt.Errorf("list index must be an int, not %s", index)
+			}
+		case exprMap:
+			if !typeOneOf(index, exprString) {
+				return exprDyn, fmt.Errorf("map key must be a string, not %s", index)
+			}
+		case exprDyn:
+		default:
+			return exprDyn, fmt.Errorf("cannot index %s", t)
+		}
+		return exprDyn, nil
+
+	case *exprUnary:
+		t, err := c.check(n.operand)
+		if err != nil {
+			return exprDyn, err
+		}
+		if n.op == "!" {
+			if !typeOneOf(t, exprBool) {
+				return exprDyn, fmt.Errorf("operator ! needs a bool, not %s", t)
+			}
+			return exprBool, nil
+		}
+		if !typeOneOf(t, exprInt, exprDouble, exprDuration) {
+			return exprDyn, fmt.Errorf("operator - cannot negate %s", t)
+		}
+		return t, nil
+
+	case *exprBinary:
+		left, err := c.check(n.left)
+		if err != nil {
+			return exprDyn, err
+		}
+		right, err := c.check(n.right)
+		if err != nil {
+			return exprDyn, err
+		}
+		return checkBinary(n.op, left, right)
+
+	case *exprTernary:
+		condition, err := c.check(n.condition)
+		if err != nil {
+			return exprDyn, err
+		}
+		if !typeOneOf(condition, exprBool) {
+			return exprDyn, fmt.Errorf("ternary condition must be a bool, not %s", condition)
+		}
+		then, err := c.check(n.then)
+		if err != nil {
+			return exprDyn, err
+		}
+		otherwise, err := c.check(n.otherwise)
+		if err != nil {
+			return exprDyn, err
+		}
+		if then == otherwise {
+			return then, nil
+		}
+		return exprDyn, nil
+
+	case *exprCreateList:
+		for _, item := range n.items {
+			if _, err := c.check(item); err != nil {
+				return exprDyn, err
+			}
+		}
+		return exprList, nil
+
+	case *exprCreateMap:
+		for i := range n.keys {
+			key, err := c.check(n.keys[i])
+			if err != nil {
+				return exprDyn, err
+			}
+			if !typeOneOf(key, exprString) {
+				return exprDyn, fmt.Errorf("map keys must be strings, not %s", key)
+			}
+			if _, err := c.check(n.values[i]); err != nil {
+				return exprDyn, err
+			}
+		}
+		return exprMap, nil
+
+	case *exprComprehension:
+		target, err := c.check(n.target)
+		if err != nil {
+			return exprDyn, err
+		}
+		if !typeOneOf(target, exprList, exprMap) {
+			return exprDyn, fmt.Errorf("%s() needs a list or map, not %s", n.macro, target)
+		}
+		if _, declared := policyExpressionVariables[n.variable]; declared {
+			return exprDyn, fmt.Errorf("%s() variable '%s' shadows a policy variable", n.macro, n.variable)
+		}
+		c.scopes = append(c.scopes, map[string]exprType{n.variable: exprDyn})
+		body, err := c.check(n.body)
+		c.scopes = c.scopes[:len(c.scopes)-1]
+		if err != nil {
+			return exprDyn, err
+		}
+		switch n.macro {
+		case "map":
+			return exprList, nil
+		case "filter":
+			if !typeOneOf(body, exprBool) {
+				return exprDyn, fmt.Errorf("filter() predicate must be a bool, not %s", body)
+			}
+			return exprList, nil
+		}
+		if !typeOneOf(body, exprBool) {
+			return exprDyn, fmt.Errorf("%s() predicate must be a bool, not %s", n.macro, body)
+		}
+		return exprBool, nil
+
+	case *exprCall:
+		return c.checkCall(n)
+	}
+	return exprDyn, fmt.Errorf("unsupported expression")
+}
+
+func checkBinary(op string, left, right exprType) (exprType, error) {
+	mismatch := fmt.Errorf("no operator %s for %s and %s", op, left, right)
+	dyn := left == exprDyn || right == exprDyn
+	switch op {
+	case "&&", "||":
+		if !typeOneOf(left, exprBool) || !typeOneOf(right, exprBool) {
+			return exprDyn, mismatch
+		}
+		return exprBool, nil
+	case "==", "!=":
+		return exprBool, nil
+	case "<", "<=", ">", ">=":
+		if dyn || left == right && typeOneOf(left, exprInt, exprDouble, exprString, exprTimestamp, exprDuration) || left.numeric() && right.numeric() {
+			return exprBool, nil
+		}
+		return exprDyn, mismatch
+	case "in":
+		if !typeOneOf(right, exprList, exprMap) {
+			return exprDyn, mismatch
+		}
+		return exprBool, nil
+	case "+":
+		switch {
+		case dyn:
+			return exprDyn, nil
+		case left.numeric() && right.numeric():
+			return numericResult(left, right), nil
+		case left == right && typeOneOf(left, exprString, exprList, exprDuration):
+			return left, nil
+		case left == exprTimestamp && right == exprDuration, left == exprDuration && right == exprTimestamp:
+			return exprTimestamp, nil
+		}
+		return exprDyn, mismatch
+	case "-":
+		switch {
+		case dyn:
+			return exprDyn, nil
+		case left.numeric() && right.numeric():
+			return numericResult(left, right), nil
+		case left == exprTimestamp && right == exprTimestamp:
+			return exprDuration, nil
+		case left == exprTimestamp && right == exprDuration:
+			return exprTimestamp, nil
+		case left == exprDuration && right == exprDuration:
+			return exprDuration, nil
+		}
+		return exprDyn, mismatch
+	case "*", "/":
+		if dyn || left.numeric() && right.numeric() {
+			return numericResult(left, right), nil
+		}
+		return exprDyn, mismatch
+	case "%":
+		if typeOneOf(left, exprInt) && typeOneOf(right, exprInt) {
+			return exprInt, nil
+		}
+		return exprDyn, mismatch
+	}
+	return exprDyn, fmt.Errorf("unknown operator %s", op)
+}
+
+func numericResult(left, right exprType) exprType {
+	switch {
+	case left == exprInt && right == exprInt:
+		return exprInt
+	case left == exprDyn || right == exprDyn:
+		return exprDyn
+	}
+	return exprDouble
+}
+
+func (c *exprChecker) checkCall(n *exprCall) (exprType, error) {
+	argTypes := make([]exprType, len(n.args))
+	for i, arg := range n.args {
+		t, err := c.check(arg)
+		if err != nil {
+			return exprDyn, err
+		}
+		argTypes[i] = t
+	}
+
+	if n.target == nil {
+		arity, ok := exprFunctions[n.function]
+		if !ok {
+			return exprDyn, fmt.Errorf("undeclared function '%s'", n.function)
+		}
+		if len(n.args) != arity {
+			return exprDyn, fmt.Errorf("%s() takes %d arguments", n.function, arity)
+		}
+		if err := checkConstantArgument(n.function, n.args); err != nil {
+			return exprDyn, err
+		}
+		return functionResult(n.function, argTypes)
+	}
+
+	receiver, err := c.check(n.target)
+	if err != nil {
+		return exprDyn, err
+	}
+	arity, ok := exprMethods[n.function]
+	if !ok {
+		return exprDyn, fmt.Errorf("undeclared method '%s'", n.function)
+	}
+	if len(n.args) != arity {
+		return exprDyn, fmt.Errorf("%s() takes %d arguments", n.function, arity)
+	}
+	switch n.function {
+	case "size":
+		if !typeOneOf(receiver, exprString, exprList, exprMap) {
+			return exprDyn, fmt.Errorf("size() is not defined on %s", receiver)
+		}
+		return exprInt, nil
+	case "distinct":
+		if !typeOneOf(receiver, exprList) {
+			return exprDyn, fmt.Errorf("distinct() is not defined on %s", receiver)
+		}
+		return exprList, nil
+	case "lowerAscii":
+		if !typeOneOf(receiver, exprString) {
+			return exprDyn, fmt.Errorf("lowerAscii() is not defined on %s", receiver)
+		}
+		return exprString, nil
+	case "matches":
+		if err := checkConstantArgument("matches", []exprNode{nil, n.args[0]}); err != nil {
+			return exprDyn, err
+		}
+	}
+	if !typeOneOf(receiver, exprString) || !typeOneOf(argTypes[0], exprString) {
+		return exprDyn, fmt.Errorf("%s() needs strings", n.function)
+	}
+	return exprBool, nil
+}
+
+func functionResult(function string, args []exprType) (exprType, error) {
+	switch function {
+	case "size":
+		if !typeOneOf(args[0], exprString, exprList, exprMap) {
+			return exprDyn, fmt.Errorf("size() is not defined on %s", args[0])
+		}
+		return exprInt, nil
+	case "timestamp":
+		if !typeOneOf(args[0], exprString, exprTimestamp, exprInt) {
+			return exprDyn, fmt.Errorf("timestamp() cannot convert %s", args[0])
+		}
+		return exprTimestamp, nil
+	case "duration":
+		if !typeOneOf(args[0], exprString, exprDuration) {
+			return exprDyn, fmt.Errorf("duration() cannot convert %s", args[0])
+		}
+		return exprDuration, nil
+	case "int":
+		return exprInt, nil
+	case "double":
+		return exprDouble, nil
+	case "string":
+		return exprString, nil
+	case "matches":
+		if !typeOneOf(args[0], exprString) || !typeOneOf(args[1], exprString) {
+			return exprDyn, fmt.Errorf("matches() needs strings")
+		}
+		return exprBool, nil
+	case "issuerID":
+		return exprString, nil
+	}
+	return exprDyn, fmt.Errorf("undeclared function '%s'", function)
+}
+
+// checkConstantArgument validates literal arguments whose errors would
+// otherwise only show at evaluation
+func checkConstantArgument(function string, args []exprNode) error {
+	var literal *exprLiteral
+	switch function {
+	case "timestamp", "duration":
+		literal, _ = args[0].(*exprLiteral)
+	case "matches":
+		literal, _ = args[1].(*exprLiteral)
+	}
+	if literal == nil {
+		return nil
+	}
+	s, ok := literal.value.(string)
+	if !ok {
+		return nil
+	}
+	var err error
+	switch function {
+	case "timestamp":
+		_, err = time.Parse(time.RFC3339, s)
+	case "duration":
+		_, err = parseExprDuration(s)
+	case "matches":
+		_, err = regexp.Compile(s)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s() argument %q: %v", function, s, err)
+	}
+	return nil
+}
+
+// parseExprDuration parses a Go duration, also accepting a leading whole
+// number of days such as 730d or 1d12h
+func parseExprDuration(s string) (time.Duration, error) {
+	if i := strings.IndexByte(s, 'd'); i > 0 {
+		days, err := strconv.ParseInt(s[:i], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		rest := time.Duration(0)
+		if i+1 < len(s) {
+			if rest, err = time.ParseDuration(s[i+1:]); err != nil {
+				return 0, err
+			}
+		}
+		return time.Duration(days)*24*time.Hour + rest, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func exprTypeOf(value interface{}) exprType {
+	switch value.(type) {
+	case nil:
+		return exprNull
+	case bool:
+		return exprBool
+	case int64:
+		return exprInt
+	case float64:
+		return exprDouble
+	case string:
+		return exprString
+	case []interface{}:
+		return exprList
+	case map[string]interface{}:
+		return exprMap
+	case time.Time:
+		return exprTimestamp
+	case time.Duration:
+		return exprDuration
+	}
+	return exprDyn
+}
+
+// Evaluator
+
+type exprEvaluator struct {
+	scopes []map[string]interface{}
+	budget int
+}
+
+func (e *exprEvaluator) charge(cost int) error {
+	e.budget -= cost
+	if e.budget < 0 {
+		return fmt.Errorf("expression exceeded its evaluation budget")
+	}
+	return nil
+}
+
+func (e *exprEvaluator) eval(node exprNode) (interface{}, error) {
+	if err := e.charge(1); err != nil {
+		return nil, err
+	}
+
+	switch n := node.(type) {
+	case *exprLiteral:
+		return n.value, nil
+
+	case *exprIdent:
+		for i := len(e.scopes) - 1; i >= 0; i-- {
+			if value, ok := e.scopes[i][n.name]; ok {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("no value for '%s'", n.name)
+
+	case *exprSelect:
+		operand, err := e.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := operand.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot select '%s' from %s", n.field, exprTypeOf(operand))
+		}
+		value, ok := m[n.field]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", n.field)
+		}
+		return value, nil
+
+	case *exprHas:
+		operand, err := e.eval(n.selection.operand)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := operand.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("has() cannot select '%s' from %s", n.selection.field, exprTypeOf(operand))
+		}
+		_, present := m[n.selection.field]
+		return present, nil
+
+	case *exprIndex:
+		operand, err := e.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		index, err := e.eval(n.index)
+		if err != nil {
+			return nil, err
+		}
+		switch container := operand.(type) {
+		case []interface{}:
+			i, ok := index.(int64)
+			if !ok {
+				return nil, fmt.Errorf("list index must be an int")
+			}
+			if i < 0 || i >= int64(len(container)) {
+				return nil, fmt.Errorf("index %d out of range", i)
+			}
+			return container[i], nil
+		case map[string]interface{}:
+			key, ok := index.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key must be a string")
+			}
+			value, ok := container[key]
+			if !ok {
+				return nil, fmt.Errorf("no such key: %s", key)
+			}
+			return value, nil
+		}
+		return nil, fmt.Errorf("cannot index %s", exprTypeOf(operand))
+
+	case *exprUnary:
+		operand, err := e.eval(n.operand)
+		if err != nil {
+			return nil, err
+		}
+		switch v := operand.(type) {
+		case bool:
+			if n.op == "!" {
+				return !v, nil
+			}
+		case int64:
+			if n.op == "-" && v != math.MinInt64 {
+				return -v, nil
+			}
+		case float64:
+			if n.op == "-" {
+				return -v, nil
+			}
+		case time.Duration:
+			if n.op == "-" {
+				return -v, nil
+			}
+		}
+		return nil, fmt.Errorf("no operator %s for %s", n.op, exprTypeOf(operand))
+
+	case *exprBinary:
+		if n.op == "&&" || n.op == "||" {
+			return e.evalLogical(n)
+		}
+		left, err := e.eval(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(n.right)
+		if err != nil {
+			return nil, err
+		}
+		return e.evalBinary(n.op, left, right)
+
+	case *exprTernary:
+		condition, err := e.eval(n.condition)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := condition.(bool)
+		if !ok {
+			return nil, fmt.Errorf("ternary condition must be a bool")
+		}
+		if b {
+			return e.eval(n.then)
+		}
+		return e.eval(n.otherwise)
+
+	case *exprCreateList:
+		items := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			value, err := e.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+
+	case *exprCreateMap:
+		m := make(map[string]interface{}, len(n.keys))
+		for i := range n.keys {
+			key, err := e.eval(n.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			s, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings")
+			}
+			if m[s], err = e.eval(n.values[i]); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case *exprComprehension:
+		return e.evalComprehension(n)
+
+	case *exprCall:
+		return e.evalCall(n)
+	}
+	return nil, fmt.Errorf("unsupported expression")
+}
+
+// evalLogical gives && and || CEL's commutative error handling: an error
+// on one side is absorbed when the other side decides the result
+func (e *exprEvaluator) evalLogical(n *exprBinary) (interface{}, error) {
+	absorbing := n.op == "||"
+	left, leftErr := e.eval(n.left)
+	if leftErr == nil {
+		b, ok := left.(bool)
+		if !ok {
+			leftErr = fmt.Errorf("operator %s needs bools", n.op)
+		} else if b == absorbing {
+			return b, nil
+		}
+	}
+	right, err := e.eval(n.right)
+	if err != nil {
+		if leftErr != nil {
+			return nil, leftErr
+		}
+		return nil, err
+	}
+	b, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator %s needs bools", n.op)
+	}
+	if b == absorbing {
+		return b, nil
+	}
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	return b, nil
+}
+
+func (e *exprEvaluator) evalBinary(op string, left, right interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return exprEqual(left, right), nil
+	case "!=":
+		return !exprEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		cmp, err := exprCompare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			if err := e.charge(len(container)); err != nil {
+				return nil, err
+			}
+			for _, item := range container {
+				if exprEqual(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, present := container[key]
+			return present, nil
+		}
+		return nil, fmt.Errorf("operator in needs a list or map, not %s", exprTypeOf(right))
+	}
+	return e.evalArithmetic(op, left, right)
+}
+
+func (e *exprEvaluator) evalArithmetic(op string, left, right interface{}) (interface{}, error) {
+	mismatch := fmt.Errorf("no operator %s for %s and %s", op, exprTypeOf(left), exprTypeOf(right))
+
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			return intArithmetic(op, l, r)
+		}
+	}
+	if l, ok := exprFloat(left); ok {
+		if r, ok := exprFloat(right); ok {
+			switch op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			case "*":
+				return l * r, nil
+			case "/":
+				return l / r, nil
+			}
+			return nil, mismatch
+		}
+	}
+
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok && op == "+" {
+			if len(l)+len(r) > maxExpressionString {
+				return nil, fmt.Errorf("string exceeds %d bytes", maxExpressionString)
+			}
+			return l + r, nil
+		}
+	case []interface{}:
+		if r, ok := right.([]interface{}); ok && op == "+" {
+			if err := e.charge(len(l) + len(r)); err != nil {
+				return nil, err
+			}
+			return append(append(make([]interface{}, 0, len(l)+len(r)), l...), r...), nil
+		}
+	case time.Time:
+		switch r := right.(type) {
+		case time.Duration:
+			if op == "+" {
+				return l.Add(r), nil
+			}
+			if op == "-" {
+				return l.Add(-r), nil
+			}
+		case time.Time:
+			if op == "-" {
+				return l.Sub(r), nil
+			}
+		}
+	case time.Duration:
+		switch r := right.(type) {
+		case time.Duration:
+			if op == "+" {
+				return l + r, nil
+			}
+			if op == "-" {
+				return l - r, nil
+			}
+		case time.Time:
+			if op == "+" {
+				return r.Add(l), nil
+			}
+		}
+	}
+	return nil, mismatch
+}
+
+func intArithmetic(op string, l, r int64) (interface{}, error) {
+	overflow := fmt.Errorf("integer overflow")
+	switch op {
+	case "+":
+		sum := l + r
+		if (sum > l) != (r > 0) {
+			return nil, overflow
+		}
+		return sum, nil
+	case "-":
+		diff := l - r
+		if (diff < l) != (r > 0) {
+			return nil, overflow
+		}
+		return diff, nil
+	case "*":
+		if l == 0 || r == 0 {
+			return int64(0), nil
+		}
+		product := l * r
+		if product/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+			return nil, overflow
+		}
+		return product, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, overflow
+		}
+		if op == "/" {
+			return l / r, nil
+		}
+		return l % r, nil
+	}
+	return nil, fmt.Errorf("no operator %s for ints", op)
+}
+
+func exprFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// exprEqual compares values structurally; numbers compare by value
+// whatever their type
+func exprEqual(left, right interface{}) bool {
+	if l, ok := exprFloat(left); ok {
+		r, ok := exprFloat(right)
+		return ok && l == r
+	}
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !exprEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, ok := r[key]
+			if !ok || !exprEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case time.Time:
+		r, ok := right.(time.Time)
+		return ok && l.Equal(r)
+	}
+	return left == right
+}
+
+func exprCompare(left, right interface{}) (int, error) {
+	if l, ok := exprFloat(left); ok {
+		if r, ok := exprFloat(right); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return l.Compare(r), nil
+		}
+	case time.Duration:
+		if r, ok := right.(time.Duration); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", exprTypeOf(left), exprTypeOf(right))
+}
+
+func (e *exprEvaluator) evalComprehension(n *exprComprehension) (interface{}, error) {
+	target, err := e.eval(n.target)
+	if err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	switch container := target.(type) {
+	case []interface{}:
+		items = container
+	case map[string]interface{}:
+		// Maps iterate over their keys, in a stable order
+		keys := make([]string, 0, len(container))
+		for key := range container {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			items = append(items, key)
+		}
+	default:
+		return nil, fmt.Errorf("%s() needs a list or map, not %s", n.macro, exprTypeOf(target))
+	}
+
+	scope := map[string]interface{}{}
+	e.scopes = append(e.scopes, scope)
+	defer func() { e.scopes = e.scopes[:len(e.scopes)-1] }()
+
+	var results []interface{}
+	matches := 0
+	var firstErr error
+	for _, item := range items {
+		scope[n.variable] = item
+		value, err := e.eval(n.body)
+		if n.macro == "map" {
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, value)
+			continue
+		}
+		if err != nil {
+			if e.budget < 0 {
+				return nil, err
+			}
+			// Like &&/||, all() and exists() tolerate errors on elements
+			// when another element decides the result
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s() predicate must produce a bool", n.macro)
+		}
+		switch n.macro {
+		case "all":
+			if !b {
+				return false, nil
+			}
+		case "exists":
+			if b {
+				return true, nil
+			}
+		case "exists_one":
+			if b {
+				matches++
+			}
+		case "filter":
+			if b {
+				results = append(results, item)
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	switch n.macro {
+	case "all":
+		return true, nil
+	case "exists":
+		return false, nil
+	case "exists_one":
+		return matches == 1, nil
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+	return results, nil
+}
+
+func (e *exprEvaluator) evalCall(n *exprCall) (interface{}, error) {
+	var receiver interface{}
+	if n.target != nil {
+		var err error
+		if receiver, err = e.eval(n.target); err != nil {
+			return nil, err
+		}
+	}
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	if n.target != nil {
+		return e.evalMethod(n.function, receiver, args)
+	}
+
+	switch n.function {
+	case "size":
+		return exprSize(args[0])
+	case "matches":
+		return exprMatches(args[0], args[1])
+	case "timestamp":
+		switch v := args[0].(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("timestamp(): %v", err)
+			}
+			return t, nil
+		case time.Time:
+			return v, nil
+		case int64:
+			return time.Unix(v, 0).UTC(), nil
+		}
+		return nil, fmt.Errorf("timestamp() cannot convert %s", exprTypeOf(args[0]))
+	case "duration":
+		switch v := args[0].(type) {
+		case string:
+			d, err := parseExprDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("duration(): %v", err)
+			}
+			return d, nil
+		case time.Duration:
+			return v, nil
+		}
+		return nil, fmt.Errorf("duration() cannot convert %s", exprTypeOf(args[0]))
+	case "int":
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+				return nil, fmt.Errorf("int(): %v out of range", v)
+			}
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(): %v", err)
+			}
+			return i, nil
+		case time.Time:
+			return v.Unix(), nil
+		}
+		return nil, fmt.Errorf("int() cannot convert %s", exprTypeOf(args[0]))
+	case "double":
+		switch v := args[0].(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("double(): %v", err)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("double() cannot convert %s", exprTypeOf(args[0]))
+	case "string":
+		switch v := args[0].(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano), nil
+		case time.Duration:
+			return v.String(), nil
+		}
+		return nil, fmt.Errorf("string() cannot convert %s", exprTypeOf(args[0]))
+	case "issuerID":
+		// A credential's issuer is either an identifier or an object with id
+		var issuer interface{} = args[0]
+		if credential, ok := issuer.(map[string]interface{}); ok {
+			if value, ok := credential["issuer"]; ok {
+				issuer = value
+			}
+		}
+		return extractIssuerID(issuer), nil
+	}
+	return nil, fmt.Errorf("undeclared function '%s'", n.function)
+}
+
+func (e *exprEvaluator) evalMethod(function string, receiver interface{}, args []interface{}) (interface{}, error) {
+	switch function {
+	case "size":
+		return exprSize(receiver)
+	case "matches":
+		return exprMatches(receiver, args[0])
+	case "distinct":
+		list, ok := receiver.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("distinct() is not defined on %s", exprTypeOf(receiver))
+		}
+		if err := e.charge(len(list) * len(list)); err != nil {
+			return nil, err
+		}
+		distinct := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			seen := false
+			for _, kept := range distinct {
+				if exprEqual(item, kept) {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				distinct = append(distinct, item)
+			}
+		}
+		return distinct, nil
+	case "lowerAscii":
+		s, ok := receiver.(string)
+		if !ok {
+			return nil, fmt.Errorf("lowerAscii() is not defined on %s", exprTypeOf(receiver))
+		}
+		return strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, s), nil
+	}
+
+	s, ok := receiver.(string)
+	arg, isString := args[0].(string)
+	if !ok || !isString {
+		return nil, fmt.Errorf("%s() needs strings", function)
+	}
+	if err := e.charge(len(s) / 64); err != nil {
+		return nil, err
+	}
+	switch function {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	}
+	return nil, fmt.Errorf("undeclared method '%s'", function)
+}
+
+func exprSize(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case map[string]interface{}:
+		return int64(len(v)), nil
+	}
+	return nil, fmt.Errorf("size() is not defined on %s", exprTypeOf(value))
+}
+
+func exprMatches(value, pattern interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	p, isString := pattern.(string)
+	if !ok || !isString {
+		return nil, fmt.Errorf("matches() needs strings")
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, fmt.Errorf("matches(): %v", err)
+	}
+	return re.MatchString(s), nil
+}
@@ -0,0 +1,307 @@
+package vc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyExpression_Evaluate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	variables := map[string]interface{}{
+		"credential": map[string]interface{}{
+			"type":         []interface{}{"VerifiableCredential", "KYCCredential"},
+			"issuanceDate": "2024-01-15T00:00:00Z",
+			"credentialSubject": map[string]interface{}{
+				"level": 3.0,
+				"name":  "Asha",
+			},
+		},
+		"issuer":      map[string]interface{}{"id": "did:example:bank", "trusted": true, "trustLevel": "high"},
+		"status":      map[string]interface{}{"checked": true, "revoked": false, "type": ""},
+		"score":       0.82,
+		"credentials": []interface{}{},
+		"now":         now,
+	}
+
+	for expression, expected := range map[string]bool{
+		`'KYCCredential' in credential.type`:                                     true,
+		`credential.credentialSubject.level >= 3 && issuer.trustLevel == "high"`: true,
+		`timestamp(credential.issuanceDate) > now - duration("730d")`:            true,
+		`now - timestamp(credential.issuanceDate) < duration("100d")`:            false,
+		`score > 0.8 ? !status.revoked : false`:                                  true,
+		`credential.type.exists(t, t.startsWith("KYC"))`:                         true,
+		`credential.type.all(t, t.endsWith("Credential"))`:                       true,
+		`credential.type.filter(t, t != "VerifiableCredential").size() == 1`:     true,
+		`credential.type.map(t, t.lowerAscii())[1] == "kyccredential"`:           true,
+		`has(credential.credentialSubject.name) && !has(credential.evidence)`:    true,
+		`credential.credentialSubject.name.matches("^A[a-z]+$")`:                 true,
+		`size([1, 1, 2].distinct()) == 2 && {"a": 1}["a"] == 1.0`:                true,
+		`1 + 2 * 3 == 7 && 7 % 4 == 3 && -(2) == -2 && 5 / 2 == 2`:               true,
+		`int("42") == 42 && double(1) == 1.0 && string(12) == "12"`:              true,
+		// The error on the left is absorbed by the right deciding the result
+		`credential.missing == 1 || true`:  true,
+		`credential.missing == 1 && false`: false,
+	} {
+		compiled, err := CompilePolicyExpression(expression)
+		require.NoError(t, err, expression)
+		result, err := compiled.Evaluate(variables)
+		require.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+
+	for expression, message := range map[string]string{
+		`credential.missing == 1`:                  "no such key",
+		`1 / (score > 1.0 ? 1 : 0) == 0`:           "division by zero",
+		`9223372036854775807 + 1 > 0`:              "overflow",
+		`credential.type[5] == "x"`:                "out of range",
+		`score == null || score > credential.type`: "cannot compare",
+	} {
+		compiled, err := CompilePolicyExpression(expression)
+		require.NoError(t, err, expression)
+		_, err = compiled.Evaluate(variables)
+		assert.ErrorContains(t, err, message, expression)
+	}
+}
+
+func TestPolicyExpression_CompileErrors(t *testing.T) {
+	for expression, message := range map[string]string{
+		`credentail.type == 1`:                     "undeclared reference",
+		`exec("rm -rf /")`:                         "undeclared function",
+		`credential.type.eval()`:                   "undeclared method",
+		`credential.issuanceDate + 1`:              "",
+		`"a" + 1 == "a1"`:                          "no operator +",
+		`1 + 2`:                                    "must evaluate to bool",
+		`timestamp("yesterday") < now`:             "invalid timestamp()",
+		`duration("2 years") > duration("1h")`:     "invalid duration()",
+		`issuer.id.matches("(")`:                   "invalid matches()",
+		`credential.type.exists(credential, true)`: "shadows",
+		`credential.type.exists(t, 1)`:             "predicate must be a bool",
+		`(true`:                                    "expected",
+		`'unterminated`:                            "unterminated",
+		`size(credential, 1) == 0`:                 "takes 1 arguments",
+		`has(credential)`:                          "field selection",
+		`now < 3`:                                  "no operator <",
+		strings.Repeat("(", 100) + "true" + strings.Repeat(")", 100): "nests deeper",
+		strings.Repeat("a", maxExpressionLength+1):                   "exceeds",
+	} {
+		_, err := CompilePolicyExpression(expression)
+		if message == "" {
+			// Dynamic operands are only checked at evaluation
+			assert.NoError(t, err, expression)
+			continue
+		}
+		assert.ErrorContains(t, err, message, expression)
+	}
+}
+
+func TestPolicyExpression_Budget(t *testing.T) {
+	items := make([]interface{}, 400)
+	for i := range items {
+		items[i] = int64(i)
+	}
+	compiled, err := CompilePolicyExpression(`credentials.all(a, credentials.all(b, a + b >= 0))`)
+	require.NoError(t, err)
+	_, err = compiled.Evaluate(map[string]interface{}{"credentials": items})
+	assert.ErrorContains(t, err, "budget")
+}
+
+func expressionFramework() *TrustFramework {
+	return &TrustFramework{
+		ID:      "compliance",
+		Name:    "Compliance",
+		Version: "1",
+		TrustedIssuers: []TrustedIssuer{
+			{DID: "did:example:bank", TrustLevel: TrustLevelHigh},
+			{DID: "did:example:acme", TrustLevel: TrustLevelMedium},
+			{DID: "did:example:globex", TrustLevel: TrustLevelMedium},
+		},
+		Policies: []TrustPolicy{{
+			ID:      "onboarding",
+			Type:    "credential",
+			Actions: []string{"reject"},
+			Conditions: map[string]interface{}{
+				"not-revoked": `!status.revoked`,
+			},
+			Rules: []TrustRule{{
+				ID:       "kyc-or-employment",
+				Type:     "expression",
+				Required: true,
+				Expression: `('KYCCredential' in credential.type && issuer.trustLevel == 'high' &&
+					timestamp(credential.issuanceDate) > now - duration('730d')) ||
+					credentials.filter(c, 'EmploymentCredential' in c.type).map(c, issuerID(c)).distinct().size() >= 2`,
+			}},
+		}},
+	}
+}
+
+func TestTrustFrameworkEngine_Expressions(t *testing.T) {
+	engine := NewTrustFrameworkEngine()
+	require.NoError(t, engine.LoadFramework(expressionFramework()))
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	credential := func(issuer, typ, issued string) *VerifiableCredential {
+		return &VerifiableCredential{
+			Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+			Type:              []string{"VerifiableCredential", typ},
+			Issuer:            issuer,
+			IssuanceDate:      issued,
+			CredentialSubject: map[string]interface{}{"id": "did:example:holder"},
+		}
+	}
+
+	decision, err := engine.EvaluateCredentialWithInput(context.Background(),
+		credential("did:example:bank", "KYCCredential", "2024-03-01T00:00:00Z"), "compliance", &PolicyInput{Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, "accept", decision.Decision, decision.Reason)
+
+	// Too old a KYC credential, and no employment credentials
+	decision, err = engine.EvaluateCredentialWithInput(context.Background(),
+		credential("did:example:bank", "KYCCredential", "2021-03-01T00:00:00Z"), "compliance", &PolicyInput{Now: now})
+	require.NoError(t, err)
+	assert.Equal(t, "reject", decision.Decision)
+
+	first := credential("did:example:acme", "EmploymentCredential", "2025-01-01T00:00:00Z")
+	second := credential("did:example:globex", "EmploymentCredential", "2025-02-01T00:00:00Z")
+	sameIssuer := credential("did:example:acme", "EmploymentCredential", "2025-03-01T00:00:00Z")
+
+	decision, err = engine.EvaluateCredentialWithInput(context.Background(), first, "compliance",
+		&PolicyInput{Now: now, Credentials: []*VerifiableCredential{first, second}})
+	require.NoError(t, err)
+	assert.Equal(t, "accept", decision.Decision, decision.Reason)
+
+	decision, err = engine.EvaluateCredentialWithInput(context.Background(), first, "compliance",
+		&PolicyInput{Now: now, Credentials: []*VerifiableCredential{first, sameIssuer}})
+	require.NoError(t, err)
+	assert.Equal(t, "reject", decision.Decision)
+
+	// The policy does not apply to revoked credentials
+	decision, err = engine.EvaluateCredentialWithInput(context.Background(), first, "compliance",
+		&PolicyInput{Now: now, StatusChecked: true, Revoked: true})
+	require.NoError(t, err)
+	assert.Equal(t, "accept", decision.Decision)
+	assert.Empty(t, decision.Violations)
+
+	// An unknown status is not taken as unrevoked: the condition cannot be
+	// evaluated, so the credential is rejected whatever the rules say
+	decision, err = engine.EvaluateCredentialWithInput(context.Background(), first, "compliance",
+		&PolicyInput{Now: now, StatusChecked: true, StatusError: "status list unavailable",
+			Credentials: []*VerifiableCredential{first, second}})
+	require.NoError(t, err)
+	assert.Equal(t, "reject", decision.Decision)
+	assert.Equal(t, "onboarding", decision.PolicyID)
+	require.Len(t, decision.Violations, 1)
+	assert.Equal(t, "conditions", decision.Violations[0].Field)
+	assert.Contains(t, decision.Violations[0].Actual, "condition not-revoked")
+}
+
+// fakeStatusResolver answers every status check with err
+type fakeStatusResolver struct {
+	err error
+}
+
+func (r *fakeStatusResolver) CheckStatus(ctx context.Context, status *CredentialStatus) error {
+	return r.err
+}
+
+func TestVerifier_StatusOutageIsNotRevocation(t *testing.T) {
+	verifier := NewDefaultCredentialVerifier(nil, nil)
+	require.NoError(t, verifier.LoadTrustFramework(expressionFramework()))
+
+	credential := &VerifiableCredential{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:              []string{"VerifiableCredential", "KYCCredential"},
+		Issuer:            "did:example:bank",
+		IssuanceDate:      time.Now().Add(-24 * time.Hour).UTC().Format(time.RFC3339),
+		CredentialSubject: map[string]interface{}{"id": "did:example:holder"},
+		CredentialStatus:  &CredentialStatus{ID: "https://example.com/status/1#5", Type: "BitstringStatusListEntry"},
+		Proof:             map[string]interface{}{"type": "Ed25519Signature2020"},
+	}
+	options := &VerificationOptions{CheckStatus: true}
+
+	resolver := &fakeStatusResolver{}
+	verifier.SetStatusResolver(resolver)
+	result, err := verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+
+	resolver.err = NewVCError(ErrorRevokedCredential, "status bit 5 is set")
+	result, err = verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "credential is revoked")
+
+	resolver.err = fmt.Errorf("status list unavailable")
+	result, err = verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "status check failed")
+	assert.NotContains(t, result.Error, "revoked")
+
+	// Under a trust framework, the outage fails the policy's conditions
+	options.TrustFramework = "compliance"
+	result, err = verifier.VerifyCredential(credential, options)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "condition not-revoked")
+}
+
+func TestTrustFrameworkEngine_ValidateExpressions(t *testing.T) {
+	engine := NewTrustFrameworkEngine()
+
+	framework := expressionFramework()
+	framework.Policies[0].Rules[0].Expression = `issuer.trustLevel = "high"`
+	assert.ErrorContains(t, engine.LoadFramework(framework), "rule kyc-or-employment")
+
+	framework = expressionFramework()
+	framework.Policies[0].Conditions["bad"] = `unknownVariable`
+	assert.ErrorContains(t, engine.LoadFramework(framework), "condition bad")
+
+	framework = expressionFramework()
+	framework.Policies[0].Conditions["bad"] = 42
+	assert.ErrorContains(t, engine.LoadFramework(framework), "expression string")
+
+	framework = expressionFramework()
+	framework.Policies[0].Rules[0].Expression = ""
+	assert.ErrorContains(t, engine.LoadFramework(framework), "no expression")
+
+	_, err := engine.GetFramework("compliance")
+	assert.Error(t, err)
+}
+
+func TestVerifier_PresentationTrustExpressions(t *testing.T) {
+	verifier := NewDefaultCredentialVerifier(nil, nil)
+	require.NoError(t, verifier.LoadTrustFramework(expressionFramework()))
+
+	employment := func(issuer string) map[string]interface{} {
+		return map[string]interface{}{
+			"@context":          []interface{}{"https://www.w3.org/2018/credentials/v1"},
+			"type":              []interface{}{"VerifiableCredential", "EmploymentCredential"},
+			"issuer":            issuer,
+			"issuanceDate":      "2025-01-01T00:00:00Z",
+			"credentialSubject": map[string]interface{}{"id": "did:example:holder"},
+			"proof":             map[string]interface{}{"type": "Ed25519Signature2020"},
+		}
+	}
+	presentation := &VerifiablePresentation{
+		Context:              []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:                 []string{"VerifiablePresentation"},
+		VerifiableCredential: []interface{}{employment("did:example:acme"), employment("did:example:globex")},
+		Proof:                map[string]interface{}{"type": "Ed25519Signature2020"},
+	}
+
+	result, err := verifier.VerifyPresentation(presentation, &VerificationOptions{TrustFramework: "compliance"})
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+
+	presentation.VerifiableCredential = presentation.VerifiableCredential[:1]
+	result, err = verifier.VerifyPresentation(presentation, &VerificationOptions{TrustFramework: "compliance"})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "policy violation")
+}
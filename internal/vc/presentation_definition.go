@@ -1,19 +1,44 @@
 package vc
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
 
 // PresentationDefinition represents a DIF Presentation Exchange presentation definition
 type PresentationDefinition struct {
-	ID      string                  `json:"id"`
-	Name    string                  `json:"name,omitempty"`
-	Purpose string                  `json:"purpose,omitempty"`
-	Format  *ClaimFormat           `json:"format,omitempty"`
-	InputDescriptors []InputDescriptor `json:"input_descriptors"`
+	ID                     string                  `json:"id"`
+	Name                   string                  `json:"name,omitempty"`
+	Purpose                string                  `json:"purpose,omitempty"`
+	Format                 *ClaimFormat            `json:"format,omitempty"`
+	SubmissionRequirements []SubmissionRequirement `json:"submission_requirements,omitempty"`
+	InputDescriptors       []InputDescriptor       `json:"input_descriptors"`
+}
+
+// SubmissionRule is the rule of a submission requirement
+type SubmissionRule string
+
+const (
+	SubmissionRuleAll  SubmissionRule = "all"
+	SubmissionRulePick SubmissionRule = "pick"
+)
+
+// SubmissionRequirement states which input descriptors, by group, or which
+// nested requirements a submission has to satisfy
+type SubmissionRequirement struct {
+	Name       string                  `json:"name,omitempty"`
+	Purpose    string                  `json:"purpose,omitempty"`
+	Rule       SubmissionRule          `json:"rule"`
+	Count      *int                    `json:"count,omitempty"`
+	Min        *int                    `json:"min,omitempty"`
+	Max        *int                    `json:"max,omitempty"`
+	From       string                  `json:"from,omitempty"`
+	FromNested []SubmissionRequirement `json:"from_nested,omitempty"`
 }
 
 // InputDescriptor describes requirements for input credentials
@@ -35,47 +60,113 @@ type Constraints struct {
 
 // Field defines a field constraint with JSONPath selectors
 type Field struct {
-	Path      []string      `json:"path"`
-	ID        string        `json:"id,omitempty"`
-	Purpose   string        `json:"purpose,omitempty"`
-	Name      string        `json:"name,omitempty"`
-	Filter    *Filter       `json:"filter,omitempty"`
-	Predicate *Predicate    `json:"predicate,omitempty"`
+	Path      []string        `json:"path"`
+	ID        string          `json:"id,omitempty"`
+	Purpose   string          `json:"purpose,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Filter    *Filter         `json:"filter,omitempty"`
+	Optional  bool            `json:"optional,omitempty"`
+	Predicate *Predicate      `json:"predicate,omitempty"`
 	Intent    *IntentToRetain `json:"intent_to_retain,omitempty"`
 }
 
-// Filter defines JSON Schema constraints for field values
+// Filter is a JSON Schema that field values are validated against. The
+// common keywords are exposed as fields; the schema as received, including
+// any other keyword, is kept and is what gets evaluated.
 type Filter struct {
-	Type        string      `json:"type,omitempty"`
-	Format      string      `json:"format,omitempty"`
-	Pattern     string      `json:"pattern,omitempty"`
-	Minimum     interface{} `json:"minimum,omitempty"`
-	Maximum     interface{} `json:"maximum,omitempty"`
-	MinLength   int         `json:"minLength,omitempty"`
-	MaxLength   int         `json:"maxLength,omitempty"`
-	Const       interface{} `json:"const,omitempty"`
-	Enum        []interface{} `json:"enum,omitempty"`
-	Not         *Filter     `json:"not,omitempty"`
-}
-
-// Predicate defines logic-based constraints
+	Type      string        `json:"type,omitempty"`
+	Format    string        `json:"format,omitempty"`
+	Pattern   string        `json:"pattern,omitempty"`
+	Minimum   interface{}   `json:"minimum,omitempty"`
+	Maximum   interface{}   `json:"maximum,omitempty"`
+	MinLength int           `json:"minLength,omitempty"`
+	MaxLength int           `json:"maxLength,omitempty"`
+	Const     interface{}   `json:"const,omitempty"`
+	Enum      []interface{} `json:"enum,omitempty"`
+	Not       *Filter       `json:"not,omitempty"`
+
+	raw interface{}
+}
+
+// filterFields has Filter's fields without its JSON methods
+type filterFields Filter
+
+// UnmarshalJSON decodes the filter, keeping the full schema
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var fields filterFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	raw, err := decodeJSONDocument(data)
+	if err != nil {
+		return err
+	}
+	*f = Filter(fields)
+	f.raw = raw
+	return nil
+}
+
+// MarshalJSON encodes the filter as the schema it was decoded from, or from
+// its fields when it was built in code
+func (f Filter) MarshalJSON() ([]byte, error) {
+	if f.raw != nil {
+		return json.Marshal(f.raw)
+	}
+	return json.Marshal(filterFields(f))
+}
+
+// schemaDocument returns the filter as a decoded JSON Schema
+func (f *Filter) schemaDocument() (interface{}, error) {
+	if f.raw != nil {
+		return f.raw, nil
+	}
+	return toJSONDocument(filterFields(*f))
+}
+
+// Predicate asks the holder to prove a field satisfies its filter without
+// disclosing the value
 type Predicate string
 
+const (
+	PredicateRequired  Predicate = "required"
+	PredicatePreferred Predicate = "preferred"
+)
+
 // IntentToRetain indicates whether the verifier intends to retain field data
 type IntentToRetain bool
 
 // LimitDisclosure controls selective disclosure
 type LimitDisclosure string
 
+const (
+	LimitDisclosureRequired  LimitDisclosure = "required"
+	LimitDisclosurePreferred LimitDisclosure = "preferred"
+)
+
 // SubjectIsIssuer indicates the credential subject must be the issuer
 type SubjectIsIssuer string
 
+// Claim format designations from the Presentation Exchange registry
+const (
+	FormatJWTVC   = "jwt_vc"
+	FormatJWTVP   = "jwt_vp"
+	FormatLDPVC   = "ldp_vc"
+	FormatLDPVP   = "ldp_vp"
+	FormatSDJWTVC = "vc+sd-jwt"
+	FormatMsoMdoc = "mso_mdoc"
+)
+
 // ClaimFormat specifies supported credential formats
 type ClaimFormat struct {
 	JWT     *JWTFormat     `json:"jwt,omitempty"`
 	JSONLD  *JSONLDFormat  `json:"jwt_vc,omitempty"`
+	JWTVP   *JWTFormat     `json:"jwt_vp,omitempty"`
+	LDPAny  *LDPFormat     `json:"ldp,omitempty"`
 	LDP     *LDPFormat     `json:"ldp_vc,omitempty"`
+	LDPVP   *LDPFormat     `json:"ldp_vp,omitempty"`
 	SDJWT   *SDJWTFormat   `json:"sd-jwt,omitempty"`
+	SDJWTVC *SDJWTFormat   `json:"vc+sd-jwt,omitempty"`
+	MsoMdoc *MsoMdocFormat `json:"mso_mdoc,omitempty"`
 }
 
 // JWTFormat specifies JWT format constraints
@@ -83,9 +174,9 @@ type JWTFormat struct {
 	Alg []string `json:"alg,omitempty"`
 }
 
-// JSONLDFormat specifies JSON-LD format constraints  
+// JSONLDFormat specifies JSON-LD format constraints
 type JSONLDFormat struct {
-	Alg   []string `json:"alg,omitempty"`
+	Alg       []string `json:"alg,omitempty"`
 	ProofType []string `json:"proof_type,omitempty"`
 }
 
@@ -96,386 +187,1002 @@ type LDPFormat struct {
 
 // SDJWTFormat specifies Selective Disclosure JWT format constraints
 type SDJWTFormat struct {
+	Alg            []string `json:"alg,omitempty"`
+	KbJWTAlg       []string `json:"kb-jwt_alg,omitempty"`
+	SDJWTAlgValues []string `json:"sd-jwt_alg_values,omitempty"`
+	KBJWTAlgValues []string `json:"kb-jwt_alg_values,omitempty"`
+}
+
+// MsoMdocFormat specifies ISO 18013-5 mdoc format constraints
+type MsoMdocFormat struct {
 	Alg []string `json:"alg,omitempty"`
-	KbJWTAlg []string `json:"kb-jwt_alg,omitempty"`
 }
 
 // PresentationSubmission represents a submission against a presentation definition
 type PresentationSubmission struct {
-	ID              string                    `json:"id"`
-	DefinitionID    string                    `json:"definition_id"`
-	DescriptorMap   []DescriptorMap          `json:"descriptor_map"`
+	ID            string          `json:"id"`
+	DefinitionID  string          `json:"definition_id"`
+	DescriptorMap []DescriptorMap `json:"descriptor_map"`
 }
 
 // DescriptorMap maps credentials to input descriptors
 type DescriptorMap struct {
-	ID         string                 `json:"id"`
-	Format     string                 `json:"format"`
-	Path       string                 `json:"path"`
-	PathNested *DescriptorMap        `json:"path_nested,omitempty"`
+	ID         string         `json:"id"`
+	Format     string         `json:"format"`
+	Path       string         `json:"path"`
+	PathNested *DescriptorMap `json:"path_nested,omitempty"`
 }
 
-// PresentationDefinitionProcessor handles presentation definition evaluation
-type PresentationDefinitionProcessor struct {
-	// Could add configuration or dependencies here
+// SubmissionPackage is a presentation submission together with the
+// credentials its descriptor map points into, in presentation order
+type SubmissionPackage struct {
+	Submission  *PresentationSubmission `json:"presentation_submission"`
+	Credentials []interface{}           `json:"verifiableCredential"`
 }
 
+// maxSubmissionSearch bounds the number of assignments the submission
+// requirement solver explores before settling for the best one found
+const maxSubmissionSearch = 1 << 16
+
+// PresentationDefinitionProcessor handles presentation definition evaluation
+type PresentationDefinitionProcessor struct{}
+
 // NewPresentationDefinitionProcessor creates a new processor
 func NewPresentationDefinitionProcessor() *PresentationDefinitionProcessor {
 	return &PresentationDefinitionProcessor{}
 }
 
-// EvaluateCredentials evaluates credentials against a presentation definition
-func (pdp *PresentationDefinitionProcessor) EvaluateCredentials(
-	definition *PresentationDefinition,
-	credentials []*VerifiableCredential,
-) (*EvaluationResult, error) {
-	
-	result := &EvaluationResult{
-		DefinitionID:   definition.ID,
-		EvaluatedAt:    time.Now(),
-		Matches:        make([]*CredentialMatch, 0),
-		Warnings:       make([]string, 0),
-		Errors:         make([]string, 0),
-	}
-
-	// Evaluate each input descriptor
-	for _, descriptor := range definition.InputDescriptors {
-		matches := pdp.evaluateInputDescriptor(&descriptor, credentials)
-		result.Matches = append(result.Matches, matches...)
-		
-		if len(matches) == 0 {
-			result.Errors = append(result.Errors, 
-				fmt.Sprintf("No credentials match input descriptor '%s'", descriptor.ID))
-		}
-	}
-
-	result.Valid = len(result.Errors) == 0
-
-	return result, nil
-}
-
 // EvaluationResult contains the result of evaluating credentials against a definition
 type EvaluationResult struct {
-	Valid         bool                `json:"valid"`
-	DefinitionID  string             `json:"definition_id"`
-	Matches       []*CredentialMatch `json:"matches"`
-	Warnings      []string           `json:"warnings,omitempty"`
-	Errors        []string           `json:"errors,omitempty"`
-	EvaluatedAt   time.Time          `json:"evaluated_at"`
+	Valid        bool               `json:"valid"`
+	DefinitionID string             `json:"definition_id"`
+	Matches      []*CredentialMatch `json:"matches"`
+	// Selected is the submission chosen from Matches: one match per
+	// descriptor to submit, using as few credentials as possible
+	Selected    []*CredentialMatch `json:"selected,omitempty"`
+	Warnings    []string           `json:"warnings,omitempty"`
+	Errors      []string           `json:"errors,omitempty"`
+	EvaluatedAt time.Time          `json:"evaluated_at"`
 }
 
 // CredentialMatch represents a credential that matches an input descriptor
 type CredentialMatch struct {
-	InputDescriptorID string                 `json:"input_descriptor_id"`
-	CredentialIndex   int                    `json:"credential_index"`
-	Credential        *VerifiableCredential  `json:"credential"`
+	InputDescriptorID string                `json:"input_descriptor_id"`
+	CredentialIndex   int                   `json:"credential_index"`
+	Credential        *VerifiableCredential `json:"credential"`
+	Format            string                `json:"format,omitempty"`
 	MatchedFields     []FieldMatch          `json:"matched_fields,omitempty"`
+	// Disclosed replaces Credential in the submission when disclosure is
+	// limited: a reduced credential document, or an SD-JWT carrying only
+	// the needed disclosures
+	Disclosed interface{} `json:"disclosed,omitempty"`
 }
 
 // FieldMatch represents a field that matched constraints
 type FieldMatch struct {
-	FieldID    string      `json:"field_id,omitempty"`
-	Path       string      `json:"path"`
-	Value      interface{} `json:"value"`
-	Satisfied  bool        `json:"satisfied"`
+	FieldID   string      `json:"field_id,omitempty"`
+	Path      string      `json:"path"`
+	Value     interface{} `json:"value"`
+	Satisfied bool        `json:"satisfied"`
+	Predicate bool        `json:"predicate,omitempty"`
+}
+
+// compiledDescriptor is an input descriptor with its paths and filters parsed
+type compiledDescriptor struct {
+	descriptor *InputDescriptor
+	fields     []compiledField
+}
+
+type compiledField struct {
+	field     *Field
+	paths     []*JSONPath
+	evaluator *schemaEvaluator
+	filter    schemaRef
+}
+
+// ValidateDefinition checks that a definition is well formed: descriptor ids
+// are unique, paths and filters compile, and submission requirements are
+// consistent and only reference known groups
+func (pdp *PresentationDefinitionProcessor) ValidateDefinition(definition *PresentationDefinition) error {
+	_, err := pdp.compileDefinition(definition)
+	return err
+}
+
+func (pdp *PresentationDefinitionProcessor) compileDefinition(definition *PresentationDefinition) ([]compiledDescriptor, error) {
+	if definition == nil {
+		return nil, fmt.Errorf("presentation definition is required")
+	}
+	if definition.ID == "" {
+		return nil, fmt.Errorf("presentation definition id is required")
+	}
+	if len(definition.InputDescriptors) == 0 {
+		return nil, fmt.Errorf("presentation definition has no input descriptors")
+	}
+
+	groups := make(map[string]bool)
+	seen := make(map[string]bool)
+	compiled := make([]compiledDescriptor, len(definition.InputDescriptors))
+
+	for i := range definition.InputDescriptors {
+		descriptor := &definition.InputDescriptors[i]
+		if descriptor.ID == "" {
+			return nil, fmt.Errorf("input descriptor %d has no id", i)
+		}
+		if seen[descriptor.ID] {
+			return nil, fmt.Errorf("duplicate input descriptor id '%s'", descriptor.ID)
+		}
+		seen[descriptor.ID] = true
+		for _, group := range descriptor.Group {
+			groups[group] = true
+		}
+
+		compiled[i].descriptor = descriptor
+		if descriptor.Constraints == nil {
+			continue
+		}
+		if ld := descriptor.Constraints.LimitDisclosure; ld != nil && *ld != LimitDisclosureRequired && *ld != LimitDisclosurePreferred {
+			return nil, fmt.Errorf("input descriptor '%s': invalid limit_disclosure '%s'", descriptor.ID, *ld)
+		}
+
+		for j := range descriptor.Constraints.Fields {
+			field := &descriptor.Constraints.Fields[j]
+			cf, err := compileField(field, fmt.Sprintf("urn:presentation-exchange:%s:field:%d", descriptor.ID, j))
+			if err != nil {
+				return nil, fmt.Errorf("input descriptor '%s' field %d: %w", descriptor.ID, j, err)
+			}
+			compiled[i].fields = append(compiled[i].fields, cf)
+		}
+	}
+
+	for _, requirement := range definition.SubmissionRequirements {
+		if err := validateRequirement(requirement, groups); err != nil {
+			return nil, err
+		}
+	}
+
+	return compiled, nil
+}
+
+func compileField(field *Field, filterURI string) (compiledField, error) {
+	cf := compiledField{field: field}
+	if len(field.Path) == 0 {
+		return cf, fmt.Errorf("path is required")
+	}
+	for _, expression := range field.Path {
+		path, err := CompileJSONPath(expression)
+		if err != nil {
+			return cf, err
+		}
+		cf.paths = append(cf.paths, path)
+	}
+
+	if field.Predicate != nil {
+		if *field.Predicate != PredicateRequired && *field.Predicate != PredicatePreferred {
+			return cf, fmt.Errorf("invalid predicate '%s'", *field.Predicate)
+		}
+		if field.Filter == nil {
+			return cf, fmt.Errorf("predicate requires a filter")
+		}
+	}
+
+	if field.Filter != nil {
+		document, err := field.Filter.schemaDocument()
+		if err != nil {
+			return cf, fmt.Errorf("invalid filter: %w", err)
+		}
+		// Filters are draft-07 schemas per the specification unless they
+		// declare otherwise
+		index := newSchemaIndex()
+		root, err := index.add(filterURI, document, draft7)
+		if err != nil {
+			return cf, fmt.Errorf("invalid filter: %w", err)
+		}
+		cf.evaluator = newSchemaEvaluator(index, nil)
+		cf.evaluator.defaultDraft = draft7
+		cf.filter = root
+	}
+	return cf, nil
 }
 
-// evaluateInputDescriptor evaluates credentials against a single input descriptor
-func (pdp *PresentationDefinitionProcessor) evaluateInputDescriptor(
-	descriptor *InputDescriptor,
+func validateRequirement(requirement SubmissionRequirement, groups map[string]bool) error {
+	name := requirement.Name
+	if name == "" {
+		name = string(requirement.Rule)
+	}
+
+	if requirement.Rule != SubmissionRuleAll && requirement.Rule != SubmissionRulePick {
+		return fmt.Errorf("submission requirement '%s': invalid rule '%s'", name, requirement.Rule)
+	}
+	if (requirement.From == "") == (len(requirement.FromNested) == 0) {
+		return fmt.Errorf("submission requirement '%s': exactly one of from and from_nested is required", name)
+	}
+	if requirement.From != "" && !groups[requirement.From] {
+		return fmt.Errorf("submission requirement '%s': no input descriptor is in group '%s'", name, requirement.From)
+	}
+	for _, bound := range []*int{requirement.Count, requirement.Min, requirement.Max} {
+		if bound != nil && *bound < 0 {
+			return fmt.Errorf("submission requirement '%s': count, min and max must not be negative", name)
+		}
+	}
+	if requirement.Min != nil && requirement.Max != nil && *requirement.Min > *requirement.Max {
+		return fmt.Errorf("submission requirement '%s': min exceeds max", name)
+	}
+
+	for _, nested := range requirement.FromNested {
+		if err := validateRequirement(nested, groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EvaluateCredentials evaluates credentials against a presentation definition
+// and solves its submission requirements for the smallest set of
+// credentials that satisfies them
+func (pdp *PresentationDefinitionProcessor) EvaluateCredentials(
+	definition *PresentationDefinition,
 	credentials []*VerifiableCredential,
-) []*CredentialMatch {
-	
-	var matches []*CredentialMatch
+) (*EvaluationResult, error) {
+	compiled, err := pdp.compileDefinition(definition)
+	if err != nil {
+		return nil, err
+	}
 
+	result := &EvaluationResult{
+		DefinitionID: definition.ID,
+		EvaluatedAt:  time.Now(),
+		Matches:      make([]*CredentialMatch, 0),
+		Warnings:     make([]string, 0),
+		Errors:       make([]string, 0),
+	}
+
+	documents := make([]interface{}, len(credentials))
 	for i, credential := range credentials {
-		if pdp.credentialMatchesDescriptor(credential, descriptor) {
-			match := &CredentialMatch{
-				InputDescriptorID: descriptor.ID,
-				CredentialIndex:   i,
-				Credential:        credential,
-				MatchedFields:     pdp.evaluateFieldConstraints(credential, descriptor),
+		// An mdoc's fields are addressed by namespace and element
+		// identifier rather than by credential member
+		var source interface{} = credential
+		if credential.Mdoc != nil {
+			source = credential.CredentialSubject
+		}
+		if documents[i], err = toJSONDocument(source); err != nil {
+			return nil, fmt.Errorf("failed to encode credential %d: %w", i, err)
+		}
+	}
+
+	candidates := make([][]*CredentialMatch, len(compiled))
+	for i := range compiled {
+		for j, credential := range credentials {
+			match, warning := pdp.matchDescriptor(definition, &compiled[i], j, credential, documents[j])
+			if warning != "" {
+				result.Warnings = append(result.Warnings, warning)
 			}
-			matches = append(matches, match)
+			if match != nil {
+				candidates[i] = append(candidates[i], match)
+			}
+		}
+		result.Matches = append(result.Matches, candidates[i]...)
+
+		if len(candidates[i]) == 0 && len(definition.SubmissionRequirements) == 0 {
+			result.Errors = append(result.Errors,
+				fmt.Sprintf("No credentials match input descriptor '%s'", compiled[i].descriptor.ID))
 		}
 	}
 
-	return matches
+	if len(result.Errors) == 0 {
+		solver := newSubmissionSolver(definition, candidates)
+		if selected, ok := solver.solve(); ok {
+			result.Selected = selected
+		} else {
+			result.Errors = append(result.Errors, solver.explain()...)
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result, nil
 }
 
-// credentialMatchesDescriptor checks if a credential matches basic descriptor requirements
-func (pdp *PresentationDefinitionProcessor) credentialMatchesDescriptor(
+// matchDescriptor checks one credential against one input descriptor. It
+// returns nil when the credential does not qualify, and a warning when it
+// qualifies only in a weaker form than requested.
+func (pdp *PresentationDefinitionProcessor) matchDescriptor(
+	definition *PresentationDefinition,
+	compiled *compiledDescriptor,
+	index int,
 	credential *VerifiableCredential,
-	descriptor *InputDescriptor,
-) bool {
-	
-	// Check format constraints
-	if descriptor.Format != nil {
-		if !pdp.checkFormatConstraints(credential, descriptor.Format) {
-			return false
-		}
+	document interface{},
+) (*CredentialMatch, string) {
+	descriptor := compiled.descriptor
+
+	format := descriptor.Format
+	if format == nil {
+		format = definition.Format
+	}
+	formatName, ok := pdp.checkFormatConstraints(credential, format)
+	if !ok {
+		return nil, ""
+	}
+	// mso_mdoc input descriptors are identified by the requested docType
+	if formatName == FormatMsoMdoc && (len(credential.Type) < 2 || credential.Type[1] != descriptor.ID) {
+		return nil, ""
+	}
+
+	match := &CredentialMatch{
+		InputDescriptorID: descriptor.ID,
+		CredentialIndex:   index,
+		Credential:        credential,
+		Format:            formatName,
+	}
+
+	constraints := descriptor.Constraints
+	if constraints == nil {
+		return match, ""
 	}
 
-	// Check field constraints
-	if descriptor.Constraints != nil && len(descriptor.Constraints.Fields) > 0 {
-		for _, field := range descriptor.Constraints.Fields {
-			if !pdp.evaluateFieldConstraint(credential, &field) {
-				return false
+	var selected []JSONPathNode
+	for _, field := range compiled.fields {
+		node, found := field.evaluate(document)
+		if !found {
+			if field.field.Optional {
+				continue
 			}
+			return nil, ""
+		}
+
+		predicate := field.field.Predicate != nil
+		match.MatchedFields = append(match.MatchedFields, FieldMatch{
+			FieldID:   field.field.ID,
+			Path:      node.Path,
+			Value:     node.Value,
+			Satisfied: true,
+			Predicate: predicate,
+		})
+		if predicate {
+			// Only the fact that the filter holds is disclosed
+			node.Value = true
 		}
+		selected = append(selected, node)
 	}
 
-	return true
+	if constraints.SubjectIsIssuer != nil && *constraints.SubjectIsIssuer == "required" {
+		if !subjectIsIssuer(credential) {
+			return nil, ""
+		}
+	}
+
+	if constraints.LimitDisclosure == nil {
+		return match, ""
+	}
+	disclosed, ok := limitDisclosure(credential, formatName, selected)
+	if ok {
+		match.Disclosed = disclosed
+		return match, ""
+	}
+	if *constraints.LimitDisclosure == LimitDisclosureRequired {
+		return nil, ""
+	}
+	return match, fmt.Sprintf("credential %d cannot limit disclosure for input descriptor '%s'; the full credential would be submitted", index, descriptor.ID)
 }
 
-// checkFormatConstraints validates credential format against descriptor format requirements
+// evaluate returns the first node, trying paths in order, whose value
+// passes the field's filter
+func (cf *compiledField) evaluate(document interface{}) (JSONPathNode, bool) {
+	for _, path := range cf.paths {
+		for _, node := range path.Query(document) {
+			if cf.evaluator == nil {
+				return node, true
+			}
+			if cf.evaluator.validate(cf.filter, node.Value, nil, "", nil, 0).valid() {
+				return node, true
+			}
+		}
+	}
+	return JSONPathNode{}, false
+}
+
+// checkFormatConstraints returns the registry designation of the
+// credential's format and whether the designation's algorithm or proof type
+// constraints accept it. A nil format accepts any credential.
 func (pdp *PresentationDefinitionProcessor) checkFormatConstraints(
 	credential *VerifiableCredential,
 	format *ClaimFormat,
-) bool {
-	
-	// Simple format checking - in a full implementation this would be more comprehensive
-	if credential.JWT != "" {
-		// JWT format credential
-		return format.JWT != nil || format.JSONLD != nil
+) (string, bool) {
+	name := pdp.getCredentialFormat(credential)
+	if format == nil {
+		return name, true
 	}
-	
-	// JSON-LD format credential
-	return format.JSONLD != nil || format.LDP != nil
-}
 
-// evaluateFieldConstraints evaluates all field constraints and returns matches
-func (pdp *PresentationDefinitionProcessor) evaluateFieldConstraints(
-	credential *VerifiableCredential,
-	descriptor *InputDescriptor,
-) []FieldMatch {
-	
-	var matches []FieldMatch
-
-	if descriptor.Constraints == nil {
-		return matches
-	}
-
-	for _, field := range descriptor.Constraints.Fields {
-		for _, path := range field.Path {
-			value, found := pdp.extractValueByPath(credential, path)
-			if found {
-				satisfied := pdp.evaluateFieldFilter(value, field.Filter)
-				matches = append(matches, FieldMatch{
-					FieldID:   field.ID,
-					Path:      path,
-					Value:     value,
-					Satisfied: satisfied,
-				})
+	switch name {
+	case FormatMsoMdoc:
+		if format.MsoMdoc != nil && (len(format.MsoMdoc.Alg) == 0 || containsString(format.MsoMdoc.Alg, mdocAlgorithm(credential))) {
+			return name, true
+		}
+	case FormatSDJWTVC:
+		alg := jwtHeaderAlg(credential.JWT)
+		for _, f := range []*SDJWTFormat{format.SDJWTVC, format.SDJWT} {
+			if f == nil {
+				continue
+			}
+			algs := f.SDJWTAlgValues
+			if len(algs) == 0 {
+				algs = f.Alg
+			}
+			if len(algs) == 0 || containsString(algs, alg) {
+				return name, true
+			}
+		}
+	case FormatJWTVC:
+		alg := jwtHeaderAlg(credential.JWT)
+		if format.JSONLD != nil && (len(format.JSONLD.Alg) == 0 || containsString(format.JSONLD.Alg, alg)) {
+			return name, true
+		}
+		if format.JWT != nil && (len(format.JWT.Alg) == 0 || containsString(format.JWT.Alg, alg)) {
+			return name, true
+		}
+	default:
+		proofTypes := credentialProofTypes(credential)
+		for _, f := range []*LDPFormat{format.LDP, format.LDPAny} {
+			if f == nil {
+				continue
+			}
+			if len(f.ProofType) == 0 {
+				return name, true
+			}
+			for _, proofType := range proofTypes {
+				if containsString(f.ProofType, proofType) {
+					return name, true
+				}
 			}
 		}
 	}
+	return name, false
+}
 
-	return matches
+// getCredentialFormat determines the format of a credential
+func (pdp *PresentationDefinitionProcessor) getCredentialFormat(credential *VerifiableCredential) string {
+	switch {
+	case credential.Mdoc != nil:
+		return FormatMsoMdoc
+	case strings.Contains(credential.JWT, "~"):
+		return FormatSDJWTVC
+	case credential.JWT != "":
+		return FormatJWTVC
+	}
+	return FormatLDPVC // Default to Linked Data Proof format
 }
 
-// evaluateFieldConstraint evaluates a single field constraint
-func (pdp *PresentationDefinitionProcessor) evaluateFieldConstraint(
-	credential *VerifiableCredential,
-	field *Field,
-) bool {
-	
-	for _, path := range field.Path {
-		value, found := pdp.extractValueByPath(credential, path)
-		if found {
-			if field.Filter != nil {
-				return pdp.evaluateFieldFilter(value, field.Filter)
-			}
-			return true // Field exists and no filter constraints
+func jwtHeaderAlg(token string) string {
+	header, _, _ := strings.Cut(token, ".")
+	data, err := base64.RawURLEncoding.DecodeString(header)
+	if err != nil {
+		return ""
+	}
+	var fields struct {
+		Alg string `json:"alg"`
+	}
+	if json.Unmarshal(data, &fields) != nil {
+		return ""
+	}
+	return fields.Alg
+}
+
+// credentialProofTypes lists the proof types, and Data Integrity
+// cryptosuites, of a credential's proofs
+func credentialProofTypes(credential *VerifiableCredential) []string {
+	var proofs []interface{}
+	switch proof := credential.Proof.(type) {
+	case []interface{}:
+		proofs = proof
+	case nil:
+	default:
+		proofs = []interface{}{proof}
+	}
+
+	var types []string
+	for _, proof := range proofs {
+		document, err := toJSONDocument(proof)
+		if err != nil {
+			continue
+		}
+		fields, _ := document.(map[string]interface{})
+		if proofType, ok := fields["type"].(string); ok {
+			types = append(types, proofType)
+		}
+		if suite, ok := fields["cryptosuite"].(string); ok {
+			types = append(types, suite)
 		}
 	}
+	return types
+}
 
-	return false // No paths matched
+func subjectIsIssuer(credential *VerifiableCredential) bool {
+	issuer := ""
+	switch v := credential.Issuer.(type) {
+	case string:
+		issuer = v
+	case map[string]interface{}:
+		issuer, _ = v["id"].(string)
+	case *Issuer:
+		issuer = v.ID
+	case Issuer:
+		issuer = v.ID
+	}
+
+	subject, _ := toJSONDocument(credential.CredentialSubject)
+	fields, _ := subject.(map[string]interface{})
+	id, _ := fields["id"].(string)
+	return issuer != "" && id == issuer
 }
 
-// extractValueByPath extracts a value from credential using JSONPath-like selector
-func (pdp *PresentationDefinitionProcessor) extractValueByPath(
-	credential *VerifiableCredential,
-	path string,
-) (interface{}, bool) {
-	
-	// Convert credential to map for path traversal
-	credMap, err := pdp.credentialToMap(credential)
+// limitDisclosure reduces a credential to the selected nodes. SD-JWT
+// credentials keep only the disclosures for selected claims and mdocs only
+// the selected issuer-signed items. A Linked Data proof covers the whole
+// credential, and deriving a selective disclosure proof from it is not
+// supported, so Linked Data credentials are never reduced. It reports false
+// for formats that cannot disclose selectively.
+func limitDisclosure(credential *VerifiableCredential, format string, selected []JSONPathNode) (interface{}, bool) {
+	switch format {
+	case FormatSDJWTVC:
+		return limitSDJWTDisclosures(credential.JWT, selected)
+	case FormatMsoMdoc:
+		return limitMdocElements(credential.Mdoc, selected)
+	}
+	return nil, false
+}
+
+// limitMdocElements keeps the issuer-signed items selected paths of the
+// form $[namespace][element] name, returning the reduced IssuerSigned as
+// base64url CBOR
+func limitMdocElements(mdoc []byte, selected []JSONPathNode) (interface{}, bool) {
+	issuerSigned, err := ParseIssuerSigned(mdoc)
 	if err != nil {
 		return nil, false
 	}
-
-	// Simple JSONPath-like implementation
-	// In a full implementation, this would use a proper JSONPath library
-	return pdp.traversePath(credMap, path)
+	elements := make(map[string][]string)
+	for _, node := range selected {
+		if len(node.Tokens) < 2 {
+			return nil, false
+		}
+		namespace, ok := node.Tokens[0].(string)
+		element, isName := node.Tokens[1].(string)
+		if !ok || !isName {
+			return nil, false
+		}
+		elements[namespace] = append(elements[namespace], element)
+	}
+	encoded, err := issuerSigned.Select(elements).Encode()
+	if err != nil {
+		return nil, false
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), true
 }
 
-// credentialToMap converts credential to map for path traversal
-func (pdp *PresentationDefinitionProcessor) credentialToMap(
-	credential *VerifiableCredential,
-) (map[string]interface{}, error) {
-	
-	credBytes, err := json.Marshal(credential)
+// limitSDJWTDisclosures keeps the disclosures on the way to, at or inside
+// a selected claim. Each disclosure is placed by the digest that references
+// it, so a claim name that recurs elsewhere in the payload, or an element of
+// an unselected array, is not disclosed. Paths into the credentialSubject
+// address the payload, where an SD-JWT VC keeps its subject's claims.
+func limitSDJWTDisclosures(sdjwt string, selected []JSONPathNode) (interface{}, bool) {
+	processor := &SDJWTProcessor{}
+	credential, err := processor.ParseSDJWT(sdjwt)
 	if err != nil {
-		return nil, err
+		return nil, false
+	}
+	claims, err := processor.ProcessDisclosures(credential)
+	if err != nil {
+		return nil, false
 	}
 
-	var credMap map[string]interface{}
-	if err := json.Unmarshal(credBytes, &credMap); err != nil {
-		return nil, err
+	paths := make([][]interface{}, 0, len(selected))
+	for _, node := range selected {
+		tokens := node.Tokens
+		if _, ok := claims.Claims["credentialSubject"]; !ok && len(tokens) > 0 && tokens[0] == "credentialSubject" {
+			tokens = tokens[1:]
+		}
+		paths = append(paths, tokens)
+	}
+
+	kept := []string{credential.JWT}
+	for _, disclosure := range credential.Disclosures {
+		location := claims.locations[disclosure.Encoded]
+		for _, path := range paths {
+			if tokensPrefix(location, path) || tokensPrefix(path, location) {
+				kept = append(kept, disclosure.Encoded)
+				break
+			}
+		}
 	}
+	return strings.Join(kept, "~") + "~", true
+}
 
-	return credMap, nil
+// tokensPrefix reports whether prefix begins tokens
+func tokensPrefix(prefix, tokens []interface{}) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
 }
 
-// traversePath implements simple JSONPath-like traversal
-func (pdp *PresentationDefinitionProcessor) traversePath(
-	data map[string]interface{},
-	path string,
-) (interface{}, bool) {
-	
-	// Handle JSONPath expressions like "$.credentialSubject.name"
-	if strings.HasPrefix(path, "$.") {
-		path = path[2:] // Remove "$."
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
+}
 
-	parts := strings.Split(path, ".")
-	var current interface{} = data
+// submissionSolver chooses, for each input descriptor, one matching
+// credential or none, so that the submission requirements hold and the
+// number of distinct credentials is smallest. Ties go to fewer descriptors
+// and then to the earliest credentials, which makes the choice
+// deterministic.
+type submissionSolver struct {
+	definition   *PresentationDefinition
+	candidates   [][]*CredentialMatch
+	requirements []SubmissionRequirement
+	groups       map[string][]int
+	eligible     []bool
 
-	for _, part := range parts {
-		switch curr := current.(type) {
-		case map[string]interface{}:
-			if val, exists := curr[part]; exists {
-				current = val
-			} else {
-				return nil, false
+	choice     []*CredentialMatch
+	best       []*CredentialMatch
+	bestCreds  []int
+	bestCount  int
+	steps      int
+	exhausted  bool
+	credsInUse map[int]int
+}
+
+func newSubmissionSolver(definition *PresentationDefinition, candidates [][]*CredentialMatch) *submissionSolver {
+	s := &submissionSolver{
+		definition: definition,
+		candidates: candidates,
+		groups:     make(map[string][]int),
+		eligible:   make([]bool, len(candidates)),
+		choice:     make([]*CredentialMatch, len(candidates)),
+		credsInUse: make(map[int]int),
+	}
+	for i, descriptor := range definition.InputDescriptors {
+		for _, group := range descriptor.Group {
+			s.groups[group] = append(s.groups[group], i)
+		}
+	}
+
+	s.requirements = definition.SubmissionRequirements
+	if len(s.requirements) == 0 {
+		// Without requirements every descriptor must be satisfied
+		for i := range s.eligible {
+			s.eligible[i] = true
+		}
+		return s
+	}
+
+	// Descriptors outside the referenced groups are not submitted
+	var mark func(requirements []SubmissionRequirement)
+	mark = func(requirements []SubmissionRequirement) {
+		for _, requirement := range requirements {
+			for _, i := range s.groups[requirement.From] {
+				s.eligible[i] = true
 			}
-		default:
-			return nil, false
+			mark(requirement.FromNested)
 		}
 	}
+	mark(s.requirements)
+	return s
+}
 
-	return current, true
+func (s *submissionSolver) solve() ([]*CredentialMatch, bool) {
+	s.search(0)
+	if s.best == nil {
+		return nil, false
+	}
+
+	var selected []*CredentialMatch
+	for _, match := range s.best {
+		if match != nil {
+			selected = append(selected, match)
+		}
+	}
+	return selected, true
 }
 
-// evaluateFieldFilter evaluates a field value against filter constraints
-func (pdp *PresentationDefinitionProcessor) evaluateFieldFilter(
-	value interface{},
-	filter *Filter,
-) bool {
-	
-	if filter == nil {
-		return true
+func (s *submissionSolver) search(i int) {
+	s.steps++
+	if s.steps > maxSubmissionSearch {
+		s.exhausted = true
+		return
+	}
+	if s.best != nil && len(s.credsInUse) > len(s.bestCreds) {
+		return
 	}
 
-	// Type constraint
-	if filter.Type != "" {
-		if !pdp.checkValueType(value, filter.Type) {
-			return false
+	if i == len(s.candidates) {
+		s.consider()
+		return
+	}
+
+	if s.eligible[i] {
+		for _, match := range s.candidates[i] {
+			s.choice[i] = match
+			s.credsInUse[match.CredentialIndex]++
+			s.search(i + 1)
+			if s.credsInUse[match.CredentialIndex]--; s.credsInUse[match.CredentialIndex] == 0 {
+				delete(s.credsInUse, match.CredentialIndex)
+			}
+			s.choice[i] = nil
+			if s.exhausted {
+				return
+			}
+		}
+	}
+
+	// Without requirements a descriptor cannot be left out
+	if len(s.requirements) > 0 || !s.eligible[i] {
+		s.search(i + 1)
+	}
+}
+
+func (s *submissionSolver) consider() {
+	fulfilled := make([]bool, len(s.choice))
+	count := 0
+	for i, match := range s.choice {
+		if match != nil {
+			fulfilled[i] = true
+			count++
+		}
+	}
+	for _, requirement := range s.requirements {
+		if !s.met(requirement, fulfilled) {
+			return
 		}
 	}
 
-	// Const constraint
-	if filter.Const != nil {
-		return pdp.valuesEqual(value, filter.Const)
+	creds := make([]int, 0, len(s.credsInUse))
+	for index := range s.credsInUse {
+		creds = append(creds, index)
 	}
+	sort.Ints(creds)
 
-	// Enum constraint
-	if len(filter.Enum) > 0 {
-		for _, enumVal := range filter.Enum {
-			if pdp.valuesEqual(value, enumVal) {
-				return true
+	if s.best != nil {
+		switch {
+		case len(creds) != len(s.bestCreds):
+			if len(creds) > len(s.bestCreds) {
+				return
+			}
+		case count != s.bestCount:
+			if count > s.bestCount {
+				return
 			}
+		case !lessInts(creds, s.bestCreds):
+			return
 		}
-		return false
 	}
+	s.best = append([]*CredentialMatch(nil), s.choice...)
+	s.bestCreds = creds
+	s.bestCount = count
+}
 
-	// Pattern constraint (for strings)
-	if filter.Pattern != "" {
-		if str, ok := value.(string); ok {
-			// Simple pattern matching - in full implementation would use regex
-			return strings.Contains(str, strings.Trim(filter.Pattern, "*"))
+func lessInts(a, b []int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
 		}
-		return false
 	}
+	return false
+}
 
+// met reports whether a requirement holds when the given descriptors are
+// fulfilled
+func (s *submissionSolver) met(requirement SubmissionRequirement, fulfilled []bool) bool {
+	satisfied, total := 0, 0
+	if requirement.From != "" {
+		for _, i := range s.groups[requirement.From] {
+			total++
+			if fulfilled[i] {
+				satisfied++
+			}
+		}
+	} else {
+		for _, nested := range requirement.FromNested {
+			total++
+			if s.met(nested, fulfilled) {
+				satisfied++
+			}
+		}
+	}
+
+	if requirement.Rule == SubmissionRuleAll {
+		return satisfied == total
+	}
+	if requirement.Count != nil && satisfied != *requirement.Count {
+		return false
+	}
+	if requirement.Min != nil && satisfied < *requirement.Min {
+		return false
+	}
+	if requirement.Max != nil && satisfied > *requirement.Max {
+		return false
+	}
 	return true
 }
 
-// checkValueType checks if value matches expected type
-func (pdp *PresentationDefinitionProcessor) checkValueType(value interface{}, expectedType string) bool {
-	switch expectedType {
-	case "string":
-		_, ok := value.(string)
-		return ok
-	case "number":
-		switch value.(type) {
-		case int, int64, float64:
-			return true
-		default:
-			return false
-		}
-	case "boolean":
-		_, ok := value.(bool)
-		return ok
-	case "array":
-		switch value.(type) {
-		case []interface{}, []string, []int, []float64:
-			return true
-		default:
-			return false
+// explain describes why no submission was found
+func (s *submissionSolver) explain() []string {
+	if s.exhausted {
+		return []string{"submission requirements are too complex to solve"}
+	}
+
+	// Check each requirement on its own with every candidate available
+	fulfilled := make([]bool, len(s.candidates))
+	for i, candidates := range s.candidates {
+		fulfilled[i] = len(candidates) > 0
+	}
+
+	var errors []string
+	for _, requirement := range s.requirements {
+		if !s.metAtBest(requirement, fulfilled) {
+			name := requirement.Name
+			if name == "" {
+				name = requirement.From
+			}
+			errors = append(errors, fmt.Sprintf("Submission requirement '%s' cannot be satisfied", name))
 		}
-	case "object":
-		_, ok := value.(map[string]interface{})
-		return ok
-	default:
-		return true // Unknown type, assume valid
 	}
+	if len(errors) == 0 {
+		errors = append(errors, "Submission requirements cannot be satisfied together")
+	}
+	return errors
 }
 
-// valuesEqual compares two values for equality
-func (pdp *PresentationDefinitionProcessor) valuesEqual(a, b interface{}) bool {
-	aJSON, aErr := json.Marshal(a)
-	bJSON, bErr := json.Marshal(b)
-	
-	if aErr != nil || bErr != nil {
-		return false
+// metAtBest reports whether a requirement can hold for some subset of the
+// fulfillable descriptors, ignoring the other requirements
+func (s *submissionSolver) metAtBest(requirement SubmissionRequirement, fulfillable []bool) bool {
+	available, total := 0, 0
+	if requirement.From != "" {
+		for _, i := range s.groups[requirement.From] {
+			total++
+			if fulfillable[i] {
+				available++
+			}
+		}
+	} else {
+		for _, nested := range requirement.FromNested {
+			total++
+			if s.metAtBest(nested, fulfillable) {
+				available++
+			}
+		}
+	}
+
+	if requirement.Rule == SubmissionRuleAll {
+		return available == total
+	}
+	needed := 0
+	if requirement.Count != nil {
+		needed = *requirement.Count
 	}
-	
-	return string(aJSON) == string(bJSON)
+	if requirement.Min != nil && *requirement.Min > needed {
+		needed = *requirement.Min
+	}
+	return available >= needed
 }
 
-// CreateSubmission creates a presentation submission for matched credentials
+// CreateSubmission creates a presentation submission for matched
+// credentials. Paths index the credentials as given to EvaluateCredentials;
+// use CreateSubmissionPackage to submit only the selected credentials.
 func (pdp *PresentationDefinitionProcessor) CreateSubmission(
 	definition *PresentationDefinition,
 	matches []*CredentialMatch,
 ) *PresentationSubmission {
-	
 	submission := &PresentationSubmission{
-		ID:            fmt.Sprintf("submission-%d", time.Now().Unix()),
+		ID:            newSubmissionID(),
 		DefinitionID:  definition.ID,
 		DescriptorMap: make([]DescriptorMap, 0),
 	}
 
 	// Create descriptor map entries for matches
 	for _, match := range matches {
-		descriptorMap := DescriptorMap{
+		format := match.Format
+		if format == "" {
+			format = pdp.getCredentialFormat(match.Credential)
+		}
+		submission.DescriptorMap = append(submission.DescriptorMap, DescriptorMap{
 			ID:     match.InputDescriptorID,
-			Format: pdp.getCredentialFormat(match.Credential),
+			Format: format,
 			Path:   fmt.Sprintf("$.verifiableCredential[%d]", match.CredentialIndex),
-		}
-		
-		submission.DescriptorMap = append(submission.DescriptorMap, descriptorMap)
+		})
 	}
 
 	return submission
 }
 
-// getCredentialFormat determines the format of a credential
-func (pdp *PresentationDefinitionProcessor) getCredentialFormat(credential *VerifiableCredential) string {
-	if credential.JWT != "" {
-		return "jwt_vc"
+// CreateSubmissionPackage builds the submission for an evaluation's
+// selected matches, together with the credentials to put in the
+// presentation. Each credential appears once, in order of first use, and
+// in its limited form when disclosure was limited.
+func (pdp *PresentationDefinitionProcessor) CreateSubmissionPackage(
+	definition *PresentationDefinition,
+	result *EvaluationResult,
+) (*SubmissionPackage, error) {
+	if result == nil || !result.Valid {
+		return nil, fmt.Errorf("credentials do not satisfy presentation definition")
+	}
+	if result.DefinitionID != definition.ID {
+		return nil, fmt.Errorf("evaluation is for definition '%s', not '%s'", result.DefinitionID, definition.ID)
 	}
-	return "ldp_vc" // Default to Linked Data Proof format
-}
\ No newline at end of file
+
+	pkg := &SubmissionPackage{
+		Submission: &PresentationSubmission{
+			ID:            newSubmissionID(),
+			DefinitionID:  definition.ID,
+			DescriptorMap: make([]DescriptorMap, 0, len(result.Selected)),
+		},
+		Credentials: make([]interface{}, 0),
+	}
+
+	// A credential submitted under several descriptors with different
+	// disclosure is submitted once per distinct form
+	type key struct {
+		index     int
+		disclosed string
+	}
+	positions := make(map[key]int)
+	for _, match := range result.Selected {
+		k := key{index: match.CredentialIndex}
+		if match.Disclosed != nil {
+			k.disclosed = compactJSON(match.Disclosed)
+		}
+
+		position, ok := positions[k]
+		if !ok {
+			position = len(pkg.Credentials)
+			positions[k] = position
+			pkg.Credentials = append(pkg.Credentials, submittedCredential(match))
+		}
+
+		pkg.Submission.DescriptorMap = append(pkg.Submission.DescriptorMap, DescriptorMap{
+			ID:     match.InputDescriptorID,
+			Format: match.Format,
+			Path:   fmt.Sprintf("$.verifiableCredential[%d]", position),
+		})
+	}
+
+	sort.SliceStable(pkg.Submission.DescriptorMap, func(i, j int) bool {
+		return descriptorIndex(definition, pkg.Submission.DescriptorMap[i].ID) < descriptorIndex(definition, pkg.Submission.DescriptorMap[j].ID)
+	})
+	return pkg, nil
+}
+
+func submittedCredential(match *CredentialMatch) interface{} {
+	switch {
+	case match.Disclosed != nil:
+		return match.Disclosed
+	case match.Credential.JWT != "":
+		return match.Credential.JWT
+	}
+	return match.Credential
+}
+
+func descriptorIndex(definition *PresentationDefinition, id string) int {
+	for i, descriptor := range definition.InputDescriptors {
+		if descriptor.ID == id {
+			return i
+		}
+	}
+	return len(definition.InputDescriptors)
+}
+
+// newSubmissionID returns a random UUID
+func newSubmissionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("submission-%d", time.Now().UnixNano())
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
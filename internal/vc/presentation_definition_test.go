@@ -0,0 +1,475 @@
+package vc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonPathStore = `{
+  "store": {
+    "book": [
+      {"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+      {"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+      {"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99},
+      {"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99}
+    ],
+    "bicycle": {"color": "red", "price": 399}
+  }
+}`
+
+func TestJSONPath(t *testing.T) {
+	document, err := decodeJSONDocument([]byte(jsonPathStore))
+	require.NoError(t, err)
+
+	values := func(expression string) []interface{} {
+		nodes, err := QueryJSONPath(expression, document)
+		require.NoError(t, err, expression)
+		out := make([]interface{}, 0, len(nodes))
+		for _, node := range nodes {
+			out = append(out, node.Value)
+		}
+		return out
+	}
+	strs := func(vs []interface{}) []string {
+		out := make([]string, len(vs))
+		for i, v := range vs {
+			out[i] = compactJSON(v)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`, `"J. R. R. Tolkien"`}, strs(values("$.store.book[*].author")))
+	assert.Len(t, values("$..author"), 4)
+	assert.Len(t, values("$.store.*"), 2)
+	assert.Len(t, values("$.store..price"), 5)
+	assert.Equal(t, []string{`"The Lord of the Rings"`}, strs(values("$..book[-1].title")))
+	assert.Equal(t, []string{`"Nigel Rees"`, `"Evelyn Waugh"`}, strs(values("$..book[:2].author")))
+	assert.Equal(t, []string{`"J. R. R. Tolkien"`, `"Evelyn Waugh"`}, strs(values("$..book[::-2].author")))
+	assert.Equal(t, []string{`"Nigel Rees"`, `"Herman Melville"`}, strs(values("$.store.book[0,2].author")))
+	assert.Equal(t, []string{`"red"`}, strs(values("$['store']['bicycle']['color']")))
+
+	// Filters, in both the RFC 9535 and the parenthesised form
+	assert.Len(t, values("$..book[?@.isbn]"), 2)
+	assert.Len(t, values("$..book[?(@.price < 10)]"), 2)
+	assert.Len(t, values("$..book[?(@.price < 10 && @.category == 'fiction')]"), 1)
+	assert.Len(t, values("$..book[?@.price > $.store.bicycle.price || !@.isbn]"), 2)
+	assert.Len(t, values("$..book[?match(@.author, 'J.*')]"), 1)
+	assert.Len(t, values("$..book[?search(@.title, 'of')]"), 3)
+	assert.Len(t, values("$..book[?length(@.title) > 15]"), 2)
+	assert.Len(t, values("$.store[?count(@.*) > 2]"), 1)
+
+	nodes, err := QueryJSONPath("$..book[?@.price == 8.99].title", document)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "$['store']['book'][2]['title']", nodes[0].Path)
+	assert.Equal(t, []interface{}{"store", "book", 2, "title"}, nodes[0].Tokens)
+
+	for _, invalid := range []string{"", "store", "$.", "$[", "$[?@.a ==]", "$.a)", "$[?foo(@)]"} {
+		_, err := CompileJSONPath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func peTestCredentials() []*VerifiableCredential {
+	return []*VerifiableCredential{
+		{
+			Context:      []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:           "urn:uuid:degree",
+			Type:         []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Issuer:       "did:example:university",
+			IssuanceDate: "2024-01-01T00:00:00Z",
+			CredentialSubject: map[string]interface{}{
+				"id":        "did:example:holder",
+				"birthDate": "1990-05-01",
+				"degree":    map[string]interface{}{"type": "BachelorDegree", "name": "Computer Science"},
+			},
+			Proof: map[string]interface{}{"type": "Ed25519Signature2020"},
+		},
+		{
+			Context:      []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:           "urn:uuid:license",
+			Type:         []string{"VerifiableCredential", "DriversLicense"},
+			Issuer:       "did:example:dmv",
+			IssuanceDate: "2024-01-01T00:00:00Z",
+			CredentialSubject: map[string]interface{}{
+				"id":        "did:example:holder",
+				"birthDate": "1990-05-01",
+				"address":   map[string]interface{}{"country": "IN", "city": "Pune"},
+			},
+			Proof: map[string]interface{}{"type": "DataIntegrityProof", "cryptosuite": "bbs-2023"},
+		},
+		{
+			Context:      []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:           "urn:uuid:utility",
+			Type:         []string{"VerifiableCredential", "UtilityBill"},
+			Issuer:       "did:example:power",
+			IssuanceDate: "2024-01-01T00:00:00Z",
+			CredentialSubject: map[string]interface{}{
+				"id":      "did:example:holder",
+				"address": map[string]interface{}{"country": "IN", "city": "Pune"},
+			},
+		},
+	}
+}
+
+func decodeDefinition(t *testing.T, definition string) *PresentationDefinition {
+	var pd PresentationDefinition
+	require.NoError(t, json.Unmarshal([]byte(definition), &pd))
+	return &pd
+}
+
+func TestPresentationDefinition_Filters(t *testing.T) {
+	processor := NewPresentationDefinitionProcessor()
+	definition := decodeDefinition(t, `{
+	  "id": "filters",
+	  "input_descriptors": [{
+	    "id": "degree",
+	    "constraints": {
+	      "fields": [
+	        {"path": ["$.type"], "filter": {"type": "array", "contains": {"const": "UniversityDegreeCredential"}}},
+	        {"path": ["$.credentialSubject.degree.type", "$.vc.credentialSubject.degree.type"], "filter": {"type": "string", "pattern": "Degree$"}},
+	        {"path": ["$.credentialSubject.birthDate"], "filter": {"type": "string", "format": "date", "formatMaximum": "2006-01-01"}},
+	        {"path": ["$.credentialSubject.nickname"], "optional": true}
+	      ]
+	    }
+	  }]
+	}`)
+
+	// The filter keeps keywords Filter has no field for
+	encoded, err := json.Marshal(definition.InputDescriptors[0].Constraints.Fields[0].Filter)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"contains"`)
+
+	result, err := processor.EvaluateCredentials(definition, peTestCredentials())
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Errors)
+	require.Len(t, result.Matches, 1)
+	assert.Equal(t, 0, result.Matches[0].CredentialIndex)
+	assert.Equal(t, FormatLDPVC, result.Matches[0].Format)
+	require.Len(t, result.Matches[0].MatchedFields, 3)
+	assert.Equal(t, "$['credentialSubject']['degree']['type']", result.Matches[0].MatchedFields[1].Path)
+
+	// Tighten the age check until nobody qualifies
+	definition.InputDescriptors[0].Constraints.Fields[2].Filter = &Filter{Type: "string", Format: "date", raw: map[string]interface{}{
+		"type": "string", "format": "date", "formatMaximum": "1980-01-01",
+	}}
+	result, err = processor.EvaluateCredentials(definition, peTestCredentials())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors, "No credentials match input descriptor 'degree'")
+
+	_, err = processor.EvaluateCredentials(decodeDefinition(t, `{
+	  "id": "bad", "input_descriptors": [{"id": "x", "constraints": {"fields": [{"path": ["credentialSubject"]}]}}]
+	}`), nil)
+	assert.Error(t, err)
+}
+
+func TestPresentationDefinition_SubmissionRequirements(t *testing.T) {
+	processor := NewPresentationDefinitionProcessor()
+	definition := decodeDefinition(t, `{
+	  "id": "kyc",
+	  "submission_requirements": [
+	    {"name": "Education", "rule": "all", "from": "A"},
+	    {"name": "Residence", "rule": "pick", "count": 1, "from": "B"},
+	    {"name": "Age", "rule": "pick", "min": 1, "from_nested": [
+	      {"rule": "all", "from": "C"},
+	      {"rule": "pick", "count": 1, "from": "D"}
+	    ]}
+	  ],
+	  "input_descriptors": [
+	    {"id": "degree", "group": ["A"], "constraints": {"fields": [{"path": ["$.credentialSubject.degree"]}]}},
+	    {"id": "utility_address", "group": ["B"], "constraints": {"fields": [{"path": ["$.type[?@ == 'UtilityBill']"]}, {"path": ["$.credentialSubject.address.country"]}]}},
+	    {"id": "license_address", "group": ["B"], "constraints": {"fields": [{"path": ["$.type[?@ == 'DriversLicense']"]}, {"path": ["$.credentialSubject.address.country"]}]}},
+	    {"id": "passport", "group": ["C"], "constraints": {"fields": [{"path": ["$.credentialSubject.passportNumber"]}]}},
+	    {"id": "birth_date", "group": ["D"], "constraints": {"fields": [{"path": ["$.credentialSubject.birthDate"]}]}},
+	    {"id": "unused", "constraints": {"fields": [{"path": ["$.id"]}]}}
+	  ]
+	}`)
+
+	result, err := processor.EvaluateCredentials(definition, peTestCredentials())
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Errors)
+
+	// The degree covers Education and Age; one address credential is needed.
+	// Both address credentials need one extra credential, so the earlier
+	// candidate, the license, wins the tie.
+	selected := make(map[string]int)
+	for _, match := range result.Selected {
+		selected[match.InputDescriptorID] = match.CredentialIndex
+	}
+	assert.Equal(t, map[string]int{"degree": 0, "license_address": 1, "birth_date": 0}, selected)
+
+	pkg, err := processor.CreateSubmissionPackage(definition, result)
+	require.NoError(t, err)
+	require.Len(t, pkg.Credentials, 2)
+	assert.Equal(t, "kyc", pkg.Submission.DefinitionID)
+	assert.Len(t, pkg.Submission.ID, 36)
+	assert.Equal(t, []DescriptorMap{
+		{ID: "degree", Format: FormatLDPVC, Path: "$.verifiableCredential[0]"},
+		{ID: "license_address", Format: FormatLDPVC, Path: "$.verifiableCredential[1]"},
+		{ID: "birth_date", Format: FormatLDPVC, Path: "$.verifiableCredential[0]"},
+	}, pkg.Submission.DescriptorMap)
+
+	// Every descriptor_map path resolves in the presentation
+	presentation, err := toJSONDocument(map[string]interface{}{"verifiableCredential": pkg.Credentials})
+	require.NoError(t, err)
+	for _, entry := range pkg.Submission.DescriptorMap {
+		nodes, err := QueryJSONPath(entry.Path, presentation)
+		require.NoError(t, err)
+		assert.Len(t, nodes, 1, entry.Path)
+	}
+
+	// A max that the only candidates exceed cannot be met
+	one := 0
+	definition.SubmissionRequirements[1] = SubmissionRequirement{Name: "Residence", Rule: SubmissionRulePick, Min: &one, Max: &one, From: "B"}
+	definition.SubmissionRequirements[0].From = "C"
+	result, err = processor.EvaluateCredentials(definition, peTestCredentials())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Errors, "Submission requirement 'Education' cannot be satisfied")
+
+	definition.SubmissionRequirements[0].From = "missing"
+	_, err = processor.EvaluateCredentials(definition, peTestCredentials())
+	assert.Error(t, err)
+}
+
+func TestPresentationDefinition_LimitDisclosure(t *testing.T) {
+	processor := NewPresentationDefinitionProcessor()
+	definition := decodeDefinition(t, `{
+	  "id": "age",
+	  "input_descriptors": [{
+	    "id": "adult",
+	    "format": {"ldp_vc": {"proof_type": ["bbs-2023"]}, "vc+sd-jwt": {"sd-jwt_alg_values": ["EdDSA"]}},
+	    "constraints": {
+	      "limit_disclosure": "required",
+	      "fields": [
+	        {"path": ["$.credentialSubject.birthDate"], "predicate": "required",
+	         "filter": {"type": "string", "format": "date", "formatMaximum": "2006-01-01"}},
+	        {"path": ["$.credentialSubject.address.city"]}
+	      ]
+	    }
+	  }]
+	}`)
+
+	sdProcessor, issuer := sdjwtTestSetup(t)
+	subject := map[string]interface{}{
+		"birthDate": "1990-05-01",
+		"address":   map[string]interface{}{"city": "Pune", "country": "IN"},
+		"employer":  map[string]interface{}{"name": "Acme", "address": map[string]interface{}{"city": "Mumbai"}},
+		"languages": []interface{}{"mr", "hi"},
+		"name":      "Asha",
+	}
+	token, err := sdProcessor.CreateSDJWT(&CredentialTemplate{
+		Type:              []string{"VerifiableCredential", "Identity"},
+		Issuer:            "did:example:gov",
+		CredentialSubject: subject,
+		SelectivelyDisclosable: []string{"birthDate", "address", "address.city", "address.country",
+			"employer.address", "employer.address.city", "languages[*]", "name"},
+	}, &IssuanceOptions{KeyID: issuer.DIDDocument.VerificationMethod[0].ID, Algorithm: "EdDSA", DecoyDigests: 1}, issuer.PrivateKey)
+	require.NoError(t, err)
+	sdjwt := &VerifiableCredential{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:              []string{"VerifiableCredential", "Identity"},
+		Issuer:            "did:example:gov",
+		IssuanceDate:      "2024-01-01T00:00:00Z",
+		CredentialSubject: subject,
+		JWT:               token,
+	}
+
+	credentials := append(peTestCredentials(), sdjwt)
+	result, err := processor.EvaluateCredentials(definition, credentials)
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Errors)
+
+	// The Ed25519 degree and the unsigned bill cannot limit disclosure, and
+	// no selective disclosure proof can be derived from the license's
+	require.Len(t, result.Matches, 1)
+	match := result.Matches[0]
+	assert.Equal(t, 3, match.CredentialIndex)
+	assert.Equal(t, FormatSDJWTVC, match.Format)
+	assert.Equal(t, "1990-05-01", match.MatchedFields[0].Value)
+	assert.True(t, match.MatchedFields[0].Predicate)
+
+	disclosed, ok := match.Disclosed.(string)
+	require.True(t, ok)
+	parsed, err := sdProcessor.ParseSDJWT(disclosed)
+	require.NoError(t, err)
+	claims, err := sdProcessor.ProcessDisclosures(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, "1990-05-01", claims.Claims["birthDate"])
+	assert.Equal(t, map[string]interface{}{"city": "Pune"}, claims.Claims["address"],
+		"only the selected member of the address is disclosed")
+	assert.Equal(t, map[string]interface{}{"name": "Acme"}, claims.Claims["employer"],
+		"a claim sharing the selected name elsewhere stays hidden")
+	assert.Empty(t, claims.Claims["languages"], "elements of an unselected array stay hidden")
+	assert.NotContains(t, claims.Claims, "name")
+
+	// With disclosure only preferred, a credential that cannot limit it
+	// still matches, with a warning
+	preferred := LimitDisclosurePreferred
+	definition.InputDescriptors[0].Format = nil
+	definition.InputDescriptors[0].Constraints.LimitDisclosure = &preferred
+	result, err = processor.EvaluateCredentials(definition, credentials[2:3])
+	require.NoError(t, err)
+	assert.False(t, result.Valid, "the utility bill has no birth date")
+
+	result, err = processor.EvaluateCredentials(definition, credentials[:1])
+	require.NoError(t, err)
+	assert.False(t, result.Valid, "the degree has no address")
+
+	result, err = processor.EvaluateCredentials(definition, credentials[1:2])
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Errors)
+	assert.Nil(t, result.Matches[0].Disclosed, "the license is submitted whole")
+	assert.Len(t, result.Warnings, 1)
+}
+
+// The presentation definitions of the DIF Presentation Exchange v2 test
+// vectors, test/v2/presentation-definition in the specification repository
+const (
+	peVectorMinimal = `{
+  "presentation_definition": {
+    "id": "32f54163-7166-48f1-93d8-ff217bdb0653",
+    "input_descriptors": [
+      {
+        "id": "wa_driver_license",
+        "name": "Washington State Business License",
+        "purpose": "We can only allow licensed Washington State business representatives into the WA Business Conference",
+        "constraints": {
+          "fields": [
+            {
+              "path": [
+                "$.credentialSubject.dateOfBirth",
+                "$.credentialSubject.dob",
+                "$.vc.credentialSubject.dateOfBirth",
+                "$.vc.credentialSubject.dob"
+              ]
+            }
+          ]
+        }
+      }
+    ]
+  }
+}`
+
+	peVectorSingleGroup = `{
+  "presentation_definition": {
+    "id": "32f54163-7166-48f1-93d8-ff217bdb0653",
+    "submission_requirements": [{
+      "name": "Citizenship Information",
+      "rule": "pick",
+      "count": 1,
+      "from": "A"
+    }],
+    "input_descriptors": [
+      {
+        "id": "citizenship_input_1",
+        "name": "EU Driver's License",
+        "group": ["A"],
+        "constraints": {
+          "fields": [
+            {
+              "path": ["$.credentialSchema.id", "$.vc.credentialSchema.id"],
+              "filter": {
+                "type": "string",
+                "const": "https://eu.com/claims/DriversLicense.json"
+              }
+            },
+            {
+              "path": ["$.issuer", "$.vc.issuer", "$.iss"],
+              "purpose": "We can only accept digital driver's licenses issued by national authorities of member states or trusted notarial auditors.",
+              "filter": {
+                "type": "string",
+                "pattern": "^did:example:gov1$|^did:example:gov2$"
+              }
+            },
+            {
+              "path": ["$.credentialSubject.dob", "$.vc.credentialSubject.dob", "$.dob"],
+              "filter": {
+                "type": "string",
+                "format": "date"
+              }
+            }
+          ]
+        }
+      },
+      {
+        "id": "citizenship_input_2",
+        "name": "US Passport",
+        "group": ["A"],
+        "constraints": {
+          "fields": [
+            {
+              "path": ["$.credentialSchema.id", "$.vc.credentialSchema.id"],
+              "filter": {
+                "type": "string",
+                "const": "hub://did:foo:123/Collections/schema.us.gov/passport.json"
+              }
+            },
+            {
+              "path": ["$.credentialSubject.birth_date", "$.vc.credentialSubject.birth_date", "$.birth_date"],
+              "filter": {
+                "type": "string",
+                "format": "date"
+              }
+            }
+          ]
+        }
+      }
+    ]
+  }
+}`
+)
+
+func decodeVector(t *testing.T, vector string) *PresentationDefinition {
+	var wrapper struct {
+		PresentationDefinition *PresentationDefinition `json:"presentation_definition"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(vector), &wrapper))
+	require.NotNil(t, wrapper.PresentationDefinition)
+	return wrapper.PresentationDefinition
+}
+
+func TestPresentationDefinition_SpecVectors(t *testing.T) {
+	processor := NewPresentationDefinitionProcessor()
+
+	t.Run("minimal example", func(t *testing.T) {
+		definition := decodeVector(t, peVectorMinimal)
+		require.NoError(t, processor.ValidateDefinition(definition))
+
+		license := &VerifiableCredential{
+			Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+			Type:              []string{"VerifiableCredential", "BusinessLicense"},
+			Issuer:            "did:example:wa",
+			IssuanceDate:      "2024-01-01T00:00:00Z",
+			CredentialSubject: map[string]interface{}{"id": "did:example:holder", "dob": "1990-05-01"},
+		}
+		result, err := processor.EvaluateCredentials(definition, append(peTestCredentials(), license))
+		require.NoError(t, err)
+		require.True(t, result.Valid, result.Errors)
+		require.Len(t, result.Selected, 1)
+		assert.Equal(t, 3, result.Selected[0].CredentialIndex)
+		assert.Equal(t, "$['credentialSubject']['dob']", result.Selected[0].MatchedFields[0].Path)
+
+		submission := processor.CreateSubmission(definition, result.Selected)
+		assert.Equal(t, definition.ID, submission.DefinitionID)
+		require.Len(t, submission.DescriptorMap, 1)
+		assert.Equal(t, "wa_driver_license", submission.DescriptorMap[0].ID)
+	})
+
+	t.Run("single group example", func(t *testing.T) {
+		definition := decodeVector(t, peVectorSingleGroup)
+		require.NoError(t, processor.ValidateDefinition(definition))
+		assert.Equal(t, []string{"A"}, definition.InputDescriptors[1].Group)
+
+		// No test credential carries either schema, so the pick cannot be
+		// satisfied
+		result, err := processor.EvaluateCredentials(definition, peTestCredentials())
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Contains(t, result.Errors, "Submission requirement 'Citizenship Information' cannot be satisfied")
+	})
+}
@@ -1,17 +1,47 @@
 package vc
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Credential schema types understood by SchemaValidator
+const (
+	// SchemaTypeJSONSchema references a JSON Schema document directly
+	SchemaTypeJSONSchema = "JsonSchema"
+	// SchemaTypeJSONSchemaCredential references a credential whose subject
+	// carries the JSON Schema in its jsonSchema property
+	SchemaTypeJSONSchemaCredential = "JsonSchemaCredential"
+	// SchemaTypeJSONSchemaValidator2018 is the VCDM 1.1 era schema type
+	SchemaTypeJSONSchemaValidator2018 = "JsonSchemaValidator2018"
+)
+
+// maxSchemaDocumentSize limits schema documents fetched over HTTPS
+const maxSchemaDocumentSize = 1 << 20
+
 // SchemaValidator provides credential schema validation capabilities
 type SchemaValidator struct {
-	client       *http.Client
+	client        *http.Client
+	registry      *SchemaRegistry
+	remoteOrigins []*url.URL // HTTPS locations remote schemas may be fetched from
+	assertFormats bool
+
+	mu           sync.Mutex
 	schemaCache  map[string]*SchemaDocument
 	cacheTimeout time.Duration
 }
@@ -27,6 +57,14 @@ type SchemaDocument struct {
 	Properties  map[string]interface{} `json:"properties,omitempty"`
 	Required    []string               `json:"required,omitempty"`
 	CachedAt    time.Time              `json:"-"`
+
+	// Schema is the JSON Schema the credential is evaluated against; for a
+	// JsonSchemaCredential it is the wrapped schema
+	Schema interface{} `json:"-"`
+	// Digest is the SRI digest of the document as retrieved
+	Digest string `json:"-"`
+
+	raw []byte
 }
 
 // SchemaValidationResult represents the result of schema validation
@@ -37,22 +75,102 @@ type SchemaValidationResult struct {
 	ValidatedAt time.Time              `json:"validatedAt"`
 }
 
-// SchemaValidationError represents a schema validation error
+// SchemaValidationError represents a schema validation error. Field is the
+// JSONPath of the failing value, Path the same location as a JSON pointer and
+// SchemaPath the JSON pointer of the failing keyword in the schema.
 type SchemaValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Code    string `json:"code"`
+	Field      string `json:"field"`
+	Message    string `json:"message"`
+	Code       string `json:"code"`
+	Path       string `json:"path"`
+	SchemaPath string `json:"schemaPath,omitempty"`
+	SchemaID   string `json:"schemaId,omitempty"`
 }
 
-// NewSchemaValidator creates a new schema validator
+// NewSchemaValidator creates an offline schema validator backed by the
+// builtin schema registry
 func NewSchemaValidator() *SchemaValidator {
-	return &SchemaValidator{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
+	return NewSchemaValidatorWithRegistry(NewSchemaRegistry())
+}
+
+// NewSchemaValidatorWithRegistry creates an offline schema validator that
+// only resolves schemas present in registry
+func NewSchemaValidatorWithRegistry(registry *SchemaRegistry) *SchemaValidator {
+	sv := &SchemaValidator{
+		registry:      registry,
+		assertFormats: true,
+		schemaCache:   make(map[string]*SchemaDocument),
+		cacheTimeout:  time.Hour, // Cache schemas for 1 hour
+	}
+	sv.client = &http.Client{
+		Timeout: 30 * time.Second,
+		// Redirects must stay within the allowlist too
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			if !sv.remoteAllowed(request.URL.String()) {
+				return fmt.Errorf("schema redirect to %s is not allowlisted", request.URL.Redacted())
+			}
+			return nil
 		},
-		schemaCache:  make(map[string]*SchemaDocument),
-		cacheTimeout: time.Hour, // Cache schemas for 1 hour
 	}
+	return sv
+}
+
+// Registry returns the registry schemas are resolved from first
+func (sv *SchemaValidator) Registry() *SchemaRegistry {
+	return sv.registry
+}
+
+// SetRemoteFetch allows schemas missing from the registry to be fetched from
+// the given HTTPS locations, such as "https://schemas.example.com/" or
+// "https://example.com/schemas/". A schema is fetched only if its ID is on an
+// allowed host and under an allowed path; with no locations, which is the
+// default, schemas are never fetched.
+func (sv *SchemaValidator) SetRemoteFetch(allowed []string) error {
+	origins := make([]*url.URL, 0, len(allowed))
+	for _, location := range allowed {
+		origin, err := url.Parse(location)
+		if err != nil || origin.Scheme != "https" || origin.Host == "" || origin.User != nil {
+			return fmt.Errorf("remote schema location %q is not an https URL", location)
+		}
+		if !strings.HasSuffix(origin.Path, "/") {
+			origin.Path += "/"
+		}
+		origins = append(origins, origin)
+	}
+
+	sv.mu.Lock()
+	sv.remoteOrigins = origins
+	sv.mu.Unlock()
+	return nil
+}
+
+// remoteAllowed reports whether a schema ID is under an allowlisted location
+func (sv *SchemaValidator) remoteAllowed(schemaID string) bool {
+	target, err := url.Parse(schemaID)
+	if err != nil || target.Scheme != "https" || target.User != nil {
+		return false
+	}
+
+	// Servers resolve dot segments, so the cleaned path is the one fetched
+	schemaPath := path.Clean("/" + target.Path)
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	for _, origin := range sv.remoteOrigins {
+		if strings.EqualFold(target.Host, origin.Host) && strings.HasPrefix(schemaPath, origin.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFormatAssertion controls whether the format keyword is asserted or, as
+// JSON Schema 2020-12 does by default, only treated as an annotation
+func (sv *SchemaValidator) SetFormatAssertion(enabled bool) {
+	sv.assertFormats = enabled
 }
 
 // ValidateCredentialSchema validates a credential against its schema(s)
@@ -68,9 +186,15 @@ func (sv *SchemaValidator) ValidateCredentialSchema(credential *VerifiableCreden
 		return result, nil
 	}
 
+	// JSON Schemas describe the credential as it is serialized
+	instance, err := toJSONDocument(credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode credential: %w", err)
+	}
+
 	// Validate against each schema
 	for _, schema := range credential.CredentialSchema {
-		schemaResult, err := sv.validateAgainstSchema(credential, &schema)
+		schemaResult, err := sv.validateAgainstSchema(instance, &schema)
 		if err != nil {
 			return nil, fmt.Errorf("failed to validate against schema %s: %w", schema.ID, err)
 		}
@@ -88,8 +212,18 @@ func (sv *SchemaValidator) ValidateCredentialSchema(credential *VerifiableCreden
 	return result, nil
 }
 
+// ValidateDocument validates any JSON-serializable value against the schema
+// registered or published under schemaID
+func (sv *SchemaValidator) ValidateDocument(schemaID string, document interface{}) (*SchemaValidationResult, error) {
+	instance, err := toJSONDocument(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	return sv.validateAgainstSchema(instance, &CredentialSchema{ID: schemaID, Type: SchemaTypeJSONSchema})
+}
+
 // validateAgainstSchema validates a credential against a specific schema
-func (sv *SchemaValidator) validateAgainstSchema(credential *VerifiableCredential, schema *CredentialSchema) (*SchemaValidationResult, error) {
+func (sv *SchemaValidator) validateAgainstSchema(instance interface{}, schema *CredentialSchema) (*SchemaValidationResult, error) {
 	result := &SchemaValidationResult{
 		Valid:       true,
 		SchemaID:    schema.ID,
@@ -97,69 +231,118 @@ func (sv *SchemaValidator) validateAgainstSchema(credential *VerifiableCredentia
 		Errors:      make([]SchemaValidationError, 0),
 	}
 
+	switch schema.Type {
+	case SchemaTypeJSONSchema, SchemaTypeJSONSchemaCredential, SchemaTypeJSONSchemaValidator2018, "":
+	default:
+		result.Valid = false
+		result.Errors = append(result.Errors, SchemaValidationError{
+			Field:    "$.credentialSchema",
+			Path:     "/credentialSchema",
+			Message:  fmt.Sprintf("unsupported credential schema type %q", schema.Type),
+			Code:     "unsupported_schema_type",
+			SchemaID: schema.ID,
+		})
+		return result, nil
+	}
+
 	// Fetch schema document
-	schemaDoc, err := sv.fetchSchema(schema.ID)
+	schemaDoc, err := sv.fetchSchema(schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch schema: %w", err)
 	}
 
-	// Validate credential type matches schema
-	if err := sv.validateCredentialType(credential, schemaDoc); err != nil {
-		result.Valid = false
-		result.Errors = append(result.Errors, SchemaValidationError{
-			Field:   "type",
-			Message: err.Error(),
-			Code:    "type_mismatch",
-		})
+	index := newSchemaIndex()
+	root, err := index.add(schema.ID, schemaDoc.Schema, draft202012)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
 	}
 
-	// Validate credential subject properties
-	if err := sv.validateCredentialSubject(credential, schemaDoc); err != nil {
-		result.Valid = false
-		result.Errors = append(result.Errors, SchemaValidationError{
-			Field:   "credentialSubject",
-			Message: err.Error(),
-			Code:    "subject_validation_failed",
-		})
-	}
+	evaluator := newSchemaEvaluator(index, sv.loadReferencedSchema)
+	evaluator.assertFormats = sv.assertFormats
 
-	// Validate required fields
-	if err := sv.validateRequiredFields(credential, schemaDoc); err != nil {
-		result.Valid = false
-		result.Errors = append(result.Errors, SchemaValidationError{
-			Field:   "credentialSubject",
-			Message: err.Error(),
-			Code:    "required_field_missing",
-		})
+	evaluation := evaluator.validate(root, instance, nil, "", nil, 0)
+	for _, e := range evaluation.errors {
+		e.SchemaID = schema.ID
+		result.Errors = append(result.Errors, e)
 	}
+	result.Valid = len(result.Errors) == 0
 
 	return result, nil
 }
 
-// fetchSchema fetches a schema document by ID
-func (sv *SchemaValidator) fetchSchema(schemaID string) (*SchemaDocument, error) {
-	// Check cache first
+// fetchSchema fetches a schema document by ID, checking the credential's
+// digestSRI when one is given
+func (sv *SchemaValidator) fetchSchema(schema *CredentialSchema) (*SchemaDocument, error) {
+	raw, err := sv.fetchRaw(schema.ID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.DigestSRI != "" {
+		if err := VerifyDigestSRI(raw, schema.DigestSRI); err != nil {
+			return nil, err
+		}
+	}
+
+	document, err := decodeJSONDocument(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema JSON: %w", err)
+	}
+
+	if schema.Type == SchemaTypeJSONSchemaCredential {
+		if document, err = unwrapJSONSchemaCredential(document); err != nil {
+			return nil, err
+		}
+	}
+
+	schemaDoc := &SchemaDocument{
+		ID:       schema.ID,
+		Type:     schema.Type,
+		Schema:   document,
+		Digest:   ComputeDigestSRI(raw),
+		CachedAt: time.Now(),
+	}
+	if m, ok := document.(map[string]interface{}); ok {
+		schemaDoc.Name, _ = m["title"].(string)
+		schemaDoc.Description, _ = m["description"].(string)
+		schemaDoc.Properties, _ = m["properties"].(map[string]interface{})
+		if required, ok := m["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					schemaDoc.Required = append(schemaDoc.Required, name)
+				}
+			}
+		}
+	}
+
+	return schemaDoc, nil
+}
+
+// fetchRaw returns the bytes of a schema document from the registry, the
+// cache or, if its location is allowlisted, over HTTPS
+func (sv *SchemaValidator) fetchRaw(schemaID string) ([]byte, error) {
+	if raw, ok := sv.registry.Get(schemaID); ok {
+		return raw, nil
+	}
+
+	sv.mu.Lock()
 	if cached, exists := sv.schemaCache[schemaID]; exists {
 		if time.Since(cached.CachedAt) < sv.cacheTimeout {
-			return cached, nil
+			sv.mu.Unlock()
+			return cached.raw, nil
 		}
 		// Cache expired, remove it
 		delete(sv.schemaCache, schemaID)
 	}
+	sv.mu.Unlock()
 
-	// Handle different schema types
-	switch {
-	case strings.HasPrefix(schemaID, "http://") || strings.HasPrefix(schemaID, "https://"):
-		return sv.fetchHTTPSchema(schemaID)
-	case strings.HasPrefix(schemaID, "did:"):
-		return sv.fetchDIDSchema(schemaID)
-	default:
-		return sv.getBuiltinSchema(schemaID)
+	if !sv.remoteAllowed(schemaID) {
+		return nil, fmt.Errorf("schema %s is not in the schema registry", schemaID)
 	}
+	return sv.fetchHTTPSchema(schemaID)
 }
 
-// fetchHTTPSchema fetches a schema from HTTP(S) URL
-func (sv *SchemaValidator) fetchHTTPSchema(url string) (*SchemaDocument, error) {
+// fetchHTTPSchema fetches a schema from an HTTPS URL
+func (sv *SchemaValidator) fetchHTTPSchema(url string) ([]byte, error) {
 	resp, err := sv.client.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch schema from %s: %w", url, err)
@@ -170,289 +353,261 @@ func (sv *SchemaValidator) fetchHTTPSchema(url string) (*SchemaDocument, error)
 		return nil, fmt.Errorf("schema fetch failed with status %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSchemaDocumentSize))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read schema response: %w", err)
 	}
 
-	var schema SchemaDocument
-	if err := json.Unmarshal(body, &schema); err != nil {
+	if _, err := decodeJSONDocument(body); err != nil {
 		return nil, fmt.Errorf("failed to parse schema JSON: %w", err)
 	}
 
-	schema.CachedAt = time.Now()
-	sv.schemaCache[url] = &schema
+	sv.mu.Lock()
+	sv.schemaCache[url] = &SchemaDocument{ID: url, CachedAt: time.Now(), raw: body}
+	sv.mu.Unlock()
 
-	return &schema, nil
+	return body, nil
 }
 
-// fetchDIDSchema fetches a schema from a DID URL (placeholder implementation)
-func (sv *SchemaValidator) fetchDIDSchema(didURL string) (*SchemaDocument, error) {
-	// TODO: Implement DID-based schema resolution
-	// This would require DID resolution and schema retrieval from DID documents
-	return nil, fmt.Errorf("DID-based schema resolution not yet implemented")
+// loadReferencedSchema resolves documents reached through $ref
+func (sv *SchemaValidator) loadReferencedSchema(uri string) (interface{}, error) {
+	raw, err := sv.fetchRaw(uri)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONDocument(raw)
 }
 
-// getBuiltinSchema returns builtin schemas for common credential types
-func (sv *SchemaValidator) getBuiltinSchema(schemaID string) (*SchemaDocument, error) {
-	builtinSchemas := map[string]*SchemaDocument{
-		"BasicCredentialSchema": {
-			ID:          "BasicCredentialSchema",
-			Type:        "JsonSchemaValidator2018",
-			Name:        "Basic Credential Schema",
-			Description: "Basic schema for general verifiable credentials",
-			Version:     "1.0",
-			Properties: map[string]interface{}{
-				"id": map[string]interface{}{
-					"type":        "string",
-					"description": "Unique identifier for the credential subject",
-				},
-			},
-			Required: []string{"id"},
-		},
-		"PersonCredentialSchema": {
-			ID:          "PersonCredentialSchema", 
-			Type:        "JsonSchemaValidator2018",
-			Name:        "Person Credential Schema",
-			Description: "Schema for person-related credentials",
-			Version:     "1.0",
-			Properties: map[string]interface{}{
-				"id": map[string]interface{}{
-					"type":        "string",
-					"description": "DID or unique identifier for the person",
-				},
-				"name": map[string]interface{}{
-					"type":        "string",
-					"description": "Full name of the person",
-				},
-				"firstName": map[string]interface{}{
-					"type":        "string", 
-					"description": "Given name of the person",
-				},
-				"lastName": map[string]interface{}{
-					"type":        "string",
-					"description": "Family name of the person",
-				},
-				"email": map[string]interface{}{
-					"type":        "string",
-					"format":      "email",
-					"description": "Email address of the person",
-				},
-				"birthDate": map[string]interface{}{
-					"type":        "string",
-					"format":      "date",
-					"description": "Birth date in YYYY-MM-DD format",
-				},
-			},
-			Required: []string{"id", "name"},
-		},
+// unwrapJSONSchemaCredential extracts the schema from a JsonSchemaCredential.
+// The wrapper's proof is not checked here; pin the credential in the registry
+// or reference it with a digestSRI to rely on its content.
+func unwrapJSONSchemaCredential(document interface{}) (interface{}, error) {
+	credential, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema credential must be a JSON object")
 	}
-
-	if schema, exists := builtinSchemas[schemaID]; exists {
-		schema.CachedAt = time.Now()
-		return schema, nil
+	if !jsonContainsString(credential["type"], SchemaTypeJSONSchemaCredential) {
+		return nil, fmt.Errorf("schema credential is not of type %s", SchemaTypeJSONSchemaCredential)
 	}
 
-	return nil, fmt.Errorf("unknown builtin schema: %s", schemaID)
-}
-
-// validateCredentialType validates that the credential type matches the schema
-func (sv *SchemaValidator) validateCredentialType(credential *VerifiableCredential, schema *SchemaDocument) error {
-	// Basic type validation - ensure VerifiableCredential is present
-	hasVCType := false
-	for _, t := range credential.Type {
-		if t == "VerifiableCredential" {
-			hasVCType = true
-			break
-		}
+	subject, ok := credential["credentialSubject"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema credential has no credentialSubject")
 	}
-
-	if !hasVCType {
-		return fmt.Errorf("credential must include VerifiableCredential type")
+	if !jsonContainsString(subject["type"], SchemaTypeJSONSchema) {
+		return nil, fmt.Errorf("schema credential subject is not of type %s", SchemaTypeJSONSchema)
 	}
 
-	// Additional type validation could be added based on schema requirements
-	return nil
-}
-
-// validateCredentialSubject validates the credential subject against schema properties
-func (sv *SchemaValidator) validateCredentialSubject(credential *VerifiableCredential, schema *SchemaDocument) error {
-	if credential.CredentialSubject == nil {
-		return fmt.Errorf("credential subject is required")
+	schema, ok := subject["jsonSchema"]
+	if !ok {
+		return nil, fmt.Errorf("schema credential subject has no jsonSchema")
 	}
+	return schema, nil
+}
 
-	// Convert credential subject to map for validation
-	var subjectMap map[string]interface{}
-	
-	switch subject := credential.CredentialSubject.(type) {
-	case map[string]interface{}:
-		subjectMap = subject
+func jsonContainsString(value interface{}, want string) bool {
+	switch v := value.(type) {
 	case string:
-		// Handle string subject (just a DID)
-		subjectMap = map[string]interface{}{"id": subject}
-	default:
-		// Try to marshal and unmarshal to convert to map
-		subjectBytes, err := json.Marshal(subject)
-		if err != nil {
-			return fmt.Errorf("failed to marshal credential subject for validation")
-		}
-		
-		if err := json.Unmarshal(subjectBytes, &subjectMap); err != nil {
-			return fmt.Errorf("failed to convert credential subject to map for validation")
-		}
-	}
-
-	// Validate each property against schema properties
-	if schema.Properties != nil {
-		for propName, propSchema := range schema.Properties {
-			if value, exists := subjectMap[propName]; exists {
-				if err := sv.validateProperty(propName, value, propSchema); err != nil {
-					return fmt.Errorf("property %s validation failed: %w", propName, err)
-				}
+		return v == want
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == want {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	return nil
+// SetCacheTimeout sets the schema cache timeout
+func (sv *SchemaValidator) SetCacheTimeout(timeout time.Duration) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	sv.cacheTimeout = timeout
 }
 
-// validateRequiredFields validates that all required fields are present
-func (sv *SchemaValidator) validateRequiredFields(credential *VerifiableCredential, schema *SchemaDocument) error {
-	if len(schema.Required) == 0 {
-		return nil
-	}
+// ClearCache clears the schema cache
+func (sv *SchemaValidator) ClearCache() {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	sv.schemaCache = make(map[string]*SchemaDocument)
+}
 
-	// Convert credential subject to map
-	var subjectMap map[string]interface{}
-	switch subject := credential.CredentialSubject.(type) {
-	case map[string]interface{}:
-		subjectMap = subject
-	case string:
-		subjectMap = map[string]interface{}{"id": subject}
-	default:
-		subjectBytes, err := json.Marshal(subject)
-		if err != nil {
-			return fmt.Errorf("failed to marshal credential subject")
-		}
-		if err := json.Unmarshal(subjectBytes, &subjectMap); err != nil {
-			return fmt.Errorf("failed to convert credential subject to map")
-		}
+// SchemaRegistry is an offline set of schema documents (JSON Schemas and
+// JsonSchemaCredentials) keyed by URI. Schemas in it are used without any
+// network access; pinned entries were checked against a digest when added.
+type SchemaRegistry struct {
+	mu        sync.RWMutex
+	documents map[string][]byte
+}
+
+// SchemaManifestEntry pins one schema file in a registry manifest
+type SchemaManifestEntry struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	DigestSRI string `json:"digestSRI"`
+}
+
+// NewSchemaRegistry creates a registry holding the builtin schemas
+func NewSchemaRegistry() *SchemaRegistry {
+	r := &SchemaRegistry{documents: make(map[string][]byte)}
+	for id, document := range builtinSchemas {
+		r.documents[id] = []byte(document)
 	}
+	return r
+}
 
-	// Check each required field
-	for _, required := range schema.Required {
-		if _, exists := subjectMap[required]; !exists {
-			return fmt.Errorf("required field '%s' is missing", required)
-		}
+// Register adds a schema document under uri
+func (r *SchemaRegistry) Register(uri string, document []byte) error {
+	if uri == "" {
+		return fmt.Errorf("schema URI is required")
+	}
+	if _, err := decodeJSONDocument(document); err != nil {
+		return fmt.Errorf("schema %s is not valid JSON: %w", uri, err)
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.documents[uri] = append([]byte(nil), document...)
 	return nil
 }
 
-// validateProperty validates a single property against its schema
-func (sv *SchemaValidator) validateProperty(name string, value interface{}, propSchema interface{}) error {
-	schemaMap, ok := propSchema.(map[string]interface{})
-	if !ok {
-		return nil // Skip validation if schema is not in expected format
+// RegisterPinned adds a schema document after checking it against an SRI
+// digest such as "sha384-..."
+func (r *SchemaRegistry) RegisterPinned(uri string, document []byte, digestSRI string) error {
+	if err := VerifyDigestSRI(document, digestSRI); err != nil {
+		return fmt.Errorf("schema %s: %w", uri, err)
 	}
+	return r.Register(uri, document)
+}
 
-	// Type validation
-	if expectedType, exists := schemaMap["type"]; exists {
-		if err := sv.validatePropertyType(name, value, expectedType); err != nil {
-			return err
-		}
+// LoadManifest registers the schemas pinned in a JSON manifest, a list of
+// SchemaManifestEntry whose files are relative to the manifest
+func (r *SchemaRegistry) LoadManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read schema manifest: %w", err)
+	}
+
+	var entries []SchemaManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse schema manifest: %w", err)
 	}
 
-	// Format validation
-	if format, exists := schemaMap["format"]; exists {
-		if err := sv.validatePropertyFormat(name, value, format); err != nil {
+	dir := filepath.Dir(path)
+	for _, entry := range entries {
+		if entry.DigestSRI == "" {
+			return fmt.Errorf("schema %s is not pinned to a digest", entry.ID)
+		}
+		document, err := os.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			return fmt.Errorf("failed to read schema %s: %w", entry.ID, err)
+		}
+		if err := r.RegisterPinned(entry.ID, document, entry.DigestSRI); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
-// validatePropertyType validates a property's type
-func (sv *SchemaValidator) validatePropertyType(name string, value interface{}, expectedType interface{}) error {
-	typeStr, ok := expectedType.(string)
-	if !ok {
-		return nil
-	}
+// Get returns the document registered under uri
+func (r *SchemaRegistry) Get(uri string) ([]byte, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	document, ok := r.documents[uri]
+	return document, ok
+}
 
-	switch typeStr {
-	case "string":
-		if _, ok := value.(string); !ok {
-			return fmt.Errorf("property %s must be a string", name)
-		}
-	case "number":
-		switch value.(type) {
-		case int, int64, float64:
-			// Valid number types
-		default:
-			return fmt.Errorf("property %s must be a number", name)
-		}
-	case "boolean":
-		if _, ok := value.(bool); !ok {
-			return fmt.Errorf("property %s must be a boolean", name)
-		}
-	case "array":
-		// Check if it's a slice
-		switch value.(type) {
-		case []interface{}, []string, []int, []float64:
-			// Valid array types
-		default:
-			return fmt.Errorf("property %s must be an array", name)
-		}
-	case "object":
-		if _, ok := value.(map[string]interface{}); !ok {
-			return fmt.Errorf("property %s must be an object", name)
-		}
+// URIs lists the registered schema URIs
+func (r *SchemaRegistry) URIs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uris := make([]string, 0, len(r.documents))
+	for uri := range r.documents {
+		uris = append(uris, uri)
 	}
+	sort.Strings(uris)
+	return uris
+}
 
-	return nil
+// ComputeDigestSRI returns the sha384 Subresource Integrity digest of a
+// document, the form used by credentialSchema.digestSRI
+func ComputeDigestSRI(document []byte) string {
+	sum := sha512.Sum384(document)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
 }
 
-// validatePropertyFormat validates a property's format
-func (sv *SchemaValidator) validatePropertyFormat(name string, value interface{}, format interface{}) error {
-	formatStr, ok := format.(string)
+// VerifyDigestSRI checks a document against a sha256, sha384 or sha512 SRI
+// digest
+func VerifyDigestSRI(document []byte, digestSRI string) error {
+	algorithm, encoded, ok := strings.Cut(digestSRI, "-")
 	if !ok {
-		return nil
+		return fmt.Errorf("malformed digestSRI %q", digestSRI)
 	}
 
-	valueStr, ok := value.(string)
-	if !ok {
-		return nil // Format validation only applies to strings
+	var h hash.Hash
+	switch algorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported digestSRI algorithm %q", algorithm)
 	}
 
-	switch formatStr {
-	case "email":
-		if !strings.Contains(valueStr, "@") {
-			return fmt.Errorf("property %s must be a valid email address", name)
-		}
-	case "date":
-		if _, err := time.Parse("2006-01-02", valueStr); err != nil {
-			return fmt.Errorf("property %s must be a valid date in YYYY-MM-DD format", name)
-		}
-	case "date-time":
-		if _, err := time.Parse(time.RFC3339, valueStr); err != nil {
-			return fmt.Errorf("property %s must be a valid date-time in RFC3339 format", name)
-		}
-	case "uri":
-		if !strings.HasPrefix(valueStr, "http://") && !strings.HasPrefix(valueStr, "https://") && !strings.HasPrefix(valueStr, "did:") {
-			return fmt.Errorf("property %s must be a valid URI", name)
-		}
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("malformed digestSRI %q: %w", digestSRI, err)
+	}
+	h.Write(document)
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return fmt.Errorf("schema digest does not match %s", digestSRI)
 	}
-
 	return nil
 }
 
-// SetCacheTimeout sets the schema cache timeout
-func (sv *SchemaValidator) SetCacheTimeout(timeout time.Duration) {
-	sv.cacheTimeout = timeout
+// builtinSchemas are always present in a SchemaRegistry
+var builtinSchemas = map[string]string{
+	"BasicCredentialSchema": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Basic Credential Schema",
+  "description": "Basic schema for general verifiable credentials",
+  "type": "object",
+  "required": ["type", "credentialSubject"],
+  "properties": {
+    "type": {"type": "array", "contains": {"const": "VerifiableCredential"}},
+    "credentialSubject": {
+      "type": "object",
+      "required": ["id"],
+      "properties": {
+        "id": {"type": "string", "description": "Unique identifier for the credential subject"}
+      }
+    }
+  }
+}`,
+	"PersonCredentialSchema": `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Person Credential Schema",
+  "description": "Schema for person-related credentials",
+  "type": "object",
+  "required": ["type", "credentialSubject"],
+  "properties": {
+    "type": {"type": "array", "contains": {"const": "VerifiableCredential"}},
+    "credentialSubject": {
+      "type": "object",
+      "required": ["id", "name"],
+      "properties": {
+        "id": {"type": "string", "description": "DID or unique identifier for the person"},
+        "name": {"type": "string", "description": "Full name of the person"},
+        "firstName": {"type": "string", "description": "Given name of the person"},
+        "lastName": {"type": "string", "description": "Family name of the person"},
+        "email": {"type": "string", "format": "email", "description": "Email address of the person"},
+        "birthDate": {"type": "string", "format": "date", "description": "Birth date in YYYY-MM-DD format"}
+      }
+    }
+  }
+}`,
 }
-
-// ClearCache clears the schema cache
-func (sv *SchemaValidator) ClearCache() {
-	sv.schemaCache = make(map[string]*SchemaDocument)
-}
\ No newline at end of file
@@ -0,0 +1,361 @@
+package vc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const degreeSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://schemas.example.com/degree.json",
+  "type": "object",
+  "required": ["credentialSubject"],
+  "properties": {
+    "credentialSubject": {
+      "type": "object",
+      "required": ["id", "degree"],
+      "properties": {
+        "id": {"type": "string", "format": "uri"},
+        "email": {"type": "string", "format": "email"},
+        "graduated": {"type": "integer", "minimum": 1900, "maximum": 2100},
+        "degree": {"$ref": "common.json#/$defs/degree"},
+        "gpa": {"type": "number", "multipleOf": 0.01, "exclusiveMaximum": 4.5}
+      }
+    }
+  }
+}`
+
+const commonSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://schemas.example.com/common.json",
+  "$defs": {
+    "degree": {
+      "type": "object",
+      "required": ["type", "name"],
+      "properties": {
+        "type": {"enum": ["BachelorDegree", "MasterDegree"]},
+        "name": {"$ref": "#name"}
+      },
+      "additionalProperties": false
+    },
+    "name": {"$anchor": "name", "type": "string", "minLength": 3, "pattern": "^[A-Z]"}
+  }
+}`
+
+func TestSchemaValidator_JSONSchema(t *testing.T) {
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.Register("https://schemas.example.com/degree.json", []byte(degreeSchema)))
+	require.NoError(t, registry.Register("https://schemas.example.com/common.json", []byte(commonSchema)))
+	validator := NewSchemaValidatorWithRegistry(registry)
+
+	credential := schemaTestCredential(map[string]interface{}{
+		"id":        "did:example:alice",
+		"email":     "alice@example.com",
+		"graduated": 2020,
+		"gpa":       3.85,
+		"degree":    map[string]interface{}{"type": "BachelorDegree", "name": "Bachelor of Science"},
+	}, CredentialSchema{ID: "https://schemas.example.com/degree.json", Type: SchemaTypeJSONSchema})
+
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, "%+v", result.Errors)
+
+	credential.CredentialSubject = map[string]interface{}{
+		"id":        "not a uri",
+		"email":     "alice",
+		"graduated": 2020.5,
+		"gpa":       3.855,
+		"degree":    map[string]interface{}{"type": "PhD", "name": "bs", "honors": true},
+	}
+	result, err = validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+
+	errorsByPath := make(map[string]SchemaValidationError)
+	for _, e := range result.Errors {
+		errorsByPath[e.Path+" "+e.Code] = e
+		assert.Equal(t, "https://schemas.example.com/degree.json", e.SchemaID)
+	}
+
+	for _, key := range []string{
+		"/credentialSubject/id format",
+		"/credentialSubject/email format",
+		"/credentialSubject/graduated type",
+		"/credentialSubject/gpa multipleOf",
+		"/credentialSubject/degree/type enum",
+		"/credentialSubject/degree/name minLength",
+		"/credentialSubject/degree/name pattern",
+		"/credentialSubject/degree/honors false",
+	} {
+		assert.Contains(t, errorsByPath, key)
+	}
+	assert.Len(t, result.Errors, 8)
+
+	nameError := errorsByPath["/credentialSubject/degree/name minLength"]
+	assert.Equal(t, "$.credentialSubject.degree.name", nameError.Field)
+	assert.Equal(t, "/properties/credentialSubject/properties/degree/$ref/properties/name/$ref/minLength", nameError.SchemaPath)
+}
+
+func TestSchemaValidator_Draft7(t *testing.T) {
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.Register("https://schemas.example.com/draft7.json", []byte(`{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "definitions": {
+    "subject": {
+      "type": "object",
+      "properties": {
+        "scores": {"type": "array", "items": [{"type": "string"}], "additionalItems": {"type": "number"}}
+      },
+      "dependencies": {"creditCard": ["billingAddress"]}
+    }
+  },
+  "properties": {
+    "credentialSubject": {"$ref": "#/definitions/subject", "required": ["ignored"]}
+  }
+}`)))
+	validator := NewSchemaValidatorWithRegistry(registry)
+
+	credential := schemaTestCredential(map[string]interface{}{
+		"scores":     []interface{}{"math", 1, 2.5},
+		"creditCard": "4111",
+	}, CredentialSchema{ID: "https://schemas.example.com/draft7.json", Type: SchemaTypeJSONSchema})
+
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1, "siblings of $ref are ignored in draft-07")
+	assert.Equal(t, "dependencies", result.Errors[0].Code)
+
+	credential.CredentialSubject = map[string]interface{}{"scores": []interface{}{"math", "x"}}
+	result, err = validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "/credentialSubject/scores/1", result.Errors[0].Path)
+	assert.Equal(t, "$.credentialSubject.scores[1]", result.Errors[0].Field)
+}
+
+func TestSchemaValidator_Applicators(t *testing.T) {
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.Register("urn:example:applicators", []byte(`{
+  "type": "object",
+  "properties": {
+    "credentialSubject": {
+      "allOf": [{"properties": {"name": {"type": "string"}}}],
+      "oneOf": [{"required": ["email"]}, {"required": ["phone"]}],
+      "if": {"properties": {"country": {"const": "IN"}}, "required": ["country"]},
+      "then": {"required": ["pan"]},
+      "properties": {"country": true, "pan": true, "email": true, "phone": true, "id": true},
+      "unevaluatedProperties": false
+    },
+    "type": {"type": "array", "prefixItems": [{"const": "VerifiableCredential"}], "unevaluatedItems": {"type": "string"}}
+  }
+}`)))
+	validator := NewSchemaValidatorWithRegistry(registry)
+
+	credential := schemaTestCredential(map[string]interface{}{
+		"name":    "Alice",
+		"email":   "alice@example.com",
+		"country": "DE",
+	}, CredentialSchema{ID: "urn:example:applicators", Type: SchemaTypeJSONSchema})
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, "%+v", result.Errors)
+
+	credential.CredentialSubject = map[string]interface{}{
+		"name":     "Alice",
+		"email":    "alice@example.com",
+		"phone":    "+91",
+		"country":  "IN",
+		"nickname": "al",
+	}
+	result, err = validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+
+	codes := make(map[string]string)
+	for _, e := range result.Errors {
+		codes[e.Code] = e.Path
+	}
+	assert.Equal(t, "/credentialSubject", codes["oneOf"])
+	assert.Equal(t, "/credentialSubject", codes["required"], "then applies when if matches")
+	assert.Equal(t, "/credentialSubject/nickname", codes["false"], "unevaluatedProperties sees through allOf")
+	assert.Len(t, result.Errors, 3)
+}
+
+func TestSchemaValidator_DynamicRef(t *testing.T) {
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.Register("https://schemas.example.com/tree", []byte(`{
+  "$id": "https://schemas.example.com/tree",
+  "$dynamicAnchor": "node",
+  "type": "object",
+  "properties": {
+    "data": true,
+    "children": {"type": "array", "items": {"$dynamicRef": "#node"}}
+  }
+}`)))
+	require.NoError(t, registry.Register("https://schemas.example.com/strict-tree", []byte(`{
+  "$id": "https://schemas.example.com/strict-tree",
+  "$dynamicAnchor": "node",
+  "$ref": "tree",
+  "unevaluatedProperties": false
+}`)))
+	validator := NewSchemaValidatorWithRegistry(registry)
+
+	tree := map[string]interface{}{
+		"children": []interface{}{map[string]interface{}{"daat": 1}},
+	}
+	result, err := validator.ValidateDocument("https://schemas.example.com/tree", tree)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+
+	result, err = validator.ValidateDocument("https://schemas.example.com/strict-tree", tree)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1, "the nested node resolves to the strict tree")
+	assert.Equal(t, "/children/0/daat", result.Errors[0].Path)
+}
+
+func TestSchemaValidator_JSONSchemaCredential(t *testing.T) {
+	schemaCredential := []byte(`{
+  "@context": ["https://www.w3.org/ns/credentials/v2"],
+  "id": "https://schemas.example.com/membership-credential",
+  "type": ["VerifiableCredential", "JsonSchemaCredential"],
+  "issuer": "did:example:issuer",
+  "credentialSubject": {
+    "id": "https://schemas.example.com/membership",
+    "type": "JsonSchema",
+    "jsonSchema": {
+      "$schema": "https://json-schema.org/draft/2020-12/schema",
+      "properties": {"credentialSubject": {"required": ["memberSince"]}}
+    }
+  }
+}`)
+	digest := ComputeDigestSRI(schemaCredential)
+
+	registry := NewSchemaRegistry()
+	require.Error(t, registry.RegisterPinned("https://schemas.example.com/membership-credential", schemaCredential, ComputeDigestSRI([]byte("{}"))))
+	require.NoError(t, registry.RegisterPinned("https://schemas.example.com/membership-credential", schemaCredential, digest))
+	validator := NewSchemaValidatorWithRegistry(registry)
+
+	credential := schemaTestCredential(map[string]interface{}{"id": "did:example:bob"}, CredentialSchema{
+		ID:        "https://schemas.example.com/membership-credential",
+		Type:      SchemaTypeJSONSchemaCredential,
+		DigestSRI: digest,
+	})
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "required", result.Errors[0].Code)
+
+	credential.CredentialSchema[0].DigestSRI = ComputeDigestSRI([]byte("other"))
+	_, err = validator.ValidateCredentialSchema(credential)
+	require.Error(t, err, "a digestSRI mismatch is an error")
+}
+
+func TestSchemaValidator_OfflineRegistry(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "common.json"), []byte(commonSchema), 0600))
+	manifest := `[{"id": "https://schemas.example.com/common.json", "file": "common.json", "digestSRI": "` + ComputeDigestSRI([]byte(commonSchema)) + `"}]`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600))
+
+	registry := NewSchemaRegistry()
+	require.NoError(t, registry.LoadManifest(filepath.Join(dir, "manifest.json")))
+	assert.Contains(t, registry.URIs(), "https://schemas.example.com/common.json")
+
+	// The degree schema references common.json, which is pinned, but is not
+	// pinned itself
+	validator := NewSchemaValidatorWithRegistry(registry)
+	credential := schemaTestCredential(map[string]interface{}{"id": "did:example:alice"},
+		CredentialSchema{ID: "https://schemas.example.com/degree.json", Type: SchemaTypeJSONSchema})
+	_, err := validator.ValidateCredentialSchema(credential)
+	require.Error(t, err, "offline validators never fetch unknown schemas")
+
+	credential.CredentialSchema[0] = CredentialSchema{ID: "urn:example:unsupported", Type: "ZkpExampleSchema2018"}
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "unsupported_schema_type", result.Errors[0].Code)
+}
+
+func TestSchemaValidator_RemoteAllowlist(t *testing.T) {
+	var fetches int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/schemas/name.json", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write([]byte(`{"type": "object", "properties": {"credentialSubject": {"required": ["name"]}}}`))
+	})
+	mux.HandleFunc("/other/name.json", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write([]byte(`{}`))
+	})
+	mux.Handle("/schemas/moved.json", http.RedirectHandler("/other/name.json", http.StatusFound))
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	validate := func(validator *SchemaValidator, schemaID string) (*SchemaValidationResult, error) {
+		credential := schemaTestCredential(map[string]interface{}{"id": "did:example:alice"},
+			CredentialSchema{ID: schemaID, Type: SchemaTypeJSONSchema})
+		return validator.ValidateCredentialSchema(credential)
+	}
+
+	// Remote schemas are never fetched by default
+	validator := NewSchemaValidator()
+	validator.client.Transport = server.Client().Transport
+	_, err := validate(validator, server.URL+"/schemas/name.json")
+	require.Error(t, err)
+	assert.Zero(t, atomic.LoadInt32(&fetches))
+
+	assert.Error(t, validator.SetRemoteFetch([]string{"http://schemas.example.com/"}), "only https locations can be allowed")
+	require.NoError(t, validator.SetRemoteFetch([]string{server.URL + "/schemas"}))
+
+	result, err := validate(validator, server.URL+"/schemas/name.json")
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1, "the fetched schema applies")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	for _, schemaID := range []string{
+		server.URL + "/other/name.json",
+		server.URL + "/schemas/../other/name.json",
+		server.URL + "/schemas/moved.json",
+		"https://schemas.example.com/schemas/name.json",
+	} {
+		_, err := validate(validator, schemaID)
+		assert.Error(t, err, schemaID)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "nothing outside the allowlist is fetched")
+}
+
+func TestSchemaValidator_BuiltinSchemas(t *testing.T) {
+	validator := NewSchemaValidator()
+
+	credential := schemaTestCredential(map[string]interface{}{
+		"id":        "did:example:alice",
+		"name":      "Alice",
+		"birthDate": "1990-02-30",
+	}, CredentialSchema{ID: "PersonCredentialSchema", Type: SchemaTypeJSONSchemaValidator2018})
+
+	result, err := validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "$.credentialSubject.birthDate", result.Errors[0].Field)
+
+	validator.SetFormatAssertion(false)
+	result, err = validator.ValidateCredentialSchema(credential)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, "formats are annotations when assertion is off")
+}
+
+func schemaTestCredential(subject map[string]interface{}, schema CredentialSchema) *VerifiableCredential {
+	return &VerifiableCredential{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            "did:example:issuer",
+		IssuanceDate:      "2024-01-01T00:00:00Z",
+		CredentialSubject: subject,
+		CredentialSchema:  []CredentialSchema{schema},
+	}
+}
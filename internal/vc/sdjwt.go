@@ -1,20 +1,51 @@
 package vc
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/sha256"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ParichayaHQ/credence/internal/did"
 )
 
+// Media types of SD-JWT VCs and their key binding JWTs
+const (
+	SDJWTVCType       = "vc+sd-jwt"
+	SDJWTDCType       = "dc+sd-jwt"
+	KeyBindingJWTType = "kb+jwt"
+)
+
+// DefaultSDAlgorithm is the disclosure digest algorithm used when _sd_alg is
+// absent
+const DefaultSDAlgorithm = "sha-256"
+
+// sdHashes are the digest algorithms accepted for _sd_alg, by their IANA
+// hash function names
+var sdHashes = map[string]crypto.Hash{
+	"sha-256": crypto.SHA256,
+	"sha-384": crypto.SHA384,
+	"sha-512": crypto.SHA512,
+}
+
+// sdReservedClaims are registered claims an SD-JWT VC must not make
+// selectively disclosable
+var sdReservedClaims = []string{"iss", "nbf", "exp", "cnf", "vct", "vct#integrity", "status", "_sd", "_sd_alg", "..."}
+
 // SDJWTProcessor handles Selective Disclosure JWT operations
 type SDJWTProcessor struct {
-	keyManager did.KeyManager
-	resolver   did.MultiResolver
+	keyManager   did.KeyManager
+	resolver     did.MultiResolver
+	typeMetadata *TypeMetadataRegistry
 }
 
 // NewSDJWTProcessor creates a new SD-JWT processor
@@ -25,7 +56,15 @@ func NewSDJWTProcessor(keyManager did.KeyManager, resolver did.MultiResolver) *S
 	}
 }
 
-// CreateSDJWT creates a Selective Disclosure JWT from a credential template
+// SetTypeMetadataRegistry enables vct type metadata checks during
+// verification
+func (p *SDJWTProcessor) SetTypeMetadataRegistry(registry *TypeMetadataRegistry) {
+	p.typeMetadata = registry
+}
+
+// CreateSDJWT creates an SD-JWT VC from a credential template. Credential
+// subject claims become top-level claims of the payload; those named in
+// SelectivelyDisclosable, at any depth, are replaced by digests.
 func (p *SDJWTProcessor) CreateSDJWT(template *CredentialTemplate, options *IssuanceOptions, privateKey interface{}) (string, error) {
 	if template == nil {
 		return "", NewVCError(ErrorInvalidCredential, "template cannot be nil")
@@ -35,24 +74,65 @@ func (p *SDJWTProcessor) CreateSDJWT(template *CredentialTemplate, options *Issu
 		return "", NewVCError(ErrorInvalidCredential, "options cannot be nil")
 	}
 
-	// Extract selectively disclosable claims
-	claims, disclosures, err := p.processSelectiveDisclosure(template.CredentialSubject, template.SelectivelyDisclosable, options.SaltGenerator)
+	sdAlg := options.SDAlgorithm
+	if sdAlg == "" {
+		sdAlg = DefaultSDAlgorithm
+	}
+	if _, ok := sdHashes[sdAlg]; !ok {
+		return "", NewVCError(ErrorInvalidCredential, "unsupported _sd_alg: "+sdAlg)
+	}
+
+	vct := template.VCT
+	if vct == "" {
+		for _, t := range template.Type {
+			if t != "VerifiableCredential" {
+				vct = t
+			}
+		}
+	}
+	if vct == "" {
+		return "", NewVCError(ErrorInvalidCredential, "vct is required for SD-JWT VC")
+	}
+
+	subject, err := p.interfaceToMap(template.CredentialSubject)
+	if err != nil {
+		return "", NewVCErrorWithDetails(ErrorInvalidCredential, "invalid credential subject", err.Error())
+	}
+	claims := make(map[string]interface{}, len(subject))
+	for key, value := range subject {
+		if key != "id" {
+			claims[key] = value
+		}
+	}
+
+	paths, err := parseSDPaths(template.SelectivelyDisclosable)
 	if err != nil {
 		return "", err
 	}
 
-	// Create the SD-JWT claims
-	sdjwtClaims := map[string]interface{}{
-		"iss":   getIssuerID(template.Issuer),
-		"iat":   getCurrentTime(),
-		"_sd":   p.createSDHashes(disclosures),
-		"cnf":   p.createConfirmationClaim(options.RequireKeyBinding),
-		"vc":    template,
+	encoder := &sdEncoder{
+		processor: p,
+		hash:      sdHashes[sdAlg],
+		salt:      options.SaltGenerator,
+		decoys:    options.DecoyDigests,
+	}
+	if encoder.salt == nil {
+		encoder.salt = generateDefaultSalt
+	}
+	encoded, err := encoder.encodeObject(claims, paths)
+	if err != nil {
+		return "", err
 	}
+	sdjwtClaims := encoded
 
-	// Add additional standard claims
-	if subject := getCredentialSubjectID(template.CredentialSubject); subject != "" {
-		sdjwtClaims["sub"] = subject
+	// Registered claims are always disclosed
+	sdjwtClaims["iss"] = getIssuerID(template.Issuer)
+	sdjwtClaims["iat"] = getCurrentTime()
+	sdjwtClaims["vct"] = vct
+	sdjwtClaims["_sd_alg"] = sdAlg
+
+	if subjectID := getCredentialSubjectID(template.CredentialSubject); subjectID != "" {
+		sdjwtClaims["sub"] = subjectID
 	}
 
 	if template.ExpirationDate != "" {
@@ -61,24 +141,23 @@ func (p *SDJWTProcessor) CreateSDJWT(template *CredentialTemplate, options *Issu
 		}
 	}
 
-	// Add non-disclosable claims
-	for key, value := range claims {
-		if !p.isSelectivelyDisclosable(key, template.SelectivelyDisclosable) {
-			sdjwtClaims[key] = value
+	if options.RequireKeyBinding || options.HolderKey != nil {
+		cnf, err := p.createConfirmationClaim(options.HolderKey)
+		if err != nil {
+			return "", err
 		}
+		sdjwtClaims["cnf"] = cnf
 	}
 
 	// Add additional claims
-	if options.AdditionalClaims != nil {
-		for key, value := range options.AdditionalClaims {
-			sdjwtClaims[key] = value
-		}
+	for key, value := range options.AdditionalClaims {
+		sdjwtClaims[key] = value
 	}
 
 	// Create header
 	header := map[string]interface{}{
 		"alg": options.Algorithm,
-		"typ": "SD-JWT",
+		"typ": SDJWTVCType,
 	}
 
 	if options.KeyID != "" {
@@ -91,15 +170,13 @@ func (p *SDJWTProcessor) CreateSDJWT(template *CredentialTemplate, options *Issu
 		return "", err
 	}
 
-	// Combine JWT with disclosures
-	result := jwt
-	for _, disclosure := range disclosures {
-		result += "~" + disclosure.Encoded
+	// Combine JWT with disclosures; the trailing ~ leaves room for a key
+	// binding JWT
+	result := jwt + "~"
+	for _, disclosure := range encoder.disclosures {
+		result += disclosure.Encoded + "~"
 	}
 
-	// Add empty key binding placeholder
-	result += "~"
-
 	return result, nil
 }
 
@@ -175,180 +252,590 @@ func (p *SDJWTProcessor) ParseSDJWT(sdjwt string) (*SDJWTCredential, error) {
 	}, nil
 }
 
-// VerifySDJWT verifies a Selective Disclosure JWT
+// VerifySDJWT verifies an SD-JWT VC: the issuer signature, every disclosure
+// against the payload digests, the key binding JWT when present, validity
+// times and, with a type metadata registry, the vct's metadata
 func (p *SDJWTProcessor) VerifySDJWT(sdjwt string, options *VerificationOptions) (*VerificationResult, error) {
+	fail := func(message string) (*VerificationResult, error) {
+		return &VerificationResult{Verified: false, Error: message}, nil
+	}
+
 	// Parse SD-JWT
 	sdCredential, err := p.ParseSDJWT(sdjwt)
 	if err != nil {
-		return &VerificationResult{
-			Verified: false,
-			Error:    err.Error(),
-		}, nil
+		return fail(err.Error())
+	}
+
+	if typ := sdCredential.Header.Type; typ != SDJWTVCType && typ != SDJWTDCType {
+		return fail("unsupported SD-JWT typ: " + typ)
 	}
 
 	// Get the issuer's public key
 	issuer, ok := sdCredential.Claims["iss"].(string)
 	if !ok {
-		return &VerificationResult{
-			Verified: false,
-			Error:    "missing or invalid issuer claim",
-		}, nil
+		return fail("missing or invalid issuer claim")
+	}
+	vct, ok := sdCredential.Claims["vct"].(string)
+	if !ok || vct == "" {
+		return fail("missing or invalid vct claim")
 	}
 
 	publicKey, err := p.resolvePublicKey(issuer, sdCredential.Header.KeyID)
 	if err != nil {
-		return &VerificationResult{
-			Verified: false,
-			Error:    "failed to resolve issuer key: " + err.Error(),
-		}, nil
+		return fail("failed to resolve issuer key: " + err.Error())
 	}
 
 	// Verify JWT signature
 	if err := p.verifyJWTSignature(sdCredential.JWT, publicKey); err != nil {
-		return &VerificationResult{
-			Verified: false,
-			Error:    "JWT signature verification failed: " + err.Error(),
-		}, nil
+		return fail("JWT signature verification failed: " + err.Error())
 	}
 
-	// Verify disclosure hashes
-	if err := p.verifyDisclosureHashes(sdCredential); err != nil {
-		return &VerificationResult{
-			Verified: false,
-			Error:    "disclosure verification failed: " + err.Error(),
-		}, nil
+	// Replace digests with the disclosed claims
+	processed, err := p.ProcessDisclosures(sdCredential)
+	if err != nil {
+		return fail("disclosure verification failed: " + err.Error())
 	}
 
-	// Verify key binding if present
-	if sdCredential.KeyBinding != nil {
-		if err := p.verifyKeyBinding(sdCredential, options); err != nil {
-			return &VerificationResult{
-				Verified: false,
-				Error:    "key binding verification failed: " + err.Error(),
-			}, nil
-		}
+	// Verify key binding, which a holder-bound credential must carry
+	if err := p.verifyKeyBinding(sdCredential, options); err != nil {
+		return fail("key binding verification failed: " + err.Error())
 	}
 
 	// Validate time claims
 	if err := p.validateTimeClaimsFromMap(sdCredential.Claims, options); err != nil {
-		return &VerificationResult{
-			Verified: false,
-			Error:    "time validation failed: " + err.Error(),
-		}, nil
+		return fail("time validation failed: " + err.Error())
 	}
 
-	// Reconstruct the full credential with disclosed claims
-	reconstructedClaims := p.reconstructClaims(sdCredential)
+	if p.typeMetadata != nil {
+		integrity, _ := sdCredential.Claims["vct#integrity"].(string)
+		if err := p.typeMetadata.Check(vct, integrity, processed); err != nil {
+			return fail("type metadata check failed: " + err.Error())
+		}
+	}
 
 	return &VerificationResult{
 		Verified:        true,
 		SDJWTCredential: sdCredential,
 		Details: map[string]interface{}{
-			"issuer":               issuer,
-			"algorithm":            sdCredential.Header.Algorithm,
-			"disclosed_claims":     reconstructedClaims,
-			"disclosure_count":     len(sdCredential.Disclosures),
-			"key_binding_present":  sdCredential.KeyBinding != nil,
+			"issuer":              issuer,
+			"vct":                 vct,
+			"algorithm":           sdCredential.Header.Algorithm,
+			"disclosed_claims":    processed.Claims,
+			"disclosure_count":    len(sdCredential.Disclosures),
+			"key_binding_present": sdCredential.KeyBinding != nil,
 		},
 	}, nil
 }
 
-// CreateKeyBindingJWT creates a key binding JWT for holder verification
+// CreateKeyBindingJWT creates a key binding JWT for an SD-JWT presentation.
+// sd_hash binds it to the issuer JWT and exactly the disclosures in sdjwt;
+// the result goes after the final ~.
 func (p *SDJWTProcessor) CreateKeyBindingJWT(sdjwt string, audience, nonce string, holderKey interface{}) (string, error) {
-	// Parse the original SD-JWT to get the confirmation claim
-	parts := strings.Split(sdjwt, "~")
-	if len(parts) < 1 {
-		return "", NewVCError(ErrorInvalidJWT, "invalid SD-JWT format")
-	}
+	presentation := sdJWTPresentationInput(sdjwt)
 
-	jwt := parts[0]
-	jwtParts := strings.Split(jwt, ".")
-	if len(jwtParts) != 3 {
-		return "", NewVCError(ErrorInvalidJWT, "JWT must have 3 parts")
+	credential, err := p.ParseSDJWT(presentation)
+	if err != nil {
+		return "", err
+	}
+	hash, err := credentialSDHash(credential.Claims)
+	if err != nil {
+		return "", err
 	}
 
 	// Create key binding claims
 	claims := map[string]interface{}{
-		"aud":   audience,
-		"nonce": nonce,
-		"iat":   getCurrentTime(),
-		"sd_hash": p.hashString(jwt), // Hash of the SD-JWT
+		"aud":     audience,
+		"nonce":   nonce,
+		"iat":     getCurrentTime(),
+		"sd_hash": sdDigest(hash, presentation),
 	}
 
 	// Create header
 	header := map[string]interface{}{
-		"alg": "EdDSA", // Adjust based on key type
-		"typ": "kb+jwt",
+		"alg": jwsAlgorithm(holderKey),
+		"typ": KeyBindingJWTType,
 	}
 
 	return p.signJWT(header, claims, holderKey)
 }
 
-// Helper methods
+// sdJWTPresentationInput returns an SD-JWT without any key binding JWT,
+// ending in ~, which is the input of sd_hash
+func sdJWTPresentationInput(sdjwt string) string {
+	if i := strings.LastIndex(sdjwt, "~"); i >= 0 {
+		return sdjwt[:i+1]
+	}
+	return sdjwt + "~"
+}
 
-func (p *SDJWTProcessor) processSelectiveDisclosure(credentialSubject interface{}, selectiveFields []string, saltGenerator func() string) (map[string]interface{}, []Disclosure, error) {
-	if saltGenerator == nil {
-		saltGenerator = generateDefaultSalt
+// jwsAlgorithm returns the JWS algorithm for a signing key
+func jwsAlgorithm(key interface{}) string {
+	if k, ok := key.(*ecdsa.PrivateKey); ok {
+		switch k.Curve {
+		case elliptic.P384():
+			return "ES384"
+		case elliptic.P521():
+			return "ES512"
+		}
+		return "ES256"
 	}
+	return "EdDSA"
+}
 
-	claims := make(map[string]interface{})
-	var disclosures []Disclosure
+// SDJWTClaims is the payload of an SD-JWT with its disclosures applied
+type SDJWTClaims struct {
+	// Claims holds the disclosed payload, without _sd, _sd_alg and
+	// undisclosed array elements
+	Claims map[string]interface{}
+	// Disclosed lists the locations, as member names and array indices,
+	// whose values came from disclosures
+	Disclosed [][]interface{}
+
+	// locations maps each applied disclosure, by its encoded form, to the
+	// location it discloses
+	locations map[string][]interface{}
+}
 
-	// Convert credential subject to map
-	subjectMap, err := p.interfaceToMap(credentialSubject)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Process each field
-	for key, value := range subjectMap {
-		if p.isSelectivelyDisclosable(key, selectiveFields) {
-			// Create disclosure
-			salt := saltGenerator()
-			disclosure := Disclosure{
-				Salt:  salt,
-				Claim: key,
-				Value: value,
+// isDisclosed reports whether the value at tokens came from a disclosure
+func (c *SDJWTClaims) isDisclosed(tokens []interface{}) bool {
+	for _, disclosed := range c.Disclosed {
+		if len(disclosed) != len(tokens) {
+			continue
+		}
+		match := true
+		for i := range tokens {
+			if disclosed[i] != tokens[i] {
+				match = false
+				break
 			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessDisclosures applies the disclosures of an SD-JWT to its payload.
+// It rejects disclosures the payload does not reference, digests that occur
+// more than once, and disclosures that would overwrite a claim.
+func (p *SDJWTProcessor) ProcessDisclosures(credential *SDJWTCredential) (*SDJWTClaims, error) {
+	hash, err := credentialSDHash(credential.Claims)
+	if err != nil {
+		return nil, err
+	}
+
+	byDigest := make(map[string]*Disclosure, len(credential.Disclosures))
+	for i := range credential.Disclosures {
+		disclosure := &credential.Disclosures[i]
+		digest := sdDigest(hash, disclosure.Encoded)
+		if _, duplicate := byDigest[digest]; duplicate {
+			return nil, NewVCError(ErrorInvalidCredential, "disclosure included more than once")
+		}
+		byDigest[digest] = disclosure
+	}
+
+	decoder := &sdDecoder{byDigest: byDigest, seen: make(map[string]bool), locations: make(map[string][]interface{})}
+	payload := make(map[string]interface{}, len(credential.Claims))
+	for key, value := range credential.Claims {
+		if key != "_sd_alg" {
+			payload[key] = value
+		}
+	}
+
+	processed, err := decoder.decode(payload, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for digest := range byDigest {
+		if !decoder.used(digest) {
+			return nil, NewVCError(ErrorInvalidCredential, "disclosure is not referenced by the SD-JWT")
+		}
+	}
+
+	return &SDJWTClaims{Claims: processed.(map[string]interface{}), Disclosed: decoder.disclosed, locations: decoder.locations}, nil
+}
+
+// sdDecoder walks a payload replacing digests with disclosed values
+type sdDecoder struct {
+	byDigest  map[string]*Disclosure
+	seen      map[string]bool
+	applied   []string
+	disclosed [][]interface{}
+	locations map[string][]interface{}
+}
+
+func (d *sdDecoder) used(digest string) bool {
+	for _, applied := range d.applied {
+		if applied == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// claim marks a digest as referenced, rejecting repeats
+func (d *sdDecoder) claim(digest string) (*Disclosure, error) {
+	if d.seen[digest] {
+		return nil, NewVCError(ErrorInvalidCredential, "digest "+digest+" appears more than once")
+	}
+	d.seen[digest] = true
+
+	disclosure, ok := d.byDigest[digest]
+	if ok {
+		d.applied = append(d.applied, digest)
+	}
+	return disclosure, nil
+}
+
+func (d *sdDecoder) decode(value interface{}, path []interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return d.decodeObject(v, path)
+	case []interface{}:
+		return d.decodeArray(v, path)
+	}
+	return value, nil
+}
 
-			// Encode disclosure
-			disclosureArray := []interface{}{salt, key, value}
-			disclosureBytes, err := json.Marshal(disclosureArray)
+func (d *sdDecoder) decodeObject(object map[string]interface{}, path []interface{}) (interface{}, error) {
+	out := make(map[string]interface{}, len(object))
+	for _, key := range sortedKeys(object) {
+		if key == "_sd" {
+			continue
+		}
+		decoded, err := d.decode(object[key], appendToken(path, key))
+		if err != nil {
+			return nil, err
+		}
+		out[key] = decoded
+	}
+
+	digests, present := object["_sd"]
+	if !present {
+		return out, nil
+	}
+	list, ok := digests.([]interface{})
+	if !ok {
+		return nil, NewVCError(ErrorInvalidCredential, "_sd must be an array")
+	}
+
+	for _, entry := range list {
+		digest, ok := entry.(string)
+		if !ok {
+			return nil, NewVCError(ErrorInvalidCredential, "_sd entries must be strings")
+		}
+		disclosure, err := d.claim(digest)
+		if err != nil {
+			return nil, err
+		}
+		if disclosure == nil {
+			continue // decoy or withheld claim
+		}
+		if disclosure.ArrayElement {
+			return nil, NewVCError(ErrorInvalidCredential, "array element disclosure referenced from _sd")
+		}
+		if disclosure.Claim == "_sd" || disclosure.Claim == "..." {
+			return nil, NewVCError(ErrorInvalidCredential, "disclosure uses reserved claim name "+disclosure.Claim)
+		}
+		if _, exists := out[disclosure.Claim]; exists {
+			return nil, NewVCError(ErrorInvalidCredential, "disclosed claim "+disclosure.Claim+" already exists")
+		}
+
+		claimPath := appendToken(path, disclosure.Claim)
+		decoded, err := d.decode(disclosure.Value, claimPath)
+		if err != nil {
+			return nil, err
+		}
+		out[disclosure.Claim] = decoded
+		d.disclosed = append(d.disclosed, claimPath)
+		d.locations[disclosure.Encoded] = claimPath
+	}
+	return out, nil
+}
+
+func (d *sdDecoder) decodeArray(array []interface{}, path []interface{}) (interface{}, error) {
+	out := make([]interface{}, 0, len(array))
+	for _, element := range array {
+		if digest, ok := arrayElementDigest(element); ok {
+			disclosure, err := d.claim(digest)
 			if err != nil {
-				return nil, nil, NewVCErrorWithDetails(ErrorInvalidCredential, "failed to encode disclosure", err.Error())
+				return nil, err
+			}
+			if disclosure == nil {
+				continue // decoy or withheld element
+			}
+			if !disclosure.ArrayElement {
+				return nil, NewVCError(ErrorInvalidCredential, "object property disclosure referenced from an array")
 			}
+			elementPath := appendToken(path, len(out))
+			decoded, err := d.decode(disclosure.Value, elementPath)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, decoded)
+			d.disclosed = append(d.disclosed, elementPath)
+			d.locations[disclosure.Encoded] = elementPath
+			continue
+		}
 
-			disclosure.Encoded = base64.RawURLEncoding.EncodeToString(disclosureBytes)
-			disclosures = append(disclosures, disclosure)
-		} else {
-			claims[key] = value
+		decoded, err := d.decode(element, appendToken(path, len(out)))
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, decoded)
 	}
+	return out, nil
+}
 
-	return claims, disclosures, nil
+// arrayElementDigest recognises the {"...": digest} placeholder of an
+// array element
+func arrayElementDigest(element interface{}) (string, bool) {
+	object, ok := element.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return "", false
+	}
+	digest, ok := object["..."].(string)
+	return digest, ok
 }
 
-func (p *SDJWTProcessor) createSDHashes(disclosures []Disclosure) []string {
-	hashes := make([]string, len(disclosures))
-	for i, disclosure := range disclosures {
-		hashes[i] = p.hashString(disclosure.Encoded)
+func appendToken(path []interface{}, token interface{}) []interface{} {
+	return append(path[:len(path):len(path)], token)
+}
+
+// Issuance
+
+// sdPathNode marks which claims below a point of the payload are
+// selectively disclosable
+type sdPathNode struct {
+	disclose   bool
+	members    map[string]*sdPathNode
+	elements   map[int]*sdPathNode
+	anyElement *sdPathNode
+}
+
+// parseSDPaths parses paths such as "address.locality" and
+// "nationalities[*]" into a tree
+func parseSDPaths(paths []string) (*sdPathNode, error) {
+	root := &sdPathNode{}
+	for _, path := range paths {
+		node := root
+		rest := path
+		for rest != "" {
+			switch {
+			case rest[0] == '[':
+				end := strings.IndexByte(rest, ']')
+				if end < 0 {
+					return nil, NewVCError(ErrorInvalidCredential, "invalid selectively disclosable path: "+path)
+				}
+				index := rest[1:end]
+				rest = strings.TrimPrefix(rest[end+1:], ".")
+				if index == "*" {
+					if node.anyElement == nil {
+						node.anyElement = &sdPathNode{}
+					}
+					node = node.anyElement
+					continue
+				}
+				i, err := strconv.Atoi(index)
+				if err != nil || i < 0 {
+					return nil, NewVCError(ErrorInvalidCredential, "invalid array index in selectively disclosable path: "+path)
+				}
+				if node.elements == nil {
+					node.elements = make(map[int]*sdPathNode)
+				}
+				if node.elements[i] == nil {
+					node.elements[i] = &sdPathNode{}
+				}
+				node = node.elements[i]
+			default:
+				end := strings.IndexAny(rest, ".[")
+				name := rest
+				if end >= 0 {
+					name = rest[:end]
+					rest = strings.TrimPrefix(rest[end:], ".")
+				} else {
+					rest = ""
+				}
+				if name == "" {
+					return nil, NewVCError(ErrorInvalidCredential, "invalid selectively disclosable path: "+path)
+				}
+				if node == root && containsString(sdReservedClaims, name) {
+					return nil, NewVCError(ErrorInvalidCredential, "claim "+name+" cannot be selectively disclosable")
+				}
+				if node.members == nil {
+					node.members = make(map[string]*sdPathNode)
+				}
+				if node.members[name] == nil {
+					node.members[name] = &sdPathNode{}
+				}
+				node = node.members[name]
+			}
+		}
+		node.disclose = true
 	}
-	return hashes
+	return root, nil
 }
 
-func (p *SDJWTProcessor) createConfirmationClaim(requireKeyBinding bool) map[string]interface{} {
-	if !requireKeyBinding {
+func (n *sdPathNode) member(name string) *sdPathNode {
+	if n == nil {
 		return nil
 	}
+	return n.members[name]
+}
 
-	// For simplicity, return a placeholder confirmation claim
-	// In a real implementation, this would include the holder's public key or reference
-	return map[string]interface{}{
-		"jwk": map[string]interface{}{
-			"kty": "OKP",
-			"crv": "Ed25519",
-		},
+func (n *sdPathNode) element(i int) *sdPathNode {
+	if n == nil {
+		return nil
+	}
+	if node, ok := n.elements[i]; ok {
+		return node
+	}
+	return n.anyElement
+}
+
+// sdEncoder replaces disclosable claims with digests, innermost first, so
+// that nested disclosures are carried inside their parent's disclosure
+type sdEncoder struct {
+	processor   *SDJWTProcessor
+	hash        crypto.Hash
+	salt        func() string
+	decoys      int
+	disclosures []Disclosure
+}
+
+func (e *sdEncoder) encode(value interface{}, node *sdPathNode) (interface{}, error) {
+	if node == nil {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return e.encodeObject(v, node)
+	case []interface{}:
+		return e.encodeArray(v, node)
+	}
+	return value, nil
+}
+
+func (e *sdEncoder) encodeObject(object map[string]interface{}, node *sdPathNode) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(object))
+	var digests []string
+
+	for _, key := range sortedKeys(object) {
+		child := node.member(key)
+		value, err := e.encode(object[key], child)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil || !child.disclose {
+			out[key] = value
+			continue
+		}
+
+		disclosure, err := e.disclose([]interface{}{e.salt(), key, value})
+		if err != nil {
+			return nil, err
+		}
+		disclosure.Claim = key
+		digests = append(digests, sdDigest(e.hash, disclosure.Encoded))
+	}
+
+	if len(digests) > 0 || (node != nil && len(node.members) > 0) {
+		digests = append(digests, e.decoyDigests()...)
+	}
+	if len(digests) > 0 {
+		// Sorting hides the original claim order
+		sort.Strings(digests)
+		list := make([]interface{}, len(digests))
+		for i, digest := range digests {
+			list[i] = digest
+		}
+		out["_sd"] = list
+	}
+	return out, nil
+}
+
+func (e *sdEncoder) encodeArray(array []interface{}, node *sdPathNode) ([]interface{}, error) {
+	out := make([]interface{}, 0, len(array))
+	for i, element := range array {
+		child := node.element(i)
+		value, err := e.encode(element, child)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil || !child.disclose {
+			out = append(out, value)
+			continue
+		}
+
+		disclosure, err := e.disclose([]interface{}{e.salt(), value})
+		if err != nil {
+			return nil, err
+		}
+		disclosure.ArrayElement = true
+		out = append(out, map[string]interface{}{"...": sdDigest(e.hash, disclosure.Encoded)})
+	}
+	return out, nil
+}
+
+func (e *sdEncoder) disclose(fields []interface{}) (*Disclosure, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, NewVCErrorWithDetails(ErrorInvalidCredential, "failed to encode disclosure", err.Error())
+	}
+	e.disclosures = append(e.disclosures, Disclosure{
+		Salt:    fields[0].(string),
+		Value:   fields[len(fields)-1],
+		Encoded: base64.RawURLEncoding.EncodeToString(data),
+	})
+	return &e.disclosures[len(e.disclosures)-1], nil
+}
+
+// decoyDigests returns digests of random data, indistinguishable from real
+// ones
+func (e *sdEncoder) decoyDigests() []string {
+	decoys := make([]string, e.decoys)
+	for i := range decoys {
+		decoys[i] = sdDigest(e.hash, generateDefaultSalt())
+	}
+	return decoys
+}
+
+// credentialSDHash returns the digest algorithm an SD-JWT payload declares
+func credentialSDHash(claims map[string]interface{}) (crypto.Hash, error) {
+	name := DefaultSDAlgorithm
+	if alg, present := claims["_sd_alg"]; present {
+		s, ok := alg.(string)
+		if !ok {
+			return 0, NewVCError(ErrorInvalidCredential, "_sd_alg must be a string")
+		}
+		name = s
 	}
+	hash, ok := sdHashes[name]
+	if !ok {
+		return 0, NewVCError(ErrorInvalidCredential, "unsupported _sd_alg: "+name)
+	}
+	return hash, nil
+}
+
+// sdDigest is the base64url digest of the ASCII input, used for
+// disclosures and sd_hash alike
+func sdDigest(hash crypto.Hash, input string) string {
+	h := hash.New()
+	h.Write([]byte(input))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (p *SDJWTProcessor) createConfirmationClaim(holderKey *did.JWK) (map[string]interface{}, error) {
+	if holderKey == nil {
+		return nil, NewVCError(ErrorInvalidCredential, "key binding requires the holder's public key")
+	}
+
+	// Never embed private key material
+	public := *holderKey
+	public.D = ""
+	return map[string]interface{}{"jwk": public}, nil
 }
 
 func (p *SDJWTProcessor) parseDisclosure(encoded string) (*Disclosure, error) {
@@ -362,8 +849,8 @@ func (p *SDJWTProcessor) parseDisclosure(encoded string) (*Disclosure, error) {
 		return nil, NewVCErrorWithDetails(ErrorInvalidJWT, "failed to parse disclosure", err.Error())
 	}
 
-	if len(disclosureArray) != 3 {
-		return nil, NewVCError(ErrorInvalidJWT, "disclosure must have 3 elements")
+	if len(disclosureArray) != 2 && len(disclosureArray) != 3 {
+		return nil, NewVCError(ErrorInvalidJWT, "disclosure must have 2 or 3 elements")
 	}
 
 	salt, ok := disclosureArray[0].(string)
@@ -371,6 +858,15 @@ func (p *SDJWTProcessor) parseDisclosure(encoded string) (*Disclosure, error) {
 		return nil, NewVCError(ErrorInvalidJWT, "disclosure salt must be string")
 	}
 
+	if len(disclosureArray) == 2 {
+		return &Disclosure{
+			Salt:         salt,
+			Value:        disclosureArray[1],
+			ArrayElement: true,
+			Encoded:      encoded,
+		}, nil
+	}
+
 	claim, ok := disclosureArray[1].(string)
 	if !ok {
 		return nil, NewVCError(ErrorInvalidJWT, "disclosure claim must be string")
@@ -419,46 +915,21 @@ func (p *SDJWTProcessor) parseKeyBinding(jwt string) (*KeyBinding, error) {
 	}, nil
 }
 
-func (p *SDJWTProcessor) verifyDisclosureHashes(credential *SDJWTCredential) error {
-	// Get the _sd array from claims
-	sdHashes, ok := credential.Claims["_sd"].([]interface{})
-	if !ok {
-		return NewVCError(ErrorInvalidCredential, "missing or invalid _sd claim")
-	}
-
-	// Convert to string array
-	expectedHashes := make([]string, len(sdHashes))
-	for i, hash := range sdHashes {
-		if hashStr, ok := hash.(string); ok {
-			expectedHashes[i] = hashStr
-		} else {
-			return NewVCError(ErrorInvalidCredential, "invalid hash in _sd array")
-		}
-	}
-
-	// Calculate hashes for disclosures
-	actualHashes := p.createSDHashes(credential.Disclosures)
-
-	// Verify all disclosure hashes are in the _sd array
-	for _, actualHash := range actualHashes {
-		found := false
-		for _, expectedHash := range expectedHashes {
-			if actualHash == expectedHash {
-				found = true
-				break
-			}
+func (p *SDJWTProcessor) verifyKeyBinding(credential *SDJWTCredential, options *VerificationOptions) error {
+	if credential.KeyBinding == nil {
+		// A credential bound to a holder key, or a request for a fresh
+		// presentation, needs the holder's key binding JWT
+		if _, bound := credential.Claims["cnf"]; bound {
+			return NewVCError(ErrorInvalidProof, "credential has a confirmation key but no key binding JWT")
 		}
-		if !found {
-			return NewVCError(ErrorInvalidCredential, "disclosure hash not found in _sd array")
+		if options != nil && (options.Challenge != "" || options.Domain != "") {
+			return NewVCError(ErrorInvalidProof, "challenge or domain requested but no key binding JWT presented")
 		}
+		return nil
 	}
 
-	return nil
-}
-
-func (p *SDJWTProcessor) verifyKeyBinding(credential *SDJWTCredential, options *VerificationOptions) error {
-	if credential.KeyBinding == nil {
-		return nil // No key binding to verify
+	if credential.KeyBinding.Header.Type != KeyBindingJWTType {
+		return NewVCError(ErrorInvalidProof, "key binding JWT typ must be "+KeyBindingJWTType)
 	}
 
 	// Get the confirmation claim from the original JWT
@@ -478,6 +949,10 @@ func (p *SDJWTProcessor) verifyKeyBinding(credential *SDJWTCredential, options *
 		return NewVCError(ErrorInvalidProof, "key binding signature verification failed: "+err.Error())
 	}
 
+	if _, ok := credential.KeyBinding.Claims["iat"].(float64); !ok {
+		return NewVCError(ErrorInvalidProof, "key binding JWT has no iat")
+	}
+
 	// Verify key binding claims
 	if options != nil {
 		if options.Challenge != "" {
@@ -493,40 +968,21 @@ func (p *SDJWTProcessor) verifyKeyBinding(credential *SDJWTCredential, options *
 		}
 	}
 
-	// Verify SD-JWT hash
-	expectedHash := p.hashString(credential.JWT)
-	if actualHash, ok := credential.KeyBinding.Claims["sd_hash"].(string); !ok || actualHash != expectedHash {
-		return NewVCError(ErrorInvalidProof, "key binding SD-JWT hash mismatch")
-	}
-
-	return nil
-}
-
-func (p *SDJWTProcessor) reconstructClaims(credential *SDJWTCredential) map[string]interface{} {
-	result := make(map[string]interface{})
-
-	// Add non-selective claims
-	for key, value := range credential.Claims {
-		if key != "_sd" && key != "cnf" {
-			result[key] = value
-		}
+	// sd_hash covers the issuer JWT and the disclosures as presented
+	hash, err := credentialSDHash(credential.Claims)
+	if err != nil {
+		return err
 	}
-
-	// Add disclosed claims
+	presentation := credential.JWT + "~"
 	for _, disclosure := range credential.Disclosures {
-		result[disclosure.Claim] = disclosure.Value
+		presentation += disclosure.Encoded + "~"
 	}
-
-	return result
-}
-
-func (p *SDJWTProcessor) isSelectivelyDisclosable(field string, selectiveFields []string) bool {
-	for _, sf := range selectiveFields {
-		if sf == field {
-			return true
-		}
+	expectedHash := sdDigest(hash, presentation)
+	if actualHash, ok := credential.KeyBinding.Claims["sd_hash"].(string); !ok || actualHash != expectedHash {
+		return NewVCError(ErrorInvalidProof, "key binding sd_hash does not match the presented SD-JWT")
 	}
-	return false
+
+	return nil
 }
 
 func (p *SDJWTProcessor) interfaceToMap(v interface{}) (map[string]interface{}, error) {
@@ -555,15 +1011,33 @@ func (p *SDJWTProcessor) interfaceToMap(v interface{}) (map[string]interface{},
 	}
 }
 
-func (p *SDJWTProcessor) hashString(input string) string {
-	hash := sha256.Sum256([]byte(input))
-	return base64.RawURLEncoding.EncodeToString(hash[:])
-}
-
 func (p *SDJWTProcessor) extractPublicKeyFromConfirmation(cnf interface{}) (interface{}, error) {
-	// This is a simplified implementation
-	// In practice, the confirmation claim could contain various key representations
-	return nil, NewVCError(ErrorInvalidProof, "key binding verification not fully implemented")
+	confirmation, ok := cnf.(map[string]interface{})
+	if !ok {
+		return nil, NewVCError(ErrorInvalidProof, "invalid confirmation claim")
+	}
+	raw, ok := confirmation["jwk"]
+	if !ok {
+		return nil, NewVCError(ErrorInvalidProof, "confirmation claim has no jwk")
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, NewVCErrorWithDetails(ErrorInvalidProof, "invalid confirmation key", err.Error())
+	}
+	var jwk did.JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, NewVCErrorWithDetails(ErrorInvalidProof, "invalid confirmation key", err.Error())
+	}
+	if jwk.D != "" {
+		return nil, NewVCError(ErrorInvalidProof, "confirmation key must not contain private material")
+	}
+
+	key, err := p.keyManager.JWKToKey(&jwk)
+	if err != nil {
+		return nil, NewVCErrorWithDetails(ErrorInvalidProof, "unsupported confirmation key", err.Error())
+	}
+	return key, nil
 }
 
 // Common helper functions
@@ -693,7 +1167,7 @@ func (p *SDJWTProcessor) extractPublicKeyFromVM(vm *did.VerificationMethod) (int
 
 		// For Ed25519, remove the multicodec prefix
 		if len(decoded) >= 2 && decoded[0] == 0xed && decoded[1] == 0x01 {
-			return decoded[2:], nil
+			return ed25519.PublicKey(decoded[2:]), nil
 		}
 
 		return decoded, nil
@@ -730,4 +1204,4 @@ func (p *SDJWTProcessor) validateTimeClaimsFromMap(claims map[string]interface{}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
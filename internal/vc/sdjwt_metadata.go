@@ -0,0 +1,346 @@
+package vc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTypeMetadataDepth bounds the extends chain of a vct
+const maxTypeMetadataDepth = 8
+
+// Selective disclosure rules of claim metadata
+const (
+	ClaimSDAlways  = "always"
+	ClaimSDAllowed = "allowed"
+	ClaimSDNever   = "never"
+)
+
+// TypeMetadata describes an SD-JWT VC type, as published for its vct
+type TypeMetadata struct {
+	VCT              string                   `json:"vct"`
+	Name             string                   `json:"name,omitempty"`
+	Description      string                   `json:"description,omitempty"`
+	Extends          string                   `json:"extends,omitempty"`
+	ExtendsIntegrity string                   `json:"extends#integrity,omitempty"`
+	Display          []map[string]interface{} `json:"display,omitempty"`
+	Claims           []ClaimMetadata          `json:"claims,omitempty"`
+	Schema           json.RawMessage          `json:"schema,omitempty"`
+	SchemaURI        string                   `json:"schema_uri,omitempty"`
+	SchemaIntegrity  string                   `json:"schema_uri#integrity,omitempty"`
+}
+
+// ClaimMetadata describes one claim of a type. Path elements are member
+// names, array indices, or null for every element of an array.
+type ClaimMetadata struct {
+	Path    []interface{}            `json:"path"`
+	SD      string                   `json:"sd,omitempty"`
+	SVGID   string                   `json:"svg_id,omitempty"`
+	Display []map[string]interface{} `json:"display,omitempty"`
+}
+
+// TypeMetadataRegistry resolves vct values to their type metadata. Types are
+// registered up front; HTTPS vcts are fetched only when remote fetch is on.
+type TypeMetadataRegistry struct {
+	client      *http.Client
+	schemas     *SchemaValidator
+	remoteFetch bool
+
+	mu        sync.RWMutex
+	documents map[string][]byte
+}
+
+// NewTypeMetadataRegistry creates an empty registry. Schemas referenced by
+// schema_uri are looked up in schemas, which may be nil when every type
+// embeds its schema.
+func NewTypeMetadataRegistry(schemas *SchemaValidator) *TypeMetadataRegistry {
+	return &TypeMetadataRegistry{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		schemas:   schemas,
+		documents: make(map[string][]byte),
+	}
+}
+
+// SetRemoteFetch controls whether unregistered https vcts are fetched
+func (r *TypeMetadataRegistry) SetRemoteFetch(enabled bool) {
+	r.remoteFetch = enabled
+}
+
+// Register adds type metadata under its vct
+func (r *TypeMetadataRegistry) Register(metadata *TypeMetadata) error {
+	document, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return r.RegisterDocument(metadata.VCT, document)
+}
+
+// RegisterDocument adds a type metadata document as published, so that
+// vct#integrity digests over its bytes can be checked
+func (r *TypeMetadataRegistry) RegisterDocument(vct string, document []byte) error {
+	var metadata TypeMetadata
+	if err := json.Unmarshal(document, &metadata); err != nil {
+		return fmt.Errorf("invalid type metadata: %w", err)
+	}
+	if metadata.VCT != vct {
+		return fmt.Errorf("type metadata is for %q, not %q", metadata.VCT, vct)
+	}
+	for _, claim := range metadata.Claims {
+		if err := validateClaimMetadata(claim); err != nil {
+			return fmt.Errorf("type metadata %s: %w", vct, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.documents[vct] = append([]byte(nil), document...)
+	r.mu.Unlock()
+	return nil
+}
+
+// Resolve returns the metadata of vct, checked against an SRI integrity
+// digest when one is given
+func (r *TypeMetadataRegistry) Resolve(vct, integrity string) (*TypeMetadata, error) {
+	document, err := r.document(vct)
+	if err != nil {
+		return nil, err
+	}
+	if integrity != "" {
+		if err := VerifyDigestSRI(document, integrity); err != nil {
+			return nil, fmt.Errorf("type metadata %s: %w", vct, err)
+		}
+	}
+
+	var metadata TypeMetadata
+	if err := json.Unmarshal(document, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid type metadata %s: %w", vct, err)
+	}
+	return &metadata, nil
+}
+
+// Chain returns vct's metadata followed by the types it extends
+func (r *TypeMetadataRegistry) Chain(vct, integrity string) ([]*TypeMetadata, error) {
+	var chain []*TypeMetadata
+	seen := make(map[string]bool)
+	for vct != "" {
+		if seen[vct] {
+			return nil, fmt.Errorf("type metadata %s extends itself", vct)
+		}
+		if len(chain) == maxTypeMetadataDepth {
+			return nil, fmt.Errorf("type metadata extends more than %d types", maxTypeMetadataDepth)
+		}
+		seen[vct] = true
+
+		metadata, err := r.Resolve(vct, integrity)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, metadata)
+		vct, integrity = metadata.Extends, metadata.ExtendsIntegrity
+	}
+	return chain, nil
+}
+
+// Check validates a processed SD-JWT VC against the metadata of its vct and
+// every type it extends: their schemas must accept the claims, and claims
+// marked sd "always" or "never" must have been disclosed accordingly
+func (r *TypeMetadataRegistry) Check(vct, integrity string, processed *SDJWTClaims) error {
+	chain, err := r.Chain(vct, integrity)
+	if err != nil {
+		return err
+	}
+
+	instance, err := toJSONDocument(processed.Claims)
+	if err != nil {
+		return err
+	}
+
+	// Claim metadata of an extending type overrides that of its parents
+	rules := make(map[string]bool)
+	for _, metadata := range chain {
+		if err := r.checkSchema(metadata, instance); err != nil {
+			return err
+		}
+		for _, claim := range metadata.Claims {
+			key := claimPathKey(claim.Path)
+			if rules[key] {
+				continue
+			}
+			rules[key] = true
+			if err := checkClaimDisclosure(claim, processed); err != nil {
+				return fmt.Errorf("type %s: %w", metadata.VCT, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *TypeMetadataRegistry) document(vct string) ([]byte, error) {
+	r.mu.RLock()
+	document, ok := r.documents[vct]
+	r.mu.RUnlock()
+	if ok {
+		return document, nil
+	}
+
+	if !r.remoteFetch || !strings.HasPrefix(vct, "https://") {
+		return nil, fmt.Errorf("unknown vct %s", vct)
+	}
+
+	resp, err := r.client.Get(vct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch type metadata %s: %w", vct, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("type metadata fetch failed with status %d", resp.StatusCode)
+	}
+	document, err = io.ReadAll(io.LimitReader(resp.Body, maxSchemaDocumentSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type metadata: %w", err)
+	}
+	if err := r.RegisterDocument(vct, document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func (r *TypeMetadataRegistry) checkSchema(metadata *TypeMetadata, instance interface{}) error {
+	var schema interface{}
+	switch {
+	case len(metadata.Schema) > 0 && metadata.SchemaURI != "":
+		return fmt.Errorf("type metadata %s has both schema and schema_uri", metadata.VCT)
+	case len(metadata.Schema) > 0:
+		document, err := decodeJSONDocument(metadata.Schema)
+		if err != nil {
+			return fmt.Errorf("type metadata %s: invalid schema: %w", metadata.VCT, err)
+		}
+		schema = document
+	case metadata.SchemaURI != "":
+		if r.schemas == nil {
+			return fmt.Errorf("type metadata %s: no schema validator for %s", metadata.VCT, metadata.SchemaURI)
+		}
+		raw, err := r.schemas.fetchRaw(metadata.SchemaURI)
+		if err != nil {
+			return err
+		}
+		if metadata.SchemaIntegrity != "" {
+			if err := VerifyDigestSRI(raw, metadata.SchemaIntegrity); err != nil {
+				return fmt.Errorf("type metadata %s: %w", metadata.VCT, err)
+			}
+		}
+		document, err := decodeJSONDocument(raw)
+		if err != nil {
+			return fmt.Errorf("type metadata %s: invalid schema: %w", metadata.VCT, err)
+		}
+		schema = document
+	default:
+		return nil
+	}
+
+	uri := metadata.SchemaURI
+	if uri == "" {
+		uri = "urn:sd-jwt-vc:" + metadata.VCT + ":schema"
+	}
+	index := newSchemaIndex()
+	root, err := index.add(uri, schema, draft202012)
+	if err != nil {
+		return fmt.Errorf("type metadata %s: invalid schema: %w", metadata.VCT, err)
+	}
+
+	var load func(string) (interface{}, error)
+	if r.schemas != nil {
+		load = r.schemas.loadReferencedSchema
+	}
+	evaluation := newSchemaEvaluator(index, load).validate(root, instance, nil, "", nil, 0)
+	if !evaluation.valid() {
+		first := evaluation.errors[0]
+		return fmt.Errorf("claims do not match the schema of %s: %s: %s", metadata.VCT, first.Field, first.Message)
+	}
+	return nil
+}
+
+func validateClaimMetadata(claim ClaimMetadata) error {
+	if len(claim.Path) == 0 {
+		return fmt.Errorf("claim path cannot be empty")
+	}
+	for _, element := range claim.Path {
+		switch e := element.(type) {
+		case nil, string:
+		case float64:
+			if e < 0 || e != float64(int(e)) {
+				return fmt.Errorf("invalid array index %v in claim path", e)
+			}
+		default:
+			return fmt.Errorf("invalid claim path element %v", element)
+		}
+	}
+	switch claim.SD {
+	case "", ClaimSDAlways, ClaimSDAllowed, ClaimSDNever:
+	default:
+		return fmt.Errorf("invalid sd value %q", claim.SD)
+	}
+	return nil
+}
+
+func claimPathKey(path []interface{}) string {
+	data, _ := json.Marshal(path)
+	return string(data)
+}
+
+// checkClaimDisclosure enforces a claim's sd rule at every location its path
+// selects. Only claims present in the presentation can be checked.
+func checkClaimDisclosure(claim ClaimMetadata, processed *SDJWTClaims) error {
+	if claim.SD != ClaimSDAlways && claim.SD != ClaimSDNever {
+		return nil
+	}
+
+	var walk func(value interface{}, path, tokens []interface{}) error
+	walk = func(value interface{}, path, tokens []interface{}) error {
+		if len(path) == 0 {
+			disclosed := processed.isDisclosed(tokens)
+			if claim.SD == ClaimSDAlways && !disclosed {
+				return fmt.Errorf("claim %s must be selectively disclosable", claimPathKey(claim.Path))
+			}
+			if claim.SD == ClaimSDNever && disclosed {
+				return fmt.Errorf("claim %s must not be selectively disclosable", claimPathKey(claim.Path))
+			}
+			return nil
+		}
+
+		switch element := path[0].(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			child, ok := object[element]
+			if !ok {
+				return nil
+			}
+			return walk(child, path[1:], appendToken(tokens, element))
+		case float64:
+			array, ok := value.([]interface{})
+			if !ok || int(element) >= len(array) {
+				return nil
+			}
+			return walk(array[int(element)], path[1:], appendToken(tokens, int(element)))
+		case nil:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil
+			}
+			for i, child := range array {
+				if err := walk(child, path[1:], appendToken(tokens, i)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(processed.Claims, claim.Path, nil)
+}
@@ -0,0 +1,337 @@
+package vc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+)
+
+// Disclosures and digests from the SD-JWT specification's examples
+const (
+	specGivenNameDisclosure = "WyIyR0xDNDJzS1F2ZUNmR2ZyeU5STjl3IiwgImdpdmVuX25hbWUiLCAiSm9obiJd"
+	specGivenNameDigest     = "jsu9yVulwQQlhFlM_3JlzMaSFzglhQG0DpfayQwLUK4"
+	specNationalityFR       = "WyJsa2x4RjVqTVlsR1RQVW92TU5JdkNBIiwgIkZSIl0"
+	specNationalityFRDigest = "w0I8EKcdCtUPkGCNUrfwVp2xEgNjtoIDlOxc9-PlOhs"
+)
+
+func TestSDJWT_SpecExamples(t *testing.T) {
+	assert.Equal(t, specGivenNameDigest, sdDigest(crypto.SHA256, specGivenNameDisclosure))
+	assert.Equal(t, specNationalityFRDigest, sdDigest(crypto.SHA256, specNationalityFR))
+
+	processor := NewSDJWTProcessor(did.NewDefaultKeyManager(), nil)
+
+	givenName, err := processor.parseDisclosure(specGivenNameDisclosure)
+	require.NoError(t, err)
+	assert.Equal(t, "given_name", givenName.Claim)
+	assert.Equal(t, "John", givenName.Value)
+
+	nationality, err := processor.parseDisclosure(specNationalityFR)
+	require.NoError(t, err)
+	assert.True(t, nationality.ArrayElement)
+	assert.Equal(t, "FR", nationality.Value)
+
+	decoy := sdDigest(crypto.SHA256, "decoy")
+	credential := &SDJWTCredential{
+		Claims: map[string]interface{}{
+			"iss":     "https://issuer.example.com",
+			"_sd_alg": "sha-256",
+			"_sd":     []interface{}{specGivenNameDigest, decoy},
+			"nationalities": []interface{}{
+				map[string]interface{}{"...": specNationalityFRDigest},
+				map[string]interface{}{"...": decoy + "x"},
+				"DE",
+			},
+		},
+		Disclosures: []Disclosure{*givenName, *nationality},
+	}
+
+	processed, err := processor.ProcessDisclosures(credential)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"iss":           "https://issuer.example.com",
+		"given_name":    "John",
+		"nationalities": []interface{}{"FR", "DE"},
+	}, processed.Claims)
+	assert.True(t, processed.isDisclosed([]interface{}{"given_name"}))
+	assert.True(t, processed.isDisclosed([]interface{}{"nationalities", 0}))
+	assert.False(t, processed.isDisclosed([]interface{}{"nationalities", 1}))
+
+	// Withholding a disclosure drops the claim and the array element
+	credential.Disclosures = nil
+	processed, err = processor.ProcessDisclosures(credential)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"DE"}, processed.Claims["nationalities"])
+	assert.NotContains(t, processed.Claims, "given_name")
+
+	// A disclosure the payload does not reference is rejected
+	credential.Disclosures = []Disclosure{*givenName, *nationality}
+	credential.Claims["_sd"] = []interface{}{decoy}
+	_, err = processor.ProcessDisclosures(credential)
+	assert.Error(t, err)
+
+	// So is a digest that appears twice
+	credential.Claims["_sd"] = []interface{}{specGivenNameDigest, specGivenNameDigest}
+	_, err = processor.ProcessDisclosures(credential)
+	assert.Error(t, err)
+
+	// And a disclosure that would overwrite a plain claim
+	credential.Claims["_sd"] = []interface{}{specGivenNameDigest}
+	credential.Claims["given_name"] = "Jane"
+	_, err = processor.ProcessDisclosures(credential)
+	assert.Error(t, err)
+}
+
+func sdjwtTestSetup(t *testing.T) (*SDJWTProcessor, *did.CreationResult) {
+	t.Helper()
+
+	keyManager := did.NewDefaultKeyManager()
+	keyResolver := did.NewKeyMethodResolver(keyManager)
+	issuer, err := keyResolver.Create(context.Background(), nil)
+	require.NoError(t, err)
+
+	resolver := did.NewMultiDIDResolver()
+	require.NoError(t, resolver.RegisterMethod("key", keyResolver))
+
+	return NewSDJWTProcessor(keyManager, resolver), issuer
+}
+
+func TestSDJWT_IssueAndVerify(t *testing.T) {
+	processor, issuer := sdjwtTestSetup(t)
+	keyManager := did.NewDefaultKeyManager()
+
+	holderKey, err := keyManager.GenerateKey(did.KeyTypeEd25519)
+	require.NoError(t, err)
+	holderJWK, err := keyManager.KeyToJWK(holderKey)
+	require.NoError(t, err)
+
+	template := &CredentialTemplate{
+		Type:   []string{"VerifiableCredential", "IdentityCredential"},
+		Issuer: issuer.DID,
+		CredentialSubject: map[string]interface{}{
+			"id":          "did:example:holder",
+			"given_name":  "Erika",
+			"family_name": "Mustermann",
+			"address": map[string]interface{}{
+				"street_address": "Heidestraße 17",
+				"locality":       "Köln",
+				"country":        "DE",
+			},
+			"nationalities": []interface{}{"DE", "FR"},
+		},
+		SelectivelyDisclosable: []string{"given_name", "address", "address.street_address", "nationalities[*]"},
+	}
+	options := &IssuanceOptions{
+		KeyID:             issuer.DIDDocument.VerificationMethod[0].ID,
+		Algorithm:         "EdDSA",
+		RequireKeyBinding: true,
+		HolderKey:         holderJWK,
+		SDAlgorithm:       "sha-384",
+		DecoyDigests:      2,
+	}
+
+	sdjwt, err := processor.CreateSDJWT(template, options, issuer.PrivateKey)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(sdjwt, "~"))
+
+	parsed, err := processor.ParseSDJWT(sdjwt)
+	require.NoError(t, err)
+	assert.Equal(t, SDJWTVCType, parsed.Header.Type)
+	assert.Equal(t, "IdentityCredential", parsed.Claims["vct"])
+	assert.Equal(t, "sha-384", parsed.Claims["_sd_alg"])
+	assert.Equal(t, "did:example:holder", parsed.Claims["sub"])
+	assert.Equal(t, "Mustermann", parsed.Claims["family_name"])
+	assert.NotContains(t, parsed.Claims, "given_name")
+	assert.NotContains(t, parsed.Claims, "address")
+	// given_name, address and two decoys
+	assert.Len(t, parsed.Claims["_sd"], 4)
+	assert.Len(t, parsed.Disclosures, 5)
+	cnf := parsed.Claims["cnf"].(map[string]interface{})["jwk"].(map[string]interface{})
+	assert.NotContains(t, cnf, "d")
+
+	// The street address disclosure is nested in the address disclosure
+	for _, disclosure := range parsed.Disclosures {
+		if disclosure.Claim == "address" {
+			address := disclosure.Value.(map[string]interface{})
+			assert.NotContains(t, address, "street_address")
+			assert.Len(t, address["_sd"], 3)
+			assert.Equal(t, "Köln", address["locality"])
+		}
+	}
+
+	// Presenting every disclosure with key binding
+	kb, err := processor.CreateKeyBindingJWT(sdjwt, "https://verifier.example", "n-0S6_WzA2Mj", holderKey)
+	require.NoError(t, err)
+	verifyOptions := &VerificationOptions{Challenge: "n-0S6_WzA2Mj", Domain: "https://verifier.example"}
+
+	result, err := processor.VerifySDJWT(sdjwt+kb, verifyOptions)
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.Error)
+	claims := result.Details["disclosed_claims"].(map[string]interface{})
+	assert.Equal(t, "Erika", claims["given_name"])
+	assert.Equal(t, "Heidestraße 17", claims["address"].(map[string]interface{})["street_address"])
+	assert.Equal(t, []interface{}{"DE", "FR"}, claims["nationalities"])
+	assert.NotContains(t, claims, "_sd_alg")
+
+	// Presenting only the address without its street and one nationality
+	parts := strings.Split(strings.TrimSuffix(sdjwt, "~"), "~")
+	selected := parts[0] + "~"
+	for _, disclosure := range parsed.Disclosures {
+		if disclosure.Claim == "address" || (disclosure.ArrayElement && disclosure.Value == "FR") {
+			selected += disclosure.Encoded + "~"
+		}
+	}
+	kb, err = processor.CreateKeyBindingJWT(selected, "https://verifier.example", "n-0S6_WzA2Mj", holderKey)
+	require.NoError(t, err)
+	result, err = processor.VerifySDJWT(selected+kb, verifyOptions)
+	require.NoError(t, err)
+	require.True(t, result.Verified, result.Error)
+	claims = result.Details["disclosed_claims"].(map[string]interface{})
+	assert.NotContains(t, claims, "given_name")
+	assert.Equal(t, map[string]interface{}{"locality": "Köln", "country": "DE"}, claims["address"])
+	assert.Equal(t, []interface{}{"FR"}, claims["nationalities"])
+
+	// The key binding JWT covers exactly the disclosures it was made for
+	result, err = processor.VerifySDJWT(sdjwt+kb, verifyOptions)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "sd_hash")
+
+	// Nonce and audience are checked
+	result, err = processor.VerifySDJWT(selected+kb, &VerificationOptions{Challenge: "other"})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+
+	// A credential bound to the holder's key is not accepted without key
+	// binding, even when the verifier asks for no challenge
+	result, err = processor.VerifySDJWT(selected, nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "key binding")
+
+	// A disclosure that is not the issuer's fails
+	forged, err := json.Marshal([]interface{}{"salt", "given_name", "Eve"})
+	require.NoError(t, err)
+	forgedPresentation := sdjwt + base64.RawURLEncoding.EncodeToString(forged) + "~"
+	kb, err = processor.CreateKeyBindingJWT(forgedPresentation, "https://verifier.example", "n-0S6_WzA2Mj", holderKey)
+	require.NoError(t, err)
+	result, err = processor.VerifySDJWT(forgedPresentation+kb, verifyOptions)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.NotContains(t, result.Error, "key binding")
+
+	// Without a confirmation key the credential verifies on its own, but not
+	// when the verifier asks for a fresh presentation
+	options.RequireKeyBinding = false
+	options.HolderKey = nil
+	unbound, err := processor.CreateSDJWT(template, options, issuer.PrivateKey)
+	require.NoError(t, err)
+	result, err = processor.VerifySDJWT(unbound, nil)
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+	result, err = processor.VerifySDJWT(unbound, &VerificationOptions{Challenge: "n-0S6_WzA2Mj"})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	result, err = processor.VerifySDJWT(unbound, &VerificationOptions{Domain: "https://verifier.example"})
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	options.RequireKeyBinding = true
+	options.HolderKey = holderJWK
+
+	// Registered claims cannot be selectively disclosable
+	template.SelectivelyDisclosable = []string{"cnf"}
+	_, err = processor.CreateSDJWT(template, options, issuer.PrivateKey)
+	assert.Error(t, err)
+
+	// Nor can an unknown digest algorithm be used
+	template.SelectivelyDisclosable = nil
+	options.SDAlgorithm = "md5"
+	_, err = processor.CreateSDJWT(template, options, issuer.PrivateKey)
+	assert.Error(t, err)
+}
+
+func TestSDJWT_TypeMetadata(t *testing.T) {
+	processor, issuer := sdjwtTestSetup(t)
+
+	registry := NewTypeMetadataRegistry(nil)
+	require.NoError(t, registry.Register(&TypeMetadata{
+		VCT:    "https://credentials.example.com/identity_credential",
+		Schema: json.RawMessage(`{"type": "object", "required": ["family_name"]}`),
+		Claims: []ClaimMetadata{
+			{Path: []interface{}{"given_name"}, SD: ClaimSDAlways},
+			{Path: []interface{}{"nationalities", nil}, SD: ClaimSDAlways},
+		},
+	}))
+	require.NoError(t, registry.Register(&TypeMetadata{
+		VCT:     "https://credentials.example.com/residence_credential",
+		Extends: "https://credentials.example.com/identity_credential",
+		Claims: []ClaimMetadata{
+			{Path: []interface{}{"family_name"}, SD: ClaimSDNever},
+		},
+	}))
+	processor.SetTypeMetadataRegistry(registry)
+
+	issue := func(subject map[string]interface{}, disclosable ...string) string {
+		sdjwt, err := processor.CreateSDJWT(&CredentialTemplate{
+			VCT:                    "https://credentials.example.com/residence_credential",
+			Issuer:                 issuer.DID,
+			CredentialSubject:      subject,
+			SelectivelyDisclosable: disclosable,
+		}, &IssuanceOptions{KeyID: issuer.DIDDocument.VerificationMethod[0].ID, Algorithm: "EdDSA"}, issuer.PrivateKey)
+		require.NoError(t, err)
+		return sdjwt
+	}
+	subject := func() map[string]interface{} {
+		return map[string]interface{}{
+			"given_name":    "Erika",
+			"family_name":   "Mustermann",
+			"nationalities": []interface{}{"DE"},
+		}
+	}
+
+	result, err := processor.VerifySDJWT(issue(subject(), "given_name", "nationalities[0]"), nil)
+	require.NoError(t, err)
+	assert.True(t, result.Verified, result.Error)
+
+	// given_name must always be disclosable, as inherited from the parent type
+	result, err = processor.VerifySDJWT(issue(subject(), "nationalities[0]"), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "given_name")
+
+	// family_name must never be
+	result, err = processor.VerifySDJWT(issue(subject(), "given_name", "nationalities[0]", "family_name"), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "family_name")
+
+	// The parent's schema applies
+	noFamily := subject()
+	delete(noFamily, "family_name")
+	result, err = processor.VerifySDJWT(issue(noFamily, "given_name", "nationalities[0]"), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Verified)
+	assert.Contains(t, result.Error, "schema")
+
+	// Integrity digests are checked when the type is resolved
+	_, err = registry.Resolve("https://credentials.example.com/identity_credential", ComputeDigestSRI([]byte("other")))
+	assert.Error(t, err)
+	_, err = registry.Resolve("https://credentials.example.com/unknown", "")
+	assert.Error(t, err)
+}
+
+// Ensure the key resolved for a did:key issuer is usable by the key manager
+func TestSDJWT_ExtractPublicKeyFromVM(t *testing.T) {
+	processor, issuer := sdjwtTestSetup(t)
+	key, err := processor.resolvePublicKey(issuer.DID, "")
+	require.NoError(t, err)
+	assert.IsType(t, ed25519.PublicKey{}, key)
+}
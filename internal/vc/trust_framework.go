@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -31,18 +32,22 @@ type TrustPolicy struct {
 	Actions     []string          `json:"actions"` // "accept", "reject", "review"
 	Priority    int               `json:"priority"`
 	Description string            `json:"description,omitempty"`
+	// Conditions are named policy expressions that must all hold for the
+	// policy to apply to a credential
 	Conditions  map[string]interface{} `json:"conditions,omitempty"`
 }
 
 // TrustRule defines specific validation rules
 type TrustRule struct {
 	ID          string            `json:"id"`
-	Type        string            `json:"type"` // "allowlist", "blocklist", "schema", "expiry", "revocation"
+	Type        string            `json:"type"` // "allowlist", "blocklist", "schema", "expiry", "revocation", "expression"
 	Field       string            `json:"field,omitempty"`
 	Operator    string            `json:"operator"` // "equals", "contains", "regex", "exists", "before", "after"
 	Value       interface{}       `json:"value,omitempty"`
 	Required    bool              `json:"required"`
 	Description string            `json:"description,omitempty"`
+	// Expression is the policy expression of an "expression" rule
+	Expression  string            `json:"expression,omitempty"`
 }
 
 // TrustedIssuer defines trust configuration for an issuer
@@ -71,6 +76,25 @@ const (
 type TrustFrameworkEngine struct {
 	frameworks  map[string]*TrustFramework
 	policyCache map[string]*PolicyDecision
+	expressions map[string]*PolicyExpression
+	mu          sync.RWMutex
+}
+
+// PolicyInput is what policy expressions see beyond the credential and the
+// framework's issuer entry
+type PolicyInput struct {
+	// Credentials presented together with the evaluated one
+	Credentials []*VerifiableCredential
+	// StatusChecked and Revoked report the credential status check.
+	// StatusError says why a checked status could not be determined, in
+	// which case Revoked is unknown rather than false.
+	StatusChecked bool
+	Revoked       bool
+	StatusError   string
+	// Score is the subject's trust score, when known
+	Score *float64
+	// Now is the evaluation time, by default the current time
+	Now time.Time
 }
 
 // PolicyDecision represents the result of policy evaluation
@@ -101,6 +125,7 @@ func NewTrustFrameworkEngine() *TrustFrameworkEngine {
 	return &TrustFrameworkEngine{
 		frameworks:  make(map[string]*TrustFramework),
 		policyCache: make(map[string]*PolicyDecision),
+		expressions: make(map[string]*PolicyExpression),
 	}
 }
 
@@ -115,12 +140,27 @@ func (tfe *TrustFrameworkEngine) LoadFramework(framework *TrustFramework) error
 		return fmt.Errorf("framework validation failed: %w", err)
 	}
 
+	tfe.mu.Lock()
 	tfe.frameworks[framework.ID] = framework
+	tfe.mu.Unlock()
 	return nil
 }
 
+// UnloadFramework removes a trust framework, reporting whether it was loaded
+func (tfe *TrustFrameworkEngine) UnloadFramework(frameworkID string) bool {
+	tfe.mu.Lock()
+	defer tfe.mu.Unlock()
+
+	_, exists := tfe.frameworks[frameworkID]
+	delete(tfe.frameworks, frameworkID)
+	return exists
+}
+
 // GetFramework retrieves a trust framework by ID
 func (tfe *TrustFrameworkEngine) GetFramework(frameworkID string) (*TrustFramework, error) {
+	tfe.mu.RLock()
+	defer tfe.mu.RUnlock()
+
 	framework, exists := tfe.frameworks[frameworkID]
 	if !exists {
 		return nil, fmt.Errorf("trust framework not found: %s", frameworkID)
@@ -130,6 +170,9 @@ func (tfe *TrustFrameworkEngine) GetFramework(frameworkID string) (*TrustFramewo
 
 // ListFrameworks returns all loaded trust frameworks
 func (tfe *TrustFrameworkEngine) ListFrameworks() []*TrustFramework {
+	tfe.mu.RLock()
+	defer tfe.mu.RUnlock()
+
 	frameworks := make([]*TrustFramework, 0, len(tfe.frameworks))
 	for _, framework := range tfe.frameworks {
 		frameworks = append(frameworks, framework)
@@ -143,6 +186,18 @@ func (tfe *TrustFrameworkEngine) EvaluateCredential(
 	credential *VerifiableCredential,
 	frameworkID string,
 ) (*PolicyDecision, error) {
+	return tfe.EvaluateCredentialWithInput(ctx, credential, frameworkID, nil)
+}
+
+// EvaluateCredentialWithInput evaluates a credential against trust
+// framework policies, giving policy expressions the presented credentials,
+// status and trust score in input
+func (tfe *TrustFrameworkEngine) EvaluateCredentialWithInput(
+	ctx context.Context,
+	credential *VerifiableCredential,
+	frameworkID string,
+	input *PolicyInput,
+) (*PolicyDecision, error) {
 
 	framework, err := tfe.GetFramework(frameworkID)
 	if err != nil {
@@ -173,9 +228,30 @@ func (tfe *TrustFrameworkEngine) EvaluateCredential(
 	highestPriorityDecision := decision
 	highestPriority := -1
 
+	env := &policyEnvironment{credential: credential, framework: framework, input: input}
+	var conditionFailure *PolicyViolation
 	for _, policy := range framework.Policies {
 		if policy.Type == "credential" || policy.Type == "issuer" {
-			policyDecision := tfe.evaluatePolicy(credential, &policy, framework)
+			applies, err := tfe.policyApplies(&policy, env)
+			if err != nil {
+				violation := PolicyViolation{
+					RuleID:      policy.ID,
+					Field:       "conditions",
+					Expected:    "conditions that evaluate",
+					Actual:      "error: " + err.Error(),
+					Severity:    "critical",
+					Description: "Policy conditions could not be evaluated",
+				}
+				decision.Violations = append(decision.Violations, violation)
+				if conditionFailure == nil {
+					conditionFailure = &violation
+				}
+				continue
+			}
+			if !applies {
+				continue
+			}
+			policyDecision := tfe.evaluatePolicy(credential, &policy, framework, env)
 			
 			// Use highest priority policy decision
 			if policy.Priority > highestPriority {
@@ -196,6 +272,15 @@ func (tfe *TrustFrameworkEngine) EvaluateCredential(
 		decision.Confidence = highestPriorityDecision.Confidence
 	}
 
+	// A policy that could not be checked outranks every decision
+	if conditionFailure != nil {
+		decision.Decision = "reject"
+		decision.Reason = "Policy " + conditionFailure.RuleID + " " + conditionFailure.Actual
+		decision.PolicyID = conditionFailure.RuleID
+		decision.Confidence = 0
+		return decision, nil
+	}
+
 	// Default to accept if no explicit reject
 	if decision.Decision == "reject" && len(decision.Violations) == 0 {
 		decision.Decision = "accept"
@@ -285,6 +370,7 @@ func (tfe *TrustFrameworkEngine) evaluatePolicy(
 	credential *VerifiableCredential,
 	policy *TrustPolicy,
 	framework *TrustFramework,
+	env *policyEnvironment,
 ) *PolicyDecision {
 
 	decision := &PolicyDecision{
@@ -299,7 +385,7 @@ func (tfe *TrustFrameworkEngine) evaluatePolicy(
 
 	// Evaluate each rule
 	for _, rule := range policy.Rules {
-		if !tfe.evaluateRule(credential, &rule, decision) {
+		if !tfe.evaluateRule(credential, &rule, decision, env) {
 			// Rule failed
 			if rule.Required {
 				decision.Decision = "reject"
@@ -330,6 +416,7 @@ func (tfe *TrustFrameworkEngine) evaluateRule(
 	credential *VerifiableCredential,
 	rule *TrustRule,
 	decision *PolicyDecision,
+	env *policyEnvironment,
 ) bool {
 
 	switch rule.Type {
@@ -343,6 +430,8 @@ func (tfe *TrustFrameworkEngine) evaluateRule(
 		return tfe.evaluateExpiryRule(credential, rule, decision)
 	case "revocation":
 		return tfe.evaluateRevocationRule(credential, rule, decision)
+	case "expression":
+		return tfe.evaluateExpressionRule(rule, decision, env)
 	default:
 		// Unknown rule type, skip
 		return true
@@ -437,12 +526,17 @@ func (tfe *TrustFrameworkEngine) evaluateExpiryRule(
 	rule *TrustRule,
 	decision *PolicyDecision,
 ) bool {
+	// VCDM 2.0 credentials express expiry as validUntil
+	field, value := "expirationDate", credential.ExpirationDate
+	if credential.DataModelVersion() == DataModelV2 {
+		field, value = "validUntil", credential.ValidUntil
+	}
 
-	if credential.ExpirationDate == "" {
+	if value == "" {
 		if rule.Required {
 			decision.Violations = append(decision.Violations, PolicyViolation{
 				RuleID:      rule.ID,
-				Field:       "expirationDate",
+				Field:       field,
 				Expected:    "expiration date required",
 				Actual:      "missing",
 				Severity:    "medium",
@@ -453,13 +547,13 @@ func (tfe *TrustFrameworkEngine) evaluateExpiryRule(
 		return true
 	}
 
-	expiryTime, err := time.Parse(time.RFC3339, credential.ExpirationDate)
+	expiryTime, err := time.Parse(time.RFC3339, value)
 	if err != nil {
 		decision.Violations = append(decision.Violations, PolicyViolation{
 			RuleID:      rule.ID,
-			Field:       "expirationDate",
+			Field:       field,
 			Expected:    "valid RFC3339 date",
-			Actual:      credential.ExpirationDate,
+			Actual:      value,
 			Severity:    "high",
 			Description: "Invalid expiration date format",
 		})
@@ -469,7 +563,7 @@ func (tfe *TrustFrameworkEngine) evaluateExpiryRule(
 	if time.Now().After(expiryTime) {
 		decision.Violations = append(decision.Violations, PolicyViolation{
 			RuleID:      rule.ID,
-			Field:       "expirationDate",
+			Field:       field,
 			Expected:    "not expired",
 			Actual:      "expired on " + expiryTime.Format(time.RFC3339),
 			Severity:    "critical",
@@ -492,6 +586,158 @@ func (tfe *TrustFrameworkEngine) evaluateRevocationRule(
 	return true
 }
 
+// policyEnvironment holds the variables policy expressions evaluate over,
+// built once per credential evaluation
+type policyEnvironment struct {
+	credential *VerifiableCredential
+	framework  *TrustFramework
+	input      *PolicyInput
+	variables  map[string]interface{}
+	err        error
+}
+
+func (env *policyEnvironment) activation() (map[string]interface{}, error) {
+	if env.variables != nil || env.err != nil {
+		return env.variables, env.err
+	}
+
+	input := env.input
+	if input == nil {
+		input = &PolicyInput{}
+	}
+	now := input.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	credential, err := toJSONDocument(env.credential)
+	if err != nil {
+		env.err = err
+		return nil, err
+	}
+	// The evaluated credential comes first, and only once, even when it
+	// was decoded separately from the presented copy
+	credentials := []interface{}{credential}
+	seen := false
+	for _, presented := range input.Credentials {
+		document, err := toJSONDocument(presented)
+		if err != nil {
+			env.err = err
+			return nil, err
+		}
+		if !seen && jsonEqual(document, credential) {
+			seen = true
+			continue
+		}
+		credentials = append(credentials, document)
+	}
+
+	issuerID := extractIssuerID(env.credential.Issuer)
+	issuer := map[string]interface{}{"id": issuerID, "trusted": false, "trustLevel": string(TrustLevelUntrusted)}
+	for _, trustedIssuer := range env.framework.TrustedIssuers {
+		if trustedIssuer.DID != issuerID {
+			continue
+		}
+		document, err := toJSONDocument(trustedIssuer)
+		if err != nil {
+			env.err = err
+			return nil, err
+		}
+		issuer, _ = document.(map[string]interface{})
+		issuer["id"] = issuerID
+		issuer["trusted"] = true
+		break
+	}
+
+	statusType := ""
+	if env.credential.CredentialStatus != nil {
+		statusType = env.credential.CredentialStatus.Type
+	}
+	// An undetermined status has no revoked member, so expressions that
+	// read it fail rather than take the credential as unrevoked
+	status := map[string]interface{}{"checked": input.StatusChecked, "type": statusType}
+	if input.StatusError != "" {
+		status["error"] = input.StatusError
+	} else {
+		status["revoked"] = input.Revoked
+	}
+	var score interface{}
+	if input.Score != nil {
+		score = *input.Score
+	}
+
+	env.variables = map[string]interface{}{
+		"credential":  credential,
+		"issuer":      issuer,
+		"status":      status,
+		"score":       score,
+		"credentials": credentials,
+		"now":         now,
+	}
+	return env.variables, nil
+}
+
+// evaluateExpression runs a policy expression in the environment
+func (tfe *TrustFrameworkEngine) evaluateExpression(source string, env *policyEnvironment) (bool, error) {
+	expression, err := tfe.compileExpression(source)
+	if err != nil {
+		return false, err
+	}
+	variables, err := env.activation()
+	if err != nil {
+		return false, err
+	}
+	return expression.Evaluate(variables)
+}
+
+// policyApplies reports whether all of a policy's conditions hold. A
+// condition that cannot be evaluated is an error, which rejects the
+// credential rather than letting the policy be skipped or applied on a
+// guess.
+func (tfe *TrustFrameworkEngine) policyApplies(policy *TrustPolicy, env *policyEnvironment) (bool, error) {
+	for _, name := range sortedKeys(policy.Conditions) {
+		source, ok := policy.Conditions[name].(string)
+		if !ok {
+			return false, fmt.Errorf("condition %s is not an expression string", name)
+		}
+		holds, err := tfe.evaluateExpression(source, env)
+		if err != nil {
+			return false, fmt.Errorf("condition %s: %w", name, err)
+		}
+		if !holds {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// evaluateExpressionRule evaluates policy expression rules. An expression
+// that fails to evaluate is a violation like one that yields false.
+func (tfe *TrustFrameworkEngine) evaluateExpressionRule(
+	rule *TrustRule,
+	decision *PolicyDecision,
+	env *policyEnvironment,
+) bool {
+	holds, err := tfe.evaluateExpression(rule.Expression, env)
+	if err == nil && holds {
+		return true
+	}
+
+	actual := "false"
+	if err != nil {
+		actual = "error: " + err.Error()
+	}
+	decision.Violations = append(decision.Violations, PolicyViolation{
+		RuleID:      rule.ID,
+		Field:       "expression",
+		Expected:    rule.Expression,
+		Actual:      actual,
+		Severity:    "high",
+		Description: "Policy expression not satisfied",
+	})
+	return false
+}
+
 // Helper methods
 
 func (tfe *TrustFrameworkEngine) validateFramework(framework *TrustFramework) error {
@@ -501,9 +747,54 @@ func (tfe *TrustFrameworkEngine) validateFramework(framework *TrustFramework) er
 	if framework.Version == "" {
 		return fmt.Errorf("framework version is required")
 	}
+
+	// Compile every expression up front so a framework with a bad
+	// expression never loads
+	for _, policy := range framework.Policies {
+		for name, condition := range policy.Conditions {
+			source, ok := condition.(string)
+			if !ok {
+				return fmt.Errorf("policy %s condition %s must be an expression string", policy.ID, name)
+			}
+			if _, err := tfe.compileExpression(source); err != nil {
+				return fmt.Errorf("policy %s condition %s: %w", policy.ID, name, err)
+			}
+		}
+		for _, rule := range policy.Rules {
+			if rule.Type != "expression" {
+				continue
+			}
+			if rule.Expression == "" {
+				return fmt.Errorf("policy %s rule %s has no expression", policy.ID, rule.ID)
+			}
+			if _, err := tfe.compileExpression(rule.Expression); err != nil {
+				return fmt.Errorf("policy %s rule %s: %w", policy.ID, rule.ID, err)
+			}
+		}
+	}
 	return nil
 }
 
+// compileExpression returns the compiled form of an expression, compiling
+// it on first use
+func (tfe *TrustFrameworkEngine) compileExpression(source string) (*PolicyExpression, error) {
+	tfe.mu.RLock()
+	compiled, ok := tfe.expressions[source]
+	tfe.mu.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := CompilePolicyExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	tfe.mu.Lock()
+	tfe.expressions[source] = compiled
+	tfe.mu.Unlock()
+	return compiled, nil
+}
+
 func (tfe *TrustFrameworkEngine) getCredentialField(credential *VerifiableCredential, fieldPath string) interface{} {
 	// Convert credential to map and traverse path
 	credBytes, _ := json.Marshal(credential)
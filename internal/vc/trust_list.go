@@ -0,0 +1,505 @@
+package vc
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// TrustListJWSType is the typ header of a signed trust list
+const TrustListJWSType = "trustlist+jws"
+
+// maxTrustListSize bounds a fetched trust list document
+const maxTrustListSize = 4 << 20
+
+// trustListStateKey is the state store key holding the highest trust list
+// sequence accepted for each framework
+const trustListStateKey = "vc/trustlists"
+
+// TrustList is a versioned, expiring distribution of a trust framework.
+// Lists for the same framework are ordered by Sequence; a verifier never
+// goes back to a lower sequence.
+type TrustList struct {
+	Sequence  uint64          `json:"sequence"`
+	IssuedAt  time.Time       `json:"issuedAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Framework *TrustFramework `json:"framework"`
+}
+
+// trustListPayload encodes the list as canonical JSON, sorted keys and no
+// whitespace, so every signer and verifier agrees on the signed bytes
+func trustListPayload(list *TrustList) ([]byte, error) {
+	document, err := toJSONDocument(list)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(canonicalJSON(document)), nil
+}
+
+// SignTrustList signs a trust list as a compact JWS. The algorithm follows
+// the key: EdDSA for Ed25519, ES256/ES384/ES512 for ECDSA.
+func SignTrustList(list *TrustList, kid string, key crypto.Signer) (string, error) {
+	if list.Framework == nil {
+		return "", fmt.Errorf("trust list has no framework")
+	}
+	alg, err := jwsAlgorithmForKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]interface{}{"alg": coseAlgorithmName(alg), "kid": kid, "typ": TrustListJWSType})
+	if err != nil {
+		return "", err
+	}
+	payload, err := trustListPayload(list)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signCOSE(alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func jwsAlgorithmForKey(key crypto.PublicKey) (int64, error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return COSEAlgEdDSA, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return COSEAlgES256, nil
+		case 384:
+			return COSEAlgES384, nil
+		case 521:
+			return COSEAlgES512, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported trust list signing key %T", key)
+}
+
+func coseAlgorithmFromName(name string) (int64, bool) {
+	for _, alg := range []int64{COSEAlgEdDSA, COSEAlgES256, COSEAlgES384, COSEAlgES512} {
+		if coseAlgorithmName(alg) == name {
+			return alg, true
+		}
+	}
+	return 0, false
+}
+
+// TrustListVerifier checks trust list signatures against the keys of the
+// trust list operators. Each key is bound to the frameworks its operator
+// publishes, so one operator cannot replace another's framework.
+type TrustListVerifier struct {
+	keys map[string]*trustListKey
+	mu   sync.RWMutex
+}
+
+type trustListKey struct {
+	key        crypto.PublicKey
+	frameworks map[string]bool
+}
+
+// NewTrustListVerifier creates a verifier with no trusted signing keys
+func NewTrustListVerifier() *TrustListVerifier {
+	return &TrustListVerifier{keys: make(map[string]*trustListKey)}
+}
+
+// AddKey trusts key to sign, under the given kid, the trust lists of the
+// given frameworks. Adding a kid again replaces its key and frameworks.
+func (v *TrustListVerifier) AddKey(kid string, key crypto.PublicKey, frameworkIDs ...string) error {
+	if _, err := jwsAlgorithmForKey(key); err != nil {
+		return err
+	}
+	if len(frameworkIDs) == 0 {
+		return fmt.Errorf("trust list key %q is not bound to any framework", kid)
+	}
+
+	frameworks := make(map[string]bool, len(frameworkIDs))
+	for _, id := range frameworkIDs {
+		if id == "" {
+			return fmt.Errorf("trust list key %q has an empty framework id", kid)
+		}
+		frameworks[id] = true
+	}
+
+	v.mu.Lock()
+	v.keys[kid] = &trustListKey{key: key, frameworks: frameworks}
+	v.mu.Unlock()
+	return nil
+}
+
+// Verify checks a signed trust list's signature, encoding, validity period
+// and framework
+func (v *TrustListVerifier) Verify(jws []byte, now time.Time) (*TrustList, error) {
+	parts := strings.Split(strings.TrimSpace(string(jws)), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("trust list must be a compact JWS")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust list header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("invalid trust list header: %w", err)
+	}
+	if header.Typ != TrustListJWSType {
+		return nil, fmt.Errorf("unexpected trust list typ %q", header.Typ)
+	}
+	alg, ok := coseAlgorithmFromName(header.Alg)
+	if !ok {
+		return nil, fmt.Errorf("unsupported trust list algorithm %q", header.Alg)
+	}
+
+	v.mu.RLock()
+	signer, ok := v.keys[header.Kid]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("trust list signed by unknown key %q", header.Kid)
+	}
+	key := signer.key
+	if expected, _ := jwsAlgorithmForKey(key); expected != alg {
+		return nil, fmt.Errorf("algorithm %s does not match key %q", header.Alg, header.Kid)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust list signature encoding: %w", err)
+	}
+	if err := verifyCOSESignature(alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, fmt.Errorf("trust list signature: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust list payload: %w", err)
+	}
+	var list TrustList
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, fmt.Errorf("invalid trust list payload: %w", err)
+	}
+	if list.Framework == nil {
+		return nil, fmt.Errorf("trust list has no framework")
+	}
+	if !signer.frameworks[list.Framework.ID] {
+		return nil, fmt.Errorf("key %q is not trusted for framework %q", header.Kid, list.Framework.ID)
+	}
+	if canonical, err := trustListPayload(&list); err != nil || !bytes.Equal(canonical, payload) {
+		return nil, fmt.Errorf("trust list payload is not canonical JSON")
+	}
+
+	if list.ExpiresAt.IsZero() || !list.ExpiresAt.After(list.IssuedAt) {
+		return nil, fmt.Errorf("trust list must expire after it is issued")
+	}
+	if now.Before(list.IssuedAt) {
+		return nil, fmt.Errorf("trust list is issued in the future")
+	}
+	if !now.Before(list.ExpiresAt) {
+		return nil, fmt.Errorf("trust list expired at %s", list.ExpiresAt.Format(time.RFC3339))
+	}
+	return &list, nil
+}
+
+// TrustListSource supplies the current signed trust list of one framework
+type TrustListSource interface {
+	Fetch(ctx context.Context) ([]byte, error)
+	String() string
+}
+
+// FileTrustListSource reads a signed trust list from disk
+type FileTrustListSource struct {
+	Path string
+}
+
+// Fetch reads the file
+func (s *FileTrustListSource) Fetch(ctx context.Context) ([]byte, error) {
+	return os.ReadFile(s.Path)
+}
+
+func (s *FileTrustListSource) String() string {
+	return "file:" + s.Path
+}
+
+// BlobGetter reads content-addressed blobs, as the full node blob store does
+type BlobGetter interface {
+	Get(ctx context.Context, cid string) ([]byte, error)
+}
+
+// BlobTrustListSource reads a signed trust list from a blob store. CID is
+// consulted on every fetch, so publishing a new list means pointing it at
+// the new blob.
+type BlobTrustListSource struct {
+	Blobs BlobGetter
+	CID   func(ctx context.Context) (string, error)
+}
+
+// Fetch reads the blob the CID function currently names
+func (s *BlobTrustListSource) Fetch(ctx context.Context) ([]byte, error) {
+	cid, err := s.CID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Blobs.Get(ctx, cid)
+}
+
+func (s *BlobTrustListSource) String() string {
+	return "blob"
+}
+
+// HTTPTrustListSource fetches a signed trust list from a registry URL
+type HTTPTrustListSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewFullNodeTrustListSource fetches a trust list blob from a full node's
+// blob API
+func NewFullNodeTrustListSource(baseURL, cid string) *HTTPTrustListSource {
+	return &HTTPTrustListSource{URL: strings.TrimSuffix(baseURL, "/") + "/v1/blobs/" + cid}
+}
+
+// Fetch downloads the trust list
+func (s *HTTPTrustListSource) Fetch(ctx context.Context) ([]byte, error) {
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("trust list registry returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTrustListSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTrustListSize {
+		return nil, fmt.Errorf("trust list exceeds %d bytes", maxTrustListSize)
+	}
+	return data, nil
+}
+
+func (s *HTTPTrustListSource) String() string {
+	return s.URL
+}
+
+// TrustListManager keeps an engine's frameworks in step with their signed
+// trust lists. A source that fails to fetch or verify leaves the framework
+// it last supplied in place until that list expires.
+type TrustListManager struct {
+	engine   *TrustFrameworkEngine
+	verifier *TrustListVerifier
+	sources  []TrustListSource
+	loaded   map[string]*loadedTrustList
+	marks    map[string]*trustListMark
+	state    store.StateStore
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+type loadedTrustList struct {
+	list   *TrustList
+	digest [32]byte
+}
+
+// trustListMark is the highest trust list accepted for a framework. It
+// outlives the loaded list, so an expired or restarted manager still
+// refuses older or conflicting lists.
+type trustListMark struct {
+	Sequence uint64 `json:"sequence"`
+	Digest   string `json:"digest"`
+}
+
+// NewTrustListManager creates a manager loading verified lists into engine
+func NewTrustListManager(engine *TrustFrameworkEngine, verifier *TrustListVerifier) *TrustListManager {
+	return &TrustListManager{
+		engine:   engine,
+		verifier: verifier,
+		loaded:   make(map[string]*loadedTrustList),
+		marks:    make(map[string]*trustListMark),
+		now:      time.Now,
+	}
+}
+
+// SetStateStore loads the sequence high-water marks kept in a state store
+// and records new ones in it, so a restart cannot roll a framework back
+func (m *TrustListManager) SetStateStore(stateStore store.StateStore) error {
+	data, err := stateStore.GetState(context.Background(), trustListStateKey)
+	if err != nil && !store.IsNotFound(err) {
+		return fmt.Errorf("failed to load trust list marks: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if data != nil {
+		var marks map[string]*trustListMark
+		if err := json.Unmarshal(data, &marks); err != nil {
+			return fmt.Errorf("failed to decode trust list marks: %w", err)
+		}
+		for id, mark := range marks {
+			if current, ok := m.marks[id]; !ok || mark.Sequence > current.Sequence {
+				m.marks[id] = mark
+			}
+		}
+	}
+
+	m.state = stateStore
+	return nil
+}
+
+func (m *TrustListManager) save(ctx context.Context) error {
+	if m.state == nil {
+		return nil
+	}
+	data, err := json.Marshal(m.marks)
+	if err != nil {
+		return fmt.Errorf("failed to encode trust list marks: %w", err)
+	}
+	if err := m.state.PutState(ctx, trustListStateKey, data); err != nil {
+		return fmt.Errorf("failed to save trust list marks: %w", err)
+	}
+	return nil
+}
+
+// AddSource registers a trust list source; it is read on the next Reload
+func (m *TrustListManager) AddSource(source TrustListSource) {
+	m.mu.Lock()
+	m.sources = append(m.sources, source)
+	m.mu.Unlock()
+}
+
+// Loaded returns the trust list a framework was last loaded from
+func (m *TrustListManager) Loaded(frameworkID string) (*TrustList, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	loaded, ok := m.loaded[frameworkID]
+	if !ok {
+		return nil, false
+	}
+	return loaded.list, true
+}
+
+// Reload fetches every source and loads lists newer than the engine holds,
+// then unloads frameworks whose list has expired. Per-source failures are
+// joined into the returned error.
+func (m *TrustListManager) Reload(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var errs []error
+	for _, source := range m.sources {
+		if err := m.reloadSource(ctx, source, now); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+
+	for id, loaded := range m.loaded {
+		if !now.Before(loaded.list.ExpiresAt) {
+			m.engine.UnloadFramework(id)
+			delete(m.loaded, id)
+			errs = append(errs, fmt.Errorf("trust list for framework %s expired", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *TrustListManager) reloadSource(ctx context.Context, source TrustListSource, now time.Time) error {
+	data, err := source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := m.verifier.Verify(data, now)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(data)
+	id := list.Framework.ID
+	mark := m.marks[id]
+	if mark != nil {
+		switch {
+		case list.Sequence < mark.Sequence:
+			return fmt.Errorf("trust list sequence %d is older than accepted sequence %d", list.Sequence, mark.Sequence)
+		case list.Sequence == mark.Sequence:
+			if hex.EncodeToString(digest[:]) != mark.Digest {
+				return fmt.Errorf("conflicting trust lists for sequence %d", list.Sequence)
+			}
+			if _, ok := m.loaded[id]; ok {
+				return nil
+			}
+		}
+	}
+
+	// Record the mark before loading, so a framework is never in use
+	// without its sequence being durable
+	m.marks[id] = &trustListMark{Sequence: list.Sequence, Digest: hex.EncodeToString(digest[:])}
+	if err := m.save(ctx); err != nil {
+		if mark != nil {
+			m.marks[id] = mark
+		} else {
+			delete(m.marks, id)
+		}
+		return err
+	}
+
+	if err := m.engine.LoadFramework(list.Framework); err != nil {
+		return err
+	}
+	m.loaded[id] = &loadedTrustList{list: list, digest: digest}
+	return nil
+}
+
+// Run reloads on every interval until ctx is done, reporting reload
+// failures to onError when it is set
+func (m *TrustListManager) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Reload(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
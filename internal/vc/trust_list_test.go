@@ -0,0 +1,261 @@
+package vc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTrustList(sequence uint64, issuedAt time.Time, issuers ...string) *TrustList {
+	framework := &TrustFramework{
+		ID:      "kyc-partners",
+		Name:    "KYC partners",
+		Version: "2025." + strings.Repeat("1", int(sequence)),
+		Issuer:  "did:example:registry",
+	}
+	for _, issuer := range issuers {
+		framework.TrustedIssuers = append(framework.TrustedIssuers, TrustedIssuer{DID: issuer, TrustLevel: TrustLevelHigh})
+	}
+	return &TrustList{
+		Sequence:  sequence,
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(24 * time.Hour),
+		Framework: framework,
+	}
+}
+
+func TestTrustList_SignAndVerify(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	verifier := NewTrustListVerifier()
+	require.NoError(t, verifier.AddKey("ed", edKey.Public(), "kyc-partners", "x"))
+	require.NoError(t, verifier.AddKey("ec", &ecKey.PublicKey, "kyc-partners"))
+	require.NoError(t, verifier.AddKey("other", &ecKey.PublicKey, "other-framework"))
+	assert.ErrorContains(t, verifier.AddKey("unbound", edKey.Public()), "not bound")
+
+	list := testTrustList(1, now.Add(-time.Hour), "did:example:bank")
+	edJWS, err := SignTrustList(list, "ed", edKey)
+	require.NoError(t, err)
+	ecJWS, err := SignTrustList(list, "ec", ecKey)
+	require.NoError(t, err)
+	otherJWS, err := SignTrustList(list, "other", ecKey)
+	require.NoError(t, err)
+
+	for _, jws := range []string{edJWS, ecJWS} {
+		verified, err := verifier.Verify([]byte(jws), now)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), verified.Sequence)
+		assert.Equal(t, "did:example:bank", verified.Framework.TrustedIssuers[0].DID)
+	}
+
+	parts := strings.Split(edJWS, ".")
+	resign := func(header, payload string) string {
+		input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
+		return input + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(edKey, []byte(input)))
+	}
+
+	for name, tc := range map[string]struct {
+		jws string
+		at  time.Time
+		err string
+	}{
+		"tampered payload": {parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sequence":2}`)) + "." + parts[2], now, "signature"},
+		"unknown key":      {resign(`{"alg":"EdDSA","kid":"unknown","typ":"trustlist+jws"}`, "{}"), now, "unknown key"},
+		"wrong framework":  {otherJWS, now, "not trusted for framework"},
+		"wrong typ":        {resign(`{"alg":"EdDSA","kid":"ed","typ":"JWT"}`, "{}"), now, "typ"},
+		"alg mismatch":     {resign(`{"alg":"ES256","kid":"ed","typ":"trustlist+jws"}`, "{}"), now, "does not match"},
+		"not canonical": {resign(`{"alg":"EdDSA","kid":"ed","typ":"trustlist+jws"}`,
+			`{"sequence": 1, "issuedAt":"2025-06-01T11:00:00Z","expiresAt":"2025-06-02T11:00:00Z","framework":{"id":"x","name":"x","version":"1"}}`), now, "canonical"},
+		"expired":   {edJWS, now.Add(48 * time.Hour), "expired"},
+		"future":    {edJWS, now.Add(-2 * time.Hour), "future"},
+		"malformed": {"abc", now, "compact JWS"},
+	} {
+		_, err := verifier.Verify([]byte(tc.jws), tc.at)
+		assert.ErrorContains(t, err, tc.err, name)
+	}
+}
+
+type memoryBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memoryBlobs) Get(ctx context.Context, cid string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[cid]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func TestTrustListManager_Reload(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifier := NewTrustListVerifier()
+	require.NoError(t, verifier.AddKey("registry", key.Public(), "kyc-partners"))
+
+	sign := func(list *TrustList) string {
+		jws, err := SignTrustList(list, "registry", key)
+		require.NoError(t, err)
+		return jws
+	}
+
+	engine := NewTrustFrameworkEngine()
+	manager := NewTrustListManager(engine, verifier)
+	manager.now = func() time.Time { return now }
+
+	path := filepath.Join(t.TempDir(), "kyc.jws")
+	require.NoError(t, os.WriteFile(path, []byte(sign(testTrustList(1, now.Add(-time.Hour), "did:example:bank"))), 0o600))
+	manager.AddSource(&FileTrustListSource{Path: path})
+	require.NoError(t, manager.Reload(context.Background()))
+
+	framework, err := engine.GetFramework("kyc-partners")
+	require.NoError(t, err)
+	assert.Len(t, framework.TrustedIssuers, 1)
+
+	t.Run("newer list is hot reloaded", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(sign(testTrustList(2, now.Add(-time.Minute), "did:example:bank", "did:example:telco"))), 0o600))
+		require.NoError(t, manager.Reload(context.Background()))
+		framework, err := engine.GetFramework("kyc-partners")
+		require.NoError(t, err)
+		assert.Len(t, framework.TrustedIssuers, 2)
+		list, ok := manager.Loaded("kyc-partners")
+		require.True(t, ok)
+		assert.Equal(t, uint64(2), list.Sequence)
+	})
+
+	t.Run("rollback is rejected", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte(sign(testTrustList(1, now.Add(-time.Hour), "did:example:bank"))), 0o600))
+		assert.ErrorContains(t, manager.Reload(context.Background()), "older")
+		framework, err := engine.GetFramework("kyc-partners")
+		require.NoError(t, err)
+		assert.Len(t, framework.TrustedIssuers, 2)
+	})
+
+	t.Run("expired list is unloaded", func(t *testing.T) {
+		now = now.Add(25 * time.Hour)
+		assert.ErrorContains(t, manager.Reload(context.Background()), "expired")
+		_, err := engine.GetFramework("kyc-partners")
+		assert.Error(t, err)
+	})
+}
+
+func TestTrustListManager_StateStore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifier := NewTrustListVerifier()
+	require.NoError(t, verifier.AddKey("registry", key.Public(), "kyc-partners"))
+
+	sign := func(list *TrustList) string {
+		jws, err := SignTrustList(list, "registry", key)
+		require.NoError(t, err)
+		return jws
+	}
+
+	config := store.DefaultConfig()
+	config.RocksDB.Path = t.TempDir()
+	stateStore, err := store.NewSQLiteStore(config)
+	require.NoError(t, err)
+	defer stateStore.Close()
+
+	newManager := func() *TrustListManager {
+		manager := NewTrustListManager(NewTrustFrameworkEngine(), verifier)
+		manager.now = func() time.Time { return now }
+		require.NoError(t, manager.SetStateStore(stateStore))
+		return manager
+	}
+
+	path := filepath.Join(t.TempDir(), "kyc.jws")
+	accepted := sign(testTrustList(2, now.Add(-time.Hour), "did:example:bank"))
+	require.NoError(t, os.WriteFile(path, []byte(accepted), 0o600))
+	manager := newManager()
+	manager.AddSource(&FileTrustListSource{Path: path})
+	require.NoError(t, manager.Reload(context.Background()))
+
+	restarted := newManager()
+	restarted.AddSource(&FileTrustListSource{Path: path})
+
+	require.NoError(t, os.WriteFile(path, []byte(sign(testTrustList(1, now.Add(-time.Hour), "did:example:bank"))), 0o600))
+	assert.ErrorContains(t, restarted.Reload(context.Background()), "older")
+
+	conflicting := testTrustList(2, now.Add(-time.Hour), "did:example:bank", "did:example:telco")
+	require.NoError(t, os.WriteFile(path, []byte(sign(conflicting)), 0o600))
+	assert.ErrorContains(t, restarted.Reload(context.Background()), "conflicting")
+	_, ok := restarted.Loaded("kyc-partners")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte(accepted), 0o600))
+	require.NoError(t, restarted.Reload(context.Background()))
+	list, ok := restarted.Loaded("kyc-partners")
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), list.Sequence)
+}
+
+func TestTrustListSources(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifier := NewTrustListVerifier()
+	require.NoError(t, verifier.AddKey("registry", key.Public(), "kyc-partners"))
+
+	list := testTrustList(1, now.Add(-time.Hour), "did:example:bank")
+	jws, err := SignTrustList(list, "registry", key)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/blobs/bafylist" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(jws))
+	}))
+	defer server.Close()
+
+	blobs := &memoryBlobs{blobs: map[string][]byte{"bafylist": []byte(jws)}}
+	sources := []TrustListSource{
+		NewFullNodeTrustListSource(server.URL+"/", "bafylist"),
+		&BlobTrustListSource{Blobs: blobs, CID: func(ctx context.Context) (string, error) { return "bafylist", nil }},
+	}
+	for _, source := range sources {
+		data, err := source.Fetch(context.Background())
+		require.NoError(t, err, source.String())
+		verified, err := verifier.Verify(data, now)
+		require.NoError(t, err, source.String())
+		assert.Equal(t, "kyc-partners", verified.Framework.ID)
+	}
+
+	missing := &HTTPTrustListSource{URL: server.URL + "/v1/blobs/unknown"}
+	_, err = missing.Fetch(context.Background())
+	assert.ErrorContains(t, err, "404")
+
+	// A default verifier's engine can be kept current by a manager
+	credentialVerifier := NewDefaultCredentialVerifier(nil, nil)
+	manager := NewTrustListManager(credentialVerifier.TrustEngine(), verifier)
+	manager.AddSource(sources[0])
+	require.NoError(t, manager.Reload(context.Background()))
+	_, err = credentialVerifier.GetTrustFramework("kyc-partners")
+	assert.NoError(t, err)
+}
@@ -2,6 +2,8 @@ package vc
 
 import (
 	"time"
+
+	"github.com/ParichayaHQ/credence/internal/did"
 )
 
 // VerifiableCredential represents a W3C Verifiable Credential
@@ -10,8 +12,10 @@ type VerifiableCredential struct {
 	ID                string                 `json:"id,omitempty"`
 	Type              []string               `json:"type"`
 	Issuer            interface{}            `json:"issuer"`
-	IssuanceDate      string                 `json:"issuanceDate"`
+	IssuanceDate      string                 `json:"issuanceDate,omitempty"`
 	ExpirationDate    string                 `json:"expirationDate,omitempty"`
+	ValidFrom         string                 `json:"validFrom,omitempty"`  // VCDM 2.0
+	ValidUntil        string                 `json:"validUntil,omitempty"` // VCDM 2.0
 	CredentialSubject interface{}            `json:"credentialSubject"`
 	Proof             interface{}            `json:"proof,omitempty"`
 	CredentialStatus  *CredentialStatus      `json:"credentialStatus,omitempty"`
@@ -22,6 +26,8 @@ type VerifiableCredential struct {
 	
 	// Additional fields for JWT representation
 	JWT string `json:"-"` // The JWT representation when applicable
+	// The issuer-signed CBOR when the credential was projected from an mdoc
+	Mdoc []byte `json:"-"`
 }
 
 // VerifiablePresentation represents a W3C Verifiable Presentation
@@ -58,8 +64,9 @@ type CredentialStatus struct {
 
 // CredentialSchema represents schema information for a credential
 type CredentialSchema struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	DigestSRI string `json:"digestSRI,omitempty"` // pins the schema document's content
 }
 
 // RefreshService represents refresh service information
@@ -126,6 +133,8 @@ type JWTHeader struct {
 	Algorithm string `json:"alg"`
 	Type      string `json:"typ"`
 	KeyID     string `json:"kid,omitempty"`
+	// ContentType is "vc" or "vp" for VC-JOSE secured credentials
+	ContentType string `json:"cty,omitempty"`
 }
 
 // SD-JWT specific types
@@ -147,6 +156,10 @@ type Disclosure struct {
 	Salt  string      `json:"-"`
 	Claim string      `json:"-"`
 	Value interface{} `json:"-"`
+
+	// ArrayElement marks a disclosure of an array element, which has no
+	// claim name
+	ArrayElement bool `json:"-"`
 	
 	// Base64-encoded disclosure
 	Encoded string `json:"-"`
@@ -224,9 +237,25 @@ type VerificationOptions struct {
 	// Whether to validate credential schema
 	ValidateSchema bool `json:"validateSchema,omitempty"`
 	
+	// Whether to expand JSON-LD credentials against the verifier's pinned
+	// contexts, rejecting unknown contexts and undefined terms
+	ExpandJSONLD bool `json:"expandJsonld,omitempty"`
+	
+	// CBOR-encoded ISO 18013-5 session transcript that mdoc device
+	// signatures must cover
+	SessionTranscript []byte `json:"sessionTranscript,omitempty"`
+	
 	// Trust framework to use for policy-based verification
 	TrustFramework string `json:"trustFramework,omitempty"`
 	
+	// Trust score of the credential subject, visible to trust policy
+	// expressions as score
+	TrustScore *float64 `json:"trustScore,omitempty"`
+	
+	// Credentials presented alongside the verified one, visible to trust
+	// policy expressions as credentials; VerifyPresentation fills it in
+	PresentedCredentials []*VerifiableCredential `json:"-"`
+	
 	// Additional verification parameters
 	Params map[string]interface{} `json:"params,omitempty"`
 }
@@ -263,9 +292,19 @@ type CredentialTemplate struct {
 	ExpirationDate    string                 `json:"expirationDate,omitempty"`
 	CredentialStatus  *CredentialStatus      `json:"credentialStatus,omitempty"`
 	CredentialSchema  []CredentialSchema     `json:"credentialSchema,omitempty"`
+
+	// VCDM 2.0 validity period, used when the first context is the v2
+	// context; ValidUntil falls back to ExpirationDate
+	ValidFrom  string `json:"validFrom,omitempty"`
+	ValidUntil string `json:"validUntil,omitempty"`
 	
 	// SD-JWT specific fields
+	// SelectivelyDisclosable lists credential subject claims to make
+	// disclosable, as dotted paths with [i] or [*] for array elements,
+	// e.g. "address.locality" or "nationalities[*]"
 	SelectivelyDisclosable []string `json:"selectivelyDisclosable,omitempty"`
+	// VCT is the SD-JWT VC type; defaults to the most specific type
+	VCT string `json:"vct,omitempty"`
 }
 
 // IssuanceOptions contains options for credential issuance
@@ -284,6 +323,15 @@ type IssuanceOptions struct {
 	
 	// For SD-JWT: whether to require key binding
 	RequireKeyBinding bool `json:"requireKeyBinding,omitempty"`
+
+	// For SD-JWT: the holder's public key, bound through the cnf claim
+	HolderKey *did.JWK `json:"holderKey,omitempty"`
+
+	// For SD-JWT: digest algorithm (_sd_alg), sha-256 by default
+	SDAlgorithm string `json:"sdAlgorithm,omitempty"`
+
+	// For SD-JWT: decoy digests added to each _sd array
+	DecoyDigests int `json:"decoyDigests,omitempty"`
 }
 
 // PresentationOptions contains options for presentation creation
@@ -2,7 +2,9 @@ package vc
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -18,16 +20,36 @@ type DefaultCredentialVerifier struct {
 	sdjwtProcessor *SDJWTProcessor
 	schemaValidator *SchemaValidator
 	trustEngine     *TrustFrameworkEngine
+	jsonldProcessor *JSONLDProcessor
+	mdocVerifier    *MdocVerifier
 	
 	// Optional status list resolver for credential status checking
 	statusResolver StatusResolver
 }
 
-// StatusResolver interface for checking credential status
+// StatusResolver interface for checking credential status. CheckStatus
+// returns nil for a credential in good standing, a *VCError with code
+// ErrorRevokedCredential for a revoked one, and any other error when the
+// status cannot be determined.
 type StatusResolver interface {
 	CheckStatus(ctx context.Context, status *CredentialStatus) error
 }
 
+// statusError gives the PolicyInput.StatusError for a status check result
+func statusError(err error, revoked bool) string {
+	if err == nil || revoked {
+		return ""
+	}
+	return err.Error()
+}
+
+// isRevocation reports whether a StatusResolver error says the credential
+// is revoked, as opposed to its status being unavailable
+func isRevocation(err error) bool {
+	var vcErr *VCError
+	return errors.As(err, &vcErr) && vcErr.Code == ErrorRevokedCredential
+}
+
 // NewDefaultCredentialVerifier creates a new comprehensive credential verifier
 func NewDefaultCredentialVerifier(keyManager did.KeyManager, resolver did.MultiResolver) *DefaultCredentialVerifier {
 	verifier := &DefaultCredentialVerifier{
@@ -39,6 +61,10 @@ func NewDefaultCredentialVerifier(keyManager did.KeyManager, resolver did.MultiR
 	verifier.sdjwtProcessor = NewSDJWTProcessor(keyManager, resolver)
 	verifier.schemaValidator = NewSchemaValidator()
 	verifier.trustEngine = NewTrustFrameworkEngine()
+	verifier.mdocVerifier = NewMdocVerifier(nil)
+	if loader, err := NewPinnedDocumentLoader(); err == nil {
+		verifier.jsonldProcessor = NewJSONLDProcessor(loader)
+	}
 	
 	return verifier
 }
@@ -48,6 +74,30 @@ func (v *DefaultCredentialVerifier) SetStatusResolver(resolver StatusResolver) {
 	v.statusResolver = resolver
 }
 
+// SetTypeMetadataRegistry makes SD-JWT VC verification check credentials
+// against the type metadata of their vct
+func (v *DefaultCredentialVerifier) SetTypeMetadataRegistry(registry *TypeMetadataRegistry) {
+	v.sdjwtProcessor.SetTypeMetadataRegistry(registry)
+}
+
+// SetDocumentLoader replaces the loader JSON-LD contexts are resolved with,
+// by default the bundled pinned contexts
+func (v *DefaultCredentialVerifier) SetDocumentLoader(loader DocumentLoader) {
+	v.jsonldProcessor = NewJSONLDProcessor(loader)
+}
+
+// SetMdocTrustAnchors sets the IACA root certificates mdoc document
+// signers must chain to; until set, mdocs are rejected
+func (v *DefaultCredentialVerifier) SetMdocTrustAnchors(roots *x509.CertPool) {
+	v.mdocVerifier.SetTrustAnchors(roots)
+}
+
+// TrustEngine returns the engine trust frameworks are evaluated with, for a
+// TrustListManager to keep up to date
+func (v *DefaultCredentialVerifier) TrustEngine() *TrustFrameworkEngine {
+	return v.trustEngine
+}
+
 // LoadTrustFramework loads a trust framework for policy-based verification
 func (v *DefaultCredentialVerifier) LoadTrustFramework(framework *TrustFramework) error {
 	return v.trustEngine.LoadFramework(framework)
@@ -80,6 +130,18 @@ func (v *DefaultCredentialVerifier) VerifyCredential(credential *VerifiableCrede
 		}, nil
 	}
 
+	// Check the validity period the credential's data model defines
+	now := time.Now()
+	if options != nil && options.Now != nil {
+		now = *options.Now
+	}
+	if err := checkValidityPeriod(credential, now); err != nil {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "validity period check failed: " + err.Error(),
+		}, nil
+	}
+
 	// Validate schema if enabled
 	if options != nil && options.ValidateSchema {
 		schemaResult, err := v.schemaValidator.ValidateCredentialSchema(credential)
@@ -105,10 +167,26 @@ func (v *DefaultCredentialVerifier) VerifyCredential(credential *VerifiableCrede
 		}
 	}
 
+	// Check status once, for trust policies and for the result
+	statusChecked := options != nil && options.CheckStatus && credential.CredentialStatus != nil
+	var statusErr error
+	if statusChecked {
+		statusErr = v.checkCredentialStatus(credential.CredentialStatus)
+	}
+	revoked := isRevocation(statusErr)
+
 	// Evaluate trust framework policies if specified
 	if options != nil && options.TrustFramework != "" {
 		ctx := context.Background()
-		policyDecision, err := v.trustEngine.EvaluateCredential(ctx, credential, options.TrustFramework)
+		input := &PolicyInput{
+			Credentials:   options.PresentedCredentials,
+			StatusChecked: statusChecked,
+			Revoked:       revoked,
+			StatusError:   statusError(statusErr, revoked),
+			Score:         options.TrustScore,
+			Now:           now,
+		}
+		policyDecision, err := v.trustEngine.EvaluateCredentialWithInput(ctx, credential, options.TrustFramework, input)
 		if err != nil {
 			return &VerificationResult{
 				Verified: false,
@@ -150,28 +228,52 @@ func (v *DefaultCredentialVerifier) VerifyCredential(credential *VerifiableCrede
 		}, nil
 	}
 
+	// Expand the credential so that every term it uses resolves through a
+	// pinned context
+	if options != nil && options.ExpandJSONLD {
+		if err := v.expandCredential(credential); err != nil {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "JSON-LD expansion failed: " + err.Error(),
+			}, nil
+		}
+	}
+
 	// For now, assume JSON-LD proof verification (would need full implementation)
 	// This is a simplified version
 	result := &VerificationResult{
 		Verified:   true, // Simplified - real implementation would verify proof
 		Credential: credential,
 		Details: map[string]interface{}{
-			"format": "json-ld",
-			"issuer": getIssuerID(credential.Issuer),
+			"format":    "json-ld",
+			"issuer":    getIssuerID(credential.Issuer),
+			"dataModel": string(credential.DataModelVersion()),
 		},
 	}
 
 	// Check credential status if requested
-	if options != nil && options.CheckStatus && credential.CredentialStatus != nil {
-		if err := v.checkCredentialStatus(credential.CredentialStatus); err != nil {
-			result.Verified = false
-			result.Error = "status check failed: " + err.Error()
-		}
+	if revoked {
+		result.Verified = false
+		result.Error = "credential is revoked: " + statusErr.Error()
+	} else if statusErr != nil {
+		result.Verified = false
+		result.Error = "status check failed: " + statusErr.Error()
 	}
 
 	return result, nil
 }
 
+// expandCredential expands a credential in safe mode, failing on contexts
+// the document loader does not serve and on properties or types that do not
+// map to IRIs
+func (v *DefaultCredentialVerifier) expandCredential(credential *VerifiableCredential) error {
+	if v.jsonldProcessor == nil {
+		return fmt.Errorf("no JSON-LD document loader configured")
+	}
+	_, err := v.jsonldProcessor.Expand(credential, &JSONLDOptions{SafeMode: true})
+	return err
+}
+
 // VerifyPresentation verifies a verifiable presentation in JSON-LD format
 func (v *DefaultCredentialVerifier) VerifyPresentation(presentation *VerifiablePresentation, options *VerificationOptions) (*VerificationResult, error) {
 	if presentation == nil {
@@ -202,6 +304,13 @@ func (v *DefaultCredentialVerifier) VerifyPresentation(presentation *VerifiableP
 		}, nil
 	}
 
+	// Trust policies over a presentation see all of its credentials
+	if options != nil && options.TrustFramework != "" && options.PresentedCredentials == nil {
+		withPresented := *options
+		withPresented.PresentedCredentials = presentedCredentials(presentation)
+		options = &withPresented
+	}
+
 	// Verify each embedded credential
 	for i, cred := range presentation.VerifiableCredential {
 		credResult, err := v.verifyEmbeddedCredential(cred, options)
@@ -225,6 +334,7 @@ func (v *DefaultCredentialVerifier) VerifyPresentation(presentation *VerifiableP
 		Presentation: presentation,
 		Details: map[string]interface{}{
 			"format":           "json-ld",
+			"dataModel":        string(presentation.DataModelVersion()),
 			"holder":           presentation.Holder,
 			"credentials_count": len(presentation.VerifiableCredential),
 		},
@@ -271,8 +381,31 @@ func (v *DefaultCredentialVerifier) VerifyAny(input interface{}, options *Verifi
 		
 	case *VerifiablePresentation:
 		return v.VerifyPresentation(cred, options)
+
+	case *EnvelopedCredential:
+		return v.VerifyEnveloped(cred, options)
+
+	case []byte:
+		// CBOR input is an ISO 18013-5 mdoc
+		return v.VerifyMdoc(cred, options)
 		
 	case map[string]interface{}:
+		// Enveloped credentials and presentations have a single type
+		if typeStr, ok := cred["type"].(string); ok && (typeStr == EnvelopedCredentialType || typeStr == EnvelopedPresentationType) {
+			var envelope EnvelopedCredential
+			envBytes, err := json.Marshal(cred)
+			if err == nil {
+				err = json.Unmarshal(envBytes, &envelope)
+			}
+			if err != nil {
+				return &VerificationResult{
+					Verified: false,
+					Error:    "failed to parse envelope: " + err.Error(),
+				}, nil
+			}
+			return v.VerifyEnveloped(&envelope, options)
+		}
+
 		// Try to determine type from JSON
 		if typeArray, ok := cred["type"].([]interface{}); ok {
 			for _, t := range typeArray {
@@ -333,6 +466,129 @@ func (v *DefaultCredentialVerifier) VerifyAny(input interface{}, options *Verifi
 	}
 }
 
+// VerifyEnveloped verifies a VCDM 2.0 enveloped credential or presentation
+// by the media type of its data: URL
+func (v *DefaultCredentialVerifier) VerifyEnveloped(envelope *EnvelopedCredential, options *VerificationOptions) (*VerificationResult, error) {
+	if envelope == nil {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "envelope cannot be nil",
+		}, nil
+	}
+
+	mediaType, secured, err := envelope.Open()
+	if err != nil {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "invalid envelope: " + err.Error(),
+		}, nil
+	}
+
+	presentation := envelope.Type == EnvelopedPresentationType
+	var result *VerificationResult
+	switch {
+	case mediaType == MediaTypeVCJWT && !presentation:
+		result, err = v.VerifyJWTCredential(secured, options)
+	case mediaType == MediaTypeVPJWT && presentation:
+		result, err = v.VerifyJWTPresentation(secured, options)
+	case (mediaType == MediaTypeVCSDJWT || mediaType == MediaTypeDCSDJWT) && !presentation:
+		result, err = v.VerifySDJWT(secured, options)
+	default:
+		return &VerificationResult{
+			Verified: false,
+			Error:    "unsupported " + envelope.Type + " media type " + mediaType,
+		}, nil
+	}
+	if err != nil || !result.Verified {
+		return result, err
+	}
+
+	// An envelope declares the 2.0 data model for what it secures
+	if result.Credential != nil && result.Credential.DataModelVersion() != DataModelV2 {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "enveloped credential must use the VCDM 2.0 context",
+		}, nil
+	}
+	if result.Presentation != nil && result.Presentation.DataModelVersion() != DataModelV2 {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "enveloped presentation must use the VCDM 2.0 context",
+		}, nil
+	}
+
+	if result.Details == nil {
+		result.Details = make(map[string]interface{})
+	}
+	result.Details["mediaType"] = mediaType
+	return result, nil
+}
+
+// VerifyMdoc verifies a CBOR DeviceResponse, or a bare IssuerSigned
+// structure when only issuer authentication is needed. The credential of
+// the result is the mdoc's projection; a DeviceResponse with several
+// documents lists all of them under the "credentials" detail.
+func (v *DefaultCredentialVerifier) VerifyMdoc(data []byte, options *VerificationOptions) (*VerificationResult, error) {
+	if options == nil {
+		options = &VerificationOptions{}
+	}
+	now := time.Now()
+	if options.Now != nil {
+		now = *options.Now
+	}
+
+	item, err := UnmarshalCBOR(data)
+	if err != nil {
+		return &VerificationResult{
+			Verified: false,
+			Error:    "invalid mdoc: " + err.Error(),
+		}, nil
+	}
+
+	var verified []*VerifiedMdoc
+	if m, ok := item.(map[interface{}]interface{}); ok && m["documents"] != nil {
+		response, err := ParseDeviceResponse(data)
+		if err == nil {
+			verified, err = v.mdocVerifier.VerifyDeviceResponse(response, options.SessionTranscript, now)
+		}
+		if err != nil {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "mdoc verification failed: " + err.Error(),
+			}, nil
+		}
+	} else {
+		issuerSigned, err := parseIssuerSigned(item)
+		var result *VerifiedMdoc
+		if err == nil {
+			result, err = v.mdocVerifier.VerifyIssuerSigned(issuerSigned, "", now)
+		}
+		if err != nil {
+			return &VerificationResult{
+				Verified: false,
+				Error:    "mdoc verification failed: " + err.Error(),
+			}, nil
+		}
+		verified = []*VerifiedMdoc{result}
+	}
+
+	credentials := make([]*VerifiableCredential, len(verified))
+	for i, mdoc := range verified {
+		credentials[i] = mdoc.Credential()
+	}
+	return &VerificationResult{
+		Verified:   true,
+		Credential: credentials[0],
+		Details: map[string]interface{}{
+			"format":              FormatMsoMdoc,
+			"docType":             verified[0].DocType,
+			"issuer":              verified[0].IssuerID,
+			"deviceAuthenticated": verified[0].DeviceAuthenticated,
+			"credentials":         credentials,
+		},
+	}, nil
+}
+
 // Helper methods for validation
 
 func (v *DefaultCredentialVerifier) validateCredentialStructure(credential *VerifiableCredential) error {
@@ -341,8 +597,9 @@ func (v *DefaultCredentialVerifier) validateCredentialStructure(credential *Veri
 		return NewVCError(ErrorInvalidContext, "@context is required")
 	}
 	
-	if credential.Context[0] != "https://www.w3.org/2018/credentials/v1" {
-		return NewVCError(ErrorInvalidContext, "first @context must be https://www.w3.org/2018/credentials/v1")
+	version, ok := contextVersion(credential.Context)
+	if !ok {
+		return NewVCError(ErrorInvalidContext, "first @context must be "+CredentialsContextV1+" or "+CredentialsContextV2)
 	}
 
 	// Validate required type
@@ -367,9 +624,26 @@ func (v *DefaultCredentialVerifier) validateCredentialStructure(credential *Veri
 		return NewVCError(ErrorInvalidIssuer, "issuer is required")
 	}
 
-	// Validate issuanceDate
-	if credential.IssuanceDate == "" {
-		return NewVCError(ErrorInvalidCredential, "issuanceDate is required")
+	switch version {
+	case DataModelV1:
+		// Validate issuanceDate
+		if credential.IssuanceDate == "" {
+			return NewVCError(ErrorInvalidCredential, "issuanceDate is required")
+		}
+		if credential.ValidFrom != "" || credential.ValidUntil != "" {
+			return NewVCError(ErrorInvalidCredential, "validFrom and validUntil require the VCDM 2.0 context")
+		}
+	case DataModelV2:
+		// The v2 context does not define the 1.1 date properties
+		if credential.IssuanceDate != "" || credential.ExpirationDate != "" {
+			return NewVCError(ErrorInvalidCredential, "issuanceDate and expirationDate are replaced by validFrom and validUntil in VCDM 2.0")
+		}
+	}
+	if _, _, err := credential.ValidityStart(); err != nil {
+		return err
+	}
+	if _, _, err := credential.ValidityEnd(); err != nil {
+		return err
 	}
 
 	// Validate credentialSubject
@@ -386,8 +660,8 @@ func (v *DefaultCredentialVerifier) validatePresentationStructure(presentation *
 		return NewVCError(ErrorInvalidContext, "@context is required")
 	}
 	
-	if presentation.Context[0] != "https://www.w3.org/2018/credentials/v1" {
-		return NewVCError(ErrorInvalidContext, "first @context must be https://www.w3.org/2018/credentials/v1")
+	if _, ok := contextVersion(presentation.Context); !ok {
+		return NewVCError(ErrorInvalidContext, "first @context must be "+CredentialsContextV1+" or "+CredentialsContextV2)
 	}
 
 	// Validate required type
@@ -420,6 +694,8 @@ func (v *DefaultCredentialVerifier) verifyEmbeddedCredential(cred interface{}, o
 		return v.VerifyAny(c, options)
 	case *VerifiableCredential:
 		return v.VerifyCredential(c, options)
+	case *EnvelopedCredential:
+		return v.VerifyEnveloped(c, options)
 	default:
 		return &VerificationResult{
 			Verified: false,
@@ -428,6 +704,28 @@ func (v *DefaultCredentialVerifier) verifyEmbeddedCredential(cred interface{}, o
 	}
 }
 
+// presentedCredentials decodes the JSON-LD credentials embedded in a
+// presentation
+func presentedCredentials(presentation *VerifiablePresentation) []*VerifiableCredential {
+	credentials := make([]*VerifiableCredential, 0, len(presentation.VerifiableCredential))
+	for _, embedded := range presentation.VerifiableCredential {
+		switch c := embedded.(type) {
+		case *VerifiableCredential:
+			credentials = append(credentials, c)
+		case map[string]interface{}:
+			data, err := json.Marshal(c)
+			if err != nil {
+				continue
+			}
+			var credential VerifiableCredential
+			if json.Unmarshal(data, &credential) == nil {
+				credentials = append(credentials, &credential)
+			}
+		}
+	}
+	return credentials
+}
+
 func (v *DefaultCredentialVerifier) checkCredentialStatus(status *CredentialStatus) error {
 	if v.statusResolver == nil {
 		// No status resolver configured, skip check
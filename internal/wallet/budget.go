@@ -0,0 +1,408 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/score"
+)
+
+// BudgetMode controls what the wallet does with vouches that exceed the
+// issuer's budget for the epoch
+type BudgetMode string
+
+const (
+	// BudgetModeEnforce refuses over-budget vouches
+	BudgetModeEnforce BudgetMode = "enforce"
+	// BudgetModeWarn creates over-budget vouches but flags them
+	BudgetModeWarn BudgetMode = "warn"
+	// BudgetModeOff disables budget checks
+	BudgetModeOff BudgetMode = "off"
+)
+
+const (
+	budgetRuleSetKey = "vouch_budget_ruleset"
+	budgetScoresKey  = "vouch_budget_scores"
+	budgetSpendKey   = "vouch_budget_spend"
+)
+
+// BudgetConfig configures where the wallet learns the active ruleset and the
+// latest signed scores of its DIDs
+type BudgetConfig struct {
+	// RulesURL serves the active consensus.RuleSet as JSON
+	RulesURL string
+	// RulesPublicKey must have signed the ruleset hash. It is required
+	// with RulesURL.
+	RulesPublicKey ed25519.PublicKey
+	// ScorerURL is the base URL of the scorer HTTP API
+	ScorerURL string
+	// ScorerPublicKey is the only key accepted on score proofs. Enforced
+	// budgets ignore scores without it.
+	ScorerPublicKey ed25519.PublicKey
+	// Mode defaults to BudgetModeEnforce
+	Mode    BudgetMode
+	Timeout time.Duration
+}
+
+// RuleSetProvider returns the active ruleset; consensus.RulesRegistry
+// satisfies it
+type RuleSetProvider interface {
+	GetActiveRuleSet(ctx context.Context) (*consensus.RuleSet, error)
+}
+
+// ScoreProvider returns the latest signed score of a DID in a context
+type ScoreProvider interface {
+	GetScoreProof(ctx context.Context, did, context string) (*score.ScoreProof, error)
+}
+
+// VouchBudgetStatus is a DID's vouch budget b_i = b0 + λ·log(1+S) for an
+// epoch together with what has been spent of it
+type VouchBudgetStatus struct {
+	DID        string  `json:"did"`
+	Context    string  `json:"context"`
+	Epoch      string  `json:"epoch"`
+	RuleSetID  string  `json:"ruleSetId"`
+	Base       int     `json:"base"`
+	Lambda     float64 `json:"lambda"`
+	Score      float64 `json:"score"`
+	ScoreEpoch int64   `json:"scoreEpoch,omitempty"`
+	Budget     float64 `json:"budget"`
+	Spent      int     `json:"spent"`
+	Remaining  float64 `json:"remaining"`
+}
+
+// Exceeded reports whether one more vouch would go over budget
+func (b *VouchBudgetStatus) Exceeded() bool {
+	return float64(b.Spent+1) > b.Budget
+}
+
+// HTTPRuleSetProvider fetches the active ruleset from a URL and checks that
+// its hash and signature are valid. Without a key it refuses every ruleset.
+type HTTPRuleSetProvider struct {
+	url       string
+	publicKey ed25519.PublicKey
+	client    *http.Client
+}
+
+// NewHTTPRuleSetProvider creates a ruleset provider for rulesURL
+func NewHTTPRuleSetProvider(rulesURL string, publicKey ed25519.PublicKey, timeout time.Duration) *HTTPRuleSetProvider {
+	return &HTTPRuleSetProvider{
+		url:       rulesURL,
+		publicKey: publicKey,
+		client:    newHTTPClient(timeout),
+	}
+}
+
+// GetActiveRuleSet implements RuleSetProvider
+func (p *HTTPRuleSetProvider) GetActiveRuleSet(ctx context.Context) (*consensus.RuleSet, error) {
+	if p.publicKey == nil {
+		return nil, fmt.Errorf("no rules public key configured, refusing unsigned rulesets")
+	}
+
+	var ruleSet consensus.RuleSet
+	if err := doJSON(ctx, p.client, http.MethodGet, p.url, nil, &ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to fetch ruleset: %w", err)
+	}
+	if err := consensus.VerifyRuleSet(&ruleSet, p.publicKey); err != nil {
+		return nil, err
+	}
+	return &ruleSet, nil
+}
+
+// HTTPScoreProvider fetches signed scores from the scorer API
+type HTTPScoreProvider struct {
+	baseURL   string
+	publicKey ed25519.PublicKey
+	client    *http.Client
+}
+
+// NewHTTPScoreProvider creates a score provider for the scorer at baseURL
+func NewHTTPScoreProvider(baseURL string, publicKey ed25519.PublicKey, timeout time.Duration) *HTTPScoreProvider {
+	return &HTTPScoreProvider{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		publicKey: publicKey,
+		client:    newHTTPClient(timeout),
+	}
+}
+
+// GetScoreProof implements ScoreProvider. Proofs that do not verify, or that
+// were signed by another scorer than the configured one, are rejected.
+func (p *HTTPScoreProvider) GetScoreProof(ctx context.Context, did, scoreContext string) (*score.ScoreProof, error) {
+	endpoint := p.baseURL + "/api/v1/score/" + url.PathEscape(did) + "/proof?context=" + url.QueryEscape(scoreContext)
+
+	var proof score.ScoreProof
+	if err := doJSON(ctx, p.client, http.MethodGet, endpoint, nil, &proof); err != nil {
+		return nil, fmt.Errorf("failed to fetch score proof: %w", err)
+	}
+	if err := verifyScoreProof(&proof, did, scoreContext, p.publicKey); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+func verifyScoreProof(proof *score.ScoreProof, did, scoreContext string, publicKey ed25519.PublicKey) error {
+	if err := score.VerifyScoreProof(proof); err != nil {
+		return fmt.Errorf("invalid score proof: %w", err)
+	}
+	if proof.Score.DID != did || proof.Score.Context != scoreContext {
+		return fmt.Errorf("score proof is for %s in %s", proof.Score.DID, proof.Score.Context)
+	}
+	if publicKey != nil && !bytes.Equal(proof.PublicKey, publicKey) {
+		return fmt.Errorf("score proof was not signed by the configured scorer")
+	}
+	return nil
+}
+
+// SetRuleSetProvider sets where the active ruleset is fetched from
+func (s *Service) SetRuleSetProvider(provider RuleSetProvider) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	s.rules = provider
+}
+
+// SetScoreProvider sets where signed scores are fetched from
+func (s *Service) SetScoreProvider(provider ScoreProvider) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	s.scores = provider
+}
+
+// SetScorerPublicKey sets the key score proofs must be signed with
+func (s *Service) SetScorerPublicKey(publicKey ed25519.PublicKey) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	s.scorerKey = publicKey
+}
+
+// SetRulesPublicKey sets the key rulesets must be signed with. Cached
+// rulesets that it did not sign are no longer used.
+func (s *Service) SetRulesPublicKey(publicKey ed25519.PublicKey) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	s.rulesKey = publicKey
+}
+
+// SetBudgetMode sets how over-budget vouches are handled
+func (s *Service) SetBudgetMode(mode BudgetMode) {
+	s.budgetMu.Lock()
+	defer s.budgetMu.Unlock()
+	s.budgetMode = mode
+}
+
+// GetVouchBudget returns the vouch budget of did in context for the current
+// epoch
+func (s *Service) GetVouchBudget(ctx context.Context, did, budgetContext string) (*VouchBudgetStatus, error) {
+	if did == "" || budgetContext == "" {
+		return nil, fmt.Errorf("did and context are required")
+	}
+
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for vouch budgets")
+	}
+	if err := defaultWallet.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	return s.vouchBudget(ctx, defaultWallet, did, budgetContext, time.Now().UTC().Format(EventEpochFormat))
+}
+
+// vouchBudget computes the budget from the active ruleset and the latest
+// signed score. The last verified ruleset and score are cached so budgets can
+// still be enforced offline, a cached score only for the epoch it was fetched
+// in; without either the default ruleset and a score of zero are used, which
+// gives the smallest budget. Enforced budgets only
+// count scores signed by the configured scorer, so without its key they fall
+// back to the smallest budget too.
+func (s *Service) vouchBudget(ctx context.Context, w *DefaultWallet, did, budgetContext, epoch string) (*VouchBudgetStatus, error) {
+	s.budgetMu.Lock()
+	rules, scores, scorerKey, rulesKey, mode := s.rules, s.scores, s.scorerKey, s.rulesKey, s.budgetMode
+	s.budgetMu.Unlock()
+
+	ruleSet := s.activeRuleSet(ctx, w, rules, rulesKey)
+	if _, ok := ruleSet.VouchBudgets[budgetContext]; !ok {
+		return nil, NewWalletError(ErrorInvalidEvent, fmt.Sprintf("ruleset %s defines no vouch budget for context %s", ruleSet.ID, budgetContext))
+	}
+
+	status := &VouchBudgetStatus{
+		DID:       did,
+		Context:   budgetContext,
+		Epoch:     epoch,
+		RuleSetID: ruleSet.ID,
+		Base:      ruleSet.VouchBudgets[budgetContext],
+		Lambda:    ruleSet.VouchBudgetLambda,
+	}
+
+	if mode == BudgetModeEnforce && scorerKey == nil {
+		scores = nil
+	}
+	if proof := s.latestScore(ctx, w, scores, scorerKey, did, budgetContext, epoch); proof != nil {
+		status.Score = proof.Score.Value
+		status.ScoreEpoch = proof.Score.Epoch
+	}
+
+	spend, err := loadBudgetMetadata[map[string]int](w, budgetSpendKey)
+	if err != nil {
+		return nil, err
+	}
+	status.Spent = spend[budgetSpendEntry(did, budgetContext, epoch)]
+	status.Budget = ruleSet.VouchBudget(budgetContext, status.Score)
+	status.Remaining = math.Max(status.Budget-float64(status.Spent), 0)
+
+	return status, nil
+}
+
+func (s *Service) activeRuleSet(ctx context.Context, w *DefaultWallet, rules RuleSetProvider, rulesKey ed25519.PublicKey) *consensus.RuleSet {
+	if rules != nil {
+		if ruleSet, err := rules.GetActiveRuleSet(ctx); err == nil && ruleSet != nil {
+			if data, err := json.Marshal(ruleSet); err == nil {
+				w.storage.SetMetadata(budgetRuleSetKey, string(data))
+			}
+			return ruleSet
+		}
+	}
+
+	// The cache may hold a ruleset from before the rules key was configured
+	if cached, err := loadBudgetMetadata[*consensus.RuleSet](w, budgetRuleSetKey); err == nil && cached != nil {
+		if rulesKey == nil || consensus.VerifyRuleSet(cached, rulesKey) == nil {
+			return cached
+		}
+	}
+	return consensus.DefaultRuleSet()
+}
+
+// cachedScoreProof is a verified score proof and the budget epoch it was
+// fetched in
+type cachedScoreProof struct {
+	Proof *score.ScoreProof `json:"proof"`
+	Epoch string            `json:"epoch"`
+}
+
+// latestScore returns the newest score proof that verifies, signed by
+// scorerKey when one is set. Without a score provider no proof is used, not
+// even a cached one. Cached proofs expire with the budget epoch they were
+// fetched in, so an offline wallet cannot keep spending on an old score.
+func (s *Service) latestScore(ctx context.Context, w *DefaultWallet, scores ScoreProvider, scorerKey ed25519.PublicKey, did, scoreContext, epoch string) *score.ScoreProof {
+	if scores == nil {
+		return nil
+	}
+
+	cached, err := loadBudgetMetadata[map[string]*cachedScoreProof](w, budgetScoresKey)
+	if err != nil || cached == nil {
+		cached = make(map[string]*cachedScoreProof)
+	}
+	key := did + "|" + scoreContext
+
+	proof, err := scores.GetScoreProof(ctx, did, scoreContext)
+	if err == nil && proof != nil && verifyScoreProof(proof, did, scoreContext, scorerKey) == nil {
+		cached[key] = &cachedScoreProof{Proof: proof, Epoch: epoch}
+		if data, err := json.Marshal(cached); err == nil {
+			w.storage.SetMetadata(budgetScoresKey, string(data))
+		}
+		return proof
+	}
+
+	// The cache may hold proofs from before the scorer key was configured
+	entry := cached[key]
+	if entry == nil || entry.Proof == nil || entry.Epoch != epoch {
+		return nil
+	}
+	if verifyScoreProof(entry.Proof, did, scoreContext, scorerKey) != nil {
+		return nil
+	}
+	return entry.Proof
+}
+
+// fetchVouchBudget returns the issuer's budget status for a vouch about to
+// be created, or nil when budgets are off. It reads the network, so callers
+// must not hold eventsMu.
+func (s *Service) fetchVouchBudget(ctx context.Context, w *DefaultWallet, from, budgetContext, epoch string) (*VouchBudgetStatus, error) {
+	s.budgetMu.Lock()
+	mode := s.budgetMode
+	s.budgetMu.Unlock()
+
+	if mode == BudgetModeOff {
+		return nil, nil
+	}
+	return s.vouchBudget(ctx, w, from, budgetContext, epoch)
+}
+
+// checkVouchBudget applies the budget mode to a vouch about to be created
+// and returns a warning for over-budget vouches that are let through. The
+// spend is re-read since status was fetched; callers hold eventsMu, so
+// concurrent vouches cannot both take the last of a budget.
+func (s *Service) checkVouchBudget(w *DefaultWallet, status *VouchBudgetStatus) (string, error) {
+	s.budgetMu.Lock()
+	mode := s.budgetMode
+	s.budgetMu.Unlock()
+
+	if mode == BudgetModeOff || status == nil {
+		return "", nil
+	}
+
+	spend, err := loadBudgetMetadata[map[string]int](w, budgetSpendKey)
+	if err != nil {
+		return "", err
+	}
+	status.Spent = spend[budgetSpendEntry(status.DID, status.Context, status.Epoch)]
+	status.Remaining = math.Max(status.Budget-float64(status.Spent), 0)
+	if !status.Exceeded() {
+		return "", nil
+	}
+
+	message := fmt.Sprintf("vouch budget of %.2f for %s in %s exhausted for epoch %s (%d spent)",
+		status.Budget, status.DID, status.Context, status.Epoch, status.Spent)
+	if mode == BudgetModeWarn {
+		return message, nil
+	}
+	return "", NewWalletError(ErrorBudgetExceeded, message)
+}
+
+// recordVouchSpend counts a created vouch against the issuer's budget
+func (s *Service) recordVouchSpend(w *DefaultWallet, from, budgetContext, epoch string) error {
+	spend, err := loadBudgetMetadata[map[string]int](w, budgetSpendKey)
+	if err != nil {
+		return err
+	}
+	if spend == nil {
+		spend = make(map[string]int)
+	}
+	spend[budgetSpendEntry(from, budgetContext, epoch)]++
+
+	data, err := json.Marshal(spend)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode vouch spend", err.Error())
+	}
+	return w.storage.SetMetadata(budgetSpendKey, string(data))
+}
+
+func budgetSpendEntry(did, budgetContext, epoch string) string {
+	return did + "|" + budgetContext + "|" + epoch
+}
+
+// loadBudgetMetadata decodes a JSON metadata value, returning the zero value
+// when it has not been stored yet
+func loadBudgetMetadata[T any](w *DefaultWallet, key string) (T, error) {
+	var value T
+	data, err := w.storage.GetMetadata(key)
+	if err != nil {
+		return value, nil
+	}
+	encoded, ok := data.(string)
+	if !ok {
+		return value, nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &value); err != nil {
+		return value, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode "+key, err.Error())
+	}
+	return value, nil
+}
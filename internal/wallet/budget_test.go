@@ -0,0 +1,223 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/score"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_VouchBudgetEnforced(t *testing.T) {
+	service, err := NewService(nil)
+	require.NoError(t, err)
+
+	defaultWallet := service.wallet.(*DefaultWallet)
+	from := createTestKeyDID(t, defaultWallet)
+	to := createTestKeyDID(t, defaultWallet)
+
+	ruleSet := consensus.DefaultRuleSet()
+	ruleSet.VouchBudgets["hiring"] = 1
+	rules := &fakeRuleSetProvider{ruleSet: ruleSet}
+	service.SetRuleSetProvider(rules)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	scores := &fakeScoreProvider{proof: signTestScore(t, privateKey, from, "hiring", math.E-1)}
+	service.SetScoreProvider(scores)
+
+	// Enforced budgets ignore scores until the scorer key is configured
+	budget, err := service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Zero(t, budget.Score)
+	service.SetScorerPublicKey(publicKey)
+
+	// b = 1 + 1.2·log(e) = 2.2, so two vouches fit in the epoch
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.InDelta(t, 2.2, budget.Budget, 1e-9)
+	assert.Equal(t, ruleSet.ID, budget.RuleSetID)
+
+	for i := 0; i < 2; i++ {
+		_, err = service.CreateEvent(context.Background(), "vouch", from, to, "hiring", "")
+		require.NoError(t, err)
+	}
+
+	_, err = service.CreateEvent(context.Background(), "vouch", from, to, "hiring", "")
+	var walletErr *WalletError
+	require.True(t, errors.As(err, &walletErr))
+	assert.Equal(t, ErrorBudgetExceeded, walletErr.Code)
+
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Equal(t, 2, budget.Spent)
+	assert.InDelta(t, 0.2, budget.Remaining, 1e-9, "a fractional remainder does not fit another vouch")
+
+	// Budgets are per context and reports are not budgeted
+	_, err = service.CreateEvent(context.Background(), "vouch", from, to, "general", "")
+	require.NoError(t, err)
+	_, err = service.CreateEvent(context.Background(), "report", from, to, "hiring", "")
+	require.NoError(t, err)
+
+	// With the providers unreachable the cached ruleset and score still apply
+	rules.err = errors.New("offline")
+	scores.err = errors.New("offline")
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.InDelta(t, 2.2, budget.Budget, 1e-9)
+
+	// but the cached score expires with the epoch it was fetched in
+	next, err := service.vouchBudget(context.Background(), defaultWallet, from, "hiring", "2999-01")
+	require.NoError(t, err)
+	assert.Zero(t, next.Score)
+	assert.InDelta(t, 1.0, next.Budget, 1e-9)
+
+	// and once a rules key is set, a cached ruleset it did not sign is dropped
+	service.SetRulesPublicKey(publicKey)
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Equal(t, consensus.DefaultRuleSet().VouchBudgets["hiring"], budget.Base)
+	service.SetRulesPublicKey(nil)
+
+	// In warn mode the vouch is created but flagged
+	service.SetBudgetMode(BudgetModeWarn)
+	result, err := service.CreateEvent(context.Background(), "vouch", from, to, "hiring", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.(*Event).Metadata["budgetWarning"])
+}
+
+func TestService_VouchBudgetDefaults(t *testing.T) {
+	service, err := NewService(nil)
+	require.NoError(t, err)
+
+	defaultWallet := service.wallet.(*DefaultWallet)
+	from := createTestKeyDID(t, defaultWallet)
+
+	// Without a ruleset or score the default base budget applies
+	budget, err := service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Equal(t, consensus.DefaultRuleSet().ID, budget.RuleSetID)
+	assert.Equal(t, 2.0, budget.Budget)
+	assert.Zero(t, budget.Score)
+
+	// Scores signed for another DID are ignored
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	service.SetScoreProvider(&fakeScoreProvider{proof: signTestScore(t, privateKey, "did:key:other", "hiring", 50)})
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Zero(t, budget.Score)
+
+	// Nor are scores signed by another scorer than the configured one
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	service.SetScorerPublicKey(publicKey)
+	service.SetScoreProvider(&fakeScoreProvider{proof: signTestScore(t, privateKey, from, "hiring", 50)})
+	budget, err = service.GetVouchBudget(context.Background(), from, "hiring")
+	require.NoError(t, err)
+	assert.Zero(t, budget.Score)
+
+	_, err = service.GetVouchBudget(context.Background(), from, "unknown")
+	require.Error(t, err)
+
+	// Enforcing budgets against a scorer needs its key
+	_, err = NewService(&Config{Budget: &BudgetConfig{ScorerURL: "http://scorer.example"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "scorer public key")
+
+	// and fetching rulesets needs the key that signs them
+	_, err = NewService(&Config{Budget: &BudgetConfig{RulesURL: "http://rules.example"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rules public key")
+}
+
+func TestHTTPBudgetProviders(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ruleSet := consensus.DefaultRuleSet()
+	ruleSet.Hash, err = consensus.ComputeRuleSetHash(ruleSet)
+	require.NoError(t, err)
+	ruleSet.Signature = ed25519.Sign(privateKey, ruleSet.Hash)
+
+	did := "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"
+	proof := signTestScore(t, privateKey, did, "general", 12.5)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rules/active", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ruleSet)
+	})
+	mux.HandleFunc("/api/v1/score/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "general", r.URL.Query().Get("context"))
+		json.NewEncoder(w).Encode(proof)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetched, err := NewHTTPRuleSetProvider(server.URL+"/rules/active", publicKey, 0).GetActiveRuleSet(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ruleSet.VouchBudgetLambda, fetched.VouchBudgetLambda)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = NewHTTPRuleSetProvider(server.URL+"/rules/active", otherKey, 0).GetActiveRuleSet(context.Background())
+	require.Error(t, err, "rulesets must be signed by the configured key")
+	_, err = NewHTTPRuleSetProvider(server.URL+"/rules/active", nil, 0).GetActiveRuleSet(context.Background())
+	require.Error(t, err, "rulesets are refused without a key to check them against")
+
+	scoreProof, err := NewHTTPScoreProvider(server.URL, publicKey, 0).GetScoreProof(context.Background(), did, "general")
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, scoreProof.Score.Value)
+
+	_, err = NewHTTPScoreProvider(server.URL, otherKey, 0).GetScoreProof(context.Background(), did, "general")
+	require.Error(t, err, "proofs from other scorers are rejected")
+
+	proof.Score.Value = 99
+	_, err = NewHTTPScoreProvider(server.URL, nil, 0).GetScoreProof(context.Background(), did, "general")
+	require.Error(t, err, "tampered scores fail verification")
+}
+
+type fakeRuleSetProvider struct {
+	ruleSet *consensus.RuleSet
+	err     error
+}
+
+func (p *fakeRuleSetProvider) GetActiveRuleSet(ctx context.Context) (*consensus.RuleSet, error) {
+	return p.ruleSet, p.err
+}
+
+type fakeScoreProvider struct {
+	proof *score.ScoreProof
+	err   error
+}
+
+func (p *fakeScoreProvider) GetScoreProof(ctx context.Context, did, context string) (*score.ScoreProof, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.proof, nil
+}
+
+// signTestScore signs a score the way the scorer's GetProof does
+func signTestScore(t *testing.T, privateKey ed25519.PrivateKey, did, scoreContext string, value float64) *score.ScoreProof {
+	proof := &score.ScoreProof{
+		Score:     &score.Score{DID: did, Context: scoreContext, Value: value, Epoch: 20000},
+		InputHash: "inputs",
+		PublicKey: privateKey.Public().(ed25519.PublicKey),
+		Algorithm: "ed25519",
+	}
+	canonical := fmt.Sprintf("%s|%s|%.6f|%d|%s", did, scoreContext, value, proof.Score.Epoch, proof.InputHash)
+	proof.Signature = ed25519.Sign(privateKey, []byte(canonical))
+	require.NoError(t, score.VerifyScoreProof(proof))
+	return proof
+}
@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/events"
+)
+
+// EventStatus tracks a signed event on its way into a checkpoint
+type EventStatus string
+
+const (
+	// EventStatusSigned events have not been accepted by the network yet
+	EventStatusSigned EventStatus = "signed"
+	// EventStatusPending events were submitted and await log inclusion
+	EventStatusPending EventStatus = "pending"
+	// EventStatusIncluded events are in the log but not yet checkpointed
+	EventStatusIncluded EventStatus = "included"
+	// EventStatusCheckpointed events are covered by a signed checkpoint
+	EventStatusCheckpointed EventStatus = "checkpointed"
+	// EventStatusExpired events went unaccepted until they could no longer
+	// be re-signed and are not retried
+	EventStatusExpired EventStatus = "expired"
+)
+
+// EventEpochFormat is the layout of event epochs (YYYY-MM)
+const EventEpochFormat = "2006-01"
+
+// SignNetworkEvent builds a canonical network event issued by from and signs
+// it with the key controlling that DID. from must be a DID held by the wallet.
+func (w *DefaultWallet) SignNetworkEvent(eventType events.EventType, from, to, context, payloadCID string) (*events.Event, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	record, err := w.storage.GetDID(from)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorDIDNotFound, "event issuer is not held by this wallet", err.Error())
+	}
+
+	nonce, err := crypto.GenerateNonce()
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate nonce", err.Error())
+	}
+
+	issuedAt := time.Now().UTC()
+	event := &events.Event{
+		Type:       eventType,
+		From:       from,
+		To:         to,
+		Context:    context,
+		Epoch:      issuedAt.Format(EventEpochFormat),
+		PayloadCID: payloadCID,
+		Nonce:      nonce,
+		IssuedAt:   issuedAt,
+	}
+
+	if err := events.ValidateEvent(event); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorInvalidEvent, "invalid event", err.Error())
+	}
+
+	canonical, err := events.CanonicalizeEvent(event.ToSignable())
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to canonicalize event", err.Error())
+	}
+
+	signature, err := w.Sign(record.KeyID, canonical)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Signature = base64.StdEncoding.EncodeToString(signature)
+	return event, nil
+}
+
+// SignAsDID signs data with the key controlling a DID held by the wallet
+func (w *DefaultWallet) SignAsDID(did string, data []byte) ([]byte, error) {
+	record, err := w.storage.GetDID(did)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorDIDNotFound, "DID is not held by this wallet", err.Error())
+	}
+	return w.Sign(record.KeyID, data)
+}
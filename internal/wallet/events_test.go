@@ -0,0 +1,445 @@
+package wallet
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultWallet_SignNetworkEvent(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+	from := createTestKeyDID(t, wallet)
+	to := createTestKeyDID(t, wallet)
+
+	event, err := wallet.SignNetworkEvent(events.EventTypeVouch, from, to, "general", "")
+	require.NoError(t, err)
+	require.NoError(t, events.ValidateEvent(event))
+	assert.Equal(t, event.IssuedAt.Format(EventEpochFormat), event.Epoch)
+
+	// The signature verifies against the key embedded in the issuer DID
+	publicKey, _, err := did.PublicKeyFromDIDKey(from)
+	require.NoError(t, err)
+	canonical, err := events.CanonicalizeEvent(event.ToSignable())
+	require.NoError(t, err)
+	signature, err := base64.StdEncoding.DecodeString(event.Signature)
+	require.NoError(t, err)
+	assert.True(t, ed25519.Verify(publicKey.(ed25519.PublicKey), canonical, signature))
+
+	_, err = wallet.SignNetworkEvent(events.EventTypeVouch, to, from, "unknown", "")
+	require.Error(t, err, "contexts are validated")
+
+	_, err = wallet.SignNetworkEvent(events.EventTypeVouch, "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK", to, "general", "")
+	require.Error(t, err, "only DIDs held by the wallet can issue events")
+}
+
+func TestService_EventLifecycle(t *testing.T) {
+	service, err := NewService(nil)
+	require.NoError(t, err)
+
+	defaultWallet := service.wallet.(*DefaultWallet)
+	from := createTestKeyDID(t, defaultWallet)
+	to := createTestKeyDID(t, defaultWallet)
+
+	network := &fakeEventNetwork{submitErr: errors.New("gateway unreachable")}
+	service.SetEventNetwork(network)
+
+	result, err := service.CreateEvent(context.Background(), "vouch", from, to, "general", "")
+	require.NoError(t, err)
+	event := result.(*Event)
+	assert.Equal(t, EventStatusSigned, event.Status, "failed submissions stay signed")
+	assert.NotEmpty(t, event.LastError)
+	require.NotNil(t, event.Network)
+
+	// The next sync retries the submission
+	network.submitErr = nil
+	updated, err := service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusPending, updated[0].Status)
+	assert.Equal(t, event.ID, network.submitted[0])
+
+	// Included in the log, but no checkpoint yet
+	network.include(t, event.ID, 4)
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusIncluded, updated[0].Status)
+	assert.Equal(t, int64(4), updated[0].Inclusion.LeafIndex)
+
+	// A checkpoint over a tree that does not yet cover the leaf changes nothing
+	committee := newTestCheckpointCommittee(t)
+	network.checkpoint = committee.sign(t, 1, []byte{0xaa}, 3)
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, updated)
+
+	// Without the committee's keys no checkpoint is trusted
+	network.append(t, 3)
+	root := network.root(t)
+	network.checkpoint = committee.sign(t, 2, root, 8)
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusIncluded, updated[0].Status)
+	assert.Contains(t, updated[0].LastError, "committee")
+
+	// Checkpoints short of the threshold, or over another root, are refused
+	service.SetCheckpointVerifier(committee.verifier)
+	network.checkpoint = committee.sign(t, 2, root, 8, committee.members[0])
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusIncluded, updated[0].Status)
+	assert.Contains(t, updated[0].LastError, "invalid checkpoint")
+
+	network.checkpoint = committee.sign(t, 2, []byte("another root"), 8)
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusIncluded, updated[0].Status)
+	assert.Contains(t, updated[0].LastError, "invalid inclusion proof")
+
+	network.checkpoint = committee.sign(t, 2, root, 8)
+	updated, err = service.SyncEvents(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, EventStatusCheckpointed, updated[0].Status)
+	assert.Empty(t, updated[0].LastError)
+	assert.Equal(t, int64(8), updated[0].Inclusion.TreeSize, "proof is re-fetched for the checkpoint tree")
+	assert.Equal(t, int64(2), updated[0].Checkpoint.Epoch)
+
+	// The proof is only good for the event it was issued for
+	assert.NoError(t, updated[0].Inclusion.Verify(event.ID, root))
+	assert.Error(t, updated[0].Inclusion.Verify("another-event", root))
+
+	stored, err := service.GetEvent(event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStatusCheckpointed, stored.(*Event).Status)
+
+	listed, err := service.ListEvents(map[string]interface{}{"status": "checkpointed"})
+	require.NoError(t, err)
+	assert.Len(t, listed.([]*Event), 1)
+}
+
+func TestService_EventSyncRecovery(t *testing.T) {
+	service, err := NewService(nil)
+	require.NoError(t, err)
+
+	defaultWallet := service.wallet.(*DefaultWallet)
+	from := createTestKeyDID(t, defaultWallet)
+	to := createTestKeyDID(t, defaultWallet)
+
+	// Submissions use the caller's context and do not hold the event lock
+	type contextKey struct{}
+	network := &fakeEventNetwork{}
+	network.onSubmit = func(ctx context.Context) {
+		assert.Equal(t, "caller", ctx.Value(contextKey{}))
+		if assert.True(t, service.eventsMu.TryLock(), "event lock held during submission") {
+			service.eventsMu.Unlock()
+		}
+	}
+	service.SetEventNetwork(network)
+	ctx := context.WithValue(context.Background(), contextKey{}, "caller")
+
+	result, err := service.CreateEvent(ctx, "report", from, to, "general", "")
+	require.NoError(t, err)
+	report := result.(*Event)
+	assert.Equal(t, EventStatusPending, report.Status)
+
+	// A failed checkpoint fetch keeps the inclusion found in the same sync
+	network.include(t, report.ID, 2)
+	network.checkpointErr = errors.New("checkpoint service down")
+	updated, err := service.SyncEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Contains(t, updated[0].LastError, "checkpoint service down")
+	stored, err := service.GetEvent(report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, EventStatusIncluded, stored.(*Event).Status)
+	assert.NotNil(t, stored.(*Event).Inclusion)
+
+	// Events left unsubmitted until the network would reject them as too
+	// old are re-signed; vouches whose epoch has ended expire instead
+	network.onSubmit = nil
+	network.submitErr = errors.New("gateway unreachable")
+	result, err = service.CreateEvent(ctx, "report", to, from, "general", "")
+	require.NoError(t, err)
+	staleReport := result.(*Event)
+	result, err = service.CreateEvent(ctx, "vouch", from, to, "general", "")
+	require.NoError(t, err)
+	staleVouch := result.(*Event)
+
+	all, err := service.getAllEvents()
+	require.NoError(t, err)
+	for _, event := range all {
+		if event.ID == staleReport.ID || event.ID == staleVouch.ID {
+			event.Network.IssuedAt = time.Now().Add(-events.MaxEventAge)
+		}
+		if event.ID == staleVouch.ID {
+			event.Network.Epoch = "2000-01"
+		}
+	}
+	require.NoError(t, service.storeAllEvents(all))
+
+	network.submitErr = nil
+	updated, err = service.SyncEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+	byType := map[string]*Event{updated[0].Type: updated[0], updated[1].Type: updated[1]}
+
+	renewed := byType["report"]
+	assert.Equal(t, EventStatusPending, renewed.Status)
+	assert.NotEqual(t, staleReport.ID, renewed.ID)
+	assert.Contains(t, network.submitted, renewed.ID)
+	require.NoError(t, events.ValidateEvent(renewed.Network))
+	_, err = service.GetEvent(renewed.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, EventStatusExpired, byType["vouch"].Status)
+	assert.NotContains(t, network.submitted, staleVouch.ID)
+
+	// Expired events are not retried
+	network.checkpointErr = nil
+	updated, err = service.SyncEvents(ctx)
+	require.NoError(t, err)
+	for _, event := range updated {
+		assert.NotEqual(t, staleVouch.ID, event.ID)
+	}
+}
+
+func TestHTTPEventNetwork(t *testing.T) {
+	var published, stored []byte
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/publish", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "events/vouch", r.URL.Query().Get("topic"))
+		published = readBody(t, r)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	mux.HandleFunc("/v1/events", func(w http.ResponseWriter, r *http.Request) {
+		stored = readBody(t, r)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/v1/checkpoints/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "No checkpoints found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/v1/events/known/inclusion", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "7", r.URL.Query().Get("tree_size"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"leaf_hash":  "abcd",
+			"leaf_value": []byte(`{"cid":"known"}`),
+			"proof":      map[string]interface{}{"leaf_index": 2, "tree_size": 7},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	network, err := NewHTTPEventNetwork(&NetworkConfig{
+		GatewayURL:  server.URL,
+		FullNodeURL: server.URL,
+		LogNodeURL:  server.URL,
+	})
+	require.NoError(t, err)
+
+	wallet := setupHDTestWallet(t)
+	event, err := wallet.SignNetworkEvent(events.EventTypeVouch, createTestKeyDID(t, wallet), createTestKeyDID(t, wallet), "general", "")
+	require.NoError(t, err)
+
+	cid, err := network.SubmitEvent(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, published, stored)
+	expected, err := events.GenerateCIDFromJSON(stored)
+	require.NoError(t, err)
+	assert.Equal(t, expected, cid)
+
+	proof, err := network.GetEventInclusion(context.Background(), "unknown", 0)
+	require.NoError(t, err)
+	assert.Nil(t, proof, "events missing from the log are pending, not errors")
+
+	proof, err = network.GetEventInclusion(context.Background(), "known", 7)
+	require.NoError(t, err)
+	require.NotNil(t, proof)
+	assert.Equal(t, int64(2), proof.LeafIndex)
+	assert.Equal(t, "abcd", proof.LeafHash)
+	assert.JSONEq(t, `{"cid":"known"}`, string(proof.LeafValue))
+
+	checkpoint, err := network.GetLatestCheckpoint(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+// fakeEventNetwork keeps a transparency log that tests include submitted
+// events in
+type fakeEventNetwork struct {
+	submitErr       error
+	submitted       []string
+	onSubmit        func(ctx context.Context)
+	checkpointErr   error
+	transparencyLog *log.MemoryTransparencyLog
+	checkpoint      *EventCheckpoint
+}
+
+// append adds count leaves that are not event references to the log
+func (n *fakeEventNetwork) append(t *testing.T, count int) {
+	if n.transparencyLog == nil {
+		var err error
+		n.transparencyLog, err = log.NewMemoryTransparencyLog(nil)
+		require.NoError(t, err)
+	}
+	leaves := make([]log.Leaf, count)
+	for i := range leaves {
+		leaves[i] = log.Leaf{LeafValue: []byte(fmt.Sprintf("filler-%d-%d", i, time.Now().UnixNano()))}
+	}
+	_, err := n.transparencyLog.AppendLeaves(context.Background(), leaves)
+	require.NoError(t, err)
+}
+
+// include appends the reference of an event after some other leaves
+func (n *fakeEventNetwork) include(t *testing.T, cid string, after int) {
+	n.append(t, after)
+	value, err := json.Marshal(log.EventReference{CID: cid, Type: string(events.EventTypeVouch)})
+	require.NoError(t, err)
+	_, err = n.transparencyLog.AppendLeaves(context.Background(), []log.Leaf{{LeafValue: value}})
+	require.NoError(t, err)
+}
+
+// root returns the current root of the log
+func (n *fakeEventNetwork) root(t *testing.T) []byte {
+	head, err := n.transparencyLog.GetSignedTreeHead(context.Background())
+	require.NoError(t, err)
+	return head.RootHash
+}
+
+func (n *fakeEventNetwork) SubmitEvent(ctx context.Context, event *events.Event) (string, error) {
+	if n.onSubmit != nil {
+		n.onSubmit(ctx)
+	}
+	if n.submitErr != nil {
+		return "", n.submitErr
+	}
+	data, _ := json.Marshal(event)
+	cid, err := events.GenerateCIDFromJSON(data)
+	n.submitted = append(n.submitted, cid)
+	return cid, err
+}
+
+func (n *fakeEventNetwork) GetEventInclusion(ctx context.Context, cid string, treeSize int64) (*EventInclusionProof, error) {
+	if n.transparencyLog == nil {
+		return nil, nil
+	}
+	size, err := n.transparencyLog.GetTreeSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := n.transparencyLog.GetLeavesByRange(ctx, 0, size-1)
+	if err != nil {
+		return nil, err
+	}
+	for _, leaf := range leaves {
+		var ref log.EventReference
+		if json.Unmarshal(leaf.LeafValue, &ref) != nil || ref.CID != cid {
+			continue
+		}
+		proof, err := n.transparencyLog.GetInclusionProof(ctx, leaf.LeafHash, treeSize)
+		if err != nil {
+			return nil, err
+		}
+		return &EventInclusionProof{
+			LeafHash:  hex.EncodeToString(leaf.LeafHash),
+			LeafValue: leaf.LeafValue,
+			LeafIndex: proof.LeafIndex,
+			TreeSize:  proof.TreeSize,
+			AuditPath: proof.AuditPath,
+		}, nil
+	}
+	return nil, nil
+}
+
+func (n *fakeEventNetwork) GetLatestCheckpoint(ctx context.Context) (*EventCheckpoint, error) {
+	if n.checkpointErr != nil {
+		return nil, n.checkpointErr
+	}
+	return n.checkpoint, nil
+}
+
+// testCheckpointCommittee signs checkpoints for a two of three committee
+type testCheckpointCommittee struct {
+	verifier   *consensus.CommitteeCheckpointVerifier
+	members    []string
+	keys       map[string]*consensus.BLSPrivateKey
+	aggregator *consensus.DefaultBLSAggregator
+}
+
+func newTestCheckpointCommittee(t *testing.T) *testCheckpointCommittee {
+	committee := &testCheckpointCommittee{
+		keys:       make(map[string]*consensus.BLSPrivateKey),
+		aggregator: consensus.NewDefaultBLSAggregator(),
+	}
+	members := make(map[string]*consensus.BLSPublicKey)
+	for i := 0; i < 3; i++ {
+		privateKey, publicKey, err := committee.aggregator.GenerateKeyPair()
+		require.NoError(t, err)
+		member := fmt.Sprintf("did:key:member%d", i)
+		committee.members = append(committee.members, member)
+		committee.keys[member] = privateKey
+		members[member] = publicKey
+	}
+
+	verifier, err := consensus.NewCommitteeCheckpointVerifier(&consensus.CheckpointCommittee{Members: members, Threshold: 2})
+	require.NoError(t, err)
+	committee.verifier = verifier
+	return committee
+}
+
+// sign returns a checkpoint over a root signed by the given members, or by
+// two of them when none are given
+func (c *testCheckpointCommittee) sign(t *testing.T, epoch int64, root []byte, treeSize int64, signers ...string) *EventCheckpoint {
+	if signers == nil {
+		signers = c.members[:2]
+	}
+	var partials []consensus.PartialSignature
+	for _, signer := range signers {
+		signature, err := c.aggregator.SignMessage(root, c.keys[signer])
+		require.NoError(t, err)
+		partials = append(partials, consensus.PartialSignature{SignerDID: signer, Signature: signature, Root: root, Epoch: epoch})
+	}
+	signature, err := c.aggregator.AggregateSignatures(partials, len(partials))
+	require.NoError(t, err)
+
+	return &EventCheckpoint{
+		Root:      hex.EncodeToString(root),
+		Epoch:     epoch,
+		TreeSize:  treeSize,
+		Timestamp: time.Now(),
+		Signers:   signers,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}
+}
+
+func createTestKeyDID(t *testing.T, wallet *DefaultWallet) string {
+	keyPair, err := wallet.GenerateKey(did.KeyTypeEd25519)
+	require.NoError(t, err)
+	record, err := wallet.CreateDID(keyPair.ID, "key")
+	require.NoError(t, err)
+	return record.DID
+}
+
+func readBody(t *testing.T, r *http.Request) []byte {
+	var body json.RawMessage
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	return body
+}
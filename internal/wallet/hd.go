@@ -0,0 +1,485 @@
+package wallet
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/did"
+)
+
+// HD keys are derived from a BIP-39 seed with SLIP-0010 along
+//
+//	m/44'/HDCoinType'/account'/purpose'/index'
+//
+// Every DID gets its own account; the purpose segment separates keys by
+// usage within that DID. All segments are hardened so the same layout works
+// for Ed25519, secp256k1 and P-256.
+//
+// The seed is sealed with a random seed key, which is itself wrapped under
+// the wallet password. The seed key is only held while the wallet is
+// unlocked, so a wallet needs a password before it can hold a root.
+
+// HDCoinType is the SLIP-0044 coin type segment used for credence keys
+const HDCoinType uint32 = 7337
+
+// MaxRestoreAccounts caps how many accounts a restore derives, following the
+// BIP-44 gap limit of 20
+const MaxRestoreAccounts = 20
+
+const hdStateKey = "hd_state"
+
+// HDKeyRecord records where an HD key was derived from
+type HDKeyRecord struct {
+	Path    string      `json:"path"`
+	Account uint32      `json:"account"`
+	Purpose KeyUsage    `json:"purpose"`
+	Index   uint32      `json:"index"`
+	KeyType did.KeyType `json:"keyType"`
+	KeyID   string      `json:"keyId"`
+	// DIDMethods lists the methods of the DIDs created for this key
+	DIDMethods []string `json:"didMethods,omitempty"`
+}
+
+// hdState is persisted in wallet storage alongside the keys it protects
+type hdState struct {
+	SealedSeed []byte `json:"sealedSeed"`
+	// Seed is only read from roots written before the seed was sealed
+	Seed     []byte         `json:"seed,omitempty"`
+	Accounts uint32         `json:"accounts"`
+	Keys     []*HDKeyRecord `json:"keys"`
+	Created  time.Time      `json:"created"`
+}
+
+// hdPurposes maps key usages to the purpose path segment
+var hdPurposes = map[KeyUsage]uint32{
+	KeyUsageAuthentication:       0,
+	KeyUsageAssertionMethod:      6,
+	KeyUsageKeyAgreement:         1,
+	KeyUsageSigning:              2,
+	KeyUsageEncryption:           3,
+	KeyUsageCapabilityInvocation: 4,
+	KeyUsageCapabilityDelegation: 5,
+}
+
+// HDPath returns the derivation path for an account, purpose and index
+func HDPath(account uint32, purpose KeyUsage, index uint32) (string, error) {
+	segment, ok := hdPurposes[purpose]
+	if !ok {
+		return "", NewWalletError(ErrorInvalidKeyType, "unsupported key purpose: "+string(purpose))
+	}
+	return crypto.FormatDerivationPath([]uint32{
+		44 + crypto.HardenedOffset,
+		HDCoinType + crypto.HardenedOffset,
+		account + crypto.HardenedOffset,
+		segment + crypto.HardenedOffset,
+		index + crypto.HardenedOffset,
+	}), nil
+}
+
+// CreateMnemonic creates a new BIP-39 root for the wallet. The mnemonic is
+// returned once and never stored; only the derived seed is kept.
+func (w *DefaultWallet) CreateMnemonic(strength int, passphrase string) (string, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return "", err
+	}
+
+	w.updateActivity()
+
+	if w.HasHDRoot() {
+		return "", NewWalletError(ErrorHDRootExists, "wallet already has a mnemonic root")
+	}
+
+	if strength == 0 {
+		strength = crypto.MnemonicStrength256
+	}
+
+	mnemonic, err := crypto.GenerateMnemonic(strength)
+	if err != nil {
+		return "", NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate mnemonic", err.Error())
+	}
+
+	if err := w.initHDRoot(mnemonic, passphrase); err != nil {
+		return "", err
+	}
+
+	return mnemonic, nil
+}
+
+// RestoreFromMnemonic installs the mnemonic root and re-derives the wallet's
+// keys and DIDs. Given the records ListHDKeys returned before, it re-derives
+// every one of them along with the DIDs created for them. Without records it
+// re-derives the default authentication key and its did:key DID for the
+// first accounts accounts.
+func (w *DefaultWallet) RestoreFromMnemonic(mnemonic, passphrase string, accounts int, keys ...*HDKeyRecord) ([]*DIDRecord, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	if accounts <= 0 {
+		accounts = 1
+	}
+	if accounts > MaxRestoreAccounts {
+		return nil, NewWalletError(ErrorTooManyAccounts, fmt.Sprintf("cannot restore more than %d accounts", MaxRestoreAccounts))
+	}
+
+	if w.HasHDRoot() {
+		matches, err := w.VerifyMnemonic(mnemonic, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			return nil, NewWalletError(ErrorHDRootExists, "wallet already has a different mnemonic root")
+		}
+	} else if err := w.initHDRoot(mnemonic, passphrase); err != nil {
+		return nil, err
+	}
+
+	if len(keys) > 0 {
+		return w.restoreHDKeys(keys)
+	}
+
+	records := make([]*DIDRecord, 0, accounts)
+	for account := 0; account < accounts; account++ {
+		record, err := w.deriveAccountDID(uint32(account), w.config.DefaultKeyType)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// VerifyMnemonic reports whether a mnemonic and passphrase reproduce the wallet root
+func (w *DefaultWallet) VerifyMnemonic(mnemonic, passphrase string) (bool, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return false, err
+	}
+
+	state, err := w.loadHDState()
+	if err != nil {
+		return false, err
+	}
+
+	seed, err := crypto.MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return false, NewWalletErrorWithDetails(ErrorInvalidMnemonic, "invalid mnemonic", err.Error())
+	}
+
+	rootSeed, err := w.hdSeed(state)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(seed, rootSeed) == 1, nil
+}
+
+// HasHDRoot reports whether the wallet has a mnemonic root
+func (w *DefaultWallet) HasHDRoot() bool {
+	_, err := w.loadHDState()
+	return err == nil
+}
+
+// DeriveKey derives and stores the key at account/purpose/index
+func (w *DefaultWallet) DeriveKey(keyType did.KeyType, account uint32, purpose KeyUsage, index uint32) (*KeyPair, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	state, err := w.loadHDState()
+	if err != nil {
+		return nil, err
+	}
+
+	return w.deriveKey(state, keyType, account, purpose, index)
+}
+
+// ListHDKeys returns the derivation records of all HD keys. Kept with the
+// mnemonic, they let RestoreFromMnemonic rebuild every key and DID.
+func (w *DefaultWallet) ListHDKeys() ([]*HDKeyRecord, error) {
+	state, err := w.loadHDState()
+	if err != nil {
+		return nil, err
+	}
+	return state.Keys, nil
+}
+
+// ExportSeed returns the BIP-39 seed after checking the wallet password
+func (w *DefaultWallet) ExportSeed(password string) ([]byte, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.mutex.RLock()
+	verifier := w.verifier
+	w.mutex.RUnlock()
+
+	// A wallet that never had a password set cannot release its seed
+	if verifier == nil {
+		return nil, NewWalletError(ErrorInvalidPassword, "wallet has no password set")
+	}
+	if !verifier.matches(password) {
+		return nil, NewWalletError(ErrorInvalidPassword, "invalid password")
+	}
+
+	state, err := w.loadHDState()
+	if err != nil {
+		return nil, err
+	}
+
+	return w.hdSeed(state)
+}
+
+// nextHDKey derives the authentication key for the next unused account
+func (w *DefaultWallet) nextHDKey(keyType did.KeyType) (*KeyPair, error) {
+	state, err := w.loadHDState()
+	if err != nil {
+		return nil, err
+	}
+	return w.deriveKey(state, keyType, state.Accounts, KeyUsageAuthentication, 0)
+}
+
+func (w *DefaultWallet) deriveAccountDID(account uint32, keyType did.KeyType) (*DIDRecord, error) {
+	state, err := w.loadHDState()
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := w.deriveKey(state, keyType, account, KeyUsageAuthentication, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.restoreDID(keyPair.ID, "key")
+}
+
+// restoreHDKeys re-derives each recorded key and recreates its DIDs
+func (w *DefaultWallet) restoreHDKeys(keys []*HDKeyRecord) ([]*DIDRecord, error) {
+	var records []*DIDRecord
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+
+		// Creating a DID updates the stored state, so reload it per key
+		state, err := w.loadHDState()
+		if err != nil {
+			return nil, err
+		}
+
+		keyPair, err := w.deriveKey(state, key.KeyType, key.Account, key.Purpose, key.Index)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, method := range key.DIDMethods {
+			record, err := w.restoreDID(keyPair.ID, method)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// restoreDID returns the key's DID for method, creating it unless an earlier
+// restore already did
+func (w *DefaultWallet) restoreDID(keyID, method string) (*DIDRecord, error) {
+	dids, err := w.storage.ListDIDs()
+	if err == nil {
+		for _, record := range dids {
+			if record.KeyID == keyID && record.Method == method {
+				return record, nil
+			}
+		}
+	}
+
+	return w.CreateDID(keyID, method)
+}
+
+// recordHDDID notes on an HD key's record that a DID was created for it, so
+// a restore recreates the DID too
+func (w *DefaultWallet) recordHDDID(keyID, method string) error {
+	state, err := w.loadHDState()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range state.Keys {
+		if record.KeyID != keyID {
+			continue
+		}
+		for _, existing := range record.DIDMethods {
+			if existing == method {
+				return nil
+			}
+		}
+		record.DIDMethods = append(record.DIDMethods, method)
+		return w.saveHDState(state)
+	}
+	return nil
+}
+
+func (w *DefaultWallet) deriveKey(state *hdState, keyType did.KeyType, account uint32, purpose KeyUsage, index uint32) (*KeyPair, error) {
+	path, err := HDPath(account, purpose, index)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range state.Keys {
+		if existing.Path == path && existing.KeyType == keyType {
+			if keyPair, err := w.storage.GetKey(existing.KeyID); err == nil {
+				return keyPair, nil
+			}
+		}
+	}
+
+	curve, err := curveForKeyType(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := w.hdSeed(state)
+	if err != nil {
+		return nil, err
+	}
+
+	extended, err := crypto.DerivePath(curve, seed, path)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "key derivation failed", err.Error())
+	}
+
+	privateKey, err := extended.PrivateKey()
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "key derivation failed", err.Error())
+	}
+
+	keyPair, err := w.storeImportedKey(privateKey, keyType, []KeyUsage{purpose}, map[string]interface{}{
+		"derivationPath": path,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.Keys = append(state.Keys, &HDKeyRecord{
+		Path:    path,
+		Account: account,
+		Purpose: purpose,
+		Index:   index,
+		KeyType: keyType,
+		KeyID:   keyPair.ID,
+	})
+	if account >= state.Accounts {
+		state.Accounts = account + 1
+	}
+
+	if err := w.saveHDState(state); err != nil {
+		return nil, err
+	}
+
+	return keyPair, nil
+}
+
+func (w *DefaultWallet) initHDRoot(mnemonic, passphrase string) error {
+	seedKey := w.currentSeedKey()
+	if seedKey == nil {
+		return NewWalletError(ErrorInvalidPassword, "set a wallet password before creating a mnemonic root")
+	}
+
+	seed, err := crypto.MnemonicToSeed(mnemonic, passphrase)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorInvalidMnemonic, "invalid mnemonic", err.Error())
+	}
+
+	sealed, err := sealWithKey(seedKey, seed)
+	if err != nil {
+		return err
+	}
+
+	return w.saveHDState(&hdState{
+		SealedSeed: sealed,
+		Keys:       []*HDKeyRecord{},
+		Created:    time.Now(),
+	})
+}
+
+// hdSeed opens the sealed seed with the seed key held while unlocked. A root
+// written before seeds were sealed is sealed on first use.
+func (w *DefaultWallet) hdSeed(state *hdState) ([]byte, error) {
+	seedKey := w.currentSeedKey()
+	if seedKey == nil {
+		return nil, NewWalletError(ErrorWalletLocked, "the HD seed is sealed; unlock the wallet with its password")
+	}
+
+	if len(state.SealedSeed) == 0 && len(state.Seed) > 0 {
+		sealed, err := sealWithKey(seedKey, state.Seed)
+		if err != nil {
+			return nil, err
+		}
+		seed := state.Seed
+		state.SealedSeed = sealed
+		state.Seed = nil
+		if err := w.saveHDState(state); err != nil {
+			return nil, err
+		}
+		return seed, nil
+	}
+
+	return openWithKey(seedKey, state.SealedSeed)
+}
+
+func (w *DefaultWallet) currentSeedKey() []byte {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.seedKey
+}
+
+func (w *DefaultWallet) loadHDState() (*hdState, error) {
+	raw, err := w.storage.GetMetadata(hdStateKey)
+	if err != nil {
+		return nil, NewWalletError(ErrorHDRootNotFound, "wallet has no mnemonic root")
+	}
+
+	data, ok := raw.(string)
+	if !ok {
+		return nil, NewWalletError(ErrorSerializationError, "unexpected HD state encoding")
+	}
+
+	var state hdState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode HD state", err.Error())
+	}
+	return &state, nil
+}
+
+func (w *DefaultWallet) saveHDState(state *hdState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode HD state", err.Error())
+	}
+	if err := w.storage.SetMetadata(hdStateKey, string(data)); err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to store HD state", err.Error())
+	}
+	return nil
+}
+
+func curveForKeyType(keyType did.KeyType) (crypto.Curve, error) {
+	switch keyType {
+	case did.KeyTypeEd25519:
+		return crypto.CurveEd25519, nil
+	case did.KeyTypeSecp256k1:
+		return crypto.CurveSecp256k1, nil
+	case did.KeyTypeSecp256r1:
+		return crypto.CurveP256, nil
+	default:
+		return "", NewWalletError(ErrorInvalidKeyType, fmt.Sprintf("HD derivation not supported for %s", keyType))
+	}
+}
@@ -0,0 +1,231 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultWallet_MnemonicRestore(t *testing.T) {
+	original := setupHDTestWallet(t)
+
+	mnemonic, err := original.CreateMnemonic(crypto.MnemonicStrength128, "extra words")
+	require.NoError(t, err)
+	assert.True(t, crypto.ValidateMnemonic(mnemonic))
+
+	_, err = original.CreateMnemonic(0, "")
+	require.Error(t, err, "a second root must not replace the first")
+
+	// GenerateKey now derives the next account from the root
+	var originalDIDs []string
+	for i := 0; i < 2; i++ {
+		keyPair, err := original.GenerateKey(did.KeyTypeEd25519)
+		require.NoError(t, err)
+		assert.Contains(t, keyPair.Metadata["derivationPath"], "m/44'/7337'/")
+
+		record, err := original.CreateDID(keyPair.ID, "key")
+		require.NoError(t, err)
+		originalDIDs = append(originalDIDs, record.DID)
+	}
+	assert.NotEqual(t, originalDIDs[0], originalDIDs[1])
+
+	restored := setupHDTestWallet(t)
+	records, err := restored.RestoreFromMnemonic(mnemonic, "extra words", 2)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, originalDIDs[0], records[0].DID)
+	assert.Equal(t, originalDIDs[1], records[1].DID)
+
+	// Restoring again is idempotent
+	again, err := restored.RestoreFromMnemonic(mnemonic, "extra words", 2)
+	require.NoError(t, err)
+	assert.Equal(t, records[0].DID, again[0].DID)
+	dids, err := restored.ListDIDs()
+	require.NoError(t, err)
+	assert.Len(t, dids, 2)
+
+	// The next generated key continues after the restored accounts
+	next, err := restored.GenerateKey(did.KeyTypeEd25519)
+	require.NoError(t, err)
+	assert.Contains(t, next.Metadata["derivationPath"], "/2'/0'/0'")
+}
+
+func TestDefaultWallet_VerifyMnemonic(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+
+	_, err := wallet.VerifyMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+	require.Error(t, err, "verification needs a root")
+
+	mnemonic, err := wallet.CreateMnemonic(0, "")
+	require.NoError(t, err)
+
+	valid, err := wallet.VerifyMnemonic(mnemonic, "")
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = wallet.VerifyMnemonic(mnemonic, "wrong passphrase")
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = wallet.VerifyMnemonic("not a mnemonic", "")
+	require.Error(t, err)
+}
+
+func TestDefaultWallet_RestoreAccountLimit(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+	_, err := wallet.RestoreFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", MaxRestoreAccounts+1)
+	require.Error(t, err)
+	var walletErr *WalletError
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, ErrorTooManyAccounts, walletErr.Code)
+	assert.False(t, wallet.HasHDRoot())
+}
+
+func TestDefaultWallet_DeriveKeyPurposes(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+	_, err := wallet.RestoreFromMnemonic("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", 1)
+	require.NoError(t, err)
+
+	auth, err := wallet.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageAuthentication, 0)
+	require.NoError(t, err)
+	agreement, err := wallet.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageKeyAgreement, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "m/44'/7337'/0'/0'/0'", auth.Metadata["derivationPath"])
+	assert.Equal(t, "m/44'/7337'/0'/1'/0'", agreement.Metadata["derivationPath"])
+	assert.NotEqual(t, auth.ID, agreement.ID)
+
+	// Assertion keys do not share the authentication segment
+	assertion, err := wallet.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageAssertionMethod, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "m/44'/7337'/0'/6'/0'", assertion.Metadata["derivationPath"])
+	assert.NotEqual(t, auth.ID, assertion.ID)
+
+	// Deriving the same path returns the stored key
+	again, err := wallet.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageAuthentication, 0)
+	require.NoError(t, err)
+	assert.Equal(t, auth.ID, again.ID)
+
+	records, err := wallet.ListHDKeys()
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestDefaultWallet_RestoreHDKeyRecords(t *testing.T) {
+	original := setupHDTestWallet(t)
+	mnemonic, err := original.CreateMnemonic(0, "")
+	require.NoError(t, err)
+
+	// A key outside the per-account authentication keys, with a DID
+	assertion, err := original.DeriveKey(did.KeyTypeEd25519, 3, KeyUsageAssertionMethod, 1)
+	require.NoError(t, err)
+	assertionDID, err := original.CreateDID(assertion.ID, "key")
+	require.NoError(t, err)
+	agreement, err := original.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageKeyAgreement, 0)
+	require.NoError(t, err)
+
+	backup, err := original.ListHDKeys()
+	require.NoError(t, err)
+	require.Len(t, backup, 2)
+	assert.Equal(t, []string{"key"}, backup[0].DIDMethods)
+	assert.Empty(t, backup[1].DIDMethods)
+
+	restored := setupHDTestWallet(t)
+	records, err := restored.RestoreFromMnemonic(mnemonic, "", 1, backup...)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, assertionDID.DID, records[0].DID)
+
+	keys, err := restored.ListHDKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	for i, key := range keys {
+		assert.Equal(t, backup[i].Path, key.Path)
+		assert.Equal(t, backup[i].KeyType, key.KeyType)
+		assert.Equal(t, backup[i].DIDMethods, key.DIDMethods)
+	}
+
+	restoredAgreement, err := restored.storage.GetKey(keys[1].KeyID)
+	require.NoError(t, err)
+	assert.Equal(t, agreement.PublicKeyJWK, restoredAgreement.PublicKeyJWK)
+
+	// Restoring again reuses the DIDs
+	_, err = restored.RestoreFromMnemonic(mnemonic, "", 1, backup...)
+	require.NoError(t, err)
+	dids, err := restored.ListDIDs()
+	require.NoError(t, err)
+	assert.Len(t, dids, 1)
+}
+
+func TestDefaultWallet_SealedSeed(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+	_, err := wallet.CreateMnemonic(0, "")
+	require.NoError(t, err)
+	seed, err := wallet.ExportSeed("secret")
+	require.NoError(t, err)
+
+	raw, err := wallet.storage.GetMetadata(hdStateKey)
+	require.NoError(t, err)
+	assert.NotContains(t, raw.(string), base64.StdEncoding.EncodeToString(seed))
+	assert.NotContains(t, raw.(string), `"seed"`)
+
+	// A wallet reopened without the password cannot open the seed
+	require.NoError(t, wallet.Lock("secret"))
+	reopened, err := NewDefaultWallet(wallet.config, wallet.storage, did.NewDefaultKeyManager())
+	require.NoError(t, err)
+	_, err = reopened.DeriveKey(did.KeyTypeEd25519, 0, KeyUsageAuthentication, 0)
+	require.Error(t, err)
+	require.Error(t, reopened.Lock("changed"), "the password cannot change while the seed key is wrapped")
+
+	// Changing the password re-wraps the seed key
+	require.NoError(t, wallet.Unlock("secret"))
+	require.NoError(t, wallet.Lock("changed"))
+	require.Error(t, wallet.Unlock("secret"))
+	require.NoError(t, wallet.Unlock("changed"))
+	again, err := wallet.ExportSeed("changed")
+	require.NoError(t, err)
+	assert.Equal(t, seed, again)
+
+	// A wallet without a password cannot hold a root
+	unprotected, err := NewDefaultWallet(wallet.config, NewInMemoryStorage(), did.NewDefaultKeyManager())
+	require.NoError(t, err)
+	_, err = unprotected.CreateMnemonic(0, "")
+	require.Error(t, err)
+}
+
+func TestDefaultWallet_ExportSeed(t *testing.T) {
+	wallet := setupHDTestWallet(t)
+	_, err := wallet.CreateMnemonic(0, "")
+	require.NoError(t, err)
+
+	_, err = wallet.ExportSeed("wrong")
+	require.Error(t, err)
+
+	seed, err := wallet.ExportSeed("secret")
+	require.NoError(t, err)
+	assert.Len(t, seed, 64)
+
+	// A locked wallet does not release its seed
+	require.NoError(t, wallet.Lock("secret"))
+	_, err = wallet.ExportSeed("secret")
+	require.Error(t, err)
+}
+
+func setupHDTestWallet(t *testing.T) *DefaultWallet {
+	config := DefaultWalletConfig()
+	config.AutoLockTimeout = 0
+	config.EncryptionEnabled = false
+	config.DIDResolver = did.NewMultiDIDResolver()
+
+	wallet, err := NewDefaultWallet(config, NewInMemoryStorage(), did.NewDefaultKeyManager())
+	require.NoError(t, err)
+
+	// The HD seed is sealed under the password, so the wallet needs one
+	require.NoError(t, wallet.Lock("secret"))
+	require.NoError(t, wallet.Unlock("secret"))
+	return wallet
+}
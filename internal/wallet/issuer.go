@@ -2,10 +2,14 @@ package wallet
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/statuslist"
 	"github.com/ParichayaHQ/credence/internal/vc"
 )
 
@@ -14,6 +18,11 @@ type IssuerService struct {
 	wallet           Wallet
 	didResolver      did.MultiResolver
 	credentialIssuer vc.CredentialIssuer
+	statusLists      statuslist.StatusListManager
+
+	// statusMu serialises entry allocation, which is recorded in the
+	// wallet's metadata
+	statusMu sync.Mutex
 }
 
 // NewIssuerService creates a new issuer service
@@ -25,6 +34,12 @@ func NewIssuerService(wallet Wallet, resolver did.MultiResolver, issuer vc.Crede
 	}
 }
 
+// SetStatusListManager makes revocation, suspension and reinstatement update
+// the status list a credential points at, so the change reaches verifiers
+func (is *IssuerService) SetStatusListManager(manager statuslist.StatusListManager) {
+	is.statusLists = manager
+}
+
 // IssueCredential issues a new verifiable credential
 func (is *IssuerService) IssueCredential(ctx context.Context, request *IssuanceRequest) (*vc.VerifiableCredential, error) {
 	if request == nil {
@@ -55,9 +70,6 @@ func (is *IssuerService) IssueCredential(ctx context.Context, request *IssuanceR
 			"signing key not found in wallet", request.SigningKeyID)
 	}
 
-	// For now, skip status list creation since we don't have statuslist manager
-	// In a full implementation, this would create revocation entries
-
 	// Build credential template for issuer
 	template := &vc.CredentialTemplate{
 		Context:           request.Context,
@@ -66,6 +78,15 @@ func (is *IssuerService) IssueCredential(ctx context.Context, request *IssuanceR
 		CredentialSubject: request.CredentialSubject,
 	}
 
+	// Revocable credentials get an entry in the issuer's revocation list
+	if request.EnableRevocation && is.statusLists != nil {
+		status, err := is.allocateRevocationEntry(request.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		template.CredentialStatus = status
+	}
+
 	if request.ExpirationDate != nil {
 		template.ExpirationDate = request.ExpirationDate.Format(time.RFC3339)
 	}
@@ -130,12 +151,9 @@ func (is *IssuerService) RevokeCredential(ctx context.Context, credentialID stri
 		return NewWalletError(ErrorInvalidCredential, "credential does not support revocation")
 	}
 
-	// For now, skip status list index parsing since CredentialStatus doesn't have StatusListIndex field
-	// In a full implementation, this would parse the index from the status list
-	_ = 0 // statusListIndex would be used here
-
-	// For now, skip status list update since we don't have statuslist manager
-	// In a full implementation, this would update the revocation status
+	if err := is.updateStatusList(credential, statuslist.StatusPurposeRevocation, true); err != nil {
+		return err
+	}
 
 	// Update credential status in wallet
 	credRecord.Status = CredentialStatusRevoked
@@ -168,6 +186,10 @@ func (is *IssuerService) SuspendCredential(ctx context.Context, credentialID str
 			"credential not found", credentialID)
 	}
 
+	if err := is.updateStatusList(credRecord.Credential, statuslist.StatusPurposeSuspension, true); err != nil {
+		return err
+	}
+
 	// Update credential status in wallet
 	credRecord.Status = CredentialStatusSuspended
 	if credRecord.Metadata == nil {
@@ -203,6 +225,10 @@ func (is *IssuerService) ReinstateCredential(ctx context.Context, credentialID s
 		return NewWalletError(ErrorInvalidCredential, "credential is not suspended")
 	}
 
+	if err := is.updateStatusList(credRecord.Credential, statuslist.StatusPurposeSuspension, false); err != nil {
+		return err
+	}
+
 	// Update credential status in wallet
 	credRecord.Status = CredentialStatusValid
 	if credRecord.Metadata == nil {
@@ -435,6 +461,92 @@ func (is *IssuerService) buildRequestFromTemplate(template *CredentialTemplate,
 	return request, nil
 }
 
+// updateStatusList sets the credential's bit in its status list. Credentials
+// without a status entry, or a service without a status list manager, only
+// change in the wallet.
+func (is *IssuerService) updateStatusList(credential *vc.VerifiableCredential, purpose statuslist.StatusPurpose, status bool) error {
+	if is.statusLists == nil || credential == nil || credential.CredentialStatus == nil {
+		return nil
+	}
+
+	// Entries are identified as <list ID>#<index>
+	entryID := credential.CredentialStatus.ID
+	separator := strings.LastIndex(entryID, "#")
+	if separator <= 0 {
+		return NewWalletErrorWithDetails(ErrorInvalidCredential, "invalid status list entry", entryID)
+	}
+	listID := entryID[:separator]
+	index, err := parseStatusListIndex(entryID[separator+1:])
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorInvalidCredential, "invalid status list index", entryID)
+	}
+
+	list, err := is.statusLists.GetStatusList(listID)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to get status list", err.Error())
+	}
+	if list.CredentialSubject.StatusPurpose != string(purpose) {
+		return NewWalletErrorWithDetails(ErrorInvalidCredential,
+			"credential status list does not support "+string(purpose), listID)
+	}
+
+	if err := is.statusLists.UpdateStatus(listID, index, status); err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to update status list", err.Error())
+	}
+	return nil
+}
+
+// allocateRevocationEntry reserves the next index in the issuer's
+// revocation list, creating the list on first use and whenever the current
+// one is full. Allocations are recorded in the wallet, so an index is never
+// handed out twice, even across restarts.
+func (is *IssuerService) allocateRevocationEntry(issuer string) (*vc.CredentialStatus, error) {
+	is.statusMu.Lock()
+	defer is.statusMu.Unlock()
+
+	storage := is.wallet.(*DefaultWallet).storage
+	allocations := make(map[string]*statusAllocation)
+	if value, err := storage.GetMetadata(statusAllocationsKey); err == nil {
+		encoded, ok := value.(string)
+		if !ok {
+			return nil, NewWalletError(ErrorSerializationError, "invalid metadata format: "+statusAllocationsKey)
+		}
+		if err := json.Unmarshal([]byte(encoded), &allocations); err != nil {
+			return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode status list allocations", err.Error())
+		}
+	}
+
+	allocation := allocations[issuer]
+	if allocation == nil || allocation.Next >= allocation.Size {
+		list, err := is.statusLists.CreateStatusList(issuer, statuslist.StatusPurposeRevocation, 0)
+		if err != nil {
+			return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to create revocation list", err.Error())
+		}
+		bits, err := statuslist.FromCompressedBase64(list.CredentialSubject.EncodedList)
+		if err != nil {
+			return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to create revocation list", err.Error())
+		}
+		allocation = &statusAllocation{ListID: list.ID, Size: bits.Length()}
+		allocations[issuer] = allocation
+	}
+
+	index := allocation.Next
+	allocation.Next++
+	data, err := json.Marshal(allocations)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode status list allocations", err.Error())
+	}
+	if err := storage.SetMetadata(statusAllocationsKey, string(data)); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to record revocation entry", err.Error())
+	}
+
+	entry, err := is.statusLists.GenerateEntry(allocation.ListID, index, statuslist.StatusPurposeRevocation)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to allocate revocation entry", err.Error())
+	}
+	return &vc.CredentialStatus{ID: entry.ID, Type: entry.Type}, nil
+}
+
 // parseStatusListIndex parses a status list index string to int
 func parseStatusListIndex(indexStr string) (int, error) {
 	var index int
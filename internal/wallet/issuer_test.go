@@ -2,10 +2,18 @@ package wallet
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/statuslist"
 	"github.com/ParichayaHQ/credence/internal/vc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -244,6 +252,197 @@ func TestIssuerService_RevokeCredential(t *testing.T) {
 	}
 }
 
+func TestIssuerService_RevokeCredential_StatusList(t *testing.T) {
+	wallet := setupTestWalletForIssuer(t)
+	manager := statuslist.NewDefaultStatusListManager(nil, statuslist.NewInMemoryStatusListProvider(), nil, nil, nil)
+	service := NewIssuerService(wallet, &MockDIDResolver{}, &MockCredentialIssuer{})
+	service.SetStatusListManager(manager)
+
+	revocations, err := manager.CreateStatusList("did:key:issuer", statuslist.StatusPurposeRevocation, 1024)
+	require.NoError(t, err)
+	suspensions, err := manager.CreateStatusList("did:key:issuer", statuslist.StatusPurposeSuspension, 1024)
+	require.NoError(t, err)
+
+	storeWithStatus := func(id, statusID string) string {
+		record, err := wallet.StoreCredential(&vc.VerifiableCredential{
+			ID:                id,
+			Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+			Type:              []string{"VerifiableCredential"},
+			IssuanceDate:      time.Now().Format(time.RFC3339),
+			CredentialSubject: map[string]interface{}{"id": "did:key:subject"},
+			CredentialStatus:  &vc.CredentialStatus{ID: statusID, Type: "StatusList2021Entry"},
+		})
+		require.NoError(t, err)
+		return record.ID
+	}
+	checkBit := func(list *statuslist.StatusList2021, index int) bool {
+		result, err := manager.CheckStatus(&statuslist.StatusListEntry{
+			StatusPurpose:        list.CredentialSubject.StatusPurpose,
+			StatusListCredential: list.ID,
+			StatusListIndex:      fmt.Sprint(index),
+		})
+		require.NoError(t, err)
+		return result.Status
+	}
+
+	revoked := storeWithStatus("cred-revoked", revocations.ID+"#7")
+	require.NoError(t, service.RevokeCredential(context.Background(), revoked, "fraud"))
+	assert.True(t, checkBit(revocations, 7))
+
+	suspended := storeWithStatus("cred-suspended", suspensions.ID+"#2")
+	require.NoError(t, service.SuspendCredential(context.Background(), suspended, "review"))
+	assert.True(t, checkBit(suspensions, 2))
+	require.NoError(t, service.ReinstateCredential(context.Background(), suspended))
+	assert.False(t, checkBit(suspensions, 2))
+
+	// A revocation cannot be recorded in a suspension list
+	mismatched := storeWithStatus("cred-mismatched", suspensions.ID+"#3")
+	err = service.RevokeCredential(context.Background(), mismatched, "fraud")
+	var walletErr *WalletError
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, ErrorInvalidCredential, walletErr.Code)
+
+	record, err := wallet.GetCredential(mismatched)
+	require.NoError(t, err)
+	assert.NotEqual(t, CredentialStatusRevoked, record.Status)
+}
+
+func TestService_PublishesIssuedCredentialStatus(t *testing.T) {
+	// A full node and gateway that record what the issuer publishes
+	var mu sync.Mutex
+	blobs := make(map[string][]byte)
+	index := make(map[string]string)
+	var deltas []*statuslist.StatusListDelta
+	var gossip []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/blobs", func(w http.ResponseWriter, r *http.Request) {
+		data := readBody(t, r)
+		cid, err := events.GenerateCID(data)
+		require.NoError(t, err)
+		mu.Lock()
+		blobs[cid] = data
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"cid": cid})
+	})
+	mux.HandleFunc("/v1/status/", func(w http.ResponseWriter, r *http.Request) {
+		var announcement events.RevocationAnnounceEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&announcement))
+		if err := statuslist.VerifyAnnouncement(&announcement, statuslist.DIDKeyResolver); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		index[strings.TrimPrefix(r.URL.Path, "/v1/status/")] = announcement.BitmapCID
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/v1/status/deltas", func(w http.ResponseWriter, r *http.Request) {
+		var delta statuslist.StatusListDelta
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&delta))
+		if err := delta.VerifyIssuer(statuslist.DIDKeyResolver); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		deltas = append(deltas, &delta)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/v1/publish", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gossip = append(gossip, r.URL.Query().Get("topic"))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service, err := NewService(&Config{Network: &NetworkConfig{GatewayURL: server.URL, FullNodeURL: server.URL}})
+	require.NoError(t, err)
+	defaultWallet := service.wallet.(*DefaultWallet)
+	keyPair, err := defaultWallet.GenerateKey(did.KeyTypeEd25519)
+	require.NoError(t, err)
+	issuerDID, err := defaultWallet.CreateDID(keyPair.ID, "key")
+	require.NoError(t, err)
+
+	issued, err := service.IssueCredential(context.Background(), &IssuanceRequest{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            issuerDID.DID,
+		CredentialSubject: map[string]interface{}{"id": "did:key:subject"},
+		SigningKeyID:      keyPair.ID,
+		EnableRevocation:  true,
+		StoreInWallet:     true,
+	})
+	require.NoError(t, err)
+	credential := issued.(*vc.VerifiableCredential)
+	require.NotNil(t, credential.CredentialStatus, "revocable credentials get a status entry")
+	listID, entryIndex, found := strings.Cut(credential.CredentialStatus.ID, "#")
+	require.True(t, found)
+
+	// Nothing is published until a status changes
+	announcements, err := service.PublishStatusLists(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, announcements)
+
+	records, err := service.wallet.ListCredentials(nil)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.NoError(t, service.RevokeCredential(context.Background(), records[0].ID, "compromised"))
+
+	announcements, err = service.PublishStatusLists(context.Background())
+	require.NoError(t, err)
+	require.Len(t, announcements, 1)
+	announcement := announcements[0]
+	assert.Equal(t, issuerDID.DID, announcement.Issuer)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{statuslist.RevocationTopic(issuerDID.DID)}, gossip)
+	assert.Equal(t, announcement.BitmapCID, index[issuerDID.DID+"/"+announcement.Epoch])
+
+	snapshot, err := statuslist.DecodeStatusListSnapshot(blobs[announcement.BitmapCID])
+	require.NoError(t, err)
+	require.Len(t, snapshot.Lists, 1)
+	assert.Equal(t, listID, snapshot.Lists[0].ID)
+	bits, err := statuslist.FromCompressedBase64(snapshot.Lists[0].EncodedList)
+	require.NoError(t, err)
+	position, err := parseStatusListIndex(entryIndex)
+	require.NoError(t, err)
+	revoked, err := bits.Get(position)
+	require.NoError(t, err)
+	assert.True(t, revoked, "the published bitmap carries the revocation")
+	assert.Empty(t, deltas, "the first publication of a list has no delta")
+	mu.Unlock()
+
+	// Later changes are also published as deltas signed by the issuer
+	_, err = service.IssueCredential(context.Background(), &IssuanceRequest{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            issuerDID.DID,
+		CredentialSubject: map[string]interface{}{"id": "did:key:subject"},
+		SigningKeyID:      keyPair.ID,
+		EnableRevocation:  true,
+		StoreInWallet:     true,
+	})
+	require.NoError(t, err)
+	records, err = service.wallet.ListCredentials(nil)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for _, record := range records {
+		if record.Status != CredentialStatusRevoked {
+			require.NoError(t, service.RevokeCredential(context.Background(), record.ID, "compromised"))
+		}
+	}
+	_, err = service.PublishStatusLists(context.Background())
+	require.NoError(t, err)
+
+	mu.Lock()
+	require.Len(t, deltas, 1)
+	assert.Equal(t, listID, deltas[0].ListID)
+	assert.Equal(t, uint64(2), deltas[0].Version)
+}
+
 func TestIssuerService_CreateCredentialTemplate(t *testing.T) {
 	tests := []struct {
 		name     string
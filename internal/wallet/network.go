@@ -0,0 +1,337 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/consensus"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/log"
+)
+
+// EventNetwork is the wallet's view of the network: where signed events are
+// submitted and where their progress through the log can be observed
+type EventNetwork interface {
+	// SubmitEvent sends a signed event and returns its CID
+	SubmitEvent(ctx context.Context, event *events.Event) (string, error)
+
+	// GetEventInclusion returns the inclusion proof of an event in the log at
+	// treeSize (0 for the current tree), or nil if it is not in the log yet
+	GetEventInclusion(ctx context.Context, cid string, treeSize int64) (*EventInclusionProof, error)
+
+	// GetLatestCheckpoint returns the latest checkpoint, or nil if there is none
+	GetLatestCheckpoint(ctx context.Context) (*EventCheckpoint, error)
+}
+
+// EventInclusionProof proves that an event reference leaf is in the log
+type EventInclusionProof struct {
+	LeafHash  string   `json:"leafHash"`
+	LeafValue []byte   `json:"leafValue,omitempty"` // The event reference the leaf hash covers
+	LeafIndex int64    `json:"leafIndex"`
+	TreeSize  int64    `json:"treeSize"`
+	AuditPath [][]byte `json:"auditPath"`
+}
+
+// Verify checks that the proof is for the event with the given CID and that
+// its leaf is in the tree with the given root
+func (p *EventInclusionProof) Verify(cid string, root []byte) error {
+	var ref log.EventReference
+	if err := json.Unmarshal(p.LeafValue, &ref); err != nil || ref.CID != cid {
+		return fmt.Errorf("inclusion proof is not for event %s", cid)
+	}
+
+	leafHash := log.HashLeaf(p.LeafValue)
+	if hex.EncodeToString(leafHash) != p.LeafHash {
+		return fmt.Errorf("inclusion proof leaf hash does not match its value")
+	}
+
+	return log.VerifyInclusion(leafHash, &log.InclusionProof{
+		LeafIndex: p.LeafIndex,
+		TreeSize:  p.TreeSize,
+		AuditPath: p.AuditPath,
+	}, root)
+}
+
+// EventCheckpoint is a committee-signed checkpoint of the log. The root is
+// hex encoded and the aggregate signature base64 encoded.
+type EventCheckpoint struct {
+	TreeID    int64     `json:"tree_id"`
+	Root      string    `json:"root"`
+	Epoch     int64     `json:"epoch"`
+	TreeSize  int64     `json:"tree_size"`
+	Timestamp time.Time `json:"timestamp"`
+	Signers   []string  `json:"signers"`
+	Signature string    `json:"signature"`
+}
+
+// Verify checks the committee's signature on the checkpoint and returns its
+// root
+func (c *EventCheckpoint) Verify(verifier consensus.CheckpointVerifier) ([]byte, error) {
+	root, err := hex.DecodeString(c.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint root: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint signature: %w", err)
+	}
+
+	if err := verifier.VerifyCheckpoint(&consensus.Checkpoint{
+		Root:      root,
+		Epoch:     c.Epoch,
+		TreeSize:  c.TreeSize,
+		Signers:   c.Signers,
+		Signature: signature,
+		Timestamp: c.Timestamp,
+	}); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// NetworkConfig holds the endpoints of the services walletd talks to. Events
+// are published through the gateway and stored on the full node when both
+// are configured; inclusion proofs come from the log node. Events are only
+// marked checkpointed against checkpoints signed by the committee.
+type NetworkConfig struct {
+	GatewayURL  string
+	FullNodeURL string
+	LogNodeURL  string
+	Committee   *consensus.CheckpointCommittee
+	Timeout     time.Duration
+	// StatusMirrors are full nodes that mirror the status lists of issued
+	// credentials
+	StatusMirrors []string
+}
+
+// HTTPEventNetwork implements EventNetwork against the p2p gateway, full node
+// and log node HTTP APIs
+type HTTPEventNetwork struct {
+	config *NetworkConfig
+	client *http.Client
+}
+
+// NewHTTPEventNetwork creates an HTTP event network client
+func NewHTTPEventNetwork(config *NetworkConfig) (*HTTPEventNetwork, error) {
+	if config == nil || (config.GatewayURL == "" && config.FullNodeURL == "") {
+		return nil, fmt.Errorf("a gateway or full node URL is required")
+	}
+
+	return &HTTPEventNetwork{
+		config: config,
+		client: newHTTPClient(config.Timeout),
+	}, nil
+}
+
+// SubmitEvent publishes the event on its gossip topic and stores it on the
+// full node. The CID is computed locally the same way the full node does.
+func (n *HTTPEventNetwork) SubmitEvent(ctx context.Context, event *events.Event) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	cid, err := events.GenerateCIDFromJSON(data)
+	if err != nil {
+		return "", err
+	}
+
+	if n.config.GatewayURL != "" {
+		if err := n.Publish(ctx, "events/"+string(event.Type), data); err != nil {
+			return "", err
+		}
+	}
+
+	if n.config.FullNodeURL != "" {
+		if err := n.do(ctx, http.MethodPost, n.endpoint(n.config.FullNodeURL, "/v1/events"), data, nil); err != nil {
+			return "", fmt.Errorf("full node submission failed: %w", err)
+		}
+	}
+
+	return cid, nil
+}
+
+// Publish implements statuslist.Gossip by publishing through the gateway
+func (n *HTTPEventNetwork) Publish(ctx context.Context, topic string, data []byte) error {
+	if n.config.GatewayURL == "" {
+		return fmt.Errorf("no gateway configured")
+	}
+
+	endpoint := n.endpoint(n.config.GatewayURL, "/v1/publish") + "?topic=" + url.QueryEscape(topic)
+
+	var result struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error,omitempty"`
+	}
+	if err := n.do(ctx, http.MethodPost, endpoint, data, &result); err != nil {
+		return fmt.Errorf("gateway publish failed: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("gateway publish failed: %s", result.Error)
+	}
+	return nil
+}
+
+// Store implements statuslist.BlobWriter by storing the blob on the full
+// node
+func (n *HTTPEventNetwork) Store(ctx context.Context, data []byte) (string, error) {
+	if n.config.FullNodeURL == "" {
+		return "", fmt.Errorf("no full node configured")
+	}
+
+	var result struct {
+		CID string `json:"cid"`
+	}
+	if err := n.do(ctx, http.MethodPost, n.endpoint(n.config.FullNodeURL, "/v1/blobs"), data, &result); err != nil {
+		return "", fmt.Errorf("full node blob storage failed: %w", err)
+	}
+	if result.CID == "" {
+		return "", fmt.Errorf("full node returned no blob CID")
+	}
+	return result.CID, nil
+}
+
+// StoreAnnouncement implements statuslist.AnnouncementIndex by recording the
+// announced bitmap CID on the full node, which checks the announcement
+func (n *HTTPEventNetwork) StoreAnnouncement(ctx context.Context, epoch string, announcement *events.RevocationAnnounceEvent) error {
+	if n.config.FullNodeURL == "" {
+		return fmt.Errorf("no full node configured")
+	}
+
+	body, err := json.Marshal(announcement)
+	if err != nil {
+		return err
+	}
+	endpoint := n.endpoint(n.config.FullNodeURL, "/v1/status/"+url.PathEscape(announcement.Issuer)+"/"+url.PathEscape(epoch))
+	if err := n.do(ctx, http.MethodPost, endpoint, body, nil); err != nil {
+		return fmt.Errorf("full node status list storage failed: %w", err)
+	}
+	return nil
+}
+
+// GetEventInclusion queries the log node for the event's inclusion proof
+func (n *HTTPEventNetwork) GetEventInclusion(ctx context.Context, cid string, treeSize int64) (*EventInclusionProof, error) {
+	if n.config.LogNodeURL == "" {
+		return nil, nil
+	}
+
+	endpoint := n.endpoint(n.config.LogNodeURL, "/v1/events/"+url.PathEscape(cid)+"/inclusion")
+	if treeSize > 0 {
+		endpoint += fmt.Sprintf("?tree_size=%d", treeSize)
+	}
+
+	var result struct {
+		LeafHash  string `json:"leaf_hash"`
+		LeafValue []byte `json:"leaf_value"`
+		Proof     struct {
+			LeafIndex int64    `json:"leaf_index"`
+			TreeSize  int64    `json:"tree_size"`
+			AuditPath [][]byte `json:"audit_path"`
+		} `json:"proof"`
+	}
+	if err := n.do(ctx, http.MethodGet, endpoint, nil, &result); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &EventInclusionProof{
+		LeafHash:  result.LeafHash,
+		LeafValue: result.LeafValue,
+		LeafIndex: result.Proof.LeafIndex,
+		TreeSize:  result.Proof.TreeSize,
+		AuditPath: result.Proof.AuditPath,
+	}, nil
+}
+
+// GetLatestCheckpoint fetches the latest checkpoint from the full node
+func (n *HTTPEventNetwork) GetLatestCheckpoint(ctx context.Context) (*EventCheckpoint, error) {
+	if n.config.FullNodeURL == "" {
+		return nil, nil
+	}
+
+	var checkpoint EventCheckpoint
+	if err := n.do(ctx, http.MethodGet, n.endpoint(n.config.FullNodeURL, "/v1/checkpoints/latest"), nil, &checkpoint); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+func (n *HTTPEventNetwork) endpoint(base, path string) string {
+	return strings.TrimRight(base, "/") + path
+}
+
+// httpStatusError is returned for non-2xx responses
+type httpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func isNotFound(err error) bool {
+	statusErr, ok := err.(*httpStatusError)
+	return ok && statusErr.StatusCode == http.StatusNotFound
+}
+
+func (n *HTTPEventNetwork) do(ctx context.Context, method, endpoint string, body []byte, out interface{}) error {
+	return doJSON(ctx, n.client, method, endpoint, body, out)
+}
+
+// newHTTPClient returns a client with timeout, or 30s when it is not positive
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// doJSON sends a JSON request and decodes a JSON response into out
+func doJSON(ctx context.Context, client *http.Client, method, endpoint string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &httpStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(message))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+)
+
+// passwordVerifierKey is the wallet metadata holding the password verifier
+const passwordVerifierKey = "password_verifier"
+
+// seedKeyMetadataKey is the wallet metadata holding the HD seed key wrapped
+// under the password
+const seedKeyMetadataKey = "hd_seed_key"
+
+// passwordIterations is the PBKDF2-HMAC-SHA256 work factor for new
+// verifiers
+const passwordIterations = 600000
+
+// passwordVerifier lets a reopened wallet check its password without
+// storing it
+type passwordVerifier struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Hash       []byte `json:"hash"`
+}
+
+// newPasswordVerifier derives a verifier for a password under a fresh salt
+func newPasswordVerifier(password string) (*passwordVerifier, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate salt", err.Error())
+	}
+	verifier := &passwordVerifier{Salt: salt, Iterations: passwordIterations}
+	hash, err := verifier.derive(password)
+	if err != nil {
+		return nil, err
+	}
+	verifier.Hash = hash
+	return verifier, nil
+}
+
+// matches reports whether a password reproduces the verifier
+func (v *passwordVerifier) matches(password string) bool {
+	hash, err := v.derive(password)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, v.Hash) == 1
+}
+
+func (v *passwordVerifier) derive(password string) ([]byte, error) {
+	hash, err := pbkdf2.Key(sha256.New, password, v.Salt, v.Iterations, sha256.Size)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to derive password hash", err.Error())
+	}
+	return hash, nil
+}
+
+// loadPasswordVerifier returns the stored verifier, or nil when no password
+// was ever set
+func loadPasswordVerifier(storage WalletStorage) (*passwordVerifier, error) {
+	raw, err := storage.GetMetadata(passwordVerifierKey)
+	if err != nil {
+		return nil, nil
+	}
+	data, ok := raw.(string)
+	if !ok {
+		return nil, NewWalletError(ErrorSerializationError, "invalid metadata format: "+passwordVerifierKey)
+	}
+	var verifier passwordVerifier
+	if err := json.Unmarshal([]byte(data), &verifier); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode password verifier", err.Error())
+	}
+	return &verifier, nil
+}
+
+// savePasswordVerifier stores a verifier in wallet metadata
+func savePasswordVerifier(storage WalletStorage, verifier *passwordVerifier) error {
+	data, err := json.Marshal(verifier)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode password verifier", err.Error())
+	}
+	if err := storage.SetMetadata(passwordVerifierKey, string(data)); err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to store password verifier", err.Error())
+	}
+	return nil
+}
+
+// wrappedSeedKey is the key the HD seed is sealed with, sealed in turn under
+// a key derived from the wallet password. It has its own salt, so the
+// verifier says nothing about the wrapping key.
+type wrappedSeedKey struct {
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Sealed     []byte `json:"sealed"`
+}
+
+// newSeedKey generates a key to seal an HD seed with
+func newSeedKey() ([]byte, error) {
+	seedKey := make([]byte, 32)
+	if _, err := rand.Read(seedKey); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate seed key", err.Error())
+	}
+	return seedKey, nil
+}
+
+// wrapSeedKey seals a seed key under a password and a fresh salt
+func wrapSeedKey(password string, seedKey []byte) (*wrappedSeedKey, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate salt", err.Error())
+	}
+	wrapped := &wrappedSeedKey{Salt: salt, Iterations: passwordIterations}
+	wrappingKey, err := wrapped.derive(password)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped.Sealed, err = sealWithKey(wrappingKey, seedKey); err != nil {
+		return nil, err
+	}
+	return wrapped, nil
+}
+
+// unwrap returns the seed key, failing when the password is wrong
+func (k *wrappedSeedKey) unwrap(password string) ([]byte, error) {
+	wrappingKey, err := k.derive(password)
+	if err != nil {
+		return nil, err
+	}
+	seedKey, err := openWithKey(wrappingKey, k.Sealed)
+	if err != nil {
+		return nil, NewWalletError(ErrorInvalidPassword, "password does not unwrap the seed key")
+	}
+	return seedKey, nil
+}
+
+func (k *wrappedSeedKey) derive(password string) ([]byte, error) {
+	key, err := pbkdf2.Key(sha256.New, password, k.Salt, k.Iterations, 32)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to derive wrapping key", err.Error())
+	}
+	return key, nil
+}
+
+// loadWrappedSeedKey returns the stored seed key, or nil when the wallet
+// has none yet
+func loadWrappedSeedKey(storage WalletStorage) (*wrappedSeedKey, error) {
+	raw, err := storage.GetMetadata(seedKeyMetadataKey)
+	if err != nil {
+		return nil, nil
+	}
+	data, ok := raw.(string)
+	if !ok {
+		return nil, NewWalletError(ErrorSerializationError, "invalid metadata format: "+seedKeyMetadataKey)
+	}
+	var wrapped wrappedSeedKey
+	if err := json.Unmarshal([]byte(data), &wrapped); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode seed key", err.Error())
+	}
+	return &wrapped, nil
+}
+
+// saveWrappedSeedKey stores a wrapped seed key in wallet metadata
+func saveWrappedSeedKey(storage WalletStorage, wrapped *wrappedSeedKey) error {
+	data, err := json.Marshal(wrapped)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode seed key", err.Error())
+	}
+	if err := storage.SetMetadata(seedKeyMetadataKey, string(data)); err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to store seed key", err.Error())
+	}
+	return nil
+}
+
+// sealWithKey encrypts plaintext with AES-256-GCM and prefixes the nonce
+func sealWithKey(key, plaintext []byte) ([]byte, error) {
+	aead, err := newKeyAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to generate nonce", err.Error())
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openWithKey decrypts what sealWithKey produced
+func openWithKey(key, sealed []byte) ([]byte, error) {
+	aead, err := newKeyAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, NewWalletError(ErrorCryptoError, "sealed data is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to open sealed data", err.Error())
+	}
+	return plaintext, nil
+}
+
+func newKeyAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "invalid sealing key", err.Error())
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "invalid sealing key", err.Error())
+	}
+	return aead, nil
+}
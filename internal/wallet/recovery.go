@@ -0,0 +1,697 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ParichayaHQ/credence/internal/crypto"
+	"github.com/ParichayaHQ/credence/internal/did"
+)
+
+// Social recovery splits the Ed25519 seed behind a DID into Shamir shares,
+// each sealed to a guardian's did:key. To recover, the owner creates a fresh
+// DID and sends guardians a request signed with it; each guardian re-seals
+// their share to it and attests to the recovery. Once the threshold is
+// reached the old key is rebuilt and a migration statement signed by both the
+// old and new keys, carrying the guardians' attestations, is produced for the
+// scorer.
+
+// RecoveryConfig describes the guardian set protecting a DID
+type RecoveryConfig struct {
+	DID       string    `json:"did"`
+	Guardians []string  `json:"guardians"`
+	Threshold int       `json:"threshold"`
+	Created   time.Time `json:"created"`
+}
+
+// GuardianShare is a recovery share sealed to a guardian's DID
+type GuardianShare struct {
+	OwnerDID    string            `json:"ownerDid"`
+	GuardianDID string            `json:"guardianDid"`
+	Index       byte              `json:"index"`
+	Threshold   int               `json:"threshold"`
+	Sealed      *crypto.SealedBox `json:"sealed"`
+	Created     time.Time         `json:"created"`
+}
+
+// RecoveryStatus represents the state of a recovery request
+type RecoveryStatus string
+
+const (
+	RecoveryStatusPending   RecoveryStatus = "pending"
+	RecoveryStatusCompleted RecoveryStatus = "completed"
+)
+
+// RecoveryRequest is created on the new device and sent to guardians
+type RecoveryRequest struct {
+	ID        string              `json:"id"`
+	OldDID    string              `json:"oldDid"`
+	NewDID    string              `json:"newDid"`
+	Status    RecoveryStatus      `json:"status"`
+	Approvals []*GuardianApproval `json:"approvals,omitempty"`
+	Created   time.Time           `json:"created"`
+	Completed *time.Time          `json:"completed,omitempty"`
+	// Signature is made by the new DID's key, so guardians only re-seal
+	// shares to a key the requester holds
+	Signature string `json:"signature"`
+}
+
+// GuardianApproval carries a guardian's share re-sealed to the new DID
+type GuardianApproval struct {
+	RequestID   string            `json:"requestId"`
+	OldDID      string            `json:"oldDid"`
+	NewDID      string            `json:"newDid"`
+	GuardianDID string            `json:"guardianDid"`
+	Index       byte              `json:"index"`
+	Threshold   int               `json:"threshold"`
+	Sealed      *crypto.SealedBox `json:"sealed"`
+	Approved    time.Time         `json:"approved"`
+	// Attestation is carried into the migration statement so the scorer can
+	// check the guardian took part
+	Attestation *did.GuardianAttestation `json:"attestation"`
+	Signature   string                   `json:"signature"`
+}
+
+const (
+	recoveryConfigPrefix  = "recovery_config:"
+	recoveryRequestPrefix = "recovery_request:"
+	guardianSharePrefix   = "guardian_share:"
+	keyMigrationsKey      = "key_migrations"
+)
+
+// SetupRecovery splits the key behind didStr into shares for the given guardians
+func (w *DefaultWallet) SetupRecovery(didStr string, guardians []string, threshold int) (*RecoveryConfig, []*GuardianShare, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, nil, err
+	}
+
+	w.updateActivity()
+
+	if err := validateGuardians(didStr, guardians, threshold); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err := w.ed25519KeyForDID(didStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secretShares, err := crypto.SplitSecret(privateKey.Seed(), len(guardians), threshold)
+	if err != nil {
+		return nil, nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to split recovery secret", err.Error())
+	}
+
+	now := time.Now()
+	shares := make([]*GuardianShare, len(guardians))
+	for i, guardian := range guardians {
+		sealed, err := sealToDID(guardian, secretShares[i].Value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shares[i] = &GuardianShare{
+			OwnerDID:    didStr,
+			GuardianDID: guardian,
+			Index:       secretShares[i].Index,
+			Threshold:   threshold,
+			Sealed:      sealed,
+			Created:     now,
+		}
+	}
+
+	config := &RecoveryConfig{
+		DID:       didStr,
+		Guardians: guardians,
+		Threshold: threshold,
+		Created:   now,
+	}
+
+	if err := w.saveRecoveryState(recoveryConfigPrefix+didStr, config); err != nil {
+		return nil, nil, err
+	}
+
+	return config, shares, nil
+}
+
+// validateGuardians checks a guardian set and its threshold
+func validateGuardians(didStr string, guardians []string, threshold int) error {
+	if len(guardians) < 2 || threshold < 2 || threshold > len(guardians) {
+		return NewWalletError(ErrorInvalidRecovery, fmt.Sprintf("invalid guardian threshold %d of %d", threshold, len(guardians)))
+	}
+
+	seen := make(map[string]bool, len(guardians))
+	for _, guardian := range guardians {
+		if guardian == didStr || seen[guardian] {
+			return NewWalletError(ErrorInvalidRecovery, "guardians must be distinct from each other and the owner")
+		}
+		seen[guardian] = true
+	}
+	return nil
+}
+
+// ImportRecoveryConfig stores the guardian configuration of a DID on the
+// device recovering it. The configuration holds no secrets, so the owner can
+// keep a copy off the device; recovery uses its threshold and guardians
+// rather than whatever the approvals claim.
+func (w *DefaultWallet) ImportRecoveryConfig(config *RecoveryConfig) error {
+	if err := w.checkUnlocked(); err != nil {
+		return err
+	}
+
+	w.updateActivity()
+
+	if config == nil || config.DID == "" {
+		return NewWalletError(ErrorInvalidRecovery, "recovery configuration requires a DID")
+	}
+	if err := validateGuardians(config.DID, config.Guardians, config.Threshold); err != nil {
+		return err
+	}
+
+	if existing, err := w.GetRecoveryConfig(config.DID); err == nil {
+		if existing.Threshold != config.Threshold || !sameGuardians(existing.Guardians, config.Guardians) {
+			return NewWalletError(ErrorInvalidRecovery, "a different recovery configuration is already stored")
+		}
+		return nil
+	}
+
+	return w.saveRecoveryState(recoveryConfigPrefix+config.DID, config)
+}
+
+// sameGuardians reports whether two guardian lists hold the same DIDs
+func sameGuardians(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, guardian := range b {
+		if !containsString(a, guardian) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString reports whether a list holds a value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRecoveryConfig returns the guardian configuration for a DID
+func (w *DefaultWallet) GetRecoveryConfig(didStr string) (*RecoveryConfig, error) {
+	var config RecoveryConfig
+	if err := w.loadRecoveryState(recoveryConfigPrefix+didStr, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// AcceptGuardianShare stores a share this wallet holds as guardian
+func (w *DefaultWallet) AcceptGuardianShare(share *GuardianShare) error {
+	if err := w.checkUnlocked(); err != nil {
+		return err
+	}
+
+	w.updateActivity()
+
+	if share == nil || share.Sealed == nil || share.OwnerDID == "" {
+		return NewWalletError(ErrorInvalidRecovery, "invalid guardian share")
+	}
+
+	// The share is only useful if we control the guardian DID
+	if _, err := w.storage.GetDID(share.GuardianDID); err != nil {
+		return NewWalletErrorWithDetails(ErrorDIDNotFound, "guardian DID not managed by this wallet", share.GuardianDID)
+	}
+
+	return w.saveRecoveryState(guardianSharePrefix+share.OwnerDID+"|"+share.GuardianDID, share)
+}
+
+// RequestRecovery starts recovery of oldDID onto newDID, which must be held
+// by this wallet. The recovery configuration of oldDID must be stored or
+// imported first.
+func (w *DefaultWallet) RequestRecovery(oldDID, newDID string) (*RecoveryRequest, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	if oldDID == "" || oldDID == newDID {
+		return nil, NewWalletError(ErrorInvalidRecovery, "recovery requires distinct old and new DIDs")
+	}
+	if _, err := w.GetRecoveryConfig(oldDID); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorInvalidRecovery, "no recovery configuration for DID", oldDID)
+	}
+	newKey, err := w.ed25519KeyForDID(newDID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &RecoveryRequest{
+		ID:      fmt.Sprintf("recovery-%d", time.Now().UnixNano()),
+		OldDID:  oldDID,
+		NewDID:  newDID,
+		Status:  RecoveryStatusPending,
+		Created: time.Now(),
+	}
+
+	input, err := request.signingInput()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := w.keyManager.Sign(newKey, input)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to sign recovery request", err.Error())
+	}
+	request.Signature = base64.RawURLEncoding.EncodeToString(signature)
+
+	if err := w.saveRecoveryState(recoveryRequestPrefix+request.ID, request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// GetRecoveryRequest returns a stored recovery request
+func (w *DefaultWallet) GetRecoveryRequest(requestID string) (*RecoveryRequest, error) {
+	var request RecoveryRequest
+	if err := w.loadRecoveryState(recoveryRequestPrefix+requestID, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ApproveRecovery is run by a guardian: it checks the request was signed by
+// the new DID, opens the held share, re-seals it to the new DID and attests
+// to the recovery
+func (w *DefaultWallet) ApproveRecovery(guardianDID string, request *RecoveryRequest) (*GuardianApproval, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	if request == nil {
+		return nil, NewWalletError(ErrorInvalidRecovery, "recovery request is required")
+	}
+	if err := w.verifyRecoveryRequest(request); err != nil {
+		return nil, err
+	}
+
+	var share GuardianShare
+	if err := w.loadRecoveryState(guardianSharePrefix+request.OldDID+"|"+guardianDID, &share); err != nil {
+		return nil, err
+	}
+
+	guardianKey, err := w.ed25519KeyForDID(guardianDID)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := crypto.OpenWithEd25519(guardianKey, share.Sealed)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to open guardian share", err.Error())
+	}
+
+	sealed, err := sealToDID(request.NewDID, value)
+	if err != nil {
+		return nil, err
+	}
+
+	approved := time.Now()
+	attestation := &did.GuardianAttestation{
+		Type:        did.GuardianAttestationType,
+		OldDID:      request.OldDID,
+		NewDID:      request.NewDID,
+		GuardianDID: guardianDID,
+		Threshold:   share.Threshold,
+		IssuedAt:    approved.UTC(),
+	}
+	if err := did.SignGuardianAttestation(w.keyManager, attestation, guardianKey); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to sign attestation", err.Error())
+	}
+
+	approval := &GuardianApproval{
+		RequestID:   request.ID,
+		OldDID:      request.OldDID,
+		NewDID:      request.NewDID,
+		GuardianDID: guardianDID,
+		Index:       share.Index,
+		Threshold:   share.Threshold,
+		Sealed:      sealed,
+		Approved:    approved,
+		Attestation: attestation,
+	}
+
+	input, err := approval.signingInput()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := w.keyManager.Sign(guardianKey, input)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to sign approval", err.Error())
+	}
+	approval.Signature = base64.RawURLEncoding.EncodeToString(signature)
+
+	return approval, nil
+}
+
+// AddRecoveryApproval verifies a guardian approval and attaches it to a pending request
+func (w *DefaultWallet) AddRecoveryApproval(approval *GuardianApproval) (*RecoveryRequest, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	if approval == nil {
+		return nil, NewWalletError(ErrorInvalidRecovery, "approval is required")
+	}
+
+	request, err := w.GetRecoveryRequest(approval.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != RecoveryStatusPending {
+		return nil, NewWalletError(ErrorInvalidRecovery, "recovery request is not pending")
+	}
+	if approval.OldDID != request.OldDID || approval.NewDID != request.NewDID {
+		return nil, NewWalletError(ErrorInvalidRecovery, "approval does not match recovery request")
+	}
+
+	if err := w.verifyApproval(approval); err != nil {
+		return nil, err
+	}
+
+	config, err := w.GetRecoveryConfig(request.OldDID)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(config.Guardians, approval.GuardianDID) {
+		return nil, NewWalletError(ErrorInvalidRecovery, "approval is not from a configured guardian")
+	}
+
+	// The attestation travels on to the scorer, which checks it against the
+	// same threshold
+	attestation := approval.Attestation
+	if attestation == nil || attestation.OldDID != request.OldDID || attestation.NewDID != request.NewDID ||
+		attestation.GuardianDID != approval.GuardianDID || attestation.Threshold != config.Threshold {
+		return nil, NewWalletError(ErrorInvalidRecovery, "approval attestation does not match recovery request")
+	}
+	if err := did.VerifyGuardianAttestation(w.keyManager, attestation); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorInvalidRecovery, "guardian attestation is invalid", err.Error())
+	}
+
+	for _, existing := range request.Approvals {
+		if existing.GuardianDID == approval.GuardianDID || existing.Index == approval.Index {
+			return nil, NewWalletError(ErrorInvalidRecovery, "duplicate guardian approval")
+		}
+	}
+
+	request.Approvals = append(request.Approvals, approval)
+	if err := w.saveRecoveryState(recoveryRequestPrefix+request.ID, request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// CompleteRecovery reconstructs the old key from approvals, imports it and
+// returns a migration statement signed by both the old and new DID keys
+func (w *DefaultWallet) CompleteRecovery(requestID string) (*did.KeyMigrationStatement, error) {
+	if err := w.checkUnlocked(); err != nil {
+		return nil, err
+	}
+
+	w.updateActivity()
+
+	request, err := w.GetRecoveryRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != RecoveryStatusPending {
+		return nil, NewWalletError(ErrorInvalidRecovery, "recovery request is not pending")
+	}
+
+	// The threshold comes from the stored configuration; approvals only
+	// count from the guardians it names
+	config, err := w.GetRecoveryConfig(request.OldDID)
+	if err != nil {
+		return nil, err
+	}
+	approvals := 0
+	for _, approval := range request.Approvals {
+		if containsString(config.Guardians, approval.GuardianDID) {
+			approvals++
+		}
+	}
+	if approvals < config.Threshold {
+		return nil, NewWalletError(ErrorRecoveryThreshold, fmt.Sprintf("%d of %d approvals collected", approvals, config.Threshold))
+	}
+
+	newKey, err := w.ed25519KeyForDID(request.NewDID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([]crypto.SecretShare, 0, len(request.Approvals))
+	guardians := make([]string, 0, len(request.Approvals))
+	attestations := make([]*did.GuardianAttestation, 0, len(request.Approvals))
+	for _, approval := range request.Approvals {
+		if !containsString(config.Guardians, approval.GuardianDID) {
+			continue
+		}
+		value, err := crypto.OpenWithEd25519(newKey, approval.Sealed)
+		if err != nil {
+			return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to open approval share", err.Error())
+		}
+		shares = append(shares, crypto.SecretShare{Index: approval.Index, Value: value})
+		guardians = append(guardians, approval.GuardianDID)
+		attestations = append(attestations, approval.Attestation)
+	}
+
+	seed, err := crypto.CombineShares(shares)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to combine shares", err.Error())
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, NewWalletError(ErrorRecoveryMismatch, "recovered secret has invalid length")
+	}
+	oldKey := ed25519.NewKeyFromSeed(seed)
+
+	// The rebuilt key must match the key embedded in the old did:key
+	expected, _, err := did.PublicKeyFromDIDKey(request.OldDID)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorInvalidDID, "cannot extract old DID key", err.Error())
+	}
+	expectedKey, ok := expected.(ed25519.PublicKey)
+	if !ok || !bytes.Equal(expectedKey, oldKey.Public().(ed25519.PublicKey)) {
+		return nil, NewWalletError(ErrorRecoveryMismatch, "recovered key does not match old DID")
+	}
+
+	statement := &did.KeyMigrationStatement{
+		Type:      did.KeyMigrationStatementType,
+		OldDID:    request.OldDID,
+		NewDID:    request.NewDID,
+		Reason:    did.MigrationReasonSocialRecovery,
+		Guardians: guardians,
+		IssuedAt:  time.Now().UTC(),
+
+		Attestations: attestations,
+	}
+	if err := did.SignKeyMigration(w.keyManager, statement, oldKey, newKey); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to sign migration statement", err.Error())
+	}
+
+	// Keep the old DID around, but only as a deactivated record
+	if _, err := w.storage.GetDID(request.OldDID); err != nil {
+		keyPair, err := w.ImportKey(oldKey, did.KeyTypeEd25519)
+		if err != nil {
+			return nil, err
+		}
+		record := &DIDRecord{
+			DID:      request.OldDID,
+			Method:   "key",
+			KeyID:    keyPair.ID,
+			Created:  time.Now(),
+			Updated:  time.Now(),
+			Status:   DIDStatusDeactivated,
+			Metadata: map[string]interface{}{"migratedTo": request.NewDID},
+		}
+		if err := w.storage.StoreDID(record); err != nil {
+			return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to store recovered DID", err.Error())
+		}
+		w.metrics.DIDsCount++
+	}
+
+	var migrations []*did.KeyMigrationStatement
+	if err := w.loadRecoveryState(keyMigrationsKey, &migrations); err != nil {
+		migrations = nil
+	}
+	migrations = append(migrations, statement)
+	if err := w.saveRecoveryState(keyMigrationsKey, migrations); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request.Status = RecoveryStatusCompleted
+	request.Completed = &now
+	if err := w.saveRecoveryState(recoveryRequestPrefix+request.ID, request); err != nil {
+		return nil, err
+	}
+
+	w.metrics.UpdatedAt = now
+	return statement, nil
+}
+
+// ListKeyMigrations returns the migration statements produced by this wallet
+func (w *DefaultWallet) ListKeyMigrations() ([]*did.KeyMigrationStatement, error) {
+	var migrations []*did.KeyMigrationStatement
+	if err := w.loadRecoveryState(keyMigrationsKey, &migrations); err != nil {
+		return []*did.KeyMigrationStatement{}, nil
+	}
+	return migrations, nil
+}
+
+func (w *DefaultWallet) verifyApproval(approval *GuardianApproval) error {
+	publicKey, _, err := did.PublicKeyFromDIDKey(approval.GuardianDID)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorInvalidDID, "invalid guardian DID", err.Error())
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(approval.Signature)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorInvalidRecovery, "invalid approval signature encoding", err.Error())
+	}
+
+	input, err := approval.signingInput()
+	if err != nil {
+		return err
+	}
+
+	if !w.keyManager.Verify(publicKey, input, signature) {
+		return NewWalletError(ErrorInvalidRecovery, "guardian approval signature is invalid")
+	}
+	return nil
+}
+
+// verifyRecoveryRequest checks a request's signature against the key
+// embedded in its new did:key
+func (w *DefaultWallet) verifyRecoveryRequest(request *RecoveryRequest) error {
+	publicKey, _, err := did.PublicKeyFromDIDKey(request.NewDID)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorInvalidDID, "invalid new DID", err.Error())
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(request.Signature)
+	if err != nil || len(signature) == 0 {
+		return NewWalletError(ErrorInvalidRecovery, "recovery request is not signed by the new DID")
+	}
+
+	input, err := request.signingInput()
+	if err != nil {
+		return err
+	}
+
+	if !w.keyManager.Verify(publicKey, input, signature) {
+		return NewWalletError(ErrorInvalidRecovery, "recovery request signature is invalid")
+	}
+	return nil
+}
+
+// signingInput covers what identifies a request; its status and approvals
+// change as recovery proceeds
+func (r *RecoveryRequest) signingInput() ([]byte, error) {
+	data, err := json.Marshal(struct {
+		ID      string    `json:"id"`
+		OldDID  string    `json:"oldDid"`
+		NewDID  string    `json:"newDid"`
+		Created time.Time `json:"created"`
+	}{r.ID, r.OldDID, r.NewDID, r.Created.UTC()})
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode recovery request", err.Error())
+	}
+	return data, nil
+}
+
+func (a *GuardianApproval) signingInput() ([]byte, error) {
+	unsigned := *a
+	unsigned.Signature = ""
+	unsigned.Approved = a.Approved.UTC()
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode approval", err.Error())
+	}
+	return data, nil
+}
+
+// ed25519KeyForDID returns the Ed25519 private key controlling a wallet DID
+func (w *DefaultWallet) ed25519KeyForDID(didStr string) (ed25519.PrivateKey, error) {
+	record, err := w.storage.GetDID(didStr)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorDIDNotFound, "DID not found", didStr)
+	}
+
+	keyPair, err := w.storage.GetKey(record.KeyID)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorKeyNotFound, "key not found", err.Error())
+	}
+
+	privateKey, ok := keyPair.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, NewWalletError(ErrorInvalidKeyType, "social recovery requires an Ed25519 key")
+	}
+	return privateKey, nil
+}
+
+func (w *DefaultWallet) saveRecoveryState(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode recovery state", err.Error())
+	}
+	if err := w.storage.SetMetadata(key, string(data)); err != nil {
+		return NewWalletErrorWithDetails(ErrorStorageError, "failed to store recovery state", err.Error())
+	}
+	return nil
+}
+
+func (w *DefaultWallet) loadRecoveryState(key string, value interface{}) error {
+	raw, err := w.storage.GetMetadata(key)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorRecoveryNotFound, "recovery state not found", key)
+	}
+	data, ok := raw.(string)
+	if !ok {
+		return NewWalletError(ErrorSerializationError, "unexpected recovery state encoding")
+	}
+	if err := json.Unmarshal([]byte(data), value); err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode recovery state", err.Error())
+	}
+	return nil
+}
+
+// sealToDID encrypts data to the Ed25519 key embedded in a did:key
+func sealToDID(didStr string, data []byte) (*crypto.SealedBox, error) {
+	publicKey, _, err := did.PublicKeyFromDIDKey(didStr)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorInvalidDID, "cannot extract DID key", err.Error())
+	}
+	edKey, ok := publicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, NewWalletError(ErrorInvalidKeyType, "recipient DID must use an Ed25519 key")
+	}
+
+	sealed, err := crypto.SealToEd25519(edKey, data)
+	if err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorCryptoError, "failed to seal share", err.Error())
+	}
+	return sealed, nil
+}
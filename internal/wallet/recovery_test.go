@@ -0,0 +1,195 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultWallet_SocialRecovery(t *testing.T) {
+	owner, ownerDID := setupRecoveryTestWallet(t)
+
+	guardianWallets := make([]*DefaultWallet, 3)
+	guardianDIDs := make([]string, 3)
+	for i := range guardianWallets {
+		guardianWallets[i], guardianDIDs[i] = setupRecoveryTestWallet(t)
+	}
+
+	config, shares, err := owner.SetupRecovery(ownerDID, guardianDIDs, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, config.Threshold)
+	require.Len(t, shares, 3)
+
+	for i, share := range shares {
+		require.NoError(t, guardianWallets[i].AcceptGuardianShare(share))
+	}
+
+	// Owner loses the device and starts over with a fresh DID, bringing only
+	// the recovery configuration
+	device, newDID := setupRecoveryTestWallet(t)
+	_, err = device.RequestRecovery(ownerDID, newDID)
+	require.Error(t, err)
+	require.NoError(t, device.ImportRecoveryConfig(config))
+	request, err := device.RequestRecovery(ownerDID, newDID)
+	require.NoError(t, err)
+	assert.Equal(t, RecoveryStatusPending, request.Status)
+
+	// A single approval does not meet the threshold, whatever it claims
+	approval, err := guardianWallets[0].ApproveRecovery(guardianDIDs[0], request)
+	require.NoError(t, err)
+	approval.Threshold = 1
+	require.NoError(t, signTestApproval(guardianWallets[0], guardianDIDs[0], approval))
+	_, err = device.AddRecoveryApproval(approval)
+	require.NoError(t, err)
+
+	_, err = device.CompleteRecovery(request.ID)
+	require.Error(t, err)
+	var walletErr *WalletError
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, ErrorRecoveryThreshold, walletErr.Code)
+
+	approval, err = guardianWallets[2].ApproveRecovery(guardianDIDs[2], request)
+	require.NoError(t, err)
+	updated, err := device.AddRecoveryApproval(approval)
+	require.NoError(t, err)
+	assert.Len(t, updated.Approvals, 2)
+
+	statement, err := device.CompleteRecovery(request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ownerDID, statement.OldDID)
+	assert.Equal(t, newDID, statement.NewDID)
+	assert.ElementsMatch(t, []string{guardianDIDs[0], guardianDIDs[2]}, statement.Guardians)
+	require.Len(t, statement.Attestations, 2)
+	require.NoError(t, did.VerifyKeyMigration(did.NewDefaultKeyManager(), statement))
+
+	// The guardians' attestations are part of what makes it valid
+	unattested := *statement
+	unattested.Attestations = statement.Attestations[:1]
+	require.Error(t, did.VerifyKeyMigration(did.NewDefaultKeyManager(), &unattested))
+
+	// The recovered DID is kept, but deactivated
+	record, err := device.GetDID(ownerDID)
+	require.NoError(t, err)
+	assert.Equal(t, DIDStatusDeactivated, record.Status)
+
+	completed, err := device.GetRecoveryRequest(request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RecoveryStatusCompleted, completed.Status)
+
+	migrations, err := device.ListKeyMigrations()
+	require.NoError(t, err)
+	assert.Len(t, migrations, 1)
+}
+
+func TestDefaultWallet_SocialRecoveryRejectsForgedApproval(t *testing.T) {
+	owner, ownerDID := setupRecoveryTestWallet(t)
+	guardianA, guardianADID := setupRecoveryTestWallet(t)
+	_, guardianBDID := setupRecoveryTestWallet(t)
+
+	config, shares, err := owner.SetupRecovery(ownerDID, []string{guardianADID, guardianBDID}, 2)
+	require.NoError(t, err)
+	require.NoError(t, guardianA.AcceptGuardianShare(shares[0]))
+
+	device, newDID := setupRecoveryTestWallet(t)
+	require.NoError(t, device.ImportRecoveryConfig(config))
+	request, err := device.RequestRecovery(ownerDID, newDID)
+	require.NoError(t, err)
+
+	// Guardians only re-seal shares to the DID that signed the request
+	_, attackerDID := setupRecoveryTestWallet(t)
+	redirected := *request
+	redirected.NewDID = attackerDID
+	_, err = guardianA.ApproveRecovery(guardianADID, &redirected)
+	require.Error(t, err)
+	unsigned := *request
+	unsigned.Signature = ""
+	_, err = guardianA.ApproveRecovery(guardianADID, &unsigned)
+	require.Error(t, err)
+
+	approval, err := guardianA.ApproveRecovery(guardianADID, request)
+	require.NoError(t, err)
+
+	// Claiming to be a different guardian invalidates the signature
+	forged := *approval
+	forged.GuardianDID = guardianBDID
+	_, err = device.AddRecoveryApproval(&forged)
+	require.Error(t, err)
+
+	// A validly signed approval from outside the guardian set is refused
+	outsider, outsiderDID := setupRecoveryTestWallet(t)
+	foreign := *approval
+	foreign.GuardianDID = outsiderDID
+	require.NoError(t, signTestApproval(outsider, outsiderDID, &foreign))
+	_, err = device.AddRecoveryApproval(&foreign)
+	require.Error(t, err)
+
+	// The stored configuration cannot be swapped for a weaker one
+	weaker := *config
+	weaker.Guardians = []string{guardianADID, outsiderDID}
+	require.Error(t, device.ImportRecoveryConfig(&weaker))
+}
+
+// signTestApproval re-signs an approval after a test changes it
+func signTestApproval(wallet *DefaultWallet, guardianDID string, approval *GuardianApproval) error {
+	guardianKey, err := wallet.ed25519KeyForDID(guardianDID)
+	if err != nil {
+		return err
+	}
+	input, err := approval.signingInput()
+	if err != nil {
+		return err
+	}
+	signature, err := wallet.keyManager.Sign(guardianKey, input)
+	if err != nil {
+		return err
+	}
+	approval.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	return nil
+}
+
+func TestDefaultWallet_SetupRecoveryValidation(t *testing.T) {
+	owner, ownerDID := setupRecoveryTestWallet(t)
+	_, guardianDID := setupRecoveryTestWallet(t)
+
+	tests := []struct {
+		name      string
+		guardians []string
+		threshold int
+	}{
+		{"single guardian", []string{guardianDID}, 1},
+		{"threshold above guardians", []string{guardianDID, "did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK"}, 3},
+		{"owner as guardian", []string{guardianDID, ownerDID}, 2},
+		{"duplicate guardian", []string{guardianDID, guardianDID}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := owner.SetupRecovery(ownerDID, tt.guardians, tt.threshold)
+			require.Error(t, err)
+			var walletErr *WalletError
+			require.ErrorAs(t, err, &walletErr)
+			assert.Equal(t, ErrorInvalidRecovery, walletErr.Code)
+		})
+	}
+}
+
+func setupRecoveryTestWallet(t *testing.T) (*DefaultWallet, string) {
+	config := DefaultWalletConfig()
+	config.AutoLockTimeout = 0
+	config.EncryptionEnabled = false
+	config.DIDResolver = did.NewMultiDIDResolver()
+
+	wallet, err := NewDefaultWallet(config, NewInMemoryStorage(), did.NewDefaultKeyManager())
+	require.NoError(t, err)
+
+	keyPair, err := wallet.GenerateKey(did.KeyTypeEd25519)
+	require.NoError(t, err)
+
+	record, err := wallet.CreateDID(keyPair.ID, "key")
+	require.NoError(t, err)
+
+	return wallet, record.DID
+}
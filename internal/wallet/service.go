@@ -2,24 +2,62 @@ package wallet
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/ParichayaHQ/credence/internal/consensus"
 	"github.com/ParichayaHQ/credence/internal/did"
+	"github.com/ParichayaHQ/credence/internal/events"
+	"github.com/ParichayaHQ/credence/internal/statuslist"
 	"github.com/ParichayaHQ/credence/internal/vc"
 )
 
 // Service provides a high-level API for wallet operations
 // This wraps the core wallet with additional HTTP service functionality
 type Service struct {
-	wallet Wallet
-	config *Config
+	wallet  Wallet
+	config  *Config
+	network EventNetwork
+	// checkpoints verifies the committee signatures on checkpoints
+	checkpoints consensus.CheckpointVerifier
+
+	// eventsMu serialises read-modify-write cycles of the event list. It is
+	// never held across network requests.
+	eventsMu sync.Mutex
+	// syncMu lets one SyncEvents run at a time
+	syncMu sync.Mutex
+
+	budgetMu   sync.Mutex
+	rules      RuleSetProvider
+	scores     ScoreProvider
+	scorerKey  ed25519.PublicKey
+	rulesKey   ed25519.PublicKey
+	budgetMode BudgetMode
+
+	// Credentials issued by the wallet's DIDs keep their status in
+	// statusLists; the publisher pushes changes to the network
+	issuer          *IssuerService
+	statusLists     *statuslist.DefaultStatusListManager
+	statusProvider  statuslist.StatusListProvider
+	statusPublisher *statuslist.StatusListPublisher
 }
 
 // Config for the wallet service
 type Config struct {
 	DataDir string
+	// AutoLockTimeout overrides the wallet idle timeout when non-zero; a
+	// negative value disables auto-lock
+	AutoLockTimeout time.Duration
+	// Network configures event submission; events are only kept locally
+	// when it is nil
+	Network *NetworkConfig
+	// Budget configures vouch budget enforcement; without it budgets are
+	// enforced against the default ruleset
+	Budget *BudgetConfig
 	// Add other service-specific configuration
 }
 
@@ -49,6 +87,10 @@ func NewService(config *Config) (*Service, error) {
 	walletConfig := DefaultWalletConfig()
 	walletConfig.StorageType = "file"
 	walletConfig.StoragePath = config.DataDir
+	walletConfig.DIDResolver = did.NewMultiDIDResolver()
+	if config.AutoLockTimeout != 0 {
+		walletConfig.AutoLockTimeout = config.AutoLockTimeout
+	}
 
 	// Create storage
 	storage := NewInMemoryStorage() // TODO: Use file storage when available
@@ -62,10 +104,116 @@ func NewService(config *Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to create wallet: %w", err)
 	}
 
-	return &Service{
-		wallet: wallet,
-		config: config,
-	}, nil
+	service := &Service{
+		wallet:         wallet,
+		config:         config,
+		budgetMode:     BudgetModeEnforce,
+		statusProvider: newStorageStatusListProvider(storage),
+	}
+	service.statusLists = statuslist.NewDefaultStatusListManager(nil, service.statusProvider, nil, keyManager, nil)
+	service.issuer = NewIssuerService(wallet, walletConfig.DIDResolver,
+		vc.NewDefaultCredentialIssuer(keyManager, walletConfig.DIDResolver))
+	service.issuer.SetStatusListManager(service.statusLists)
+
+	if budget := config.Budget; budget != nil {
+		if budget.Mode != "" {
+			service.budgetMode = budget.Mode
+		}
+		if budget.RulesURL != "" {
+			if budget.RulesPublicKey == nil {
+				return nil, fmt.Errorf("fetching rulesets requires the rules public key")
+			}
+			service.rules = NewHTTPRuleSetProvider(budget.RulesURL, budget.RulesPublicKey, budget.Timeout)
+			service.rulesKey = budget.RulesPublicKey
+		}
+		if budget.ScorerURL != "" {
+			if service.budgetMode == BudgetModeEnforce && budget.ScorerPublicKey == nil {
+				return nil, fmt.Errorf("enforcing vouch budgets requires the scorer public key")
+			}
+			service.scores = NewHTTPScoreProvider(budget.ScorerURL, budget.ScorerPublicKey, budget.Timeout)
+		}
+		service.scorerKey = budget.ScorerPublicKey
+	}
+
+	if config.Network != nil {
+		network, err := NewHTTPEventNetwork(config.Network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure event network: %w", err)
+		}
+		service.network = network
+
+		if config.Network.Committee != nil {
+			verifier, err := consensus.NewCommitteeCheckpointVerifier(config.Network.Committee)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure checkpoint committee: %w", err)
+			}
+			service.checkpoints = verifier
+		}
+
+		// Status list bitmaps are stored on the full node and announced
+		// through the gateway
+		if config.Network.FullNodeURL != "" {
+			var gossip statuslist.Gossip
+			if config.Network.GatewayURL != "" {
+				gossip = network
+			}
+			publisher := statuslist.NewStatusListPublisher(
+				nil, service.statusProvider, network, network, gossip, wallet)
+			// Verifiers holding a list catch up from the deltas on the
+			// full node instead of downloading it again
+			publisher.SetDeltaSink(statuslist.NewHTTPDeltaClient(config.Network.FullNodeURL, config.Network.Timeout), wallet)
+			if len(config.Network.StatusMirrors) > 0 {
+				publisher.SetMirrors(config.Network.StatusMirrors, nil)
+			}
+			if err := publisher.SetStateStore(&metadataStateStore{storage: storage}); err != nil {
+				return nil, fmt.Errorf("failed to load status list publisher: %w", err)
+			}
+			service.SetStatusListPublisher(publisher)
+		}
+	}
+
+	return service, nil
+}
+
+// SetCheckpointVerifier sets how checkpoints are verified before events are
+// marked checkpointed
+func (s *Service) SetCheckpointVerifier(verifier consensus.CheckpointVerifier) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.checkpoints = verifier
+}
+
+// SetEventNetwork sets the network signed events are submitted to
+func (s *Service) SetEventNetwork(network EventNetwork) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.network = network
+}
+
+// SetStatusListPublisher makes revocation, suspension and reinstatement
+// queue the issuer's status lists for publication
+func (s *Service) SetStatusListPublisher(publisher *statuslist.StatusListPublisher) {
+	s.statusPublisher = publisher
+	s.statusLists.SetPublisher(publisher)
+}
+
+// ErrStatusListsUnpublished is returned when status lists are to be published
+// but no full node is configured to publish them to
+var ErrStatusListsUnpublished = NewWalletError(ErrorNotPublished,
+	"status lists are not published: no full node is configured")
+
+// PublishesStatusLists reports whether status changes of issued credentials
+// reach the network. Without a full node they stay in the wallet.
+func (s *Service) PublishesStatusLists() bool {
+	return s.statusPublisher != nil
+}
+
+// PublishStatusLists publishes the status lists changed since the last call
+func (s *Service) PublishStatusLists(ctx context.Context) ([]*events.RevocationAnnounceEvent, error) {
+	if s.statusPublisher == nil {
+		return nil, ErrStatusListsUnpublished
+	}
+	return s.statusPublisher.Flush(ctx)
 }
 
 // Close closes the service and releases resources
@@ -77,17 +225,25 @@ func (s *Service) Close() error {
 // Key Management
 
 func (s *Service) GenerateKey(keyType string) (interface{}, error) {
-	var kt did.KeyType
+	kt, err := parseKeyType(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.wallet.GenerateKey(kt)
+}
+
+func parseKeyType(keyType string) (did.KeyType, error) {
 	switch keyType {
 	case "Ed25519":
-		kt = did.KeyTypeEd25519
+		return did.KeyTypeEd25519, nil
 	case "Secp256k1":
-		kt = did.KeyTypeSecp256k1
+		return did.KeyTypeSecp256k1, nil
+	case "P256":
+		return did.KeyTypeSecp256r1, nil
 	default:
-		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+		return "", fmt.Errorf("unsupported key type: %s", keyType)
 	}
-
-	return s.wallet.GenerateKey(kt)
 }
 
 func (s *Service) ListKeys() (interface{}, error) {
@@ -181,9 +337,24 @@ func (s *Service) DeleteCredential(credentialID string) error {
 	return s.wallet.DeleteCredential(credentialID)
 }
 
-// Event represents a trust event (vouch or report)
+// Credential Issuance
+
+// IssueCredential issues a credential signed by one of the wallet's keys.
+// Revocable credentials get an entry in the issuer's revocation list.
+func (s *Service) IssueCredential(ctx context.Context, request *IssuanceRequest) (interface{}, error) {
+	return s.issuer.IssueCredential(ctx, request)
+}
+
+// RevokeCredential revokes an issued credential and queues its status list
+// for publication
+func (s *Service) RevokeCredential(ctx context.Context, credentialID, reason string) error {
+	return s.issuer.RevokeCredential(ctx, credentialID, reason)
+}
+
+// Event represents a trust event (vouch or report) issued from the wallet.
+// The signed network event is kept alongside its progress through the log.
 type Event struct {
-	ID         string                 `json:"id"`
+	ID         string                 `json:"id"`         // CID of the network event
 	Type       string                 `json:"type"`       // "vouch" or "report"
 	From       string                 `json:"from"`       // DID of the issuer
 	To         string                 `json:"to"`         // DID of the subject
@@ -192,11 +363,19 @@ type Event struct {
 	Timestamp  int64                  `json:"timestamp"`
 	Signature  string                 `json:"signature,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	// Network tracking
+	Network     *events.Event        `json:"event,omitempty"`
+	Status      EventStatus          `json:"status,omitempty"`
+	SubmittedAt *time.Time           `json:"submittedAt,omitempty"`
+	Inclusion   *EventInclusionProof `json:"inclusion,omitempty"`
+	Checkpoint  *EventCheckpoint     `json:"checkpoint,omitempty"`
+	LastError   string               `json:"lastError,omitempty"`
 }
 
 // Event Management
 
-func (s *Service) CreateEvent(eventType, from, to, context, payloadCID string) (interface{}, error) {
+func (s *Service) CreateEvent(ctx context.Context, eventType, from, to, eventContext, payloadCID string) (interface{}, error) {
 	// Validate event type
 	if eventType != "vouch" && eventType != "report" {
 		return nil, fmt.Errorf("invalid event type: %s. Must be 'vouch' or 'report'", eventType)
@@ -207,38 +386,322 @@ func (s *Service) CreateEvent(eventType, from, to, context, payloadCID string) (
 		return nil, fmt.Errorf("from and to DIDs are required")
 	}
 
-	if context == "" {
+	if eventContext == "" {
 		return nil, fmt.Errorf("context is required")
 	}
 
-	// Create event
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for events")
+	}
+
+	// Vouches are checked against the issuer's budget for the epoch before
+	// anything is signed. The ruleset and score come from the network, so
+	// they are fetched before eventsMu is taken.
+	var budget *VouchBudgetStatus
+	if eventType == "vouch" {
+		if err := defaultWallet.checkUnlocked(); err != nil {
+			return nil, err
+		}
+		epoch := time.Now().UTC().Format(EventEpochFormat)
+		status, err := s.fetchVouchBudget(ctx, defaultWallet, from, eventContext, epoch)
+		if err != nil {
+			return nil, err
+		}
+		budget = status
+	}
+
+	event, err := s.recordEvent(defaultWallet, eventType, from, to, eventContext, payloadCID, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed submission is recorded on the event and retried by SyncEvents
+	s.eventsMu.Lock()
+	network := s.network
+	s.eventsMu.Unlock()
+	if network != nil {
+		submitEvent(ctx, network, event)
+		if err := s.mergeEvents(map[string]*Event{event.ID: event}); err != nil {
+			return nil, fmt.Errorf("failed to store event: %w", err)
+		}
+	}
+
+	return event, nil
+}
+
+// recordEvent signs an event, counts a vouch against its issuer's budget and
+// stores the event as signed
+func (s *Service) recordEvent(w *DefaultWallet, eventType, from, to, eventContext, payloadCID string, budget *VouchBudgetStatus) (*Event, error) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+
+	budgetWarning, err := s.checkVouchBudget(w, budget)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build and sign the canonical network event
+	signed, err := w.SignNetworkEvent(events.EventType(eventType), from, to, eventContext, payloadCID)
+	if err != nil {
+		return nil, err
+	}
+	cid, err := networkEventCID(signed)
+	if err != nil {
+		return nil, err
+	}
+
 	event := &Event{
-		ID:         fmt.Sprintf("event-%d", getCurrentTimestamp()),
+		ID:         cid,
 		Type:       eventType,
 		From:       from,
 		To:         to,
-		Context:    context,
+		Context:    eventContext,
 		PayloadCID: payloadCID,
-		Timestamp:  getCurrentTimestamp(),
+		Timestamp:  signed.IssuedAt.Unix(),
+		Signature:  signed.Signature,
+		Network:    signed,
+		Status:     EventStatusSigned,
+	}
+	if budgetWarning != "" {
+		event.Metadata = map[string]interface{}{"budgetWarning": budgetWarning}
+	}
+
+	if eventType == "vouch" {
+		if err := s.recordVouchSpend(w, from, eventContext, signed.Epoch); err != nil {
+			return nil, err
+		}
 	}
 
 	// Get existing events
-	events, err := s.getAllEvents()
+	stored, err := s.getAllEvents()
 	if err != nil {
-		events = []*Event{} // Start with empty list if error
+		stored = []*Event{} // Start with empty list if error
 	}
 
-	// Add new event
-	events = append(events, event)
-
 	// Store updated events list
-	if err := s.storeAllEvents(events); err != nil {
+	if err := s.storeAllEvents(append(stored, event)); err != nil {
 		return nil, fmt.Errorf("failed to store event: %w", err)
 	}
 
 	return event, nil
 }
 
+// SyncEvents submits events the network has not accepted yet and advances
+// submitted events from pending to included to checkpointed, storing the
+// inclusion proof at each step. It returns the events that changed. Failures
+// are recorded on the events they affect, and whatever progress was made is
+// stored regardless.
+func (s *Service) SyncEvents(ctx context.Context) ([]*Event, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.eventsMu.Lock()
+	network, verifier := s.network, s.checkpoints
+	all, err := s.getAllEvents()
+	s.eventsMu.Unlock()
+
+	if network == nil {
+		return nil, fmt.Errorf("no event network configured")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	var (
+		checkpoint    *EventCheckpoint
+		checkpointErr error
+		fetched       bool
+		updated       []*Event
+		changed       = make(map[string]*Event)
+	)
+	for _, event := range all {
+		if event.Network == nil || event.Status == EventStatusCheckpointed || event.Status == EventStatusExpired {
+			continue
+		}
+		storedID := event.ID
+		before := event.Status
+		lastError := event.LastError
+
+		switch event.Status {
+		case EventStatusSigned:
+			if s.renewEvent(event) {
+				submitEvent(ctx, network, event)
+			}
+		case EventStatusPending:
+			proof, err := network.GetEventInclusion(ctx, event.ID, 0)
+			if err != nil {
+				event.LastError = err.Error()
+			} else if proof != nil {
+				event.Inclusion = proof
+				event.Status = EventStatusIncluded
+				event.LastError = ""
+			}
+		}
+
+		if event.Status == EventStatusIncluded {
+			if !fetched {
+				checkpoint, checkpointErr = network.GetLatestCheckpoint(ctx)
+				fetched = true
+			}
+			if checkpointErr != nil {
+				event.LastError = fmt.Sprintf("failed to get latest checkpoint: %v", checkpointErr)
+			} else {
+				checkpointEvent(ctx, network, verifier, event, checkpoint)
+			}
+		}
+
+		if event.Status != before || event.LastError != lastError || event.ID != storedID {
+			updated = append(updated, event)
+			changed[storedID] = event
+		}
+	}
+
+	if len(changed) > 0 {
+		if err := s.mergeEvents(changed); err != nil {
+			return nil, fmt.Errorf("failed to store events: %w", err)
+		}
+	}
+
+	return updated, nil
+}
+
+// eventRenewalMargin is how long before the network's age limit a signed
+// event is re-signed, so it is not rejected in transit
+const eventRenewalMargin = time.Hour
+
+// renewEvent re-signs a signed event before the network rejects it as too
+// old, which changes its ID. A vouch is only re-signed within the epoch its
+// budget was spent in; after that it expires. It reports whether the event
+// can be submitted.
+func (s *Service) renewEvent(event *Event) bool {
+	if time.Since(event.Network.IssuedAt) < events.MaxEventAge-eventRenewalMargin {
+		return true
+	}
+
+	if event.Type == "vouch" && time.Now().UTC().Format(EventEpochFormat) != event.Network.Epoch {
+		event.Status = EventStatusExpired
+		event.LastError = fmt.Sprintf("vouch was not accepted before epoch %s ended", event.Network.Epoch)
+		return false
+	}
+
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		event.LastError = "wallet type not supported for events"
+		return false
+	}
+	signed, err := defaultWallet.SignNetworkEvent(events.EventType(event.Type), event.From, event.To, event.Context, event.PayloadCID)
+	if err != nil {
+		event.LastError = fmt.Sprintf("event is too old to submit and could not be re-signed: %v", err)
+		return false
+	}
+	cid, err := networkEventCID(signed)
+	if err != nil {
+		event.LastError = err.Error()
+		return false
+	}
+
+	event.ID = cid
+	event.Network = signed
+	event.Timestamp = signed.IssuedAt.Unix()
+	event.Signature = signed.Signature
+	return true
+}
+
+// mergeEvents stores updated events over the stored events with the given
+// IDs. An update is dropped when the stored event has since moved further
+// along, e.g. because a sync and a creation both submitted it.
+func (s *Service) mergeEvents(updates map[string]*Event) error {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+
+	all, err := s.getAllEvents()
+	if err != nil {
+		return err
+	}
+	for i, stored := range all {
+		update, ok := updates[stored.ID]
+		if !ok || eventProgress[update.Status] < eventProgress[stored.Status] {
+			continue
+		}
+		all[i] = update
+	}
+	return s.storeAllEvents(all)
+}
+
+// eventProgress orders event statuses along the way to a checkpoint
+var eventProgress = map[EventStatus]int{
+	EventStatusSigned:       0,
+	EventStatusPending:      1,
+	EventStatusIncluded:     2,
+	EventStatusCheckpointed: 3,
+	EventStatusExpired:      3,
+}
+
+// networkEventCID returns the CID a signed network event is stored under
+func networkEventCID(signed *events.Event) (string, error) {
+	data, err := json.Marshal(signed)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode event: %w", err)
+	}
+	cid, err := events.GenerateCIDFromJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute event CID: %w", err)
+	}
+	return cid, nil
+}
+
+// submitEvent sends a signed event to the network
+func submitEvent(ctx context.Context, network EventNetwork, event *Event) {
+	if _, err := network.SubmitEvent(ctx, event.Network); err != nil {
+		event.LastError = err.Error()
+		return
+	}
+
+	now := time.Now()
+	event.SubmittedAt = &now
+	event.Status = EventStatusPending
+	event.LastError = ""
+}
+
+// checkpointEvent marks an included event as checkpointed once a checkpoint
+// signed by the committee covers its leaf, replacing the stored proof with
+// one for the checkpoint's tree size that verifies against its root. Without
+// a committee to check signatures against no event is checkpointed.
+func checkpointEvent(ctx context.Context, network EventNetwork, verifier consensus.CheckpointVerifier, event *Event, checkpoint *EventCheckpoint) {
+	if checkpoint == nil || event.Inclusion == nil || checkpoint.TreeSize <= event.Inclusion.LeafIndex {
+		return
+	}
+	if verifier == nil {
+		event.LastError = "no checkpoint committee configured"
+		return
+	}
+
+	root, err := checkpoint.Verify(verifier)
+	if err != nil {
+		event.LastError = fmt.Sprintf("invalid checkpoint: %v", err)
+		return
+	}
+
+	proof, err := network.GetEventInclusion(ctx, event.ID, checkpoint.TreeSize)
+	if err != nil {
+		event.LastError = err.Error()
+		return
+	}
+	if proof == nil || proof.TreeSize != checkpoint.TreeSize {
+		return
+	}
+	if err := proof.Verify(event.ID, root); err != nil {
+		event.LastError = fmt.Sprintf("invalid inclusion proof: %v", err)
+		return
+	}
+
+	event.Inclusion = proof
+	event.Checkpoint = checkpoint
+	event.Status = EventStatusCheckpointed
+	event.LastError = ""
+}
+
 func (s *Service) ListEvents(filter map[string]interface{}) (interface{}, error) {
 	events, err := s.getAllEvents()
 	if err != nil {
@@ -323,6 +786,9 @@ func (s *Service) matchesEventFilter(event *Event, filter map[string]interface{}
 	if context, ok := filter["context"].(string); ok && event.Context != context {
 		return false
 	}
+	if status, ok := filter["status"].(string); ok && string(event.Status) != status {
+		return false
+	}
 	return true
 }
 
@@ -602,25 +1068,18 @@ func (s *Service) CreatePresentationSubmission(definition interface{}, matchedCr
 		credentials = append(credentials, record.Credential)
 	}
 
-	// Create matches (simplified - in a full implementation would re-evaluate)
+	// Re-evaluate so the submission honours the definition's constraints
+	// and submission requirements
 	processor := vc.NewPresentationDefinitionProcessor()
-	var matches []*vc.CredentialMatch
-
-	for i, credential := range credentials {
-		// For simplicity, create a match for each credential against first input descriptor
-		if len(presDef.InputDescriptors) > 0 {
-			match := &vc.CredentialMatch{
-				InputDescriptorID: presDef.InputDescriptors[0].ID,
-				CredentialIndex:   i,
-				Credential:        credential,
-			}
-			matches = append(matches, match)
-		}
+	result, err := processor.EvaluateCredentials(&presDef, credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate presentation definition: %w", err)
+	}
+	if !result.Valid {
+		return nil, fmt.Errorf("credentials do not satisfy presentation definition: %s", strings.Join(result.Errors, "; "))
 	}
 
-	// Create submission
-	submission := processor.CreateSubmission(&presDef, matches)
-	return submission, nil
+	return processor.CreateSubmissionPackage(&presDef, result)
 }
 
 // Advanced Verification Operations
@@ -767,6 +1226,15 @@ func (s *Service) Unlock(password string) error {
 	return s.wallet.Unlock(password)
 }
 
+// UnlockBlockedUntil reports when an unlock attempt will next be accepted
+// after repeated failures, or the zero time if unlocking is not throttled
+func (s *Service) UnlockBlockedUntil() time.Time {
+	if defaultWallet, ok := s.wallet.(*DefaultWallet); ok {
+		return defaultWallet.UnlockBlockedUntil()
+	}
+	return time.Time{}
+}
+
 func (s *Service) GetStatus() interface{} {
 	isLocked := s.wallet.IsLocked()
 	
@@ -795,6 +1263,171 @@ func (s *Service) GetStatus() interface{} {
 	}
 }
 
+// Mnemonic Backup Operations
+
+func (s *Service) CreateMnemonic(strength int, passphrase string) (interface{}, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for mnemonic backup")
+	}
+
+	mnemonic, err := defaultWallet.CreateMnemonic(strength, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"mnemonic": mnemonic,
+	}, nil
+}
+
+func (s *Service) RestoreFromMnemonic(mnemonic, passphrase string, accounts int, keys []*HDKeyRecord) (interface{}, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for mnemonic backup")
+	}
+
+	return defaultWallet.RestoreFromMnemonic(mnemonic, passphrase, accounts, keys...)
+}
+
+func (s *Service) ListHDKeys() (interface{}, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for mnemonic backup")
+	}
+
+	return defaultWallet.ListHDKeys()
+}
+
+func (s *Service) VerifyMnemonic(mnemonic, passphrase string) (interface{}, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for mnemonic backup")
+	}
+
+	valid, err := defaultWallet.VerifyMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"valid": valid,
+	}, nil
+}
+
+func (s *Service) DeriveKey(keyType string, account uint32, purpose string, index uint32) (interface{}, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for key derivation")
+	}
+
+	if keyType == "" {
+		keyType = "Ed25519"
+	}
+
+	kt, err := parseKeyType(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	if purpose == "" {
+		purpose = string(KeyUsageAuthentication)
+	}
+
+	return defaultWallet.DeriveKey(kt, account, KeyUsage(purpose), index)
+}
+
+// Social Recovery Operations
+
+func (s *Service) recoveryWallet() (*DefaultWallet, error) {
+	defaultWallet, ok := s.wallet.(*DefaultWallet)
+	if !ok {
+		return nil, fmt.Errorf("wallet type not supported for recovery")
+	}
+	return defaultWallet, nil
+}
+
+func (s *Service) SetupRecovery(did string, guardians []string, threshold int) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+
+	config, shares, err := defaultWallet.SetupRecovery(did, guardians, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"config": config,
+		"shares": shares,
+	}, nil
+}
+
+func (s *Service) AcceptGuardianShare(share *GuardianShare) error {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return err
+	}
+	return defaultWallet.AcceptGuardianShare(share)
+}
+
+func (s *Service) ImportRecoveryConfig(config *RecoveryConfig) error {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return err
+	}
+	return defaultWallet.ImportRecoveryConfig(config)
+}
+
+func (s *Service) RequestRecovery(oldDID, newDID string) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.RequestRecovery(oldDID, newDID)
+}
+
+func (s *Service) GetRecoveryRequest(requestID string) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.GetRecoveryRequest(requestID)
+}
+
+func (s *Service) ApproveRecovery(guardianDID string, request *RecoveryRequest) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.ApproveRecovery(guardianDID, request)
+}
+
+func (s *Service) AddRecoveryApproval(approval *GuardianApproval) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.AddRecoveryApproval(approval)
+}
+
+func (s *Service) CompleteRecovery(requestID string) (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.CompleteRecovery(requestID)
+}
+
+func (s *Service) ListKeyMigrations() (interface{}, error) {
+	defaultWallet, err := s.recoveryWallet()
+	if err != nil {
+		return nil, err
+	}
+	return defaultWallet.ListKeyMigrations()
+}
+
 // Helper functions
 
 func getCurrentTimestamp() int64 {
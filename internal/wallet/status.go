@@ -0,0 +1,137 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/ParichayaHQ/credence/internal/statuslist"
+	"github.com/ParichayaHQ/credence/internal/store"
+)
+
+// statusListsKey is the wallet metadata the issuer's status lists are kept in
+const statusListsKey = "status_lists"
+
+// statusAllocationsKey is the wallet metadata recording which list new
+// entries of each issuer go into
+const statusAllocationsKey = "status_list_allocations"
+
+// statusAllocation is the revocation list an issuer's new credentials get
+// entries in and the next index that has not been handed out
+type statusAllocation struct {
+	ListID string `json:"listId"`
+	Size   int    `json:"size"`
+	Next   int    `json:"next"`
+}
+
+// metadataStateStore implements store.StateStore on wallet metadata, so
+// status list state is saved, exported and imported with the wallet
+type metadataStateStore struct {
+	storage WalletStorage
+}
+
+// PutState implements store.StateStore
+func (s *metadataStateStore) PutState(ctx context.Context, key string, data []byte) error {
+	return s.storage.SetMetadata(key, string(data))
+}
+
+// GetState implements store.StateStore
+func (s *metadataStateStore) GetState(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.storage.GetMetadata(key)
+	if err != nil {
+		return nil, store.ErrNotFound
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, NewWalletError(ErrorSerializationError, "invalid metadata format: "+key)
+	}
+	return []byte(encoded), nil
+}
+
+// Close implements store.StateStore
+func (s *metadataStateStore) Close() error {
+	return nil
+}
+
+// storageStatusListProvider keeps the issuer's status lists in wallet
+// metadata, so allocated entries and their bits survive a restart
+type storageStatusListProvider struct {
+	storage WalletStorage
+	mutex   sync.Mutex
+}
+
+func newStorageStatusListProvider(storage WalletStorage) *storageStatusListProvider {
+	return &storageStatusListProvider{storage: storage}
+}
+
+// FetchStatusList implements statuslist.StatusListProvider
+func (p *storageStatusListProvider) FetchStatusList(listID string) (*statuslist.StatusList2021, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	lists, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	list, exists := lists[listID]
+	if !exists {
+		return nil, statuslist.NewStatusListError(statuslist.ErrorListNotFound, "status list not found: "+listID)
+	}
+	return list, nil
+}
+
+// StoreStatusList implements statuslist.StatusListProvider
+func (p *storageStatusListProvider) StoreStatusList(list *statuslist.StatusList2021) error {
+	if list == nil || list.ID == "" {
+		return statuslist.NewStatusListError(statuslist.ErrorInvalidStatusList, "status list ID cannot be empty")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	lists, err := p.load()
+	if err != nil {
+		return err
+	}
+	lists[list.ID] = list
+	data, err := json.Marshal(lists)
+	if err != nil {
+		return NewWalletErrorWithDetails(ErrorSerializationError, "failed to encode status lists", err.Error())
+	}
+	return p.storage.SetMetadata(statusListsKey, string(data))
+}
+
+// ListStatusLists implements statuslist.StatusListProvider
+func (p *storageStatusListProvider) ListStatusLists() ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	lists, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(lists))
+	for id := range lists {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// load decodes the stored lists; every call returns fresh copies
+func (p *storageStatusListProvider) load() (map[string]*statuslist.StatusList2021, error) {
+	lists := make(map[string]*statuslist.StatusList2021)
+	value, err := p.storage.GetMetadata(statusListsKey)
+	if err != nil {
+		return lists, nil
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, NewWalletError(ErrorSerializationError, "invalid metadata format: "+statusListsKey)
+	}
+	if err := json.Unmarshal([]byte(encoded), &lists); err != nil {
+		return nil, NewWalletErrorWithDetails(ErrorSerializationError, "failed to decode status lists", err.Error())
+	}
+	return lists, nil
+}
@@ -0,0 +1,61 @@
+package wallet
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ParichayaHQ/credence/internal/statuslist"
+	"github.com/ParichayaHQ/credence/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssuerService_RevocationEntriesPersist(t *testing.T) {
+	wallet := setupTestWalletForIssuer(t)
+	storage := wallet.(*DefaultWallet).storage
+	newIssuer := func() *IssuerService {
+		service := NewIssuerService(wallet, &MockDIDResolver{}, &MockCredentialIssuer{})
+		service.SetStatusListManager(statuslist.NewDefaultStatusListManager(
+			nil, newStorageStatusListProvider(storage), nil, nil, nil))
+		return service
+	}
+
+	first, err := newIssuer().allocateRevocationEntry("did:key:issuer")
+	require.NoError(t, err)
+
+	// A restarted issuer keeps filling the same list instead of reusing index 0
+	second, err := newIssuer().allocateRevocationEntry("did:key:issuer")
+	require.NoError(t, err)
+
+	firstList, firstIndex, _ := strings.Cut(first.ID, "#")
+	secondList, secondIndex, _ := strings.Cut(second.ID, "#")
+	assert.Equal(t, firstList, secondList)
+	assert.Equal(t, "0", firstIndex)
+	assert.Equal(t, "1", secondIndex)
+
+	lists, err := newStorageStatusListProvider(storage).ListStatusLists()
+	require.NoError(t, err)
+	assert.Equal(t, []string{firstList}, lists)
+}
+
+func TestMetadataStateStore(t *testing.T) {
+	stateStore := &metadataStateStore{storage: NewInMemoryStorage()}
+
+	_, err := stateStore.GetState(context.Background(), "statuslist/publisher")
+	assert.True(t, store.IsNotFound(err))
+
+	require.NoError(t, stateStore.PutState(context.Background(), "statuslist/publisher", []byte(`{"epoch":"2025-03"}`)))
+	data, err := stateStore.GetState(context.Background(), "statuslist/publisher")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"epoch":"2025-03"}`, string(data))
+}
+
+func TestService_StatusListsUnpublishedWithoutFullNode(t *testing.T) {
+	service, err := NewService(&Config{})
+	require.NoError(t, err)
+
+	assert.False(t, service.PublishesStatusLists())
+	_, err = service.PublishStatusLists(context.Background())
+	assert.Equal(t, ErrStatusListsUnpublished, err)
+}
@@ -218,6 +218,11 @@ type WalletConfig struct {
 	// Auto-lock configuration
 	AutoLockTimeout   time.Duration `json:"autoLockTimeout"`
 	
+	// Unlock throttling: after MaxUnlockAttempts consecutive failures further
+	// attempts are refused for UnlockBackoff, doubling on every later failure
+	MaxUnlockAttempts int           `json:"maxUnlockAttempts"`
+	UnlockBackoff     time.Duration `json:"unlockBackoff"`
+	
 	// DID resolution
 	DIDResolver       did.MultiResolver `json:"-"`
 	
@@ -234,6 +239,8 @@ func DefaultWalletConfig() *WalletConfig {
 		DefaultKeyType:      did.KeyTypeEd25519,
 		DefaultAlgorithm:    "EdDSA",
 		AutoLockTimeout:     time.Hour,
+		MaxUnlockAttempts:   5,
+		UnlockBackoff:       30 * time.Second,
 	}
 }
 
@@ -268,6 +275,18 @@ const (
 	ErrorStorageError       = "storage_error"
 	ErrorCryptoError        = "crypto_error"
 	ErrorSerializationError = "serialization_error"
+	ErrorInvalidRecovery    = "invalid_recovery"
+	ErrorRecoveryNotFound   = "recovery_not_found"
+	ErrorRecoveryThreshold  = "recovery_threshold_not_met"
+	ErrorRecoveryMismatch   = "recovery_key_mismatch"
+	ErrorHDRootExists       = "hd_root_exists"
+	ErrorHDRootNotFound     = "hd_root_not_found"
+	ErrorInvalidMnemonic    = "invalid_mnemonic"
+	ErrorTooManyAccounts    = "too_many_accounts"
+	ErrorTooManyAttempts    = "too_many_unlock_attempts"
+	ErrorInvalidEvent       = "invalid_event"
+	ErrorBudgetExceeded     = "vouch_budget_exceeded"
+	ErrorNotPublished       = "not_published"
 )
 
 // NewWalletError creates a new wallet error
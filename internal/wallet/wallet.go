@@ -17,13 +17,18 @@ type DefaultWallet struct {
 	
 	// Security
 	locked    bool
-	password  string
+	verifier  *passwordVerifier // Nil until a password is set
+	seedKey   []byte            // Opens the HD seed; held only while unlocked
 	mutex     sync.RWMutex
 	
 	// Auto-lock timer
 	lastActivity time.Time
 	autoLockTimer *time.Timer
 	
+	// Unlock throttling
+	failedUnlocks      int
+	unlockBlockedUntil time.Time
+	
 	// Metrics
 	metrics *WalletMetrics
 }
@@ -42,11 +47,20 @@ func NewDefaultWallet(config *WalletConfig, storage WalletStorage, keyManager di
 		keyManager = did.NewDefaultKeyManager()
 	}
 	
+	verifier, err := loadPasswordVerifier(storage)
+	if err != nil {
+		return nil, err
+	}
+	
+	// An encrypted wallet that already has a password starts locked. A new
+	// wallet has no password to unlock it with, so it starts unlocked and
+	// locks itself once idle for AutoLockTimeout.
 	wallet := &DefaultWallet{
 		config:     config,
 		storage:    storage,
 		keyManager: keyManager,
-		locked:     config.EncryptionEnabled,
+		locked:     config.EncryptionEnabled && verifier != nil,
+		verifier:   verifier,
 		lastActivity: time.Now(),
 		metrics: &WalletMetrics{
 			CreatedAt: time.Now(),
@@ -71,6 +85,11 @@ func (w *DefaultWallet) GenerateKey(keyType did.KeyType) (*KeyPair, error) {
 	
 	w.updateActivity()
 	
+	// Derive from the mnemonic root when the wallet has one
+	if w.HasHDRoot() {
+		return w.nextHDKey(keyType)
+	}
+	
 	// Generate key using the key manager
 	privateKey, err := w.keyManager.GenerateKey(keyType)
 	if err != nil {
@@ -127,6 +146,15 @@ func (w *DefaultWallet) ImportKey(privateKey interface{}, keyType did.KeyType) (
 	
 	w.updateActivity()
 	
+	return w.storeImportedKey(privateKey, keyType, []KeyUsage{KeyUsageAuthentication, KeyUsageAssertionMethod}, nil)
+}
+
+// storeImportedKey builds a key pair record for an existing private key and stores it
+func (w *DefaultWallet) storeImportedKey(privateKey interface{}, keyType did.KeyType, usage []KeyUsage, metadata map[string]interface{}) (*KeyPair, error) {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	
 	// Get public key
 	publicKey, err := w.keyManager.GetPublicKey(privateKey)
 	if err != nil {
@@ -154,8 +182,8 @@ func (w *DefaultWallet) ImportKey(privateKey interface{}, keyType did.KeyType) (
 		PrivateKeyJWK: privateKeyJWK,
 		Algorithm:     w.getAlgorithmForKeyType(keyType),
 		Created:       time.Now(),
-		Usage:         []KeyUsage{KeyUsageAuthentication, KeyUsageAssertionMethod},
-		Metadata:      make(map[string]interface{}),
+		Usage:         usage,
+		Metadata:      metadata,
 	}
 	
 	// Store the key
@@ -287,6 +315,13 @@ func (w *DefaultWallet) CreateDID(keyID string, method string) (*DIDRecord, erro
 		return nil, NewWalletErrorWithDetails(ErrorStorageError, "failed to store DID", err.Error())
 	}
 	
+	// HD keys remember their DIDs so a restore can recreate them
+	if _, derived := keyPair.Metadata["derivationPath"]; derived {
+		if err := w.recordHDDID(keyID, method); err != nil {
+			return nil, err
+		}
+	}
+	
 	// Update metrics
 	w.metrics.DIDsCount++
 	w.metrics.UpdatedAt = time.Now()
@@ -406,18 +441,23 @@ func (w *DefaultWallet) StoreCredential(credential *vc.VerifiableCredential) (*C
 		return nil, NewWalletError(ErrorInvalidCredential, "credential cannot be nil")
 	}
 	
-	// Parse issuance date
-	issuanceDate, err := time.Parse(time.RFC3339, credential.IssuanceDate)
+	// Parse issuance date; VCDM 2.0 credentials carry validFrom instead,
+	// which is optional
+	issuanceDate, ok, err := credential.ValidityStart()
 	if err != nil {
 		return nil, NewWalletErrorWithDetails(ErrorInvalidCredential, "invalid issuance date", err.Error())
 	}
+	if !ok {
+		if credential.DataModelVersion() != vc.DataModelV2 {
+			return nil, NewWalletError(ErrorInvalidCredential, "invalid issuance date")
+		}
+		issuanceDate = time.Now()
+	}
 	
 	// Parse expiration date if present
 	var expirationDate *time.Time
-	if credential.ExpirationDate != "" {
-		if expDate, err := time.Parse(time.RFC3339, credential.ExpirationDate); err == nil {
-			expirationDate = &expDate
-		}
+	if expDate, ok, err := credential.ValidityEnd(); err == nil && ok {
+		expirationDate = &expDate
 	}
 	
 	// Create credential record
@@ -545,9 +585,10 @@ func (w *DefaultWallet) CreatePresentation(credentialIDs []string, options *Pres
 // Helper methods
 
 func (w *DefaultWallet) checkUnlocked() error {
-	w.mutex.RLock()
-	defer w.mutex.RUnlock()
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
 	
+	w.expireSession()
 	if w.locked {
 		return NewWalletError(ErrorWalletLocked, "wallet is locked")
 	}
@@ -565,10 +606,25 @@ func (w *DefaultWallet) updateActivity() {
 	}
 }
 
+// expireSession locks the wallet once it has been idle for AutoLockTimeout.
+// The timer alone is not enough: it can fire late, and a wallet unlocked
+// after it fired would otherwise stay open. Callers must hold w.mutex.
+func (w *DefaultWallet) expireSession() {
+	if !w.locked && w.config.AutoLockTimeout > 0 && time.Since(w.lastActivity) >= w.config.AutoLockTimeout {
+		w.lock()
+	}
+}
+
+// lock locks the wallet and drops the HD seed key. Callers must hold w.mutex.
+func (w *DefaultWallet) lock() {
+	w.locked = true
+	w.seedKey = nil
+}
+
 func (w *DefaultWallet) startAutoLockTimer() {
 	w.autoLockTimer = time.AfterFunc(w.config.AutoLockTimeout, func() {
 		w.mutex.Lock()
-		w.locked = true
+		w.lock()
 		w.mutex.Unlock()
 	})
 }
@@ -660,8 +716,44 @@ func (w *DefaultWallet) Lock(password string) error {
 		return NewWalletError(ErrorInvalidPassword, "password cannot be empty")
 	}
 	
-	w.locked = true
-	w.password = password
+	// The password outlives the process, so a reopened wallet can be
+	// unlocked with it
+	verifier, err := newPasswordVerifier(password)
+	if err != nil {
+		return err
+	}
+	
+	// The HD seed key is re-wrapped under the new password. A wallet that
+	// was reopened unlocked has not unwrapped it yet, so it has to be locked
+	// with the password it already has.
+	seedKey := w.seedKey
+	if seedKey == nil {
+		wrapped, err := loadWrappedSeedKey(w.storage)
+		if err != nil {
+			return err
+		}
+		if wrapped != nil {
+			if seedKey, err = wrapped.unwrap(password); err != nil {
+				return NewWalletError(ErrorInvalidPassword, "the password cannot change until the wallet has been unlocked")
+			}
+		} else if seedKey, err = newSeedKey(); err != nil {
+			return err
+		}
+	}
+	wrapped, err := wrapSeedKey(password, seedKey)
+	if err != nil {
+		return err
+	}
+	
+	if err := saveWrappedSeedKey(w.storage, wrapped); err != nil {
+		return err
+	}
+	if err := savePasswordVerifier(w.storage, verifier); err != nil {
+		return err
+	}
+	
+	w.lock()
+	w.verifier = verifier
 	return nil
 }
 
@@ -673,14 +765,35 @@ func (w *DefaultWallet) Unlock(password string) error {
 		return NewWalletError(ErrorWalletUnlocked, "wallet is already unlocked")
 	}
 	
-	// TODO: Implement proper password verification
-	// For now, just check if passwords match
-	if w.password != "" && w.password != password {
+	now := time.Now()
+	if now.Before(w.unlockBlockedUntil) {
+		return NewWalletErrorWithDetails(ErrorTooManyAttempts, "too many failed unlock attempts",
+			fmt.Sprintf("retry after %s", w.unlockBlockedUntil.Format(time.RFC3339)))
+	}
+	
+	if w.verifier != nil && !w.verifier.matches(password) {
+		// The backoff runs from when the slow check finished
+		w.recordFailedUnlock(time.Now())
 		return NewWalletError(ErrorInvalidPassword, "invalid password")
 	}
 	
+	wrapped, err := loadWrappedSeedKey(w.storage)
+	if err != nil {
+		return err
+	}
+	if wrapped != nil {
+		if w.seedKey, err = wrapped.unwrap(password); err != nil {
+			return err
+		}
+	}
+	
+	w.failedUnlocks = 0
+	w.unlockBlockedUntil = time.Time{}
 	w.locked = false
-	w.lastActivity = time.Now()
+	w.lastActivity = now
+	if w.config.AutoLockTimeout > 0 && w.autoLockTimer != nil {
+		w.autoLockTimer.Reset(w.config.AutoLockTimeout)
+	}
 	unlockTime := time.Now()
 	w.metrics.LastUnlocked = &unlockTime
 	w.metrics.UnlockCount++
@@ -688,9 +801,39 @@ func (w *DefaultWallet) Unlock(password string) error {
 	return nil
 }
 
-func (w *DefaultWallet) IsLocked() bool {
+// UnlockBlockedUntil returns when the next unlock attempt will be accepted,
+// or the zero time if unlocking is not throttled
+func (w *DefaultWallet) UnlockBlockedUntil() time.Time {
 	w.mutex.RLock()
 	defer w.mutex.RUnlock()
+	if time.Now().Before(w.unlockBlockedUntil) {
+		return w.unlockBlockedUntil
+	}
+	return time.Time{}
+}
+
+// recordFailedUnlock counts a wrong password and starts or extends the
+// backoff window. Callers must hold w.mutex.
+func (w *DefaultWallet) recordFailedUnlock(now time.Time) {
+	w.failedUnlocks++
+	
+	limit := w.config.MaxUnlockAttempts
+	if limit <= 0 || w.failedUnlocks < limit || w.config.UnlockBackoff <= 0 {
+		return
+	}
+	
+	// Double the backoff for every failure past the limit, capped at 2^10
+	shift := w.failedUnlocks - limit
+	if shift > 10 {
+		shift = 10
+	}
+	w.unlockBlockedUntil = now.Add(w.config.UnlockBackoff << uint(shift))
+}
+
+func (w *DefaultWallet) IsLocked() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.expireSession()
 	return w.locked
 }
 
@@ -569,6 +569,77 @@ func TestDefaultWallet_AutoLock(t *testing.T) {
 	assert.Equal(t, ErrorWalletLocked, walletErr.Code)
 }
 
+func TestDefaultWallet_AutoLockAfterUnlock(t *testing.T) {
+	config := DefaultWalletConfig()
+	config.AutoLockTimeout = 50 * time.Millisecond
+	
+	wallet, err := NewDefaultWallet(config, NewInMemoryStorage(), &MockKeyManager{})
+	require.NoError(t, err)
+	
+	// Let the initial timer fire, then unlock: the idle timeout must apply again
+	time.Sleep(80 * time.Millisecond)
+	require.NoError(t, wallet.Unlock(""))
+	assert.False(t, wallet.IsLocked())
+	
+	time.Sleep(80 * time.Millisecond)
+	assert.True(t, wallet.IsLocked())
+}
+
+func TestDefaultWallet_UnlockThrottling(t *testing.T) {
+	config := DefaultWalletConfig()
+	config.EncryptionEnabled = false
+	config.MaxUnlockAttempts = 2
+	config.UnlockBackoff = 50 * time.Millisecond
+	
+	wallet, err := NewDefaultWallet(config, NewInMemoryStorage(), &MockKeyManager{})
+	require.NoError(t, err)
+	require.NoError(t, wallet.Lock("secret"))
+	
+	var walletErr *WalletError
+	for i := 0; i < config.MaxUnlockAttempts; i++ {
+		err = wallet.Unlock("wrong")
+		require.ErrorAs(t, err, &walletErr)
+		assert.Equal(t, ErrorInvalidPassword, walletErr.Code)
+	}
+	assert.False(t, wallet.UnlockBlockedUntil().IsZero())
+	
+	// The correct password is refused until the backoff has passed
+	err = wallet.Unlock("secret")
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, ErrorTooManyAttempts, walletErr.Code)
+	
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, wallet.Unlock("secret"))
+	assert.True(t, wallet.UnlockBlockedUntil().IsZero())
+}
+
+func TestDefaultWallet_StartsLockedOnceAPasswordIsSet(t *testing.T) {
+	config := DefaultWalletConfig()
+	config.EncryptionEnabled = true
+	storage := NewInMemoryStorage()
+	
+	// A brand-new wallet has no password to unlock with
+	wallet, err := NewDefaultWallet(config, storage, &MockKeyManager{})
+	require.NoError(t, err)
+	assert.False(t, wallet.IsLocked())
+	require.NoError(t, wallet.Lock("secret"))
+	
+	// Reopened, it needs the password it was locked with
+	reopened, err := NewDefaultWallet(config, storage, &MockKeyManager{})
+	require.NoError(t, err)
+	assert.True(t, reopened.IsLocked())
+	var walletErr *WalletError
+	require.ErrorAs(t, reopened.Unlock("wrong"), &walletErr)
+	assert.Equal(t, ErrorInvalidPassword, walletErr.Code)
+	require.NoError(t, reopened.Unlock("secret"))
+	
+	// Without encryption the wallet opens unlocked
+	config.EncryptionEnabled = false
+	plain, err := NewDefaultWallet(config, storage, &MockKeyManager{})
+	require.NoError(t, err)
+	assert.False(t, plain.IsLocked())
+}
+
 func TestDefaultWallet_ActivityTracking(t *testing.T) {
 	wallet := setupTestWalletWithMocks(t)
 	
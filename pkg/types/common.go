@@ -139,9 +139,22 @@ type ConsistencyProof struct {
 
 // StatusListProof represents proof of credential status
 type StatusListProof struct {
-	Issuer    string `json:"issuer" validate:"required,did"`
-	Epoch     int64  `json:"epoch" validate:"min=0"`
-	BitmapCID string `json:"bitmapCID" validate:"required"`
+	Issuer    string          `json:"issuer" validate:"required,did"`
+	Epoch     int64           `json:"epoch" validate:"min=0"`
+	BitmapCID string          `json:"bitmapCID" validate:"required"`
+	ListEpoch string          `json:"listEpoch,omitempty"` // Publication epoch of the bitmap (YYYY-MM)
+	Receipts  []MirrorReceipt `json:"receipts,omitempty"`
+}
+
+// MirrorReceipt is a status list mirror's timestamped signature over the
+// bitmap CID it fetched and verified from an issuer
+type MirrorReceipt struct {
+	Mirror    string    `json:"mirror" validate:"required"` // Base64 Ed25519 public key of the mirror
+	Issuer    string    `json:"issuer" validate:"required,did"`
+	Epoch     string    `json:"epoch" validate:"required"`
+	BitmapCID string    `json:"bitmapCID" validate:"required"`
+	Timestamp time.Time `json:"timestamp" validate:"required"`
+	Signature string    `json:"sig" validate:"required"`
 }
 
 // ThresholdProof represents a zero-knowledge threshold proof